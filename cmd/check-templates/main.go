@@ -0,0 +1,51 @@
+package main
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/services"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// check-templates renders every email template with sample data and prints the report;
+// it exits with status 1 when a template fails, so it can gate template edits in CI.
+// -course renders the acceptance and enrollment emails with that course's content blocks
+// and needs the database.
+//
+//	go run ./cmd/check-templates
+//	go run ./cmd/check-templates -course 3
+func main() {
+	courseID := flag.Int("course", 0, "course whose name, fee and content blocks to render (default: sample data)")
+	flag.Parse()
+
+	config.LoadConfig()
+
+	if *courseID > 0 {
+		if err := db.InitDB(); err != nil {
+			log.Fatalf("Error initializing database: %v", err)
+		}
+		defer db.DB.Close()
+	}
+
+	report, err := services.CheckEmailTemplates(context.Background(), *courseID)
+	if err != nil {
+		log.Fatalf("Error checking email templates: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+
+	if report.Failed > 0 {
+		fmt.Printf("%d of %d email templates failed\n", report.Failed, report.Checked)
+		os.Exit(1)
+	}
+	fmt.Printf("All %d email templates passed\n", report.Checked)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/models"
+	"admission-module/services"
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// doctor verifies the external integrations (database and schema, Kafka,
+// SMTP, Razorpay, document storage) and prints a pass/fail matrix.
+// Exits non-zero when any check fails.
+//
+//	go run ./cmd/doctor
+func main() {
+	config.LoadConfig()
+
+	// Connect without applying migrations so the schema check reports the real state
+	if err := db.Connect(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if db.DB != nil {
+		defer db.DB.Close()
+	}
+
+	report := services.RunDoctor(context.Background())
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tSTATUS\tTIME\tDETAIL")
+	for _, c := range report.Checks {
+		fmt.Fprintf(w, "%s\t%s\t%dms\t%s\n", c.Name, c.Status, c.DurationMS, c.Detail)
+	}
+	w.Flush()
+
+	for _, c := range report.Checks {
+		if c.Status == models.DoctorStatusFail {
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/services"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// merge-courses folds duplicate courses (same name and duration) into the oldest
+// course, re-pointing payments and lead selections, then enforces uniqueness.
+//
+//	go run ./cmd/merge-courses -dry-run
+//	go run ./cmd/merge-courses
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report duplicate courses without merging them")
+	flag.Parse()
+
+	config.LoadConfig()
+
+	if err := db.InitDB(); err != nil {
+		log.Fatalf("Error initializing database: %v", err)
+	}
+	defer db.DB.Close()
+
+	groups, err := services.MergeDuplicateCourses(context.Background(), *dryRun)
+	if err != nil {
+		log.Fatalf("Error merging duplicate courses: %v", err)
+	}
+
+	if len(groups) == 0 {
+		fmt.Println("No duplicate courses found")
+		return
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(groups); err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+
+	if *dryRun {
+		fmt.Printf("%d duplicate course groups found (dry run, nothing changed)\n", len(groups))
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// migrate applies or rolls back the embedded schema migrations (db/migrations) and
+// records the version in the schema_migrations table.
+//
+//	go run ./cmd/migrate up          apply all pending migrations
+//	go run ./cmd/migrate down [n]    roll back n migrations (default 1)
+//	go run ./cmd/migrate goto V      migrate up or down to version V
+//	go run ./cmd/migrate version     print the current and latest version
+//	go run ./cmd/migrate force V     mark version V as applied and clean, after fixing a failed migration by hand
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	command, args := os.Args[1], os.Args[2:]
+
+	config.LoadConfig()
+
+	if err := db.Connect(); err != nil {
+		log.Fatalf("Error connecting to database: %v", err)
+	}
+	defer db.DB.Close()
+
+	if err := run(command, args); err != nil {
+		log.Fatalf("migrate %s: %v", command, err)
+	}
+	printVersion()
+}
+
+func run(command string, args []string) error {
+	if command == "up" {
+		return db.Migrate()
+	}
+
+	m, err := db.NewMigrator()
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	switch command {
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			steps, err = strconv.Atoi(args[0])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("invalid number of migrations %q", args[0])
+			}
+		}
+		err = m.Steps(-steps)
+	case "goto":
+		version, parseErr := versionArg(args)
+		if parseErr != nil {
+			return parseErr
+		}
+		err = m.Migrate(uint(version))
+	case "force":
+		version, parseErr := versionArg(args)
+		if parseErr != nil {
+			return parseErr
+		}
+		err = m.Force(version)
+	case "version":
+		return nil
+	default:
+		usage()
+	}
+
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+func versionArg(args []string) (int, error) {
+	if len(args) == 0 {
+		return 0, fmt.Errorf("version is required")
+	}
+	version, err := strconv.Atoi(args[0])
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid version %q", args[0])
+	}
+	return version, nil
+}
+
+func printVersion() {
+	version, latest, dirty, err := db.SchemaVersion()
+	if err != nil {
+		log.Fatalf("Error reading schema version: %v", err)
+	}
+	state := ""
+	if dirty {
+		state = " (dirty)"
+	}
+	fmt.Printf("Schema version %d%s, latest %d\n", version, state, latest)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: migrate up | down [n] | goto VERSION | version | force VERSION")
+	os.Exit(2)
+}
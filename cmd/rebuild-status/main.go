@@ -0,0 +1,71 @@
+package main
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/services"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// rebuild-status replays the application status history of the given leads and prints
+// the leads whose stored status differs; -apply corrects them.
+//
+//	go run ./cmd/rebuild-status -leads 12,15,40
+//	go run ./cmd/rebuild-status -leads 12,15,40 -apply
+func main() {
+	leads := flag.String("leads", "", "comma-separated lead ids to rebuild")
+	apply := flag.Bool("apply", false, "correct the leads whose status differs (default: report only)")
+	flag.Parse()
+
+	var leadIDs []int
+	for _, field := range strings.Split(*leads, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		id, err := strconv.Atoi(field)
+		if err != nil || id <= 0 {
+			log.Fatalf("Invalid lead id %q", field)
+		}
+		leadIDs = append(leadIDs, id)
+	}
+
+	config.LoadConfig()
+
+	if err := db.InitDB(); err != nil {
+		log.Fatalf("Error initializing database: %v", err)
+	}
+	defer db.DB.Close()
+
+	diffs, err := services.RebuildStatusProjection(context.Background(), leadIDs, *apply)
+	if err != nil {
+		log.Fatalf("Error rebuilding status projection: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(diffs); err != nil {
+		log.Fatalf("Error writing report: %v", err)
+	}
+
+	changed, applied := 0, 0
+	for _, diff := range diffs {
+		if diff.Changed {
+			changed++
+		}
+		if diff.Applied {
+			applied++
+		}
+	}
+	if *apply {
+		fmt.Printf("%d of %d leads differed from their history, %d corrected\n", changed, len(diffs), applied)
+	} else {
+		fmt.Printf("%d of %d leads differ from their history (dry run, nothing changed)\n", changed, len(diffs))
+	}
+}
@@ -4,9 +4,15 @@ import (
 	"admission-module/config"
 	"admission-module/db"
 	"admission-module/http"
+	"admission-module/http/middleware"
 	"admission-module/logger"
 	"admission-module/services"
+	"admission-module/services/kafka"
+	"admission-module/services/scheduler"
+	"admission-module/utils"
+	"context"
 	"fmt"
+	"html"
 	"log"
 	netHttp "net/http"
 	"os"
@@ -14,6 +20,7 @@ import (
 	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 )
 
 func main() {
@@ -35,74 +42,187 @@ func main() {
 	// Load configuration
 	config.LoadConfig()
 
-	// Initialize Kafka producer (non-fatal)
+	// Lead emails and phone numbers are encrypted with this key
+	if err := utils.InitPII(config.AppConfig.PIIEncryptionKey); err != nil {
+		logger.Fatal("Invalid PII encryption configuration: %v", err)
+	}
+
+	// Setup routes
+	http.SetupRoutes()
+
+	// Outermost first: request ID, access log, metrics, API usage, then panic recovery
+	// so a recovered panic is still logged and counted as a 500, body limits, and the
+	// startup gate answering everything but the probes with 503 until startup is done
+	var handler netHttp.Handler = netHttp.DefaultServeMux
+	handler = middleware.RequireStarted(handler)
+	handler = middleware.LimitBody(handler)
+	handler = middleware.Recover(handler)
+	handler = middleware.APIUsage(handler)
+	handler = middleware.Metrics(handler)
+	handler = middleware.AccessLog(handler)
+	handler = middleware.RequestID(handler)
+
+	// Start the server before the database and Kafka so /live and /ready answer while
+	// migrations run
+	server := &netHttp.Server{
+		Addr:         ":" + config.AppConfig.ServerPort,
+		Handler:      handler,
+		ReadTimeout:  time.Duration(config.AppConfig.HTTPReadTimeoutSeconds) * time.Second,
+		WriteTimeout: time.Duration(config.AppConfig.HTTPWriteTimeoutSeconds) * time.Second,
+		IdleTimeout:  time.Duration(config.AppConfig.HTTPIdleTimeoutSeconds) * time.Second,
+	}
+	tlsEnabled, redirectServer, err := configureTLS(server)
+	if err != nil {
+		logger.Fatal("Invalid TLS configuration: %v", err)
+	}
+	go func() {
+		var err error
+		if !tlsEnabled {
+			logger.Info("HTTP server listening on %s", server.Addr)
+			err = server.ListenAndServe()
+		} else {
+			logger.Info("HTTPS server listening on %s", server.Addr)
+			// Certificates come from TLSConfig (files loaded by configureTLS, or autocert)
+			err = server.ListenAndServeTLS(config.AppConfig.TLSCertFile, config.AppConfig.TLSKeyFile)
+		}
+		if err != nil && err != netHttp.ErrServerClosed {
+			logger.Fatal("HTTP server failed: %v", err)
+		}
+	}()
+	if redirectServer != nil {
+		go func() {
+			logger.Info("HTTP redirect server listening on %s", redirectServer.Addr)
+			if err := redirectServer.ListenAndServe(); err != nil && err != netHttp.ErrServerClosed {
+				logger.Fatal("HTTP redirect server failed: %v", err)
+			}
+		}()
+	}
+
+	// Wait for the database and Kafka, retrying with backoff (STARTUP_POLICY decides
+	// whether Kafka is required)
+	if err := connectDependencies(); err != nil {
+		logger.Fatal("Startup failed: %v", err)
+	}
+
+	// Initialize Kafka producer (non-fatal under the degrade policy; it reconnects on publish)
 	services.InitProducer()
 
 	// Initialize Kafka DLQ producer (non-fatal)
 	services.InitDLQProducer()
 
-	// Initialize and start Kafka consumer (non-fatal)
-	consumerTopics := []string{"payments", "applications", "emails"}
-	if err := services.InitConsumer(consumerTopics); err != nil {
-		logger.Warn("Failed to initialize Kafka consumer: %v", err)
-	} else {
-		services.StartConsumer()
+	// Apply schema migrations on the connection opened by connectDependencies, or with
+	// MIGRATE_ON_STARTUP=false only check that they were applied by ./cmd/migrate
+	if config.AppConfig.MigrateOnStartup {
+		if err := db.Migrate(); err != nil {
+			logger.Fatal("Error initializing database: %v", err)
+		}
+	} else if err := db.CheckSchemaVersion(); err != nil {
+		logger.Fatal("%v (MIGRATE_ON_STARTUP=false; run `go run ./cmd/migrate up`)", err)
 	}
+	services.MarkMigrationsApplied()
 
-	// Start DLQ auto-retry mechanism
-	services.StartDLQAutoRetry()
+	// Encrypt and index lead contact details stored before PII_ENCRYPTION_KEY was set, so
+	// duplicate checks find them
+	if n, err := services.EncryptLeadContacts(context.Background()); err != nil {
+		logger.Fatal("Error encrypting lead contact details: %v", err)
+	} else if n > 0 {
+		logger.Info("Encrypted and indexed contact details of %d leads", n)
+	}
 
-	// Initialize database
-	if err := db.InitDB(); err != nil {
-		logger.Fatal("Error initializing database: %v", err)
+	// Create/refresh the fallback counselor for leads nobody has capacity for
+	if err := services.EnsureHouseAccount(context.Background()); err != nil {
+		logger.Warn("Failed to set up house account: %v", err)
 	}
 
+	// Detect SMTP configuration; emails are parked in the outbox while it is missing
+	services.InitEmailChannel()
+
 	// Register email processor for Kafka consumer
 	// This callback will be invoked when Kafka consumer receives email.send events
-	services.RegisterEmailProcessor(func(event map[string]interface{}) error {
-		recipient, ok := event["recipient"].(string)
-		if !ok || recipient == "" {
-			return fmt.Errorf("invalid recipient in email event")
-		}
-		subject, ok := event["subject"].(string)
-		if !ok || subject == "" {
-			return fmt.Errorf("invalid subject in email event")
-		}
-		body, ok := event["body"].(string)
-		if !ok || body == "" {
-			return fmt.Errorf("invalid body in email event")
-		}
-		var attachment []string
-		if att, ok := event["attachment"].(string); ok && att != "" {
-			attachment = append(attachment, att)
-		}
-		return services.SendEmailDirect(recipient, subject, body, attachment...)
-	})
+	services.RegisterEmailProcessor(services.DeliverEmailEvent)
 
 	// Register interview scheduler for Kafka consumer
 	// This callback will be invoked when Kafka consumer receives interview.schedule events
 	services.RegisterInterviewScheduler(func(studentID int, email string) error {
-		_, err := services.ScheduleMeet(studentID, email)
+		_, err := services.ScheduleMeet(context.Background(), studentID, email)
 		return err
 	})
 
-	// Setup routes
-	http.SetupRoutes()
+	// Register DLQ alert handler for messages whose retry budget is exhausted
+	// Alerts bypass Kafka so they still go out when the broker is the problem
+	services.RegisterDLQAlertHandler(func(alert kafka.DLQAlert) error {
+		target := alert.Policy.EscalationTarget
+		if target == "" {
+			target = config.AppConfig.DLQAlertEmail
+		}
+		if target == "" {
+			return nil
+		}
+		subject := fmt.Sprintf("[DLQ] Retry budget exhausted for %s message %s", alert.Topic, alert.MessageID)
+		body := fmt.Sprintf("<p>Message <strong>%s</strong> on topic <strong>%s</strong> failed %d retries.</p><p>Last error: %s</p>",
+			alert.MessageID, alert.Topic, alert.RetryCount, html.EscapeString(alert.ErrorMessage))
+		return services.DeliverEmail(target, subject, body)
+	})
+
+	// Initialize and start Kafka consumer once the schema is migrated and its handlers are
+	// registered, so no offset is committed for a message whose writes could not succeed
+	consumerTopics := []string{"leads", "payments", "applications", "emails"}
+	if err := services.InitConsumer(consumerTopics); err != nil {
+		if config.AppConfig.StartupPolicy == config.StartupPolicyFailFast {
+			logger.Fatal("Failed to initialize Kafka consumer: %v", err)
+		}
+		logger.Warn("Failed to initialize Kafka consumer: %v", err)
+	} else {
+		services.StartConsumer()
+	}
+
+	// Start background jobs: DLQ auto-retry, email outbox flush, document
+	// generation, follow-up reminders and escalation of leads stuck in NEW
+	if err := services.RegisterScheduledJobs(); err != nil {
+		logger.Fatal("Error registering scheduled jobs: %v", err)
+	}
+	scheduler.Start()
+	services.MarkStartupComplete()
+	logger.Info("Startup complete, ready for traffic")
 
 	// Set up graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	// Start server in a goroutine
+	// Reload non-critical settings on SIGHUP
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
 	go func() {
-		log.Fatal(netHttp.ListenAndServe(":8080", nil))
+		for range hupChan {
+			result := config.Reload()
+			logger.Info("Configuration reloaded on SIGHUP: applied=%v restart_required=%v", result.Applied, result.RestartRequired)
+		}
 	}()
 
 	// Wait for shutdown signal
-	<-sigChan
+	sig := <-sigChan
+	logger.Info("Received %s, shutting down", sig)
+
+	// Stop accepting connections and let in-flight requests finish before the
+	// services they use go away; whatever is still running after the timeout is cut off
+	drainTimeout := time.Duration(config.AppConfig.ShutdownTimeoutSeconds) * time.Second
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("HTTP requests still running after %s, closing their connections: %v", drainTimeout, err)
+		server.Close()
+	}
+	if redirectServer != nil {
+		redirectServer.Shutdown(shutdownCtx)
+	}
+	cancel()
 
-	// Stop DLQ auto-retry
-	services.StopDLQAutoRetry()
+	// Stop background jobs and wait for in-flight runs
+	scheduler.Stop()
+
+	// Write API usage still held in memory
+	if err := services.FlushAPIUsage(context.Background()); err != nil {
+		logger.Error("Error flushing API usage: %v", err)
+	}
 
 	// Stop consumer gracefully
 	if err := services.StopConsumer(); err != nil {
@@ -113,6 +233,17 @@ func main() {
 	if err := services.Close(); err != nil {
 		logger.Error("Error closing Kafka producer: %v", err)
 	}
+
+	// Close the database pools last: everything above may still use them
+	if db.Replica != nil {
+		if err := db.Replica.Close(); err != nil {
+			logger.Error("Error closing read replica: %v", err)
+		}
+	}
+	if err := db.DB.Close(); err != nil {
+		logger.Error("Error closing database: %v", err)
+	}
+	logger.Info("Shutdown complete")
 }
 
 // findProjectRoot walks up from start and returns the first directory containing go.mod
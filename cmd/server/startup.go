@@ -0,0 +1,99 @@
+package main
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/services"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Startup states of a dependency, logged in the startup summary
+const (
+	dependencyUp       = "up"
+	dependencyDegraded = "degraded"
+	dependencyDisabled = "disabled"
+)
+
+// startupDependency is an external service the server connects to before taking traffic
+type startupDependency struct {
+	name     string
+	required bool // the server cannot run without it, whatever STARTUP_POLICY says
+	disabled bool // not configured, skipped
+	connect  func(ctx context.Context) error
+}
+
+// connectDependencies waits for the database and Kafka (see Config.StartupPolicy) and
+// logs the state each one starts in. It returns an error when the server must not
+// start: a required dependency, or under fail-fast any dependency, stayed unreachable.
+func connectDependencies() error {
+	policy := config.AppConfig.StartupPolicy
+	if policy != config.StartupPolicyDegrade && policy != config.StartupPolicyFailFast {
+		return fmt.Errorf("invalid STARTUP_POLICY %q (use %s or %s)", policy, config.StartupPolicyDegrade, config.StartupPolicyFailFast)
+	}
+
+	dependencies := []startupDependency{
+		{name: "database", required: true, connect: func(ctx context.Context) error { return db.Connect() }},
+		{
+			// Without the replica, reporting reads fall back to the primary
+			name:     "database-replica",
+			disabled: config.AppConfig.DBReplicaDSN == "",
+			connect:  func(ctx context.Context) error { return db.ConnectReplica() },
+		},
+		{
+			name:     "kafka",
+			disabled: strings.TrimSpace(config.AppConfig.KafkaBrokers) == "",
+			connect: func(ctx context.Context) error {
+				_, err := services.CheckKafkaBroker(ctx)
+				return err
+			},
+		},
+	}
+
+	var summary []string
+	for _, dep := range dependencies {
+		state := dependencyUp
+		if dep.disabled {
+			state = dependencyDisabled
+		} else if err := waitForDependency(dep); err != nil {
+			if dep.required || policy == config.StartupPolicyFailFast {
+				return fmt.Errorf("%s unreachable after %d attempts: %w", dep.name, config.AppConfig.StartupRetryAttempts, err)
+			}
+			logger.Warn("Starting without %s (STARTUP_POLICY=%s): %v", dep.name, policy, err)
+			state = dependencyDegraded
+		}
+		summary = append(summary, dep.name+"="+state)
+	}
+
+	logger.Info("Startup dependencies: %s (policy %s)", strings.Join(summary, ", "), policy)
+	return nil
+}
+
+// waitForDependency connects to dep, retrying with a doubling backoff, and returns the
+// last error once the attempts are used up
+func waitForDependency(dep startupDependency) error {
+	attempts := config.AppConfig.StartupRetryAttempts
+	backoff := time.Duration(config.AppConfig.StartupRetryBackoffSeconds) * time.Second
+	maxBackoff := time.Duration(config.AppConfig.StartupRetryMaxBackoffSeconds) * time.Second
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err = dep.connect(ctx)
+		cancel()
+		if err == nil {
+			logger.Info("✓ Connected to %s (attempt %d/%d)", dep.name, attempt, attempts)
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+		logger.Warn("Connecting to %s failed (attempt %d/%d), retrying in %s: %v", dep.name, attempt, attempts, backoff, err)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxBackoff)
+	}
+	return err
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"admission-module/config"
+	"admission-module/logger"
+	"crypto/tls"
+	"fmt"
+	"net"
+	netHttp "net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// configureTLS sets server up for HTTPS from the TLS_* settings and reports whether TLS
+// is on. The returned plain HTTP server (nil unless TLS_HTTP_PORT is set, which autocert
+// defaults to 80) answers ACME HTTP-01 challenges and redirects everything else to HTTPS.
+func configureTLS(server *netHttp.Server) (enabled bool, redirect *netHttp.Server, err error) {
+	cfg := config.AppConfig
+	domains := cfg.AutocertDomains()
+	httpPort := cfg.TLSHTTPPort
+
+	var fallback netHttp.Handler = netHttp.HandlerFunc(redirectToHTTPS)
+	switch {
+	case len(domains) > 0:
+		if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+			return false, nil, fmt.Errorf("set either TLS_CERT_FILE/TLS_KEY_FILE or TLS_AUTOCERT_DOMAINS, not both")
+		}
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(domains...),
+			Cache:      autocert.DirCache(cfg.TLSAutocertCacheDir),
+			Email:      cfg.TLSAutocertEmail,
+		}
+		server.TLSConfig = manager.TLSConfig()
+		fallback = manager.HTTPHandler(fallback)
+		if httpPort == "" {
+			httpPort = "80"
+		}
+		logger.Info("TLS enabled with Let's Encrypt certificates for %s (cache %s)", strings.Join(domains, ", "), cfg.TLSAutocertCacheDir)
+
+	case cfg.TLSCertFile != "" || cfg.TLSKeyFile != "":
+		if cfg.TLSCertFile == "" || cfg.TLSKeyFile == "" {
+			return false, nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+		}
+		// Fail at startup rather than on the first handshake
+		if _, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile); err != nil {
+			return false, nil, fmt.Errorf("error loading TLS certificate: %w", err)
+		}
+		server.TLSConfig = &tls.Config{}
+		logger.Info("TLS enabled with certificate %s", cfg.TLSCertFile)
+
+	default:
+		return false, nil, nil
+	}
+
+	server.TLSConfig.MinVersion = tls.VersionTLS12
+	if httpPort != "" {
+		redirect = &netHttp.Server{
+			Addr:              ":" + httpPort,
+			Handler:           fallback,
+			ReadHeaderTimeout: 10 * time.Second,
+			IdleTimeout:       time.Duration(cfg.HTTPIdleTimeoutSeconds) * time.Second,
+		}
+	}
+	return true, redirect, nil
+}
+
+// redirectToHTTPS sends plain HTTP requests to the same URL over HTTPS on SERVER_PORT
+func redirectToHTTPS(w netHttp.ResponseWriter, r *netHttp.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if port := config.AppConfig.ServerPort; port != "443" {
+		host = net.JoinHostPort(host, port)
+	}
+	netHttp.Redirect(w, r, "https://"+host+r.URL.RequestURI(), netHttp.StatusPermanentRedirect)
+}
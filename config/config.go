@@ -1,9 +1,13 @@
 package config
 
 import (
+	"admission-module/logger"
+	"io"
+	"log"
 	"os"
-
-	"github.com/joho/godotenv"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -12,6 +16,21 @@ type Config struct {
 	DBUser     string
 	DBPassword string
 	DBName     string
+	// Database connection pool: open connections are capped at DBMaxOpenConns (each lead
+	// upload row holds one for its transaction), DBMaxIdleConns are kept for reuse, and a
+	// connection is closed after DBConnMaxLifetimeMinutes, or DBConnMaxIdleTimeMinutes unused
+	DBMaxOpenConns           int
+	DBMaxIdleConns           int
+	DBConnMaxLifetimeMinutes int
+	DBConnMaxIdleTimeMinutes int
+	// DBReplicaDSN is an optional read replica (a lib/pq connection string or postgres://
+	// URL) serving the lead list, analytics, reports and exports
+	DBReplicaDSN string
+	// DBQueryTimeoutSeconds bounds each payment and application operation on the database
+	// (its queries and transaction together); cancelling the request cancels it sooner
+	DBQueryTimeoutSeconds int
+	// DBSlowQueryThresholdMs logs each statement taking at least this long; 0 disables
+	DBSlowQueryThresholdMs int
 
 	RazorpayKeyID         string
 	RazorpayKeySecret     string
@@ -22,36 +41,320 @@ type Config struct {
 	SMTPUser  string
 	SMTPPass  string
 	EmailFrom string
+	// EmailDailyRecipientCap caps the emails one recipient is sent per day, counting only
+	// categories outside EmailQuotaExemptCategories (transactional and staff by default)
+	EmailDailyRecipientCap     int
+	EmailQuotaExemptCategories map[string]bool
 	// Kafka
 	KafkaBrokers  string
 	KafkaTopic    string
 	KafkaDLQTopic string
+	// Kafka connection security for managed clusters (SASL_SSL): KafkaSASLMechanism is
+	// PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512; KafkaTLSCAFile adds a CA to the system roots
+	KafkaSASLMechanism string
+	KafkaSASLUsername  string
+	KafkaSASLPassword  string
+	KafkaTLSEnabled    bool
+	KafkaTLSCAFile     string
+	// KafkaAsyncPublish makes Publish enqueue messages without waiting for the broker;
+	// failed deliveries are recorded in the DLQ
+	KafkaAsyncPublish bool
+	// KafkaPublishTimeoutSeconds bounds each attempt of a synchronous publish
+	KafkaPublishTimeoutSeconds int
+	// KafkaConsumerGroup is the consumer group of all topic readers; give each environment
+	// sharing a broker its own group. KafkaStartOffset ("latest" or "earliest") is where a
+	// new group starts reading.
+	KafkaConsumerGroup string
+	KafkaStartOffset   string
+	// KafkaConsumerConcurrency is the number of workers processing each consumed topic;
+	// KafkaTopicConcurrency overrides it per topic (KAFKA_TOPIC_CONCURRENCY="emails=8,payments=1")
+	KafkaConsumerConcurrency int
+	KafkaTopicConcurrency    map[string]int
+	// DLQ messages are stored redacted: string fields are cut to DLQMaxFieldChars, and the
+	// original payload is kept for retries only up to DLQMaxPayloadBytes
+	DLQMaxFieldChars   int
+	DLQMaxPayloadBytes int
+	// DLQArchiveAfterDays is how long resolved DLQ messages stay in dlq_messages before archival
+	DLQArchiveAfterDays int
+	// Default DLQ retry policy, used for topics without a dlq_retry_policy row: attempts before
+	// escalation, and an exponential backoff (seconds * multiplier^retries, capped at the max)
+	DLQRetryMaxRetries        int
+	DLQRetryBackoffSeconds    int
+	DLQRetryBackoffMultiplier float64
+	DLQRetryMaxBackoffSeconds int
+	// AdminAPIToken guards sensitive admin endpoints (X-Admin-Token header); they are disabled when empty
+	AdminAPIToken string
+	// PIIEncryptionKey (32 bytes, base64) encrypts lead emails and phone numbers at rest;
+	// they are stored in plaintext when empty
+	PIIEncryptionKey string
+	// DLQAlertEmail receives DLQ escalations when a retry policy has no escalation target
+	DLQAlertEmail string
+	// DLQ backlog alert: sent to DLQAlertEmail and DLQAlertSlackWebhookURL when more than
+	// DLQAlertThreshold messages are unresolved, at most once per DLQAlertCooldownMinutes
+	DLQAlertThreshold       int
+	DLQAlertCooldownMinutes int
+	DLQAlertSlackWebhookURL string
+	// FollowUpStaleDays is how long a lead may go without activity before its counselor is reminded
+	FollowUpStaleDays int
+	// House account receives leads when no counselor has capacity.
+	// Disabled when neither HOUSE_ACCOUNT_EMAIL nor EMAIL_FROM is set.
+	HouseAccountName  string
+	HouseAccountEmail string
+	HouseAccountPhone string
+	// PaymentPageURL is where students complete a pending order (order_id and student_id are appended)
+	PaymentPageURL string
+	// PaymentLinkResendsPerDay caps counselor-triggered payment link resends per lead
+	PaymentLinkResendsPerDay int
+	// PaymentLinkExpiryDays is how long a Razorpay Payment Link can be paid
+	PaymentLinkExpiryDays int
+	// Course fee invoices are issued by the institution below and numbered
+	// InvoicePrefix/<financial year>/<serial>, restarting every financial year. Fees include
+	// InvoiceTaxRate percent GST: IGST when the student's state differs from
+	// InstitutionStateCode, otherwise split equally into CGST and SGST.
+	InstitutionName    string
+	InstitutionAddress string
+	InstitutionGSTIN   string
+	// InstitutionStateCode is the GST state code of the institution, by default the first
+	// two digits of its GSTIN
+	InstitutionStateCode string
+	InstitutionEmail     string
+	InvoicePrefix        string
+	InvoiceTaxRate       float64
+	// ReceiptPrefix numbers the PDF receipts of captured payments like invoices
+	ReceiptPrefix string
+	// RejectedLeadRetentionDays is how long a rejected lead keeps its PII before anonymization
+	RejectedLeadRetentionDays int
+	// RetentionPolicies overrides the window of retention policies, in days, by policy name;
+	// 0 switches a policy off (RETENTION_POLICIES="purge_sent_events=14, purge_api_usage=0").
+	// With RetentionDryRun the scheduled retention run only counts what it would remove.
+	RetentionPolicies map[string]int
+	RetentionDryRun   bool
+	// razorpay_webhooks is partitioned by month. Partitions are created
+	// WebhookPartitionPremakeMonths ahead; those older than WebhookPartitionArchiveMonths are
+	// detached into standalone cold-storage tables, dropped after WebhookPartitionDropMonths
+	// (0 keeps them until removed by hand).
+	WebhookPartitionPremakeMonths int
+	WebhookPartitionArchiveMonths int
+	WebhookPartitionDropMonths    int
+	// ManagerReportEmails receive the scheduled admissions summary (comma-separated)
+	ManagerReportEmails string
+	// AdminSummaryEnabled turns on the end-of-day summary emailed at AdminSummarySchedule to
+	// the recipients managed through /admin/summary-recipients
+	AdminSummaryEnabled bool
+	// LeadEscalationDays is how long a lead may stay NEW before it is reassigned or sent to the admin queue
+	LeadEscalationDays int
+	// Counselor shift windows ("09:00-14:00", server local time); new leads arriving during
+	// a shift go to the counselors scheduled for it first. "off" disables the shift.
+	CounselorMorningShift string
+	CounselorEveningShift string
+	// CourseFeeDeadlineDays is how long an accepted student has to pay the course fee before the offer expires
+	CourseFeeDeadlineDays int
+	// Acceptances into courses whose fee is at least DualApprovalMinCourseFee (0 disables)
+	// need two distinct approvers; AcceptanceApproverEmails (comma-separated) are asked
+	// for the second approval
+	DualApprovalMinCourseFee float64
+	AcceptanceApproverEmails string
+	// Spreadsheets emailed to the POP3 mailbox InboundMailUser@InboundMailHost by
+	// InboundMailAllowedSenders (addresses or @domains, comma-separated) are imported as leads
+	InboundMailHost           string
+	InboundMailPort           int
+	InboundMailUser           string
+	InboundMailPassword       string
+	InboundMailAllowedSenders string
+	// Enrolled students (course fee paid) are handed to the LMS/ERP: EnrollmentSyncMode "rest"
+	// POSTs each record to EnrollmentSyncURL (bearer EnrollmentSyncToken), "file" writes it to
+	// document storage under enrollment-exports/. Disabled (records stay queued) when empty.
+	EnrollmentSyncMode        string
+	EnrollmentSyncURL         string
+	EnrollmentSyncToken       string
+	EnrollmentSyncMaxAttempts int
+	// Generated documents (async exports) are stored under DocumentStorageDir
+	// and linked to requesters through AppBaseURL
+	DocumentStorageDir string
+	AppBaseURL         string
+	// Schedules of background jobs (cron expressions or "@every <duration>")
+	DLQRetrySchedule         string
+	FollowUpReminderSchedule string
+	LeadEscalationSchedule   string
+	OfferExpirySchedule      string
+	InboundMailSchedule      string
+	RetentionSchedule        string
+	DailyReportSchedule      string
+	AdminSummarySchedule     string
+	WeeklyReportSchedule     string
+	MetricsSnapshotSchedule  string
+	// Payment webhook SLO: WebhookSLOTarget of webhooks processed successfully within
+	// WebhookSLOLatencyMs. Burn rate alerts go to SLOAlertEmail (falls back to DLQAlertEmail).
+	WebhookSLOTarget    float64
+	WebhookSLOLatencyMs int
+	SLOAlertEmail       string
+	WebhookSLOSchedule  string
+	// Lead integrations quiet for longer than their cadence are reported to
+	// IntegrationAlertEmail and IntegrationAlertSlackWebhookURL (falling back to the DLQ
+	// alert channels), again every IntegrationAlertRepeatHours while they stay quiet
+	IntegrationAlertEmail           string
+	IntegrationAlertSlackWebhookURL string
+	IntegrationAlertRepeatHours     int
+	IntegrationHealthSchedule       string
+	// The payment-reconciliation job compares the Razorpay payments and orders of the last
+	// PaymentReconciliationWindowHours with the payment tables
+	PaymentReconciliationSchedule    string
+	PaymentReconciliationWindowHours int
+	// LogLevel is the minimum level written by the logger (DEBUG, INFO, WARN, ERROR)
+	LogLevel string
+	// LogFormat is "text" (default) or "json" for one JSON object per log entry
+	LogFormat string
+	// LogFile, when set, also writes logs to this file, rotated by size and time
+	LogFile string
+	// LogFileOnly stops writing logs to stdout once LogFile is set
+	LogFileOnly bool
+	// LogFileMaxSizeMB rotates the log file once it reaches this size
+	LogFileMaxSizeMB int
+	// LogFileRotateHours rotates the log file at this interval (24 = daily, at UTC midnight)
+	LogFileRotateHours int
+	// LogFileMaxBackups is the number of rotated log files kept
+	LogFileMaxBackups int
+	// LogFileMaxAgeDays deletes rotated log files older than this
+	LogFileMaxAgeDays int
+	// ServerPort is the HTTP listen port. On SIGINT/SIGTERM the server stops accepting
+	// connections and gives in-flight requests ShutdownTimeoutSeconds to finish.
+	ServerPort             string
+	ShutdownTimeoutSeconds int
+	// At startup the database and Kafka are retried StartupRetryAttempts times, waiting
+	// StartupRetryBackoffSeconds doubled after every attempt up to StartupRetryMaxBackoffSeconds.
+	// StartupPolicy decides what Kafka still unreachable after that does: StartupPolicyDegrade
+	// starts without it (events wait in the outbox), StartupPolicyFailFast exits. The
+	// database is required under both.
+	StartupPolicy                 string
+	StartupRetryAttempts          int
+	StartupRetryBackoffSeconds    int
+	StartupRetryMaxBackoffSeconds int
+	// MigrateOnStartup applies pending schema migrations when the server starts. Without it
+	// the server refuses to start on an outdated schema; run `go run ./cmd/migrate up` first.
+	MigrateOnStartup bool
+	// HTTPS on ServerPort: with TLSCertFile and TLSKeyFile, or with certificates from
+	// Let's Encrypt for TLSAutocertDomains (comma-separated), cached in TLSAutocertCacheDir.
+	// TLSHTTPPort serves the ACME HTTP-01 challenge (port 80 by default with autocert) and
+	// redirects other plain HTTP requests to HTTPS. Plain HTTP when none is set.
+	TLSCertFile         string
+	TLSKeyFile          string
+	TLSAutocertDomains  string
+	TLSAutocertEmail    string
+	TLSAutocertCacheDir string
+	TLSHTTPPort         string
+	// Request bodies are limited to MaxRequestBodyKB, except spreadsheet uploads
+	// (MaxUploadBodyMB), which get UploadTimeoutSeconds to be sent and processed instead
+	// of the server read and write timeouts
+	MaxRequestBodyKB     int
+	MaxUploadBodyMB      int
+	UploadTimeoutSeconds int
+	// HTTP server timeouts: reading a request (headers and body), writing the response,
+	// and keeping an idle keep-alive connection open
+	HTTPReadTimeoutSeconds  int
+	HTTPWriteTimeoutSeconds int
+	HTTPIdleTimeoutSeconds  int
+	// FeatureFlags holds the features enabled through FEATURE_FLAGS (comma-separated names)
+	FeatureFlags map[string]bool
+	// RateLimits holds the token bucket of each rate limit policy applied to public endpoints
+	// (RATE_LIMITS="create-lead=20:10" for 20 requests a minute, bursts of 10), per client IP.
+	// RateLimitBackend "redis" shares the buckets between instances through RateLimitRedisAddr;
+	// the default "memory" keeps them per instance. With RateLimitProxyHops > 0 the client IP
+	// is the X-Forwarded-For entry that many hops from the right (added by trusted proxies).
+	RateLimits             map[string]RateLimit
+	RateLimitBackend       string
+	RateLimitRedisAddr     string
+	RateLimitRedisPassword string
+	RateLimitRedisDB       int
+	RateLimitProxyHops     int
+	// CORS policies for browser clients: PublicCORS (CORS_*) covers the lead, course and
+	// payment APIs, AdminCORS (ADMIN_CORS_*, origins defaulting to CORS_ALLOWED_ORIGINS)
+	// the /admin and DLQ APIs
+	PublicCORS CORSPolicy
+	AdminCORS  CORSPolicy
+	// APIV1Sunset is the date (YYYY-MM-DD) after which the deprecated v1 API (and the
+	// unversioned routes) may be removed, announced in the Sunset header
+	APIV1Sunset string
+}
+
+// RateLimit is a token bucket: Burst requests at once, refilled at PerMinute a minute
+type RateLimit struct {
+	PerMinute int
+	Burst     int
 }
 
+// CORSPolicy is the cross-origin access granted to browsers. AllowedOrigins holds exact
+// origins ("https://crm.example.com"), subdomain patterns ("https://*.example.com") or
+// "*" for any; no origins means no cross-origin access. MaxAgeSeconds is how long a
+// browser may cache a preflight answer.
+type CORSPolicy struct {
+	AllowedOrigins []string
+	AllowedMethods string
+	AllowedHeaders string
+	MaxAgeSeconds  int
+}
+
+// Startup policies, see Config.StartupPolicy
+const (
+	StartupPolicyDegrade  = "degrade"
+	StartupPolicyFailFast = "fail-fast"
+)
+
 var AppConfig Config
 
 func LoadConfig() {
-	// Try loading .env from different locations
-	envLocations := []string{
-		".env",              // project root
-		"config/.env",       // config subdirectory
-		"../config/.env",    // one level up
-		"../../config/.env", // two levels up
+	ReloadEnv()
+
+	AppConfig = load()
+	applyLogFile(AppConfig)
+	applyRuntimeSettings(AppConfig)
+}
+
+// applyLogFile sends the logger and the standard log package to the configured log file,
+// alongside stdout unless LOG_FILE_ONLY is set. Changes need a restart.
+func applyLogFile(cfg Config) {
+	if cfg.LogFile == "" {
+		return
 	}
 
-	for _, location := range envLocations {
-		if err := godotenv.Load(location); err == nil {
-			break
-		}
+	file, err := logger.NewRotatingFile(logger.RotateConfig{
+		Filename:    cfg.LogFile,
+		MaxSizeMB:   cfg.LogFileMaxSizeMB,
+		RotateEvery: time.Duration(cfg.LogFileRotateHours) * time.Hour,
+		MaxBackups:  cfg.LogFileMaxBackups,
+		MaxAge:      time.Duration(cfg.LogFileMaxAgeDays) * 24 * time.Hour,
+	})
+	if err != nil {
+		logger.Error("Error opening LOG_FILE %s, logging to stdout only: %v", cfg.LogFile, err)
+		return
 	}
 
-	AppConfig = Config{
+	// The standard log package keeps writing to stderr, as before
+	var out, stdOut io.Writer = file, file
+	if !cfg.LogFileOnly {
+		out = io.MultiWriter(os.Stdout, file)
+		stdOut = io.MultiWriter(os.Stderr, file)
+	}
+	logger.SetOutput(out)
+	log.SetOutput(stdOut)
+}
+
+// load builds a Config from the current process environment
+func load() Config {
+	cfg := Config{
 		DBHost:     getEnvWithDefault("DB_HOST", "localhost"),
 		DBPort:     getEnvWithDefault("DB_PORT", "5432"),
 		DBUser:     getEnvWithDefault("DB_USER", "postgres"),
 		DBPassword: getEnvWithDefault("DB_PASSWORD", "Sai@6303179072$"),
 		DBName:     getEnvWithDefault("DB_NAME", "postgres"),
 
+		DBMaxOpenConns:           getEnvIntWithDefault("DB_MAX_OPEN_CONNS", 25),
+		DBMaxIdleConns:           getEnvIntWithDefault("DB_MAX_IDLE_CONNS", 10),
+		DBConnMaxLifetimeMinutes: getEnvIntWithDefault("DB_CONN_MAX_LIFETIME_MINUTES", 30),
+		DBConnMaxIdleTimeMinutes: getEnvIntWithDefault("DB_CONN_MAX_IDLE_TIME_MINUTES", 5),
+		DBQueryTimeoutSeconds:    getEnvIntWithDefault("DB_QUERY_TIMEOUT_SECONDS", 10),
+		DBSlowQueryThresholdMs:   getEnvIntWithDefault("DB_SLOW_QUERY_THRESHOLD_MS", 500),
+		DBReplicaDSN:             os.Getenv("DB_REPLICA_DSN"),
+
 		RazorpayKeyID:         os.Getenv("RazorpayKeyID"),
 		RazorpayKeySecret:     os.Getenv("RazorpayKeySecret"),
 		RazorpayWebhookSecret: os.Getenv("RAZORPAY_WEBHOOK_SECRET"),
@@ -62,11 +365,185 @@ func LoadConfig() {
 		SMTPPass:  os.Getenv("SMTP_PASS"),
 		EmailFrom: os.Getenv("EMAIL_FROM"),
 
+		EmailDailyRecipientCap:     getEnvIntWithDefault("EMAIL_DAILY_RECIPIENT_CAP", 10),
+		EmailQuotaExemptCategories: parseFeatureFlags(getEnvWithDefault("EMAIL_QUOTA_EXEMPT_CATEGORIES", "transactional,staff")),
+
 		// Kafka settings (comma-separated brokers)
 		KafkaBrokers:  getEnvWithDefault("KAFKA_BROKERS", "127.0.0.1:9092"),
 		KafkaTopic:    getEnvWithDefault("KAFKA_TOPIC", "admissions.payments"),
 		KafkaDLQTopic: getEnvWithDefault("KAFKA_DLQ_TOPIC", "admissions.payments.dlq"),
+		DLQAlertEmail: os.Getenv("DLQ_ALERT_EMAIL"),
+
+		DLQAlertThreshold:       getEnvIntWithDefault("DLQ_ALERT_THRESHOLD", 100),
+		DLQAlertCooldownMinutes: getEnvIntWithDefault("DLQ_ALERT_COOLDOWN_MINUTES", 60),
+		DLQAlertSlackWebhookURL: os.Getenv("DLQ_ALERT_SLACK_WEBHOOK_URL"),
+
+		DLQMaxFieldChars:    getEnvIntWithDefault("DLQ_MAX_FIELD_CHARS", 256),
+		DLQMaxPayloadBytes:  getEnvIntWithDefault("DLQ_MAX_PAYLOAD_BYTES", 64*1024),
+		DLQArchiveAfterDays: getEnvIntWithDefault("DLQ_ARCHIVE_AFTER_DAYS", 30),
+		AdminAPIToken:       os.Getenv("ADMIN_API_TOKEN"),
+		PIIEncryptionKey:    os.Getenv("PII_ENCRYPTION_KEY"),
+
+		DLQRetryMaxRetries:        getEnvIntWithDefault("DLQ_RETRY_MAX_RETRIES", 3),
+		DLQRetryBackoffSeconds:    getEnvIntWithDefault("DLQ_RETRY_BACKOFF_SECONDS", 10),
+		DLQRetryBackoffMultiplier: getEnvMultiplierWithDefault("DLQ_RETRY_BACKOFF_MULTIPLIER", 2),
+		DLQRetryMaxBackoffSeconds: getEnvIntWithDefault("DLQ_RETRY_MAX_BACKOFF_SECONDS", 3600),
+
+		KafkaSASLMechanism: os.Getenv("KAFKA_SASL_MECHANISM"),
+		KafkaSASLUsername:  os.Getenv("KAFKA_SASL_USERNAME"),
+		KafkaSASLPassword:  os.Getenv("KAFKA_SASL_PASSWORD"),
+		KafkaTLSEnabled:    getEnvBool("KAFKA_TLS"),
+		KafkaTLSCAFile:     os.Getenv("KAFKA_TLS_CA_FILE"),
+
+		KafkaAsyncPublish:          getEnvBool("KAFKA_ASYNC_PUBLISH"),
+		KafkaPublishTimeoutSeconds: getEnvIntWithDefault("KAFKA_PUBLISH_TIMEOUT_SECONDS", 5),
+		KafkaConsumerGroup:         getEnvWithDefault("KAFKA_CONSUMER_GROUP", "admission-module-consumer-group"),
+		KafkaStartOffset:           strings.ToLower(getEnvWithDefault("KAFKA_START_OFFSET", "latest")),
+
+		KafkaConsumerConcurrency: getEnvIntWithDefault("KAFKA_CONSUMER_CONCURRENCY", 4),
+		KafkaTopicConcurrency:    parseTopicConcurrency(os.Getenv("KAFKA_TOPIC_CONCURRENCY")),
+
+		FollowUpStaleDays:  getEnvIntWithDefault("FOLLOW_UP_STALE_DAYS", 3),
+		LeadEscalationDays: getEnvIntWithDefault("LEAD_ESCALATION_DAYS", 7),
+
+		CounselorMorningShift: getEnvWithDefault("COUNSELOR_MORNING_SHIFT", "09:00-14:00"),
+		CounselorEveningShift: getEnvWithDefault("COUNSELOR_EVENING_SHIFT", "14:00-21:00"),
+
+		CourseFeeDeadlineDays:    getEnvIntWithDefault("COURSE_FEE_DEADLINE_DAYS", 14),
+		DualApprovalMinCourseFee: getEnvAmountWithDefault("DUAL_APPROVAL_MIN_COURSE_FEE", 0),
+		AcceptanceApproverEmails: os.Getenv("ACCEPTANCE_APPROVER_EMAILS"),
+
+		RejectedLeadRetentionDays: getEnvIntWithDefault("REJECTED_LEAD_RETENTION_DAYS", 90),
+		RetentionPolicies:         parseRetentionPolicies(os.Getenv("RETENTION_POLICIES")),
+		RetentionDryRun:           getEnvBool("RETENTION_DRY_RUN"),
+
+		WebhookPartitionPremakeMonths: getEnvIntWithDefault("WEBHOOK_PARTITION_PREMAKE_MONTHS", 3),
+		WebhookPartitionArchiveMonths: getEnvIntWithDefault("WEBHOOK_PARTITION_ARCHIVE_MONTHS", 12),
+		WebhookPartitionDropMonths:    getEnvIntWithDefault("WEBHOOK_PARTITION_DROP_MONTHS", 0),
+
+		ManagerReportEmails: os.Getenv("MANAGER_REPORT_EMAILS"),
+		AdminSummaryEnabled: getEnvBool("ADMIN_SUMMARY_ENABLED"),
+
+		PaymentLinkResendsPerDay: getEnvIntWithDefault("PAYMENT_LINK_RESENDS_PER_DAY", 3),
+		PaymentLinkExpiryDays:    getEnvIntWithDefault("PAYMENT_LINK_EXPIRY_DAYS", 7),
+
+		InstitutionName:      getEnvWithDefault("INSTITUTION_NAME", "Sai University"),
+		InstitutionAddress:   os.Getenv("INSTITUTION_ADDRESS"),
+		InstitutionGSTIN:     os.Getenv("INSTITUTION_GSTIN"),
+		InstitutionStateCode: getEnvWithDefault("INSTITUTION_STATE_CODE", gstinStateCode(os.Getenv("INSTITUTION_GSTIN"))),
+		InstitutionEmail:     getEnvWithDefault("INSTITUTION_EMAIL", os.Getenv("EMAIL_FROM")),
+		InvoicePrefix:        getEnvWithDefault("INVOICE_PREFIX", "INV"),
+		InvoiceTaxRate:       getEnvAmountWithDefault("INVOICE_TAX_RATE", 18),
+		ReceiptPrefix:        getEnvWithDefault("RECEIPT_PREFIX", "RCPT"),
+
+		HouseAccountName:  getEnvWithDefault("HOUSE_ACCOUNT_NAME", "Admissions Team"),
+		HouseAccountEmail: getEnvWithDefault("HOUSE_ACCOUNT_EMAIL", os.Getenv("EMAIL_FROM")),
+		HouseAccountPhone: os.Getenv("HOUSE_ACCOUNT_PHONE"),
+
+		InboundMailHost:           os.Getenv("INBOUND_MAIL_HOST"),
+		InboundMailPort:           getEnvIntWithDefault("INBOUND_MAIL_PORT", 995),
+		InboundMailUser:           os.Getenv("INBOUND_MAIL_USER"),
+		InboundMailPassword:       os.Getenv("INBOUND_MAIL_PASSWORD"),
+		InboundMailAllowedSenders: os.Getenv("INBOUND_MAIL_ALLOWED_SENDERS"),
+
+		EnrollmentSyncMode:        strings.ToLower(os.Getenv("ENROLLMENT_SYNC_MODE")),
+		EnrollmentSyncURL:         os.Getenv("ENROLLMENT_SYNC_URL"),
+		EnrollmentSyncToken:       os.Getenv("ENROLLMENT_SYNC_TOKEN"),
+		EnrollmentSyncMaxAttempts: getEnvIntWithDefault("ENROLLMENT_SYNC_MAX_ATTEMPTS", 8),
+
+		DocumentStorageDir: getEnvWithDefault("DOCUMENT_STORAGE_DIR", "generated"),
+		AppBaseURL:         getEnvWithDefault("APP_BASE_URL", "http://localhost:8080"),
+
+		DLQRetrySchedule:         getEnvWithDefault("DLQ_RETRY_SCHEDULE", "@every 10s"),
+		FollowUpReminderSchedule: getEnvWithDefault("FOLLOW_UP_REMINDER_SCHEDULE", "@hourly"),
+		LeadEscalationSchedule:   getEnvWithDefault("LEAD_ESCALATION_SCHEDULE", "30 * * * *"),
+		OfferExpirySchedule:      getEnvWithDefault("OFFER_EXPIRY_SCHEDULE", "15 * * * *"),
+		InboundMailSchedule:      getEnvWithDefault("INBOUND_MAIL_SCHEDULE", "@every 5m"),
+		RetentionSchedule:        getEnvWithDefault("RETENTION_SCHEDULE", "0 2 * * *"),
+		DailyReportSchedule:      getEnvWithDefault("DAILY_REPORT_SCHEDULE", "0 7 * * *"),
+		AdminSummarySchedule:     getEnvWithDefault("ADMIN_SUMMARY_SCHEDULE", "55 23 * * *"),
+		WeeklyReportSchedule:     getEnvWithDefault("WEEKLY_REPORT_SCHEDULE", "0 7 * * 1"),
+		MetricsSnapshotSchedule:  getEnvWithDefault("METRICS_SNAPSHOT_SCHEDULE", "5 0 * * *"),
+
+		WebhookSLOTarget:    getEnvFloatWithDefault("WEBHOOK_SLO_TARGET", 0.99),
+		WebhookSLOLatencyMs: getEnvIntWithDefault("WEBHOOK_SLO_LATENCY_MS", 2000),
+		SLOAlertEmail:       os.Getenv("SLO_ALERT_EMAIL"),
+		WebhookSLOSchedule:  getEnvWithDefault("WEBHOOK_SLO_SCHEDULE", "*/5 * * * *"),
+
+		IntegrationAlertEmail:           os.Getenv("INTEGRATION_ALERT_EMAIL"),
+		IntegrationAlertSlackWebhookURL: os.Getenv("INTEGRATION_ALERT_SLACK_WEBHOOK_URL"),
+		IntegrationAlertRepeatHours:     getEnvIntWithDefault("INTEGRATION_ALERT_REPEAT_HOURS", 24),
+		IntegrationHealthSchedule:       getEnvWithDefault("INTEGRATION_HEALTH_SCHEDULE", "*/15 * * * *"),
+
+		PaymentReconciliationSchedule:    getEnvWithDefault("PAYMENT_RECONCILIATION_SCHEDULE", "40 * * * *"),
+		PaymentReconciliationWindowHours: getEnvIntWithDefault("PAYMENT_RECONCILIATION_WINDOW_HOURS", 48),
+
+		LogLevel:     getEnvWithDefault("LOG_LEVEL", "INFO"),
+		LogFormat:    getEnvWithDefault("LOG_FORMAT", "text"),
+		FeatureFlags: parseFeatureFlags(os.Getenv("FEATURE_FLAGS")),
+
+		ServerPort:             getEnvWithDefault("SERVER_PORT", "8080"),
+		ShutdownTimeoutSeconds: getEnvIntWithDefault("SHUTDOWN_TIMEOUT_SECONDS", 30),
+
+		StartupPolicy:                 strings.ToLower(getEnvWithDefault("STARTUP_POLICY", StartupPolicyDegrade)),
+		StartupRetryAttempts:          getEnvIntWithDefault("STARTUP_RETRY_ATTEMPTS", 5),
+		StartupRetryBackoffSeconds:    getEnvIntWithDefault("STARTUP_RETRY_BACKOFF_SECONDS", 1),
+		StartupRetryMaxBackoffSeconds: getEnvIntWithDefault("STARTUP_RETRY_MAX_BACKOFF_SECONDS", 30),
+		MigrateOnStartup:              getEnvBoolWithDefault("MIGRATE_ON_STARTUP", true),
+
+		TLSCertFile:         os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:          os.Getenv("TLS_KEY_FILE"),
+		TLSAutocertDomains:  os.Getenv("TLS_AUTOCERT_DOMAINS"),
+		TLSAutocertEmail:    os.Getenv("TLS_AUTOCERT_EMAIL"),
+		TLSAutocertCacheDir: getEnvWithDefault("TLS_AUTOCERT_CACHE_DIR", "certs"),
+		TLSHTTPPort:         os.Getenv("TLS_HTTP_PORT"),
+
+		MaxRequestBodyKB:        getEnvIntWithDefault("MAX_REQUEST_BODY_KB", 1024),
+		MaxUploadBodyMB:         getEnvIntWithDefault("MAX_UPLOAD_BODY_MB", 20),
+		UploadTimeoutSeconds:    getEnvIntWithDefault("UPLOAD_TIMEOUT_SECONDS", 300),
+		HTTPReadTimeoutSeconds:  getEnvIntWithDefault("HTTP_READ_TIMEOUT_SECONDS", 30),
+		HTTPWriteTimeoutSeconds: getEnvIntWithDefault("HTTP_WRITE_TIMEOUT_SECONDS", 60),
+		HTTPIdleTimeoutSeconds:  getEnvIntWithDefault("HTTP_IDLE_TIMEOUT_SECONDS", 120),
+
+		RateLimits:             parseRateLimits(getEnvWithDefault("RATE_LIMITS", "create-lead=20:10,initiate-payment=10:5")),
+		RateLimitBackend:       strings.ToLower(getEnvWithDefault("RATE_LIMIT_BACKEND", "memory")),
+		RateLimitRedisAddr:     getEnvWithDefault("RATE_LIMIT_REDIS_ADDR", "localhost:6379"),
+		RateLimitRedisPassword: os.Getenv("RATE_LIMIT_REDIS_PASSWORD"),
+		RateLimitRedisDB:       getEnvIntWithDefault("RATE_LIMIT_REDIS_DB", 0),
+		RateLimitProxyHops:     getEnvIntWithDefault("RATE_LIMIT_PROXY_HOPS", 0),
+
+		PublicCORS: CORSPolicy{
+			AllowedOrigins: splitList(getEnvWithDefault("CORS_ALLOWED_ORIGINS", "*")),
+			AllowedMethods: getEnvWithDefault("CORS_ALLOWED_METHODS", "GET, POST, PUT, DELETE, OPTIONS"),
+			AllowedHeaders: getEnvWithDefault("CORS_ALLOWED_HEADERS", "Content-Type, X-Actor"),
+			MaxAgeSeconds:  getEnvIntWithDefault("CORS_MAX_AGE_SECONDS", 600),
+		},
+		AdminCORS: CORSPolicy{
+			AllowedOrigins: splitList(getEnvWithDefault("ADMIN_CORS_ALLOWED_ORIGINS", getEnvWithDefault("CORS_ALLOWED_ORIGINS", "*"))),
+			AllowedMethods: getEnvWithDefault("ADMIN_CORS_ALLOWED_METHODS", "GET, POST, PUT, DELETE, OPTIONS"),
+			AllowedHeaders: getEnvWithDefault("ADMIN_CORS_ALLOWED_HEADERS", "Content-Type, X-Admin-Token, X-Actor"),
+			MaxAgeSeconds:  getEnvIntWithDefault("CORS_MAX_AGE_SECONDS", 600),
+		},
+		APIV1Sunset: os.Getenv("API_V1_SUNSET"),
+
+		LogFile:            os.Getenv("LOG_FILE"),
+		LogFileOnly:        getEnvBool("LOG_FILE_ONLY"),
+		LogFileMaxSizeMB:   getEnvIntWithDefault("LOG_FILE_MAX_SIZE_MB", 100),
+		LogFileRotateHours: getEnvIntWithDefault("LOG_FILE_ROTATE_HOURS", 24),
+		LogFileMaxBackups:  getEnvIntWithDefault("LOG_FILE_MAX_BACKUPS", 14),
+		LogFileMaxAgeDays:  getEnvIntWithDefault("LOG_FILE_MAX_AGE_DAYS", 30),
 	}
+
+	cfg.PaymentPageURL = getEnvWithDefault("PAYMENT_PAGE_URL", cfg.AppBaseURL+"/static/test-payment.html")
+	return cfg
+}
+
+// gstinStateCode returns the state code a GSTIN starts with, "" for an empty GSTIN
+func gstinStateCode(gstin string) string {
+	if len(gstin) < 2 {
+		return ""
+	}
+	return gstin[:2]
 }
 
 func getEnvWithDefault(key, defaultValue string) string {
@@ -76,6 +553,156 @@ func getEnvWithDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvIntWithDefault(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvFloatWithDefault reads a ratio in (0, 1], e.g. an SLO target
+func getEnvFloatWithDefault(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil && value > 0 && value <= 1 {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvMultiplierWithDefault reads a growth factor of at least 1, e.g. a backoff multiplier
+func getEnvMultiplierWithDefault(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil && value >= 1 {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvAmountWithDefault reads a non-negative amount of money, e.g. a fee threshold
+func getEnvAmountWithDefault(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil && value >= 0 {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvBool reports whether key is set to a true value (1, true, yes, on)
+func getEnvBool(key string) bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(key))) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// getEnvBoolWithDefault reads a true (1, true, yes, on) or false (0, false, no, off) value,
+// falling back to defaultValue when key is unset or not a boolean
+func getEnvBoolWithDefault(key string, defaultValue bool) bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(key))) {
+	case "1", "true", "yes", "on":
+		return true
+	case "0", "false", "no", "off":
+		return false
+	}
+	return defaultValue
+}
+
+// parseFeatureFlags turns "new_dashboard, bulk_sms" into a set of enabled flags
+func parseFeatureFlags(value string) map[string]bool {
+	flags := map[string]bool{}
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			flags[strings.ToLower(name)] = true
+		}
+	}
+	return flags
+}
+
+// splitList turns "a, b,,c" into its non-empty, trimmed entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseTopicConcurrency turns "emails=8, payments=1" into per-topic worker counts,
+// ignoring entries without a positive count
+func parseTopicConcurrency(value string) map[string]int {
+	counts := map[string]int{}
+	for _, entry := range strings.Split(value, ",") {
+		topic, count, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(count)); err == nil && n > 0 {
+			counts[strings.TrimSpace(topic)] = n
+		}
+	}
+	return counts
+}
+
+// parseRetentionPolicies turns "purge_sent_events=14, purge_api_usage=0" into retention
+// windows in days per policy, ignoring entries without a non-negative number of days
+func parseRetentionPolicies(value string) map[string]int {
+	windows := map[string]int{}
+	for _, entry := range strings.Split(value, ",") {
+		policy, days, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(days)); err == nil && n >= 0 {
+			windows[strings.TrimSpace(policy)] = n
+		}
+	}
+	return windows
+}
+
+// parseRateLimits turns "create-lead=20:10, initiate-payment=10" into token buckets per
+// policy (requests per minute, then the burst, which defaults to the per-minute rate),
+// ignoring entries without a positive rate
+func parseRateLimits(value string) map[string]RateLimit {
+	limits := map[string]RateLimit{}
+	for _, entry := range strings.Split(value, ",") {
+		policy, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		perMinute, burst, hasBurst := strings.Cut(strings.TrimSpace(spec), ":")
+		limit := RateLimit{}
+		if n, err := strconv.Atoi(strings.TrimSpace(perMinute)); err == nil && n > 0 {
+			limit.PerMinute, limit.Burst = n, n
+		} else {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(burst)); hasBurst && err == nil && n > 0 {
+			limit.Burst = n
+		}
+		limits[strings.TrimSpace(policy)] = limit
+	}
+	return limits
+}
+
+// ConsumerConcurrency returns the number of workers processing messages of topic
+func (c Config) ConsumerConcurrency(topic string) int {
+	if n, ok := c.KafkaTopicConcurrency[topic]; ok {
+		return n
+	}
+	return c.KafkaConsumerConcurrency
+}
+
+// AutocertDomains returns the domains in TLS_AUTOCERT_DOMAINS
+func (c Config) AutocertDomains() []string {
+	var domains []string
+	for _, domain := range strings.Split(c.TLSAutocertDomains, ",") {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
 func GetDBConnString() string {
 	return "host=" + AppConfig.DBHost +
 		" port=" + AppConfig.DBPort +
@@ -0,0 +1,275 @@
+package config
+
+import (
+	"admission-module/logger"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/joho/godotenv"
+)
+
+// Configuration is layered, later layers overriding earlier ones:
+//
+//  1. base:    the first .env file found (see envLocations)
+//  2. overlay: .env.<APP_ENV> next to the base file, e.g. .env.production
+//  3. secrets: one file per variable in SECRETS_DIR (file name = key, content = value),
+//     as mounted by Docker or Kubernetes secrets
+//
+// Variables set in the real process environment always win over every layer.
+var envLocations = []string{
+	".env",              // project root
+	"config/.env",       // config subdirectory
+	"../config/.env",    // one level up
+	"../../config/.env", // two levels up
+}
+
+// reloadableSettings are the Config fields Reload applies to a running server.
+// Everything else (database, Kafka, payment keys, schedules) needs a restart.
+var reloadableSettings = map[string]bool{
+	"EmailDailyRecipientCap":          true,
+	"EmailQuotaExemptCategories":      true,
+	"DLQAlertEmail":                   true,
+	"DLQAlertThreshold":               true,
+	"DLQAlertCooldownMinutes":         true,
+	"DLQAlertSlackWebhookURL":         true,
+	"DLQArchiveAfterDays":             true,
+	"DLQRetryMaxRetries":              true,
+	"DLQRetryBackoffSeconds":          true,
+	"DLQRetryBackoffMultiplier":       true,
+	"DLQRetryMaxBackoffSeconds":       true,
+	"SLOAlertEmail":                   true,
+	"WebhookSLOTarget":                true,
+	"WebhookSLOLatencyMs":             true,
+	"IntegrationAlertEmail":           true,
+	"IntegrationAlertSlackWebhookURL": true,
+	"IntegrationAlertRepeatHours":     true,
+	"FollowUpStaleDays":               true,
+	"LeadEscalationDays":              true,
+	"CounselorMorningShift":           true,
+	"CounselorEveningShift":           true,
+	"CourseFeeDeadlineDays":           true,
+	"DualApprovalMinCourseFee":        true,
+	"AcceptanceApproverEmails":        true,
+	"InboundMailAllowedSenders":       true,
+	"EnrollmentSyncMaxAttempts":       true,
+	"RejectedLeadRetentionDays":       true,
+	"RetentionPolicies":               true,
+	"RetentionDryRun":                 true,
+	"PaymentLinkResendsPerDay":        true,
+	"InstitutionName":                 true,
+	"InstitutionAddress":              true,
+	"InstitutionGSTIN":                true,
+	"InstitutionStateCode":            true,
+	"InstitutionEmail":                true,
+	"InvoicePrefix":                   true,
+	"InvoiceTaxRate":                  true,
+	"ReceiptPrefix":                   true,
+	"LogLevel":                        true,
+	"LogFormat":                       true,
+	"FeatureFlags":                    true,
+	"RateLimits":                      true,
+	"RateLimitProxyHops":              true,
+	"PublicCORS":                      true,
+	"AdminCORS":                       true,
+	"APIV1Sunset":                     true,
+}
+
+var (
+	envMutex sync.Mutex
+	// processEnv holds the variables set before any layer was loaded
+	processEnv map[string]bool
+	// layeredEnv holds the variables currently provided by the layers
+	layeredEnv = map[string]string{}
+
+	reloadMutex sync.Mutex
+	flagsMutex  sync.RWMutex
+)
+
+// ReloadResult reports which settings a reload applied and which changed but need a restart
+type ReloadResult struct {
+	Applied         []string `json:"applied"`
+	RestartRequired []string `json:"restart_required"`
+}
+
+// ReloadEnv reads the config layers into the process environment. Calling it
+// again picks up settings added, changed or removed in the files since the last
+// call; variables from the real process environment are never touched.
+func ReloadEnv() {
+	envMutex.Lock()
+	defer envMutex.Unlock()
+
+	if processEnv == nil {
+		processEnv = map[string]bool{}
+		for _, kv := range os.Environ() {
+			processEnv[strings.SplitN(kv, "=", 2)[0]] = true
+		}
+	}
+
+	merged := readEnvLayers()
+
+	for key := range layeredEnv {
+		if _, ok := merged[key]; !ok {
+			os.Unsetenv(key)
+		}
+	}
+	for key, value := range merged {
+		if !processEnv[key] {
+			os.Setenv(key, value)
+		}
+	}
+	layeredEnv = merged
+}
+
+// readEnvLayers merges base, overlay and secrets into one map
+func readEnvLayers() map[string]string {
+	merged := map[string]string{}
+
+	var baseDir string
+	for _, location := range envLocations {
+		values, err := godotenv.Read(location)
+		if err != nil {
+			continue
+		}
+		for k, v := range values {
+			merged[k] = v
+		}
+		baseDir = filepath.Dir(location)
+		break
+	}
+
+	appEnv := merged["APP_ENV"]
+	if processEnv["APP_ENV"] {
+		appEnv = os.Getenv("APP_ENV")
+	}
+	if appEnv != "" && baseDir != "" {
+		if values, err := godotenv.Read(filepath.Join(baseDir, ".env."+appEnv)); err == nil {
+			for k, v := range values {
+				merged[k] = v
+			}
+		}
+	}
+
+	secretsDir := merged["SECRETS_DIR"]
+	if processEnv["SECRETS_DIR"] {
+		secretsDir = os.Getenv("SECRETS_DIR")
+	}
+	if secretsDir != "" {
+		entries, err := os.ReadDir(secretsDir)
+		if err != nil {
+			logger.Warn("Could not read secrets directory %s: %v", secretsDir, err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(secretsDir, entry.Name()))
+			if err != nil {
+				logger.Warn("Could not read secret %s: %v", entry.Name(), err)
+				continue
+			}
+			merged[entry.Name()] = strings.TrimRight(string(data), "\r\n")
+		}
+	}
+
+	return merged
+}
+
+// Reload re-reads the config layers and applies the non-critical settings (rate
+// limits, reminder/escalation/retention thresholds, log level, feature flags) to the running
+// server. Changes to other settings are reported as requiring a restart.
+func Reload() ReloadResult {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+
+	ReloadEnv()
+	fresh := load()
+
+	result := ReloadResult{Applied: []string{}, RestartRequired: []string{}}
+	current := reflect.ValueOf(&AppConfig).Elem()
+	next := reflect.ValueOf(fresh)
+	for i := 0; i < current.NumField(); i++ {
+		name := current.Type().Field(i).Name
+		if reflect.DeepEqual(current.Field(i).Interface(), next.Field(i).Interface()) {
+			continue
+		}
+		if !reloadableSettings[name] {
+			result.RestartRequired = append(result.RestartRequired, name)
+			continue
+		}
+		if name == "FeatureFlags" || name == "RateLimits" || name == "PublicCORS" || name == "AdminCORS" || name == "EmailQuotaExemptCategories" || name == "RetentionPolicies" {
+			flagsMutex.Lock()
+			current.Field(i).Set(next.Field(i))
+			flagsMutex.Unlock()
+		} else {
+			current.Field(i).Set(next.Field(i))
+		}
+		result.Applied = append(result.Applied, name)
+	}
+
+	applyRuntimeSettings(AppConfig)
+	return result
+}
+
+// FeatureEnabled reports whether a feature flag is switched on in FEATURE_FLAGS
+func FeatureEnabled(name string) bool {
+	flagsMutex.RLock()
+	defer flagsMutex.RUnlock()
+	return AppConfig.FeatureFlags[strings.ToLower(name)]
+}
+
+// RateLimitFor returns the token bucket of a rate limit policy from RATE_LIMITS; ok is
+// false when the policy is not limited
+func RateLimitFor(policy string) (limit RateLimit, ok bool) {
+	flagsMutex.RLock()
+	defer flagsMutex.RUnlock()
+	limit, ok = AppConfig.RateLimits[policy]
+	return limit, ok
+}
+
+// EmailQuotaExempt reports whether emails of category bypass the daily recipient cap
+// (EMAIL_QUOTA_EXEMPT_CATEGORIES)
+func EmailQuotaExempt(category string) bool {
+	flagsMutex.RLock()
+	defer flagsMutex.RUnlock()
+	return AppConfig.EmailQuotaExemptCategories[strings.ToLower(category)]
+}
+
+// RetentionDays returns the window of a retention policy from RETENTION_POLICIES, or
+// defaultDays when it is not listed there; 0 means the policy is switched off
+func RetentionDays(policy string, defaultDays int) int {
+	flagsMutex.RLock()
+	defer flagsMutex.RUnlock()
+	if days, ok := AppConfig.RetentionPolicies[policy]; ok {
+		return days
+	}
+	return defaultDays
+}
+
+// CORSPolicyFor returns the admin CORS policy (ADMIN_CORS_*) or the public one (CORS_*)
+func CORSPolicyFor(admin bool) CORSPolicy {
+	flagsMutex.RLock()
+	defer flagsMutex.RUnlock()
+	if admin {
+		return AppConfig.AdminCORS
+	}
+	return AppConfig.PublicCORS
+}
+
+// applyRuntimeSettings pushes settings owned by other packages (log level and format) to them
+func applyRuntimeSettings(cfg Config) {
+	if format, ok := logger.ParseFormat(cfg.LogFormat); ok {
+		logger.SetFormat(format)
+	} else {
+		logger.Warn("Unknown LOG_FORMAT %q, keeping current format", cfg.LogFormat)
+	}
+
+	level, ok := logger.ParseLevel(cfg.LogLevel)
+	if !ok {
+		logger.Warn("Unknown LOG_LEVEL %q, keeping current level", cfg.LogLevel)
+		return
+	}
+	logger.SetLevel(level)
+}
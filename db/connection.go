@@ -2,296 +2,88 @@ package db
 
 import (
 	"admission-module/config"
+	"context"
 	"database/sql"
 	"fmt"
-	"io/ioutil"
-	"log"
-	"os"
-	"path/filepath"
-	"strings"
+	"regexp"
 	"time"
-
-	_ "github.com/lib/pq"
 )
 
 var DB *sql.DB
 
 func InitDB() error {
+	if err := Connect(); err != nil {
+		return err
+	}
+	return Migrate()
+}
+
+// Connect opens and pings the database without creating tables or applying migrations
+func Connect() error {
 	var err error
 	connStr := config.GetDBConnString()
 
-	DB, err = sql.Open("postgres", connStr)
+	DB, err = openInstrumented(poolPrimary, connStr)
 	if err != nil {
 		return fmt.Errorf("error opening database: %w", err)
 	}
+	DB.SetMaxOpenConns(config.AppConfig.DBMaxOpenConns)
+	DB.SetMaxIdleConns(config.AppConfig.DBMaxIdleConns)
+	DB.SetConnMaxLifetime(time.Duration(config.AppConfig.DBConnMaxLifetimeMinutes) * time.Minute)
+	DB.SetConnMaxIdleTime(time.Duration(config.AppConfig.DBConnMaxIdleTimeMinutes) * time.Minute)
 
 	// Test the connection
 	err = DB.Ping()
 	if err != nil {
+		DB.Close()
 		return fmt.Errorf("error connecting to database: %w", err)
 	}
 
-	// Create tables
-	if err := createTables(); err != nil {
-		return fmt.Errorf("error creating tables: %w", err)
-	}
-
 	return nil
 }
 
-func createTables() error {
-	// Renamed: counsellors -> counselor
-	counselorTable := `
-	CREATE TABLE IF NOT EXISTS counselor (
-		id SERIAL PRIMARY KEY,
-		name VARCHAR(255) NOT NULL,
-		email VARCHAR(255) NOT NULL,
-		phone VARCHAR(20),
-		assigned_count INTEGER DEFAULT 0,
-		max_capacity INTEGER DEFAULT 10,
-		is_referral_enabled BOOLEAN DEFAULT false,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	// Renamed: courses -> course
-	courseTable := `
-	CREATE TABLE IF NOT EXISTS course (
-		id SERIAL PRIMARY KEY,
-		name VARCHAR(255) NOT NULL,
-		description TEXT,
-		fee NUMERIC(10, 2) NOT NULL,
-		duration VARCHAR(100),
-		is_active INTEGER DEFAULT 1,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	// Renamed: leads -> student_lead
-	leadTable := `
-	CREATE TABLE IF NOT EXISTS student_lead (
-		id SERIAL PRIMARY KEY,
-		name VARCHAR(255) NOT NULL,
-		email VARCHAR(255) NOT NULL,
-		phone VARCHAR(20) NOT NULL,
-		education VARCHAR(255),
-		lead_source VARCHAR(100),
-		counselor_id INTEGER,
-		registration_fee_status VARCHAR(50) DEFAULT 'PENDING',
-		course_fee_status VARCHAR(50) DEFAULT 'PENDING',
-		meet_link TEXT,
-		application_status VARCHAR(50) DEFAULT 'NEW',
-		registration_payment_id INTEGER,
-		selected_course_id INTEGER,
-		course_payment_id INTEGER,
-		interview_scheduled_at TIMESTAMP,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-
-		CONSTRAINT fk_counselor
-			FOREIGN KEY (counselor_id)
-			REFERENCES counselor(id)
-			ON DELETE SET NULL,
-		CONSTRAINT fk_selected_course
-			FOREIGN KEY (selected_course_id)
-			REFERENCES course(id)
-			ON DELETE SET NULL
-	);`
-
-	// Registration fee payment table
-	registrationPaymentTable := `
-	CREATE TABLE IF NOT EXISTS registration_payment (
-		id SERIAL PRIMARY KEY,
-		student_id INTEGER NOT NULL UNIQUE,
-		amount NUMERIC(10, 2) NOT NULL,
-		status VARCHAR(50) DEFAULT 'PENDING',
-		order_id VARCHAR(255) UNIQUE,
-		payment_id VARCHAR(255),
-		razorpay_sign TEXT,
-		error_message TEXT,
-		timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-
-		CONSTRAINT fk_student_reg_payment
-			FOREIGN KEY (student_id)
-			REFERENCES student_lead(id)
-			ON DELETE CASCADE
-	);`
-
-	// Course fee payment table
-	coursePaymentTable := `
-	CREATE TABLE IF NOT EXISTS course_payment (
-		id SERIAL PRIMARY KEY,
-		student_id INTEGER NOT NULL,
-		course_id INTEGER NOT NULL,
-		amount NUMERIC(10, 2) NOT NULL,
-		status VARCHAR(50) DEFAULT 'PENDING',
-		order_id VARCHAR(255) UNIQUE,
-		payment_id VARCHAR(255),
-		razorpay_sign TEXT,
-		error_message TEXT,
-		timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-
-		CONSTRAINT fk_student_course_payment
-			FOREIGN KEY (student_id)
-			REFERENCES student_lead(id)
-			ON DELETE CASCADE,
-		CONSTRAINT fk_course_payment_course
-			FOREIGN KEY (course_id)
-			REFERENCES course(id)
-			ON DELETE CASCADE,
-		CONSTRAINT unique_student_course
-			UNIQUE(student_id, course_id)
-	);`
-
-	// Razorpay webhook logs table
-	_ = `
-	CREATE TABLE IF NOT EXISTS razorpay_webhook_logs (
-		id BIGSERIAL PRIMARY KEY,
-		webhook_id VARCHAR(255) UNIQUE NOT NULL,
-		event_type VARCHAR(100) NOT NULL,
-		order_id VARCHAR(255),
-		payment_id VARCHAR(255),
-		student_id INTEGER,
-		amount_paise BIGINT,
-		currency VARCHAR(10),
-		status VARCHAR(50),
-		payload JSONB NOT NULL,
-		signature VARCHAR(255),
-		signature_valid BOOLEAN,
-		processing_status VARCHAR(50) DEFAULT 'PENDING',
-		processed_at TIMESTAMP,
-		error_message TEXT,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	// Razorpay webhooks table
-	_ = `
-	CREATE TABLE IF NOT EXISTS razorpay_webhooks (
-		id SERIAL PRIMARY KEY,
-		webhook_id VARCHAR(255) UNIQUE NOT NULL,
-		event_type VARCHAR(100) NOT NULL,
-		payload JSONB NOT NULL,
-		status VARCHAR(50) DEFAULT 'RECEIVED',
-		processed_at TIMESTAMP,
-		error_message TEXT,
-		retry_count INTEGER DEFAULT 0,
-		signature_valid BOOLEAN DEFAULT false,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	// Create counselor table first (referenced by student_lead)
-	if _, err := DB.Exec(counselorTable); err != nil {
-		return fmt.Errorf("error creating counselor table: %w", err)
-	}
-
-	// Create course table
-	if _, err := DB.Exec(courseTable); err != nil {
-		return fmt.Errorf("error creating course table: %w", err)
-	}
-	// Create student_lead table
-	if _, err := DB.Exec(leadTable); err != nil {
-		return fmt.Errorf("error creating student_lead table: %w", err)
-	}
-
-	// Create registration_payment table
-	if _, err := DB.Exec(registrationPaymentTable); err != nil {
-		return fmt.Errorf("error creating registration_payment table: %w", err)
-	}
-
-	// Create course_payment table
-	if _, err := DB.Exec(coursePaymentTable); err != nil {
-		return fmt.Errorf("error creating course_payment table: %w", err)
-	}
-
-	// DLQ messages table for Dead Letter Queue
-	dlqTable := `
-	CREATE TABLE IF NOT EXISTS dlq_messages (
-		id SERIAL PRIMARY KEY,
-		message_id UUID UNIQUE,
-		topic VARCHAR(255) NOT NULL,
-		key TEXT,
-		value JSONB NOT NULL,
-		error_message TEXT,
-		retry_count INT DEFAULT 0,
-		max_retries INT DEFAULT 3,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		last_retry_at TIMESTAMP,
-		resolved BOOLEAN DEFAULT FALSE,
-		resolved_at TIMESTAMP,
-		notes TEXT
-	);`
-
-	if _, err := DB.Exec(dlqTable); err != nil {
-		return fmt.Errorf("error creating dlq_messages table: %w", err)
-	}
-
-	// Apply schema migrations
-	if err := applyMigrations(); err != nil {
-		log.Printf("Warning: Error applying migrations: %v", err)
-	}
-
-	// Insert default dummy data if empty
-	if err := insertDefaultData(); err != nil {
-		log.Printf("Warning: Error inserting default data: %v", err)
-	}
-
-	return nil
+// WithTimeout bounds an operation on the database by DBQueryTimeoutSeconds. The returned
+// context is also cancelled with ctx, e.g. when the client of a request goes away.
+func WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(config.AppConfig.DBQueryTimeoutSeconds)*time.Second)
 }
 
-func applyMigrations() error {
-	// Find project root to resolve migration file path correctly
-	cwd, err := os.Getwd()
-	if err != nil {
-		log.Printf("Warning: Could not get working directory: %v", err)
-		return err
-	}
+var createTablePattern = regexp.MustCompile(`(?i)CREATE TABLE IF NOT EXISTS\s+(\w+)`)
 
-	projectRoot := findProjectRoot(cwd)
-	if projectRoot == "" {
-		log.Printf("Warning: Could not find project root (go.mod not found)")
-		return fmt.Errorf("project root not found")
+// CheckSchema compares the live database against the tables declared in the embedded
+// migrations. It returns the schema version and the declared tables that do not exist yet.
+func CheckSchema(ctx context.Context) (string, []string, error) {
+	if DB == nil {
+		return "", nil, fmt.Errorf("database is not initialized")
 	}
 
-	// Read and execute the single consolidated migration file
-	migrationFile := "001_complete_schema.sql"
-	migrationPath := filepath.Join(projectRoot, "db", "migrations", migrationFile)
-
-	// Read migration file
-	migrationSQL, err := ioutil.ReadFile(migrationPath)
+	version, latest, dirty, err := SchemaVersion()
 	if err != nil {
-		log.Printf("Warning: Could not read migration file at %s: %v", migrationPath, err)
-		return err
+		return "", nil, err
 	}
-
-	// Execute migration
-	if _, err := DB.Exec(string(migrationSQL)); err != nil {
-		log.Printf("Warning: Error executing migration %s: %v", migrationFile, err)
-		return err
+	state := fmt.Sprintf("schema version %d of %d", version, latest)
+	if dirty {
+		state += " (dirty)"
 	}
 
-	return nil
-}
-
-// findProjectRoot walks up from start and returns the first directory containing go.mod
-func findProjectRoot(start string) string {
-	dir := start
-	for {
-		// check for go.mod
-		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
-			return dir
-		}
-		// move up
-		parent := filepath.Dir(dir)
-		if parent == dir || strings.HasSuffix(dir, ":\\") || parent == "" {
-			break
+	migrations, err := readUpMigrations()
+	if err != nil {
+		return state, nil, err
+	}
+	missing := []string{}
+	for _, migrationSQL := range migrations {
+		for _, match := range createTablePattern.FindAllStringSubmatch(migrationSQL, -1) {
+			var exists bool
+			if err := DB.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", match[1]).Scan(&exists); err != nil {
+				return state, nil, fmt.Errorf("error checking table %s: %w", match[1], err)
+			}
+			if !exists {
+				missing = append(missing, match[1])
+			}
 		}
-		dir = parent
 	}
-	return ""
+
+	return state, missing, nil
 }
 
 func insertDefaultData() error {
@@ -0,0 +1,187 @@
+package db
+
+import (
+	"admission-module/config"
+	"admission-module/logger"
+	"admission-module/metrics"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Connection pool labels of the query metrics
+const (
+	poolPrimary = "primary"
+	poolReplica = "replica"
+)
+
+// maxQueryShapes bounds the distinct statements labelled in the latency histogram; any
+// statement seen after that is counted as "other"
+const maxQueryShapes = 200
+
+// maxQueryLabelLength truncates the statement kept as a label and in slow query logs
+const maxQueryLabelLength = 160
+
+// openInstrumented opens a lib/pq pool whose statements are timed into the
+// admission_db_query_duration_seconds histogram and logged when slower than
+// DB_SLOW_QUERY_THRESHOLD_MS
+func openInstrumented(pool, dsn string) (*sql.DB, error) {
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(&instrumentedConnector{connector: connector, pool: pool}), nil
+}
+
+// instrumentedConnector hands out lib/pq connections wrapped in instrumentedConn
+type instrumentedConnector struct {
+	connector driver.Connector
+	pool      string
+}
+
+func (c *instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedConn{Conn: conn, pool: c.pool}, nil
+}
+
+func (c *instrumentedConnector) Driver() driver.Driver {
+	return c.connector.Driver()
+}
+
+// instrumentedConn times the queries and statements run directly on a connection, which
+// is how database/sql runs every Query and Exec, in a transaction or not. The time is to
+// the first rows, not to the end of reading them.
+type instrumentedConn struct {
+	driver.Conn
+	pool string
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	observeQuery(ctx, c.pool, query, time.Since(start), err)
+	return rows, err
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	result, err := execer.ExecContext(ctx, query, args)
+	observeQuery(ctx, c.pool, query, time.Since(start), err)
+	return result, err
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// observeQuery records a finished statement and logs it when it was slow
+func observeQuery(ctx context.Context, pool, query string, elapsed time.Duration, err error) {
+	if err == driver.ErrSkip {
+		return
+	}
+	threshold := time.Duration(config.AppConfig.DBSlowQueryThresholdMs) * time.Millisecond
+	slow := threshold > 0 && elapsed >= threshold
+
+	metrics.ObserveDBQuery(pool, queryShapes.label(query), elapsed, slow)
+	if slow {
+		logger.FromContext(ctx).Warn("Slow query on %s took %v (threshold %v): %s",
+			pool, elapsed.Round(time.Millisecond), threshold, normalizeQuery(query))
+	}
+}
+
+var (
+	quotedLiteralPattern  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numericLiteralPattern = regexp.MustCompile(`(^|[^$\w])\d+(?:\.\d+)?`)
+	whitespacePattern     = regexp.MustCompile(`\s+`)
+)
+
+// normalizeQuery collapses whitespace and replaces literals with ?, so statements that
+// differ only in the values formatted into them share a label. Placeholders ($1) stay.
+func normalizeQuery(query string) string {
+	query = quotedLiteralPattern.ReplaceAllString(query, "?")
+	query = numericLiteralPattern.ReplaceAllString(query, "${1}?")
+	query = strings.TrimSpace(whitespacePattern.ReplaceAllString(query, " "))
+	if len(query) > maxQueryLabelLength {
+		query = strings.ToValidUTF8(query[:maxQueryLabelLength], "") + "..."
+	}
+	return query
+}
+
+// queryShapeSet remembers the labels given to statements so far
+type queryShapeSet struct {
+	mu     sync.Mutex
+	labels map[string]string // statement as sent -> normalized label
+	shapes map[string]bool
+}
+
+var queryShapes = &queryShapeSet{labels: map[string]string{}, shapes: map[string]bool{}}
+
+// label returns the histogram label of query: its normalized text, or "other" once
+// maxQueryShapes distinct statements have been labelled
+func (s *queryShapeSet) label(query string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if label, ok := s.labels[query]; ok {
+		return label
+	}
+	label := normalizeQuery(query)
+	if !s.shapes[label] {
+		if len(s.shapes) >= maxQueryShapes {
+			return "other"
+		}
+		s.shapes[label] = true
+	}
+	if len(s.labels) < maxQueryShapes*4 {
+		s.labels[query] = label
+	}
+	return label
+}
@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// migrationFiles holds the numbered schema migrations (NNN_name.up.sql / .down.sql),
+// compiled into the binary so no SQL files need to ship next to it
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// ErrSchemaOutdated is returned by CheckSchemaVersion when migrations are pending
+var ErrSchemaOutdated = errors.New("database schema is not up to date")
+
+// ErrSchemaDirty is returned when a migration failed part way and the schema_migrations
+// version is marked dirty. Fix the schema by hand, then run `go run ./cmd/migrate force <version>`.
+var ErrSchemaDirty = errors.New("database schema is dirty")
+
+// NewMigrator returns a migrator for the embedded migrations on a dedicated connection
+// from DB. Close it when done; that returns the connection and leaves DB open.
+func NewMigrator() (*migrate.Migrate, error) {
+	if DB == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+
+	source, err := iofs.New(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error reading embedded migrations: %w", err)
+	}
+
+	ctx := context.Background()
+	conn, err := DB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error acquiring migration connection: %w", err)
+	}
+	driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error preparing migration driver: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+	if err != nil {
+		driver.Close()
+		return nil, fmt.Errorf("error creating migrator: %w", err)
+	}
+	m.Log = migrateLogger{}
+	return m, nil
+}
+
+// Migrate applies the pending migrations on the connection opened by Connect, then seeds
+// the default counselors and courses into an empty database
+func Migrate() error {
+	m, err := NewMigrator()
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		var dirty migrate.ErrDirty
+		if errors.As(err, &dirty) {
+			return fmt.Errorf("%w at version %d", ErrSchemaDirty, dirty.Version)
+		}
+		return fmt.Errorf("error applying migrations: %w", err)
+	}
+
+	version, _, err := m.Version()
+	if err != nil {
+		return fmt.Errorf("error reading schema version: %w", err)
+	}
+	log.Printf("Database schema at version %d", version)
+
+	// Insert default dummy data if empty
+	if err := insertDefaultData(); err != nil {
+		log.Printf("Warning: Error inserting default data: %v", err)
+	}
+	return nil
+}
+
+// SchemaVersion returns the version the database is at and the latest embedded version.
+// version is 0 when no migration has run yet.
+func SchemaVersion() (version, latest uint, dirty bool, err error) {
+	latest, err = LatestMigrationVersion()
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	m, err := NewMigrator()
+	if err != nil {
+		return 0, latest, false, err
+	}
+	defer m.Close()
+
+	version, dirty, err = m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, latest, false, nil
+	}
+	if err != nil {
+		return 0, latest, false, fmt.Errorf("error reading schema version: %w", err)
+	}
+	return version, latest, dirty, nil
+}
+
+// CheckSchemaVersion returns an error unless the database is at the latest embedded
+// version, for servers started with MIGRATE_ON_STARTUP=false
+func CheckSchemaVersion() error {
+	version, latest, dirty, err := SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("%w at version %d", ErrSchemaDirty, version)
+	}
+	if version < latest {
+		return fmt.Errorf("%w: at version %d, latest is %d", ErrSchemaOutdated, version, latest)
+	}
+	return nil
+}
+
+// LatestMigrationVersion returns the highest version among the embedded migrations
+func LatestMigrationVersion() (uint, error) {
+	source, err := iofs.New(migrationFiles, "migrations")
+	if err != nil {
+		return 0, fmt.Errorf("error reading embedded migrations: %w", err)
+	}
+	defer source.Close()
+
+	version, err := source.First()
+	if err != nil {
+		return 0, fmt.Errorf("error reading embedded migrations: %w", err)
+	}
+	for {
+		next, err := source.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("error reading embedded migrations: %w", err)
+		}
+		version = next
+	}
+}
+
+// readUpMigrations returns the SQL of every embedded up migration, in version order
+func readUpMigrations() ([]string, error) {
+	names, err := fs.Glob(migrationFiles, "migrations/*.up.sql")
+	if err != nil {
+		return nil, err
+	}
+	var contents []string
+	for _, name := range names {
+		data, err := migrationFiles.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", name, err)
+		}
+		contents = append(contents, string(data))
+	}
+	return contents, nil
+}
+
+// migrateLogger prints migrate's progress through the standard logger
+type migrateLogger struct{}
+
+func (migrateLogger) Printf(format string, v ...interface{}) {
+	log.Printf("migrate: "+format, v...)
+}
+
+func (migrateLogger) Verbose() bool {
+	return false
+}
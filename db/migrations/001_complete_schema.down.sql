@@ -0,0 +1,53 @@
+-- ============================================
+-- Complete Schema Migration - rollback
+-- ============================================
+-- Drops everything 001_complete_schema.up.sql creates. All data is lost.
+
+DROP VIEW IF EXISTS payments;
+
+DROP TABLE IF EXISTS
+    commission_entry,
+    commission_rule,
+    email_overflow,
+    email_recipient_quota,
+    email_outbox,
+    payment_link_resend,
+    invoice,
+    enrollment_sync,
+    document_job,
+    course_content_block,
+    acceptance_approval,
+    application_status_history,
+    lead_escalation,
+    lead_review,
+    lead_integration,
+    lead_note_mention,
+    lead_note,
+    lead_event,
+    user_notification,
+    counselor_shift,
+    interviewer,
+    marketing_spend,
+    inbound_import,
+    import_history,
+    retention_run,
+    metrics_snapshot,
+    api_usage,
+    event_outbox,
+    processed_events,
+    razorpay_webhooks,
+    dlq_reveal_log,
+    dlq_retry_policy,
+    dlq_messages_archive,
+    dlq_messages,
+    course_payment,
+    registration_payment,
+    student_lead,
+    course,
+    counselor
+    CASCADE;
+
+DROP SEQUENCE IF EXISTS invoice_number_seq;
+
+DROP FUNCTION IF EXISTS record_application_status_history();
+DROP FUNCTION IF EXISTS set_student_lead_status_changed_at();
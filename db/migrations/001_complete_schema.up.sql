@@ -0,0 +1,911 @@
+-- ============================================
+-- Complete Schema Migration - All tables
+-- ============================================
+-- This migration consolidates all schema setup:
+-- - Base tables (counselor, course, student_lead)
+-- - Payment tables (registration_payment, course_payment)
+-- - DLQ messages for failed event processing
+-- - Webhook audit and tracking
+--
+-- Every statement is idempotent, so databases created before versioned migrations
+-- (when this file ran on every startup) are brought up to version 1 in place.
+-- Schema changes go in new numbered files, never in this one.
+
+-- ============================================
+-- 1. BASE TABLES
+-- ============================================
+
+-- Counselor table
+CREATE TABLE IF NOT EXISTS counselor (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    phone VARCHAR(20),
+    assigned_count INTEGER DEFAULT 0,
+    max_capacity INTEGER DEFAULT 10,
+    is_referral_enabled BOOLEAN DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Course table
+CREATE TABLE IF NOT EXISTS course (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    fee NUMERIC(10, 2) NOT NULL,
+    duration VARCHAR(100),
+    is_active INTEGER DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Student Lead table
+CREATE TABLE IF NOT EXISTS student_lead (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    phone VARCHAR(20) NOT NULL,
+    education VARCHAR(255),
+    lead_source VARCHAR(100),
+    counselor_id INTEGER,
+    registration_fee_status VARCHAR(50) DEFAULT 'PENDING',
+    course_fee_status VARCHAR(50) DEFAULT 'PENDING',
+    meet_link TEXT,
+    application_status VARCHAR(50) DEFAULT 'NEW',
+    registration_payment_id INTEGER,
+    selected_course_id INTEGER,
+    course_payment_id INTEGER,
+    interview_scheduled_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_counselor
+        FOREIGN KEY (counselor_id)
+        REFERENCES counselor(id)
+        ON DELETE SET NULL,
+    CONSTRAINT fk_selected_course
+        FOREIGN KEY (selected_course_id)
+        REFERENCES course(id)
+        ON DELETE SET NULL
+);
+
+-- Lead Note table for counselor interactions (calls, follow-ups, ...)
+CREATE TABLE IF NOT EXISTS lead_note (
+    id SERIAL PRIMARY KEY,
+    lead_id INTEGER NOT NULL,
+    counselor_id INTEGER,
+    note_type VARCHAR(20) NOT NULL DEFAULT 'NOTE',
+    content TEXT NOT NULL,
+    follow_up_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_lead_note_lead
+        FOREIGN KEY (lead_id)
+        REFERENCES student_lead(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_lead_note_counselor
+        FOREIGN KEY (counselor_id)
+        REFERENCES counselor(id)
+        ON DELETE SET NULL
+);
+
+-- Lead Note Mention table (@mentioned counselors per note)
+CREATE TABLE IF NOT EXISTS lead_note_mention (
+    note_id INTEGER NOT NULL REFERENCES lead_note(id) ON DELETE CASCADE,
+    counselor_id INTEGER NOT NULL REFERENCES counselor(id) ON DELETE CASCADE,
+    PRIMARY KEY (note_id, counselor_id)
+);
+
+-- User Notification table for in-app counselor notifications
+CREATE TABLE IF NOT EXISTS user_notification (
+    id SERIAL PRIMARY KEY,
+    counselor_id INTEGER NOT NULL REFERENCES counselor(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    message TEXT,
+    lead_id INTEGER REFERENCES student_lead(id) ON DELETE CASCADE,
+    note_id INTEGER REFERENCES lead_note(id) ON DELETE CASCADE,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Lead Event table (Kafka events per lead, used for the activity timeline)
+CREATE TABLE IF NOT EXISTS lead_event (
+    id SERIAL PRIMARY KEY,
+    lead_id INTEGER NOT NULL REFERENCES student_lead(id) ON DELETE CASCADE,
+    event_type VARCHAR(100) NOT NULL,
+    topic VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+    event_hash VARCHAR(64) NOT NULL UNIQUE,
+    occurred_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Marketing Spend table (spend per lead source/campaign per period, for CAC reporting)
+CREATE TABLE IF NOT EXISTS marketing_spend (
+    id SERIAL PRIMARY KEY,
+    lead_source VARCHAR(100) NOT NULL,
+    campaign VARCHAR(255) NOT NULL DEFAULT '',
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    amount NUMERIC(12, 2) NOT NULL,
+    notes TEXT,
+    recorded_by VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT uq_marketing_spend_period UNIQUE (lead_source, campaign, period_start, period_end),
+    CONSTRAINT chk_marketing_spend_period CHECK (period_end >= period_start)
+);
+
+-- Document Job table (outbox of async document generation requests)
+CREATE TABLE IF NOT EXISTS document_job (
+    id SERIAL PRIMARY KEY,
+    job_type VARCHAR(50) NOT NULL,
+    format VARCHAR(10) NOT NULL,
+    params JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    requested_by VARCHAR(255),
+    notify_email VARCHAR(255),
+    file_name VARCHAR(255),
+    storage_key VARCHAR(500),
+    error_message TEXT,
+    attempts INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+-- Lead Escalation table (stale NEW leads reassigned or flagged to the admin queue)
+CREATE TABLE IF NOT EXISTS lead_escalation (
+    id SERIAL PRIMARY KEY,
+    lead_id INTEGER NOT NULL REFERENCES student_lead(id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL,
+    from_counselor_id INTEGER REFERENCES counselor(id) ON DELETE SET NULL,
+    to_counselor_id INTEGER REFERENCES counselor(id) ON DELETE SET NULL,
+    reason TEXT,
+    resolved_at TIMESTAMP,
+    resolution_notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Lead Review table (submissions matching an existing lead on email or phone only, held for a counselor)
+CREATE TABLE IF NOT EXISTS lead_review (
+    id SERIAL PRIMARY KEY,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    source VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    phone VARCHAR(20) NOT NULL,
+    lead_data JSONB NOT NULL,
+    reason TEXT NOT NULL,
+    matched_lead_ids INTEGER[] NOT NULL DEFAULT '{}',
+    resolved_lead_id INTEGER REFERENCES student_lead(id) ON DELETE SET NULL,
+    resolved_by VARCHAR(255),
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Course Content Block table (department-managed snippets injected into course emails)
+CREATE TABLE IF NOT EXISTS course_content_block (
+    id SERIAL PRIMARY KEY,
+    course_id INTEGER NOT NULL REFERENCES course(id) ON DELETE CASCADE,
+    block_key VARCHAR(50) NOT NULL,
+    title VARCHAR(150) NOT NULL,
+    body TEXT NOT NULL,
+    email_type VARCHAR(20) NOT NULL DEFAULT 'all',
+    position INT NOT NULL DEFAULT 0,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_by VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (course_id, block_key)
+);
+
+-- Interviewer table (staff interviewing students, balanced by weekly load and daily cap)
+CREATE TABLE IF NOT EXISTS interviewer (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    daily_cap INTEGER NOT NULL DEFAULT 4,
+    course_ids INTEGER[] NOT NULL DEFAULT '{}',
+    skills TEXT[] NOT NULL DEFAULT '{}',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Acceptance Approval table (dual approval of acceptances into high-value courses)
+CREATE TABLE IF NOT EXISTS acceptance_approval (
+    id SERIAL PRIMARY KEY,
+    student_id INTEGER NOT NULL REFERENCES student_lead(id) ON DELETE CASCADE,
+    course_id INTEGER NOT NULL REFERENCES course(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    requested_by VARCHAR(255) NOT NULL,
+    approved_by VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP
+);
+
+-- Counselor Shift table (weekdays a counselor works the MORNING or EVENING shift;
+-- new leads arriving during a shift go to on-shift counselors first)
+CREATE TABLE IF NOT EXISTS counselor_shift (
+    id SERIAL PRIMARY KEY,
+    counselor_id INTEGER NOT NULL REFERENCES counselor(id) ON DELETE CASCADE,
+    shift VARCHAR(20) NOT NULL,
+    weekdays SMALLINT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(counselor_id, shift)
+);
+
+-- ============================================
+-- 2. PAYMENT TABLES
+-- ============================================
+
+-- Payment Link Resend table (counselor-triggered resends, used for throttling)
+CREATE TABLE IF NOT EXISTS payment_link_resend (
+    id SERIAL PRIMARY KEY,
+    lead_id INTEGER NOT NULL REFERENCES student_lead(id) ON DELETE CASCADE,
+    order_id VARCHAR(255) NOT NULL,
+    payment_type VARCHAR(50) NOT NULL,
+    channel VARCHAR(20) NOT NULL DEFAULT 'EMAIL',
+    counselor_id INTEGER REFERENCES counselor(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Registration Payment table
+CREATE TABLE IF NOT EXISTS registration_payment (
+    id SERIAL PRIMARY KEY,
+    student_id INTEGER NOT NULL UNIQUE,
+    amount NUMERIC(10, 2) NOT NULL,
+    status VARCHAR(50) DEFAULT 'PENDING',
+    order_id VARCHAR(255) UNIQUE,
+    payment_id VARCHAR(255),
+    razorpay_sign TEXT,
+    error_message TEXT,
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_student_reg_payment
+        FOREIGN KEY (student_id)
+        REFERENCES student_lead(id)
+        ON DELETE CASCADE
+);
+
+-- Course Payment table
+CREATE TABLE IF NOT EXISTS course_payment (
+    id SERIAL PRIMARY KEY,
+    student_id INTEGER NOT NULL,
+    course_id INTEGER NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL,
+    status VARCHAR(50) DEFAULT 'PENDING',
+    order_id VARCHAR(255) UNIQUE,
+    payment_id VARCHAR(255),
+    razorpay_sign TEXT,
+    error_message TEXT,
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT fk_student_course_payment
+        FOREIGN KEY (student_id)
+        REFERENCES student_lead(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_course_payment_course
+        FOREIGN KEY (course_id)
+        REFERENCES course(id)
+        ON DELETE CASCADE,
+    CONSTRAINT unique_student_course
+        UNIQUE(student_id, course_id)
+);
+
+-- Payments view (both payment tables, so lookups by order or student need not probe each table)
+CREATE OR REPLACE VIEW payments AS
+SELECT id, 'REGISTRATION'::VARCHAR(50) AS payment_type, student_id, NULL::INTEGER AS course_id,
+       amount, status, order_id, payment_id, razorpay_sign, error_message, timestamp, updated_at
+FROM registration_payment
+UNION ALL
+SELECT id, 'COURSE_FEE'::VARCHAR(50) AS payment_type, student_id, course_id,
+       amount, status, order_id, payment_id, razorpay_sign, error_message, timestamp, updated_at
+FROM course_payment;
+
+-- Invoice table (numbered course fee invoices, one per installment of a course payment)
+CREATE SEQUENCE IF NOT EXISTS invoice_number_seq;
+
+CREATE TABLE IF NOT EXISTS invoice (
+    id SERIAL PRIMARY KEY,
+    invoice_number VARCHAR(40) NOT NULL UNIQUE,
+    student_id INTEGER NOT NULL REFERENCES student_lead(id) ON DELETE CASCADE,
+    course_id INTEGER NOT NULL REFERENCES course(id) ON DELETE CASCADE,
+    course_payment_id INTEGER REFERENCES course_payment(id) ON DELETE SET NULL,
+    order_id VARCHAR(255),
+    installment_no INTEGER NOT NULL DEFAULT 1,
+    installment_count INTEGER NOT NULL DEFAULT 1,
+    tax_rate NUMERIC(5, 2) NOT NULL DEFAULT 0,
+    taxable_amount NUMERIC(10, 2) NOT NULL,
+    cgst_amount NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    sgst_amount NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    total_amount NUMERIC(10, 2) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'ISSUED',
+    storage_key VARCHAR(255),
+    payment_id VARCHAR(255),
+    issued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    paid_at TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_invoice_installment UNIQUE (course_payment_id, installment_no)
+);
+
+-- Enrollment Sync table (handoff of students whose course fee is paid to the LMS/ERP)
+CREATE TABLE IF NOT EXISTS enrollment_sync (
+    student_id INTEGER PRIMARY KEY REFERENCES student_lead(id) ON DELETE CASCADE,
+    course_id INTEGER NOT NULL REFERENCES course(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    attempts INTEGER DEFAULT 0,
+    last_error TEXT,
+    external_id VARCHAR(255),
+    next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    synced_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ============================================
+-- 3. MESSAGE QUEUE TABLES
+-- ============================================
+
+-- DLQ Messages table for failed event processing
+CREATE TABLE IF NOT EXISTS dlq_messages (
+    id SERIAL PRIMARY KEY,
+    message_id UUID UNIQUE,
+    topic VARCHAR(255) NOT NULL,
+    key TEXT,
+    value JSONB NOT NULL,
+    error_message TEXT,
+    retry_count INT DEFAULT 0,
+    max_retries INT DEFAULT 3,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_retry_at TIMESTAMP,
+    resolved BOOLEAN DEFAULT FALSE,
+    resolved_at TIMESTAMP,
+    notes TEXT
+);
+
+-- Escalation marker set once a message exhausts its topic's retry budget
+ALTER TABLE dlq_messages ADD COLUMN IF NOT EXISTS escalated_at TIMESTAMP;
+
+-- Root cause tag set when resolving (smtp-outage, bad-schema, kafka-down)
+ALTER TABLE dlq_messages ADD COLUMN IF NOT EXISTS failure_category VARCHAR(50);
+
+-- value holds a PII-masked copy; the original payload is kept in raw_value for retries
+-- and audited reveals, and cleared once the message is resolved
+ALTER TABLE dlq_messages ADD COLUMN IF NOT EXISTS raw_value BYTEA;
+ALTER TABLE dlq_messages ADD COLUMN IF NOT EXISTS payload_size INT;
+
+-- When the auto-retry loop next attempts the message (pushed back by exponential backoff after each failure)
+ALTER TABLE dlq_messages ADD COLUMN IF NOT EXISTS next_retry_at TIMESTAMP;
+
+-- Quarantine marker: set with escalated_at when the retry budget is exhausted; the auto-retry
+-- loop and retry-all skip quarantined messages until they are force retried or resolved
+ALTER TABLE dlq_messages ADD COLUMN IF NOT EXISTS quarantined_at TIMESTAMP;
+UPDATE dlq_messages SET quarantined_at = escalated_at
+WHERE escalated_at IS NOT NULL AND quarantined_at IS NULL AND resolved = FALSE;
+
+-- Processed Events table (consumer-side deduplication of Kafka redeliveries)
+CREATE TABLE IF NOT EXISTS processed_events (
+    event_id VARCHAR(128) PRIMARY KEY,
+    topic VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PROCESSING',
+    claimed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    processed_at TIMESTAMP
+);
+
+-- DLQ Archive table (resolved DLQ messages moved out of dlq_messages, row stored as gzip-compressed JSON)
+CREATE TABLE IF NOT EXISTS dlq_messages_archive (
+    id INTEGER PRIMARY KEY,
+    message_id UUID UNIQUE,
+    topic VARCHAR(255) NOT NULL,
+    failure_category VARCHAR(50),
+    created_at TIMESTAMP,
+    resolved_at TIMESTAMP,
+    archived_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    payload BYTEA NOT NULL
+);
+
+-- DLQ Reveal Log (audit trail of admins viewing original DLQ payloads)
+CREATE TABLE IF NOT EXISTS dlq_reveal_log (
+    id SERIAL PRIMARY KEY,
+    message_id UUID NOT NULL,
+    revealed_by VARCHAR(255) NOT NULL,
+    reason TEXT NOT NULL,
+    payload_available BOOLEAN NOT NULL,
+    revealed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- DLQ Retry Policy table (per-topic retry budget; '*' is the default policy)
+CREATE TABLE IF NOT EXISTS dlq_retry_policy (
+    topic VARCHAR(255) PRIMARY KEY,
+    max_retries INT NOT NULL DEFAULT 3,
+    backoff_seconds INT NOT NULL DEFAULT 10,
+    backoff_multiplier NUMERIC(4, 2) NOT NULL DEFAULT 1,
+    escalation_target VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Default policies: emails retry often, payments escalate fast. Other topics follow the
+-- DLQ_RETRY_* settings unless a '*' policy is configured.
+INSERT INTO dlq_retry_policy (topic, max_retries, backoff_seconds, backoff_multiplier) VALUES
+    ('emails', 10, 30, 2),
+    ('payments', 2, 60, 1)
+ON CONFLICT (topic) DO NOTHING;
+
+-- Drop the formerly seeded fixed-interval '*' policy unless it was edited, so DLQ_RETRY_* applies
+DELETE FROM dlq_retry_policy
+WHERE topic = '*' AND max_retries = 3 AND backoff_seconds = 10 AND backoff_multiplier = 1
+    AND escalation_target IS NULL AND updated_at = created_at;
+
+-- Email Outbox table for emails held back while SMTP is unconfigured
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id SERIAL PRIMARY KEY,
+    recipient VARCHAR(255) NOT NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    attachment TEXT,
+    status VARCHAR(20) DEFAULT 'PENDING',
+    attempts INT DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP
+);
+
+-- Event Outbox table (Kafka events written in the same transaction as the state change)
+CREATE TABLE IF NOT EXISTS event_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    topic VARCHAR(255) NOT NULL,
+    event_key VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) DEFAULT 'PENDING',
+    attempts INT DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP
+);
+
+-- ============================================
+-- 4. WEBHOOK TABLES
+-- ============================================
+
+-- Razorpay Webhooks table for audit and tracking
+CREATE TABLE IF NOT EXISTS razorpay_webhooks (
+    id SERIAL PRIMARY KEY,
+    webhook_id VARCHAR(255) UNIQUE NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(50) DEFAULT 'RECEIVED',
+    processed_at TIMESTAMP,
+    error_message TEXT,
+    retry_count INTEGER DEFAULT 0,
+    signature_valid BOOLEAN DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Processing latency and outcome of the latest delivery, for the webhook SLO
+ALTER TABLE razorpay_webhooks ADD COLUMN IF NOT EXISTS processing_ms INT;
+ALTER TABLE razorpay_webhooks ADD COLUMN IF NOT EXISTS processing_ok BOOLEAN;
+-- X-Request-ID of the delivery, matching HTTP logs, PostgreSQL application_name and event payloads
+ALTER TABLE razorpay_webhooks ADD COLUMN IF NOT EXISTS request_id VARCHAR(64);
+
+-- ============================================
+-- 5. IMPORT TABLES
+-- ============================================
+
+-- Import History table for bulk lead uploads
+CREATE TABLE IF NOT EXISTS import_history (
+    id SERIAL PRIMARY KEY,
+    file_name VARCHAR(255),
+    file_hash CHAR(64) NOT NULL,
+    uploaded_by VARCHAR(255),
+    total_count INTEGER DEFAULT 0,
+    success_count INTEGER DEFAULT 0,
+    failed_count INTEGER DEFAULT 0,
+    created_lead_ids INTEGER[] DEFAULT '{}',
+    status VARCHAR(20) DEFAULT 'COMPLETED',
+    rolled_back_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Duplicate policy of the run (skip, update or merge) and the rows it skipped or applied to existing leads
+ALTER TABLE import_history ADD COLUMN IF NOT EXISTS duplicate_policy VARCHAR(10) DEFAULT 'skip';
+ALTER TABLE import_history ADD COLUMN IF NOT EXISTS updated_count INTEGER DEFAULT 0;
+ALTER TABLE import_history ADD COLUMN IF NOT EXISTS skipped_count INTEGER DEFAULT 0;
+
+-- Inbound Import table (spreadsheets emailed to the import mailbox, queued for the import pipeline)
+CREATE TABLE IF NOT EXISTS inbound_import (
+    id SERIAL PRIMARY KEY,
+    sender VARCHAR(255) NOT NULL,
+    subject TEXT,
+    file_name VARCHAR(255) NOT NULL,
+    file_hash CHAR(64) NOT NULL,
+    storage_key TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    import_id INTEGER REFERENCES import_history(id) ON DELETE SET NULL,
+    error_message TEXT,
+    attempts INTEGER DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    processed_at TIMESTAMP
+);
+
+-- Soft-delete marker for leads removed by an import rollback
+ALTER TABLE student_lead ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+-- House account: fallback counselor for leads when nobody has capacity (at most one)
+ALTER TABLE counselor ADD COLUMN IF NOT EXISTS is_house_account BOOLEAN DEFAULT false;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_counselor_house_account ON counselor(is_house_account) WHERE is_house_account;
+
+-- When the lead entered its current application_status (maintained by trigger below)
+ALTER TABLE student_lead ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMP;
+
+CREATE OR REPLACE FUNCTION set_student_lead_status_changed_at() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' OR NEW.application_status IS DISTINCT FROM OLD.application_status THEN
+        NEW.status_changed_at := NOW();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_student_lead_status_changed_at ON student_lead;
+CREATE TRIGGER trg_student_lead_status_changed_at
+    BEFORE INSERT OR UPDATE OF application_status ON student_lead
+    FOR EACH ROW EXECUTE FUNCTION set_student_lead_status_changed_at();
+
+-- Application Status History table (every application_status change, for projection rebuilds)
+CREATE TABLE IF NOT EXISTS application_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    lead_id INTEGER NOT NULL REFERENCES student_lead(id) ON DELETE CASCADE,
+    from_status VARCHAR(50),
+    to_status VARCHAR(50) NOT NULL,
+    source VARCHAR(30) NOT NULL DEFAULT 'app',
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Corrections written by a projection rebuild set admission.status_source so they are
+-- recorded as such and taken as authoritative by later rebuilds
+CREATE OR REPLACE FUNCTION record_application_status_history() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' OR NEW.application_status IS DISTINCT FROM OLD.application_status THEN
+        INSERT INTO application_status_history (lead_id, from_status, to_status, source)
+        VALUES (
+            NEW.id,
+            CASE WHEN TG_OP = 'UPDATE' THEN OLD.application_status END,
+            COALESCE(NEW.application_status, 'NEW'),
+            COALESCE(NULLIF(current_setting('admission.status_source', true), ''), 'app')
+        );
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_student_lead_status_history ON student_lead;
+CREATE TRIGGER trg_student_lead_status_history
+    AFTER INSERT OR UPDATE OF application_status ON student_lead
+    FOR EACH ROW EXECUTE FUNCTION record_application_status_history();
+
+-- Set on a soft-deleted lead that was merged into another lead
+ALTER TABLE student_lead ADD COLUMN IF NOT EXISTS merged_into_id INTEGER;
+
+-- Campaign attribution for CAC reporting
+ALTER TABLE student_lead ADD COLUMN IF NOT EXISTS campaign VARCHAR(255);
+
+-- UTM attribution captured at lead creation
+ALTER TABLE student_lead ADD COLUMN IF NOT EXISTS utm_source VARCHAR(255);
+ALTER TABLE student_lead ADD COLUMN IF NOT EXISTS utm_medium VARCHAR(255);
+ALTER TABLE student_lead ADD COLUMN IF NOT EXISTS utm_campaign VARCHAR(255);
+
+-- Last time the assigned counselor was reminded to follow up on the lead
+ALTER TABLE student_lead ADD COLUMN IF NOT EXISTS follow_up_reminded_at TIMESTAMP;
+
+-- Last time the lead was escalated for staying NEW too long
+ALTER TABLE student_lead ADD COLUMN IF NOT EXISTS escalated_at TIMESTAMP;
+
+-- Re-marketing consent given by the student; consenting leads are never anonymized
+ALTER TABLE student_lead ADD COLUMN IF NOT EXISTS remarketing_consent BOOLEAN DEFAULT false;
+
+-- Set once the retention engine has anonymized the lead's PII
+ALTER TABLE student_lead ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMP;
+
+-- Current intake of a course, handed to the LMS/ERP with each enrollment
+ALTER TABLE course ADD COLUMN IF NOT EXISTS batch VARCHAR(50);
+
+-- Course fee payment deadline set on acceptance; unpaid offers expire after it
+ALTER TABLE student_lead ADD COLUMN IF NOT EXISTS course_fee_deadline TIMESTAMP;
+ALTER TABLE student_lead ADD COLUMN IF NOT EXISTS offer_expired_at TIMESTAMP;
+
+-- Interviewer assigned when the interview was scheduled
+ALTER TABLE student_lead ADD COLUMN IF NOT EXISTS interviewer_id INTEGER REFERENCES interviewer(id) ON DELETE SET NULL;
+
+-- When the interview invite email was delivered (pending interview inspection)
+ALTER TABLE student_lead ADD COLUMN IF NOT EXISTS interview_invite_sent_at TIMESTAMP;
+
+-- Retention Run table (one row per retention policy execution, with counts)
+CREATE TABLE IF NOT EXISTS retention_run (
+    id SERIAL PRIMARY KEY,
+    policy VARCHAR(100) NOT NULL,
+    processed_count INTEGER DEFAULT 0,
+    skipped_count INTEGER DEFAULT 0,
+    error_message TEXT,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP
+);
+
+-- API Usage table (requests per consumer and route, aggregated per hour)
+CREATE TABLE IF NOT EXISTS api_usage (
+    hour_start TIMESTAMP NOT NULL,
+    consumer VARCHAR(100) NOT NULL,
+    route VARCHAR(255) NOT NULL,
+    method VARCHAR(10) NOT NULL,
+    request_count BIGINT NOT NULL DEFAULT 0,
+    client_error_count BIGINT NOT NULL DEFAULT 0,
+    server_error_count BIGINT NOT NULL DEFAULT 0,
+    total_duration_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+    max_duration_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+    PRIMARY KEY (hour_start, consumer, route, method)
+);
+
+-- Lead Integration table (inbound lead pipes such as Zapier or ad connectors, matched on
+-- lead_source/utm_source, that are expected to deliver a lead at least every cadence)
+CREATE TABLE IF NOT EXISTS lead_integration (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    lead_source VARCHAR(100) NOT NULL,
+    utm_source VARCHAR(255) NOT NULL DEFAULT '',
+    expected_cadence_minutes INTEGER NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    status VARCHAR(20) NOT NULL DEFAULT 'UNKNOWN',
+    last_alert_at TIMESTAMP,
+    checked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Metrics Snapshot table (one row per day of key metrics, for dashboard trends)
+CREATE TABLE IF NOT EXISTS metrics_snapshot (
+    snapshot_date DATE PRIMARY KEY,
+    total_leads INTEGER NOT NULL DEFAULT 0,
+    new_leads INTEGER NOT NULL DEFAULT 0,
+    registrations_paid INTEGER NOT NULL DEFAULT 0,
+    new_registrations_paid INTEGER NOT NULL DEFAULT 0,
+    enrollments INTEGER NOT NULL DEFAULT 0,
+    new_enrollments INTEGER NOT NULL DEFAULT 0,
+    outstanding_course_fees INTEGER NOT NULL DEFAULT 0,
+    outstanding_course_fee_amount NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    pending_payment_orders INTEGER NOT NULL DEFAULT 0,
+    dlq_depth INTEGER NOT NULL DEFAULT 0,
+    dlq_quarantined INTEGER NOT NULL DEFAULT 0,
+    captured_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Email Quota table (emails delivered to each recipient per day, for the daily cap)
+CREATE TABLE IF NOT EXISTS email_recipient_quota (
+    recipient VARCHAR(255) NOT NULL,
+    quota_date DATE NOT NULL,
+    sent_count INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (recipient, quota_date)
+);
+
+-- Email Overflow table (emails dropped because their recipient reached the daily cap)
+CREATE TABLE IF NOT EXISTS email_overflow (
+    id BIGSERIAL PRIMARY KEY,
+    recipient VARCHAR(255) NOT NULL,
+    category VARCHAR(50) NOT NULL,
+    subject TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Commission Rule table (counselor commission per enrollment, per counselor and/or course)
+CREATE TABLE IF NOT EXISTS commission_rule (
+    id SERIAL PRIMARY KEY,
+    counselor_id INTEGER REFERENCES counselor(id) ON DELETE CASCADE,
+    course_id INTEGER REFERENCES course(id) ON DELETE CASCADE,
+    rule_type VARCHAR(20) NOT NULL,
+    value NUMERIC(10, 2) NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT chk_commission_rule_type CHECK (rule_type IN ('FLAT', 'PERCENTAGE')),
+    CONSTRAINT chk_commission_rule_value CHECK (value >= 0 AND (rule_type <> 'PERCENTAGE' OR value <= 100))
+);
+
+-- Commission Entry table (ledger of accrued commissions, refund reversals and manual adjustments)
+CREATE TABLE IF NOT EXISTS commission_entry (
+    id BIGSERIAL PRIMARY KEY,
+    counselor_id INTEGER NOT NULL REFERENCES counselor(id),
+    student_id INTEGER REFERENCES student_lead(id) ON DELETE SET NULL,
+    course_id INTEGER,
+    course_payment_id INTEGER,
+    rule_id INTEGER REFERENCES commission_rule(id) ON DELETE SET NULL,
+    entry_type VARCHAR(20) NOT NULL,
+    base_amount NUMERIC(10, 2),
+    amount NUMERIC(10, 2) NOT NULL,
+    reason TEXT,
+    created_by VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT chk_commission_entry_type CHECK (entry_type IN ('ACCRUAL', 'REFUND', 'ADJUSTMENT'))
+);
+
+-- ============================================
+-- 6. INDEXES FOR PERFORMANCE
+-- ============================================
+
+-- Student Lead indexes
+CREATE INDEX IF NOT EXISTS idx_student_lead_email ON student_lead(email);
+CREATE INDEX IF NOT EXISTS idx_student_lead_phone ON student_lead(phone);
+CREATE INDEX IF NOT EXISTS idx_student_lead_created_at ON student_lead(created_at);
+CREATE INDEX IF NOT EXISTS idx_student_lead_counselor_id ON student_lead(counselor_id);
+CREATE INDEX IF NOT EXISTS idx_student_lead_active ON student_lead(id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_student_lead_offer_deadline ON student_lead(course_fee_deadline) WHERE application_status = 'ACCEPTED';
+CREATE INDEX IF NOT EXISTS idx_student_lead_waitlist ON student_lead(selected_course_id, status_changed_at) WHERE application_status = 'WAITLISTED';
+CREATE INDEX IF NOT EXISTS idx_student_lead_interviewer ON student_lead(interviewer_id, interview_scheduled_at) WHERE interviewer_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_application_status_history_lead ON application_status_history(lead_id, id);
+CREATE INDEX IF NOT EXISTS idx_student_lead_source_created ON student_lead(LOWER(lead_source), created_at DESC);
+
+-- Course uniqueness (name + duration). Only enforced once existing duplicates
+-- have been merged with cmd/merge-courses, so the migration never fails on old data.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM course GROUP BY LOWER(name), COALESCE(duration, '') HAVING COUNT(*) > 1
+    ) THEN
+        CREATE UNIQUE INDEX IF NOT EXISTS uq_course_name_duration ON course (LOWER(name), COALESCE(duration, ''));
+    END IF;
+END $$;
+
+-- Lead note indexes
+CREATE INDEX IF NOT EXISTS idx_lead_note_lead_created ON lead_note(lead_id, created_at DESC);
+
+-- Lead event indexes
+CREATE INDEX IF NOT EXISTS idx_lead_event_lead_occurred ON lead_event(lead_id, occurred_at);
+
+-- Document job indexes
+CREATE INDEX IF NOT EXISTS idx_document_job_queue ON document_job(created_at) WHERE status IN ('PENDING', 'PROCESSING');
+
+-- Lead escalation indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_lead_review_pending ON lead_review(email, phone) WHERE status = 'PENDING';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_acceptance_approval_pending ON acceptance_approval(student_id) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_lead_escalation_open ON lead_escalation(created_at) WHERE action = 'ADMIN_QUEUE' AND resolved_at IS NULL;
+
+-- Notification indexes
+CREATE INDEX IF NOT EXISTS idx_user_notification_counselor ON user_notification(counselor_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_user_notification_unread ON user_notification(counselor_id) WHERE read_at IS NULL;
+
+-- Counselor indexes
+CREATE INDEX IF NOT EXISTS idx_counselor_assignment 
+ON counselor(assigned_count, id) 
+WHERE assigned_count < max_capacity;
+CREATE INDEX IF NOT EXISTS idx_counselor_shift_shift ON counselor_shift(shift);
+
+-- Payment indexes
+CREATE INDEX IF NOT EXISTS idx_payment_link_resend_lead ON payment_link_resend(lead_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_registration_payment_student ON registration_payment(student_id);
+CREATE INDEX IF NOT EXISTS idx_registration_payment_order ON registration_payment(order_id);
+CREATE INDEX IF NOT EXISTS idx_course_payment_student ON course_payment(student_id);
+CREATE INDEX IF NOT EXISTS idx_course_payment_course ON course_payment(course_id);
+CREATE INDEX IF NOT EXISTS idx_course_payment_order ON course_payment(order_id);
+CREATE INDEX IF NOT EXISTS idx_invoice_student ON invoice(student_id, issued_at DESC);
+CREATE INDEX IF NOT EXISTS idx_invoice_order ON invoice(order_id);
+
+-- DLQ indexes
+CREATE INDEX IF NOT EXISTS idx_dlq_created_at ON dlq_messages(created_at);
+CREATE INDEX IF NOT EXISTS idx_dlq_resolved ON dlq_messages(resolved);
+CREATE INDEX IF NOT EXISTS idx_dlq_topic ON dlq_messages(topic);
+CREATE INDEX IF NOT EXISTS idx_dlq_unresolved ON dlq_messages(resolved) WHERE resolved = FALSE;
+CREATE INDEX IF NOT EXISTS idx_dlq_failure_category ON dlq_messages(failure_category);
+CREATE INDEX IF NOT EXISTS idx_dlq_reveal_log_message ON dlq_reveal_log(message_id);
+CREATE INDEX IF NOT EXISTS idx_dlq_resolved_at ON dlq_messages(resolved_at) WHERE resolved = TRUE;
+DROP INDEX IF EXISTS idx_dlq_next_retry;
+CREATE INDEX IF NOT EXISTS idx_dlq_next_retry_due ON dlq_messages(next_retry_at) WHERE resolved = FALSE AND quarantined_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_dlq_quarantined ON dlq_messages(quarantined_at) WHERE resolved = FALSE AND quarantined_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_dlq_archive_archived_at ON dlq_messages_archive(archived_at);
+CREATE INDEX IF NOT EXISTS idx_enrollment_sync_due ON enrollment_sync(next_attempt_at) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_inbound_import_pending ON inbound_import(created_at) WHERE status IN ('PENDING', 'PROCESSING');
+CREATE INDEX IF NOT EXISTS idx_processed_events_processed_at ON processed_events(processed_at);
+
+-- Outbox indexes
+CREATE INDEX IF NOT EXISTS idx_email_outbox_pending ON email_outbox(created_at) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_event_outbox_pending ON event_outbox(id) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS idx_event_outbox_sent_at ON event_outbox(sent_at) WHERE status = 'SENT';
+CREATE INDEX IF NOT EXISTS idx_email_overflow_created_at ON email_overflow(created_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_commission_rule_active_scope
+ON commission_rule(COALESCE(counselor_id, 0), COALESCE(course_id, 0)) WHERE active;
+CREATE UNIQUE INDEX IF NOT EXISTS uq_commission_entry_accrual ON commission_entry(course_payment_id) WHERE entry_type = 'ACCRUAL';
+CREATE INDEX IF NOT EXISTS idx_commission_entry_created_at ON commission_entry(created_at, counselor_id);
+
+-- Webhook indexes
+CREATE INDEX IF NOT EXISTS idx_razorpay_webhooks_event_type 
+ON razorpay_webhooks(event_type);
+
+CREATE INDEX IF NOT EXISTS idx_razorpay_webhooks_status 
+ON razorpay_webhooks(status);
+
+CREATE INDEX IF NOT EXISTS idx_razorpay_webhooks_webhook_id 
+ON razorpay_webhooks(webhook_id);
+
+CREATE INDEX IF NOT EXISTS idx_razorpay_webhooks_created_at 
+ON razorpay_webhooks(created_at DESC);
+
+-- Import history indexes
+CREATE INDEX IF NOT EXISTS idx_import_history_file_hash ON import_history(file_hash);
+
+-- Retention indexes
+CREATE INDEX IF NOT EXISTS idx_retention_run_started ON retention_run(started_at DESC);
+
+-- API usage indexes
+CREATE INDEX IF NOT EXISTS idx_api_usage_consumer ON api_usage(consumer, hour_start DESC);
+
+-- ============================================
+-- 7. COMMENTS FOR DOCUMENTATION
+-- ============================================
+
+COMMENT ON TABLE counselor IS 'Admission counselors who guide and manage student leads';
+COMMENT ON TABLE counselor_shift IS 'Counselor shift schedules: the ISO weekdays (1 = Monday) each counselor works the MORNING or EVENING shift';
+COMMENT ON TABLE course IS 'Educational programs offered by the institution';
+COMMENT ON TABLE course_content_block IS 'Course-specific email snippets (orientation, documents, contacts) for acceptance and enrollment emails';
+COMMENT ON TABLE student_lead IS 'Student applicants and their admission progress';
+COMMENT ON TABLE lead_review IS 'Leads sharing only the email or only the phone of an existing lead, awaiting approve-as-new or merge';
+COMMENT ON TABLE interviewer IS 'Interview staff with daily caps and the programs (course ids) and skills they interview for';
+COMMENT ON TABLE acceptance_approval IS 'Acceptances into high-value courses awaiting (or given) a second, distinct approver';
+COMMENT ON TABLE lead_note IS 'Counselor interaction log (calls, emails, meetings, follow-ups) per lead';
+COMMENT ON TABLE lead_note_mention IS 'Counselors @mentioned in lead notes';
+COMMENT ON TABLE marketing_spend IS 'Marketing spend per lead source/campaign per period';
+COMMENT ON TABLE document_job IS 'Async document generation requests (bulk exports, reports) and their outputs';
+COMMENT ON TABLE lead_escalation IS 'Stale NEW leads that were reassigned or flagged to the admin queue';
+COMMENT ON TABLE lead_event IS 'Admission journey events (lead.created, payment.*, application.*) consumed from Kafka';
+COMMENT ON TABLE user_notification IS 'In-app notifications for counselors (e.g. note mentions)';
+COMMENT ON TABLE registration_payment IS 'Registration fee payments from students';
+COMMENT ON TABLE course_payment IS 'Course-specific fee payments';
+COMMENT ON VIEW payments IS 'Registration and course fee payments in one relation, tagged with payment_type';
+COMMENT ON TABLE payment_link_resend IS 'Payment instructions re-sent to leads by counselors';
+COMMENT ON TABLE dlq_messages IS 'Dead Letter Queue for messages that failed event processing';
+COMMENT ON TABLE razorpay_webhooks IS 'Audit log of all Razorpay webhook events';
+COMMENT ON TABLE processed_events IS 'Kafka events already handled by the consumer, so redeliveries are skipped';
+COMMENT ON TABLE dlq_messages_archive IS 'Resolved DLQ messages archived after DLQ_ARCHIVE_AFTER_DAYS (full row as gzip-compressed JSON)';
+COMMENT ON TABLE dlq_reveal_log IS 'Audit trail of admins revealing original (unredacted) DLQ payloads';
+COMMENT ON TABLE dlq_retry_policy IS 'Per-topic DLQ retry budget, backoff and escalation target';
+COMMENT ON TABLE event_outbox IS 'Kafka events stored atomically with their state change and relayed to Kafka by a worker';
+COMMENT ON TABLE email_outbox IS 'Emails queued while the SMTP channel is disabled, flushed once configured';
+COMMENT ON TABLE email_recipient_quota IS 'Capped-category emails delivered to each recipient per day (EMAIL_DAILY_RECIPIENT_CAP)';
+COMMENT ON TABLE email_overflow IS 'Emails dropped because their recipient had reached the daily email cap';
+COMMENT ON TABLE commission_rule IS 'Counselor commission per enrollment (FLAT amount or PERCENTAGE of the course fee); the most specific active rule applies';
+COMMENT ON TABLE commission_entry IS 'Commission ledger: ACCRUAL on course fee capture, REFUND reversals and manual ADJUSTMENTs, summed per month for payroll';
+COMMENT ON TABLE enrollment_sync IS 'Handoff of enrolled students (course fee paid) to the LMS/ERP, with delivery attempts and status';
+COMMENT ON TABLE inbound_import IS 'Spreadsheets received by email (INBOUND_MAIL_*), imported as leads attributed to the sender';
+COMMENT ON TABLE import_history IS 'Bulk lead upload runs, keyed by file hash to detect re-uploads';
+COMMENT ON TABLE application_status_history IS 'Every application_status change per lead (source app or projection_rebuild), replayed to rebuild statuses';
+COMMENT ON TABLE retention_run IS 'Data retention policy runs (e.g. anonymization of rejected leads) with processed/skipped counts';
+COMMENT ON TABLE api_usage IS 'HTTP requests per API consumer, route and method, aggregated per hour (4xx/5xx counts, latency)';
+COMMENT ON TABLE lead_integration IS 'Inbound lead integrations with their expected cadence; UNHEALTHY (and alerted) when no lead arrived within it';
+COMMENT ON TABLE metrics_snapshot IS 'Daily snapshots of lead, conversion, outstanding payment and DLQ metrics for dashboard trends';
+COMMENT ON TABLE invoice IS 'Course fee invoices (INVOICE_PREFIX/<financial year>/<serial>) with GST breakdown, one per installment; ISSUED until the payment is captured';
+COMMENT ON COLUMN api_usage.consumer IS 'key:<api key hash>, client:<X-Client-ID>, admin, razorpay or ip:<address>';
+
+COMMENT ON COLUMN counselor.is_referral_enabled IS 'Whether this counselor can be assigned to referral leads';
+COMMENT ON COLUMN student_lead.registration_fee_status IS 'Status of registration fee payment (PENDING, PAID)';
+COMMENT ON COLUMN student_lead.course_fee_status IS 'Status of course fee payment (PENDING, PAID)';
+COMMENT ON COLUMN lead_event.event_hash IS 'SHA-256 of the message value so redelivered or retried events are stored once';
+COMMENT ON COLUMN counselor.is_house_account IS 'Fallback counselor holding leads no counselor had capacity for (the unassigned queue)';
+COMMENT ON COLUMN student_lead.status_changed_at IS 'When the lead entered its current application_status (NULL for leads older than the column)';
+COMMENT ON COLUMN student_lead.merged_into_id IS 'Lead this duplicate was merged into (the duplicate is soft-deleted)';
+COMMENT ON COLUMN student_lead.anonymized_at IS 'When the lead''s PII was anonymized by the retention engine';
+COMMENT ON COLUMN student_lead.campaign IS 'Marketing campaign that produced the lead (matched against marketing_spend)';
+COMMENT ON COLUMN student_lead.deleted_at IS 'Set when the lead was soft-deleted (e.g. by an import rollback)';
+COMMENT ON COLUMN student_lead.course_fee_deadline IS 'Course fee payment deadline set on acceptance; the offer expires (OFFER_EXPIRED) when unpaid by then';
+COMMENT ON COLUMN student_lead.offer_expired_at IS 'When an unpaid offer was withdrawn and its seat released to the waitlist';
+COMMENT ON COLUMN student_lead.interview_invite_sent_at IS 'When the latest interview invite email was handed to SMTP';
+COMMENT ON COLUMN razorpay_webhooks.webhook_id IS 'Unique webhook ID from Razorpay to prevent duplicate processing';
+COMMENT ON COLUMN razorpay_webhooks.signature_valid IS 'Whether the webhook signature was validated successfully';
@@ -0,0 +1,52 @@
+-- ============================================
+-- Monthly partitions for razorpay_webhooks - rollback
+-- ============================================
+-- Folds the attached partitions back into a plain table. Partitions already detached
+-- to cold storage (razorpay_webhooks_pYYYY_MM tables outside razorpay_webhooks) are left
+-- as they are.
+
+ALTER TABLE razorpay_webhooks RENAME TO razorpay_webhooks_partitioned;
+
+CREATE TABLE razorpay_webhooks (
+    id INTEGER NOT NULL DEFAULT nextval('razorpay_webhooks_id_seq') PRIMARY KEY,
+    webhook_id VARCHAR(255) UNIQUE NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(50) DEFAULT 'RECEIVED',
+    processed_at TIMESTAMP,
+    error_message TEXT,
+    retry_count INTEGER DEFAULT 0,
+    signature_valid BOOLEAN DEFAULT false,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    processing_ms INT,
+    processing_ok BOOLEAN,
+    request_id VARCHAR(64)
+);
+
+ALTER SEQUENCE razorpay_webhooks_id_seq AS INTEGER OWNED BY razorpay_webhooks.id;
+
+INSERT INTO razorpay_webhooks (id, webhook_id, event_type, payload, status, processed_at, error_message, retry_count,
+    signature_valid, created_at, updated_at, processing_ms, processing_ok, request_id)
+SELECT id, webhook_id, event_type, payload, status, processed_at, error_message, retry_count,
+    signature_valid, created_at, updated_at, processing_ms, processing_ok, request_id
+FROM razorpay_webhooks_partitioned;
+
+DROP TABLE razorpay_webhooks_partitioned;
+DROP TABLE IF EXISTS razorpay_webhook_key;
+
+CREATE INDEX IF NOT EXISTS idx_razorpay_webhooks_event_type
+ON razorpay_webhooks(event_type);
+
+CREATE INDEX IF NOT EXISTS idx_razorpay_webhooks_status
+ON razorpay_webhooks(status);
+
+CREATE INDEX IF NOT EXISTS idx_razorpay_webhooks_webhook_id
+ON razorpay_webhooks(webhook_id);
+
+CREATE INDEX IF NOT EXISTS idx_razorpay_webhooks_created_at
+ON razorpay_webhooks(created_at DESC);
+
+COMMENT ON TABLE razorpay_webhooks IS 'Audit log of all Razorpay webhook events';
+COMMENT ON COLUMN razorpay_webhooks.webhook_id IS 'Unique webhook ID from Razorpay to prevent duplicate processing';
+COMMENT ON COLUMN razorpay_webhooks.signature_valid IS 'Whether the webhook signature was validated successfully';
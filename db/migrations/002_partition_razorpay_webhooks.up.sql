@@ -0,0 +1,95 @@
+-- ============================================
+-- Monthly partitions for razorpay_webhooks
+-- ============================================
+-- The webhook audit log only grows. It is range-partitioned on created_at into one
+-- partition per month (razorpay_webhooks_pYYYY_MM), so queries on recent webhooks scan
+-- recent partitions only, and old months are detached to cold storage or dropped whole
+-- instead of deleted row by row. The webhook-partitions job creates upcoming months; the
+-- retention job archives old ones (services/partition.go).
+--
+-- A partitioned table can only enforce uniqueness together with the partition key, so
+-- webhook_id uniqueness moves to razorpay_webhook_key, which also records when the
+-- webhook was stored, i.e. which partition holds it.
+
+ALTER TABLE razorpay_webhooks RENAME TO razorpay_webhooks_legacy;
+
+CREATE TABLE razorpay_webhooks (
+    id BIGINT NOT NULL DEFAULT nextval('razorpay_webhooks_id_seq'),
+    webhook_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(50) DEFAULT 'RECEIVED',
+    processed_at TIMESTAMP,
+    error_message TEXT,
+    retry_count INTEGER DEFAULT 0,
+    signature_valid BOOLEAN DEFAULT false,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    processing_ms INT,
+    processing_ok BOOLEAN,
+    request_id VARCHAR(64),
+
+    CONSTRAINT pk_razorpay_webhooks PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+-- Keep the ID sequence (and so the IDs) of the old table
+ALTER SEQUENCE razorpay_webhooks_id_seq AS BIGINT OWNED BY razorpay_webhooks.id;
+
+-- One partition per month from the oldest webhook up to three months ahead
+DO $$
+DECLARE
+    month DATE;
+    last_month DATE := (date_trunc('month', NOW()) + INTERVAL '3 months')::date;
+BEGIN
+    SELECT LEAST(date_trunc('month', MIN(COALESCE(created_at, updated_at))), date_trunc('month', NOW()))::date
+    INTO month
+    FROM razorpay_webhooks_legacy;
+    month := COALESCE(month, date_trunc('month', NOW())::date);
+
+    WHILE month <= last_month LOOP
+        EXECUTE format('CREATE TABLE IF NOT EXISTS %I PARTITION OF razorpay_webhooks FOR VALUES FROM (%L) TO (%L)',
+            'razorpay_webhooks_p' || to_char(month, 'YYYY_MM'), month, (month + INTERVAL '1 month')::date);
+        month := (month + INTERVAL '1 month')::date;
+    END LOOP;
+END
+$$;
+
+-- Catches rows outside the monthly partitions, e.g. when the partition job has not run;
+-- they are moved out when their month's partition is created
+CREATE TABLE IF NOT EXISTS razorpay_webhooks_default PARTITION OF razorpay_webhooks DEFAULT;
+
+INSERT INTO razorpay_webhooks (id, webhook_id, event_type, payload, status, processed_at, error_message, retry_count,
+    signature_valid, created_at, updated_at, processing_ms, processing_ok, request_id)
+SELECT id, webhook_id, event_type, payload, status, processed_at, error_message, retry_count,
+    signature_valid, COALESCE(created_at, updated_at, NOW()), updated_at, processing_ms, processing_ok, request_id
+FROM razorpay_webhooks_legacy;
+
+-- Webhook IDs seen, with the created_at of the stored webhook
+CREATE TABLE IF NOT EXISTS razorpay_webhook_key (
+    webhook_id VARCHAR(255) PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL
+);
+
+INSERT INTO razorpay_webhook_key (webhook_id, created_at)
+SELECT webhook_id, created_at FROM razorpay_webhooks
+ON CONFLICT (webhook_id) DO NOTHING;
+
+DROP TABLE razorpay_webhooks_legacy;
+
+-- Created on the parent, so every partition gets them
+CREATE INDEX IF NOT EXISTS idx_razorpay_webhooks_event_type
+ON razorpay_webhooks(event_type);
+
+CREATE INDEX IF NOT EXISTS idx_razorpay_webhooks_status
+ON razorpay_webhooks(status);
+
+CREATE INDEX IF NOT EXISTS idx_razorpay_webhooks_webhook_id
+ON razorpay_webhooks(webhook_id);
+
+CREATE INDEX IF NOT EXISTS idx_razorpay_webhooks_created_at
+ON razorpay_webhooks(created_at DESC);
+
+COMMENT ON TABLE razorpay_webhooks IS 'Audit log of all Razorpay webhook events, partitioned by month of created_at';
+COMMENT ON COLUMN razorpay_webhooks.webhook_id IS 'Webhook ID from Razorpay; unique through razorpay_webhook_key';
+COMMENT ON COLUMN razorpay_webhooks.signature_valid IS 'Whether the webhook signature was validated successfully';
+COMMENT ON TABLE razorpay_webhook_key IS 'Razorpay webhook IDs seen, to prevent duplicate processing, with the created_at locating the stored webhook';
@@ -0,0 +1,13 @@
+-- ============================================
+-- End-of-day summary recipients
+-- ============================================
+-- Addresses the nightly administration summary (services/admin_summary.go) is emailed
+-- to, managed through /admin/summary-recipients. Emails are stored lower-cased.
+CREATE TABLE IF NOT EXISTS admin_summary_recipient (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    added_by VARCHAR(100),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE admin_summary_recipient IS 'Recipients of the end-of-day administration summary email';
@@ -0,0 +1,22 @@
+-- ============================================
+-- Audit log
+-- ============================================
+-- Who changed what and when: lead merges and review resolutions, application decisions,
+-- course updates and DLQ resolutions, written by the handlers making the change
+-- (services/audit.go) and listed through /audit. changes maps each changed field to its
+-- {"from", "to"} values.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    entity_type VARCHAR(30) NOT NULL,
+    entity_id VARCHAR(100) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    actor VARCHAR(100) NOT NULL,
+    changes JSONB,
+    request_id VARCHAR(64),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at DESC);
+
+COMMENT ON TABLE audit_log IS 'Who changed which lead, application, course or DLQ message, and when';
@@ -0,0 +1,9 @@
+-- Fails while encrypted values are stored: they do not fit the old widths
+DROP INDEX IF EXISTS idx_student_lead_email_index;
+DROP INDEX IF EXISTS idx_student_lead_phone_index;
+ALTER TABLE student_lead DROP COLUMN IF EXISTS email_index;
+ALTER TABLE student_lead DROP COLUMN IF EXISTS phone_index;
+ALTER TABLE student_lead ALTER COLUMN email TYPE VARCHAR(255);
+ALTER TABLE student_lead ALTER COLUMN phone TYPE VARCHAR(20);
+CREATE INDEX IF NOT EXISTS idx_student_lead_email ON student_lead(email);
+CREATE INDEX IF NOT EXISTS idx_student_lead_phone ON student_lead(phone);
@@ -0,0 +1,20 @@
+-- ============================================
+-- Lead contact encryption
+-- ============================================
+-- student_lead.email and phone hold AES-GCM ciphertext when PII_ENCRYPTION_KEY is set
+-- (utils/pii.go), which no longer fits their old widths. Lookups and duplicate checks
+-- match the blind indexes instead: keyed HMACs of the plaintext, filled on write and, for
+-- existing rows, by the backfill the server runs at startup (services/lead_pii.go).
+ALTER TABLE student_lead ALTER COLUMN email TYPE TEXT;
+ALTER TABLE student_lead ALTER COLUMN phone TYPE TEXT;
+ALTER TABLE student_lead ADD COLUMN IF NOT EXISTS email_index VARCHAR(64);
+ALTER TABLE student_lead ADD COLUMN IF NOT EXISTS phone_index VARCHAR(64);
+
+-- Ciphertext is never compared, so the plaintext indexes go
+DROP INDEX IF EXISTS idx_student_lead_email;
+DROP INDEX IF EXISTS idx_student_lead_phone;
+CREATE INDEX IF NOT EXISTS idx_student_lead_email_index ON student_lead(email_index);
+CREATE INDEX IF NOT EXISTS idx_student_lead_phone_index ON student_lead(phone_index);
+
+COMMENT ON COLUMN student_lead.email_index IS 'Blind index (keyed HMAC) of the email, for lookups';
+COMMENT ON COLUMN student_lead.phone_index IS 'Blind index (keyed HMAC) of the phone, for lookups';
@@ -0,0 +1,2 @@
+ALTER TABLE retention_run DROP COLUMN IF EXISTS retention_days;
+ALTER TABLE retention_run DROP COLUMN IF EXISTS dry_run;
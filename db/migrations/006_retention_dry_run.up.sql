@@ -0,0 +1,8 @@
+-- ============================================
+-- Retention dry runs
+-- ============================================
+-- Retention runs record the window they applied (RETENTION_POLICIES or the policy's
+-- default) and whether they were dry runs, which only count the records they would
+-- remove (RETENTION_DRY_RUN, or POST /admin/retention/runs?dry_run=true).
+ALTER TABLE retention_run ADD COLUMN IF NOT EXISTS dry_run BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE retention_run ADD COLUMN IF NOT EXISTS retention_days INTEGER;
@@ -0,0 +1,18 @@
+-- Columns cannot be dropped from a view with CREATE OR REPLACE
+DROP VIEW IF EXISTS payments;
+CREATE VIEW payments AS
+SELECT id, 'REGISTRATION'::VARCHAR(50) AS payment_type, student_id, NULL::INTEGER AS course_id,
+       amount, status, order_id, payment_id, razorpay_sign, error_message, timestamp, updated_at
+FROM registration_payment
+UNION ALL
+SELECT id, 'COURSE_FEE'::VARCHAR(50) AS payment_type, student_id, course_id,
+       amount, status, order_id, payment_id, razorpay_sign, error_message, timestamp, updated_at
+FROM course_payment;
+COMMENT ON VIEW payments IS 'Registration and course fee payments in one relation, tagged with payment_type';
+
+ALTER TABLE registration_payment DROP COLUMN IF EXISTS refund_id;
+ALTER TABLE registration_payment DROP COLUMN IF EXISTS refund_amount;
+ALTER TABLE registration_payment DROP COLUMN IF EXISTS refunded_at;
+ALTER TABLE course_payment DROP COLUMN IF EXISTS refund_id;
+ALTER TABLE course_payment DROP COLUMN IF EXISTS refund_amount;
+ALTER TABLE course_payment DROP COLUMN IF EXISTS refunded_at;
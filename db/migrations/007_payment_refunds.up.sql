@@ -0,0 +1,23 @@
+-- ============================================
+-- Payment refunds
+-- ============================================
+-- Refunds made through POST /refund-payment. refund_id is the latest Razorpay refund of
+-- the payment and refund_amount the total refunded so far; a payment is REFUNDED once
+-- its whole amount is, PARTIALLY_REFUNDED before that.
+ALTER TABLE registration_payment ADD COLUMN IF NOT EXISTS refund_id VARCHAR(255);
+ALTER TABLE registration_payment ADD COLUMN IF NOT EXISTS refund_amount NUMERIC(10, 2);
+ALTER TABLE registration_payment ADD COLUMN IF NOT EXISTS refunded_at TIMESTAMP;
+ALTER TABLE course_payment ADD COLUMN IF NOT EXISTS refund_id VARCHAR(255);
+ALTER TABLE course_payment ADD COLUMN IF NOT EXISTS refund_amount NUMERIC(10, 2);
+ALTER TABLE course_payment ADD COLUMN IF NOT EXISTS refunded_at TIMESTAMP;
+
+CREATE OR REPLACE VIEW payments AS
+SELECT id, 'REGISTRATION'::VARCHAR(50) AS payment_type, student_id, NULL::INTEGER AS course_id,
+       amount, status, order_id, payment_id, razorpay_sign, error_message, timestamp, updated_at,
+       refund_id, refund_amount, refunded_at
+FROM registration_payment
+UNION ALL
+SELECT id, 'COURSE_FEE'::VARCHAR(50) AS payment_type, student_id, course_id,
+       amount, status, order_id, payment_id, razorpay_sign, error_message, timestamp, updated_at,
+       refund_id, refund_amount, refunded_at
+FROM course_payment;
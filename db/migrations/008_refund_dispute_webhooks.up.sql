@@ -0,0 +1,50 @@
+-- ============================================
+-- Refund and dispute webhooks
+-- ============================================
+-- Every refund of a payment, whether made through POST /refund-payment or in the Razorpay
+-- dashboard, with Razorpay's status: pending, processed or failed. The refund.processed
+-- and refund.failed webhooks update it; a refund is added to its payment's refund_amount
+-- once, and taken off again if it fails. commission_entry_id is the commission reversal
+-- of a course fee refund, which a failed refund reinstates.
+CREATE TABLE IF NOT EXISTS payment_refund (
+    refund_id VARCHAR(255) PRIMARY KEY,
+    order_id VARCHAR(255) NOT NULL,
+    payment_type VARCHAR(50) NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    reason TEXT,
+    commission_entry_id BIGINT REFERENCES commission_entry(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_refund_order ON payment_refund(order_id);
+
+-- Refunds made before: the amount is the payment's total, exact for payments refunded once
+INSERT INTO payment_refund (refund_id, order_id, payment_type, amount, status, created_at, updated_at)
+SELECT refund_id, order_id, payment_type, refund_amount, 'processed', refunded_at, refunded_at
+FROM payments
+WHERE refund_id IS NOT NULL AND order_id IS NOT NULL
+ON CONFLICT (refund_id) DO NOTHING;
+
+COMMENT ON TABLE payment_refund IS 'Razorpay refunds of registration and course fee payments, with their status';
+
+-- The latest chargeback dispute raised on a payment (payment.dispute.* webhooks), with
+-- Razorpay's status: open, under_review, won, lost or closed
+ALTER TABLE registration_payment ADD COLUMN IF NOT EXISTS dispute_id VARCHAR(255);
+ALTER TABLE registration_payment ADD COLUMN IF NOT EXISTS dispute_status VARCHAR(50);
+ALTER TABLE registration_payment ADD COLUMN IF NOT EXISTS disputed_at TIMESTAMP;
+ALTER TABLE course_payment ADD COLUMN IF NOT EXISTS dispute_id VARCHAR(255);
+ALTER TABLE course_payment ADD COLUMN IF NOT EXISTS dispute_status VARCHAR(50);
+ALTER TABLE course_payment ADD COLUMN IF NOT EXISTS disputed_at TIMESTAMP;
+
+CREATE OR REPLACE VIEW payments AS
+SELECT id, 'REGISTRATION'::VARCHAR(50) AS payment_type, student_id, NULL::INTEGER AS course_id,
+       amount, status, order_id, payment_id, razorpay_sign, error_message, timestamp, updated_at,
+       refund_id, refund_amount, refunded_at, dispute_id, dispute_status, disputed_at
+FROM registration_payment
+UNION ALL
+SELECT id, 'COURSE_FEE'::VARCHAR(50) AS payment_type, student_id, course_id,
+       amount, status, order_id, payment_id, razorpay_sign, error_message, timestamp, updated_at,
+       refund_id, refund_amount, refunded_at, dispute_id, dispute_status, disputed_at
+FROM course_payment;
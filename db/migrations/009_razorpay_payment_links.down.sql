@@ -0,0 +1 @@
+DROP TABLE IF EXISTS razorpay_payment_link;
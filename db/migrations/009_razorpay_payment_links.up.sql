@@ -0,0 +1,30 @@
+-- ============================================
+-- Razorpay Payment Links
+-- ============================================
+-- Hosted payment links created for a lead by a counselor (POST /leads/{id}/payment-links)
+-- and emailed to the student, who pays on Razorpay's page instead of our checkout. The
+-- order Razorpay creates for the payment is only known from the payment_link.paid
+-- webhook, which records it here and as the lead's registration or course payment.
+CREATE TABLE IF NOT EXISTS razorpay_payment_link (
+    id VARCHAR(255) PRIMARY KEY,
+    lead_id INTEGER NOT NULL REFERENCES student_lead(id) ON DELETE CASCADE,
+    payment_type VARCHAR(50) NOT NULL,
+    course_id INTEGER REFERENCES course(id) ON DELETE SET NULL,
+    amount NUMERIC(10, 2) NOT NULL,
+    short_url TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'created',
+    order_id VARCHAR(255),
+    payment_id VARCHAR(255),
+    error_message TEXT,
+    counselor_id INTEGER REFERENCES counselor(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP,
+    paid_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT chk_razorpay_payment_link_status CHECK (status IN ('created', 'paid', 'expired', 'cancelled'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_razorpay_payment_link_lead ON razorpay_payment_link(lead_id, created_at DESC);
+
+COMMENT ON TABLE razorpay_payment_link IS 'Razorpay Payment Links emailed to leads, with the order and payment they were paid with';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS payment_receipt;
+DROP SEQUENCE IF EXISTS receipt_number_seq;
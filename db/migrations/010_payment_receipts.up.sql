@@ -0,0 +1,30 @@
+-- ============================================
+-- Payment receipts
+-- ============================================
+-- The PDF receipt issued when a payment is captured, numbered RECEIPT_PREFIX/<financial
+-- year>/<serial> and attached to the confirmation email. Course fee receipts carry the
+-- GST breakdown of their invoice.
+CREATE SEQUENCE IF NOT EXISTS receipt_number_seq;
+
+CREATE TABLE IF NOT EXISTS payment_receipt (
+    id SERIAL PRIMARY KEY,
+    receipt_number VARCHAR(40) NOT NULL UNIQUE,
+    student_id INTEGER NOT NULL REFERENCES student_lead(id) ON DELETE CASCADE,
+    order_id VARCHAR(255) NOT NULL UNIQUE,
+    payment_id VARCHAR(255) NOT NULL,
+    payment_type VARCHAR(50) NOT NULL,
+    course_id INTEGER REFERENCES course(id) ON DELETE SET NULL,
+    invoice_id INTEGER REFERENCES invoice(id) ON DELETE SET NULL,
+    tax_rate NUMERIC(5, 2) NOT NULL DEFAULT 0,
+    taxable_amount NUMERIC(10, 2) NOT NULL,
+    cgst_amount NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    sgst_amount NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    total_amount NUMERIC(10, 2) NOT NULL,
+    storage_key VARCHAR(255),
+    paid_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_receipt_student ON payment_receipt(student_id);
+
+COMMENT ON TABLE payment_receipt IS 'Numbered PDF receipts of captured payments';
@@ -0,0 +1,13 @@
+ALTER TABLE payment_receipt DROP COLUMN IF EXISTS igst_amount;
+ALTER TABLE invoice DROP COLUMN IF EXISTS igst_amount, DROP COLUMN IF EXISTS place_of_supply;
+ALTER TABLE student_lead DROP COLUMN IF EXISTS state_code;
+
+-- The global sequences continue above every serial issued, so numbers stay unique
+CREATE SEQUENCE IF NOT EXISTS invoice_number_seq;
+SELECT setval('invoice_number_seq',
+    COALESCE((SELECT MAX(last_serial) FROM document_number_series WHERE series = 'invoice'), 0) + 1, false);
+CREATE SEQUENCE IF NOT EXISTS receipt_number_seq;
+SELECT setval('receipt_number_seq',
+    COALESCE((SELECT MAX(last_serial) FROM document_number_series WHERE series = 'receipt'), 0) + 1, false);
+
+DROP TABLE IF EXISTS document_number_series;
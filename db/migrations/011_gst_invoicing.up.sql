@@ -0,0 +1,40 @@
+-- ============================================
+-- GST invoicing
+-- ============================================
+-- Invoice and receipt numbers restart at 1 every financial year: the last serial of each
+-- series and year is kept here instead of in a global sequence. Existing numbers carry on.
+CREATE TABLE IF NOT EXISTS document_number_series (
+    series VARCHAR(20) NOT NULL,
+    financial_year VARCHAR(7) NOT NULL,
+    last_serial BIGINT NOT NULL,
+    PRIMARY KEY (series, financial_year)
+);
+
+INSERT INTO document_number_series (series, financial_year, last_serial)
+SELECT 'invoice', parts[1], MAX(parts[2]::bigint)
+FROM (SELECT regexp_match(invoice_number, '(\d{4}-\d{2})/(\d+)$') AS parts FROM invoice) numbered
+WHERE parts IS NOT NULL
+GROUP BY parts[1]
+ON CONFLICT DO NOTHING;
+
+INSERT INTO document_number_series (series, financial_year, last_serial)
+SELECT 'receipt', parts[1], MAX(parts[2]::bigint)
+FROM (SELECT regexp_match(receipt_number, '(\d{4}-\d{2})/(\d+)$') AS parts FROM payment_receipt) numbered
+WHERE parts IS NOT NULL
+GROUP BY parts[1]
+ON CONFLICT DO NOTHING;
+
+DROP SEQUENCE IF EXISTS invoice_number_seq;
+DROP SEQUENCE IF EXISTS receipt_number_seq;
+
+-- The student's GST state code decides the place of supply: IGST when it differs from the
+-- institution's state, CGST and SGST otherwise (and when it is unknown)
+ALTER TABLE student_lead ADD COLUMN IF NOT EXISTS state_code VARCHAR(2);
+
+ALTER TABLE invoice
+    ADD COLUMN IF NOT EXISTS place_of_supply VARCHAR(2),
+    ADD COLUMN IF NOT EXISTS igst_amount NUMERIC(10, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE payment_receipt ADD COLUMN IF NOT EXISTS igst_amount NUMERIC(10, 2) NOT NULL DEFAULT 0;
+
+COMMENT ON TABLE document_number_series IS 'Last serial of each document series (invoice, receipt) per financial year';
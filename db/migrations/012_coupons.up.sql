@@ -0,0 +1,55 @@
+-- ============================================
+-- Coupon codes
+-- ============================================
+-- Discount codes a student enters at checkout (POST /initiate-payment). A coupon takes a
+-- percentage or a flat amount off the registration or course fee, optionally only for one
+-- payment type or course and within a validity window. max_redemptions caps its uses
+-- overall (NULL: unlimited) and max_redemptions_per_student per student; an order that
+-- fails or is cancelled gives its use back.
+CREATE TABLE IF NOT EXISTS coupon (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    discount_type VARCHAR(10) NOT NULL,
+    discount_value NUMERIC(10, 2) NOT NULL,
+    payment_type VARCHAR(50),
+    course_id INTEGER REFERENCES course(id) ON DELETE CASCADE,
+    valid_from TIMESTAMP,
+    valid_until TIMESTAMP,
+    max_redemptions INTEGER,
+    max_redemptions_per_student INTEGER NOT NULL DEFAULT 1,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT chk_coupon_discount_type CHECK (discount_type IN ('percent', 'flat')),
+    CONSTRAINT chk_coupon_discount_value CHECK (discount_value > 0 AND (discount_type <> 'percent' OR discount_value <= 100)),
+    CONSTRAINT chk_coupon_payment_type CHECK (payment_type IS NULL OR payment_type IN ('REGISTRATION', 'COURSE_FEE'))
+);
+
+COMMENT ON TABLE coupon IS 'Discount codes for registration and course fees, with their validity and usage limits';
+
+-- The coupon an order was created with; amount is the fee after discount_amount.
+-- coupon_code keeps the code the student entered even if the coupon is deleted.
+ALTER TABLE registration_payment ADD COLUMN IF NOT EXISTS coupon_id INTEGER REFERENCES coupon(id) ON DELETE SET NULL;
+ALTER TABLE registration_payment ADD COLUMN IF NOT EXISTS coupon_code VARCHAR(50);
+ALTER TABLE registration_payment ADD COLUMN IF NOT EXISTS discount_amount NUMERIC(10, 2) NOT NULL DEFAULT 0;
+ALTER TABLE course_payment ADD COLUMN IF NOT EXISTS coupon_id INTEGER REFERENCES coupon(id) ON DELETE SET NULL;
+ALTER TABLE course_payment ADD COLUMN IF NOT EXISTS coupon_code VARCHAR(50);
+ALTER TABLE course_payment ADD COLUMN IF NOT EXISTS discount_amount NUMERIC(10, 2) NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_registration_payment_coupon ON registration_payment(coupon_id) WHERE coupon_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_course_payment_coupon ON course_payment(coupon_id) WHERE coupon_id IS NOT NULL;
+
+CREATE OR REPLACE VIEW payments AS
+SELECT id, 'REGISTRATION'::VARCHAR(50) AS payment_type, student_id, NULL::INTEGER AS course_id,
+       amount, status, order_id, payment_id, razorpay_sign, error_message, timestamp, updated_at,
+       refund_id, refund_amount, refunded_at, dispute_id, dispute_status, disputed_at,
+       coupon_id, coupon_code, discount_amount
+FROM registration_payment
+UNION ALL
+SELECT id, 'COURSE_FEE'::VARCHAR(50) AS payment_type, student_id, course_id,
+       amount, status, order_id, payment_id, razorpay_sign, error_message, timestamp, updated_at,
+       refund_id, refund_amount, refunded_at, dispute_id, dispute_status, disputed_at,
+       coupon_id, coupon_code, discount_amount
+FROM course_payment;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS payment_reconciliation_issue;
+DROP TABLE IF EXISTS payment_reconciliation_run;
@@ -0,0 +1,49 @@
+-- ============================================
+-- Payment reconciliation
+-- ============================================
+-- Each run of the payment-reconciliation job compares the payments and orders Razorpay
+-- lists for its window with registration_payment and course_payment.
+CREATE TABLE IF NOT EXISTS payment_reconciliation_run (
+    id SERIAL PRIMARY KEY,
+    window_start TIMESTAMP NOT NULL,
+    window_end TIMESTAMP NOT NULL,
+    payments_checked INTEGER NOT NULL DEFAULT 0,
+    orders_checked INTEGER NOT NULL DEFAULT 0,
+    issues_found INTEGER NOT NULL DEFAULT 0,
+    error_message TEXT,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_reconciliation_run_started ON payment_reconciliation_run(started_at DESC);
+
+-- A discrepancy found by a run, once per type and order: missing_webhook (captured on
+-- Razorpay, not paid here), amount_mismatch or orphaned_order (paid on Razorpay, no payment
+-- row here). Later runs refresh last_seen_at; resolved_at is set once the payment row
+-- agrees with Razorpay, or by hand.
+CREATE TABLE IF NOT EXISTS payment_reconciliation_issue (
+    id SERIAL PRIMARY KEY,
+    issue_type VARCHAR(30) NOT NULL,
+    order_id VARCHAR(255) NOT NULL,
+    payment_id VARCHAR(255),
+    payment_type VARCHAR(50),
+    student_id INTEGER,
+    razorpay_status VARCHAR(20),
+    razorpay_amount NUMERIC(10, 2),
+    recorded_status VARCHAR(50),
+    recorded_amount NUMERIC(10, 2),
+    detail TEXT NOT NULL,
+    run_id INTEGER REFERENCES payment_reconciliation_run(id) ON DELETE SET NULL,
+    first_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP,
+    resolution_note TEXT,
+
+    CONSTRAINT uq_payment_reconciliation_issue UNIQUE (issue_type, order_id),
+    CONSTRAINT chk_payment_reconciliation_issue_type CHECK (issue_type IN ('missing_webhook', 'amount_mismatch', 'orphaned_order'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_reconciliation_issue_open ON payment_reconciliation_issue(first_seen_at DESC) WHERE resolved_at IS NULL;
+
+COMMENT ON TABLE payment_reconciliation_run IS 'Runs of the payment-reconciliation job against the Razorpay API';
+COMMENT ON TABLE payment_reconciliation_issue IS 'Differences between Razorpay and the payment tables found by reconciliation';
@@ -0,0 +1,7 @@
+ALTER TABLE commission_rule DROP CONSTRAINT IF EXISTS commission_rule_course_id_fkey;
+ALTER TABLE commission_rule ADD CONSTRAINT commission_rule_course_id_fkey
+    FOREIGN KEY (course_id) REFERENCES course(id) ON DELETE CASCADE;
+
+ALTER TABLE invoice DROP CONSTRAINT IF EXISTS invoice_course_id_fkey;
+ALTER TABLE invoice ADD CONSTRAINT invoice_course_id_fkey
+    FOREIGN KEY (course_id) REFERENCES course(id) ON DELETE CASCADE;
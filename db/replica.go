@@ -0,0 +1,48 @@
+package db
+
+import (
+	"admission-module/config"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Replica is the read-only connection to DB_REPLICA_DSN, or nil when no replica is
+// configured or it could not be reached at startup
+var Replica *sql.DB
+
+// ConnectReplica opens and pings the read replica with the same pool settings as the
+// primary. Without DB_REPLICA_DSN it does nothing.
+func ConnectReplica() error {
+	dsn := config.AppConfig.DBReplicaDSN
+	if dsn == "" {
+		return nil
+	}
+
+	replica, err := openInstrumented(poolReplica, dsn)
+	if err != nil {
+		return fmt.Errorf("error opening read replica: %w", err)
+	}
+	replica.SetMaxOpenConns(config.AppConfig.DBMaxOpenConns)
+	replica.SetMaxIdleConns(config.AppConfig.DBMaxIdleConns)
+	replica.SetConnMaxLifetime(time.Duration(config.AppConfig.DBConnMaxLifetimeMinutes) * time.Minute)
+	replica.SetConnMaxIdleTime(time.Duration(config.AppConfig.DBConnMaxIdleTimeMinutes) * time.Minute)
+
+	if err := replica.Ping(); err != nil {
+		replica.Close()
+		return fmt.Errorf("error connecting to read replica: %w", err)
+	}
+
+	Replica = replica
+	return nil
+}
+
+// Reader returns the connection for reporting reads that can lag the primary a little:
+// the replica when one is connected, the primary otherwise. Writes, and reads deciding
+// a write, stay on DB.
+func Reader() *sql.DB {
+	if Replica != nil {
+		return Replica
+	}
+	return DB
+}
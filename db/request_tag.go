@@ -0,0 +1,27 @@
+package db
+
+import (
+	"admission-module/logger"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// TagTransaction sets the transaction's application_name to the request ID carried by ctx,
+// so its statements can be matched to the HTTP request in PostgreSQL logs (%a in
+// log_line_prefix) and pg_stat_activity. The name reverts when the transaction ends.
+func TagTransaction(ctx context.Context, tx *sql.Tx) error {
+	requestID := logger.RequestIDFromContext(ctx)
+	if requestID == "" {
+		return nil
+	}
+
+	name := "admission-module req=" + requestID
+	if len(name) > 63 { // PostgreSQL truncates application_name to 63 bytes
+		name = name[:63]
+	}
+	if _, err := tx.ExecContext(ctx, "SELECT set_config('application_name', $1, true)", name); err != nil {
+		return fmt.Errorf("error tagging transaction with request ID: %w", err)
+	}
+	return nil
+}
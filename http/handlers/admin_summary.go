@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// SummaryRecipients lists or adds recipients of the end-of-day summary email
+// GET  /admin/summary-recipients
+// POST /admin/summary-recipients
+func SummaryRecipients(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		listSummaryRecipients(w, r)
+	case http.MethodPost:
+		addSummaryRecipient(w, r)
+	default:
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func listSummaryRecipients(w http.ResponseWriter, r *http.Request) {
+	recipients, err := services.ListAdminSummaryRecipients(r.Context())
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching summary recipients: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch summary recipients")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retrieved %d summary recipients", len(recipients)), recipients)
+}
+
+func addSummaryRecipient(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email   string `json:"email"`
+		AddedBy string `json:"added_by"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	recipient, err := services.AddAdminSummaryRecipient(r.Context(), req.Email, req.AddedBy)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidSummaryRecipient):
+			response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrSummaryRecipientExists):
+			response.ErrorResponse(w, http.StatusConflict, err.Error())
+		default:
+			logger.FromContext(r.Context()).Error("Error adding summary recipient: %v", err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to add summary recipient")
+		}
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusCreated, "Summary recipient added", recipient)
+}
+
+// RemoveSummaryRecipient stops sending the end-of-day summary to a recipient
+// DELETE /admin/summary-recipients/{id}
+func RemoveSummaryRecipient(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid summary recipient ID")
+		return
+	}
+
+	if err := services.RemoveAdminSummaryRecipient(r.Context(), id); err != nil {
+		if errors.Is(err, services.ErrSummaryRecipientNotFound) {
+			response.ErrorResponse(w, http.StatusNotFound, err.Error())
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error removing summary recipient %d: %v", id, err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to remove summary recipient")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Summary recipient removed", map[string]interface{}{"id": id})
+}
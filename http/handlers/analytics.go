@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/services"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// GetFunnel returns lead counts per admission stage for leads created in the date range
+// GET /analytics/funnel?from=2025-01-01&to=2025-03-31&counselor_id=3
+func GetFunnel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	from, to, err := parseReportDateRange(r)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	counselorID, err := parseCounselorFilter(r)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	funnel, err := services.GetFunnel(r.Context(), from, to, counselorID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error computing funnel: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error computing funnel")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Admission funnel", funnel)
+}
+
+// GetStageAging returns, per application status, how many leads have sat in it for
+// 0-2, 3-7, 8-14 and 15+ days
+// GET /analytics/stage-aging?counselor_id=3
+func GetStageAging(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	counselorID, err := parseCounselorFilter(r)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stages, err := services.GetStageAging(r.Context(), counselorID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error computing stage aging: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error computing stage aging")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Lead stage aging", stages)
+}
+
+// GetCampaignConversions returns lead conversions grouped by UTM source/medium/campaign
+// GET /analytics/campaigns?from=2025-01-01&to=2025-03-31
+func GetCampaignConversions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	from, to, err := parseReportDateRange(r)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conversions, err := services.GetCampaignConversions(r.Context(), from, to)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error computing campaign conversions: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error computing campaign conversions")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retrieved %d campaigns", len(conversions)), conversions)
+}
+
+// parseCounselorFilter reads the optional counselor_id query parameter
+func parseCounselorFilter(r *http.Request) (*int64, error) {
+	str := r.URL.Query().Get("counselor_id")
+	if str == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.ParseInt(str, 10, 64)
+	if err != nil || parsed <= 0 {
+		return nil, fmt.Errorf("invalid counselor_id")
+	}
+	return &parsed, nil
+}
+
+// GetMetricsSnapshots returns the daily metrics snapshots (leads, conversions, outstanding
+// course fees, DLQ depth) in the date range, oldest first, for dashboard trends
+// GET /analytics/snapshots?from=2025-01-01&to=2025-03-31 (default: the last 30 days)
+func GetMetricsSnapshots(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	from, to, err := parseReportDateRange(r)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	series, err := services.ListMetricsSnapshots(r.Context(), from, to)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching metrics snapshots: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error fetching metrics snapshots")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retrieved %d snapshots", len(series.Snapshots)), series)
+}
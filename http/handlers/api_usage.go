@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/services"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// GetAPIUsage returns request counts, error rates and latency per API consumer and route
+// GET /admin/api-usage?hours=24&consumer=ip:10.0.0.5&route=/verify-payment&by=hour&limit=100
+func GetAPIUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	hours := 24
+	if hoursStr := query.Get("hours"); hoursStr != "" {
+		parsed, err := strconv.Atoi(hoursStr)
+		if err != nil || parsed <= 0 {
+			response.ErrorResponse(w, http.StatusBadRequest, "hours must be a positive number")
+			return
+		}
+		hours = parsed
+	}
+	limit := 100
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			limit = parsedLimit
+		}
+	}
+
+	// Usage still in memory is written first so the report includes the current minute
+	if err := services.FlushAPIUsage(r.Context()); err != nil {
+		logger.FromContext(r.Context()).Warn("Error flushing API usage before report: %v", err)
+	}
+
+	usage, err := services.GetAPIUsage(r.Context(), services.APIUsageFilter{
+		Since:    time.Now().Add(-time.Duration(hours) * time.Hour),
+		Consumer: query.Get("consumer"),
+		Route:    query.Get("route"),
+		Hourly:   query.Get("by") == "hour",
+		Limit:    limit,
+	})
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching API usage: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch API usage")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retrieved %d API usage rows for the last %d hours", len(usage), hours), usage)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"admission-module/http/middleware"
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/models"
+	"admission-module/services"
+	"admission-module/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// recordAudit writes a change made while handling r to the audit log, attributed to
+// middleware.AuditActor. The change itself has already been made, so a failure to write
+// the entry is logged rather than failing the request.
+func recordAudit(r *http.Request, entityType, entityID, action string, changes models.AuditChanges) {
+	entry := &models.AuditLogEntry{
+		EntityType: entityType,
+		EntityID:   entityID,
+		Action:     action,
+		Actor:      middleware.AuditActor(r),
+		Changes:    changes,
+	}
+	if err := services.RecordAudit(r.Context(), entry); err != nil {
+		logger.FromContext(r.Context()).Error("Error auditing %s of %s %s by %s: %v",
+			action, entityType, entityID, entry.Actor, err)
+	}
+}
+
+// GetAuditLog lists audit log entries, newest first
+// GET /audit?entity_type=lead&entity_id=42&actor=...&action=...&created_after=...&created_before=...&limit=100
+func GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	writeAuditLog(w, r, query.Get("entity_type"), query.Get("entity_id"))
+}
+
+// GetEntityAuditLog lists the audit log of one entity, newest first
+// GET /audit/{entity_type}/{entity_id}
+func GetEntityAuditLog(w http.ResponseWriter, r *http.Request) {
+	writeAuditLog(w, r, r.PathValue("entity_type"), r.PathValue("entity_id"))
+}
+
+func writeAuditLog(w http.ResponseWriter, r *http.Request, entityType, entityID string) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	timeParams, err := utils.ParseTimeFilters(r)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	query := r.URL.Query()
+	limit := 100
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			limit = parsedLimit
+		}
+	}
+
+	entries, err := services.ListAuditLog(r.Context(), services.AuditLogFilter{
+		EntityType:    entityType,
+		EntityID:      entityID,
+		Actor:         query.Get("actor"),
+		Action:        query.Get("action"),
+		CreatedAfter:  timeParams.CreatedAfter,
+		CreatedBefore: timeParams.CreatedBefore,
+		Limit:         limit,
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAuditEntity) {
+			response.ErrorResponse(w, http.StatusBadRequest,
+				"entity_type must be one of: "+strings.Join(services.AuditEntityTypes, ", "))
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error fetching audit log: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch audit log")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retrieved %d audit log entries", len(entries)), map[string]interface{}{
+		"count": len(entries),
+		"data":  entries,
+	})
+}
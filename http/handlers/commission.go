@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/models"
+	"admission-module/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// CommissionRules lists or sets counselor commission rules
+// GET  /admin/commission-rules?all=true
+// POST /admin/commission-rules
+func CommissionRules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		listCommissionRules(w, r)
+	case http.MethodPost:
+		setCommissionRule(w, r)
+	default:
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func listCommissionRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := services.ListCommissionRules(r.Context(), r.URL.Query().Get("all") == "true")
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching commission rules: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch commission rules")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retrieved %d commission rules", len(rules)), rules)
+}
+
+func setCommissionRule(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		CounselorID *int    `json:"counselor_id"`
+		CourseID    *int    `json:"course_id"`
+		RuleType    string  `json:"rule_type"`
+		Value       float64 `json:"value"`
+		CreatedBy   string  `json:"created_by"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	rule := &models.CommissionRule{
+		CounselorID: req.CounselorID,
+		CourseID:    req.CourseID,
+		RuleType:    req.RuleType,
+		Value:       req.Value,
+		CreatedBy:   req.CreatedBy,
+	}
+	if err := services.SetCommissionRule(r.Context(), rule); err != nil {
+		if errors.Is(err, services.ErrInvalidCommissionRule) {
+			response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error storing commission rule: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to store commission rule")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusCreated, "Commission rule saved", rule)
+}
+
+// DeactivateCommissionRule stops a commission rule from applying to new enrollments
+// DELETE /admin/commission-rules/{id}
+func DeactivateCommissionRule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	ruleID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || ruleID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid commission rule ID")
+		return
+	}
+
+	if err := services.DeactivateCommissionRule(r.Context(), ruleID); err != nil {
+		if errors.Is(err, services.ErrCommissionRuleNotFound) {
+			response.ErrorResponse(w, http.StatusNotFound, err.Error())
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error deactivating commission rule %d: %v", ruleID, err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to deactivate commission rule")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Commission rule deactivated", map[string]interface{}{"id": ruleID})
+}
+
+// RecordCommissionAdjustment adds a manual correction to a counselor's commissions
+// POST /admin/commissions/adjustments
+func RecordCommissionAdjustment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req struct {
+		CounselorID int     `json:"counselor_id"`
+		StudentID   *int    `json:"student_id"`
+		Amount      float64 `json:"amount"`
+		Reason      string  `json:"reason"`
+		CreatedBy   string  `json:"created_by"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.CounselorID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "counselor_id is required")
+		return
+	}
+	if req.Amount == 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "amount must not be zero")
+		return
+	}
+	if req.Reason == "" {
+		response.ErrorResponse(w, http.StatusBadRequest, "reason is required")
+		return
+	}
+
+	entry := &models.CommissionEntry{
+		CounselorID: req.CounselorID,
+		StudentID:   req.StudentID,
+		Amount:      req.Amount,
+		Reason:      req.Reason,
+		CreatedBy:   req.CreatedBy,
+	}
+	if err := services.RecordCommissionAdjustment(r.Context(), entry); err != nil {
+		logger.FromContext(r.Context()).Error("Error recording commission adjustment: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to record commission adjustment")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusCreated, "Commission adjustment recorded", entry)
+}
+
+// RecordCommissionRefund reverses the commission of a refunded course fee in proportion
+// to the amount refunded
+// POST /admin/commissions/refunds
+func RecordCommissionRefund(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req struct {
+		CoursePaymentID int     `json:"course_payment_id"`
+		RefundAmount    float64 `json:"refund_amount"`
+		Reason          string  `json:"reason"`
+		CreatedBy       string  `json:"created_by"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.CoursePaymentID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "course_payment_id is required")
+		return
+	}
+	if req.RefundAmount <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "refund_amount must be positive")
+		return
+	}
+
+	entry, err := services.RecordCommissionRefund(r.Context(), req.CoursePaymentID, req.RefundAmount, req.Reason, req.CreatedBy)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrCommissionNotAccrued):
+			response.ErrorResponse(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, services.ErrCommissionFullyReversed):
+			response.ErrorResponse(w, http.StatusConflict, err.Error())
+		default:
+			logger.FromContext(r.Context()).Error("Error reversing commission of course payment %d: %v", req.CoursePaymentID, err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to reverse commission")
+		}
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusCreated, "Commission reversed", entry)
+}
+
+// GetCommissionReport returns the commissions per counselor for a month, for payroll
+// GET /admin/commissions/report?month=2025-03&counselor_id=4&format=csv
+func GetCommissionReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	month := time.Now().UTC()
+	if monthStr := query.Get("month"); monthStr != "" {
+		parsed, err := time.Parse(services.CommissionMonthLayout, monthStr)
+		if err != nil {
+			response.ErrorResponse(w, http.StatusBadRequest, "Invalid month. Use YYYY-MM")
+			return
+		}
+		month = parsed
+	}
+	counselorID := 0
+	if idStr := query.Get("counselor_id"); idStr != "" {
+		parsed, err := strconv.Atoi(idStr)
+		if err != nil || parsed <= 0 {
+			response.ErrorResponse(w, http.StatusBadRequest, "Invalid counselor_id")
+			return
+		}
+		counselorID = parsed
+	}
+	format := query.Get("format")
+	if format != "" && format != "json" && format != services.ExportFormatCSV {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid format. Must be json or csv")
+		return
+	}
+
+	report, err := services.GetCommissionReport(r.Context(), month, counselorID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error building commission report: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error building commission report")
+		return
+	}
+
+	if format == services.ExportFormatCSV {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "commissions_"+report.Month+".csv"))
+		w.Header().Set("Content-Type", "text/csv")
+		if err := services.WriteCommissionReportCSV(w, report); err != nil {
+			logger.FromContext(r.Context()).Error("Error writing commission report CSV: %v", err)
+		}
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Commission report for "+report.Month, report)
+}
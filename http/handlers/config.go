@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"admission-module/config"
+	"admission-module/http/response"
+	"admission-module/logger"
+	"net/http"
+)
+
+// ReloadConfig re-reads the config layers and applies non-critical settings
+// (rate limits, thresholds, log level, feature flags) without a restart.
+// Same as sending SIGHUP to the server process.
+// POST /admin/config/reload
+func ReloadConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	result := config.Reload()
+	logger.FromContext(r.Context()).Info("Configuration reloaded via API: applied=%v restart_required=%v", result.Applied, result.RestartRequired)
+
+	response.SuccessResponse(w, http.StatusOK, "Configuration reloaded", result)
+}
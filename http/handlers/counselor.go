@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/models"
+	"admission-module/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// GetCounselorMetrics returns performance metrics for a counselor
+// GET /counselors/{id}/metrics?from=2025-01-01&to=2025-03-31
+func GetCounselorMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	counselorID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || counselorID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid counselor ID")
+		return
+	}
+
+	from, to, err := parseReportDateRange(r)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	metrics, err := services.GetCounselorMetrics(r.Context(), counselorID, from, to)
+	if err != nil {
+		if errors.Is(err, services.ErrCounselorNotFound) {
+			response.ErrorResponse(w, http.StatusNotFound, "Counselor not found")
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error computing metrics for counselor %d: %v", counselorID, err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error computing counselor metrics")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Counselor metrics", metrics)
+}
+
+// CounselorShifts returns or replaces a counselor's shift schedule. New leads arriving
+// during a shift are routed to the counselors scheduled for it first.
+// GET /counselors/{id}/shifts
+// PUT /counselors/{id}/shifts {"shifts": [{"shift": "MORNING", "weekdays": [1, 2, 3, 4, 5]}]}
+func CounselorShifts(w http.ResponseWriter, r *http.Request) {
+	counselorID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || counselorID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid counselor ID")
+		return
+	}
+
+	var schedule *models.CounselorShiftSchedule
+	switch r.Method {
+	case http.MethodGet:
+		schedule, err = services.GetCounselorShifts(r.Context(), counselorID, time.Now())
+
+	case http.MethodPut:
+		var req struct {
+			Shifts []services.CounselorShiftRequest `json:"shifts"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		schedule, err = services.SetCounselorShifts(r.Context(), counselorID, req.Shifts, time.Now())
+
+	default:
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidCounselorShift):
+			response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrCounselorNotFound):
+			response.ErrorResponse(w, http.StatusNotFound, "Counselor not found")
+		default:
+			logger.FromContext(r.Context()).Error("Error handling shifts of counselor %d: %v", counselorID, err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to process counselor shifts")
+		}
+		return
+	}
+	response.SuccessResponse(w, http.StatusOK, "Counselor shifts", schedule)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Coupons lists the coupon codes with their redemptions, or adds one
+// GET /coupons
+// POST /coupons {"code": "EARLYBIRD", "discount_type": "percent", "discount_value": 10, "max_redemptions": 100}
+func Coupons(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		coupons, err := services.ListCoupons(r.Context())
+		if err != nil {
+			logger.FromContext(r.Context()).Error("Error fetching coupons: %v", err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch coupons")
+			return
+		}
+		response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retrieved %d coupons", len(coupons)), coupons)
+
+	case http.MethodPost:
+		var req services.CouponRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		coupon, err := services.CreateCoupon(r.Context(), req)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidCoupon) {
+				response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			logger.FromContext(r.Context()).Error("Error creating coupon: %v", err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to create coupon")
+			return
+		}
+		response.SuccessResponse(w, http.StatusCreated, "Coupon created", coupon)
+
+	default:
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// UpdateCoupon replaces a coupon's discount, scope, validity, limits and active flag
+// PUT /coupons/{id}
+func UpdateCoupon(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid coupon ID")
+		return
+	}
+
+	var req services.CouponRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	coupon, err := services.UpdateCoupon(r.Context(), id, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidCoupon):
+			response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrCouponNotFound):
+			response.ErrorResponse(w, http.StatusNotFound, "Coupon not found")
+		default:
+			logger.FromContext(r.Context()).Error("Error updating coupon %d: %v", id, err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to update coupon")
+		}
+		return
+	}
+	response.SuccessResponse(w, http.StatusOK, "Coupon updated", coupon)
+}
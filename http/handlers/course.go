@@ -3,13 +3,14 @@ package handlers
 import (
 	"admission-module/db"
 	"admission-module/http/response"
+	"admission-module/logger"
 	"admission-module/models"
+	"admission-module/services"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
-	"time"
 )
 
 // GetCourses retrieves all active courses
@@ -19,7 +20,7 @@ func GetCourses(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := `SELECT id, name, description, fee, duration, is_active, created_at, updated_at FROM course WHERE is_active = 1 ORDER BY id ASC`
+	query := `SELECT id, name, description, fee, duration, COALESCE(batch, ''), is_active, created_at, updated_at FROM course WHERE is_active = 1 ORDER BY id ASC`
 	rows, err := db.DB.QueryContext(r.Context(), query)
 	if err != nil {
 		response.ErrorResponse(w, http.StatusInternalServerError, "Error fetching courses")
@@ -30,7 +31,7 @@ func GetCourses(w http.ResponseWriter, r *http.Request) {
 	courses := []models.Course{}
 	for rows.Next() {
 		var course models.Course
-		if err := rows.Scan(&course.ID, &course.Name, &course.Description, &course.Fee, &course.Duration, &course.IsActive, &course.CreatedAt, &course.UpdatedAt); err != nil {
+		if err := rows.Scan(&course.ID, &course.Name, &course.Description, &course.Fee, &course.Duration, &course.Batch, &course.IsActive, &course.CreatedAt, &course.UpdatedAt); err != nil {
 			response.ErrorResponse(w, http.StatusInternalServerError, "Error processing courses")
 			return
 		}
@@ -65,8 +66,8 @@ func GetCourseByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var course models.Course
-	query := `SELECT id, name, description, fee, duration, is_active, created_at, updated_at FROM course WHERE id = $1`
-	err = db.DB.QueryRowContext(r.Context(), query, courseID).Scan(&course.ID, &course.Name, &course.Description, &course.Fee, &course.Duration, &course.IsActive, &course.CreatedAt, &course.UpdatedAt)
+	query := `SELECT id, name, description, fee, duration, COALESCE(batch, ''), is_active, created_at, updated_at FROM course WHERE id = $1`
+	err = db.DB.QueryRowContext(r.Context(), query, courseID).Scan(&course.ID, &course.Name, &course.Description, &course.Fee, &course.Duration, &course.Batch, &course.IsActive, &course.CreatedAt, &course.UpdatedAt)
 	if err != nil {
 		response.ErrorResponse(w, http.StatusNotFound, "Course not found")
 		return
@@ -75,7 +76,7 @@ func GetCourseByID(w http.ResponseWriter, r *http.Request) {
 	response.SuccessResponse(w, http.StatusOK, "Course retrieved", course)
 }
 
-// CreateCourse creates a new course (admin endpoint)
+// CreateCourse creates a new course, or updates the existing course with the same name and duration (admin endpoint)
 func CreateCourse(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -87,6 +88,7 @@ func CreateCourse(w http.ResponseWriter, r *http.Request) {
 		Description string  `json:"description"`
 		Fee         float64 `json:"fee"`
 		Duration    string  `json:"duration"`
+		Batch       string  `json:"batch"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -99,20 +101,29 @@ func CreateCourse(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	now := time.Now()
-	var courseID int
-	query := `INSERT INTO course (name, description, fee, duration, is_active, created_at, updated_at) VALUES ($1, $2, $3, $4, 1, $5, $6) RETURNING id`
-	err := db.DB.QueryRowContext(r.Context(), query, req.Name, req.Description, req.Fee, req.Duration, now, now).Scan(&courseID)
+	result, err := services.UpsertCourse(r.Context(), services.UpsertCourseRequest{
+		Name:        req.Name,
+		Description: req.Description,
+		Fee:         req.Fee,
+		Duration:    req.Duration,
+		Batch:       req.Batch,
+	})
 	if err != nil {
-		log.Printf("Error creating course: %v", err)
+		logger.FromContext(r.Context()).Error("Error creating course: %v", err)
 		response.ErrorResponse(w, http.StatusInternalServerError, "Error creating course")
 		return
 	}
 
-	response.SuccessResponse(w, http.StatusCreated, "Course created successfully", map[string]interface{}{
-		"course_id": courseID,
+	statusCode, message := http.StatusCreated, "Course created successfully"
+	if !result.Created {
+		statusCode, message = http.StatusOK, "Course already exists, updated successfully"
+	}
+
+	response.SuccessResponse(w, statusCode, message, map[string]interface{}{
+		"course_id": result.CourseID,
 		"name":      req.Name,
 		"fee":       req.Fee,
+		"created":   result.Created,
 	})
 }
 
@@ -129,6 +140,7 @@ func UpdateCourse(w http.ResponseWriter, r *http.Request) {
 		Description string  `json:"description"`
 		Fee         float64 `json:"fee"`
 		Duration    string  `json:"duration"`
+		Batch       string  `json:"batch"`
 		IsActive    bool    `json:"is_active"`
 	}
 
@@ -142,30 +154,52 @@ func UpdateCourse(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isActiveInt := 0
-	if req.IsActive {
-		isActiveInt = 1
+	// The course as it was, for the audit log
+	before, err := services.PostgresRepositories().Courses.FindByID(r.Context(), req.ID)
+	if errors.Is(err, services.ErrCourseNotFound) {
+		response.ErrorResponse(w, http.StatusNotFound, "Course not found")
+		return
 	}
-
-	query := `UPDATE course SET name = $1, description = $2, fee = $3, duration = $4, is_active = $5, updated_at = $6 WHERE id = $7`
-	result, err := db.DB.ExecContext(r.Context(), query, req.Name, req.Description, req.Fee, req.Duration, isActiveInt, time.Now(), req.ID)
 	if err != nil {
-		log.Printf("Error updating course: %v", err)
+		logger.FromContext(r.Context()).Error("Error fetching course %d: %v", req.ID, err)
 		response.ErrorResponse(w, http.StatusInternalServerError, "Error updating course")
 		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		response.ErrorResponse(w, http.StatusInternalServerError, "Error checking update")
-		return
+	isActiveInt := 0
+	if req.IsActive {
+		isActiveInt = 1
 	}
 
-	if rowsAffected == 0 {
+	err = services.UpdateCourse(r.Context(), req.ID, services.UpsertCourseRequest{
+		Name:        req.Name,
+		Description: req.Description,
+		Fee:         req.Fee,
+		Duration:    req.Duration,
+		Batch:       req.Batch,
+	}, isActiveInt)
+	switch {
+	case errors.Is(err, services.ErrCourseNotFound):
 		response.ErrorResponse(w, http.StatusNotFound, "Course not found")
 		return
+	case errors.Is(err, services.ErrDuplicateCourse):
+		response.ErrorResponse(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		logger.FromContext(r.Context()).Error("Error updating course: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error updating course")
+		return
 	}
 
+	changes := models.AuditChanges{}
+	changes.Add("name", before.Name, req.Name)
+	changes.Add("description", before.Description, req.Description)
+	changes.Add("fee", before.Fee, req.Fee)
+	changes.Add("duration", before.Duration, req.Duration)
+	changes.Add("batch", before.Batch, req.Batch)
+	changes.Add("is_active", before.IsActive, isActiveInt)
+	recordAudit(r, services.AuditEntityCourse, strconv.Itoa(req.ID), services.AuditActionUpdate, changes)
+
 	response.SuccessResponse(w, http.StatusOK, "Course updated successfully", map[string]interface{}{
 		"course_id": req.ID,
 	})
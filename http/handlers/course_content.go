@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// GetCourseContentBlocks lists the email content blocks of a course
+// GET /courses/{id}/content-blocks
+func GetCourseContentBlocks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	courseID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || courseID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid course ID")
+		return
+	}
+
+	blocks, err := services.ListCourseContentBlocks(r.Context(), courseID)
+	if err != nil {
+		if errors.Is(err, services.ErrCourseNotFound) {
+			response.ErrorResponse(w, http.StatusNotFound, "Course not found")
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error fetching content blocks of course %d: %v", courseID, err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch content blocks")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retrieved %d content blocks", len(blocks)), blocks)
+}
+
+// CourseContentBlock creates, replaces or deletes one email content block of a course
+// PUT /courses/{id}/content-blocks/{key}
+// DELETE /courses/{id}/content-blocks/{key}
+func CourseContentBlock(w http.ResponseWriter, r *http.Request) {
+	courseID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || courseID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid course ID")
+		return
+	}
+	key := r.PathValue("key")
+
+	switch r.Method {
+	case http.MethodPut:
+		var req services.UpsertContentBlockRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		block, err := services.UpsertCourseContentBlock(r.Context(), courseID, key, req)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidContentBlock):
+				response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+			case errors.Is(err, services.ErrCourseNotFound):
+				response.ErrorResponse(w, http.StatusNotFound, "Course not found")
+			default:
+				logger.FromContext(r.Context()).Error("Error saving content block %s of course %d: %v", key, courseID, err)
+				response.ErrorResponse(w, http.StatusInternalServerError, "Failed to save content block")
+			}
+			return
+		}
+		response.SuccessResponse(w, http.StatusOK, "Content block saved", block)
+
+	case http.MethodDelete:
+		if err := services.DeleteCourseContentBlock(r.Context(), courseID, key); err != nil {
+			if errors.Is(err, services.ErrContentBlockNotFound) {
+				response.ErrorResponse(w, http.StatusNotFound, "Content block not found")
+				return
+			}
+			logger.FromContext(r.Context()).Error("Error deleting content block %s of course %d: %v", key, courseID, err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to delete content block")
+			return
+		}
+		response.SuccessResponse(w, http.StatusOK, "Content block deleted", map[string]interface{}{
+			"course_id": courseID,
+			"key":       key,
+		})
+
+	default:
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
@@ -2,41 +2,101 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 
+	"admission-module/config"
 	"admission-module/http/response"
 	"admission-module/logger"
+	"admission-module/models"
 	"admission-module/services"
+	"admission-module/services/kafka"
 )
 
-// GetDLQMessages retrieves unresolved DLQ messages
-// GET /api/dlq/messages?limit=50
+// GetDLQMessages lists DLQ messages, newest first. Unresolved messages are listed
+// unless resolved=true or resolved=all is given.
+// GET /api/dlq/messages?limit=50&offset=0&cursor=&topic=&resolved=&category=&from=YYYY-MM-DD&to=YYYY-MM-DD&q=
 func GetDLQMessages(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Get limit from query parameter, default to 50
-	limit := 50
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+	query := r.URL.Query()
+	filter := kafka.DLQFilter{
+		Topic:    query.Get("topic"),
+		Category: query.Get("category"),
+		Search:   strings.TrimSpace(query.Get("q")),
+		Limit:    50,
+	}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
 		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
-			limit = parsedLimit
+			filter.Limit = parsedLimit
+		}
+	}
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		parsedOffset, err := strconv.Atoi(offsetStr)
+		if err != nil || parsedOffset < 0 {
+			response.ErrorResponse(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		filter.Offset = parsedOffset
+	}
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		cursor, err := strconv.Atoi(cursorStr)
+		if err != nil || cursor <= 0 {
+			response.ErrorResponse(w, http.StatusBadRequest, "cursor must be a next_cursor value from a previous page")
+			return
 		}
+		filter.BeforeID = cursor
+	}
+
+	switch resolved := strings.ToLower(query.Get("resolved")); resolved {
+	case "", "false":
+		unresolved := false
+		filter.Resolved = &unresolved
+	case "true":
+		isResolved := true
+		filter.Resolved = &isResolved
+	case "all":
+	default:
+		response.ErrorResponse(w, http.StatusBadRequest, "resolved must be true, false or all")
+		return
+	}
+
+	if filter.Category != "" && !kafka.IsValidFailureCategory(filter.Category) {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid category. Use one of: "+strings.Join(kafka.FailureCategories, ", "))
+		return
 	}
 
-	messages, err := services.GetDLQMessages(limit)
+	from, to, err := parseReportDateRange(r)
 	if err != nil {
-		logger.Error("Error fetching DLQ messages: %v", err)
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter.From = from
+	if to != nil {
+		// to is inclusive: include the whole day
+		end := to.AddDate(0, 0, 1)
+		filter.To = &end
+	}
+
+	page, err := services.ListDLQMessages(filter)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching DLQ messages: %v", err)
 		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch DLQ messages: "+err.Error())
 		return
 	}
+	if page == nil {
+		page = &kafka.DLQPage{Messages: []map[string]interface{}{}}
+	}
 
-	response.SuccessResponse(w, http.StatusOK, "DLQ messages retrieved", map[string]interface{}{
-		"count": len(messages),
-		"data":  messages,
-	})
+	response.SuccessResponse(w, http.StatusOK, "DLQ messages retrieved", page)
 }
 
 // RetryDLQMessage retries processing of a specific DLQ message
@@ -54,7 +114,11 @@ func RetryDLQMessage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := services.RetryDLQMessage(messageID); err != nil {
-		logger.Error("Error retrying DLQ message %s: %v", messageID, err)
+		if errors.Is(err, kafka.ErrDLQMessageQuarantined) {
+			response.ErrorResponse(w, http.StatusConflict, "Message is quarantined; use POST /api/dlq/quarantine/{id}/force-retry")
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error retrying DLQ message %s: %v", messageID, err)
 		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to retry message: "+err.Error())
 		return
 	}
@@ -64,6 +128,89 @@ func RetryDLQMessage(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// GetQuarantinedDLQMessages lists unresolved messages that exhausted their retry budget.
+// The auto-retry loop skips them; they are reprocessed only by a force retry.
+// GET /api/dlq/quarantine?limit=50&offset=0&cursor=&topic=&q=
+func GetQuarantinedDLQMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := kafka.DLQFilter{
+		Topic:  query.Get("topic"),
+		Search: strings.TrimSpace(query.Get("q")),
+		Limit:  50,
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			filter.Limit = parsedLimit
+		}
+	}
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		parsedOffset, err := strconv.Atoi(offsetStr)
+		if err != nil || parsedOffset < 0 {
+			response.ErrorResponse(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		filter.Offset = parsedOffset
+	}
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		cursor, err := strconv.Atoi(cursorStr)
+		if err != nil || cursor <= 0 {
+			response.ErrorResponse(w, http.StatusBadRequest, "cursor must be a next_cursor value from a previous page")
+			return
+		}
+		filter.BeforeID = cursor
+	}
+
+	page, err := services.ListQuarantinedDLQMessages(filter)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching quarantined DLQ messages: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch quarantined messages: "+err.Error())
+		return
+	}
+	if page == nil {
+		page = &kafka.DLQPage{Messages: []map[string]interface{}{}}
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Quarantined DLQ messages retrieved", page)
+}
+
+// ForceRetryDLQMessage reprocesses a quarantined message once. A message that fails again
+// stays quarantined.
+// POST /api/dlq/quarantine/{id}/force-retry
+func ForceRetryDLQMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	messageID := r.PathValue("id")
+	processErr, err := services.ForceRetryDLQMessage(messageID)
+	if err != nil {
+		switch {
+		case errors.Is(err, kafka.ErrInvalidMessageID):
+			response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, kafka.ErrDLQMessageNotQuarantined):
+			response.ErrorResponse(w, http.StatusNotFound, "No unresolved quarantined message with this ID")
+		default:
+			logger.FromContext(r.Context()).Error("Error force retrying DLQ message %s: %v", messageID, err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to force retry message: "+err.Error())
+		}
+		return
+	}
+	if processErr != nil {
+		response.ErrorResponse(w, http.StatusUnprocessableEntity, "Message failed again and stays quarantined: "+processErr.Error())
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Quarantined message reprocessed and resolved", map[string]interface{}{
+		"messageId": messageID,
+	})
+}
+
 // ResolveDLQMessage marks a DLQ message as resolved
 // POST /api/dlq/messages/:messageId/resolve
 func ResolveDLQMessage(w http.ResponseWriter, r *http.Request) {
@@ -86,16 +233,265 @@ func ResolveDLQMessage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := services.ResolveDLQMessage(messageID, req.Notes); err != nil {
-		logger.Error("Error resolving DLQ message %s: %v", messageID, err)
+		logger.FromContext(r.Context()).Error("Error resolving DLQ message %s: %v", messageID, err)
 		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to resolve message: "+err.Error())
 		return
 	}
 
+	recordAudit(r, services.AuditEntityDLQMessage, messageID, services.AuditActionResolve, models.AuditChanges{
+		"resolved": {To: true},
+		"notes":    {To: req.Notes},
+	})
+
 	response.SuccessResponse(w, http.StatusOK, "Message marked as resolved", map[string]interface{}{
 		"messageId": messageID,
 	})
 }
 
+// ResolveDLQMessages resolves several DLQ messages at once with a shared note and failure category
+// POST /api/dlq/messages/resolve-batch
+func ResolveDLQMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		MessageIDs []string `json:"message_ids"`
+		Notes      string   `json:"notes"`
+		Category   string   `json:"category"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(req.MessageIDs) == 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "message_ids is required")
+		return
+	}
+	if req.Notes == "" {
+		req.Notes = "Manually resolved"
+	}
+
+	resolved, err := services.ResolveDLQMessages(req.MessageIDs, req.Notes, req.Category)
+	if err != nil {
+		switch {
+		case errors.Is(err, kafka.ErrInvalidFailureCategory):
+			response.ErrorResponse(w, http.StatusBadRequest, "category must be one of: "+strings.Join(kafka.FailureCategories, ", "))
+		case errors.Is(err, kafka.ErrInvalidMessageID):
+			response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		default:
+			logger.FromContext(r.Context()).Error("Error resolving DLQ messages: %v", err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to resolve messages: "+err.Error())
+		}
+		return
+	}
+
+	for _, messageID := range req.MessageIDs {
+		recordAudit(r, services.AuditEntityDLQMessage, messageID, services.AuditActionResolve, models.AuditChanges{
+			"resolved": {To: true},
+			"notes":    {To: req.Notes},
+			"category": {To: req.Category},
+		})
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Messages marked as resolved", map[string]interface{}{
+		"requested": len(req.MessageIDs),
+		"resolved":  resolved,
+		"category":  req.Category,
+	})
+}
+
+// RetryAllDLQMessages retries all unresolved DLQ messages, optionally of one topic, in batches
+// POST /api/dlq/retry-all {"topic": "email.send", "max": 500}
+func RetryAllDLQMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Topic string `json:"topic"`
+		Max   int    `json:"max"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	if req.Max < 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "max must not be negative")
+		return
+	}
+
+	result, err := services.RetryAllDLQMessages(r.Context(), req.Topic, req.Max)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error bulk retrying DLQ messages: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to retry messages: "+err.Error())
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retried %d messages: %d succeeded, %d failed", result.Processed, result.Succeeded, result.Failed), result)
+}
+
+// ResolveAllDLQMessages resolves all unresolved DLQ messages, optionally of one topic, in batches
+// POST /api/dlq/resolve-all {"topic": "email.send", "notes": "...", "category": "smtp-outage"}
+func ResolveAllDLQMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Topic    string `json:"topic"`
+		Notes    string `json:"notes"`
+		Category string `json:"category"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Notes == "" {
+		req.Notes = "Bulk resolved"
+	}
+
+	result, err := services.ResolveAllDLQMessages(r.Context(), req.Topic, req.Notes, req.Category)
+	if err != nil {
+		if errors.Is(err, kafka.ErrInvalidFailureCategory) {
+			response.ErrorResponse(w, http.StatusBadRequest, "category must be one of: "+strings.Join(kafka.FailureCategories, ", "))
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error bulk resolving DLQ messages: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to resolve messages: "+err.Error())
+		return
+	}
+
+	// One entry for the whole sweep: the resolved messages are not listed individually
+	recordAudit(r, services.AuditEntityDLQMessage, "all", services.AuditActionResolve, models.AuditChanges{
+		"resolved_count": {To: result.Succeeded},
+		"topic":          {To: req.Topic},
+		"notes":          {To: req.Notes},
+		"category":       {To: req.Category},
+	})
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Resolved %d messages", result.Succeeded), result)
+}
+
+// ArchiveDLQMessages archives resolved DLQ messages older than older_than_days
+// (default DLQ_ARCHIVE_AFTER_DAYS) and removes them from the DLQ
+// POST /api/dlq/archive {"older_than_days": 30}
+func ArchiveDLQMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		OlderThanDays *int `json:"older_than_days"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	days := config.AppConfig.DLQArchiveAfterDays
+	if req.OlderThanDays != nil {
+		if *req.OlderThanDays < 1 {
+			response.ErrorResponse(w, http.StatusBadRequest, "older_than_days must be at least 1")
+			return
+		}
+		days = *req.OlderThanDays
+	}
+
+	cutoff := services.DLQArchiveCutoff(days)
+	archived, err := services.ArchiveResolvedDLQMessages(r.Context(), cutoff)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error archiving DLQ messages: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to archive messages: "+err.Error())
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Archived %d resolved messages", archived), map[string]interface{}{
+		"archived":        archived,
+		"resolved_before": cutoff,
+	})
+}
+
+// GetArchivedDLQMessage returns an archived DLQ message (redacted, as it was when resolved)
+// GET /api/dlq/archive/{id}
+func GetArchivedDLQMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	message, err := services.GetArchivedDLQMessage(r.PathValue("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, kafka.ErrInvalidMessageID):
+			response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, kafka.ErrArchivedDLQMessageNotFound):
+			response.ErrorResponse(w, http.StatusNotFound, err.Error())
+		default:
+			logger.FromContext(r.Context()).Error("Error reading archived DLQ message: %v", err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to read archived message")
+		}
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Archived DLQ message retrieved", message)
+}
+
+// RevealDLQMessage returns the original, unredacted payload of a DLQ message.
+// Admin-only (X-Admin-Token); every reveal is recorded with who asked and why.
+// POST /admin/dlq/messages/{id}/reveal
+func RevealDLQMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		RequestedBy string `json:"requested_by"`
+		Reason      string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.RequestedBy = strings.TrimSpace(req.RequestedBy)
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.RequestedBy == "" || req.Reason == "" {
+		response.ErrorResponse(w, http.StatusBadRequest, "requested_by and reason are required")
+		return
+	}
+
+	messageID := r.PathValue("id")
+	payload, err := services.RevealDLQMessage(messageID, req.RequestedBy, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, kafka.ErrInvalidMessageID):
+			response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, kafka.ErrDLQMessageNotFound):
+			response.ErrorResponse(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, kafka.ErrDLQPayloadUnavailable):
+			response.ErrorResponse(w, http.StatusGone, err.Error())
+		default:
+			logger.FromContext(r.Context()).Error("Error revealing DLQ message %s: %v", messageID, err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to reveal message")
+		}
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "DLQ payload revealed (access logged)", map[string]interface{}{
+		"message_id": messageID,
+		"value":      payload,
+	})
+}
+
 // GetDLQStats retrieves statistics about DLQ messages
 // GET /api/dlq/stats
 func GetDLQStats(w http.ResponseWriter, r *http.Request) {
@@ -106,10 +502,67 @@ func GetDLQStats(w http.ResponseWriter, r *http.Request) {
 
 	stats, err := services.GetDLQStats()
 	if err != nil {
-		logger.Error("Error fetching DLQ statistics: %v", err)
+		logger.FromContext(r.Context()).Error("Error fetching DLQ statistics: %v", err)
 		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch DLQ statistics: "+err.Error())
 		return
 	}
+	if stats == nil {
+		stats = map[string]interface{}{}
+	}
+	stats["alert"] = services.GetDLQAlertState()
 
 	response.SuccessResponse(w, http.StatusOK, "DLQ statistics", stats)
 }
+
+// DLQPolicies lists or updates per-topic DLQ retry policies
+// GET /api/dlq/policies
+// PUT /api/dlq/policies
+func DLQPolicies(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		policies, err := services.GetDLQRetryPolicies()
+		if err != nil {
+			logger.FromContext(r.Context()).Error("Error fetching DLQ retry policies: %v", err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch DLQ retry policies: "+err.Error())
+			return
+		}
+
+		data := make([]kafka.RetryPolicy, 0, len(policies))
+		for _, policy := range policies {
+			data = append(data, policy)
+		}
+		sort.Slice(data, func(i, j int) bool { return data[i].Topic < data[j].Topic })
+
+		response.SuccessResponse(w, http.StatusOK, "DLQ retry policies retrieved", map[string]interface{}{
+			"count": len(data),
+			"data":  data,
+		})
+	case http.MethodPut:
+		var policy kafka.RetryPolicy
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if policy.Topic == "" {
+			response.ErrorResponse(w, http.StatusBadRequest, "topic is required (use \"*\" for the default policy)")
+			return
+		}
+		if policy.MaxRetries < 0 || policy.BackoffSeconds < 0 {
+			response.ErrorResponse(w, http.StatusBadRequest, "max_retries and backoff_seconds must not be negative")
+			return
+		}
+		if policy.BackoffMultiplier < 1 {
+			policy.BackoffMultiplier = 1
+		}
+
+		if err := services.UpsertDLQRetryPolicy(policy); err != nil {
+			logger.FromContext(r.Context()).Error("Error saving DLQ retry policy for %s: %v", policy.Topic, err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to save DLQ retry policy: "+err.Error())
+			return
+		}
+
+		response.SuccessResponse(w, http.StatusOK, "DLQ retry policy saved", policy)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/models"
+	"admission-module/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// RequestDocument queues an export/report to be generated in the background
+// POST /documents
+func RequestDocument(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req struct {
+		Type        string          `json:"type"`
+		Format      string          `json:"format"`
+		Params      json.RawMessage `json:"params,omitempty"`
+		RequestedBy string          `json:"requested_by"`
+		NotifyEmail string          `json:"notify_email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Format == "" {
+		req.Format = services.ExportFormatCSV
+	}
+
+	job := &models.DocumentJob{
+		JobType:     req.Type,
+		Format:      req.Format,
+		Params:      req.Params,
+		RequestedBy: req.RequestedBy,
+		NotifyEmail: req.NotifyEmail,
+	}
+	if err := services.EnqueueDocumentJob(r.Context(), job); err != nil {
+		if errors.Is(err, services.ErrUnsupportedDocument) {
+			response.ErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Unsupported document type %q or format %q", req.Type, req.Format))
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error queueing document job: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error queueing document job")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusAccepted, "Document generation queued", job)
+}
+
+// GetDocumentJob returns the status of a document job
+// GET /documents/{id}
+func GetDocumentJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	jobID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || jobID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid document job ID")
+		return
+	}
+
+	job, err := services.GetDocumentJob(r.Context(), jobID)
+	if err != nil {
+		if errors.Is(err, services.ErrDocumentJobNotFound) {
+			response.ErrorResponse(w, http.StatusNotFound, "Document job not found")
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error fetching document job %d: %v", jobID, err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error fetching document job")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Document job retrieved", job)
+}
+
+// DownloadDocument streams the generated file of a completed document job
+// GET /documents/{id}/download
+func DownloadDocument(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	jobID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || jobID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid document job ID")
+		return
+	}
+
+	job, file, err := services.OpenDocument(r.Context(), jobID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrDocumentJobNotFound):
+			response.ErrorResponse(w, http.StatusNotFound, "Document job not found")
+		case errors.Is(err, services.ErrDocumentNotReady):
+			response.ErrorResponse(w, http.StatusConflict, "Document is not ready yet")
+		default:
+			logger.FromContext(r.Context()).Error("Error opening document %d: %v", jobID, err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Error opening document")
+		}
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", job.FileName))
+	if job.Format == services.ExportFormatCSV {
+		w.Header().Set("Content-Type", "text/csv")
+	} else {
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	}
+
+	if _, err := io.Copy(w, file); err != nil {
+		logger.FromContext(r.Context()).Error("Error streaming document %d: %v", jobID, err)
+	}
+}
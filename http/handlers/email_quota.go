@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/services"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// GetEmailOverflow lists the recipients that reached the daily email cap, per day, with
+// how many emails they were not sent
+// GET /admin/email-overflow?days=7&limit=200
+func GetEmailOverflow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	days := 7
+	if daysStr := query.Get("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed <= 0 {
+			response.ErrorResponse(w, http.StatusBadRequest, "days must be a positive number")
+			return
+		}
+		days = parsed
+	}
+	limit := 200
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			limit = parsedLimit
+		}
+	}
+
+	overflow, err := services.ListEmailOverflow(r.Context(), time.Now().AddDate(0, 0, -days), limit)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching email overflow: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch email overflow")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("%d recipients reached the daily email cap in the last %d days", len(overflow), days), overflow)
+}
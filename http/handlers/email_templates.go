@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/services"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// CheckEmailTemplates renders every email template with sample data and reports broken
+// variables, unresolved tokens, unbalanced HTML and missing templates. With course_id
+// the acceptance and enrollment emails use that course's content blocks.
+// GET /admin/email-templates/check?course_id=3
+func CheckEmailTemplates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	courseID := 0
+	if value := r.URL.Query().Get("course_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			response.ErrorResponse(w, http.StatusBadRequest, "Invalid course_id")
+			return
+		}
+		courseID = id
+	}
+
+	report, err := services.CheckEmailTemplates(r.Context(), courseID)
+	if err != nil {
+		if errors.Is(err, services.ErrCourseNotFound) {
+			response.ErrorResponse(w, http.StatusNotFound, "Course not found")
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error checking email templates: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to check email templates")
+		return
+	}
+
+	message := fmt.Sprintf("All %d email templates passed", report.Checked)
+	if report.Failed > 0 {
+		message = fmt.Sprintf("%d of %d email templates failed", report.Failed, report.Checked)
+	}
+	response.SuccessResponse(w, http.StatusOK, message, report)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/models"
+	"admission-module/services"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// GetEnrollmentSyncs lists the LMS/ERP handoffs of enrolled students
+// GET /enrollment-sync?status=FAILED&limit=50
+func GetEnrollmentSyncs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	status := strings.ToUpper(r.URL.Query().Get("status"))
+	switch status {
+	case "", models.EnrollmentSyncPending, models.EnrollmentSyncSynced, models.EnrollmentSyncFailed:
+	default:
+		response.ErrorResponse(w, http.StatusBadRequest, "status must be PENDING, SYNCED or FAILED")
+		return
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			limit = parsedLimit
+		}
+	}
+
+	syncs, err := services.GetEnrollmentSyncs(r.Context(), status, limit)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching enrollment syncs: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch enrollment syncs")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retrieved %d enrollment syncs", len(syncs)), syncs)
+}
+
+// RetryEnrollmentSync queues a student's LMS/ERP handoff again with a fresh attempt budget
+// POST /enrollment-sync/{student_id}/retry
+func RetryEnrollmentSync(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	studentID, err := strconv.Atoi(r.PathValue("student_id"))
+	if err != nil || studentID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+
+	if err := services.RetryEnrollmentSync(r.Context(), studentID); err != nil {
+		if errors.Is(err, services.ErrEnrollmentSyncNotFound) {
+			response.ErrorResponse(w, http.StatusNotFound, "No enrollment handoff for this student")
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error retrying enrollment sync of student %d: %v", studentID, err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error retrying enrollment sync")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Enrollment sync queued", map[string]interface{}{
+		"student_id": studentID,
+	})
+}
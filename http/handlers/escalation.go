@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// GetEscalationQueue lists stale leads that could not be reassigned automatically
+// GET /admin/escalations
+func GetEscalationQueue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	escalations, err := services.GetAdminEscalationQueue(r.Context())
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching escalation queue: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error fetching escalation queue")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retrieved %d escalations", len(escalations)), escalations)
+}
+
+// ResolveEscalation removes a lead from the admin escalation queue
+// POST /admin/escalations/{id}/resolve
+func ResolveEscalation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	escalationID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || escalationID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid escalation ID")
+		return
+	}
+
+	var req struct {
+		Notes string `json:"notes"`
+	}
+	// Notes are optional; an empty body is fine
+	_ = json.NewDecoder(r.Body).Decode(&req)
+
+	if err := services.ResolveEscalation(r.Context(), escalationID, req.Notes); err != nil {
+		if errors.Is(err, services.ErrEscalationNotFound) {
+			response.ErrorResponse(w, http.StatusNotFound, "Escalation not found or already resolved")
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error resolving escalation %d: %v", escalationID, err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error resolving escalation")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Escalation resolved", map[string]interface{}{
+		"escalation_id": escalationID,
+	})
+}
@@ -0,0 +1,217 @@
+// Package handlertest provides fake services and request helpers for exercising
+// the HTTP handlers without a database, Kafka or Razorpay.
+package handlertest
+
+import (
+	"admission-module/http/handlers"
+	"admission-module/http/response"
+	"admission-module/services"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+var (
+	_ handlers.PaymentService     = (*FakePaymentService)(nil)
+	_ handlers.ApplicationService = (*FakeApplicationService)(nil)
+)
+
+// SavedPayment records one SavePaymentRecord call on FakePaymentService
+type SavedPayment struct {
+	StudentID int
+	OrderID   string
+	Request   services.InitiatePaymentRequest
+}
+
+// FakePaymentService implements handlers.PaymentService with canned results.
+// Zero values describe the happy path: eligible, request passed through unchanged,
+// order "order_test" created and saved.
+type FakePaymentService struct {
+	mu sync.Mutex
+
+	Ineligible     bool
+	IneligibleWhy  string
+	EligibilityErr error
+	PrepareErr     error
+	OrderErr       error
+	SaveErr        error
+	VerifyErr      error
+
+	// Status, PaymentType and StudentID answer GetPaymentStatus; StatusErr makes it fail
+	Status      string
+	PaymentType string
+	StudentID   int
+	StatusErr   error
+
+	Saved    []SavedPayment
+	Verified []services.VerifyPaymentRequest
+}
+
+func (f *FakePaymentService) CheckPaymentEligibility(ctx context.Context, studentID int, paymentType string, courseID *int) (bool, string, error) {
+	if f.EligibilityErr != nil {
+		return false, f.IneligibleWhy, f.EligibilityErr
+	}
+	return !f.Ineligible, f.IneligibleWhy, nil
+}
+
+func (f *FakePaymentService) ValidateAndPreparePayment(ctx context.Context, req services.InitiatePaymentRequest) (*services.InitiatePaymentRequest, error) {
+	if f.PrepareErr != nil {
+		return nil, f.PrepareErr
+	}
+	if req.Amount == 0 && req.PaymentType == services.PaymentTypeRegistration {
+		req.Amount = services.RegistrationFee
+	}
+	return &req, nil
+}
+
+func (f *FakePaymentService) CreateRazorpayOrder(ctx context.Context, req services.InitiatePaymentRequest) (*services.InitiatePaymentResponse, error) {
+	if f.OrderErr != nil {
+		return nil, f.OrderErr
+	}
+	return &services.InitiatePaymentResponse{
+		OrderID:  "order_test",
+		Amount:   req.Amount,
+		Currency: "INR",
+		Receipt:  "receipt_test",
+	}, nil
+}
+
+func (f *FakePaymentService) SavePaymentRecord(ctx context.Context, studentID int, orderID string, req services.InitiatePaymentRequest) error {
+	if f.SaveErr != nil {
+		return f.SaveErr
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Saved = append(f.Saved, SavedPayment{StudentID: studentID, OrderID: orderID, Request: req})
+	return nil
+}
+
+func (f *FakePaymentService) VerifyPayment(ctx context.Context, req services.VerifyPaymentRequest) (*services.VerifyPaymentResult, error) {
+	if f.VerifyErr != nil {
+		return nil, f.VerifyErr
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Verified = append(f.Verified, req)
+	return &services.VerifyPaymentResult{}, nil
+}
+
+func (f *FakePaymentService) GetPaymentStatus(ctx context.Context, orderID string) (string, string, int, error) {
+	if f.StatusErr != nil {
+		return "", "", 0, f.StatusErr
+	}
+	return f.Status, f.PaymentType, f.StudentID, nil
+}
+
+// FakeApplicationService implements handlers.ApplicationService with canned results.
+// Zero values describe a student whose registration fee is PAID and whose accept/reject/waitlist succeeds.
+type FakeApplicationService struct {
+	mu sync.Mutex
+
+	// RegistrationStatus defaults to "PAID"; RegistrationErr makes the lookup fail
+	// (use services.ErrPaymentNotFound for a student without a registration payment)
+	RegistrationStatus string
+	RegistrationErr    error
+
+	AcceptResult *services.AcceptApplicationResult
+	AcceptErr    error
+	RejectResult *services.RejectApplicationResult
+	RejectErr    error
+	WaitlistErr  error
+
+	Accepted   []services.AcceptApplicationRequest
+	Rejected   []services.RejectApplicationRequest
+	Waitlisted []services.WaitlistApplicationRequest
+	Notified   int
+}
+
+func (f *FakeApplicationService) GetRegistrationPaymentStatus(ctx context.Context, studentID int) (string, error) {
+	if f.RegistrationErr != nil {
+		return "", f.RegistrationErr
+	}
+	if f.RegistrationStatus == "" {
+		return services.PaymentStatusPaid, nil
+	}
+	return f.RegistrationStatus, nil
+}
+
+func (f *FakeApplicationService) AcceptApplication(ctx context.Context, req services.AcceptApplicationRequest) (*services.AcceptApplicationResult, error) {
+	if f.AcceptErr != nil {
+		return nil, f.AcceptErr
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Accepted = append(f.Accepted, req)
+	if f.AcceptResult != nil {
+		return f.AcceptResult, nil
+	}
+	return &services.AcceptApplicationResult{CourseID: req.SelectedCourseID}, nil
+}
+
+func (f *FakeApplicationService) RejectApplication(ctx context.Context, req services.RejectApplicationRequest) (*services.RejectApplicationResult, error) {
+	if f.RejectErr != nil {
+		return nil, f.RejectErr
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Rejected = append(f.Rejected, req)
+	if f.RejectResult != nil {
+		return f.RejectResult, nil
+	}
+	return &services.RejectApplicationResult{}, nil
+}
+
+func (f *FakeApplicationService) WaitlistApplication(ctx context.Context, req services.WaitlistApplicationRequest) (*services.WaitlistApplicationResult, error) {
+	if f.WaitlistErr != nil {
+		return nil, f.WaitlistErr
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Waitlisted = append(f.Waitlisted, req)
+	return &services.WaitlistApplicationResult{CourseID: req.SelectedCourseID, Position: len(f.Waitlisted)}, nil
+}
+
+func (f *FakeApplicationService) NotifyAccepted(result *services.AcceptApplicationResult) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Notified++
+	return nil
+}
+
+func (f *FakeApplicationService) NotifyRejected(result *services.RejectApplicationResult) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Notified++
+	return nil
+}
+
+func (f *FakeApplicationService) NotifyWaitlisted(result *services.WaitlistApplicationResult) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Notified++
+	return nil
+}
+
+// Do sends a request with an optional JSON body to a handler and records the response
+func Do(handler http.HandlerFunc, method, target string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, target, &buf)
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+// Decode parses a recorded response in the standard response envelope
+func Decode(rec *httptest.ResponseRecorder) (response.StandardResponse, error) {
+	var body response.StandardResponse
+	err := json.NewDecoder(rec.Body).Decode(&body)
+	return body, err
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"admission-module/db"
+	"admission-module/http/response"
+	"admission-module/models"
+	"admission-module/services"
+	"net/http"
+)
+
+// Readyz reports whether the service can take traffic and which delivery channels are available.
+// A disabled email channel degrades the service but does not make it unready.
+// GET /readyz
+func Readyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if db.DB == nil || db.DB.PingContext(r.Context()) != nil {
+		response.ErrorResponse(w, http.StatusServiceUnavailable, "Database is not reachable")
+		return
+	}
+
+	emailChannel := map[string]interface{}{
+		"enabled": services.IsEmailChannelEnabled(),
+	}
+	if pending, err := services.GetPendingOutboxCount(r.Context()); err == nil {
+		emailChannel["pending_outbox"] = pending
+	}
+
+	status := "ready"
+	if !services.IsEmailChannelEnabled() {
+		status = "degraded"
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Service is "+status, map[string]interface{}{
+		"status": status,
+		"channels": map[string]interface{}{
+			"email": emailChannel,
+			"kafka": map[string]interface{}{
+				"connected":        services.IsConnected(),
+				"consumer_running": services.IsConsumerRunning(),
+				"consumed_topics":  services.ConsumedTopics(),
+				"async_delivery":   services.GetAsyncDeliveryStats(),
+			},
+		},
+	})
+}
+
+// Live is the liveness probe: it answers as long as the process can serve HTTP and
+// checks no dependency, so a database or Kafka outage never gets the pod restarted
+// GET /live
+func Live(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	response.SuccessResponse(w, http.StatusOK, "Service is alive", services.Liveness())
+}
+
+// Ready is the readiness probe: 503 until schema migrations are applied and the Kafka
+// consumer is started, and whenever the database is unreachable
+// GET /ready
+func Ready(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	report := services.CheckReadiness(r.Context())
+	if report.Status != models.HealthStatusUp {
+		response.SendJSON(w, http.StatusServiceUnavailable, response.StandardResponse{
+			Status:  "error",
+			Message: "Service is not ready",
+			Data:    report,
+		})
+		return
+	}
+	response.SuccessResponse(w, http.StatusOK, "Service is ready", report)
+}
+
+// Health reports the state of each dependency for load balancers and uptime monitors.
+// Responds with 503 when the database is down, or with strict=true when any configured
+// dependency is down; a Kafka or SMTP outage otherwise only marks the service DEGRADED.
+// GET /health?strict=true
+func Health(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	report := services.CheckHealth(r.Context())
+	strict := r.URL.Query().Get("strict") == "true"
+	if report.Status == models.HealthStatusDown || (strict && report.Status == models.HealthStatusDegraded) {
+		response.SendJSON(w, http.StatusServiceUnavailable, response.StandardResponse{
+			Status:  "error",
+			Message: "Service is " + report.Status,
+			Data:    report,
+		})
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Service is "+report.Status, report)
+}
+
+// Doctor runs the environment self-checks and returns a pass/fail matrix.
+// Responds with 503 when any check fails.
+// GET /doctor
+func Doctor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	report := services.RunDoctor(r.Context())
+	if !report.Healthy {
+		response.SendJSON(w, http.StatusServiceUnavailable, response.StandardResponse{
+			Status:  "error",
+			Message: "One or more checks failed",
+			Data:    report,
+		})
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "All checks passed", report)
+}
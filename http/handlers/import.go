@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/models"
+	"admission-module/services"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// GetImportJobs lists recent bulk lead upload runs
+// GET /import-jobs?limit=50
+func GetImportJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			limit = parsedLimit
+		}
+	}
+
+	jobs, err := services.GetImportHistory(r.Context(), limit)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching import jobs: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch import jobs")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retrieved %d import jobs", len(jobs)), jobs)
+}
+
+// GetInboundImports lists recent spreadsheets received through the import mailbox
+// GET /import-jobs/inbound?limit=50
+func GetInboundImports(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			limit = parsedLimit
+		}
+	}
+
+	imports, err := services.GetInboundImports(r.Context(), limit)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching inbound imports: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch inbound imports")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retrieved %d inbound imports", len(imports)), imports)
+}
+
+// RollbackImportJob soft-deletes the leads created by an import and decrements counselor counts
+// POST /import-jobs/{id}/rollback
+func RollbackImportJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	importID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || importID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid import job ID")
+		return
+	}
+
+	result, err := services.RollbackImport(r.Context(), importID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrImportNotFound):
+			response.ErrorResponse(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, services.ErrImportAlreadyRolledBack):
+			response.ErrorResponse(w, http.StatusConflict, err.Error())
+		default:
+			logger.FromContext(r.Context()).Error("Error rolling back import %d: %v", importID, err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to roll back import job")
+		}
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Rolled back import #%d: %d leads deleted, %d skipped",
+		importID, result.DeletedCount, result.SkippedCount), result)
+}
+
+// ImportDecisions applies application decisions taken offline, read from an uploaded
+// sheet with student_id, decision and course columns. Each row is validated like
+// /application-action and applied on its own; dry_run=true only validates.
+// POST /admin/decisions/import?dry_run=true (multipart: file, decided_by, report_to)
+func ImportDecisions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid file")
+		return
+	}
+	defer file.Close()
+
+	tempFile, err := os.CreateTemp("", "decisions_*.xlsx")
+	if err != nil {
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error processing file")
+		return
+	}
+	defer os.Remove(tempFile.Name())
+	_, err = io.Copy(tempFile, file)
+	tempFile.Close()
+	if err != nil {
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error saving file")
+		return
+	}
+
+	rows, err := services.ParseDecisionSheet(tempFile.Name())
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := services.ImportDecisions(r.Context(), rows, services.DecisionImportOptions{
+		DecidedBy: r.FormValue("decided_by"),
+		DryRun:    r.URL.Query().Get("dry_run") == "true",
+		ReportTo:  r.FormValue("report_to"),
+	})
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error importing decisions: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to import decisions")
+		return
+	}
+
+	for _, row := range result.Rows {
+		status := row.Decision
+		switch row.Action {
+		case models.DecisionRowApplied:
+		case models.DecisionRowPendingApproval:
+			status = services.ApplicationStatusPendingApproval
+		default:
+			continue
+		}
+		changes := models.AuditChanges{
+			"application_status": {From: row.PreviousStatus, To: status},
+			"source":             {To: "decision_import"},
+			"decided_by":         {To: result.DecidedBy},
+		}
+		if row.CourseID != 0 {
+			changes["selected_course_id"] = models.AuditChange{To: row.CourseID}
+		}
+		recordAudit(r, services.AuditEntityLead, strconv.Itoa(row.StudentID), services.AuditActionStatusChange, changes)
+	}
+
+	message := fmt.Sprintf("Applied %d of %d decisions, %d failed", result.AppliedCount, result.TotalCount, result.FailedCount)
+	if result.DryRun {
+		message = fmt.Sprintf("Dry run: %d of %d decisions valid", result.TotalCount-result.FailedCount, result.TotalCount)
+	}
+	response.SuccessResponse(w, http.StatusOK, message, result)
+}
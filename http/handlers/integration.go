@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// LeadIntegrations lists the inbound lead integrations with their health, or adds one
+// GET /integrations
+// POST /integrations {"name": "Zapier - Facebook Lead Ads", "lead_source": "facebook", "expected_cadence_minutes": 360}
+func LeadIntegrations(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		integrations, err := services.ListLeadIntegrations(r.Context(), time.Now())
+		if err != nil {
+			logger.FromContext(r.Context()).Error("Error fetching lead integrations: %v", err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch lead integrations")
+			return
+		}
+		response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retrieved %d integrations", len(integrations)), integrations)
+
+	case http.MethodPost:
+		var req services.LeadIntegrationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		integration, err := services.CreateLeadIntegration(r.Context(), req)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidLeadIntegration) {
+				response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			logger.FromContext(r.Context()).Error("Error creating lead integration: %v", err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to create lead integration")
+			return
+		}
+		response.SuccessResponse(w, http.StatusCreated, "Lead integration created", integration)
+
+	default:
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// UpdateLeadIntegration replaces a lead integration's match, cadence and active flag
+// PUT /integrations/{id}
+func UpdateLeadIntegration(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid integration ID")
+		return
+	}
+
+	var req services.LeadIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	integration, err := services.UpdateLeadIntegration(r.Context(), id, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidLeadIntegration):
+			response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrLeadIntegrationNotFound):
+			response.ErrorResponse(w, http.StatusNotFound, "Lead integration not found")
+		default:
+			logger.FromContext(r.Context()).Error("Error updating lead integration %d: %v", id, err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to update lead integration")
+		}
+		return
+	}
+	response.SuccessResponse(w, http.StatusOK, "Lead integration updated", integration)
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/services"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// GetPendingInterviews lists students whose interview scheduling has not finished, oldest first
+// GET /admin/interviews/pending?days=7
+func GetPendingInterviews(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	days := 7
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed <= 0 {
+			response.ErrorResponse(w, http.StatusBadRequest, "days must be a positive number")
+			return
+		}
+		days = parsed
+	}
+
+	pending, err := services.ListPendingInterviews(r.Context(), time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching pending interviews: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch pending interviews")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Found %d pending interviews in the last %d days", len(pending), days), pending)
+}
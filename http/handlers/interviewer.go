@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Interviewers lists interviewers with their current load, or adds one
+// GET /interviewers
+// POST /interviewers
+func Interviewers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		interviewers, err := services.ListInterviewers(r.Context(), time.Now())
+		if err != nil {
+			logger.FromContext(r.Context()).Error("Error fetching interviewers: %v", err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch interviewers")
+			return
+		}
+		response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retrieved %d interviewers", len(interviewers)), interviewers)
+
+	case http.MethodPost:
+		var req services.InterviewerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		interviewer, err := services.CreateInterviewer(r.Context(), req)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidInterviewer) {
+				response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			logger.FromContext(r.Context()).Error("Error creating interviewer: %v", err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to create interviewer")
+			return
+		}
+		response.SuccessResponse(w, http.StatusCreated, "Interviewer created", interviewer)
+
+	default:
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// UpdateInterviewer replaces an interviewer's details, daily cap, programs and skills
+// PUT /interviewers/{id}
+func UpdateInterviewer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid interviewer ID")
+		return
+	}
+
+	var req services.InterviewerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	interviewer, err := services.UpdateInterviewer(r.Context(), id, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInterviewer):
+			response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrInterviewerNotFound):
+			response.ErrorResponse(w, http.StatusNotFound, "Interviewer not found")
+		default:
+			logger.FromContext(r.Context()).Error("Error updating interviewer %d: %v", id, err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to update interviewer")
+		}
+		return
+	}
+	response.SuccessResponse(w, http.StatusOK, "Interviewer updated", interviewer)
+}
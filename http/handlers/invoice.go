@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/services"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// GetStudentInvoices lists the course fee invoices of a student for the finance team
+// GET /students/{id}/invoices
+func GetStudentInvoices(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	studentID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || studentID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+
+	invoices, err := services.ListStudentInvoices(r.Context(), studentID)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error listing invoices of student %d: %v", studentID, err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to list invoices")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("%d invoices", len(invoices)), invoices)
+}
+
+// DownloadInvoice streams the HTML document of an invoice
+// GET /invoices/{id}/download
+func DownloadInvoice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	invoiceID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || invoiceID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid invoice ID")
+		return
+	}
+
+	invoice, file, err := services.OpenInvoice(r.Context(), invoiceID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvoiceNotFound) {
+			response.ErrorResponse(w, http.StatusNotFound, "Invoice not found")
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error opening invoice %d: %v", invoiceID, err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error opening invoice")
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", services.InvoiceFileName(invoice)))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := io.Copy(w, file); err != nil {
+		logger.FromContext(r.Context()).Error("Error streaming invoice %d: %v", invoiceID, err)
+	}
+}
+
+// ExportInvoices exports the invoices of a financial year for accounting, as xlsx (default)
+// or csv
+// GET /invoices/export?financial_year=2026-27&format=csv
+func ExportInvoices(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = services.ExportFormatXLSX
+	}
+	if format != services.ExportFormatXLSX && format != services.ExportFormatCSV {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid format. Must be xlsx or csv")
+		return
+	}
+	year := r.URL.Query().Get("financial_year")
+	if year == "" {
+		year = services.CurrentFinancialYear()
+	}
+
+	invoices, err := services.ListInvoicesForExport(r.Context(), year)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFinancialYear) {
+			response.ErrorResponse(w, http.StatusBadRequest, "Invalid financial_year. Use the form 2026-27")
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error exporting invoices of %s: %v", year, err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error fetching invoices")
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("invoices_%s.%s", year, format)))
+	if format == services.ExportFormatCSV {
+		w.Header().Set("Content-Type", "text/csv")
+	} else {
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	}
+
+	if err := services.WriteInvoicesExport(w, format, invoices); err != nil {
+		// Headers are already sent, so the client will see a truncated file
+		logger.FromContext(r.Context()).Error("Error writing invoice export: %v", err)
+	}
+}
+
+// DownloadPaymentReceipt streams the PDF receipt of a captured payment, by its order ID
+// GET /payments/{id}/receipt
+func DownloadPaymentReceipt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	orderID := r.PathValue("id")
+	if orderID == "" {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+
+	receipt, file, err := services.OpenPaymentReceipt(r.Context(), orderID)
+	if err != nil {
+		if errors.Is(err, services.ErrReceiptNotFound) {
+			response.ErrorResponse(w, http.StatusNotFound, "Receipt not found")
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error opening receipt of order %s: %v", orderID, err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error opening receipt")
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", services.ReceiptFileName(receipt)))
+	w.Header().Set("Content-Type", "application/pdf")
+	if _, err := io.Copy(w, file); err != nil {
+		logger.FromContext(r.Context()).Error("Error streaming receipt of order %s: %v", orderID, err)
+	}
+}
@@ -2,27 +2,31 @@ package handlers
 
 import (
 	"admission-module/db"
+	"admission-module/http/middleware"
 	resp "admission-module/http/response"
+	"admission-module/logger"
 	"admission-module/models"
 	"admission-module/services"
 	"admission-module/utils"
-	"context"
-	"database/sql"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"os"
 	"time"
 )
 
+// LeadService serves the lead APIs
 type LeadService struct {
-	db *sql.DB
+	leads services.LeadRepository
 }
 
-func NewLeadService(database *sql.DB) *LeadService {
-	return &LeadService{db: database}
+// NewLeadService creates a LeadService reading leads from the given repository
+func NewLeadService(leads services.LeadRepository) *LeadService {
+	return &LeadService{leads: leads}
 }
 
 func (s *LeadService) UploadLeads(w http.ResponseWriter, r *http.Request) {
@@ -33,10 +37,17 @@ func (s *LeadService) UploadLeads(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
+	// What to do with rows matching an existing lead: skip (default), update or merge
+	duplicatePolicy, err := services.ParseDuplicatePolicy(r.FormValue("duplicate_policy"))
+	if err != nil {
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Extract and validate file upload
-	file, _, err := r.FormFile("file")
+	file, header, err := r.FormFile("file")
 	if err != nil {
-		log.Printf("Error getting form file: %v", err)
+		logger.FromContext(r.Context()).Error("Error getting form file: %v", err)
 		respondError(w, "Invalid file", http.StatusBadRequest)
 		return
 	}
@@ -54,122 +65,132 @@ func (s *LeadService) UploadLeads(w http.ResponseWriter, r *http.Request) {
 		os.Remove(tempFilePath)
 	}()
 
-	// Copy uploaded file to temp location
-	if _, err = io.Copy(tempFile, file); err != nil {
+	// Copy uploaded file to temp location, hashing it on the way
+	hasher := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(tempFile, hasher), file); err != nil {
 		respondError(w, "Error saving file", http.StatusInternalServerError)
 		return
 	}
+	fileHash := hex.EncodeToString(hasher.Sum(nil))
+
+	// Reject re-uploads of an already imported file unless explicitly forced
+	previousImport, err := services.FindImportByHash(ctx, fileHash)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error checking import history: %v", err)
+		respondError(w, "Error checking import history", http.StatusInternalServerError)
+		return
+	}
+	force := r.URL.Query().Get("force") == "true"
+	if previousImport != nil && !force {
+		respondError(w, fmt.Sprintf("This file was already imported on %s (import #%d). Re-upload with ?force=true to import it again",
+			previousImport.CreatedAt.Format(time.RFC3339), previousImport.ID), http.StatusConflict)
+		return
+	}
 
 	if err := tempFile.Close(); err != nil {
 		// Silent fail on temp file close
 	}
 
-	// Parse Excel file
-	leads, err := services.ParseExcel(tempFilePath)
+	// Import the leads and record the run so later uploads of the same file can be detected
+	result, err := services.ImportLeadsFile(ctx, tempFilePath, &models.ImportHistory{
+		FileName:        header.Filename,
+		FileHash:        fileHash,
+		UploadedBy:      r.FormValue("uploaded_by"),
+		DuplicatePolicy: duplicatePolicy,
+	})
 	if err != nil {
-		respondError(w, "Error parsing Excel: "+err.Error(), http.StatusBadRequest)
+		respondError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Remove duplicates within the uploaded file
-	leads = utils.DeduplicateLeads(leads)
-
-	// Process each lead and track results
-	successCount := 0
-	failedLeads := []map[string]string{}
+	// Build response
+	response := map[string]interface{}{
+		"message":          fmt.Sprintf("Successfully uploaded %d leads", result.SuccessCount),
+		"success_count":    result.SuccessCount,
+		"updated_count":    result.UpdatedCount,
+		"skipped_count":    result.SkippedCount,
+		"failed_count":     result.FailedCount,
+		"total_count":      result.TotalCount,
+		"duplicate_policy": result.DuplicatePolicy,
+		"rows":             result.Rows,
+	}
 
-	for i, lead := range leads {
-		if err := s.processAndInsertLead(ctx, &lead); err != nil {
-			failedLeads = append(failedLeads, map[string]string{
-				"row":   fmt.Sprintf("%d", i+2),
-				"email": lead.Email,
-				"phone": lead.Phone,
-				"error": err.Error(),
-			})
-			continue
-		}
-		successCount++
+	if len(result.FailedLeads) > 0 {
+		response["failed_leads"] = result.FailedLeads
 	}
 
-	// Build response
-	response := map[string]interface{}{
-		"message":       fmt.Sprintf("Successfully uploaded %d leads", successCount),
-		"success_count": successCount,
-		"failed_count":  len(failedLeads),
-		"total_count":   len(leads),
+	if previousImport != nil {
+		response["warning"] = fmt.Sprintf("This file was already imported on %s (import #%d)",
+			previousImport.CreatedAt.Format(time.RFC3339), previousImport.ID)
 	}
 
-	if len(failedLeads) > 0 {
-		response["failed_leads"] = failedLeads
+	if result.ImportID != 0 {
+		response["import_id"] = result.ImportID
 	}
 
 	respondJSON(w, http.StatusOK, response)
 }
 
-func (s *LeadService) processAndInsertLead(ctx context.Context, lead *models.Lead) error {
-	// Set timestamps
-	now := time.Now()
-	lead.CreatedAt = now
-	lead.UpdatedAt = now
-
-	// Validate lead data
-	if err := utils.ValidateLead(lead); err != nil {
-		return fmt.Errorf("validation failed: %w", err)
+func (s *LeadService) GetLeads(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
-	// Start database transaction
-	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	ctx := r.Context()
+
+	// Parse and validate query parameters
+	timeParams, err := utils.ParseTimeFilters(r)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		respondError(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	defer tx.Rollback()
 
-	// Check for duplicate lead
-	exists, err := utils.LeadExists(ctx, tx, lead.Email, lead.Phone)
+	leads, err := s.leads.List(ctx, services.LeadListFilter{
+		CreatedAfter:  timeParams.CreatedAfter,
+		CreatedBefore: timeParams.CreatedBefore,
+		Unassigned:    r.URL.Query().Get("unassigned") == "true",
+	})
 	if err != nil {
-		return fmt.Errorf("error checking duplicate: %w", err)
-	}
-	if exists {
-		return fmt.Errorf("lead already exists with this email or phone")
+		logger.FromContext(ctx).Error("Error fetching leads: %v", err)
+		respondError(w, "Error fetching leads", http.StatusInternalServerError)
+		return
 	}
 
-	// Assign counselor if not already assigned
-	if lead.CounsellorID == nil {
-		counselorID, err := utils.GetAvailableCounselorID(ctx, tx, lead.LeadSource)
-		if err != nil {
-			return fmt.Errorf("error assigning counselor: %w", err)
-		}
-		lead.CounsellorID = counselorID
+	// Attach last-note metadata in a single query
+	leadIDs := make([]int64, len(leads))
+	for i := range leads {
+		leadIDs[i] = int64(leads[i].ID)
 	}
-
-	// Insert lead into database
-	leadID, err := utils.InsertLead(ctx, tx, lead)
+	lastNotes, err := services.GetLastNoteSummaries(ctx, leadIDs)
 	if err != nil {
-		return fmt.Errorf("error inserting lead: %w", err)
+		logger.FromContext(r.Context()).Warn("Error fetching last note summaries: %v", err)
 	}
-	lead.ID = int(leadID)
 
-	// Update counselor assignment count atomically
-	if lead.CounsellorID != nil {
-		if err := utils.UpdateCounselorAssignmentCount(ctx, tx, *lead.CounsellorID); err != nil {
-			return fmt.Errorf("error updating counselor count: %w", err)
+	// Convert leads to the response shape of the requested API version
+	convert := leadResponseConverters[middleware.APIVersionFromContext(ctx)]
+	withCounselor := r.URL.Query().Get("include") == "counselor"
+	leadResponses := make([]interface{}, len(leads))
+	for i := range leads {
+		var lastNote *models.LeadNoteSummary
+		if summary, ok := lastNotes[leads[i].ID]; ok {
+			lastNote = &summary
 		}
+		leadResponses[i] = convert(&leads[i], lastNote, withCounselor)
 	}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	// Send welcome email asynchronously
-	if err := services.SendWelcomeEmailWithCounselorInfo(ctx, lead); err != nil {
-		// Don't fail the operation if email fails
+	response := GetLeadsResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("Retrieved %d leads successfully", len(leads)),
+		Count:   len(leads),
+		Data:    leadResponses,
 	}
-
-	return nil
+	respondJSON(w, http.StatusOK, response)
 }
 
-func (s *LeadService) GetLeads(w http.ResponseWriter, r *http.Request) {
+// ExportLeads streams the filtered lead list as an XLSX (default) or CSV file
+// GET /leads/export?format=xlsx|csv&created_after=...&created_before=...
+func (s *LeadService) ExportLeads(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		respondError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -177,75 +198,40 @@ func (s *LeadService) GetLeads(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
-	// Parse and validate query parameters
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = services.ExportFormatXLSX
+	}
+	if format != services.ExportFormatXLSX && format != services.ExportFormatCSV {
+		respondError(w, "Invalid format. Must be xlsx or csv", http.StatusBadRequest)
+		return
+	}
+
 	timeParams, err := utils.ParseTimeFilters(r)
 	if err != nil {
 		respondError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Build dynamic query with filters
-	query := `
-		SELECT 
-			id, name, email, phone, education, lead_source, 
-			counselor_id, meet_link, 
-			application_status, registration_payment_id, selected_course_id, 
-			course_payment_id, interview_scheduled_at, created_at, updated_at 
-		FROM student_lead 
-		WHERE 1=1`
-
-	args := []interface{}{}
-	argCount := 0
-
-	// Add time-based filters dynamically
-	if timeParams.CreatedAfter != nil {
-		argCount++
-		query += fmt.Sprintf(" AND created_at >= $%d", argCount)
-		args = append(args, *timeParams.CreatedAfter)
-	}
-
-	if timeParams.CreatedBefore != nil {
-		argCount++
-		query += fmt.Sprintf(" AND created_at <= $%d", argCount)
-		args = append(args, *timeParams.CreatedBefore)
-	}
-
-	query += " ORDER BY id ASC"
-
-	// Execute query
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	leads, err := services.GetLeadExportRows(ctx, timeParams.CreatedAfter, timeParams.CreatedBefore)
 	if err != nil {
+		logger.FromContext(r.Context()).Error("Error exporting leads: %v", err)
 		respondError(w, "Error fetching leads", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 
-	// Scan results
-	leads := []models.Lead{}
-	for rows.Next() {
-		lead, err := utils.ScanLead(rows)
-		if err != nil {
-			respondError(w, "Error processing leads", http.StatusInternalServerError)
-			return
-		}
-		leads = append(leads, lead)
+	fileName := fmt.Sprintf("leads_%s.%s", time.Now().Format("20060102_150405"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	if format == services.ExportFormatCSV {
+		w.Header().Set("Content-Type", "text/csv")
+	} else {
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
 	}
 
-	if err = rows.Err(); err != nil {
-		respondError(w, "Error processing leads", http.StatusInternalServerError)
-		return
+	if err := services.WriteLeadsExport(w, format, leads); err != nil {
+		// Headers are already sent, so the client will see a truncated file
+		logger.FromContext(r.Context()).Error("Error writing lead export: %v", err)
 	}
-
-	// Convert leads to response format
-	leadResponses := utils.ConvertLeadsToResponse(leads)
-
-	response := GetLeadsResponse{
-		Status:  "success",
-		Message: fmt.Sprintf("Retrieved %d leads successfully", len(leads)),
-		Count:   len(leads),
-		Data:    leadResponses,
-	}
-	respondJSON(w, http.StatusOK, response)
 }
 
 func (s *LeadService) CreateLead(w http.ResponseWriter, r *http.Request) {
@@ -264,10 +250,22 @@ func (s *LeadService) CreateLead(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Process and insert lead
-	if err := s.processAndInsertLead(ctx, &lead); err != nil {
+	if err := services.CreateLead(ctx, &lead); err != nil {
+		// A partial match is held for a counselor rather than created or rejected
+		var reviewErr *services.LeadReviewError
+		if errors.As(err, &reviewErr) {
+			respondJSON(w, http.StatusAccepted, map[string]interface{}{
+				"status":    "pending_review",
+				"message":   "Lead matches an existing lead on email or phone and was queued for review",
+				"review_id": reviewErr.ReviewID,
+				"reason":    reviewErr.Reason,
+			})
+			return
+		}
+
 		// Determine appropriate HTTP status code based on error type
 		statusCode := http.StatusInternalServerError
-		if err.Error() == "lead already exists with this email or phone" {
+		if errors.Is(err, services.ErrLeadExists) {
 			statusCode = http.StatusConflict
 		} else if len(err.Error()) > 10 && err.Error()[:10] == "validation" {
 			statusCode = http.StatusBadRequest
@@ -278,7 +276,7 @@ func (s *LeadService) CreateLead(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Fetch counselor name for response
-	counselorName := utils.GetCounselorNameByID(ctx, s.db, lead.CounsellorID)
+	counselorName := utils.GetCounselorNameByID(ctx, db.Reader(), lead.CounsellorID)
 
 	response := CreateLeadResponse{
 		Message:       "Lead created successfully",
@@ -291,10 +289,32 @@ func (s *LeadService) CreateLead(w http.ResponseWriter, r *http.Request) {
 }
 
 type GetLeadsResponse struct {
-	Status  string                `json:"status"`
-	Message string                `json:"message"`
-	Count   int                   `json:"count"`
-	Data    []models.LeadResponse `json:"data"`
+	Status  string        `json:"status"`
+	Message string        `json:"message"`
+	Count   int           `json:"count"`
+	Data    []interface{} `json:"data"`
+}
+
+// leadResponseConverters render a lead, with its last note, in the response shape of
+// each API version (models.LeadResponse for v1, models.LeadResponseV2 for v2). The
+// assigned counselor with their contact details is embedded when withCounselor is set.
+var leadResponseConverters = map[string]func(lead *models.Lead, lastNote *models.LeadNoteSummary, withCounselor bool) interface{}{
+	middleware.APIVersionV1: func(lead *models.Lead, lastNote *models.LeadNoteSummary, withCounselor bool) interface{} {
+		response := lead.ToResponse()
+		response.LastNote = lastNote
+		if withCounselor {
+			response.Counselor = lead.Counselor
+		}
+		return response
+	},
+	middleware.APIVersionV2: func(lead *models.Lead, lastNote *models.LeadNoteSummary, withCounselor bool) interface{} {
+		response := lead.ToResponseV2()
+		response.LastNote = lastNote
+		if withCounselor {
+			response.Counselor = lead.Counselor
+		}
+		return response
+	},
 }
 
 type CreateLeadResponse struct {
@@ -314,27 +334,36 @@ func respondError(w http.ResponseWriter, message string, status int) {
 
 var service *LeadService
 
-func InitHandlers(database *sql.DB) {
-	service = NewLeadService(database)
+// InitHandlers sets the LeadService behind the lead routes, e.g. one over fake repositories.
+// By default the lead list is read from the replica (services.ReportingRepositories).
+func InitHandlers(leads services.LeadRepository) {
+	service = NewLeadService(leads)
 }
 
 func UploadLeads(w http.ResponseWriter, r *http.Request) {
 	if service == nil {
-		service = NewLeadService(db.DB)
+		service = NewLeadService(services.ReportingRepositories().Leads)
 	}
 	service.UploadLeads(w, r)
 }
 
 func GetLeads(w http.ResponseWriter, r *http.Request) {
 	if service == nil {
-		service = NewLeadService(db.DB)
+		service = NewLeadService(services.ReportingRepositories().Leads)
 	}
 	service.GetLeads(w, r)
 }
 
+func ExportLeads(w http.ResponseWriter, r *http.Request) {
+	if service == nil {
+		service = NewLeadService(services.ReportingRepositories().Leads)
+	}
+	service.ExportLeads(w, r)
+}
+
 func CreateLead(w http.ResponseWriter, r *http.Request) {
 	if service == nil {
-		service = NewLeadService(db.DB)
+		service = NewLeadService(services.ReportingRepositories().Leads)
 	}
 	service.CreateLead(w, r)
 }
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/models"
+	"admission-module/services"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// MergeLead folds a duplicate lead into another lead
+// POST /leads/{id}/merge?into={keepId}
+func MergeLead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	duplicateID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || duplicateID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid lead ID")
+		return
+	}
+	keepID, err := strconv.Atoi(r.URL.Query().Get("into"))
+	if err != nil || keepID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid or missing 'into' lead ID")
+		return
+	}
+
+	result, err := services.MergeLeads(r.Context(), duplicateID, keepID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrLeadNotFound):
+			response.ErrorResponse(w, http.StatusNotFound, "Lead not found")
+		case errors.Is(err, services.ErrMergeSameLead):
+			response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrMergeHasPayments):
+			response.ErrorResponse(w, http.StatusConflict, err.Error())
+		default:
+			logger.FromContext(r.Context()).Error("Error merging lead %d into %d: %v", duplicateID, keepID, err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Error merging leads")
+		}
+		return
+	}
+
+	recordAudit(r, services.AuditEntityLead, strconv.Itoa(duplicateID), services.AuditActionMerge, models.AuditChanges{
+		"merged_into": {To: keepID},
+	})
+
+	response.SuccessResponse(w, http.StatusOK, "Leads merged", result)
+}
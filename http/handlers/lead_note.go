@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/models"
+	"admission-module/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LeadNotes records or lists counselor interactions for a lead
+// GET  /leads/{id}/notes
+// POST /leads/{id}/notes
+func LeadNotes(w http.ResponseWriter, r *http.Request) {
+	leadID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || leadID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid lead ID")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		getLeadNotes(w, r, leadID)
+	case http.MethodPost:
+		createLeadNote(w, r, leadID)
+	default:
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func getLeadNotes(w http.ResponseWriter, r *http.Request, leadID int) {
+	notes, err := services.GetLeadNotes(r.Context(), leadID)
+	if err != nil {
+		if errors.Is(err, services.ErrLeadNotFound) {
+			response.ErrorResponse(w, http.StatusNotFound, "Lead not found")
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error fetching notes for lead %d: %v", leadID, err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error fetching lead notes")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retrieved %d notes", len(notes)), notes)
+}
+
+func createLeadNote(w http.ResponseWriter, r *http.Request, leadID int) {
+	var req struct {
+		CounselorID *int64     `json:"counselor_id,omitempty"`
+		NoteType    string     `json:"note_type"`
+		Content     string     `json:"content"`
+		FollowUpAt  *time.Time `json:"follow_up_at,omitempty"`
+		// NotifyByEmail also emails @mentioned counselors (they always get an in-app notification)
+		NotifyByEmail bool `json:"notify_by_email"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+
+	req.Content = strings.TrimSpace(req.Content)
+	if req.Content == "" {
+		response.ErrorResponse(w, http.StatusBadRequest, "content is required")
+		return
+	}
+
+	req.NoteType = strings.ToUpper(req.NoteType)
+	if req.NoteType != "" && !models.IsValidNoteType(req.NoteType) {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid note_type. Must be CALL, EMAIL, MEETING, FOLLOW_UP or NOTE")
+		return
+	}
+
+	note := &models.LeadNote{
+		LeadID:      leadID,
+		CounselorID: req.CounselorID,
+		NoteType:    req.NoteType,
+		Content:     req.Content,
+		FollowUpAt:  req.FollowUpAt,
+	}
+
+	if err := services.CreateLeadNote(r.Context(), note, req.NotifyByEmail); err != nil {
+		var unknownMentions *services.UnknownMentionError
+		switch {
+		case errors.Is(err, services.ErrLeadNotFound):
+			response.ErrorResponse(w, http.StatusNotFound, "Lead not found")
+			return
+		case errors.Is(err, services.ErrCounselorNotFound):
+			response.ErrorResponse(w, http.StatusBadRequest, "Counselor not found")
+			return
+		case errors.Is(err, services.ErrMentionForbidden):
+			response.ErrorResponse(w, http.StatusForbidden, err.Error())
+			return
+		case errors.As(err, &unknownMentions):
+			response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error creating note for lead %d: %v", leadID, err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error saving lead note")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusCreated, "Note added successfully", note)
+}
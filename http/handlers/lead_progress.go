@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/services"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// GetLeadProgress returns the lead's application steps and the next action
+// GET /leads/{id}/progress
+func GetLeadProgress(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	leadID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || leadID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid lead ID")
+		return
+	}
+
+	progress, err := services.GetLeadProgress(r.Context(), leadID)
+	if err != nil {
+		if errors.Is(err, services.ErrLeadNotFound) {
+			response.ErrorResponse(w, http.StatusNotFound, "Lead not found")
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error fetching progress for lead %d: %v", leadID, err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error fetching lead progress")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Lead progress retrieved", progress)
+}
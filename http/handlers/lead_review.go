@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/models"
+	"admission-module/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// GetLeadReviews lists leads held for review because they share only the email or only
+// the phone of an existing lead
+// GET /lead-reviews?status=PENDING&limit=50
+func GetLeadReviews(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	status := strings.ToUpper(r.URL.Query().Get("status"))
+	switch status {
+	case "":
+		status = models.LeadReviewPending
+	case "ALL":
+		status = ""
+	case models.LeadReviewPending, models.LeadReviewApproved, models.LeadReviewMerged:
+	default:
+		response.ErrorResponse(w, http.StatusBadRequest, "status must be PENDING, APPROVED, MERGED or ALL")
+		return
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			limit = parsedLimit
+		}
+	}
+
+	reviews, err := services.GetLeadReviews(r.Context(), status, limit)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching lead reviews: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch lead reviews")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retrieved %d lead reviews", len(reviews)), reviews)
+}
+
+// leadReviewRequest is the body of the approve and merge actions
+type leadReviewRequest struct {
+	LeadID     int    `json:"lead_id"` // merge target; optional when there is a single match
+	ResolvedBy string `json:"resolved_by"`
+}
+
+// ApproveLeadReview creates a reviewed submission as a new lead
+// POST /lead-reviews/{id}/approve
+func ApproveLeadReview(w http.ResponseWriter, r *http.Request) {
+	reviewID, req, ok := decodeLeadReviewAction(w, r)
+	if !ok {
+		return
+	}
+
+	lead, err := services.ApproveLeadReview(r.Context(), reviewID, req.ResolvedBy)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrLeadReviewNotFound):
+			response.ErrorResponse(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, services.ErrLeadExists):
+			response.ErrorResponse(w, http.StatusConflict, err.Error())
+		default:
+			logger.FromContext(r.Context()).Error("Error approving lead review %d: %v", reviewID, err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to approve lead review")
+		}
+		return
+	}
+
+	recordAudit(r, services.AuditEntityLead, strconv.Itoa(lead.ID), services.AuditActionReviewApproved, models.AuditChanges{
+		"review_id":   {To: reviewID},
+		"resolved_by": {To: req.ResolvedBy},
+	})
+
+	response.SuccessResponse(w, http.StatusCreated, "Lead created from review", map[string]interface{}{
+		"review_id":     reviewID,
+		"lead_id":       lead.ID,
+		"counsellor_id": lead.CounsellorID,
+	})
+}
+
+// MergeLeadReview folds a reviewed submission into an existing lead
+// POST /lead-reviews/{id}/merge
+func MergeLeadReview(w http.ResponseWriter, r *http.Request) {
+	reviewID, req, ok := decodeLeadReviewAction(w, r)
+	if !ok {
+		return
+	}
+
+	leadID, updated, err := services.MergeLeadReview(r.Context(), reviewID, req.LeadID, req.ResolvedBy)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrLeadReviewNotFound):
+			response.ErrorResponse(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, services.ErrLeadNotFound):
+			response.ErrorResponse(w, http.StatusNotFound, "Lead not found")
+		case errors.Is(err, services.ErrLeadReviewTargetRequired):
+			response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		default:
+			logger.FromContext(r.Context()).Error("Error merging lead review %d: %v", reviewID, err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to merge lead review")
+		}
+		return
+	}
+	if updated == nil {
+		updated = []string{}
+	}
+	recordAudit(r, services.AuditEntityLead, strconv.Itoa(leadID), services.AuditActionReviewMerged, models.AuditChanges{
+		"review_id":      {To: reviewID},
+		"updated_fields": {To: updated},
+		"resolved_by":    {To: req.ResolvedBy},
+	})
+
+	response.SuccessResponse(w, http.StatusOK, "Lead review merged", map[string]interface{}{
+		"review_id":      reviewID,
+		"lead_id":        leadID,
+		"updated_fields": updated,
+	})
+}
+
+// decodeLeadReviewAction checks the method and reads the review ID and optional body
+func decodeLeadReviewAction(w http.ResponseWriter, r *http.Request) (int, leadReviewRequest, bool) {
+	var req leadReviewRequest
+	if r.Method != http.MethodPost {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return 0, req, false
+	}
+
+	reviewID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || reviewID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid review ID")
+		return 0, req, false
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return 0, req, false
+	}
+	return reviewID, req, true
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/services"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// GetLeadTimeline returns the lead's admission journey built from Kafka events
+// GET /leads/{id}/timeline
+func GetLeadTimeline(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	leadID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || leadID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid lead ID")
+		return
+	}
+
+	events, err := services.GetLeadTimeline(r.Context(), leadID)
+	if err != nil {
+		if errors.Is(err, services.ErrLeadNotFound) {
+			response.ErrorResponse(w, http.StatusNotFound, "Lead not found")
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error fetching timeline for lead %d: %v", leadID, err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error fetching lead timeline")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retrieved %d events", len(events)), events)
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"admission-module/db"
 	"admission-module/services"
+	"admission-module/utils"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -21,7 +22,7 @@ func ScheduleMeet(w http.ResponseWriter, r *http.Request) {
 
 	// Get student email
 	var email string
-	err := db.DB.QueryRow("SELECT email FROM student_lead WHERE id = $1", req.StudentID).Scan(&email)
+	err := db.DB.QueryRowContext(r.Context(), "SELECT email FROM student_lead WHERE id = $1", req.StudentID).Scan(utils.DecryptedPII(&email))
 	if err != nil {
 		http.Error(w, "Student not found", http.StatusNotFound)
 		return
@@ -29,7 +30,7 @@ func ScheduleMeet(w http.ResponseWriter, r *http.Request) {
 
 	// REQUIREMENT: Check if registration fee is PAID before allowing interview scheduling
 	var regPaymentStatus string
-	err = db.DB.QueryRow("SELECT status FROM registration_payment WHERE student_id = $1", req.StudentID).Scan(&regPaymentStatus)
+	err = db.DB.QueryRowContext(r.Context(), "SELECT status FROM registration_payment WHERE student_id = $1", req.StudentID).Scan(&regPaymentStatus)
 	if err != nil {
 		http.Error(w, "Registration payment record not found. Please complete registration fee payment first", http.StatusBadRequest)
 		return
@@ -40,21 +41,21 @@ func ScheduleMeet(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Schedule meet
-	meetLink, err := services.ScheduleMeet(req.StudentID, email)
+	meetLink, err := services.ScheduleMeet(r.Context(), req.StudentID, email)
 	if err != nil {
 		http.Error(w, "Error scheduling meet: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Note: meet_link is already stored in ScheduleMeet(), just update application_status
-	_, err = db.DB.Exec("UPDATE student_lead SET application_status = 'MEETING_SCHEDULED', updated_at = CURRENT_TIMESTAMP WHERE id = $1", req.StudentID)
+	_, err = db.DB.ExecContext(r.Context(), "UPDATE student_lead SET application_status = 'MEETING_SCHEDULED', updated_at = CURRENT_TIMESTAMP WHERE id = $1", req.StudentID)
 	if err != nil {
 		http.Error(w, "Error updating lead", http.StatusInternalServerError)
 		return
 	}
 
 	// Send email
-	_ = services.SendEmail(email, "Google Meet Scheduled", "Your meet link: "+meetLink)
+	_ = services.SendCategorizedEmail(services.EmailCategoryTransactional, email, "Google Meet Scheduled", "Your meet link: "+meetLink)
 
 	// Publish to Kafka
 	evt := map[string]interface{}{
@@ -66,7 +67,7 @@ func ScheduleMeet(w http.ResponseWriter, r *http.Request) {
 		"scheduled_at": time.Now().Unix(),
 	}
 	evtJSON, _ := json.Marshal(evt)
-	services.Publish("meetings", fmt.Sprintf("student-%d", req.StudentID), string(evtJSON))
+	services.Publish(r.Context(), "meetings", fmt.Sprintf("student-%d", req.StudentID), string(evtJSON))
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"meet_link": meetLink})
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/services"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// GetCounselorNotifications lists in-app notifications for a counselor
+// GET /counselors/{id}/notifications?unread=true&limit=50
+func GetCounselorNotifications(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	counselorID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || counselorID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid counselor ID")
+		return
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			limit = parsedLimit
+		}
+	}
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	notifications, err := services.GetCounselorNotifications(r.Context(), counselorID, unreadOnly, limit)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching notifications for counselor %d: %v", counselorID, err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error fetching notifications")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retrieved %d notifications", len(notifications)), notifications)
+}
+
+// MarkNotificationRead marks a counselor's notification as read
+// POST /counselors/{id}/notifications/{notificationId}/read
+func MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	counselorID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || counselorID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid counselor ID")
+		return
+	}
+	notificationID, err := strconv.Atoi(r.PathValue("notificationId"))
+	if err != nil || notificationID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid notification ID")
+		return
+	}
+
+	if err := services.MarkNotificationRead(r.Context(), counselorID, notificationID); err != nil {
+		if errors.Is(err, services.ErrNotificationNotFound) {
+			response.ErrorResponse(w, http.StatusNotFound, "Notification not found")
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error marking notification %d as read: %v", notificationID, err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error updating notification")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Notification marked as read", map[string]interface{}{
+		"notification_id": notificationID,
+	})
+}
@@ -1,15 +1,43 @@
 package handlers
 
 import (
+	"admission-module/http/middleware"
 	resp "admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/models"
 	"admission-module/services"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
-// InitiatePaymentHandler handles payment initiation requests
+// PaymentService is the part of services.PaymentService the payment handlers use
+type PaymentService interface {
+	CheckPaymentEligibility(ctx context.Context, studentID int, paymentType string, courseID *int) (canPay bool, reason string, err error)
+	ValidateAndPreparePayment(ctx context.Context, req services.InitiatePaymentRequest) (*services.InitiatePaymentRequest, error)
+	CreateRazorpayOrder(ctx context.Context, req services.InitiatePaymentRequest) (*services.InitiatePaymentResponse, error)
+	SavePaymentRecord(ctx context.Context, studentID int, orderID string, req services.InitiatePaymentRequest) error
+	VerifyPayment(ctx context.Context, req services.VerifyPaymentRequest) (*services.VerifyPaymentResult, error)
+	GetPaymentStatus(ctx context.Context, orderID string) (status string, paymentType string, studentID int, err error)
+}
+
+// PaymentHandler serves the payment APIs
+type PaymentHandler struct {
+	payments PaymentService
+}
+
+// NewPaymentHandler creates a PaymentHandler backed by the given payment service
+func NewPaymentHandler(payments PaymentService) *PaymentHandler {
+	return &PaymentHandler{payments: payments}
+}
+
+// InitiatePayment handles payment initiation requests
 // This handler supports both registration and course fee payments
-func InitiatePaymentHandler(w http.ResponseWriter, r *http.Request) {
+// POST /initiate-payment
+func (h *PaymentHandler) InitiatePayment(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		resp.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
@@ -20,6 +48,7 @@ func InitiatePaymentHandler(w http.ResponseWriter, r *http.Request) {
 		Amount      float64 `json:"amount"`
 		PaymentType string  `json:"payment_type"`
 		CourseID    *int    `json:"course_id,omitempty"`
+		CouponCode  string  `json:"coupon_code,omitempty"`
 	}
 
 	// Parse request
@@ -45,10 +74,8 @@ func InitiatePaymentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	paymentService := services.NewPaymentService()
-
 	// Check payment eligibility
-	canPay, reason, err := paymentService.CheckPaymentEligibility(req.StudentID, req.PaymentType, req.CourseID)
+	canPay, reason, err := h.payments.CheckPaymentEligibility(r.Context(), req.StudentID, req.PaymentType, req.CourseID)
 	if err != nil {
 		resp.ErrorResponse(w, http.StatusBadRequest, reason)
 		return
@@ -59,11 +86,12 @@ func InitiatePaymentHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate and prepare payment
-	preparedReq, err := paymentService.ValidateAndPreparePayment(services.InitiatePaymentRequest{
+	preparedReq, err := h.payments.ValidateAndPreparePayment(r.Context(), services.InitiatePaymentRequest{
 		StudentID:   req.StudentID,
 		Amount:      req.Amount,
 		PaymentType: req.PaymentType,
 		CourseID:    req.CourseID,
+		CouponCode:  strings.TrimSpace(req.CouponCode),
 	})
 	if err != nil {
 		resp.ErrorResponse(w, http.StatusBadRequest, err.Error())
@@ -71,17 +99,22 @@ func InitiatePaymentHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create Razorpay order
-	orderResp, err := paymentService.CreateRazorpayOrder(*preparedReq)
+	orderResp, err := h.payments.CreateRazorpayOrder(r.Context(), *preparedReq)
 	if err != nil {
+		if errors.Is(err, services.ErrCouponNotApplicable) {
+			resp.ErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		resp.ErrorResponse(w, http.StatusInternalServerError, "Error creating payment order: "+err.Error())
 		return
 	}
 
 	// Save payment record
-	if err := paymentService.SavePaymentRecord(req.StudentID, orderResp.OrderID, *preparedReq); err != nil {
+	if err := h.payments.SavePaymentRecord(r.Context(), req.StudentID, orderResp.OrderID, *preparedReq); err != nil {
 		// Determine if this is a client error or server error
 		if err.Error() == "registration payment already completed - student has already paid registration fee" ||
-			err.Error() == "course payment already completed - student has already paid fee for course" {
+			strings.HasPrefix(err.Error(), "course payment already completed - student has already paid fee for course") ||
+			errors.Is(err, services.ErrCouponNotApplicable) {
 			resp.ErrorResponse(w, http.StatusBadRequest, err.Error())
 		} else {
 			resp.ErrorResponse(w, http.StatusInternalServerError, err.Error())
@@ -89,11 +122,8 @@ func InitiatePaymentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Publish event asynchronously
-	paymentService.PublishPaymentInitiatedEvent(req.StudentID, orderResp.OrderID, *preparedReq)
-
 	// Return success response with order details
-	resp.SuccessResponse(w, http.StatusOK, "Payment order created successfully", map[string]interface{}{
+	data := map[string]interface{}{
 		"order_id":     orderResp.OrderID,
 		"amount":       orderResp.Amount,
 		"currency":     orderResp.Currency,
@@ -101,13 +131,19 @@ func InitiatePaymentHandler(w http.ResponseWriter, r *http.Request) {
 		"payment_type": req.PaymentType,
 		"student_id":   req.StudentID,
 		"message":      "Please complete the payment using Razorpay",
-	})
+	}
+	if preparedReq.CouponID != nil {
+		data["coupon_code"] = preparedReq.CouponCode
+		data["discount_amount"] = preparedReq.DiscountAmount
+	}
+	resp.SuccessResponse(w, http.StatusOK, "Payment order created successfully", data)
 }
 
-// VerifyPaymentHandler handles payment verification requests
+// VerifyPayment handles payment verification requests
 // This is a client-side verification endpoint that checks signature
 // The actual database update happens via Razorpay webhook
-func VerifyPaymentHandler(w http.ResponseWriter, r *http.Request) {
+// POST /verify-payment
+func (h *PaymentHandler) VerifyPayment(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		resp.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
@@ -139,16 +175,15 @@ func VerifyPaymentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	paymentService := services.NewPaymentService()
-
 	// Verify payment signature (this is client-side verification only)
 	// The actual database update will happen when the webhook arrives from Razorpay
-	_, err := paymentService.VerifyPayment(services.VerifyPaymentRequest{
+	_, err := h.payments.VerifyPayment(r.Context(), services.VerifyPaymentRequest{
 		OrderID:      req.OrderID,
 		PaymentID:    req.PaymentID,
 		RazorpaySign: req.RazorpaySign,
 	})
 	if err != nil {
+		logger.FromContext(r.Context()).Warn("Payment verification failed for order %s (payment %s): %v", req.OrderID, req.PaymentID, err)
 		resp.ErrorResponse(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -166,8 +201,9 @@ func VerifyPaymentHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// GetPaymentStatusHandler returns the current payment status for an order
-func GetPaymentStatusHandler(w http.ResponseWriter, r *http.Request) {
+// GetPaymentStatus returns the current payment status for an order
+// GET /payment-status?order_id=
+func (h *PaymentHandler) GetPaymentStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		resp.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
@@ -179,9 +215,7 @@ func GetPaymentStatusHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	paymentService := services.NewPaymentService()
-
-	status, paymentType, studentID, err := paymentService.GetPaymentStatus(orderID)
+	status, paymentType, studentID, err := h.payments.GetPaymentStatus(r.Context(), orderID)
 	if err != nil {
 		resp.ErrorResponse(w, http.StatusNotFound, "Payment not found for order_id: "+orderID)
 		return
@@ -195,15 +229,167 @@ func GetPaymentStatusHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// Backward compatibility wrappers
-func InitiatePayment(w http.ResponseWriter, r *http.Request) {
-	InitiatePaymentHandler(w, r)
+// ResendPaymentLink re-sends the latest pending order's payment instructions to a lead
+// POST /leads/{id}/resend-payment-link
+func ResendPaymentLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		resp.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	leadID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || leadID <= 0 {
+		resp.ErrorResponse(w, http.StatusBadRequest, "Invalid lead ID")
+		return
+	}
+
+	var req struct {
+		CounselorID *int64 `json:"counselor_id,omitempty"`
+	}
+	// The body is optional; it only identifies the counselor for the activity feed
+	_ = json.NewDecoder(r.Body).Decode(&req)
+
+	result, err := services.ResendPaymentLink(r.Context(), leadID, req.CounselorID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrLeadNotFound):
+			resp.ErrorResponse(w, http.StatusNotFound, "Lead not found")
+		case errors.Is(err, services.ErrNoPendingPayment):
+			resp.ErrorResponse(w, http.StatusConflict, err.Error())
+		case errors.Is(err, services.ErrResendLimitReached):
+			resp.ErrorResponse(w, http.StatusTooManyRequests, err.Error())
+		default:
+			logger.FromContext(r.Context()).Error("Error resending payment link for lead %d: %v", leadID, err)
+			resp.ErrorResponse(w, http.StatusInternalServerError, "Error resending payment link")
+		}
+		return
+	}
+
+	resp.SuccessResponse(w, http.StatusOK, "Payment link re-sent", result)
 }
 
-func VerifyPayment(w http.ResponseWriter, r *http.Request) {
-	VerifyPaymentHandler(w, r)
+// PaymentLinks creates and emails a Razorpay Payment Link for a lead's fee (POST), or lists
+// the links created for the lead (GET)
+// GET, POST /leads/{id}/payment-links
+func PaymentLinks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		resp.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	leadID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || leadID <= 0 {
+		resp.ErrorResponse(w, http.StatusBadRequest, "Invalid lead ID")
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		links, err := services.ListRazorpayPaymentLinks(r.Context(), leadID)
+		if err != nil {
+			if errors.Is(err, services.ErrLeadNotFound) {
+				resp.ErrorResponse(w, http.StatusNotFound, "Lead not found")
+				return
+			}
+			logger.FromContext(r.Context()).Error("Error listing payment links of lead %d: %v", leadID, err)
+			resp.ErrorResponse(w, http.StatusInternalServerError, "Error listing payment links")
+			return
+		}
+		resp.SuccessResponse(w, http.StatusOK, "Payment links retrieved", links)
+		return
+	}
+
+	var req struct {
+		PaymentType string `json:"payment_type"`
+		CourseID    *int   `json:"course_id,omitempty"`
+		CounselorID *int64 `json:"counselor_id,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp.ErrorResponse(w, http.StatusBadRequest, "Invalid request format: "+err.Error())
+		return
+	}
+	if req.PaymentType == "" {
+		req.PaymentType = services.PaymentTypeRegistration
+	}
+	if req.PaymentType != services.PaymentTypeRegistration && req.PaymentType != services.PaymentTypeCourseFee {
+		resp.ErrorResponse(w, http.StatusBadRequest, "Invalid payment type - must be REGISTRATION or COURSE_FEE")
+		return
+	}
+
+	link, err := services.CreateRazorpayPaymentLink(r.Context(), services.CreatePaymentLinkRequest{
+		LeadID:      leadID,
+		PaymentType: req.PaymentType,
+		CourseID:    req.CourseID,
+		CounselorID: req.CounselorID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrLeadNotFound):
+			resp.ErrorResponse(w, http.StatusNotFound, "Lead not found")
+		case errors.Is(err, services.ErrPaymentNotAllowed):
+			resp.ErrorResponse(w, http.StatusConflict, err.Error())
+		default:
+			logger.FromContext(r.Context()).Error("Error creating payment link for lead %d: %v", leadID, err)
+			resp.ErrorResponse(w, http.StatusInternalServerError, "Error creating payment link")
+		}
+		return
+	}
+
+	resp.SuccessResponse(w, http.StatusCreated, "Payment link created and emailed", link)
 }
 
-func GetPaymentStatus(w http.ResponseWriter, r *http.Request) {
-	GetPaymentStatusHandler(w, r)
+// RefundPayment refunds a captured payment through Razorpay, in full or in part
+// POST /refund-payment
+func RefundPayment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		resp.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req struct {
+		OrderID string  `json:"order_id"`
+		Amount  float64 `json:"amount,omitempty"`
+		Reason  string  `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp.ErrorResponse(w, http.StatusBadRequest, "Invalid request format: "+err.Error())
+		return
+	}
+	req.OrderID = strings.TrimSpace(req.OrderID)
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.OrderID == "" || req.Reason == "" {
+		resp.ErrorResponse(w, http.StatusBadRequest, "order_id and reason are required")
+		return
+	}
+	if req.Amount < 0 {
+		resp.ErrorResponse(w, http.StatusBadRequest, "amount must be positive; omit it to refund the rest of the payment")
+		return
+	}
+
+	refund, err := services.RefundPayment(r.Context(), services.RefundPaymentRequest{
+		OrderID: req.OrderID,
+		Amount:  req.Amount,
+		Reason:  req.Reason,
+	}, &models.AuditLogEntry{
+		EntityType: services.AuditEntityPayment,
+		EntityID:   req.OrderID,
+		Action:     services.AuditActionRefund,
+		Actor:      middleware.AuditActor(r),
+		Changes:    models.AuditChanges{"reason": {To: req.Reason}},
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPaymentNotFound):
+			resp.ErrorResponse(w, http.StatusNotFound, "Payment not found for order_id: "+req.OrderID)
+		case errors.Is(err, services.ErrPaymentNotRefundable):
+			resp.ErrorResponse(w, http.StatusConflict, err.Error())
+		case errors.Is(err, services.ErrRefundExceedsPayment):
+			resp.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		default:
+			logger.FromContext(r.Context()).Error("Error refunding order %s: %v", req.OrderID, err)
+			resp.ErrorResponse(w, http.StatusInternalServerError, "Error refunding payment: "+err.Error())
+		}
+		return
+	}
+
+	resp.SuccessResponse(w, http.StatusOK, "Payment refunded", refund)
 }
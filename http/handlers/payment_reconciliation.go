@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PaymentReconciliation returns the reconciliation report (GET) or reconciles the payments
+// of the last PAYMENT_RECONCILIATION_WINDOW_HOURS against Razorpay now (POST)
+// GET  /admin/payment-reconciliation?status=open|resolved|all&limit=100
+// POST /admin/payment-reconciliation
+func PaymentReconciliation(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		status := r.URL.Query().Get("status")
+		if status == "" {
+			status = "open"
+		}
+		if status != "open" && status != "resolved" && status != "all" {
+			response.ErrorResponse(w, http.StatusBadRequest, "status must be open, resolved or all")
+			return
+		}
+		limit := 100
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+				limit = parsedLimit
+			}
+		}
+
+		report, err := services.GetPaymentReconciliationReport(r.Context(), status, limit)
+		if err != nil {
+			logger.FromContext(r.Context()).Error("Error fetching payment reconciliation report: %v", err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch reconciliation report")
+			return
+		}
+		response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retrieved %d reconciliation issues", len(report.Issues)), report)
+
+	case http.MethodPost:
+		run, err := services.RunPaymentReconciliation(r.Context(), time.Now())
+		if err != nil {
+			if errors.Is(err, services.ErrReconciliationUnavailable) {
+				response.ErrorResponse(w, http.StatusServiceUnavailable, "Razorpay credentials not configured")
+				return
+			}
+			logger.FromContext(r.Context()).Error("Error reconciling payments: %v", err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to reconcile payments")
+			return
+		}
+		response.SuccessResponse(w, http.StatusOK,
+			fmt.Sprintf("Checked %d payments and %d orders, %d discrepancies", run.PaymentsChecked, run.OrdersChecked, run.IssuesFound), run)
+
+	default:
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// ResolveReconciliationIssue closes a reconciliation issue by hand
+// POST /admin/payment-reconciliation/issues/{id}/resolve {"note": "refunded pay_..."}
+func ResolveReconciliationIssue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid issue ID")
+		return
+	}
+
+	var req struct {
+		Note string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	if req.Note == "" {
+		response.ErrorResponse(w, http.StatusBadRequest, "note is required")
+		return
+	}
+
+	issue, err := services.ResolveReconciliationIssue(r.Context(), id, req.Note)
+	if err != nil {
+		if errors.Is(err, services.ErrReconciliationIssueNotFound) {
+			response.ErrorResponse(w, http.StatusNotFound, "Reconciliation issue not found")
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error resolving reconciliation issue %d: %v", id, err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to resolve reconciliation issue")
+		return
+	}
+	response.SuccessResponse(w, http.StatusOK, "Reconciliation issue resolved", issue)
+}
@@ -0,0 +1,178 @@
+package handlers_test
+
+import (
+	"admission-module/http/handlers"
+	"admission-module/http/handlers/handlertest"
+	"admission-module/services"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestInitiatePayment(t *testing.T) {
+	courseID := 7
+	couponErr := fmt.Errorf("%w: WELCOME10 has expired", services.ErrCouponNotApplicable)
+
+	tests := []struct {
+		name       string
+		method     string
+		body       interface{}
+		payments   *handlertest.FakePaymentService
+		wantStatus int
+		wantError  string
+		wantSaved  int
+	}{
+		{
+			name:       "wrong method",
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "malformed body",
+			body:       "not an object",
+			wantStatus: http.StatusBadRequest,
+			wantError:  "Invalid request format",
+		},
+		{
+			name:       "missing student",
+			body:       map[string]interface{}{"payment_type": services.PaymentTypeRegistration},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "Invalid student ID",
+		},
+		{
+			name:       "unknown payment type",
+			body:       map[string]interface{}{"student_id": 1, "payment_type": "DONATION"},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "Invalid payment type",
+		},
+		{
+			name:       "ineligible",
+			body:       map[string]interface{}{"student_id": 1},
+			payments:   &handlertest.FakePaymentService{Ineligible: true, IneligibleWhy: "application not accepted"},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "application not accepted",
+		},
+		{
+			name:       "registration",
+			body:       map[string]interface{}{"student_id": 1},
+			wantStatus: http.StatusOK,
+			wantSaved:  1,
+		},
+		{
+			name:       "course fee",
+			body:       map[string]interface{}{"student_id": 1, "payment_type": services.PaymentTypeCourseFee, "course_id": courseID, "amount": 50000},
+			wantStatus: http.StatusOK,
+			wantSaved:  1,
+		},
+		{
+			name:       "coupon rejected while pricing",
+			body:       map[string]interface{}{"student_id": 1, "coupon_code": "WELCOME10"},
+			payments:   &handlertest.FakePaymentService{PrepareErr: couponErr},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "WELCOME10 has expired",
+		},
+		{
+			name:       "coupon run out before the order",
+			body:       map[string]interface{}{"student_id": 1, "coupon_code": "WELCOME10"},
+			payments:   &handlertest.FakePaymentService{OrderErr: couponErr},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "WELCOME10 has expired",
+		},
+		{
+			name:       "coupon run out while saving",
+			body:       map[string]interface{}{"student_id": 1, "coupon_code": "WELCOME10"},
+			payments:   &handlertest.FakePaymentService{SaveErr: couponErr},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "WELCOME10 has expired",
+		},
+		{
+			name:       "registration already paid",
+			body:       map[string]interface{}{"student_id": 1},
+			payments:   &handlertest.FakePaymentService{SaveErr: errors.New("registration payment already completed - student has already paid registration fee")},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "already paid",
+		},
+		{
+			name:       "course fee already paid",
+			body:       map[string]interface{}{"student_id": 1, "payment_type": services.PaymentTypeCourseFee, "course_id": courseID},
+			payments:   &handlertest.FakePaymentService{SaveErr: fmt.Errorf("course payment already completed - student has already paid fee for course %d", courseID)},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "already paid",
+		},
+		{
+			name:       "eligibility check fails",
+			body:       map[string]interface{}{"student_id": 1},
+			payments:   &handlertest.FakePaymentService{EligibilityErr: errors.New("connection refused"), IneligibleWhy: "Error checking eligibility"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "razorpay fails",
+			body:       map[string]interface{}{"student_id": 1},
+			payments:   &handlertest.FakePaymentService{OrderErr: errors.New("razorpay credentials not configured")},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "Error creating payment order",
+		},
+		{
+			name:       "saving fails",
+			body:       map[string]interface{}{"student_id": 1},
+			payments:   &handlertest.FakePaymentService{SaveErr: errors.New("connection reset")},
+			wantStatus: http.StatusInternalServerError,
+			wantError:  "connection reset",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			payments := tt.payments
+			if payments == nil {
+				payments = &handlertest.FakePaymentService{}
+			}
+			h := handlers.NewPaymentHandler(payments)
+			rec := handlertest.Do(h.InitiatePayment, method, "/initiate-payment", tt.body)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			body, err := handlertest.Decode(rec)
+			if err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if tt.wantError != "" && !strings.Contains(body.Error, tt.wantError) {
+				t.Errorf("error = %q, want it to contain %q", body.Error, tt.wantError)
+			}
+			if len(payments.Saved) != tt.wantSaved {
+				t.Errorf("saved %d payments, want %d", len(payments.Saved), tt.wantSaved)
+			}
+		})
+	}
+}
+
+func TestInitiatePaymentPassesCouponCode(t *testing.T) {
+	payments := &handlertest.FakePaymentService{}
+	h := handlers.NewPaymentHandler(payments)
+	rec := handlertest.Do(h.InitiatePayment, http.MethodPost, "/initiate-payment",
+		map[string]interface{}{"student_id": 3, "coupon_code": "  WELCOME10 "})
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if len(payments.Saved) != 1 {
+		t.Fatalf("saved %d payments, want 1", len(payments.Saved))
+	}
+	saved := payments.Saved[0]
+	if saved.StudentID != 3 || saved.OrderID != "order_test" {
+		t.Errorf("saved student %d order %q, want student 3 order order_test", saved.StudentID, saved.OrderID)
+	}
+	if saved.Request.CouponCode != "WELCOME10" {
+		t.Errorf("coupon code = %q, want WELCOME10", saved.Request.CouponCode)
+	}
+	if saved.Request.Amount != services.RegistrationFee {
+		t.Errorf("amount = %v, want %v", saved.Request.Amount, services.RegistrationFee)
+	}
+}
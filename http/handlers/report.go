@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/models"
+	"admission-module/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MarketingSpend records or lists marketing spend per lead source/campaign
+// GET  /marketing-spend?from=2025-01-01&to=2025-03-31
+// POST /marketing-spend
+func MarketingSpend(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		listMarketingSpend(w, r)
+	case http.MethodPost:
+		recordMarketingSpend(w, r)
+	default:
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func listMarketingSpend(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseReportDateRange(r)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entries, err := services.GetMarketingSpend(r.Context(), from, to)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching marketing spend: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error fetching marketing spend")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retrieved %d spend entries", len(entries)), entries)
+}
+
+func recordMarketingSpend(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		LeadSource  string  `json:"lead_source"`
+		Campaign    string  `json:"campaign"`
+		PeriodStart string  `json:"period_start"`
+		PeriodEnd   string  `json:"period_end"`
+		Amount      float64 `json:"amount"`
+		Notes       string  `json:"notes"`
+		RecordedBy  string  `json:"recorded_by"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if strings.TrimSpace(req.LeadSource) == "" {
+		response.ErrorResponse(w, http.StatusBadRequest, "lead_source is required")
+		return
+	}
+	if req.Amount < 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "amount must not be negative")
+		return
+	}
+
+	periodStart, err := time.Parse(services.ReportDateLayout, req.PeriodStart)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid period_start. Use YYYY-MM-DD")
+		return
+	}
+	periodEnd, err := time.Parse(services.ReportDateLayout, req.PeriodEnd)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid period_end. Use YYYY-MM-DD")
+		return
+	}
+	if periodEnd.Before(periodStart) {
+		response.ErrorResponse(w, http.StatusBadRequest, "period_end must not be before period_start")
+		return
+	}
+
+	spend := &models.MarketingSpend{
+		LeadSource:  req.LeadSource,
+		Campaign:    req.Campaign,
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+		Amount:      req.Amount,
+		Notes:       req.Notes,
+		RecordedBy:  req.RecordedBy,
+	}
+	if err := services.RecordMarketingSpend(r.Context(), spend); err != nil {
+		logger.FromContext(r.Context()).Error("Error recording marketing spend: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error recording marketing spend")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusCreated, "Marketing spend recorded", spend)
+}
+
+// GetCACReport returns cost per lead and cost per enrollment by lead source and campaign
+// GET /reports/cac?from=2025-01-01&to=2025-03-31&format=csv
+func GetCACReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	from, to, err := parseReportDateRange(r)
+	if err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != services.ExportFormatCSV {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid format. Must be json or csv")
+		return
+	}
+
+	report, err := services.GetCACReport(r.Context(), from, to)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error building CAC report: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error building CAC report")
+		return
+	}
+
+	if format == services.ExportFormatCSV {
+		fileName := fmt.Sprintf("cac_report_%s.csv", time.Now().Format("20060102_150405"))
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+		w.Header().Set("Content-Type", "text/csv")
+		if err := services.WriteCACReportCSV(w, report); err != nil {
+			logger.FromContext(r.Context()).Error("Error writing CAC report CSV: %v", err)
+		}
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "CAC report", report)
+}
+
+// parseReportDateRange reads optional from/to dates (YYYY-MM-DD) from the query string
+func parseReportDateRange(r *http.Request) (*time.Time, *time.Time, error) {
+	var from, to *time.Time
+
+	if str := r.URL.Query().Get("from"); str != "" {
+		parsed, err := time.Parse(services.ReportDateLayout, str)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid from date. Use YYYY-MM-DD (e.g., 2025-01-01)")
+		}
+		from = &parsed
+	}
+
+	if str := r.URL.Query().Get("to"); str != "" {
+		parsed, err := time.Parse(services.ReportDateLayout, str)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid to date. Use YYYY-MM-DD (e.g., 2025-03-31)")
+		}
+		to = &parsed
+	}
+
+	if from != nil && to != nil && to.Before(*from) {
+		return nil, nil, fmt.Errorf("to date must not be before from date")
+	}
+
+	return from, to, nil
+}
+
+// GetPotentialDuplicates lists clusters of leads that probably describe the same student
+// GET /reports/potential-duplicates?min_confidence=0.6
+func GetPotentialDuplicates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	minConfidence := 0.6
+	if str := r.URL.Query().Get("min_confidence"); str != "" {
+		parsed, err := strconv.ParseFloat(str, 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			response.ErrorResponse(w, http.StatusBadRequest, "min_confidence must be between 0 and 1")
+			return
+		}
+		minConfidence = parsed
+	}
+
+	clusters, err := services.FindPotentialDuplicates(r.Context(), minConfidence)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error finding potential duplicates: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error finding potential duplicates")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Found %d potential duplicate clusters", len(clusters)), clusters)
+}
+
+// GetManagerSummary previews the scheduled manager summary (the previous full day or week)
+// GET /reports/manager-summary?period=daily|weekly
+func GetManagerSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = models.SummaryPeriodDaily
+	}
+
+	summary, err := services.GetManagerSummary(r.Context(), period, time.Now())
+	if errors.Is(err, services.ErrInvalidSummaryPeriod) {
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error building manager summary: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error building manager summary")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Manager summary generated", summary)
+}
+
+// GetCapacityForecast forecasts next week's lead inflow against free counselor capacity
+// GET /reports/capacity-forecast?weeks=8
+func GetCapacityForecast(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	weeks := 8
+	if str := r.URL.Query().Get("weeks"); str != "" {
+		parsed, err := strconv.Atoi(str)
+		if err != nil || parsed < 1 || parsed > 52 {
+			response.ErrorResponse(w, http.StatusBadRequest, "weeks must be between 1 and 52")
+			return
+		}
+		weeks = parsed
+	}
+
+	forecast, err := services.GetCapacityForecast(r.Context(), weeks, time.Now())
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error building capacity forecast: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error building capacity forecast")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Capacity forecast generated", forecast)
+}
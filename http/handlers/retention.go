@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"admission-module/config"
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/services"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// RetentionRuns lists recent retention runs (GET) or runs every retention policy now (POST).
+// dry_run defaults to RETENTION_DRY_RUN.
+// GET  /admin/retention/runs?limit=50
+// POST /admin/retention/runs?dry_run=true
+func RetentionRuns(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		limit := 50
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+				limit = parsedLimit
+			}
+		}
+
+		runs, err := services.GetRetentionRuns(r.Context(), limit)
+		if err != nil {
+			logger.FromContext(r.Context()).Error("Error fetching retention runs: %v", err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch retention runs")
+			return
+		}
+		response.SuccessResponse(w, http.StatusOK, fmt.Sprintf("Retrieved %d retention runs", len(runs)), runs)
+
+	case http.MethodPost:
+		dryRun := config.AppConfig.RetentionDryRun
+		if dryRunStr := r.URL.Query().Get("dry_run"); dryRunStr != "" {
+			parsed, err := strconv.ParseBool(dryRunStr)
+			if err != nil {
+				response.ErrorResponse(w, http.StatusBadRequest, "dry_run must be true or false")
+				return
+			}
+			dryRun = parsed
+		}
+
+		runs, err := services.RunRetention(r.Context(), dryRun)
+		if err != nil {
+			logger.FromContext(r.Context()).Error("Error running retention policies: %v", err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to run retention policies")
+			return
+		}
+		message := fmt.Sprintf("Ran %d retention policies", len(runs))
+		if dryRun {
+			message = fmt.Sprintf("Dry-ran %d retention policies; nothing was changed", len(runs))
+		}
+		response.SuccessResponse(w, http.StatusOK, message, runs)
+
+	default:
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
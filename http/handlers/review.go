@@ -1,17 +1,41 @@
 package handlers
 
 import (
-	"admission-module/db"
 	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/models"
 	"admission-module/services"
-	"database/sql"
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
 	"net/http"
+	"strconv"
 )
 
-// ApplicationActionHandler handles application accept/reject requests
-func ApplicationActionHandler(w http.ResponseWriter, r *http.Request) {
+// ApplicationService is the part of services.ApplicationService the application handlers use
+type ApplicationService interface {
+	GetRegistrationPaymentStatus(ctx context.Context, studentID int) (string, error)
+	AcceptApplication(ctx context.Context, req services.AcceptApplicationRequest) (*services.AcceptApplicationResult, error)
+	RejectApplication(ctx context.Context, req services.RejectApplicationRequest) (*services.RejectApplicationResult, error)
+	WaitlistApplication(ctx context.Context, req services.WaitlistApplicationRequest) (*services.WaitlistApplicationResult, error)
+	NotifyAccepted(result *services.AcceptApplicationResult) error
+	NotifyRejected(result *services.RejectApplicationResult) error
+	NotifyWaitlisted(result *services.WaitlistApplicationResult) error
+}
+
+// ApplicationHandler serves the application review APIs
+type ApplicationHandler struct {
+	applications ApplicationService
+}
+
+// NewApplicationHandler creates an ApplicationHandler backed by the given application service
+func NewApplicationHandler(applications ApplicationService) *ApplicationHandler {
+	return &ApplicationHandler{applications: applications}
+}
+
+// ApplicationAction handles application accept/reject/waitlist requests
+// POST /application-action
+func (h *ApplicationHandler) ApplicationAction(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
@@ -21,6 +45,7 @@ func ApplicationActionHandler(w http.ResponseWriter, r *http.Request) {
 		StudentID        int    `json:"student_id"`
 		Status           string `json:"status"`
 		SelectedCourseID *int   `json:"selected_course_id,omitempty"`
+		ApprovedBy       string `json:"approved_by,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -28,67 +53,190 @@ func ApplicationActionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Status != "ACCEPTED" && req.Status != "REJECTED" {
-		response.ErrorResponse(w, http.StatusBadRequest, "Invalid status. Must be ACCEPTED or REJECTED")
+	if req.Status != services.ApplicationStatusAccepted && req.Status != services.ApplicationStatusRejected && req.Status != services.ApplicationStatusWaitlisted {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid status. Must be ACCEPTED, REJECTED or WAITLISTED")
 		return
 	}
 
-	if req.Status == "ACCEPTED" && req.SelectedCourseID == nil {
-		response.ErrorResponse(w, http.StatusBadRequest, "Selected course ID is required for acceptance")
+	if req.Status != services.ApplicationStatusRejected && req.SelectedCourseID == nil {
+		response.ErrorResponse(w, http.StatusBadRequest, "Selected course ID is required for acceptance and waitlisting")
 		return
 	}
 
-	// REQUIREMENT: Check if registration fee is PAID before allowing application status updates
-	var regPaymentStatus string
-	err := db.DB.QueryRow("SELECT status FROM registration_payment WHERE student_id = $1", req.StudentID).Scan(&regPaymentStatus)
-	if err == sql.ErrNoRows {
-		response.ErrorResponse(w, http.StatusBadRequest, "Registration payment record not found. Please complete registration fee payment first")
+	if !h.requireRegistrationPaid(r.Context(), w, req.StudentID) {
 		return
 	}
-	if err != nil {
-		response.ErrorResponse(w, http.StatusInternalServerError, "Error checking registration payment status")
+
+	switch req.Status {
+	case services.ApplicationStatusAccepted:
+		h.handleAcceptance(w, r, services.AcceptApplicationRequest{
+			StudentID:        req.StudentID,
+			SelectedCourseID: *req.SelectedCourseID,
+			ApprovedBy:       req.ApprovedBy,
+		})
+	case services.ApplicationStatusWaitlisted:
+		h.handleWaitlist(w, r, req.StudentID, *req.SelectedCourseID)
+	default:
+		h.handleRejection(w, r, req.StudentID)
+	}
+}
+
+// OverrideAcceptance lets an admin accept an application without a second approver,
+// resolving any pending dual approval
+// POST /admin/applications/{id}/accept
+func (h *ApplicationHandler) OverrideAcceptance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
-	if regPaymentStatus != "PAID" {
-		response.ErrorResponse(w, http.StatusBadRequest, "Application status cannot be updated. Registration payment status is "+regPaymentStatus+". Please complete registration fee payment first")
+
+	studentID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || studentID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid student ID")
 		return
 	}
 
-	appService := services.NewApplicationService()
+	var req struct {
+		SelectedCourseID int    `json:"selected_course_id"`
+		ApprovedBy       string `json:"approved_by"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+	if req.SelectedCourseID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Selected course ID is required")
+		return
+	}
 
-	if req.Status == "ACCEPTED" {
-		handleApplicationAcceptance(w, appService, req.StudentID, *req.SelectedCourseID)
-	} else {
-		handleApplicationRejection(w, appService, req.StudentID)
+	if !h.requireRegistrationPaid(r.Context(), w, studentID) {
+		return
 	}
-}
 
-func handleApplicationAcceptance(w http.ResponseWriter, appService *services.ApplicationService, studentID, courseID int) {
-	result, err := appService.AcceptApplication(services.AcceptApplicationRequest{
+	h.handleAcceptance(w, r, services.AcceptApplicationRequest{
 		StudentID:        studentID,
-		SelectedCourseID: courseID,
+		SelectedCourseID: req.SelectedCourseID,
+		ApprovedBy:       req.ApprovedBy,
+		Override:         true,
 	})
+}
+
+// GetPendingAcceptanceApprovals lists acceptances awaiting a second approver
+// GET /application-approvals
+func GetPendingAcceptanceApprovals(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	approvals, err := services.GetPendingAcceptanceApprovals(r.Context())
 	if err != nil {
-		log.Printf("Error accepting application: %v", err)
-		response.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		logger.FromContext(r.Context()).Error("Error fetching pending approvals: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to fetch pending approvals")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Pending approvals retrieved", map[string]interface{}{
+		"approvals": approvals,
+		"count":     len(approvals),
+	})
+}
+
+// requireRegistrationPaid answers the request and returns false unless the student's
+// registration fee is PAID, which every application decision requires
+func (h *ApplicationHandler) requireRegistrationPaid(ctx context.Context, w http.ResponseWriter, studentID int) bool {
+	regPaymentStatus, err := h.applications.GetRegistrationPaymentStatus(ctx, studentID)
+	if errors.Is(err, services.ErrPaymentNotFound) {
+		response.ErrorResponse(w, http.StatusBadRequest, "Registration payment record not found. Please complete registration fee payment first")
+		return false
+	}
+	if err != nil {
+		response.ErrorResponse(w, http.StatusInternalServerError, "Error checking registration payment status")
+		return false
+	}
+	if regPaymentStatus != "PAID" {
+		response.ErrorResponse(w, http.StatusBadRequest, "Application status cannot be updated. Registration payment status is "+regPaymentStatus+". Please complete registration fee payment first")
+		return false
+	}
+	return true
+}
+
+// writeDecisionError answers a failed accept/reject/waitlist, with 409 for a decision
+// the application's current status (or its pending approval) does not allow
+func writeDecisionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrApproverRequired):
+		response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, services.ErrInvalidStatusTransition),
+		errors.Is(err, services.ErrSameApprover),
+		errors.Is(err, services.ErrApprovalCourseMismatch):
+		response.ErrorResponse(w, http.StatusConflict, err.Error())
+		return
+	}
+	response.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+}
+
+func (h *ApplicationHandler) handleAcceptance(w http.ResponseWriter, r *http.Request, req services.AcceptApplicationRequest) {
+	ctx := r.Context()
+	studentID := req.StudentID
+	result, err := h.applications.AcceptApplication(ctx, req)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error accepting application: %v", err)
+		writeDecisionError(w, err)
+		return
+	}
+
+	status := services.ApplicationStatusAccepted
+	if result.PendingApproval {
+		status = services.ApplicationStatusPendingApproval
+	}
+	changes := models.AuditChanges{
+		"application_status": {To: status},
+		"selected_course_id": {To: result.CourseID},
+		"approved_by":        {To: req.ApprovedBy},
+	}
+	if req.Override {
+		changes["override"] = models.AuditChange{To: true}
+	}
+	recordAudit(r, services.AuditEntityLead, strconv.Itoa(studentID), services.AuditActionStatusChange, changes)
+
+	if result.PendingApproval {
+		// Ask the other approvers to confirm
+		go func() {
+			if err := h.applications.NotifyAccepted(result); err != nil {
+				logger.FromContext(ctx).Warn("Failed to queue approval request: %v", err)
+			}
+		}()
+
+		response.SuccessResponse(w, http.StatusAccepted, "Acceptance recorded, awaiting a second approval", map[string]interface{}{
+			"student_id":      studentID,
+			"student_name":    result.StudentName,
+			"selected_course": result.CourseName,
+			"course_id":       result.CourseID,
+			"status":          services.ApplicationStatusPendingApproval,
+			"requested_by":    result.RequestedBy,
+			"next_step":       "A different approver must accept the application for the same course",
+		})
 		return
 	}
 
 	// Send acceptance email asynchronously via Kafka
 	go func() {
-		if err := services.SendAcceptanceEmail(result.StudentName, result.StudentEmail, result.CourseName, result.CourseFee); err != nil {
-			log.Printf("Warning: failed to queue acceptance email: %v", err)
+		if err := h.applications.NotifyAccepted(result); err != nil {
+			logger.FromContext(ctx).Warn("Failed to queue acceptance email: %v", err)
 		}
 	}()
 
 	response.SuccessResponse(w, http.StatusOK, "Application accepted successfully", map[string]interface{}{
-		"student_id":      studentID,
-		"student_name":    result.StudentName,
-		"student_email":   result.StudentEmail,
-		"selected_course": result.CourseName,
-		"course_id":       result.CourseID,
-		"course_fee":      result.CourseFee,
-		"next_step":       "Please proceed with course fee payment",
+		"student_id":       studentID,
+		"student_name":     result.StudentName,
+		"student_email":    result.StudentEmail,
+		"selected_course":  result.CourseName,
+		"course_id":        result.CourseID,
+		"course_fee":       result.CourseFee,
+		"payment_deadline": result.PaymentDeadline,
+		"next_step":        "Please proceed with course fee payment before the deadline",
 		"payment_details": map[string]interface{}{
 			"payment_type": "COURSE_FEE",
 			"amount":       result.CourseFee,
@@ -98,20 +246,58 @@ func handleApplicationAcceptance(w http.ResponseWriter, appService *services.App
 	})
 }
 
-func handleApplicationRejection(w http.ResponseWriter, appService *services.ApplicationService, studentID int) {
-	result, err := appService.RejectApplication(services.RejectApplicationRequest{
+func (h *ApplicationHandler) handleWaitlist(w http.ResponseWriter, r *http.Request, studentID, courseID int) {
+	ctx := r.Context()
+	result, err := h.applications.WaitlistApplication(ctx, services.WaitlistApplicationRequest{
+		StudentID:        studentID,
+		SelectedCourseID: courseID,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("Error waitlisting application: %v", err)
+		writeDecisionError(w, err)
+		return
+	}
+	recordAudit(r, services.AuditEntityLead, strconv.Itoa(studentID), services.AuditActionStatusChange, models.AuditChanges{
+		"application_status": {To: services.ApplicationStatusWaitlisted},
+		"selected_course_id": {To: result.CourseID},
+	})
+
+	// Send waitlist email asynchronously via Kafka
+	go func() {
+		if err := h.applications.NotifyWaitlisted(result); err != nil {
+			logger.FromContext(ctx).Warn("Failed to queue waitlist email: %v", err)
+		}
+	}()
+
+	response.SuccessResponse(w, http.StatusOK, "Application waitlisted successfully", map[string]interface{}{
+		"student_id":        studentID,
+		"student_name":      result.StudentName,
+		"student_email":     result.StudentEmail,
+		"selected_course":   result.CourseName,
+		"course_id":         result.CourseID,
+		"waitlist_position": result.Position,
+		"next_step":         "The student will be offered a seat when one is released",
+	})
+}
+
+func (h *ApplicationHandler) handleRejection(w http.ResponseWriter, r *http.Request, studentID int) {
+	ctx := r.Context()
+	result, err := h.applications.RejectApplication(ctx, services.RejectApplicationRequest{
 		StudentID: studentID,
 	})
 	if err != nil {
-		log.Printf("Error rejecting application: %v", err)
-		response.ErrorResponse(w, http.StatusInternalServerError, err.Error())
+		logger.FromContext(ctx).Error("Error rejecting application: %v", err)
+		writeDecisionError(w, err)
 		return
 	}
+	recordAudit(r, services.AuditEntityLead, strconv.Itoa(studentID), services.AuditActionStatusChange, models.AuditChanges{
+		"application_status": {To: services.ApplicationStatusRejected},
+	})
 
 	// Send rejection email asynchronously via Kafka
 	go func() {
-		if err := services.SendRejectionEmail(result.StudentName, result.StudentEmail); err != nil {
-			log.Printf("Warning: failed to queue rejection email: %v", err)
+		if err := h.applications.NotifyRejected(result); err != nil {
+			logger.FromContext(ctx).Warn("Failed to queue rejection email: %v", err)
 		}
 	}()
 
@@ -123,7 +309,3 @@ func handleApplicationRejection(w http.ResponseWriter, appService *services.Appl
 		"notification":  "Rejection email has been sent to the student",
 	})
 }
-
-func ApplicationAction(w http.ResponseWriter, r *http.Request) {
-	ApplicationActionHandler(w, r)
-}
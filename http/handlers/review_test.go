@@ -0,0 +1,166 @@
+package handlers_test
+
+import (
+	"admission-module/http/handlers"
+	"admission-module/http/handlers/handlertest"
+	"admission-module/services"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestApplicationAction(t *testing.T) {
+	course := 4
+
+	tests := []struct {
+		name         string
+		method       string
+		body         interface{}
+		applications *handlertest.FakeApplicationService
+		wantStatus   int
+		wantError    string
+		wantAccepted int
+		wantRejected int
+		wantWaitlist int
+	}{
+		{
+			name:       "wrong method",
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "malformed body",
+			body:       "not an object",
+			wantStatus: http.StatusBadRequest,
+			wantError:  "Invalid request format",
+		},
+		{
+			name:       "unknown status",
+			body:       map[string]interface{}{"student_id": 1, "status": "MAYBE"},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "Invalid status",
+		},
+		{
+			name:       "accept without course",
+			body:       map[string]interface{}{"student_id": 1, "status": services.ApplicationStatusAccepted},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "Selected course ID is required",
+		},
+		{
+			name:       "waitlist without course",
+			body:       map[string]interface{}{"student_id": 1, "status": services.ApplicationStatusWaitlisted},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "Selected course ID is required",
+		},
+		{
+			name:         "no registration payment",
+			body:         map[string]interface{}{"student_id": 1, "status": services.ApplicationStatusRejected},
+			applications: &handlertest.FakeApplicationService{RegistrationErr: services.ErrPaymentNotFound},
+			wantStatus:   http.StatusBadRequest,
+			wantError:    "Registration payment record not found",
+		},
+		{
+			name:         "registration payment lookup fails",
+			body:         map[string]interface{}{"student_id": 1, "status": services.ApplicationStatusRejected},
+			applications: &handlertest.FakeApplicationService{RegistrationErr: errors.New("connection refused")},
+			wantStatus:   http.StatusInternalServerError,
+			wantError:    "Error checking registration payment status",
+		},
+		{
+			name:         "registration fee pending",
+			body:         map[string]interface{}{"student_id": 1, "status": services.ApplicationStatusRejected},
+			applications: &handlertest.FakeApplicationService{RegistrationStatus: services.PaymentStatusPending},
+			wantStatus:   http.StatusBadRequest,
+			wantError:    "Registration payment status is PENDING",
+		},
+		{
+			name:         "accept",
+			body:         map[string]interface{}{"student_id": 1, "status": services.ApplicationStatusAccepted, "selected_course_id": course},
+			wantStatus:   http.StatusOK,
+			wantAccepted: 1,
+		},
+		{
+			name: "accept awaiting a second approval",
+			body: map[string]interface{}{"student_id": 1, "status": services.ApplicationStatusAccepted, "selected_course_id": course, "approved_by": "alice"},
+			applications: &handlertest.FakeApplicationService{
+				AcceptResult: &services.AcceptApplicationResult{CourseID: course, PendingApproval: true, RequestedBy: "alice"},
+			},
+			wantStatus:   http.StatusAccepted,
+			wantAccepted: 1,
+		},
+		{
+			name:         "accept needs an approver",
+			body:         map[string]interface{}{"student_id": 1, "status": services.ApplicationStatusAccepted, "selected_course_id": course},
+			applications: &handlertest.FakeApplicationService{AcceptErr: services.ErrApproverRequired},
+			wantStatus:   http.StatusBadRequest,
+			wantError:    services.ErrApproverRequired.Error(),
+		},
+		{
+			name:         "accept by the same approver",
+			body:         map[string]interface{}{"student_id": 1, "status": services.ApplicationStatusAccepted, "selected_course_id": course, "approved_by": "alice"},
+			applications: &handlertest.FakeApplicationService{AcceptErr: services.ErrSameApprover},
+			wantStatus:   http.StatusConflict,
+		},
+		{
+			name:         "reject",
+			body:         map[string]interface{}{"student_id": 1, "status": services.ApplicationStatusRejected},
+			wantStatus:   http.StatusOK,
+			wantRejected: 1,
+		},
+		{
+			name:         "reject an accepted application",
+			body:         map[string]interface{}{"student_id": 1, "status": services.ApplicationStatusRejected},
+			applications: &handlertest.FakeApplicationService{RejectErr: fmt.Errorf("%w: ACCEPTED to REJECTED", services.ErrInvalidStatusTransition)},
+			wantStatus:   http.StatusConflict,
+			wantError:    "ACCEPTED to REJECTED",
+		},
+		{
+			name:         "waitlist",
+			body:         map[string]interface{}{"student_id": 1, "status": services.ApplicationStatusWaitlisted, "selected_course_id": course},
+			wantStatus:   http.StatusOK,
+			wantWaitlist: 1,
+		},
+		{
+			name:         "waitlist fails",
+			body:         map[string]interface{}{"student_id": 1, "status": services.ApplicationStatusWaitlisted, "selected_course_id": course},
+			applications: &handlertest.FakeApplicationService{WaitlistErr: errors.New("connection reset")},
+			wantStatus:   http.StatusInternalServerError,
+			wantError:    "connection reset",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			applications := tt.applications
+			if applications == nil {
+				applications = &handlertest.FakeApplicationService{}
+			}
+			h := handlers.NewApplicationHandler(applications)
+			rec := handlertest.Do(h.ApplicationAction, method, "/application-action", tt.body)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			body, err := handlertest.Decode(rec)
+			if err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if tt.wantError != "" && !strings.Contains(body.Error, tt.wantError) {
+				t.Errorf("error = %q, want it to contain %q", body.Error, tt.wantError)
+			}
+			if len(applications.Accepted) != tt.wantAccepted ||
+				len(applications.Rejected) != tt.wantRejected ||
+				len(applications.Waitlisted) != tt.wantWaitlist {
+				t.Errorf("accepted/rejected/waitlisted %d/%d/%d, want %d/%d/%d",
+					len(applications.Accepted), len(applications.Rejected), len(applications.Waitlisted),
+					tt.wantAccepted, tt.wantRejected, tt.wantWaitlist)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"admission-module/db"
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/metrics"
+	"admission-module/services"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GetWebhookSLO returns payment webhook latency/success SLO compliance and burn rates
+// GET /admin/slo
+func GetWebhookSLO(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	report, err := services.GetWebhookSLO(r.Context(), time.Now())
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error computing webhook SLO: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to compute webhook SLO")
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Webhook SLO retrieved", report)
+}
+
+// Metrics exposes HTTP, Kafka, email, DLQ and DB pool metrics along with the
+// webhook SLO in the Prometheus text format
+// GET /metrics
+func Metrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report, err := services.GetWebhookSLO(r.Context(), time.Now())
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error computing webhook SLO metrics: %v", err)
+		http.Error(w, "Failed to compute metrics", http.StatusInternalServerError)
+		return
+	}
+
+	var b strings.Builder
+	metrics.WriteText(&b)
+	writeRuntimeGauges(&b)
+
+	gauge := func(name, help string, value func(i int) float64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for i, window := range report.Windows {
+			fmt.Fprintf(&b, "%s{window=%q} %g\n", name, window.Window, value(i))
+		}
+	}
+	gauge("admission_webhook_events", "Payment webhooks processed in the window",
+		func(i int) float64 { return float64(report.Windows[i].Total) })
+	gauge("admission_webhook_good_events", "Payment webhooks processed successfully within the latency threshold",
+		func(i int) float64 { return float64(report.Windows[i].Good) })
+	gauge("admission_webhook_failed_events", "Payment webhooks that failed processing",
+		func(i int) float64 { return float64(report.Windows[i].Failed) })
+	gauge("admission_webhook_slo_compliance", "Share of good payment webhooks",
+		func(i int) float64 { return report.Windows[i].Compliance })
+	gauge("admission_webhook_slo_burn_rate", "Error budget burn rate of the payment webhook SLO",
+		func(i int) float64 { return report.Windows[i].BurnRate })
+	gauge("admission_webhook_latency_p99_ms", "99th percentile payment webhook processing latency",
+		func(i int) float64 { return report.Windows[i].P99LatencyMs })
+	fmt.Fprintf(&b, "# HELP admission_webhook_slo_target Payment webhook SLO target\n# TYPE admission_webhook_slo_target gauge\nadmission_webhook_slo_target %g\n", report.Target)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(b.String()))
+}
+
+// writeRuntimeGauges writes the gauges read at scrape time: consumer lag, DLQ size and
+// the database connection pool
+func writeRuntimeGauges(b *strings.Builder) {
+	lag := map[string]float64{}
+	for topic, n := range services.ConsumerLag() {
+		lag[topic] = float64(n)
+	}
+	metrics.WriteGaugeVec(b, "admission_kafka_consumer_lag", "Messages the consumer is behind the partition head, by topic", "topic", lag)
+
+	if stats, err := services.GetDLQStats(); err != nil {
+		logger.Error("Error reading DLQ stats for metrics: %v", err)
+	} else if stats != nil {
+		metrics.WriteGaugeVec(b, "admission_dlq_messages", "DLQ messages by state", "state", map[string]float64{
+			"unresolved":  float64(stats["unresolved_messages"].(int)),
+			"quarantined": float64(stats["quarantined_messages"].(int)),
+			"resolved":    float64(stats["resolved_messages"].(int)),
+		})
+	}
+
+	if db.DB != nil {
+		pool := db.DB.Stats()
+		metrics.WriteGauge(b, "admission_db_open_connections", "Open database connections", float64(pool.OpenConnections))
+		metrics.WriteGauge(b, "admission_db_in_use_connections", "Database connections in use", float64(pool.InUse))
+		metrics.WriteGauge(b, "admission_db_idle_connections", "Idle database connections", float64(pool.Idle))
+		metrics.WriteGauge(b, "admission_db_max_open_connections", "Maximum open database connections", float64(pool.MaxOpenConnections))
+		metrics.WriteGauge(b, "admission_db_wait_count", "Connections waited for since startup", float64(pool.WaitCount))
+		metrics.WriteGauge(b, "admission_db_wait_duration_seconds", "Time spent waiting for connections since startup", pool.WaitDuration.Seconds())
+		metrics.WriteGaugeVec(b, "admission_db_closed_connections", "Connections closed by the pool since startup, by reason", "reason", map[string]float64{
+			"max_idle":      float64(pool.MaxIdleClosed),
+			"max_idle_time": float64(pool.MaxIdleTimeClosed),
+			"max_lifetime":  float64(pool.MaxLifetimeClosed),
+		})
+	}
+	if db.Replica != nil {
+		pool := db.Replica.Stats()
+		metrics.WriteGauge(b, "admission_db_replica_open_connections", "Open read replica connections", float64(pool.OpenConnections))
+		metrics.WriteGauge(b, "admission_db_replica_in_use_connections", "Read replica connections in use", float64(pool.InUse))
+		metrics.WriteGauge(b, "admission_db_replica_wait_count", "Read replica connections waited for since startup", float64(pool.WaitCount))
+	}
+}
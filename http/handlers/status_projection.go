@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// RebuildStatusProjection replays the status history of the given leads and reports the
+// leads whose stored application status differs; with "apply": true they are corrected
+// POST /admin/leads/status-projection/rebuild
+func RebuildStatusProjection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req struct {
+		LeadIDs []int `json:"lead_ids"`
+		Apply   bool  `json:"apply"` // false (default) only reports the differences
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	diffs, err := services.RebuildStatusProjection(r.Context(), req.LeadIDs, req.Apply)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidStatusProjection) {
+			response.ErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error rebuilding status projection: %v", err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to rebuild status projection")
+		return
+	}
+
+	changed, applied, failed := 0, 0, 0
+	for _, diff := range diffs {
+		switch {
+		case diff.Error != "":
+			failed++
+		case diff.Applied:
+			applied++
+		case diff.Changed:
+			changed++
+		}
+	}
+
+	message := fmt.Sprintf("%d of %d leads differ from their history (dry run, nothing changed)", changed, len(diffs))
+	if req.Apply {
+		message = fmt.Sprintf("Corrected %d of %d leads", applied, len(diffs))
+	}
+	if failed > 0 {
+		message += fmt.Sprintf("; %d failed", failed)
+	}
+	response.SuccessResponse(w, http.StatusOK, message, diffs)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"admission-module/http/middleware"
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/models"
+	"admission-module/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ExportStudentData returns everything held about a student as one JSON bundle, for a
+// data subject access request
+// GET /students/{id}/data-export
+func ExportStudentData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	studentID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || studentID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+
+	export, err := services.ExportStudentData(r.Context(), studentID)
+	if err != nil {
+		if errors.Is(err, services.ErrLeadNotFound) {
+			response.ErrorResponse(w, http.StatusNotFound, "Student not found")
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error exporting data of student %d: %v", studentID, err)
+		response.ErrorResponse(w, http.StatusInternalServerError, "Failed to export student data")
+		return
+	}
+	recordAudit(r, services.AuditEntityLead, strconv.Itoa(studentID), services.AuditActionDataExport, nil)
+
+	response.SuccessResponse(w, http.StatusOK, "Student data exported", export)
+}
+
+// EraseStudentData anonymizes a student's personal data on request of the student.
+// The erasure is recorded in the audit log, in the same transaction, with who requested
+// it and why.
+// DELETE /students/{id}/data {"requested_by": "...", "reason": "..."}
+func EraseStudentData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		response.ErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	studentID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || studentID <= 0 {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid student ID")
+		return
+	}
+
+	var req struct {
+		RequestedBy string `json:"requested_by"`
+		Reason      string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.ErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.RequestedBy = strings.TrimSpace(req.RequestedBy)
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.RequestedBy == "" || req.Reason == "" {
+		response.ErrorResponse(w, http.StatusBadRequest, "requested_by and reason are required")
+		return
+	}
+
+	erasure, err := services.EraseStudentData(r.Context(), studentID, &models.AuditLogEntry{
+		EntityType: services.AuditEntityLead,
+		EntityID:   strconv.Itoa(studentID),
+		Action:     services.AuditActionDataErasure,
+		Actor:      middleware.AuditActor(r),
+		Changes: models.AuditChanges{
+			"personal_data": {From: "held", To: "erased"},
+			"requested_by":  {To: req.RequestedBy},
+			"reason":        {To: req.Reason},
+		},
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrLeadNotFound):
+			response.ErrorResponse(w, http.StatusNotFound, "Student not found")
+		case errors.Is(err, services.ErrStudentDataAlreadyErased):
+			response.ErrorResponse(w, http.StatusConflict, err.Error())
+		default:
+			logger.FromContext(r.Context()).Error("Error erasing data of student %d: %v", studentID, err)
+			response.ErrorResponse(w, http.StatusInternalServerError, "Failed to erase student data")
+		}
+		return
+	}
+
+	response.SuccessResponse(w, http.StatusOK, "Student personal data erased", erasure)
+}
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"admission-module/config"
 	"admission-module/http/handlers"
 	"admission-module/http/middleware"
 	"admission-module/services"
@@ -9,10 +10,14 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // SetupRoutes configures all HTTP routes and middleware
 func SetupRoutes() {
+	paymentHandler := handlers.NewPaymentHandler(services.NewPaymentService())
+	applicationHandler := handlers.NewApplicationHandler(services.NewApplicationService())
+
 	// Serve static files
 	staticDir := "static"
 	absStaticDir, err := filepath.Abs(staticDir)
@@ -34,31 +39,167 @@ func SetupRoutes() {
 	})
 
 	// Lead Management APIs
-	http.HandleFunc("/upload-leads", middleware.EnableCORS(handlers.UploadLeads))
-	http.HandleFunc("/leads", middleware.EnableCORS(handlers.GetLeads))
-	http.HandleFunc("/create-lead", middleware.EnableCORS(handlers.CreateLead))
+	handleAPI("/upload-leads", middleware.EnableCORS(handlers.UploadLeads))
+	for _, pattern := range versionedPatterns("/upload-leads") {
+		middleware.SetBodyLimit(pattern, int64(config.AppConfig.MaxUploadBodyMB)<<20,
+			time.Duration(config.AppConfig.UploadTimeoutSeconds)*time.Second)
+	}
+	handleAPI("/leads", middleware.EnableCORS(handlers.GetLeads))
+	handleAPI("/leads/export", middleware.EnableCORS(handlers.ExportLeads))
+	handleAPI("/leads/{id}/notes", middleware.EnableCORS(handlers.LeadNotes))
+	handleAPI("/leads/{id}/timeline", middleware.EnableCORS(handlers.GetLeadTimeline))
+	handleAPI("/leads/{id}/progress", middleware.EnableCORS(handlers.GetLeadProgress))
+	handleAPI("/leads/{id}/merge", middleware.EnableCORS(handlers.MergeLead))
+	handleAPI("/leads/{id}/resend-payment-link", middleware.EnableCORS(handlers.ResendPaymentLink))
+	handleAPI("/leads/{id}/payment-links", middleware.EnableCORS(handlers.PaymentLinks))
+	handleAPI("/create-lead", middleware.EnableCORS(middleware.RateLimit("create-lead", handlers.CreateLead)))
+	handleAPI("/lead-reviews", middleware.EnableCORS(handlers.GetLeadReviews))
+	handleAPI("/lead-reviews/{id}/approve", middleware.EnableCORS(handlers.ApproveLeadReview))
+	handleAPI("/lead-reviews/{id}/merge", middleware.EnableCORS(handlers.MergeLeadReview))
+
+	// Inbound Lead Integration Health APIs
+	handleAPI("/integrations", middleware.EnableCORS(handlers.LeadIntegrations))
+	handleAPI("/integrations/{id}", middleware.EnableCORS(handlers.UpdateLeadIntegration))
+
+	// Import History APIs
+	handleAPI("/import-jobs", middleware.EnableCORS(handlers.GetImportJobs))
+	handleAPI("/import-jobs/inbound", middleware.EnableCORS(handlers.GetInboundImports))
+	handleAPI("/import-jobs/{id}/rollback", middleware.EnableCORS(handlers.RollbackImportJob))
+
+	// Marketing spend and reporting APIs
+	handleAPI("/marketing-spend", middleware.EnableCORS(handlers.MarketingSpend))
+	handleAPI("/reports/cac", middleware.EnableCORS(handlers.GetCACReport))
+	handleAPI("/reports/potential-duplicates", middleware.EnableCORS(handlers.GetPotentialDuplicates))
+	handleAPI("/reports/manager-summary", middleware.EnableCORS(handlers.GetManagerSummary))
+	handleAPI("/reports/capacity-forecast", middleware.EnableCORS(handlers.GetCapacityForecast))
+	handleAPI("/analytics/funnel", middleware.EnableCORS(handlers.GetFunnel))
+	handleAPI("/analytics/stage-aging", middleware.EnableCORS(handlers.GetStageAging))
+	handleAPI("/analytics/campaigns", middleware.EnableCORS(handlers.GetCampaignConversions))
+	handleAPI("/analytics/snapshots", middleware.EnableCORS(handlers.GetMetricsSnapshots))
+
+	// Counselor commission APIs (payroll)
+	handleAPI("/admin/commission-rules", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.CommissionRules)))
+	handleAPI("/admin/commission-rules/{id}", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.DeactivateCommissionRule)))
+	handleAPI("/admin/commissions/adjustments", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.RecordCommissionAdjustment)))
+	handleAPI("/admin/commissions/refunds", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.RecordCommissionRefund)))
+	handleAPI("/admin/commissions/report", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.GetCommissionReport)))
+
+	// End-of-day summary email recipients
+	handleAPI("/admin/summary-recipients", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.SummaryRecipients)))
+	handleAPI("/admin/summary-recipients/{id}", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.RemoveSummaryRecipient)))
+
+	// Audit log of data changes (admin)
+	handleAPI("/audit", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.GetAuditLog)))
+	handleAPI("/audit/{entity_type}/{entity_id}", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.GetEntityAuditLog)))
+
+	// Lead Escalation APIs
+	handleAPI("/admin/escalations", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.GetEscalationQueue)))
+	handleAPI("/admin/escalations/{id}/resolve", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.ResolveEscalation)))
+	handleAPI("/admin/config/reload", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.ReloadConfig)))
+	handleAPI("/admin/retention/runs", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.RetentionRuns)))
+
+	// Async Document Generation APIs
+	handleAPI("/documents", middleware.EnableCORS(handlers.RequestDocument))
+	handleAPI("/documents/{id}", middleware.EnableCORS(handlers.GetDocumentJob))
+	handleAPI("/documents/{id}/download", middleware.EnableCORS(handlers.DownloadDocument))
+
+	// Counselor Notification APIs
+	handleAPI("/counselors/{id}/notifications", middleware.EnableCORS(handlers.GetCounselorNotifications))
+	handleAPI("/counselors/{id}/notifications/{notificationId}/read", middleware.EnableCORS(handlers.MarkNotificationRead))
+
+	// Counselor Performance APIs
+	handleAPI("/counselors/{id}/metrics", middleware.EnableCORS(handlers.GetCounselorMetrics))
+	handleAPI("/counselors/{id}/shifts", middleware.EnableCORS(handlers.CounselorShifts))
 
 	// Course Management APIs
-	http.HandleFunc("/courses", middleware.EnableCORS(handlers.GetCourses))
-	http.HandleFunc("/course", middleware.EnableCORS(handlers.GetCourseByID))
-	http.HandleFunc("/create-course", middleware.EnableCORS(handlers.CreateCourse))
-	http.HandleFunc("/update-course", middleware.EnableCORS(handlers.UpdateCourse))
+	handleAPI("/courses", middleware.EnableCORS(handlers.GetCourses))
+	handleAPI("/course", middleware.EnableCORS(handlers.GetCourseByID))
+	handleAPI("/create-course", middleware.EnableCORS(handlers.CreateCourse))
+	handleAPI("/update-course", middleware.EnableCORS(handlers.UpdateCourse))
+	handleAPI("/courses/{id}/content-blocks", middleware.EnableCORS(handlers.GetCourseContentBlocks))
+	handleAPI("/courses/{id}/content-blocks/{key}", middleware.EnableCORS(handlers.CourseContentBlock))
+	handleAPI("/admin/email-templates/check", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.CheckEmailTemplates)))
 
 	// Payment APIs
-	http.HandleFunc("/initiate-payment", middleware.EnableCORS(handlers.InitiatePayment))
-	http.HandleFunc("/verify-payment", middleware.EnableCORS(handlers.VerifyPayment))
-	http.HandleFunc("/payment-status", middleware.EnableCORS(handlers.GetPaymentStatus))
+	handleAPI("/initiate-payment", middleware.EnableCORS(middleware.RateLimit("initiate-payment", paymentHandler.InitiatePayment)))
+	handleAPI("/verify-payment", middleware.EnableCORS(paymentHandler.VerifyPayment))
+	handleAPI("/payment-status", middleware.EnableCORS(paymentHandler.GetPaymentStatus))
+	handleAPI("/refund-payment", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.RefundPayment)))
+	handleAPI("/coupons", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.Coupons)))
+	handleAPI("/coupons/{id}", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.UpdateCoupon)))
+	handleAPI("/students/{id}/invoices", middleware.EnableCORS(handlers.GetStudentInvoices))
+	handleAPI("/students/{id}/data", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.EraseStudentData)))
+	handleAPI("/students/{id}/data-export", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.ExportStudentData)))
+	handleAPI("/invoices/{id}/download", middleware.EnableCORS(handlers.DownloadInvoice))
+	handleAPI("/invoices/export", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.ExportInvoices)))
+	handleAPI("/payments/{id}/receipt", middleware.EnableCORS(handlers.DownloadPaymentReceipt))
+
+	// LMS/ERP Enrollment Handoff APIs
+	handleAPI("/enrollment-sync", middleware.EnableCORS(handlers.GetEnrollmentSyncs))
+	handleAPI("/enrollment-sync/{student_id}/retry", middleware.EnableCORS(handlers.RetryEnrollmentSync))
 
 	// Razorpay Webhook - No CORS needed for webhook (server-to-server)
 	http.HandleFunc("/razorpay/webhook", services.RazorpayWebhookHandler)
 
 	// Interview & Application APIs
-	http.HandleFunc("/schedule-meet", middleware.EnableCORS(handlers.ScheduleMeet))
-	http.HandleFunc("/application-action", middleware.EnableCORS(handlers.ApplicationAction))
+	handleAPI("/schedule-meet", middleware.EnableCORS(handlers.ScheduleMeet))
+	handleAPI("/interviewers", middleware.EnableCORS(handlers.Interviewers))
+	handleAPI("/interviewers/{id}", middleware.EnableCORS(handlers.UpdateInterviewer))
+	handleAPI("/application-action", middleware.EnableCORS(applicationHandler.ApplicationAction))
+	handleAPI("/application-approvals", middleware.EnableCORS(handlers.GetPendingAcceptanceApprovals))
+	handleAPI("/admin/applications/{id}/accept", middleware.EnableAdminCORS(middleware.RequireAdminToken(applicationHandler.OverrideAcceptance)))
+	handleAPI("/admin/decisions/import", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.ImportDecisions)))
+	for _, pattern := range versionedPatterns("/admin/decisions/import") {
+		middleware.SetBodyLimit(pattern, int64(config.AppConfig.MaxUploadBodyMB)<<20,
+			time.Duration(config.AppConfig.UploadTimeoutSeconds)*time.Second)
+	}
+	handleAPI("/admin/leads/status-projection/rebuild", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.RebuildStatusProjection)))
+
+	// Health APIs
+	http.HandleFunc("/live", handlers.Live)
+	http.HandleFunc("/ready", handlers.Ready)
+	http.HandleFunc("/health", handlers.Health)
+	http.HandleFunc("/readyz", handlers.Readyz)
+	http.HandleFunc("/doctor", handlers.Doctor)
+	http.HandleFunc("/metrics", handlers.Metrics)
+	handleAPI("/admin/slo", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.GetWebhookSLO)))
+	handleAPI("/admin/api-usage", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.GetAPIUsage)))
+	handleAPI("/admin/email-overflow", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.GetEmailOverflow)))
+	handleAPI("/admin/interviews/pending", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.GetPendingInterviews)))
+	handleAPI("/admin/payment-reconciliation", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.PaymentReconciliation)))
+	handleAPI("/admin/payment-reconciliation/issues/{id}/resolve", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.ResolveReconciliationIssue)))
 
 	// DLQ Management APIs
-	http.HandleFunc("/api/dlq/messages", middleware.EnableCORS(handlers.GetDLQMessages))
-	http.HandleFunc("/api/dlq/messages/retry/", middleware.EnableCORS(handlers.RetryDLQMessage))
-	http.HandleFunc("/api/dlq/messages/resolve/", middleware.EnableCORS(handlers.ResolveDLQMessage))
-	http.HandleFunc("/api/dlq/stats", middleware.EnableCORS(handlers.GetDLQStats))
+	handleAPI("/api/dlq/messages", middleware.EnableAdminCORS(handlers.GetDLQMessages))
+	handleAPI("/api/dlq/messages/retry/", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.RetryDLQMessage)))
+	handleAPI("/api/dlq/messages/resolve/", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.ResolveDLQMessage)))
+	handleAPI("/api/dlq/messages/resolve-batch", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.ResolveDLQMessages)))
+	handleAPI("/api/dlq/retry-all", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.RetryAllDLQMessages)))
+	handleAPI("/api/dlq/resolve-all", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.ResolveAllDLQMessages)))
+	handleAPI("/api/dlq/archive", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.ArchiveDLQMessages)))
+	handleAPI("/api/dlq/archive/{id}", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.GetArchivedDLQMessage)))
+	handleAPI("/api/dlq/quarantine", middleware.EnableAdminCORS(handlers.GetQuarantinedDLQMessages))
+	handleAPI("/api/dlq/quarantine/{id}/force-retry", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.ForceRetryDLQMessage)))
+	handleAPI("/api/dlq/stats", middleware.EnableAdminCORS(handlers.GetDLQStats))
+	handleAPI("/api/dlq/policies", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.DLQPolicies)))
+	handleAPI("/admin/dlq/messages/{id}/reveal", middleware.EnableAdminCORS(middleware.RequireAdminToken(handlers.RevealDLQMessage)))
+}
+
+// handleAPI registers an API route under every API version (/v1/leads, /v2/leads) and at
+// its bare path, which serves v1 for clients from before versioning
+func handleAPI(pattern string, handler http.HandlerFunc) {
+	patterns := versionedPatterns(pattern)
+	http.HandleFunc(patterns[0], middleware.APIVersion(middleware.APIVersionV1, handler))
+	for i, version := range middleware.APIVersions {
+		http.HandleFunc(patterns[i+1], middleware.APIVersion(version, handler))
+	}
+}
+
+// versionedPatterns returns the bare pattern followed by its variant under each API version
+func versionedPatterns(pattern string) []string {
+	patterns := []string{pattern}
+	for _, version := range middleware.APIVersions {
+		patterns = append(patterns, "/"+version+pattern)
+	}
+	return patterns
 }
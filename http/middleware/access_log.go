@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"admission-module/logger"
+	"net/http"
+	"time"
+)
+
+// AccessLog writes one entry per request with the method, path, status, latency, client
+// IP and (when RequestID runs first) request ID. Server errors are logged at WARN.
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		elapsed := time.Since(started)
+		log := logger.FromContext(r.Context()).WithFields(map[string]interface{}{
+			"method":     r.Method,
+			"path":       r.URL.Path,
+			"status":     rec.status,
+			"latency_ms": elapsed.Milliseconds(),
+			"remote_ip":  rateLimitClientIP(r),
+		})
+		if rec.status >= http.StatusInternalServerError {
+			log.Warn("%s %s -> %d in %s", r.Method, r.URL.Path, rec.status, elapsed)
+		} else {
+			log.Info("%s %s -> %d in %s", r.Method, r.URL.Path, rec.status, elapsed)
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"admission-module/config"
+	"admission-module/http/response"
+	"crypto/subtle"
+	"net/http"
+)
+
+// hasValidAdminToken reports whether the request's X-Admin-Token header matches a
+// configured ADMIN_API_TOKEN
+func hasValidAdminToken(r *http.Request) bool {
+	expected := config.AppConfig.AdminAPIToken
+	token := r.Header.Get("X-Admin-Token")
+	return expected != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// RequireAdminToken only lets requests through whose X-Admin-Token header matches
+// ADMIN_API_TOKEN. The endpoint is disabled while no token is configured.
+func RequireAdminToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		expected := config.AppConfig.AdminAPIToken
+		if expected == "" {
+			response.ErrorResponse(w, http.StatusForbidden, "Admin endpoint disabled: ADMIN_API_TOKEN is not set")
+			return
+		}
+
+		if !hasValidAdminToken(r) {
+			response.ErrorResponse(w, http.StatusUnauthorized, "Invalid or missing admin token")
+			return
+		}
+
+		next(w, r)
+	}
+}
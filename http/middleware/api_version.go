@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"admission-module/config"
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// API versions. Every API route is served under /v1 and /v2, and at its bare path as an
+// alias of v1 for clients from before versioning. Handlers whose response shape changed
+// between versions pick a converter by APIVersionFromContext.
+const (
+	APIVersionV1 = "v1" // deprecated, see API_V1_SUNSET
+	APIVersionV2 = "v2"
+
+	CurrentAPIVersion = APIVersionV2
+)
+
+// APIVersions lists the supported versions, oldest first
+var APIVersions = []string{APIVersionV1, APIVersionV2}
+
+// APIVersionHeader tells clients which version answered the request
+const APIVersionHeader = "X-API-Version"
+
+type apiVersionKey struct{}
+
+// APIVersion serves next as version of the API, marking responses of versions older than
+// CurrentAPIVersion as deprecated: Deprecation, Sunset (API_V1_SUNSET, when set) and a
+// Link to the same route under the current version.
+func APIVersion(version string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(APIVersionHeader, version)
+		if version != CurrentAPIVersion {
+			w.Header().Set("Deprecation", "true")
+			if sunset, err := time.Parse("2006-01-02", config.AppConfig.APIV1Sunset); err == nil {
+				w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			successor := "/" + CurrentAPIVersion + strings.TrimPrefix(r.URL.Path, "/"+version)
+			w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), apiVersionKey{}, version)))
+	}
+}
+
+// APIVersionFromContext returns the API version of the request, v1 outside a versioned route
+func APIVersionFromContext(ctx context.Context) string {
+	if version, ok := ctx.Value(apiVersionKey{}).(string); ok {
+		return version
+	}
+	return APIVersionV1
+}
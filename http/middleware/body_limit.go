@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"admission-module/config"
+	"admission-module/http/response"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// bodyLimit is the largest body a route accepts and how long the client gets to send
+// it and read the response
+type bodyLimit struct {
+	maxBytes int64
+	timeout  time.Duration
+}
+
+var (
+	bodyLimitsMutex sync.RWMutex
+	// bodyLimits holds the routes (by pattern) allowed a larger body than MAX_REQUEST_BODY_KB
+	bodyLimits = map[string]bodyLimit{}
+)
+
+// SetBodyLimit lets requests to a route pattern carry up to maxBytes, extending the
+// server read and write timeouts to timeout (e.g. spreadsheet uploads)
+func SetBodyLimit(pattern string, maxBytes int64, timeout time.Duration) {
+	bodyLimitsMutex.Lock()
+	defer bodyLimitsMutex.Unlock()
+	bodyLimits[pattern] = bodyLimit{maxBytes: maxBytes, timeout: timeout}
+}
+
+// LimitBody reads request bodies up front, up to the route's limit (MAX_REQUEST_BODY_KB
+// unless set with SetBodyLimit), so handlers decode from memory. A larger body gets a
+// 413 and a client that stops sending before the read timeout gets a 408, instead of a
+// handler's generic 400. Routes are looked up on http.DefaultServeMux.
+func LimitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		limit := bodyLimit{maxBytes: int64(config.AppConfig.MaxRequestBodyKB) << 10}
+		_, pattern := http.DefaultServeMux.Handler(r)
+		bodyLimitsMutex.RLock()
+		if routeLimit, ok := bodyLimits[pattern]; ok {
+			limit = routeLimit
+		}
+		bodyLimitsMutex.RUnlock()
+
+		if limit.timeout > 0 {
+			controller := http.NewResponseController(w)
+			deadline := time.Now().Add(limit.timeout)
+			if err := controller.SetReadDeadline(deadline); err == nil {
+				controller.SetWriteDeadline(deadline)
+			}
+		}
+
+		if r.ContentLength > limit.maxBytes {
+			bodyTooLarge(w, limit.maxBytes)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit.maxBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			var netErr net.Error
+			switch {
+			case errors.As(err, &tooLarge):
+				bodyTooLarge(w, limit.maxBytes)
+			case errors.Is(err, os.ErrDeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()):
+				// The write deadline may have passed with the read deadline; leave time for the reply
+				http.NewResponseController(w).SetWriteDeadline(time.Now().Add(5 * time.Second))
+				w.Header().Set("Connection", "close")
+				response.ErrorResponse(w, http.StatusRequestTimeout, "Request body was not received in time")
+			default:
+				response.ErrorResponse(w, http.StatusBadRequest, "Could not read request body")
+			}
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+func bodyTooLarge(w http.ResponseWriter, maxBytes int64) {
+	w.Header().Set("Connection", "close")
+	response.ErrorResponse(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body exceeds the %s limit", formatBytes(maxBytes)))
+}
+
+// formatBytes renders a body limit as "512 KB" or "20 MB"
+func formatBytes(n int64) string {
+	if n >= 1<<20 && n%(1<<20) == 0 {
+		return fmt.Sprintf("%d MB", n>>20)
+	}
+	return fmt.Sprintf("%d KB", (n+1<<10-1)>>10)
+}
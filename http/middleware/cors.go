@@ -1,13 +1,42 @@
 package middleware
 
-import "net/http"
+import (
+	"admission-module/config"
+	"net/http"
+	"strconv"
+	"strings"
+)
 
+// exposedHeaders are the response headers browser clients may read
+const exposedHeaders = "X-Request-ID, X-API-Version, Deprecation, Sunset, Link, Retry-After"
+
+// EnableCORS applies the public CORS policy (CORS_*) to next
 func EnableCORS(next http.HandlerFunc) http.HandlerFunc {
+	return corsHandler(false, next)
+}
+
+// EnableAdminCORS applies the admin CORS policy (ADMIN_CORS_*) to next. Wrap it around
+// RequireAdminToken so preflight requests, which carry no token, are answered.
+func EnableAdminCORS(next http.HandlerFunc) http.HandlerFunc {
+	return corsHandler(true, next)
+}
+
+func corsHandler(admin bool, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		policy := config.CORSPolicyFor(admin)
+		w.Header().Add("Vary", "Origin")
+
+		if origin, ok := allowedOrigin(policy, r.Header.Get("Origin")); ok {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", policy.AllowedMethods)
+			w.Header().Set("Access-Control-Allow-Headers", policy.AllowedHeaders)
+			w.Header().Set("Access-Control-Expose-Headers", exposedHeaders)
+			if r.Method == "OPTIONS" && policy.MaxAgeSeconds > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(policy.MaxAgeSeconds))
+			}
+		}
 
+		// A preflight from a disallowed origin gets no CORS headers, so the browser blocks it
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
 			return
@@ -16,3 +45,27 @@ func EnableCORS(next http.HandlerFunc) http.HandlerFunc {
 		next(w, r)
 	}
 }
+
+// allowedOrigin returns the Access-Control-Allow-Origin value for a request origin: "*"
+// when the policy allows any origin, otherwise the origin itself if it is listed or
+// matches a "https://*.example.com" pattern
+func allowedOrigin(policy config.CORSPolicy, origin string) (string, bool) {
+	for _, allowed := range policy.AllowedOrigins {
+		if allowed == "*" {
+			return "*", true
+		}
+		if origin == "" {
+			continue
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin, true
+		}
+		if scheme, domain, ok := strings.Cut(allowed, "://*."); ok {
+			host, found := strings.CutPrefix(strings.ToLower(origin), strings.ToLower(scheme)+"://")
+			if found && strings.HasSuffix(host, "."+strings.ToLower(domain)) {
+				return origin, true
+			}
+		}
+	}
+	return "", false
+}
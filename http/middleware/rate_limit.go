@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"admission-module/config"
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/metrics"
+	"admission-module/ratelimit"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rateLimitWarnInterval spaces out the warnings logged while the limiter backend fails
+const rateLimitWarnInterval = time.Minute
+
+var (
+	limiterOnce sync.Once
+	limiter     ratelimit.Limiter
+	// limiterWarnedAt is when a backend failure was last logged (unix seconds)
+	limiterWarnedAt atomic.Int64
+)
+
+// rateLimiter returns the limiter of RATE_LIMIT_BACKEND, created on first use
+func rateLimiter() ratelimit.Limiter {
+	limiterOnce.Do(func() {
+		cfg := config.AppConfig
+		if cfg.RateLimitBackend == "redis" {
+			limiter = ratelimit.NewRedisLimiter(cfg.RateLimitRedisAddr, cfg.RateLimitRedisPassword, cfg.RateLimitRedisDB)
+			logger.Info("Rate limiting with Redis at %s", cfg.RateLimitRedisAddr)
+			return
+		}
+		limiter = ratelimit.NewMemoryLimiter()
+	})
+	return limiter
+}
+
+// RateLimit limits the requests each client IP makes to next with the token bucket of
+// policy in RATE_LIMITS; a policy missing there is not limited. Rejected requests get a
+// 429 with Retry-After and are counted in admission_http_rate_limited_total. When the
+// backend fails the request is let through, so Redis going down cannot take the
+// endpoint down with it.
+func RateLimit(policy string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, ok := config.RateLimitFor(policy)
+		if !ok {
+			next(w, r)
+			return
+		}
+
+		result, err := rateLimiter().Allow(r.Context(), policy+":"+rateLimitClientIP(r),
+			ratelimit.Limit{PerMinute: limit.PerMinute, Burst: limit.Burst})
+		if err != nil {
+			now := time.Now().Unix()
+			if last := limiterWarnedAt.Load(); now-last >= int64(rateLimitWarnInterval.Seconds()) && limiterWarnedAt.CompareAndSwap(last, now) {
+				logger.FromContext(r.Context()).Warn("Rate limiter unavailable, letting %s requests through: %v", policy, err)
+			}
+			next(w, r)
+			return
+		}
+
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit.PerMinute))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		if !result.Allowed {
+			retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			metrics.ObserveRateLimited(policy)
+			response.ErrorResponse(w, http.StatusTooManyRequests, "Too many requests, please try again later")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// rateLimitClientIP is the address the limit applies to. Behind RATE_LIMIT_PROXY_HOPS
+// trusted proxies it is the X-Forwarded-For entry the outermost proxy added; entries
+// further left are set by the client and could be forged to dodge the limit.
+func rateLimitClientIP(r *http.Request) string {
+	if hops := config.AppConfig.RateLimitProxyHops; hops > 0 {
+		entries := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+		if len(entries) >= hops {
+			if ip := strings.TrimSpace(entries[len(entries)-hops]); net.ParseIP(ip) != nil {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
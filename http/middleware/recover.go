@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"admission-module/http/response"
+	"admission-module/logger"
+	"admission-module/metrics"
+	"net/http"
+	"runtime/debug"
+)
+
+// headerTracker notes whether the handler has started its response
+type headerTracker struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (t *headerTracker) WriteHeader(status int) {
+	t.wroteHeader = true
+	t.ResponseWriter.WriteHeader(status)
+}
+
+func (t *headerTracker) Write(b []byte) (int, error) {
+	t.wroteHeader = true
+	return t.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (t *headerTracker) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
+
+// Recover turns a handler panic into a 500 error response, logging the panic with its
+// stack and the request ID and counting it in admission_http_panics_total. A response
+// the handler had already started cannot be replaced and is left as it is.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tracker := &headerTracker{ResponseWriter: w}
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				// Deliberate abort: let net/http drop the connection quietly
+				panic(recovered)
+			}
+
+			route := r.Pattern
+			if route == "" {
+				route = "unmatched"
+			}
+			metrics.ObserveHTTPPanic(route)
+			logger.FromContext(r.Context()).Error("Panic serving %s %s: %v\n%s", r.Method, r.URL.Path, recovered, debug.Stack())
+
+			if !tracker.wroteHeader {
+				response.ErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+			}
+		}()
+
+		next.ServeHTTP(tracker, r)
+	})
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"admission-module/http/response"
+	"admission-module/services"
+	"net/http"
+)
+
+// startupProbes are answered while the service is still starting
+var startupProbes = map[string]bool{
+	"/live":  true,
+	"/ready": true,
+}
+
+// RequireStarted answers every request but the liveness and readiness probes with a 503
+// until services.MarkStartupComplete, since the database and Kafka are not set up yet
+func RequireStarted(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !services.StartupComplete() && !startupProbes[r.URL.Path] {
+			w.Header().Set("Retry-After", "5")
+			response.ErrorResponse(w, http.StatusServiceUnavailable, "Service is starting")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
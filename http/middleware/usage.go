@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"admission-module/services"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxClientIDLength caps self-reported X-Client-ID values so consumer names fit api_usage
+const maxClientIDLength = 64
+
+// APIUsage records every request against the calling consumer and the matched route,
+// aggregated per hour into api_usage (see services.RecordAPIUsage)
+func APIUsage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		services.RecordAPIUsage(APIConsumer(r), route, r.Method, rec.status, time.Since(started))
+	})
+}
+
+// APIConsumer identifies who made a request: an API key (by hash, never the key itself),
+// a valid admin token, Razorpay webhooks, a self-reported X-Client-ID, or else the client IP
+func APIConsumer(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		sum := sha256.Sum256([]byte(key))
+		return "key:" + hex.EncodeToString(sum[:6])
+	}
+	if hasValidAdminToken(r) {
+		return "admin"
+	}
+	if r.Header.Get("X-Razorpay-Signature") != "" {
+		return "razorpay"
+	}
+	if clientID := strings.TrimSpace(r.Header.Get("X-Client-ID")); clientID != "" {
+		if len(clientID) > maxClientIDLength {
+			clientID = clientID[:maxClientIDLength]
+		}
+		return "client:" + clientID
+	}
+	return "ip:" + rateLimitClientIP(r)
+}
+
+// AuditActor names who made a change for the audit log: the staff member in the X-Actor
+// header set by the admissions frontend, or else the API consumer
+func AuditActor(r *http.Request) string {
+	if actor := strings.TrimSpace(r.Header.Get("X-Actor")); actor != "" {
+		if len(actor) > maxClientIDLength {
+			actor = actor[:maxClientIDLength]
+		}
+		return actor
+	}
+	return APIConsumer(r)
+}
@@ -0,0 +1,28 @@
+package logger
+
+import "context"
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying the request ID
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, or "" outside a request
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// FromContext returns the default logger with a request_id field when ctx carries a
+// request ID, so entries written while handling a request can be correlated
+func FromContext(ctx context.Context) *Logger {
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		return defaultLogger.WithFields(map[string]interface{}{"request_id": requestID})
+	}
+	return defaultLogger
+}
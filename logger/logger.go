@@ -1,12 +1,15 @@
 package logger
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -44,16 +47,60 @@ func (l Level) String() string {
 	}
 }
 
+// ParseLevel converts a level name such as "debug" or "WARN" to a Level
+func ParseLevel(name string) (Level, bool) {
+	for level := DEBUG; level <= FATAL; level++ {
+		if strings.EqualFold(strings.TrimSpace(name), level.String()) {
+			return level, true
+		}
+	}
+	return INFO, false
+}
+
+// Format selects how log entries are written
+type Format int
+
+const (
+	// TextFormat writes "[timestamp] LEVEL caller message key=value ..." lines
+	TextFormat Format = iota
+	// JSONFormat writes one JSON object per entry, for log shippers such as ELK
+	JSONFormat
+)
+
+// String returns the name of the format as used in LOG_FORMAT
+func (f Format) String() string {
+	if f == JSONFormat {
+		return "json"
+	}
+	return "text"
+}
+
+// ParseFormat converts a format name ("text" or "json") to a Format
+func ParseFormat(name string) (Format, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "text":
+		return TextFormat, true
+	case "json":
+		return JSONFormat, true
+	}
+	return TextFormat, false
+}
+
 // Logger represents a structured logger
 type Logger struct {
-	level  Level
-	logger *log.Logger
-	writer io.Writer
+	level        Level
+	format       Format
+	timeFormat   string
+	enableCaller bool
+	fields       map[string]interface{}
+	logger       *log.Logger
+	writer       io.Writer
 }
 
 // Config holds the configuration for the logger
 type Config struct {
 	Level        Level
+	Format       Format
 	Output       io.Writer
 	TimeFormat   string
 	EnableCaller bool
@@ -69,8 +116,11 @@ func New(config Config) *Logger {
 	}
 
 	logger := &Logger{
-		level:  config.Level,
-		writer: config.Output,
+		level:        config.Level,
+		format:       config.Format,
+		timeFormat:   config.TimeFormat,
+		enableCaller: config.EnableCaller,
+		writer:       config.Output,
 	}
 
 	logger.logger = log.New(logger.writer, "", 0)
@@ -93,20 +143,34 @@ func (l *Logger) SetLevel(level Level) {
 	l.level = level
 }
 
-// log writes a log entry if the level is enabled
-func (l *Logger) log(level Level, message string, args ...interface{}) {
+// SetFormat switches between text and JSON output
+func (l *Logger) SetFormat(format Format) {
+	l.format = format
+}
+
+// SetOutput redirects entries to w. Loggers derived with WithFields share the output
+// and follow the change.
+func (l *Logger) SetOutput(w io.Writer) {
+	l.writer = w
+	l.logger.SetOutput(w)
+}
+
+// log writes a log entry if the level is enabled. callerSkip is the number of stack
+// frames between the code that logged and log itself.
+func (l *Logger) log(level Level, callerSkip int, message string, args ...interface{}) {
 	if level < l.level {
 		return
 	}
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	now := time.Now()
 	levelStr := level.String()
 
+	// JSON entries always carry the caller so they can be searched by source file
 	var caller string
-	if l.logger.Flags()&log.Lshortfile != 0 {
-		_, file, line, ok := runtime.Caller(2)
+	if l.enableCaller || l.format == JSONFormat {
+		_, file, line, ok := runtime.Caller(callerSkip + 1)
 		if ok {
-			caller = fmt.Sprintf("%s:%d ", filepath.Base(file), line)
+			caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
 		}
 	}
 
@@ -115,62 +179,138 @@ func (l *Logger) log(level Level, message string, args ...interface{}) {
 		formattedMessage = fmt.Sprintf(message, args...)
 	}
 
-	logEntry := fmt.Sprintf("[%s] %s %s%s\n", timestamp, levelStr, caller, formattedMessage)
-
-	l.logger.Print(logEntry)
+	if l.format == JSONFormat {
+		l.logger.Print(l.jsonEntry(now, levelStr, caller, formattedMessage))
+	} else {
+		l.logger.Print(l.textEntry(now, levelStr, caller, formattedMessage))
+	}
 
 	if level == FATAL {
 		os.Exit(1)
 	}
 }
 
+// textEntry formats an entry as "[timestamp] LEVEL caller message key=value ..."
+func (l *Logger) textEntry(now time.Time, level, caller, message string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s ", now.Format(l.timeFormat), level)
+	if caller != "" {
+		b.WriteString(caller + " ")
+	}
+	b.WriteString(message)
+	for _, key := range l.fieldKeys() {
+		fmt.Fprintf(&b, " %s=%v", key, l.fields[key])
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// jsonEntry formats an entry as a single-line JSON object
+func (l *Logger) jsonEntry(now time.Time, level, caller, message string) string {
+	entry := map[string]interface{}{
+		"timestamp": now.Format(time.RFC3339Nano),
+		"level":     level,
+		"message":   message,
+	}
+	if caller != "" {
+		entry["caller"] = caller
+	}
+	if len(l.fields) > 0 {
+		fields := make(map[string]interface{}, len(l.fields))
+		for key, value := range l.fields {
+			fields[key] = jsonFieldValue(value)
+		}
+		entry["fields"] = fields
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		// Only reachable through a field value json cannot encode; keep the entry
+		delete(entry, "fields")
+		entry["fields_error"] = err.Error()
+		data, _ = json.Marshal(entry)
+	}
+	return string(data) + "\n"
+}
+
+// jsonFieldValue makes a field value encodable: errors and Stringers become their text
+// (an error would otherwise encode as {}), values json rejects are formatted with %v
+func jsonFieldValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	if _, err := json.Marshal(value); err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return value
+}
+
+// fieldKeys returns the logger's field names in a stable order
+func (l *Logger) fieldKeys() []string {
+	keys := make([]string, 0, len(l.fields))
+	for key := range l.fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // Debug logs a debug message
 func (l *Logger) Debug(message string, args ...interface{}) {
-	l.log(DEBUG, message, args...)
+	l.log(DEBUG, 1, message, args...)
 }
 
 // Info logs an info message
 func (l *Logger) Info(message string, args ...interface{}) {
-	l.log(INFO, message, args...)
+	l.log(INFO, 1, message, args...)
 }
 
 // Warn logs a warning message
 func (l *Logger) Warn(message string, args ...interface{}) {
-	l.log(WARN, message, args...)
+	l.log(WARN, 1, message, args...)
 }
 
 // Error logs an error message
 func (l *Logger) Error(message string, args ...interface{}) {
-	l.log(ERROR, message, args...)
+	l.log(ERROR, 1, message, args...)
 }
 
 // Fatal logs a fatal message and exits the program
 func (l *Logger) Fatal(message string, args ...interface{}) {
-	l.log(FATAL, message, args...)
+	l.log(FATAL, 1, message, args...)
 }
 
 // WithCaller enables caller information in log entries
 func (l *Logger) WithCaller() *Logger {
-	l.logger.SetFlags(log.Lshortfile)
+	l.enableCaller = true
 	return l
 }
 
-// WithFields creates a logger with structured fields (simple implementation)
+// WithFields returns a logger that adds the given key/value fields to every entry,
+// on top of the fields this logger already carries. Text entries end with key=value
+// pairs; JSON entries carry them in a "fields" object.
 func (l *Logger) WithFields(fields map[string]interface{}) *Logger {
-	// For simplicity, we'll just prefix the message with fields
-	// In a real implementation, you might want to use a more sophisticated approach
-	prefix := ""
-	for k, v := range fields {
-		prefix += fmt.Sprintf("%s=%v ", k, v)
+	merged := make(map[string]interface{}, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		merged[k] = v
 	}
-	if prefix != "" {
-		prefix = prefix[:len(prefix)-1] + " " // remove last space and add space after
+	for k, v := range fields {
+		merged[k] = v
 	}
 
 	return &Logger{
-		level:  l.level,
-		writer: l.writer,
-		logger: log.New(l.writer, prefix, l.logger.Flags()),
+		level:        l.level,
+		format:       l.format,
+		timeFormat:   l.timeFormat,
+		enableCaller: l.enableCaller,
+		fields:       merged,
+		writer:       l.writer,
+		logger:       l.logger,
 	}
 }
 
@@ -187,27 +327,47 @@ func SetDefault(logger *Logger) {
 	defaultLogger = logger
 }
 
+// SetLevel sets the minimum level of the default logger
+func SetLevel(level Level) {
+	defaultLogger.SetLevel(level)
+}
+
+// SetFormat sets the output format of the default logger
+func SetFormat(format Format) {
+	defaultLogger.SetFormat(format)
+}
+
+// SetOutput redirects the default logger, e.g. to a RotatingFile
+func SetOutput(w io.Writer) {
+	defaultLogger.SetOutput(w)
+}
+
+// WithFields returns a logger that adds fields to every entry of the default logger
+func WithFields(fields map[string]interface{}) *Logger {
+	return defaultLogger.WithFields(fields)
+}
+
 // Debug logs a debug message using the default logger
 func Debug(message string, args ...interface{}) {
-	defaultLogger.Debug(message, args...)
+	defaultLogger.log(DEBUG, 1, message, args...)
 }
 
 // Info logs an info message using the default logger
 func Info(message string, args ...interface{}) {
-	defaultLogger.Info(message, args...)
+	defaultLogger.log(INFO, 1, message, args...)
 }
 
 // Warn logs a warning message using the default logger
 func Warn(message string, args ...interface{}) {
-	defaultLogger.Warn(message, args...)
+	defaultLogger.log(WARN, 1, message, args...)
 }
 
 // Error logs an error message using the default logger
 func Error(message string, args ...interface{}) {
-	defaultLogger.Error(message, args...)
+	defaultLogger.log(ERROR, 1, message, args...)
 }
 
 // Fatal logs a fatal message using the default logger
 func Fatal(message string, args ...interface{}) {
-	defaultLogger.Fatal(message, args...)
+	defaultLogger.log(FATAL, 1, message, args...)
 }
@@ -0,0 +1,193 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat names rotated files; it sorts chronologically and is safe in file names
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotateConfig controls when a RotatingFile starts a new file and which old files it keeps
+type RotateConfig struct {
+	Filename    string        // e.g. logs/admission.log; backups go next to it
+	MaxSizeMB   int           // rotate once the file would exceed this size; 0 = no size limit
+	RotateEvery time.Duration // rotate at each multiple of this interval (UTC); 0 = no time rotation
+	MaxBackups  int           // rotated files to keep; 0 = keep all
+	MaxAge      time.Duration // delete rotated files older than this; 0 = keep regardless of age
+}
+
+// RotatingFile is an io.Writer that appends to a log file and rotates it by size and time,
+// renaming the old file to name-<timestamp>.ext and pruning backups beyond the retention
+type RotatingFile struct {
+	mu       sync.Mutex
+	config   RotateConfig
+	file     *os.File
+	size     int64
+	periodAt time.Time // start of the rotation interval the current file belongs to
+}
+
+// NewRotatingFile opens (or creates) the log file, creating its directory if needed
+func NewRotatingFile(config RotateConfig) (*RotatingFile, error) {
+	if config.Filename == "" {
+		return nil, fmt.Errorf("log file name is required")
+	}
+	if err := os.MkdirAll(filepath.Dir(config.Filename), 0o755); err != nil {
+		return nil, fmt.Errorf("error creating log directory: %w", err)
+	}
+
+	r := &RotatingFile{config: config}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens the log file for appending. A file left from an earlier run counts towards
+// the interval it was last written in, so a restart after midnight still rotates it.
+func (r *RotatingFile) open() error {
+	file, err := os.OpenFile(r.config.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("error opening log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("error reading log file: %w", err)
+	}
+
+	r.file = file
+	r.size = info.Size()
+	r.periodAt = r.period(time.Now())
+	if r.size > 0 {
+		r.periodAt = r.period(info.ModTime())
+	}
+	return nil
+}
+
+// period returns the start of the rotation interval containing t
+func (r *RotatingFile) period(t time.Time) time.Time {
+	if r.config.RotateEvery <= 0 {
+		return time.Time{}
+	}
+	return t.UTC().Truncate(r.config.RotateEvery)
+}
+
+// Write appends p to the log file, rotating first when p would exceed the size limit or
+// the rotation interval has passed
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	maxSize := int64(r.config.MaxSizeMB) * 1024 * 1024
+	sizeExceeded := maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > maxSize
+	if sizeExceeded || !r.period(time.Now()).Equal(r.periodAt) {
+		if err := r.rotate(); err != nil {
+			// Keep logging to the current file rather than losing entries
+			fmt.Fprintf(os.Stderr, "log rotation failed: %v\n", err)
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, renames it to a timestamped backup and opens a new one
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return os.ErrClosed
+	}
+	return r.rotate()
+}
+
+func (r *RotatingFile) rotate() error {
+	if r.size == 0 {
+		// Nothing to keep; just move on to the new interval
+		r.periodAt = r.period(time.Now())
+		return nil
+	}
+
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("error closing log file: %w", err)
+	}
+	ext := filepath.Ext(r.config.Filename)
+	backup := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(r.config.Filename, ext), time.Now().UTC().Format(backupTimeFormat), ext)
+	renameErr := os.Rename(r.config.Filename, backup)
+
+	// Reopen even when the rename failed so logging carries on
+	if err := r.open(); err != nil {
+		r.file = nil
+		return err
+	}
+	r.periodAt = r.period(time.Now())
+	if renameErr != nil {
+		return fmt.Errorf("error renaming log file: %w", renameErr)
+	}
+
+	r.prune()
+	return nil
+}
+
+// prune removes backups beyond MaxBackups (oldest first) and those older than MaxAge
+func (r *RotatingFile) prune() {
+	if r.config.MaxBackups <= 0 && r.config.MaxAge <= 0 {
+		return
+	}
+
+	ext := filepath.Ext(r.config.Filename)
+	prefix := strings.TrimSuffix(r.config.Filename, ext) + "-"
+	matches, err := filepath.Glob(prefix + "*" + ext)
+	if err != nil {
+		return
+	}
+
+	type backup struct {
+		path string
+		at   time.Time
+	}
+	var backups []backup
+	for _, path := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(path, prefix), ext)
+		at, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue // not one of ours
+		}
+		backups = append(backups, backup{path: path, at: at})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].at.After(backups[j].at) })
+
+	cutoff := time.Now().Add(-r.config.MaxAge)
+	for i, b := range backups {
+		tooMany := r.config.MaxBackups > 0 && i >= r.config.MaxBackups
+		tooOld := r.config.MaxAge > 0 && b.at.Before(cutoff)
+		if tooMany || tooOld {
+			if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+				fmt.Fprintf(os.Stderr, "error removing old log file %s: %v\n", b.path, err)
+			}
+		}
+	}
+}
+
+// Close closes the log file; later writes fail with os.ErrClosed
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
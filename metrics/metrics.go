@@ -0,0 +1,251 @@
+// Package metrics keeps the in-process counters and histograms exposed on /metrics
+// in the Prometheus text format. It has no dependencies on the rest of the module so
+// both the HTTP middleware and the Kafka package can record into it.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Result label values for publish and send outcomes
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// httpLatencyBuckets are the upper bounds (seconds) of the HTTP latency histogram
+var httpLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// dbLatencyBuckets are the upper bounds (seconds) of the database query latency histogram
+var dbLatencyBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}
+
+var (
+	httpRequests = newCounterVec("admission_http_requests_total",
+		"HTTP requests handled, by route pattern, method and status code", "route", "method", "status")
+	httpDuration = newHistogramVec("admission_http_request_duration_seconds",
+		"HTTP request latency, by route pattern and method", httpLatencyBuckets, "route", "method")
+	httpPanics = newCounterVec("admission_http_panics_total",
+		"Handler panics recovered and answered with a 500, by route pattern", "route")
+	httpRateLimited = newCounterVec("admission_http_rate_limited_total",
+		"Requests rejected with a 429 by a rate limit, by policy", "policy")
+	kafkaPublishes = newCounterVec("admission_kafka_publish_total",
+		"Kafka messages published, by topic and result", "topic", "result")
+	emailSends = newCounterVec("admission_email_send_total",
+		"Emails handed to the SMTP server, by result", "result")
+	emailQuotaOverflow = newCounterVec("admission_email_quota_overflow_total",
+		"Emails dropped because their recipient reached the daily cap, by category", "category")
+	dbQueryDuration = newHistogramVec("admission_db_query_duration_seconds",
+		"Database query latency, by connection pool and normalized statement", dbLatencyBuckets, "pool", "query")
+	dbSlowQueries = newCounterVec("admission_db_slow_queries_total",
+		"Database queries slower than DB_SLOW_QUERY_THRESHOLD_MS, by connection pool", "pool")
+)
+
+// ObserveHTTPRequest records one handled HTTP request
+func ObserveHTTPRequest(route, method string, status int, elapsed time.Duration) {
+	httpRequests.inc(route, method, strconv.Itoa(status))
+	httpDuration.observe(elapsed.Seconds(), route, method)
+}
+
+// ObserveHTTPPanic records a handler panic recovered on route
+func ObserveHTTPPanic(route string) {
+	httpPanics.inc(route)
+}
+
+// ObserveRateLimited records a request rejected by the rate limit policy
+func ObserveRateLimited(policy string) {
+	httpRateLimited.inc(policy)
+}
+
+// ObserveKafkaPublish records the outcome of publishing one message to topic
+func ObserveKafkaPublish(topic string, err error) {
+	kafkaPublishes.add(1, topic, result(err))
+}
+
+// ObserveKafkaPublishes records the outcome of a batch of n messages to topic
+func ObserveKafkaPublishes(topic string, n int, err error) {
+	kafkaPublishes.add(float64(n), topic, result(err))
+}
+
+// ObserveEmailSend records the outcome of one email delivery attempt
+func ObserveEmailSend(err error) {
+	emailSends.add(1, result(err))
+}
+
+// ObserveEmailQuotaOverflow records an email of category dropped by the daily recipient cap
+func ObserveEmailQuotaOverflow(category string) {
+	emailQuotaOverflow.inc(category)
+}
+
+// ObserveDBQuery records one statement run on pool, identified by its normalized text
+func ObserveDBQuery(pool, query string, elapsed time.Duration, slow bool) {
+	dbQueryDuration.observe(elapsed.Seconds(), pool, query)
+	if slow {
+		dbSlowQueries.inc(pool)
+	}
+}
+
+// WriteText writes all recorded counters and histograms in the Prometheus text format
+func WriteText(w io.Writer) {
+	httpRequests.write(w)
+	httpDuration.write(w)
+	httpPanics.write(w)
+	httpRateLimited.write(w)
+	kafkaPublishes.write(w)
+	emailSends.write(w)
+	emailQuotaOverflow.write(w)
+	dbQueryDuration.write(w)
+	dbSlowQueries.write(w)
+}
+
+// WriteGauge writes a single unlabelled gauge in the Prometheus text format
+func WriteGauge(w io.Writer, name, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", name, help, name, name, value)
+}
+
+// WriteGaugeVec writes a gauge with one label, one sample per key of values (sorted)
+func WriteGaugeVec(w io.Writer, name, help, label string, values map[string]float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s{%s=%q} %g\n", name, label, k, values[k])
+	}
+}
+
+func result(err error) string {
+	if err != nil {
+		return ResultFailure
+	}
+	return ResultSuccess
+}
+
+// labelKey joins label values into a map key; \xff never appears in valid UTF-8
+func labelKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// formatLabels renders name="value" pairs for the given label names and key
+func formatLabels(names []string, key string, extra ...string) string {
+	values := strings.Split(key, "\xff")
+	parts := make([]string, 0, len(names)+len(extra)/2)
+	for i, name := range names {
+		parts = append(parts, fmt.Sprintf("%s=%q", name, values[i]))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		parts = append(parts, fmt.Sprintf("%s=%q", extra[i], extra[i+1]))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// counterVec is a monotonically increasing counter partitioned by labels
+type counterVec struct {
+	name, help string
+	labels     []string
+	mu         sync.Mutex
+	values     map[string]float64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, values: map[string]float64{}}
+}
+
+func (c *counterVec) inc(labelValues ...string) {
+	c.add(1, labelValues...)
+}
+
+func (c *counterVec) add(delta float64, labelValues ...string) {
+	c.mu.Lock()
+	c.values[labelKey(labelValues)] += delta
+	c.mu.Unlock()
+}
+
+func (c *counterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %g\n", c.name, formatLabels(c.labels, key), c.values[key])
+	}
+}
+
+// histogram holds the per-bucket counts of one label combination
+type histogram struct {
+	counts []uint64 // non-cumulative, one per bucket
+	count  uint64
+	sum    float64
+}
+
+// histogramVec is a histogram partitioned by labels
+type histogramVec struct {
+	name, help string
+	buckets    []float64
+	labels     []string
+	mu         sync.Mutex
+	values     map[string]*histogram
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	return &histogramVec{name: name, help: help, buckets: buckets, labels: labels, values: map[string]*histogram{}}
+}
+
+func (h *histogramVec) observe(value float64, labelValues ...string) {
+	key := labelKey(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hist := h.values[key]
+	if hist == nil {
+		hist = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hist
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			hist.counts[i]++
+			break
+		}
+	}
+	hist.count++
+	hist.sum += value
+}
+
+func (h *histogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, key := range sortedKeys(h.values) {
+		hist := h.values[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += hist.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name,
+				formatLabels(h.labels, key, "le", strconv.FormatFloat(bound, 'g', -1, 64)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, key, "le", "+Inf"), hist.count)
+		fmt.Fprintf(w, "%s_sum%s %g\n", h.name, formatLabels(h.labels, key), hist.sum)
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, key), hist.count)
+	}
+}
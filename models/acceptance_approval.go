@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Acceptance approval status constants
+const (
+	AcceptanceApprovalPending    = "PENDING"
+	AcceptanceApprovalApproved   = "APPROVED"   // confirmed by a second approver
+	AcceptanceApprovalOverridden = "OVERRIDDEN" // accepted by an admin without a second approver
+	AcceptanceApprovalCancelled  = "CANCELLED"  // the application was rejected or waitlisted instead
+)
+
+// AcceptanceApproval is the first approval of an acceptance into a course that needs
+// two distinct approvers
+type AcceptanceApproval struct {
+	ID          int        `json:"id"`
+	StudentID   int        `json:"student_id"`
+	StudentName string     `json:"student_name,omitempty"`
+	CourseID    int        `json:"course_id"`
+	CourseName  string     `json:"course_name,omitempty"`
+	CourseFee   float64    `json:"course_fee"`
+	Status      string     `json:"status"`
+	RequestedBy string     `json:"requested_by"`
+	ApprovedBy  string     `json:"approved_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// AdminDailySummary is the end-of-day summary emailed to the administration: the day's
+// admissions activity, acceptances and DLQ issues
+type AdminDailySummary struct {
+	ManagerSummary
+	Acceptances int `json:"acceptances"`
+	// NewDLQMessages failed during the day; UnresolvedDLQMessages and QuarantinedDLQMessages
+	// are the whole backlog at the time of the summary
+	NewDLQMessages         int `json:"new_dlq_messages"`
+	UnresolvedDLQMessages  int `json:"unresolved_dlq_messages"`
+	QuarantinedDLQMessages int `json:"quarantined_dlq_messages"`
+}
+
+// AdminSummaryRecipient is an address the end-of-day summary is sent to
+type AdminSummaryRecipient struct {
+	ID        int       `json:"id"`
+	Email     string    `json:"email"`
+	AddedBy   string    `json:"added_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Funnel stage constants, in pipeline order
+const (
+	FunnelStageLeadCreated      = "lead_created"
+	FunnelStageRegistrationPaid = "registration_paid"
+	FunnelStageInterviewed      = "interviewed"
+	FunnelStageAccepted         = "accepted"
+	FunnelStageCoursePaid       = "course_paid"
+)
+
+// FunnelStage is the number of leads that reached a pipeline stage
+type FunnelStage struct {
+	Stage string `json:"stage"`
+	Count int    `json:"count"`
+	// ConversionFromPrevious is Count divided by the previous stage's count
+	ConversionFromPrevious float64 `json:"conversion_from_previous"`
+	// ConversionFromStart is Count divided by the leads created
+	ConversionFromStart float64 `json:"conversion_from_start"`
+}
+
+// Funnel is the admission funnel for leads created within a date range
+type Funnel struct {
+	From   *time.Time    `json:"from,omitempty"`
+	To     *time.Time    `json:"to,omitempty"`
+	Stages []FunnelStage `json:"stages"`
+}
+
+// StageAging counts leads in one application_status by how long they have been in it
+type StageAging struct {
+	Status     string `json:"status"`
+	Total      int    `json:"total"`
+	Days0To2   int    `json:"days_0_2"`
+	Days3To7   int    `json:"days_3_7"`
+	Days8To14  int    `json:"days_8_14"`
+	Days15Plus int    `json:"days_15_plus"`
+}
+
+// CampaignConversion is the conversion breakdown for one UTM source/medium/campaign
+type CampaignConversion struct {
+	UTMSource        string  `json:"utm_source"`
+	UTMMedium        string  `json:"utm_medium"`
+	UTMCampaign      string  `json:"utm_campaign"`
+	Leads            int     `json:"leads"`
+	RegistrationPaid int     `json:"registration_paid"`
+	Enrollments      int     `json:"enrollments"`
+	ConversionRate   float64 `json:"conversion_rate"`
+}
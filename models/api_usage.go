@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// APIUsage is the traffic of one API consumer on one route over a window, or over one
+// hour when usage is listed per hour
+type APIUsage struct {
+	HourStart        *time.Time `json:"hour_start,omitempty"`
+	Consumer         string     `json:"consumer"`
+	Route            string     `json:"route"`
+	Method           string     `json:"method"`
+	RequestCount     int64      `json:"request_count"`
+	ClientErrorCount int64      `json:"client_error_count"` // 4xx responses
+	ServerErrorCount int64      `json:"server_error_count"` // 5xx responses
+	ErrorRate        float64    `json:"error_rate"`         // share of 4xx and 5xx responses
+	AvgDurationMs    float64    `json:"avg_duration_ms"`
+	MaxDurationMs    float64    `json:"max_duration_ms"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// AuditLogEntry records one change to a lead, application, course or DLQ message
+type AuditLogEntry struct {
+	ID         int64        `json:"id"`
+	EntityType string       `json:"entity_type"`
+	EntityID   string       `json:"entity_id"`
+	Action     string       `json:"action"`
+	Actor      string       `json:"actor"`
+	Changes    AuditChanges `json:"changes,omitempty"`
+	RequestID  string       `json:"request_id,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// AuditChange is the value of a field before and after a change; From is null for a
+// value that did not exist before
+type AuditChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// AuditChanges maps each changed field to its old and new value
+type AuditChanges map[string]AuditChange
+
+// Add records field going from one value to another, unless the two are the same
+func (c AuditChanges) Add(field string, from, to interface{}) {
+	if from != nil && fmt.Sprint(from) == fmt.Sprint(to) {
+		return
+	}
+	c[field] = AuditChange{From: from, To: to}
+}
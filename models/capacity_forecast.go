@@ -0,0 +1,39 @@
+package models
+
+// SourceForecast is the expected lead inflow of one lead source
+type SourceForecast struct {
+	LeadSource        string  `json:"lead_source"`
+	HistoricalLeads   int     `json:"historical_leads"`
+	AvgDailyLeads     float64 `json:"avg_daily_leads"`
+	ForecastNextWeek  float64 `json:"forecast_next_week"`
+	RoutedToReferrals bool    `json:"routed_to_referral_counselors"`
+}
+
+// DailyForecast is the expected lead inflow for one day of the forecast week
+type DailyForecast struct {
+	Date  string  `json:"date"`
+	Leads float64 `json:"leads"`
+}
+
+// CapacityForecast compares next week's expected lead inflow with free counselor capacity
+type CapacityForecast struct {
+	HistoryWeeks  int              `json:"history_weeks"`
+	ForecastFrom  string           `json:"forecast_from"`
+	ForecastTo    string           `json:"forecast_to"`
+	Sources       []SourceForecast `json:"sources"`
+	Days          []DailyForecast  `json:"days"`
+	ForecastLeads float64          `json:"forecast_leads"`
+	// Capacity of counselors eligible for routing (the house account is excluded)
+	Counselors    int `json:"counselors"`
+	TotalCapacity int `json:"total_capacity"`
+	AssignedLeads int `json:"assigned_leads"`
+	FreeCapacity  int `json:"free_capacity"`
+	// Referral leads can only go to referral-enabled counselors
+	ReferralForecastLeads float64 `json:"referral_forecast_leads"`
+	ReferralFreeCapacity  int     `json:"referral_free_capacity"`
+	// Shortfall is the forecast inflow not covered by free capacity (0 when capacity suffices)
+	Shortfall         float64 `json:"shortfall"`
+	ReferralShortfall float64 `json:"referral_shortfall"`
+	// Pressure is forecast inflow divided by free capacity (nil when there is no free capacity)
+	Pressure *float64 `json:"pressure"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// Commission rule types
+const (
+	CommissionRuleFlat       = "FLAT"       // Value is a fixed amount per enrollment
+	CommissionRulePercentage = "PERCENTAGE" // Value is a percentage of the captured course fee
+)
+
+// Commission ledger entry types
+const (
+	CommissionEntryAccrual    = "ACCRUAL"    // earned when a course fee is captured
+	CommissionEntryRefund     = "REFUND"     // reversal of an accrual for a refunded course fee (negative)
+	CommissionEntryAdjustment = "ADJUSTMENT" // manual correction, either sign
+)
+
+// CommissionRule is the commission a counselor earns per enrollment. A rule applies to a
+// counselor, a course or a counselor on a course; the most specific active one wins.
+type CommissionRule struct {
+	ID            int       `json:"id"`
+	CounselorID   *int      `json:"counselor_id,omitempty"`
+	CounselorName string    `json:"counselor_name,omitempty"`
+	CourseID      *int      `json:"course_id,omitempty"`
+	CourseName    string    `json:"course_name,omitempty"`
+	RuleType      string    `json:"rule_type"` // FLAT or PERCENTAGE
+	Value         float64   `json:"value"`
+	Active        bool      `json:"active"`
+	CreatedBy     string    `json:"created_by,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// CommissionEntry is one line of the commission ledger
+type CommissionEntry struct {
+	ID              int64     `json:"id"`
+	CounselorID     int       `json:"counselor_id"`
+	StudentID       *int      `json:"student_id,omitempty"`
+	CourseID        *int      `json:"course_id,omitempty"`
+	CoursePaymentID *int      `json:"course_payment_id,omitempty"`
+	RuleID          *int      `json:"rule_id,omitempty"`
+	EntryType       string    `json:"entry_type"`
+	BaseAmount      *float64  `json:"base_amount,omitempty"` // course fee captured, or refunded
+	Amount          float64   `json:"amount"`
+	Reason          string    `json:"reason,omitempty"`
+	CreatedBy       string    `json:"created_by,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// CommissionReportRow sums one counselor's commission ledger over a month
+type CommissionReportRow struct {
+	CounselorID   int     `json:"counselor_id"`
+	CounselorName string  `json:"counselor_name"`
+	Enrollments   int     `json:"enrollments"` // accruals in the month
+	Accrued       float64 `json:"accrued"`
+	Refunds       float64 `json:"refunds"` // negative
+	Adjustments   float64 `json:"adjustments"`
+	Net           float64 `json:"net"`
+}
+
+// CommissionReport is the monthly commission report for payroll
+type CommissionReport struct {
+	Month   string                `json:"month"` // YYYY-MM
+	Rows    []CommissionReportRow `json:"rows"`
+	Totals  CommissionReportRow   `json:"totals"`
+	Entries []CommissionEntry     `json:"entries,omitempty"` // ledger lines, when filtered by counselor
+}
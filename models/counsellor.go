@@ -7,3 +7,19 @@ type Counsellor struct {
 	AssignedCount int    `json:"assigned_count"`
 	MaxCapacity   int    `json:"max_capacity"`
 }
+
+// CounselorMetrics summarises a counselor's performance for manager dashboards
+type CounselorMetrics struct {
+	CounselorID   int64  `json:"counselor_id"`
+	CounselorName string `json:"counselor_name"`
+	LeadsAssigned int    `json:"leads_assigned"`
+	// Enrollments are assigned leads whose course fee is paid
+	Enrollments    int     `json:"enrollments"`
+	ConversionRate float64 `json:"conversion_rate"`
+	// Registration payments collected from assigned leads
+	RegistrationPayments        int     `json:"registration_payments"`
+	RegistrationAmountCollected float64 `json:"registration_amount_collected"`
+	// AvgResponseHours is the average time from lead creation to the counselor's first note
+	AvgResponseHours  *float64 `json:"avg_response_hours"`
+	LeadsWithoutNotes int      `json:"leads_without_notes"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Counselor shifts; their hours come from COUNSELOR_MORNING_SHIFT and COUNSELOR_EVENING_SHIFT
+const (
+	CounselorShiftMorning = "MORNING"
+	CounselorShiftEvening = "EVENING"
+)
+
+// CounselorShift is a shift a counselor works on the given ISO weekdays (1 = Monday, 7 = Sunday)
+type CounselorShift struct {
+	Shift    string  `json:"shift"`
+	Weekdays []int64 `json:"weekdays"`
+	// Window is the shift's hours from the config, e.g. "09:00-14:00" ("off" when disabled)
+	Window    string    `json:"window"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CounselorShiftSchedule is a counselor's weekly shift schedule
+type CounselorShiftSchedule struct {
+	CounselorID   int64            `json:"counselor_id"`
+	CounselorName string           `json:"counselor_name"`
+	Shifts        []CounselorShift `json:"shifts"`
+	// OnShiftNow reports whether new leads arriving now are routed to this counselor first
+	OnShiftNow bool `json:"on_shift_now"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Coupon discount types
+const (
+	CouponDiscountPercent = "percent"
+	CouponDiscountFlat    = "flat"
+)
+
+// Coupon is a discount code for the registration or course fee. PaymentType and CourseID,
+// when set, restrict it to that fee; MaxRedemptions is unlimited when nil.
+type Coupon struct {
+	ID                       int        `json:"id"`
+	Code                     string     `json:"code"`
+	Description              string     `json:"description,omitempty"`
+	DiscountType             string     `json:"discount_type"`  // percent or flat
+	DiscountValue            float64    `json:"discount_value"` // percent, or rupees off
+	PaymentType              string     `json:"payment_type,omitempty"`
+	CourseID                 *int       `json:"course_id,omitempty"`
+	ValidFrom                *time.Time `json:"valid_from,omitempty"`
+	ValidUntil               *time.Time `json:"valid_until,omitempty"`
+	MaxRedemptions           *int       `json:"max_redemptions,omitempty"`
+	MaxRedemptionsPerStudent int        `json:"max_redemptions_per_student"`
+	IsActive                 bool       `json:"is_active"`
+	// Redemptions counts the orders created with the coupon that did not fail or get cancelled
+	Redemptions int       `json:"redemptions"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
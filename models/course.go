@@ -9,7 +9,8 @@ type Course struct {
 	Description string    `json:"description"`
 	Fee         float64   `json:"fee"`
 	Duration    string    `json:"duration"`
-	IsActive    int       `json:"is_active"` // 0 = inactive, 1 = active
+	Batch       string    `json:"batch,omitempty"` // current intake, handed to the LMS/ERP on enrollment
+	IsActive    int       `json:"is_active"`       // 0 = inactive, 1 = active
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -21,6 +22,7 @@ type CourseResponse struct {
 	Description string  `json:"description"`
 	Fee         float64 `json:"fee"`
 	Duration    string  `json:"duration"`
+	Batch       string  `json:"batch,omitempty"`
 	IsActive    int     `json:"is_active"` // 0 = inactive, 1 = active
 	CreatedAt   string  `json:"created_at"`
 	UpdatedAt   string  `json:"updated_at"`
@@ -34,6 +36,7 @@ func (c *Course) ToResponse() CourseResponse {
 		Description: c.Description,
 		Fee:         c.Fee,
 		Duration:    c.Duration,
+		Batch:       c.Batch,
 		IsActive:    c.IsActive,
 		CreatedAt:   c.CreatedAt.Format(time.RFC3339),
 		UpdatedAt:   c.UpdatedAt.Format(time.RFC3339),
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Emails a course content block can be injected into
+const (
+	ContentBlockEmailAll        = "all"
+	ContentBlockEmailAcceptance = "acceptance"
+	ContentBlockEmailEnrollment = "enrollment"
+)
+
+// CourseContentBlock is a department-managed snippet (orientation dates, documents needed,
+// department contacts) added to a course's acceptance and/or enrollment emails
+type CourseContentBlock struct {
+	ID        int       `json:"id"`
+	CourseID  int       `json:"course_id"`
+	Key       string    `json:"key"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`       // plain text; line breaks are kept
+	EmailType string    `json:"email_type"` // all, acceptance or enrollment
+	Position  int       `json:"position"`   // blocks render in ascending position
+	IsActive  bool      `json:"is_active"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,37 @@
+package models
+
+// Decision import row actions reported per spreadsheet row
+const (
+	DecisionRowApplied         = "APPLIED"
+	DecisionRowPendingApproval = "PENDING_APPROVAL" // first of two approvals recorded
+	DecisionRowValid           = "VALID"            // dry run: the decision would be applied
+	DecisionRowFailed          = "FAILED"
+)
+
+// DecisionImportRow is one decision read from an offline decision sheet
+type DecisionImportRow struct {
+	Row       int    `json:"row"` // spreadsheet row number (header is row 1)
+	StudentID int    `json:"student_id"`
+	Decision  string `json:"decision"` // ACCEPTED, REJECTED or WAITLISTED
+	Course    string `json:"course"`   // course ID or name, required unless REJECTED
+}
+
+// DecisionImportRowResult is the outcome of one decision sheet row
+type DecisionImportRowResult struct {
+	DecisionImportRow
+	CourseID       int    `json:"course_id,omitempty"`
+	PreviousStatus string `json:"previous_status,omitempty"`
+	Action         string `json:"action"` // APPLIED, PENDING_APPROVAL, VALID or FAILED
+	EmailQueued    bool   `json:"email_queued"`
+	Error          string `json:"error,omitempty"`
+}
+
+// DecisionImportResult summarises one import of offline application decisions
+type DecisionImportResult struct {
+	DryRun       bool                      `json:"dry_run"`
+	DecidedBy    string                    `json:"decided_by,omitempty"`
+	TotalCount   int                       `json:"total_count"`
+	AppliedCount int                       `json:"applied_count"` // includes acceptances pending a second approval
+	FailedCount  int                       `json:"failed_count"`
+	Rows         []DecisionImportRowResult `json:"rows"`
+}
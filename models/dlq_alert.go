@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// DLQAlertState is the DLQ backlog alert as of the last auto-retry pass
+type DLQAlertState struct {
+	Firing         bool       `json:"firing"` // more than Threshold messages unresolved
+	Unresolved     int        `json:"unresolved"`
+	Threshold      int        `json:"threshold"`
+	FiringSince    *time.Time `json:"firing_since,omitempty"`
+	LastAlertAt    *time.Time `json:"last_alert_at,omitempty"`
+	NextAlertAfter *time.Time `json:"next_alert_after,omitempty"` // end of the cool-down while firing
+	CheckedAt      *time.Time `json:"checked_at,omitempty"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// Doctor check status constants
+const (
+	DoctorStatusPass = "PASS"
+	DoctorStatusFail = "FAIL"
+	DoctorStatusSkip = "SKIP"
+)
+
+// DoctorCheck is the outcome of one environment self-check
+type DoctorCheck struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Detail     string `json:"detail"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// DoctorReport collects the results of all self-checks
+type DoctorReport struct {
+	Healthy bool          `json:"healthy"`
+	Checks  []DoctorCheck `json:"checks"`
+}
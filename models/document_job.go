@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Document job type constants
+const (
+	DocumentJobTypeLeadExport = "lead_export"
+	DocumentJobTypeCACReport  = "cac_report"
+)
+
+// Document job status constants
+const (
+	DocumentJobStatusPending    = "PENDING"
+	DocumentJobStatusProcessing = "PROCESSING"
+	DocumentJobStatusCompleted  = "COMPLETED"
+	DocumentJobStatusFailed     = "FAILED"
+)
+
+// DocumentJob is a queued request to generate a document outside the HTTP request
+type DocumentJob struct {
+	ID           int             `json:"id"`
+	JobType      string          `json:"job_type"`
+	Format       string          `json:"format"`
+	Params       json.RawMessage `json:"params"`
+	Status       string          `json:"status"`
+	RequestedBy  string          `json:"requested_by,omitempty"`
+	NotifyEmail  string          `json:"notify_email,omitempty"`
+	FileName     string          `json:"file_name,omitempty"`
+	StorageKey   string          `json:"-"`
+	DownloadURL  string          `json:"download_url,omitempty"`
+	ErrorMessage string          `json:"error_message,omitempty"`
+	Attempts     int             `json:"attempts"`
+	CreatedAt    time.Time       `json:"created_at"`
+	StartedAt    *time.Time      `json:"started_at,omitempty"`
+	CompletedAt  *time.Time      `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// EmailOverflow summarises the emails one recipient did not get on one day because the
+// daily recipient cap was reached
+type EmailOverflow struct {
+	Recipient     string    `json:"recipient"`
+	Date          string    `json:"date"`       // YYYY-MM-DD
+	SentCount     int       `json:"sent_count"` // capped-category emails delivered that day
+	DroppedCount  int       `json:"dropped_count"`
+	Categories    []string  `json:"categories"`
+	LastSubject   string    `json:"last_subject"`
+	LastDroppedAt time.Time `json:"last_dropped_at"`
+}
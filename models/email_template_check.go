@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// EmailTemplateCheck is the result of rendering one email template with sample data
+type EmailTemplateCheck struct {
+	Name      string   `json:"name"`
+	Subject   string   `json:"subject,omitempty"`
+	BodyBytes int      `json:"body_bytes"`
+	OK        bool     `json:"ok"`
+	Problems  []string `json:"problems,omitempty"` // broken variables, unresolved tokens, HTML errors
+}
+
+// EmailTemplateReport lists the checks of every email template
+type EmailTemplateReport struct {
+	CheckedAt time.Time            `json:"checked_at"`
+	CourseID  int                  `json:"course_id,omitempty"` // course whose content blocks were rendered
+	Checked   int                  `json:"checked"`
+	Failed    int                  `json:"failed"`
+	Templates []EmailTemplateCheck `json:"templates"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// Enrollment sync status constants
+const (
+	EnrollmentSyncPending = "PENDING" // waiting for its first or next delivery attempt
+	EnrollmentSyncSynced  = "SYNCED"
+	EnrollmentSyncFailed  = "FAILED" // attempts exhausted; retried manually
+)
+
+// EnrollmentSync tracks the handoff of an enrolled student to the LMS/ERP
+type EnrollmentSync struct {
+	StudentID     int        `json:"student_id"`
+	StudentName   string     `json:"student_name"`
+	CourseID      int        `json:"course_id"`
+	CourseName    string     `json:"course_name"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	ExternalID    string     `json:"external_id,omitempty"` // id returned by the LMS/ERP, or the export file key
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	SyncedAt      *time.Time `json:"synced_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// EnrollmentRecord is the document handed to the LMS/ERP for an enrolled student
+type EnrollmentRecord struct {
+	Student    EnrollmentStudent   `json:"student"`
+	Course     EnrollmentCourse    `json:"course"`
+	Payments   []EnrollmentPayment `json:"payments"`
+	EnrolledAt time.Time           `json:"enrolled_at"`
+}
+
+// EnrollmentStudent is the student profile part of an EnrollmentRecord
+type EnrollmentStudent struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Email      string `json:"email"`
+	Phone      string `json:"phone"`
+	Education  string `json:"education,omitempty"`
+	LeadSource string `json:"lead_source,omitempty"`
+}
+
+// EnrollmentCourse is the course and batch the student enrolled in
+type EnrollmentCourse struct {
+	ID       int     `json:"id"`
+	Name     string  `json:"name"`
+	Duration string  `json:"duration,omitempty"`
+	Batch    string  `json:"batch,omitempty"`
+	Fee      float64 `json:"fee"`
+}
+
+// EnrollmentPayment is a paid registration or course fee
+type EnrollmentPayment struct {
+	Type      string    `json:"type"` // REGISTRATION or COURSE_FEE
+	OrderID   string    `json:"order_id"`
+	PaymentID string    `json:"payment_id"`
+	Amount    float64   `json:"amount"`
+	PaidAt    time.Time `json:"paid_at"`
+}
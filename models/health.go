@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Health status constants, overall and per dependency
+const (
+	HealthStatusUp       = "UP"
+	HealthStatusDegraded = "DEGRADED" // overall: a non-critical dependency is down
+	HealthStatusDown     = "DOWN"
+	HealthStatusDisabled = "DISABLED" // dependency not configured
+)
+
+// HealthComponent is the state of one dependency. A critical component being down takes
+// the whole service down; the others only degrade it.
+type HealthComponent struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Critical  bool   `json:"critical"`
+	Detail    string `json:"detail,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// HealthReport is the answer of GET /health
+type HealthReport struct {
+	Status     string            `json:"status"`
+	Components []HealthComponent `json:"components"`
+	CheckedAt  time.Time         `json:"checked_at"`
+}
@@ -0,0 +1,87 @@
+package models
+
+import "time"
+
+// Import status constants
+const (
+	ImportStatusCompleted  = "COMPLETED"
+	ImportStatusRolledBack = "ROLLED_BACK"
+)
+
+// Duplicate policies: what an import does with a row matching an existing lead by email or phone
+const (
+	DuplicatePolicySkip   = "skip"   // leave the existing lead untouched
+	DuplicatePolicyUpdate = "update" // fill the existing lead's empty fields from the row
+	DuplicatePolicyMerge  = "merge"  // overwrite the existing lead's fields with the row's non-empty values
+)
+
+// Import row actions reported per spreadsheet row
+const (
+	ImportRowCreated = "CREATED"
+	ImportRowSkipped = "SKIPPED"
+	ImportRowUpdated = "UPDATED"
+	ImportRowFailed  = "FAILED"
+	ImportRowReview  = "REVIEW" // partial email/phone match queued for manual review
+)
+
+// ImportHistory records a single bulk lead upload run
+type ImportHistory struct {
+	ID              int        `json:"id"`
+	FileName        string     `json:"file_name"`
+	FileHash        string     `json:"file_hash"` // SHA-256 of the uploaded file (hex)
+	UploadedBy      string     `json:"uploaded_by"`
+	TotalCount      int        `json:"total_count"`
+	SuccessCount    int        `json:"success_count"`
+	FailedCount     int        `json:"failed_count"`
+	UpdatedCount    int        `json:"updated_count"`
+	SkippedCount    int        `json:"skipped_count"`
+	DuplicatePolicy string     `json:"duplicate_policy"`
+	CreatedLeadIDs  []int64    `json:"created_lead_ids"` // rollback deletes these; updates to existing leads are not reverted
+	Status          string     `json:"status"`
+	RolledBackAt    *time.Time `json:"rolled_back_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// ImportRollbackResult summarises the outcome of rolling back an import
+type ImportRollbackResult struct {
+	ImportID        int     `json:"import_id"`
+	DeletedLeadIDs  []int64 `json:"deleted_lead_ids"`
+	SkippedLeadIDs  []int64 `json:"skipped_lead_ids"` // leads left in place (already paid or already deleted)
+	DeletedCount    int     `json:"deleted_count"`
+	SkippedCount    int     `json:"skipped_count"`
+	CounselorsFreed int     `json:"counselors_freed"`
+}
+
+// ImportResult summarises one run of the bulk import pipeline
+type ImportResult struct {
+	ImportID        int               `json:"import_id,omitempty"`
+	DuplicatePolicy string            `json:"duplicate_policy"`
+	TotalCount      int               `json:"total_count"`
+	SuccessCount    int               `json:"success_count"` // leads created
+	UpdatedCount    int               `json:"updated_count"`
+	SkippedCount    int               `json:"skipped_count"`
+	ReviewCount     int               `json:"review_count"` // also counted as skipped in import_history
+	FailedCount     int               `json:"failed_count"`
+	FailedLeads     []ImportRowError  `json:"failed_leads,omitempty"`
+	Rows            []ImportRowResult `json:"rows"`
+}
+
+// ImportRowResult is the outcome of one spreadsheet row
+type ImportRowResult struct {
+	Row           int      `json:"row"` // spreadsheet row number (header is row 1)
+	Email         string   `json:"email"`
+	Phone         string   `json:"phone"`
+	Action        string   `json:"action"` // CREATED, SKIPPED, UPDATED, REVIEW or FAILED
+	LeadID        int      `json:"lead_id,omitempty"`
+	ReviewID      int      `json:"review_id,omitempty"`
+	UpdatedFields []string `json:"updated_fields,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
+
+// ImportRowError is a spreadsheet row that could not be imported
+type ImportRowError struct {
+	Row   int    `json:"row,string"` // spreadsheet row number (header is row 1)
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+	Error string `json:"error"`
+}
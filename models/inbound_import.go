@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Inbound import status constants
+const (
+	InboundImportStatusPending    = "PENDING"
+	InboundImportStatusProcessing = "PROCESSING"
+	InboundImportStatusCompleted  = "COMPLETED"
+	InboundImportStatusFailed     = "FAILED"
+)
+
+// InboundImport is a spreadsheet received by email and queued for the lead import pipeline
+type InboundImport struct {
+	ID           int        `json:"id"`
+	Sender       string     `json:"sender"`
+	Subject      string     `json:"subject,omitempty"`
+	FileName     string     `json:"file_name"`
+	FileHash     string     `json:"file_hash"`
+	StorageKey   string     `json:"-"`
+	Status       string     `json:"status"`
+	ImportID     *int       `json:"import_id,omitempty"`
+	ErrorMessage string     `json:"error_message,omitempty"`
+	Attempts     int        `json:"attempts"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ProcessedAt  *time.Time `json:"processed_at,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Stages of an interview request that has not completed yet
+const (
+	InterviewStageQueued        = "QUEUED"         // interview.schedule waiting in the event outbox
+	InterviewStageScheduling    = "SCHEDULING"     // consumed, no meeting booked yet
+	InterviewStageInvitePending = "INVITE_PENDING" // meeting booked, invite email not sent yet
+)
+
+// PendingInterview is a student whose interview scheduling (after the registration
+// payment) has not finished
+type PendingInterview struct {
+	StudentID      int        `json:"student_id"`
+	StudentName    string     `json:"student_name"`
+	StudentEmail   string     `json:"student_email"`
+	Stage          string     `json:"stage"`
+	RequestedAt    time.Time  `json:"requested_at"`             // interview.schedule queued
+	ConsumedAt     *time.Time `json:"consumed_at"`              // interview.schedule consumed
+	ScheduledAt    *time.Time `json:"scheduled_at"`             // booked interview time
+	MeetLink       string     `json:"meet_link,omitempty"`      // set once booked
+	InviteSentAt   *time.Time `json:"invite_sent_at"`           // invite handed to SMTP
+	DLQMessageID   string     `json:"dlq_message_id,omitempty"` // unresolved DLQ message of the request, if it failed
+	WaitingMinutes int        `json:"waiting_minutes"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Interviewer is a staff member who interviews students after their registration payment
+type Interviewer struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	DailyCap int    `json:"daily_cap"` // interviews per day at most
+	// CourseIDs are the programs the interviewer interviews for; empty means any program
+	CourseIDs []int64 `json:"course_ids"`
+	// Skills are matched against the student's education to prefer a fitting interviewer
+	Skills   []string `json:"skills"`
+	IsActive bool     `json:"is_active"`
+	// Load, filled in listings: interviews booked this week and today
+	BookedThisWeek int       `json:"booked_this_week"`
+	BookedToday    int       `json:"booked_today"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// Invoice statuses
+const (
+	InvoiceStatusIssued = "ISSUED" // awaiting payment
+	InvoiceStatusPaid   = "PAID"
+)
+
+// Invoice is a numbered course fee invoice for one installment, with its GST breakdown.
+// Amounts are in INR; TotalAmount includes the tax.
+type Invoice struct {
+	ID               int        `json:"id"`
+	InvoiceNumber    string     `json:"invoice_number"`
+	StudentID        int        `json:"student_id"`
+	StudentName      string     `json:"student_name"`
+	StudentEmail     string     `json:"student_email,omitempty"`
+	StudentPhone     string     `json:"student_phone,omitempty"`
+	CourseID         int        `json:"course_id"`
+	CourseName       string     `json:"course_name"`
+	OrderID          string     `json:"order_id,omitempty"`
+	PaymentID        string     `json:"payment_id,omitempty"`
+	InstallmentNo    int        `json:"installment_no"`
+	InstallmentCount int        `json:"installment_count"`
+	TaxRate          float64    `json:"tax_rate"` // percent
+	TaxableAmount    float64    `json:"taxable_amount"`
+	CGSTAmount       float64    `json:"cgst_amount"`
+	SGSTAmount       float64    `json:"sgst_amount"`
+	IGSTAmount       float64    `json:"igst_amount"`     // inter-state supplies, instead of CGST and SGST
+	PlaceOfSupply    string     `json:"place_of_supply"` // GST state code
+	TotalAmount      float64    `json:"total_amount"`
+	Status           string     `json:"status"`
+	IssuedAt         time.Time  `json:"issued_at"`
+	PaidAt           *time.Time `json:"paid_at,omitempty"`
+	StorageKey       string     `json:"-"`
+	DownloadURL      string     `json:"download_url"`
+}
+
+// PaymentReceipt is the numbered receipt of a captured payment, with its GST breakdown.
+// Course fee receipts carry the amounts of their invoice.
+type PaymentReceipt struct {
+	ID            int       `json:"id"`
+	ReceiptNumber string    `json:"receipt_number"`
+	StudentID     int       `json:"student_id"`
+	StudentName   string    `json:"student_name"`
+	StudentEmail  string    `json:"student_email,omitempty"`
+	StudentPhone  string    `json:"student_phone,omitempty"`
+	OrderID       string    `json:"order_id"`
+	PaymentID     string    `json:"payment_id"`
+	PaymentType   string    `json:"payment_type"`
+	CourseID      *int      `json:"course_id,omitempty"`
+	CourseName    string    `json:"course_name,omitempty"`
+	InvoiceNumber string    `json:"invoice_number,omitempty"`
+	TaxRate       float64   `json:"tax_rate"` // percent
+	TaxableAmount float64   `json:"taxable_amount"`
+	CGSTAmount    float64   `json:"cgst_amount"`
+	SGSTAmount    float64   `json:"sgst_amount"`
+	IGSTAmount    float64   `json:"igst_amount"`
+	TotalAmount   float64   `json:"total_amount"`
+	PaidAt        time.Time `json:"paid_at"`
+	StorageKey    string    `json:"-"`
+	DownloadURL   string    `json:"download_url"`
+}
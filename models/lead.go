@@ -12,6 +12,12 @@ type Lead struct {
 	Phone                 string     `json:"phone"`
 	Education             string     `json:"education"`
 	LeadSource            string     `json:"lead_source"`
+	Campaign              string     `json:"campaign,omitempty"`
+	UTMSource             string     `json:"utm_source,omitempty"`
+	UTMMedium             string     `json:"utm_medium,omitempty"`
+	UTMCampaign           string     `json:"utm_campaign,omitempty"`
+	RemarketingConsent    bool       `json:"remarketing_consent"`
+	StateCode             string     `json:"state_code,omitempty"`
 	CounsellorID          *int64     `json:"counsellor_id,omitempty"`
 	MeetLink              string     `json:"meet_link"`
 	ApplicationStatus     string     `json:"application_status"`
@@ -21,22 +27,34 @@ type Lead struct {
 	InterviewScheduledAt  *time.Time `json:"interview_scheduled_at,omitempty"`
 	CreatedAt             time.Time  `json:"created_at"`
 	UpdatedAt             time.Time  `json:"updated_at"`
+	// Counselor is filled in by the lead list from the assigned counselor, if any
+	Counselor *LeadCounselor `json:"-"`
+}
+
+// LeadCounselor is the counselor assigned to a lead, as embedded in the lead list
+type LeadCounselor struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Phone string `json:"phone"`
 }
 
 // LeadResponse is the structured response for API responses
 type LeadResponse struct {
-	ID                   int     `json:"id"`
-	Name                 string  `json:"name"`
-	Email                string  `json:"email"`
-	Phone                string  `json:"phone"`
-	Education            string  `json:"education"`
-	LeadSource           string  `json:"lead_source"`
-	MeetLink             string  `json:"meet_link"`
-	ApplicationStatus    string  `json:"application_status"`
-	SelectedCourseID     *int    `json:"selected_course_id,omitempty"`
-	InterviewScheduledAt *string `json:"interview_scheduled_at,omitempty"`
-	CreatedAt            string  `json:"created_at"`
-	UpdatedAt            string  `json:"updated_at"`
+	ID                   int              `json:"id"`
+	Name                 string           `json:"name"`
+	Email                string           `json:"email"`
+	Phone                string           `json:"phone"`
+	Education            string           `json:"education"`
+	LeadSource           string           `json:"lead_source"`
+	MeetLink             string           `json:"meet_link"`
+	ApplicationStatus    string           `json:"application_status"`
+	SelectedCourseID     *int             `json:"selected_course_id,omitempty"`
+	InterviewScheduledAt *string          `json:"interview_scheduled_at,omitempty"`
+	LastNote             *LeadNoteSummary `json:"last_note,omitempty"`
+	Counselor            *LeadCounselor   `json:"counselor,omitempty"`
+	CreatedAt            string           `json:"created_at"`
+	UpdatedAt            string           `json:"updated_at"`
 }
 
 // ToResponse converts Lead to LeadResponse with formatted timestamps
@@ -61,3 +79,73 @@ func (l *Lead) ToResponse() LeadResponse {
 		UpdatedAt:            l.UpdatedAt.Format(time.RFC3339),
 	}
 }
+
+// LeadResponseV2 is the v2 shape of a lead: the assigned counselor is included, the
+// interview fields are grouped (null until one is scheduled) and optional fields are
+// null instead of left out
+type LeadResponseV2 struct {
+	ID                int              `json:"id"`
+	Name              string           `json:"name"`
+	Email             string           `json:"email"`
+	Phone             string           `json:"phone"`
+	Education         string           `json:"education"`
+	LeadSource        string           `json:"lead_source"`
+	CounselorID       *int64           `json:"counselor_id"`
+	CounselorName     *string          `json:"counselor_name"`
+	Counselor         *LeadCounselor   `json:"counselor,omitempty"`
+	ApplicationStatus string           `json:"application_status"`
+	SelectedCourseID  *int             `json:"selected_course_id"`
+	Interview         *LeadInterview   `json:"interview"`
+	LastNote          *LeadNoteSummary `json:"last_note"`
+	CreatedAt         string           `json:"created_at"`
+	UpdatedAt         string           `json:"updated_at"`
+}
+
+// LeadInterview is the scheduled interview of a lead
+type LeadInterview struct {
+	ScheduledAt *string `json:"scheduled_at"`
+	MeetLink    string  `json:"meet_link"`
+}
+
+// ToResponseV2 converts Lead to LeadResponseV2
+func (l *Lead) ToResponseV2() LeadResponseV2 {
+	v1 := l.ToResponse()
+	var interview *LeadInterview
+	if v1.InterviewScheduledAt != nil || l.MeetLink != "" {
+		interview = &LeadInterview{ScheduledAt: v1.InterviewScheduledAt, MeetLink: l.MeetLink}
+	}
+	var counselorName *string
+	if l.Counselor != nil {
+		counselorName = &l.Counselor.Name
+	}
+	return LeadResponseV2{
+		ID:                l.ID,
+		Name:              l.Name,
+		Email:             l.Email,
+		Phone:             l.Phone,
+		Education:         l.Education,
+		LeadSource:        l.LeadSource,
+		CounselorID:       l.CounsellorID,
+		CounselorName:     counselorName,
+		ApplicationStatus: l.ApplicationStatus,
+		SelectedCourseID:  l.SelectedCourseID,
+		Interview:         interview,
+		CreatedAt:         v1.CreatedAt,
+		UpdatedAt:         v1.UpdatedAt,
+	}
+}
+
+// LeadExportRow is a flattened lead used for offline (CSV/XLSX) reporting
+type LeadExportRow struct {
+	ID                    int
+	Name                  string
+	Email                 string
+	Phone                 string
+	Education             string
+	LeadSource            string
+	CounselorName         string
+	RegistrationFeeStatus string
+	CourseFeeStatus       string
+	ApplicationStatus     string
+	CreatedAt             time.Time
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// DuplicateLead is a lead that belongs to a potential duplicate cluster
+type DuplicateLead struct {
+	ID         int       `json:"id"`
+	Name       string    `json:"name"`
+	Email      string    `json:"email"`
+	Phone      string    `json:"phone"`
+	LeadSource string    `json:"lead_source"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DuplicateCluster groups leads that probably describe the same student.
+// The oldest lead is suggested as the one to keep; MergeLinks fold the others into it.
+type DuplicateCluster struct {
+	Confidence  float64         `json:"confidence"`
+	Reasons     []string        `json:"reasons"`
+	KeepLeadID  int             `json:"keep_lead_id"`
+	Leads       []DuplicateLead `json:"leads"`
+	MergeLinks  []string        `json:"merge_links"`
+	SourceCount int             `json:"source_count"`
+}
+
+// LeadMergeResult reports what was moved when merging a duplicate lead
+type LeadMergeResult struct {
+	KeptLeadID   int `json:"kept_lead_id"`
+	MergedLeadID int `json:"merged_lead_id"`
+	NotesMoved   int `json:"notes_moved"`
+	EventsMoved  int `json:"events_moved"`
+}
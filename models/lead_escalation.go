@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Lead escalation action constants
+const (
+	EscalationActionReassigned = "REASSIGNED"
+	EscalationActionAdminQueue = "ADMIN_QUEUE"
+)
+
+// LeadEscalation records a stale lead being reassigned or flagged for an admin
+type LeadEscalation struct {
+	ID              int        `json:"id"`
+	LeadID          int        `json:"lead_id"`
+	LeadName        string     `json:"lead_name,omitempty"`
+	Action          string     `json:"action"`
+	FromCounselorID *int64     `json:"from_counselor_id,omitempty"`
+	ToCounselorID   *int64     `json:"to_counselor_id,omitempty"`
+	Reason          string     `json:"reason"`
+	ResolvedAt      *time.Time `json:"resolved_at,omitempty"`
+	ResolutionNotes string     `json:"resolution_notes,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
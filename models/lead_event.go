@@ -0,0 +1,16 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// LeadEvent is a Kafka event (lead.created, payment.*, application.*, ...) recorded against a lead
+type LeadEvent struct {
+	ID         int             `json:"id"`
+	LeadID     int             `json:"lead_id"`
+	EventType  string          `json:"event_type"`
+	Topic      string          `json:"topic"`
+	Payload    json.RawMessage `json:"payload"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
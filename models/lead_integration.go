@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Lead integration health
+const (
+	IntegrationStatusUnknown   = "UNKNOWN" // inactive, or not checked yet
+	IntegrationStatusHealthy   = "HEALTHY"
+	IntegrationStatusUnhealthy = "UNHEALTHY"
+)
+
+// LeadIntegration is an inbound lead pipe (Zapier zap, ad connector) recognised by the
+// lead_source, and optionally utm_source, of the leads it creates. It is unhealthy once
+// no lead has arrived for longer than ExpectedCadenceMinutes.
+type LeadIntegration struct {
+	ID                     int    `json:"id"`
+	Name                   string `json:"name"`
+	LeadSource             string `json:"lead_source"`
+	UTMSource              string `json:"utm_source,omitempty"`
+	ExpectedCadenceMinutes int    `json:"expected_cadence_minutes"`
+	IsActive               bool   `json:"is_active"`
+	Status                 string `json:"status"`
+	// LastLeadAt is the newest lead from the integration, including leads held for review
+	LastLeadAt *time.Time `json:"last_lead_at"`
+	// QuietMinutes is how long no lead has arrived (since the integration was added when none ever did)
+	QuietMinutes int `json:"quiet_minutes"`
+	// UnhealthySince is when the quiet period exceeded the cadence
+	UnhealthySince *time.Time `json:"unhealthy_since"`
+	// LastAlertAt is the last alert of the current unhealthy period; CheckedAt the last
+	// run of the integration-health job
+	LastAlertAt *time.Time `json:"last_alert_at"`
+	CheckedAt   *time.Time `json:"checked_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
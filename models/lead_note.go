@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Lead note type constants
+const (
+	NoteTypeCall     = "CALL"
+	NoteTypeEmail    = "EMAIL"
+	NoteTypeMeeting  = "MEETING"
+	NoteTypeFollowUp = "FOLLOW_UP"
+	NoteTypeNote     = "NOTE"
+)
+
+// LeadNote is a counselor interaction recorded against a lead (call, follow-up, etc.)
+type LeadNote struct {
+	ID            int        `json:"id"`
+	LeadID        int        `json:"lead_id"`
+	CounselorID   *int64     `json:"counselor_id,omitempty"`
+	CounselorName string     `json:"counselor_name,omitempty"`
+	NoteType      string     `json:"note_type"`
+	Content       string     `json:"content"`
+	FollowUpAt    *time.Time `json:"follow_up_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+
+	Mentions []LeadNoteMention `json:"mentions,omitempty"`
+}
+
+// LeadNoteMention is a counselor @mentioned in a lead note
+type LeadNoteMention struct {
+	CounselorID int64  `json:"counselor_id"`
+	Name        string `json:"name"`
+}
+
+// LeadNoteSummary is the last-note metadata included in the lead list
+type LeadNoteSummary struct {
+	NoteType      string  `json:"note_type"`
+	CounselorName string  `json:"counselor_name,omitempty"`
+	FollowUpAt    *string `json:"follow_up_at,omitempty"`
+	CreatedAt     string  `json:"created_at"`
+}
+
+// IsValidNoteType reports whether t is one of the supported note types
+func IsValidNoteType(t string) bool {
+	switch t {
+	case NoteTypeCall, NoteTypeEmail, NoteTypeMeeting, NoteTypeFollowUp, NoteTypeNote:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Progress step keys, in the order a student goes through them
+const (
+	ProgressStepProfile      = "profile_complete"
+	ProgressStepRegistration = "registration_paid"
+	ProgressStepInterview    = "interview_done"
+	ProgressStepDecision     = "decision"
+	ProgressStepCourseFee    = "course_fee_paid"
+	ProgressStepEnrolled     = "enrolled"
+)
+
+// Progress step status constants
+const (
+	StepStatusCompleted     = "COMPLETED"
+	StepStatusCurrent       = "CURRENT"
+	StepStatusPending       = "PENDING"
+	StepStatusNotApplicable = "NOT_APPLICABLE" // steps after a rejection
+)
+
+// ProgressStep is one step of a lead's application
+type ProgressStep struct {
+	Key         string     `json:"key"`
+	Label       string     `json:"label"`
+	Status      string     `json:"status"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Detail      string     `json:"detail,omitempty"` // e.g. the decision or the interview time
+}
+
+// LeadProgress is the normalized application progress shared by the student portal and counselors
+type LeadProgress struct {
+	LeadID            int            `json:"lead_id"`
+	ApplicationStatus string         `json:"application_status"`
+	CurrentStep       string         `json:"current_step,omitempty"` // empty once enrolled or rejected
+	NextAction        string         `json:"next_action"`
+	CompletedSteps    int            `json:"completed_steps"`
+	TotalSteps        int            `json:"total_steps"`
+	Steps             []ProgressStep `json:"steps"`
+}
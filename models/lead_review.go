@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Lead review status constants
+const (
+	LeadReviewPending  = "PENDING"
+	LeadReviewApproved = "APPROVED" // created as a new lead
+	LeadReviewMerged   = "MERGED"   // folded into an existing lead
+)
+
+// LeadReview is a submitted lead whose email or phone (but not both) matches an
+// existing lead, held for a counselor to approve as new or merge
+type LeadReview struct {
+	ID             int               `json:"id"`
+	Status         string            `json:"status"`
+	Source         string            `json:"source"` // api, or import:<file name>
+	Lead           Lead              `json:"lead"`   // the submitted data
+	Reason         string            `json:"reason"`
+	Matches        []LeadReviewMatch `json:"matches"`
+	ResolvedLeadID *int              `json:"resolved_lead_id,omitempty"`
+	ResolvedBy     string            `json:"resolved_by,omitempty"`
+	ResolvedAt     *time.Time        `json:"resolved_at,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// LeadReviewMatch is an existing lead sharing the email or phone of a reviewed submission
+type LeadReviewMatch struct {
+	LeadID    int    `json:"lead_id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Phone     string `json:"phone"`
+	MatchedOn string `json:"matched_on"` // email or phone
+	Deleted   bool   `json:"deleted,omitempty"`
+}
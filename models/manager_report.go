@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Manager summary period constants
+const (
+	SummaryPeriodDaily  = "daily"
+	SummaryPeriodWeekly = "weekly"
+)
+
+// SourceCount is the number of leads from one lead source
+type SourceCount struct {
+	LeadSource string `json:"lead_source"`
+	Count      int    `json:"count"`
+}
+
+// ManagerSummary is the admissions activity summary emailed to managers
+type ManagerSummary struct {
+	Period               string        `json:"period"`
+	From                 time.Time     `json:"from"`
+	To                   time.Time     `json:"to"`
+	NewLeads             int           `json:"new_leads"`
+	NewLeadsBySource     []SourceCount `json:"new_leads_by_source"`
+	RegistrationPayments int           `json:"registration_payments"`
+	RegistrationAmount   float64       `json:"registration_amount"`
+	CoursePayments       int           `json:"course_payments"`
+	CourseAmount         float64       `json:"course_amount"`
+	InterviewsHeld       int           `json:"interviews_held"`
+	UpcomingInterviews   int           `json:"upcoming_interviews"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// MarketingSpend is the amount spent on a lead source/campaign over a period
+type MarketingSpend struct {
+	ID          int       `json:"id"`
+	LeadSource  string    `json:"lead_source"`
+	Campaign    string    `json:"campaign"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Amount      float64   `json:"amount"`
+	Notes       string    `json:"notes,omitempty"`
+	RecordedBy  string    `json:"recorded_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CACReportRow combines spend and conversions for one lead source/campaign.
+// Cost fields are nil when there are no leads/enrollments to divide by.
+type CACReportRow struct {
+	LeadSource        string   `json:"lead_source"`
+	Campaign          string   `json:"campaign"`
+	Spend             float64  `json:"spend"`
+	Leads             int      `json:"leads"`
+	Enrollments       int      `json:"enrollments"` // leads with course fee paid
+	ConversionRate    float64  `json:"conversion_rate"`
+	CostPerLead       *float64 `json:"cost_per_lead"`
+	CostPerEnrollment *float64 `json:"cost_per_enrollment"`
+}
+
+// CACReport is the customer acquisition cost report for a date range
+type CACReport struct {
+	From   *time.Time     `json:"from,omitempty"`
+	To     *time.Time     `json:"to,omitempty"`
+	Rows   []CACReportRow `json:"rows"`
+	Totals CACReportRow   `json:"totals"`
+}
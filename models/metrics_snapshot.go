@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// MetricsSnapshot is one day of key metrics. Totals, outstanding fees and DLQ depth are
+// as of CapturedAt (shortly after the day ended); the New* counts cover the day itself.
+type MetricsSnapshot struct {
+	Date                 string `json:"date"` // YYYY-MM-DD
+	TotalLeads           int    `json:"total_leads"`
+	NewLeads             int    `json:"new_leads"`
+	RegistrationsPaid    int    `json:"registrations_paid"`
+	NewRegistrationsPaid int    `json:"new_registrations_paid"`
+	Enrollments          int    `json:"enrollments"` // course fee paid
+	NewEnrollments       int    `json:"new_enrollments"`
+	// Accepted students who have not paid the course fee yet, and the fees they owe
+	OutstandingCourseFees      int       `json:"outstanding_course_fees"`
+	OutstandingCourseFeeAmount float64   `json:"outstanding_course_fee_amount"`
+	PendingPaymentOrders       int       `json:"pending_payment_orders"`
+	DLQDepth                   int       `json:"dlq_depth"` // unresolved messages
+	DLQQuarantined             int       `json:"dlq_quarantined"`
+	CapturedAt                 time.Time `json:"captured_at"`
+}
+
+// MetricsSnapshotSeries is the snapshots in a date range, oldest first
+type MetricsSnapshotSeries struct {
+	From      string            `json:"from"`
+	To        string            `json:"to"`
+	Snapshots []MetricsSnapshot `json:"snapshots"`
+}
@@ -3,14 +3,54 @@ package models
 import "time"
 
 type Payment struct {
-	ID              int       `json:"id"`
-	StudentID       int       `json:"student_id"`
-	Amount          float64   `json:"amount"`
-	Status          string    `json:"status"`
-	PaymentType     string    `json:"payment_type"` // REGISTRATION or COURSE_FEE
-	Timestamp       time.Time `json:"timestamp"`
-	OrderID         string    `json:"order_id"`
-	PaymentID       string    `json:"payment_id"`
-	RazorpaySign    string    `json:"razorpay_signature"`
-	RelatedCourseID *int      `json:"related_course_id,omitempty"`
+	ID              int        `json:"id"`
+	StudentID       int        `json:"student_id"`
+	Amount          float64    `json:"amount"`
+	Status          string     `json:"status"`
+	PaymentType     string     `json:"payment_type"` // REGISTRATION or COURSE_FEE
+	Timestamp       time.Time  `json:"timestamp"`
+	OrderID         string     `json:"order_id"`
+	PaymentID       string     `json:"payment_id"`
+	RazorpaySign    string     `json:"razorpay_signature"`
+	RelatedCourseID *int       `json:"related_course_id,omitempty"`
+	ErrorMessage    string     `json:"error_message,omitempty"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	RefundID        string     `json:"refund_id,omitempty"`     // latest Razorpay refund
+	RefundAmount    float64    `json:"refund_amount,omitempty"` // total refunded so far
+	RefundedAt      *time.Time `json:"refunded_at,omitempty"`
+	DisputeID       string     `json:"dispute_id,omitempty"`     // latest chargeback dispute
+	DisputeStatus   string     `json:"dispute_status,omitempty"` // Razorpay's: open, under_review, won, lost or closed
+}
+
+// PaymentRefund is a refund made through Razorpay and recorded on its payment
+type PaymentRefund struct {
+	RefundID      string    `json:"refund_id"`
+	RefundStatus  string    `json:"refund_status"` // Razorpay's: pending, processed or failed
+	StudentID     int       `json:"student_id"`
+	OrderID       string    `json:"order_id"`
+	PaymentID     string    `json:"payment_id"`
+	PaymentType   string    `json:"payment_type"`
+	Amount        float64   `json:"amount"`
+	TotalRefunded float64   `json:"total_refunded"`
+	PaymentStatus string    `json:"payment_status"` // REFUNDED or PARTIALLY_REFUNDED
+	RefundedAt    time.Time `json:"refunded_at"`
+}
+
+// RazorpayPaymentLink is a hosted Razorpay payment page emailed to a lead. OrderID and
+// PaymentID are set once the link is paid.
+type RazorpayPaymentLink struct {
+	ID           string     `json:"id"`
+	LeadID       int        `json:"lead_id"`
+	PaymentType  string     `json:"payment_type"`
+	CourseID     *int       `json:"course_id,omitempty"`
+	Amount       float64    `json:"amount"`
+	ShortURL     string     `json:"short_url"`
+	Status       string     `json:"status"` // created, paid, expired or cancelled
+	OrderID      string     `json:"order_id,omitempty"`
+	PaymentID    string     `json:"payment_id,omitempty"`
+	ErrorMessage string     `json:"error_message,omitempty"`
+	CounselorID  *int64     `json:"counselor_id,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	PaidAt       *time.Time `json:"paid_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
 }
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Payment reconciliation issue types
+const (
+	ReconciliationMissingWebhook = "missing_webhook" // captured on Razorpay, not paid here
+	ReconciliationAmountMismatch = "amount_mismatch"
+	ReconciliationOrphanedOrder  = "orphaned_order" // paid on Razorpay, no payment row here
+)
+
+// PaymentReconciliationRun records one comparison of Razorpay's payments and orders
+// created between WindowStart and WindowEnd with the payment tables
+type PaymentReconciliationRun struct {
+	ID              int        `json:"id"`
+	WindowStart     time.Time  `json:"window_start"`
+	WindowEnd       time.Time  `json:"window_end"`
+	PaymentsChecked int        `json:"payments_checked"`
+	OrdersChecked   int        `json:"orders_checked"`
+	IssuesFound     int        `json:"issues_found"`
+	ErrorMessage    string     `json:"error_message,omitempty"`
+	StartedAt       time.Time  `json:"started_at"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+}
+
+// PaymentReconciliationIssue is a difference between Razorpay and a payment row, kept
+// once per type and order until it is resolved
+type PaymentReconciliationIssue struct {
+	ID             int        `json:"id"`
+	IssueType      string     `json:"issue_type"`
+	OrderID        string     `json:"order_id"`
+	PaymentID      string     `json:"payment_id,omitempty"`
+	PaymentType    string     `json:"payment_type,omitempty"`
+	StudentID      *int       `json:"student_id,omitempty"`
+	RazorpayStatus string     `json:"razorpay_status,omitempty"`
+	RazorpayAmount *float64   `json:"razorpay_amount,omitempty"`
+	RecordedStatus string     `json:"recorded_status,omitempty"`
+	RecordedAmount *float64   `json:"recorded_amount,omitempty"`
+	Detail         string     `json:"detail"`
+	FirstSeenAt    time.Time  `json:"first_seen_at"`
+	LastSeenAt     time.Time  `json:"last_seen_at"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	ResolutionNote string     `json:"resolution_note,omitempty"`
+}
+
+// PaymentReconciliationReport is the latest run with the issues asked for and the number
+// of open issues by type
+type PaymentReconciliationReport struct {
+	LastRun    *PaymentReconciliationRun    `json:"last_run"`
+	OpenCounts map[string]int               `json:"open_counts"`
+	Issues     []PaymentReconciliationIssue `json:"issues"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Retention policy name constants
+const (
+	RetentionPolicyAnonymizeRejectedLeads   = "anonymize_rejected_leads"
+	RetentionPolicyPurgeRejectedLeads       = "purge_rejected_leads"
+	RetentionPolicyPurgeSentEvents          = "purge_sent_events"
+	RetentionPolicyPurgeProcessedEvents     = "purge_processed_events"
+	RetentionPolicyArchiveResolvedDLQ       = "archive_resolved_dlq"
+	RetentionPolicyPurgeArchivedDLQ         = "purge_archived_dlq"
+	RetentionPolicyPurgeAPIUsage            = "purge_api_usage"
+	RetentionPolicyPurgeEmailQuota          = "purge_email_quota"
+	RetentionPolicyArchiveWebhookPartitions = "archive_webhook_partitions"
+)
+
+// RetentionRun records one execution of a retention policy. A dry run only counts the
+// records the policy would process, leaving them in place.
+type RetentionRun struct {
+	ID             int        `json:"id"`
+	Policy         string     `json:"policy"`
+	DryRun         bool       `json:"dry_run,omitempty"`
+	RetentionDays  int        `json:"retention_days,omitempty"`
+	ProcessedCount int        `json:"processed_count"`
+	SkippedCount   int        `json:"skipped_count"` // e.g. leads kept because they have financial records
+	ErrorMessage   string     `json:"error_message,omitempty"`
+	StartedAt      time.Time  `json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,23 @@
+package models
+
+// SLOWindow is the webhook SLO compliance over one rolling window
+type SLOWindow struct {
+	Window       string  `json:"window"`
+	Total        int     `json:"total"`
+	Good         int     `json:"good"`   // processed successfully within the latency threshold
+	Failed       int     `json:"failed"` // processing errors (5xx)
+	Compliance   float64 `json:"compliance"`
+	BurnRate     float64 `json:"burn_rate"` // error budget consumption speed; 1 spends the budget exactly over the SLO period
+	P50LatencyMs float64 `json:"p50_latency_ms"`
+	P99LatencyMs float64 `json:"p99_latency_ms"`
+}
+
+// SLOReport is the payment webhook SLO status across the rolling windows
+type SLOReport struct {
+	Objective          string      `json:"objective"`
+	Target             float64     `json:"target"`
+	LatencyThresholdMs int         `json:"latency_threshold_ms"`
+	Windows            []SLOWindow `json:"windows"`
+	FastBurn           bool        `json:"fast_burn"` // budget burning >14.4x over both 5m and 1h
+	SlowBurn           bool        `json:"slow_burn"` // budget burning >6x over both 30m and 6h
+}
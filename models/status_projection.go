@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Status projection sources: where a rebuild found the lead's status changes
+const (
+	StatusProjectionSourceHistory = "history" // application_status_history
+	StatusProjectionSourceEvents  = "events"  // lead_event (leads older than the history table)
+	StatusProjectionSourceNone    = "none"    // nothing to replay; the lead is left unchanged
+)
+
+// StatusChange is one application status change replayed by a projection rebuild
+type StatusChange struct {
+	From      string    `json:"from,omitempty"` // status before the change, when recorded
+	Status    string    `json:"status"`
+	Source    string    `json:"source"` // app, projection_rebuild, or the event type for events
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// StatusProjectionDiff compares a lead's stored application status with the status
+// recomputed by replaying its history through the status transitions
+type StatusProjectionDiff struct {
+	LeadID          int            `json:"lead_id"`
+	CurrentStatus   string         `json:"current_status"`
+	ProjectedStatus string         `json:"projected_status"`
+	Source          string         `json:"source"`
+	Changed         bool           `json:"changed"`
+	Applied         bool           `json:"applied"`
+	Replayed        int            `json:"replayed"`
+	Rejected        []StatusChange `json:"rejected_changes,omitempty"` // changes the transitions do not allow
+	Error           string         `json:"error,omitempty"`
+}
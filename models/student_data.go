@@ -0,0 +1,23 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// StudentDataExport is everything held about a student, for a data subject access
+// request. Each section is the JSON of the matching rows; lead is an object, the
+// others are arrays.
+type StudentDataExport struct {
+	StudentID  int                        `json:"student_id"`
+	ExportedAt time.Time                  `json:"exported_at"`
+	Sections   map[string]json.RawMessage `json:"data"`
+}
+
+// StudentDataErasure reports an erasure of a student's personal data: how many rows
+// were anonymized or removed in each place it was held
+type StudentDataErasure struct {
+	StudentID int            `json:"student_id"`
+	ErasedAt  time.Time      `json:"erased_at"`
+	Rows      map[string]int `json:"rows"`
+}
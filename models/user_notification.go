@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Notification type constants
+const (
+	NotificationTypeMention = "MENTION"
+)
+
+// UserNotification is an in-app notification addressed to a counselor
+type UserNotification struct {
+	ID          int        `json:"id"`
+	CounselorID int64      `json:"counselor_id"`
+	Type        string     `json:"type"`
+	Title       string     `json:"title"`
+	Message     string     `json:"message"`
+	LeadID      *int       `json:"lead_id,omitempty"`
+	NoteID      *int       `json:"note_id,omitempty"`
+	ReadAt      *time.Time `json:"read_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -0,0 +1,100 @@
+// Package ratelimit implements token bucket rate limiting with the buckets kept in memory
+// (per process) or in Redis (shared by all instances). Like metrics it has no dependencies
+// on the rest of the module; the HTTP middleware supplies the limits.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket: Burst requests at once, refilled at PerMinute a minute
+type Limit struct {
+	PerMinute int
+	Burst     int
+}
+
+// Result is the outcome of taking one token from a bucket
+type Result struct {
+	Allowed    bool
+	Remaining  int           // whole tokens left after this request
+	RetryAfter time.Duration // when the next token is available, if not Allowed
+}
+
+// Limiter takes tokens from the bucket of a key
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// sweepInterval is how often the memory limiter drops buckets that have refilled
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// MemoryLimiter keeps the buckets of one process in memory
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryLimiter returns an empty in-memory limiter
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{buckets: map[string]*bucket{}, now: time.Now}
+}
+
+// Allow takes a token from the bucket of key, creating it full
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		l.buckets[key] = b
+	}
+	b.limit = limit
+	b.tokens = refill(b.tokens, now.Sub(b.updated), limit)
+	b.updated = now
+
+	if b.tokens < 1 {
+		return Result{RetryAfter: untilNextToken(b.tokens, limit)}, nil
+	}
+	b.tokens--
+	return Result{Allowed: true, Remaining: int(b.tokens)}, nil
+}
+
+// sweep drops buckets that are full again, which behave like new ones
+func (l *MemoryLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if refill(b.tokens, now.Sub(b.updated), b.limit) >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// refill adds the tokens earned over elapsed, up to the burst
+func refill(tokens float64, elapsed time.Duration, limit Limit) float64 {
+	tokens += elapsed.Minutes() * float64(limit.PerMinute)
+	return math.Min(tokens, float64(limit.Burst))
+}
+
+func untilNextToken(tokens float64, limit Limit) time.Duration {
+	if limit.PerMinute <= 0 {
+		return time.Minute
+	}
+	return time.Duration((1 - tokens) / float64(limit.PerMinute) * float64(time.Minute))
+}
@@ -0,0 +1,235 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisTimeout bounds a Redis round trip when the request context has no earlier deadline
+const redisTimeout = 500 * time.Millisecond
+
+// maxIdleRedisConns caps the connections kept open between requests
+const maxIdleRedisConns = 8
+
+// tokenBucketScript refills and takes from a bucket stored as a hash {tokens, ts} in one
+// atomic step. Time comes from the Redis server so instances with skewed clocks agree.
+// Returns {allowed, remaining tokens, milliseconds until the next token}.
+const tokenBucketScript = `
+local rate = tonumber(ARGV[1]) / 60000
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local allowed, retry = 0, 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate))
+return {allowed, math.floor(tokens), retry}`
+
+// RedisLimiter keeps the buckets in Redis, so all instances share them
+type RedisLimiter struct {
+	addr     string
+	password string
+	db       int
+
+	mu   sync.Mutex
+	idle []*redisConn
+}
+
+// NewRedisLimiter returns a limiter using the Redis server at addr; connections are
+// opened on first use
+func NewRedisLimiter(addr, password string, db int) *RedisLimiter {
+	return &RedisLimiter{addr: addr, password: password, db: db}
+}
+
+// Allow takes a token from the bucket of key
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	reply, err := l.do(ctx, "EVAL", tokenBucketScript, "1", "ratelimit:"+key,
+		strconv.Itoa(limit.PerMinute), strconv.Itoa(limit.Burst))
+	if err != nil {
+		return Result{}, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 3 {
+		return Result{}, fmt.Errorf("unexpected rate limit reply: %v", reply)
+	}
+	allowed, _ := values[0].(int64)
+	remaining, _ := values[1].(int64)
+	retryMs, _ := values[2].(int64)
+	return Result{
+		Allowed:    allowed == 1,
+		Remaining:  int(remaining),
+		RetryAfter: time.Duration(retryMs) * time.Millisecond,
+	}, nil
+}
+
+// Close closes the idle connections
+func (l *RedisLimiter) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, c := range l.idle {
+		c.conn.Close()
+	}
+	l.idle = nil
+	return nil
+}
+
+// do runs one command, reusing an idle connection when there is one. A connection that
+// failed is closed instead of being reused.
+func (l *RedisLimiter) do(ctx context.Context, args ...string) (interface{}, error) {
+	c, err := l.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(redisTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	c.conn.SetDeadline(deadline)
+
+	reply, err := c.command(args...)
+	var redisErr redisError
+	if err != nil && !errors.As(err, &redisErr) {
+		c.conn.Close()
+		return nil, err
+	}
+	l.put(c)
+	return reply, err
+}
+
+func (l *RedisLimiter) get(ctx context.Context) (*redisConn, error) {
+	l.mu.Lock()
+	if n := len(l.idle); n > 0 {
+		c := l.idle[n-1]
+		l.idle = l.idle[:n-1]
+		l.mu.Unlock()
+		return c, nil
+	}
+	l.mu.Unlock()
+	return dialRedis(ctx, l.addr, l.password, l.db)
+}
+
+func (l *RedisLimiter) put(c *redisConn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.idle) >= maxIdleRedisConns {
+		c.conn.Close()
+		return
+	}
+	l.idle = append(l.idle, c)
+}
+
+// redisConn is a minimal RESP client connection: enough to authenticate and run scripts
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// redisError is an error reply from the server; the connection stays usable
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+func dialRedis(ctx context.Context, addr, password string, db int) (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: redisTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to Redis: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(redisTimeout))
+
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+	if password != "" {
+		if _, err := c.command("AUTH", password); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("error authenticating to Redis: %w", err)
+		}
+	}
+	if db != 0 {
+		if _, err := c.command("SELECT", strconv.Itoa(db)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("error selecting Redis database %d: %w", db, err)
+		}
+	}
+	return c, nil
+}
+
+// command sends args as a RESP array of bulk strings and reads the reply
+func (c *redisConn) command(args ...string) (interface{}, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&sb, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, sb.String()); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+// readReply parses one RESP reply: simple strings, errors, integers, bulk strings
+// (nil when absent) and arrays of those
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("malformed Redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		values := make([]interface{}, n)
+		for i := range values {
+			if values[i], err = c.readReply(); err != nil {
+				var redisErr redisError
+				if !errors.As(err, &redisErr) {
+					return nil, err
+				}
+				values[i] = err
+			}
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("malformed Redis reply: %q", line)
+	}
+}
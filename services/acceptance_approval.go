@@ -0,0 +1,155 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+var (
+	// ErrApproverRequired is returned when an acceptance needing dual approval names no approver
+	ErrApproverRequired = errors.New("approved_by is required for courses needing dual approval")
+	// ErrSameApprover is returned when the first approver tries to confirm their own acceptance
+	ErrSameApprover = errors.New("the acceptance must be confirmed by a different approver")
+	// ErrApprovalCourseMismatch is returned when the pending acceptance is for another course
+	ErrApprovalCourseMismatch = errors.New("the pending acceptance is for a different course")
+)
+
+// requiresDualApproval reports whether accepting into a course with this fee needs two approvers
+func requiresDualApproval(courseFee float64) bool {
+	minFee := config.AppConfig.DualApprovalMinCourseFee
+	return minFee > 0 && courseFee >= minFee
+}
+
+// findPendingApproval locks and returns the pending acceptance approval of a student, or nil
+func findPendingApproval(ctx context.Context, tx *sql.Tx, studentID int) (*models.AcceptanceApproval, error) {
+	var a models.AcceptanceApproval
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, student_id, course_id, status, requested_by, created_at
+		FROM acceptance_approval
+		WHERE student_id = $1 AND status = $2
+		FOR UPDATE`, studentID, models.AcceptanceApprovalPending,
+	).Scan(&a.ID, &a.StudentID, &a.CourseID, &a.Status, &a.RequestedBy, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading pending approval: %w", err)
+	}
+	return &a, nil
+}
+
+// createPendingApproval records the first approval of an acceptance
+func createPendingApproval(ctx context.Context, tx *sql.Tx, studentID, courseID int, requestedBy string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO acceptance_approval (student_id, course_id, status, requested_by)
+		VALUES ($1, $2, $3, $4)`,
+		studentID, courseID, models.AcceptanceApprovalPending, requestedBy)
+	if err != nil {
+		return fmt.Errorf("error recording approval: %w", err)
+	}
+	return nil
+}
+
+// resolvePendingApproval closes a pending approval as APPROVED, OVERRIDDEN or CANCELLED
+func resolvePendingApproval(ctx context.Context, tx *sql.Tx, approvalID int, status, approvedBy string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE acceptance_approval
+		SET status = $1, approved_by = NULLIF($2, ''), resolved_at = NOW()
+		WHERE id = $3`, status, approvedBy, approvalID)
+	if err != nil {
+		return fmt.Errorf("error resolving approval: %w", err)
+	}
+	return nil
+}
+
+// cancelPendingApproval cancels a student's pending acceptance, if any, when the
+// application is rejected or waitlisted instead
+func cancelPendingApproval(ctx context.Context, tx *sql.Tx, studentID int) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE acceptance_approval SET status = $1, resolved_at = NOW()
+		WHERE student_id = $2 AND status = $3`,
+		models.AcceptanceApprovalCancelled, studentID, models.AcceptanceApprovalPending)
+	if err != nil {
+		return fmt.Errorf("error cancelling pending approval: %w", err)
+	}
+	return nil
+}
+
+// GetPendingAcceptanceApprovals lists acceptances awaiting a second approver, oldest first
+func GetPendingAcceptanceApprovals(ctx context.Context) ([]models.AcceptanceApproval, error) {
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT aa.id, aa.student_id, sl.name, aa.course_id, c.name, c.fee, aa.status, aa.requested_by, aa.created_at
+		FROM acceptance_approval aa
+		JOIN student_lead sl ON sl.id = aa.student_id
+		JOIN course c ON c.id = aa.course_id
+		WHERE aa.status = $1
+		ORDER BY aa.created_at ASC`, models.AcceptanceApprovalPending)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching pending approvals: %w", err)
+	}
+	defer rows.Close()
+
+	approvals := []models.AcceptanceApproval{}
+	for rows.Next() {
+		var a models.AcceptanceApproval
+		if err := rows.Scan(&a.ID, &a.StudentID, &a.StudentName, &a.CourseID, &a.CourseName, &a.CourseFee,
+			&a.Status, &a.RequestedBy, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error reading pending approval: %w", err)
+		}
+		approvals = append(approvals, a)
+	}
+	return approvals, rows.Err()
+}
+
+// pendingApprovers returns the configured approvers other than the one who gave the first approval
+func pendingApprovers(requestedBy string) []string {
+	var approvers []string
+	for _, email := range strings.Split(config.AppConfig.AcceptanceApproverEmails, ",") {
+		email = strings.TrimSpace(email)
+		if email != "" && !strings.EqualFold(email, requestedBy) {
+			approvers = append(approvers, email)
+		}
+	}
+	return approvers
+}
+
+// notifyPendingApprovers asks the other approvers to confirm an acceptance
+func notifyPendingApprovers(result *AcceptApplicationResult) error {
+	approvers := pendingApprovers(result.RequestedBy)
+	if len(approvers) == 0 {
+		log.Printf("Warning: acceptance of %s awaits a second approval but ACCEPTANCE_APPROVER_EMAILS names no other approver", result.StudentName)
+		return nil
+	}
+
+	subject, body := buildApprovalRequestEmail(result)
+	var firstErr error
+	for _, approver := range approvers {
+		if err := SendCategorizedEmail(EmailCategoryStaff, approver, subject, body); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// buildApprovalRequestEmail renders the request for a second approval of an acceptance
+func buildApprovalRequestEmail(result *AcceptApplicationResult) (subject, body string) {
+	body = fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <p><strong>%s</strong> accepted <strong>%s</strong> (student ID %d) into <strong>%s</strong> (fee: ₹%.2f).</p>
+    <p>Acceptances into this course need a second approver. Confirm by accepting the application
+    for the same course, or reject or waitlist it instead.</p>
+</body>
+</html>
+	`, result.RequestedBy, result.StudentName, result.StudentID, result.CourseName, result.CourseFee)
+
+	return fmt.Sprintf("Approval needed: %s for %s", result.StudentName, result.CourseName), body
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"admission-module/utils"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidSummaryRecipient is returned when a summary recipient is not a valid email
+	ErrInvalidSummaryRecipient = errors.New("a valid email is required")
+	// ErrSummaryRecipientExists is returned when the email already receives the summary
+	ErrSummaryRecipientExists = errors.New("this email already receives the summary")
+	// ErrSummaryRecipientNotFound is returned when no recipient has the given ID
+	ErrSummaryRecipientNotFound = errors.New("summary recipient not found")
+)
+
+// ListAdminSummaryRecipients returns the recipients of the end-of-day summary by email
+func ListAdminSummaryRecipients(ctx context.Context) ([]models.AdminSummaryRecipient, error) {
+	rows, err := db.DB.QueryContext(ctx,
+		"SELECT id, email, COALESCE(added_by, ''), created_at FROM admin_summary_recipient ORDER BY email")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching summary recipients: %w", err)
+	}
+	defer rows.Close()
+
+	recipients := []models.AdminSummaryRecipient{}
+	for rows.Next() {
+		var r models.AdminSummaryRecipient
+		if err := rows.Scan(&r.ID, &r.Email, &r.AddedBy, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error reading summary recipient: %w", err)
+		}
+		recipients = append(recipients, r)
+	}
+	return recipients, rows.Err()
+}
+
+// AddAdminSummaryRecipient adds an address to the end-of-day summary
+func AddAdminSummaryRecipient(ctx context.Context, email, addedBy string) (*models.AdminSummaryRecipient, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if utils.ValidateEmail(email) != nil {
+		return nil, ErrInvalidSummaryRecipient
+	}
+
+	r := &models.AdminSummaryRecipient{Email: email, AddedBy: strings.TrimSpace(addedBy)}
+	err := db.DB.QueryRowContext(ctx, `
+		INSERT INTO admin_summary_recipient (email, added_by) VALUES ($1, NULLIF($2, ''))
+		RETURNING id, created_at`, r.Email, r.AddedBy).Scan(&r.ID, &r.CreatedAt)
+	if isUniqueViolation(err) {
+		return nil, ErrSummaryRecipientExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error adding summary recipient: %w", err)
+	}
+	return r, nil
+}
+
+// RemoveAdminSummaryRecipient stops sending the end-of-day summary to a recipient
+func RemoveAdminSummaryRecipient(ctx context.Context, id int) error {
+	res, err := db.DB.ExecContext(ctx, "DELETE FROM admin_summary_recipient WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("error removing summary recipient: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSummaryRecipientNotFound
+	}
+	return nil
+}
+
+// GetAdminDailySummary collects the day's numbers from midnight until now: new leads and
+// payments as in the manager summary, acceptances and the DLQ messages that failed, with
+// the DLQ backlog as of now
+func GetAdminDailySummary(ctx context.Context, now time.Time) (*models.AdminDailySummary, error) {
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	summary := &models.AdminDailySummary{
+		ManagerSummary: models.ManagerSummary{Period: models.SummaryPeriodDaily, From: from, To: now},
+	}
+	if err := collectActivity(ctx, &summary.ManagerSummary); err != nil {
+		return nil, err
+	}
+
+	err := db.Reader().QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(DISTINCT lead_id) FROM application_status_history
+			 WHERE to_status = $3 AND changed_at >= $1 AND changed_at < $2),
+			(SELECT COUNT(*) FROM dlq_messages WHERE created_at >= $1 AND created_at < $2),
+			(SELECT COUNT(*) FROM dlq_messages WHERE resolved = FALSE),
+			(SELECT COUNT(*) FROM dlq_messages WHERE resolved = FALSE AND quarantined_at IS NOT NULL)`,
+		from, now, ApplicationStatusAccepted,
+	).Scan(&summary.Acceptances, &summary.NewDLQMessages,
+		&summary.UnresolvedDLQMessages, &summary.QuarantinedDLQMessages)
+	if err != nil {
+		return nil, fmt.Errorf("error counting acceptances and DLQ messages: %w", err)
+	}
+	return summary, nil
+}
+
+// SendAdminDailySummary emails the day's summary to every summary recipient. Does nothing
+// unless ADMIN_SUMMARY_ENABLED is set and someone is on the recipient list.
+func SendAdminDailySummary(ctx context.Context) error {
+	if !config.AppConfig.AdminSummaryEnabled || db.DB == nil {
+		return nil
+	}
+
+	recipients, err := ListAdminSummaryRecipients(ctx)
+	if err != nil {
+		return err
+	}
+	if len(recipients) == 0 {
+		logger.Warn("End-of-day summary enabled but no recipients are configured")
+		return nil
+	}
+
+	summary, err := GetAdminDailySummary(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	subject, body := buildAdminDailySummaryEmail(summary)
+
+	var failed int
+	for _, recipient := range recipients {
+		if err := SendCategorizedEmail(EmailCategoryStaff, recipient.Email, subject, body); err != nil {
+			logger.Error("Error sending end-of-day summary to %s: %v", recipient.Email, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d end-of-day summary emails failed", failed, len(recipients))
+	}
+
+	logger.Info("Sent end-of-day summary to %d recipients", len(recipients))
+	return nil
+}
+
+// buildAdminDailySummaryEmail renders the end-of-day summary
+func buildAdminDailySummaryEmail(summary *models.AdminDailySummary) (subject, body string) {
+	dlqNote := "No new DLQ failures."
+	if summary.NewDLQMessages > 0 || summary.QuarantinedDLQMessages > 0 {
+		dlqNote = "Review the DLQ: failed messages are not redelivered until retried or resolved."
+	}
+
+	body = fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; color: #333;">
+    <h2>End-of-day admissions summary</h2>
+    <p>%s, until %s</p>
+    <table border="1" cellpadding="6" cellspacing="0" style="border-collapse: collapse;">
+        <tr><td>New leads</td><td>%d</td></tr>
+        <tr><td>Acceptances</td><td>%d</td></tr>
+    </table>
+    <h3>Payments collected</h3>
+    <table border="1" cellpadding="6" cellspacing="0" style="border-collapse: collapse;">
+        <tr><th>Type</th><th>Count</th><th>Amount (INR)</th></tr>
+        <tr><td>Registration fee</td><td>%d</td><td>%.2f</td></tr>
+        <tr><td>Course fee</td><td>%d</td><td>%.2f</td></tr>
+        <tr><td><strong>Total</strong></td><td>%d</td><td><strong>%.2f</strong></td></tr>
+    </table>
+    <h3>DLQ</h3>
+    <table border="1" cellpadding="6" cellspacing="0" style="border-collapse: collapse;">
+        <tr><td>Failed today</td><td>%d</td></tr>
+        <tr><td>Unresolved</td><td>%d</td></tr>
+        <tr><td>Quarantined</td><td>%d</td></tr>
+    </table>
+    <p>%s</p>
+</body>
+</html>`,
+		summary.From.Format("Monday, 02 Jan 2006"), summary.To.Format("15:04"),
+		summary.NewLeads, summary.Acceptances,
+		summary.RegistrationPayments, summary.RegistrationAmount,
+		summary.CoursePayments, summary.CourseAmount,
+		summary.RegistrationPayments+summary.CoursePayments, summary.RegistrationAmount+summary.CourseAmount,
+		summary.NewDLQMessages, summary.UnresolvedDLQMessages, summary.QuarantinedDLQMessages,
+		dlqNote)
+
+	return fmt.Sprintf("End-of-day summary: %s", summary.From.Format("02 Jan 2006")), body
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/models"
+	"context"
+	"fmt"
+	"time"
+)
+
+// GetFunnel counts how many leads created within [from, to] reached each admission stage.
+// A stage counts leads that reached it or any later stage, so the funnel never widens.
+func GetFunnel(ctx context.Context, from, to *time.Time, counselorID *int64) (*models.Funnel, error) {
+	var counts [5]int
+	err := db.Reader().QueryRowContext(ctx, `
+		WITH cohort AS (
+			SELECT
+				sl.id,
+				(sl.course_fee_status = $3
+					OR EXISTS (SELECT 1 FROM course_payment cp WHERE cp.student_id = sl.id AND cp.status = $3)) AS course_paid,
+				sl.application_status = 'ACCEPTED' AS accepted,
+				(sl.interview_scheduled_at IS NOT NULL
+					OR sl.application_status IN ('INTERVIEW_SCHEDULED', 'MEETING_SCHEDULED')) AS interviewed,
+				(sl.registration_fee_status = $3
+					OR EXISTS (SELECT 1 FROM registration_payment rp WHERE rp.student_id = sl.id AND rp.status = $3)) AS registration_paid
+			FROM student_lead sl
+			WHERE sl.deleted_at IS NULL
+				AND ($1::date IS NULL OR sl.created_at >= $1::date)
+				AND ($2::date IS NULL OR sl.created_at < $2::date + 1)
+				AND ($4::int IS NULL OR sl.counselor_id = $4)
+		)
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE registration_paid OR interviewed OR accepted OR course_paid),
+			COUNT(*) FILTER (WHERE interviewed OR accepted OR course_paid),
+			COUNT(*) FILTER (WHERE accepted OR course_paid),
+			COUNT(*) FILTER (WHERE course_paid)
+		FROM cohort`,
+		nullableTime(from), nullableTime(to), PaymentStatusPaid, counselorID,
+	).Scan(&counts[0], &counts[1], &counts[2], &counts[3], &counts[4])
+	if err != nil {
+		return nil, fmt.Errorf("error computing funnel: %w", err)
+	}
+
+	stageNames := []string{
+		models.FunnelStageLeadCreated,
+		models.FunnelStageRegistrationPaid,
+		models.FunnelStageInterviewed,
+		models.FunnelStageAccepted,
+		models.FunnelStageCoursePaid,
+	}
+
+	funnel := &models.Funnel{From: from, To: to, Stages: make([]models.FunnelStage, len(stageNames))}
+	for i, name := range stageNames {
+		stage := models.FunnelStage{Stage: name, Count: counts[i]}
+		if i > 0 && counts[i-1] > 0 {
+			stage.ConversionFromPrevious = float64(counts[i]) / float64(counts[i-1])
+		} else if i == 0 && counts[0] > 0 {
+			stage.ConversionFromPrevious = 1
+		}
+		if counts[0] > 0 {
+			stage.ConversionFromStart = float64(counts[i]) / float64(counts[0])
+		}
+		funnel.Stages[i] = stage
+	}
+
+	return funnel, nil
+}
+
+// GetStageAging buckets active leads by the days spent in their current application_status.
+// Leads that predate status tracking fall back to their last update, then creation time.
+func GetStageAging(ctx context.Context, counselorID *int64) ([]models.StageAging, error) {
+	rows, err := db.Reader().QueryContext(ctx, `
+		SELECT
+			COALESCE(application_status, 'NEW') AS status,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE age_days <= 2),
+			COUNT(*) FILTER (WHERE age_days BETWEEN 3 AND 7),
+			COUNT(*) FILTER (WHERE age_days BETWEEN 8 AND 14),
+			COUNT(*) FILTER (WHERE age_days >= 15)
+		FROM (
+			SELECT application_status,
+				EXTRACT(DAY FROM NOW() - COALESCE(status_changed_at, updated_at, created_at))::int AS age_days
+			FROM student_lead
+			WHERE deleted_at IS NULL
+				AND ($1::int IS NULL OR counselor_id = $1)
+		) aged
+		GROUP BY 1
+		ORDER BY 1`, counselorID)
+	if err != nil {
+		return nil, fmt.Errorf("error computing stage aging: %w", err)
+	}
+	defer rows.Close()
+
+	stages := []models.StageAging{}
+	for rows.Next() {
+		var s models.StageAging
+		if err := rows.Scan(&s.Status, &s.Total, &s.Days0To2, &s.Days3To7, &s.Days8To14, &s.Days15Plus); err != nil {
+			return nil, fmt.Errorf("error reading stage aging: %w", err)
+		}
+		stages = append(stages, s)
+	}
+
+	return stages, rows.Err()
+}
+
+// GetCampaignConversions groups leads created within [from, to] by UTM source, medium
+// and campaign. The campaign falls back to the lead's campaign column, and leads
+// without UTM data are grouped under empty values.
+func GetCampaignConversions(ctx context.Context, from, to *time.Time) ([]models.CampaignConversion, error) {
+	rows, err := db.Reader().QueryContext(ctx, `
+		SELECT
+			COALESCE(utm_source, ''), COALESCE(utm_medium, ''), COALESCE(utm_campaign, campaign, ''),
+			COUNT(*),
+			COUNT(*) FILTER (WHERE registration_fee_status = $3),
+			COUNT(*) FILTER (WHERE course_fee_status = $3)
+		FROM student_lead
+		WHERE deleted_at IS NULL
+			AND ($1::date IS NULL OR created_at >= $1::date)
+			AND ($2::date IS NULL OR created_at < $2::date + 1)
+		GROUP BY 1, 2, 3
+		ORDER BY 4 DESC, 1, 2, 3`, nullableTime(from), nullableTime(to), PaymentStatusPaid)
+	if err != nil {
+		return nil, fmt.Errorf("error computing campaign conversions: %w", err)
+	}
+	defer rows.Close()
+
+	conversions := []models.CampaignConversion{}
+	for rows.Next() {
+		var c models.CampaignConversion
+		if err := rows.Scan(&c.UTMSource, &c.UTMMedium, &c.UTMCampaign, &c.Leads, &c.RegistrationPaid, &c.Enrollments); err != nil {
+			return nil, fmt.Errorf("error reading campaign conversions: %w", err)
+		}
+		if c.Leads > 0 {
+			c.ConversionRate = float64(c.Enrollments) / float64(c.Leads)
+		}
+		conversions = append(conversions, c)
+	}
+
+	return conversions, rows.Err()
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/models"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// apiUsageRetentionDays is how long hourly API usage is kept by default
+const apiUsageRetentionDays = 90
+
+type apiUsageKey struct {
+	hour     time.Time
+	consumer string
+	route    string
+	method   string
+}
+
+type apiUsageCounts struct {
+	requests     int64
+	clientErrors int64
+	serverErrors int64
+	totalMs      float64
+	maxMs        float64
+}
+
+// API usage is aggregated in memory and written to api_usage by the api-usage-flush job,
+// so requests never wait on the database for it
+var (
+	apiUsageMutex   sync.Mutex
+	apiUsagePending = map[apiUsageKey]*apiUsageCounts{}
+)
+
+// RecordAPIUsage counts one request of a consumer against its route's current hour.
+// consumer must fit api_usage.consumer (100 characters) and route should be the matched
+// route pattern, not the raw path.
+func RecordAPIUsage(consumer, route, method string, status int, elapsed time.Duration) {
+	key := apiUsageKey{
+		hour:     time.Now().UTC().Truncate(time.Hour),
+		consumer: consumer,
+		route:    route,
+		method:   method,
+	}
+	ms := float64(elapsed) / float64(time.Millisecond)
+
+	apiUsageMutex.Lock()
+	defer apiUsageMutex.Unlock()
+	counts := apiUsagePending[key]
+	if counts == nil {
+		counts = &apiUsageCounts{}
+		apiUsagePending[key] = counts
+	}
+	counts.add(apiUsageCounts{requests: 1, totalMs: ms, maxMs: ms})
+	switch {
+	case status >= 500:
+		counts.serverErrors++
+	case status >= 400:
+		counts.clientErrors++
+	}
+}
+
+func (c *apiUsageCounts) add(o apiUsageCounts) {
+	c.requests += o.requests
+	c.clientErrors += o.clientErrors
+	c.serverErrors += o.serverErrors
+	c.totalMs += o.totalMs
+	if o.maxMs > c.maxMs {
+		c.maxMs = o.maxMs
+	}
+}
+
+// FlushAPIUsage adds the usage recorded since the last flush to api_usage. On failure
+// the counts are kept in memory for the next flush.
+func FlushAPIUsage(ctx context.Context) error {
+	apiUsageMutex.Lock()
+	pending := apiUsagePending
+	apiUsagePending = map[apiUsageKey]*apiUsageCounts{}
+	apiUsageMutex.Unlock()
+
+	if len(pending) == 0 || db.DB == nil {
+		restoreAPIUsage(pending)
+		return nil
+	}
+
+	if err := writeAPIUsage(ctx, pending); err != nil {
+		restoreAPIUsage(pending)
+		return err
+	}
+	return nil
+}
+
+// restoreAPIUsage merges counts that could not be written back into the pending set
+func restoreAPIUsage(pending map[apiUsageKey]*apiUsageCounts) {
+	apiUsageMutex.Lock()
+	defer apiUsageMutex.Unlock()
+	for key, counts := range pending {
+		if current := apiUsagePending[key]; current != nil {
+			current.add(*counts)
+		} else {
+			apiUsagePending[key] = counts
+		}
+	}
+}
+
+func writeAPIUsage(ctx context.Context, pending map[apiUsageKey]*apiUsageCounts) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for key, c := range pending {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO api_usage (hour_start, consumer, route, method, request_count,
+				client_error_count, server_error_count, total_duration_ms, max_duration_ms)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (hour_start, consumer, route, method) DO UPDATE SET
+				request_count = api_usage.request_count + EXCLUDED.request_count,
+				client_error_count = api_usage.client_error_count + EXCLUDED.client_error_count,
+				server_error_count = api_usage.server_error_count + EXCLUDED.server_error_count,
+				total_duration_ms = api_usage.total_duration_ms + EXCLUDED.total_duration_ms,
+				max_duration_ms = GREATEST(api_usage.max_duration_ms, EXCLUDED.max_duration_ms)`,
+			key.hour, key.consumer, key.route, key.method, c.requests,
+			c.clientErrors, c.serverErrors, c.totalMs, c.maxMs)
+		if err != nil {
+			return fmt.Errorf("error writing API usage: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// APIUsageFilter narrows GetAPIUsage to a window and optionally a consumer or route
+type APIUsageFilter struct {
+	Since    time.Time
+	Consumer string
+	Route    string
+	Hourly   bool // one row per hour instead of totals over the window
+	Limit    int
+}
+
+// GetAPIUsage returns request counts, error rates and latency per consumer and route
+// since filter.Since, busiest first (or newest hour first when hourly)
+func GetAPIUsage(ctx context.Context, filter APIUsageFilter) ([]models.APIUsage, error) {
+	hourColumn, orderBy := "NULL::timestamp", "SUM(request_count) DESC"
+	if filter.Hourly {
+		hourColumn, orderBy = "hour_start", "hour_start DESC, SUM(request_count) DESC"
+	}
+
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT `+hourColumn+`, consumer, route, method,
+			SUM(request_count), SUM(client_error_count), SUM(server_error_count),
+			SUM(total_duration_ms), MAX(max_duration_ms)
+		FROM api_usage
+		WHERE hour_start >= $1
+			AND ($2 = '' OR consumer = $2)
+			AND ($3 = '' OR route = $3)
+		GROUP BY 1, consumer, route, method
+		ORDER BY `+orderBy+`, consumer, route, method
+		LIMIT $4`,
+		filter.Since.UTC().Truncate(time.Hour), filter.Consumer, filter.Route, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching API usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := []models.APIUsage{}
+	for rows.Next() {
+		var u models.APIUsage
+		var totalMs float64
+		if err := rows.Scan(&u.HourStart, &u.Consumer, &u.Route, &u.Method, &u.RequestCount,
+			&u.ClientErrorCount, &u.ServerErrorCount, &totalMs, &u.MaxDurationMs); err != nil {
+			return nil, fmt.Errorf("error reading API usage: %w", err)
+		}
+		if u.RequestCount > 0 {
+			u.ErrorRate = float64(u.ClientErrorCount+u.ServerErrorCount) / float64(u.RequestCount)
+			u.AvgDurationMs = totalMs / float64(u.RequestCount)
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
+// purgeAPIUsage is a retention policy deleting hourly API usage older than cutoff
+func purgeAPIUsage(ctx context.Context, cutoff time.Time, dryRun bool) (int, int, error) {
+	if dryRun {
+		return countRows(ctx, "SELECT COUNT(*) FROM api_usage WHERE hour_start < $1", cutoff.UTC())
+	}
+	result, err := db.DB.ExecContext(ctx, "DELETE FROM api_usage WHERE hour_start < $1", cutoff.UTC())
+	if err != nil {
+		return 0, 0, fmt.Errorf("error purging API usage: %w", err)
+	}
+	purged, _ := result.RowsAffected()
+	return int(purged), 0, nil
+}
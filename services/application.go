@@ -1,28 +1,63 @@
 package services
 
 import (
+	"admission-module/config"
 	"admission-module/db"
+	"admission-module/models"
+	"context"
+	"database/sql"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 )
 
 // ApplicationService handles all application review operations
-type ApplicationService struct{}
+type ApplicationService struct {
+	courses  CourseRepository
+	payments PaymentRepository
+}
 
 // AcceptApplicationRequest represents the request for accepting an application
 type AcceptApplicationRequest struct {
 	StudentID        int
 	SelectedCourseID int
+	// ApprovedBy names the staff member taking the decision; required for courses
+	// needing dual approval
+	ApprovedBy string
+	// Override lets an admin accept without waiting for a second approver
+	Override bool
 }
 
 // AcceptApplicationResult contains the result of accepting an application
 type AcceptApplicationResult struct {
+	StudentID    int
 	StudentName  string
 	StudentEmail string
 	CourseName   string
 	CourseFee    float64
 	CourseID     int
+	// PaymentDeadline is when the offer expires if the course fee is still unpaid
+	PaymentDeadline time.Time
+	// PendingApproval is set when this was the first of two required approvals; the
+	// application is PENDING_APPROVAL and RequestedBy is the first approver
+	PendingApproval bool
+	RequestedBy     string
+}
+
+// WaitlistApplicationRequest represents the request for waitlisting an application
+type WaitlistApplicationRequest struct {
+	StudentID        int
+	SelectedCourseID int
+}
+
+// WaitlistApplicationResult contains the result of waitlisting an application
+type WaitlistApplicationResult struct {
+	StudentName  string
+	StudentEmail string
+	CourseName   string
+	CourseID     int
+	Position     int // 1-based position on the course waitlist
 }
 
 // RejectApplicationRequest represents the request for rejecting an application
@@ -36,61 +71,255 @@ type RejectApplicationResult struct {
 	StudentEmail string
 }
 
-// NewApplicationService creates a new ApplicationService instance
+// NewApplicationService creates an ApplicationService over the application database
 func NewApplicationService() *ApplicationService {
-	return &ApplicationService{}
+	return NewApplicationServiceWith(PostgresRepositories())
 }
 
-// AcceptApplication accepts an application and returns course details
-func (s *ApplicationService) AcceptApplication(req AcceptApplicationRequest) (*AcceptApplicationResult, error) {
+// NewApplicationServiceWith creates an ApplicationService over the given repositories.
+// Decisions themselves change the lead in a database transaction.
+func NewApplicationServiceWith(repos Repositories) *ApplicationService {
+	return &ApplicationService{courses: repos.Courses, payments: repos.Payments}
+}
+
+// GetRegistrationPaymentStatus returns the status of the student's registration payment.
+// Returns ErrPaymentNotFound when the student has no registration payment.
+func (s *ApplicationService) GetRegistrationPaymentStatus(ctx context.Context, studentID int) (string, error) {
+	ctx, cancel := db.WithTimeout(ctx)
+	defer cancel()
+	return s.payments.RegistrationStatus(ctx, studentID)
+}
+
+// AcceptApplication accepts an application, sets the course fee payment deadline and returns course details.
+// Courses whose fee reaches DUAL_APPROVAL_MIN_COURSE_FEE need two distinct approvers: the first
+// acceptance only marks the application PENDING_APPROVAL, the second (by someone else) confirms it.
+// An admin Override accepts straight away.
+func (s *ApplicationService) AcceptApplication(ctx context.Context, req AcceptApplicationRequest) (*AcceptApplicationResult, error) {
+	ctx, cancel := db.WithTimeout(ctx)
+	defer cancel()
+
+	// Get course details
+	course, err := s.courses.FindByID(ctx, req.SelectedCourseID)
+	if err != nil {
+		return nil, fmt.Errorf("course not found")
+	}
+	courseName, courseFee := course.Name, course.Fee
+
+	approvedBy := strings.TrimSpace(req.ApprovedBy)
+	dualApproval := requiresDualApproval(courseFee) && !req.Override
+	if dualApproval && approvedBy == "" {
+		return nil, ErrApproverRequired
+	}
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	// Get student details
-	var name, email string
-	err := db.DB.QueryRow("SELECT name, email FROM student_lead WHERE id = $1", req.StudentID).Scan(&name, &email)
+	name, email, err := NewLeadRepository(tx).FindContact(ctx, req.StudentID)
 	if err != nil {
 		return nil, fmt.Errorf("student not found")
 	}
 
-	// Get course details
-	var courseName string
-	var courseFee float64
-	err = db.DB.QueryRow("SELECT name, fee FROM course WHERE id = $1", req.SelectedCourseID).Scan(&courseName, &courseFee)
+	result := &AcceptApplicationResult{
+		StudentID:    req.StudentID,
+		StudentName:  name,
+		StudentEmail: email,
+		CourseName:   courseName,
+		CourseFee:    courseFee,
+		CourseID:     req.SelectedCourseID,
+	}
+
+	pending, err := findPendingApproval(ctx, tx, req.StudentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if dualApproval && pending == nil {
+		// First approval: hold the acceptance until someone else confirms it
+		if _, err := transitionApplicationStatus(ctx, tx, req.StudentID, ApplicationStatusPendingApproval); err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE student_lead SET selected_course_id = $1, course_fee_deadline = NULL WHERE id = $2",
+			req.SelectedCourseID, req.StudentID); err != nil {
+			return nil, fmt.Errorf("error updating lead status")
+		}
+		if err := createPendingApproval(ctx, tx, req.StudentID, req.SelectedCourseID, approvedBy); err != nil {
+			return nil, err
+		}
+		if err := enqueueApplicationEvent(ctx, tx, "pending_approval", req.StudentID, req.SelectedCourseID, courseName); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("error committing transaction: %w", err)
+		}
+
+		log.Printf("Acceptance of student %s (ID: %d) into %s approved by %s, awaiting a second approval",
+			name, req.StudentID, courseName, approvedBy)
+		result.PendingApproval = true
+		result.RequestedBy = approvedBy
+		return result, nil
+	}
+
+	if pending != nil {
+		if pending.CourseID != req.SelectedCourseID && !req.Override {
+			return nil, ErrApprovalCourseMismatch
+		}
+		if dualApproval && strings.EqualFold(pending.RequestedBy, approvedBy) {
+			return nil, ErrSameApprover
+		}
+	}
+
+	if _, err := transitionApplicationStatus(ctx, tx, req.StudentID, ApplicationStatusAccepted); err != nil {
+		return nil, err
+	}
+	deadline, err := setCourseFeeDeadline(ctx, tx, req.StudentID, req.SelectedCourseID)
+	if err != nil {
+		return nil, err
+	}
+	if pending != nil {
+		status := models.AcceptanceApprovalApproved
+		if req.Override {
+			status = models.AcceptanceApprovalOverridden
+		}
+		if err := resolvePendingApproval(ctx, tx, pending.ID, status, approvedBy); err != nil {
+			return nil, err
+		}
+		result.RequestedBy = pending.RequestedBy
+	}
+	if err := enqueueApplicationEvent(ctx, tx, "accepted", req.StudentID, req.SelectedCourseID, courseName); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	log.Printf("Application accepted for student: %s (ID: %d) - Course: %s, course fee due by %s",
+		name, req.StudentID, courseName, deadline.Format(time.RFC3339))
+
+	result.PaymentDeadline = deadline
+	return result, nil
+}
+
+// setCourseFeeDeadline records the accepted course and gives the student
+// CourseFeeDeadlineDays from now to pay its fee
+func setCourseFeeDeadline(ctx context.Context, tx *sql.Tx, studentID, courseID int) (time.Time, error) {
+	days := config.AppConfig.CourseFeeDeadlineDays
+	if days <= 0 {
+		days = 14
+	}
+
+	var deadline time.Time
+	err := tx.QueryRowContext(ctx, `
+		UPDATE student_lead
+		SET selected_course_id = $1, course_fee_deadline = NOW() + make_interval(days => $2), offer_expired_at = NULL
+		WHERE id = $3
+		RETURNING course_fee_deadline`, courseID, days, studentID).Scan(&deadline)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error setting course fee deadline: %w", err)
+	}
+	return deadline, nil
+}
+
+// WaitlistApplication puts an application on the waitlist of a course. Waitlisted
+// students are offered seats released by expired offers, longest-waiting first.
+func (s *ApplicationService) WaitlistApplication(ctx context.Context, req WaitlistApplicationRequest) (*WaitlistApplicationResult, error) {
+	ctx, cancel := db.WithTimeout(ctx)
+	defer cancel()
+
+	course, err := s.courses.FindByID(ctx, req.SelectedCourseID)
 	if err != nil {
 		return nil, fmt.Errorf("course not found")
 	}
+	courseName := course.Name
 
-	// Update application status
-	_, err = db.DB.Exec(
-		"UPDATE student_lead SET application_status = $1, selected_course_id = $2 WHERE id = $3",
-		"ACCEPTED", req.SelectedCourseID, req.StudentID)
+	tx, err := db.DB.BeginTx(ctx, nil)
 	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	name, email, err := NewLeadRepository(tx).FindContact(ctx, req.StudentID)
+	if err != nil {
+		return nil, fmt.Errorf("student not found")
+	}
+
+	if _, err := transitionApplicationStatus(ctx, tx, req.StudentID, ApplicationStatusWaitlisted); err != nil {
+		return nil, err
+	}
+	if err := cancelPendingApproval(ctx, tx, req.StudentID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE student_lead SET selected_course_id = $1, course_fee_deadline = NULL WHERE id = $2",
+		req.SelectedCourseID, req.StudentID); err != nil {
 		return nil, fmt.Errorf("error updating lead status")
 	}
 
-	log.Printf("Application accepted for student: %s (ID: %d) - Course: %s", name, req.StudentID, courseName)
+	var position int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM student_lead
+		WHERE application_status = $1 AND selected_course_id = $2 AND deleted_at IS NULL`,
+		ApplicationStatusWaitlisted, req.SelectedCourseID).Scan(&position)
+	if err != nil {
+		return nil, fmt.Errorf("error reading waitlist position: %w", err)
+	}
+	if err := enqueueApplicationEvent(ctx, tx, "waitlisted", req.StudentID, req.SelectedCourseID, courseName); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	log.Printf("Application waitlisted for student: %s (ID: %d) - Course: %s, position %d", name, req.StudentID, courseName, position)
 
-	return &AcceptApplicationResult{
+	return &WaitlistApplicationResult{
 		StudentName:  name,
 		StudentEmail: email,
 		CourseName:   courseName,
-		CourseFee:    courseFee,
 		CourseID:     req.SelectedCourseID,
+		Position:     position,
 	}, nil
 }
 
 // RejectApplication rejects an application
-func (s *ApplicationService) RejectApplication(req RejectApplicationRequest) (*RejectApplicationResult, error) {
+func (s *ApplicationService) RejectApplication(ctx context.Context, req RejectApplicationRequest) (*RejectApplicationResult, error) {
+	ctx, cancel := db.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	// Get student details
-	var name, email string
-	err := db.DB.QueryRow("SELECT name, email FROM student_lead WHERE id = $1", req.StudentID).Scan(&name, &email)
+	name, email, err := NewLeadRepository(tx).FindContact(ctx, req.StudentID)
 	if err != nil {
 		return nil, fmt.Errorf("student not found")
 	}
 
-	// Update application status
-	_, err = db.DB.Exec("UPDATE student_lead SET application_status = $1 WHERE id = $2", "REJECTED", req.StudentID)
-	if err != nil {
+	if _, err := transitionApplicationStatus(ctx, tx, req.StudentID, ApplicationStatusRejected); err != nil {
+		return nil, err
+	}
+	if err := cancelPendingApproval(ctx, tx, req.StudentID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE student_lead SET course_fee_deadline = NULL WHERE id = $1", req.StudentID); err != nil {
 		return nil, fmt.Errorf("error updating lead status")
 	}
+	if err := enqueueApplicationEvent(ctx, tx, "rejected", req.StudentID, 0, ""); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
 
 	log.Printf("Application rejected for student: %s (ID: %d)", name, req.StudentID)
 
@@ -100,19 +329,36 @@ func (s *ApplicationService) RejectApplication(req RejectApplicationRequest) (*R
 	}, nil
 }
 
-// PublishApplicationEvent publishes application events to Kafka
-func PublishApplicationEvent(eventType string, studentID int, email, course string, status string) {
-	go func() {
-		evt := map[string]interface{}{
-			"event":      "application." + eventType,
-			"student_id": studentID,
-			"email":      email,
-			"course":     course,
-			"status":     status,
-			"timestamp":  time.Now().UTC().Format(time.RFC3339),
-		}
-		if err := Publish("applications", fmt.Sprintf("student-%d", studentID), evt); err != nil {
-			log.Printf("Warning: failed to publish application event: %v", err)
-		}
-	}()
+// NotifyAccepted queues the acceptance email for an accepted application, or asks the
+// other approvers to confirm an acceptance still pending a second approval
+func (s *ApplicationService) NotifyAccepted(result *AcceptApplicationResult) error {
+	if result.PendingApproval {
+		return notifyPendingApprovers(result)
+	}
+	return SendAcceptanceEmail(result.StudentName, result.StudentEmail, result.CourseID, result.CourseName, result.CourseFee, result.PaymentDeadline)
+}
+
+// NotifyWaitlisted queues the waitlist email for a waitlisted application
+func (s *ApplicationService) NotifyWaitlisted(result *WaitlistApplicationResult) error {
+	return SendWaitlistEmail(result.StudentName, result.StudentEmail, result.CourseName, result.Position)
+}
+
+// NotifyRejected queues the rejection email for a rejected application
+func (s *ApplicationService) NotifyRejected(result *RejectApplicationResult) error {
+	return SendRejectionEmail(result.StudentName, result.StudentEmail)
+}
+
+// enqueueApplicationEvent writes application.<eventType> to the event outbox (it also lands in the lead timeline).
+// A rejection has no course: pass 0 and the event carries none.
+func enqueueApplicationEvent(ctx context.Context, tx *sql.Tx, eventType string, studentID, courseID int, courseName string) error {
+	evt := map[string]interface{}{
+		"event":      "application." + eventType,
+		"student_id": studentID,
+		"ts":         time.Now().UTC().Format(time.RFC3339),
+	}
+	if courseID != 0 {
+		evt["course_id"] = courseID
+		evt["course"] = courseName
+	}
+	return EnqueueEvent(ctx, tx, "applications", fmt.Sprintf("student-%d", studentID), evt)
 }
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Application status constants (student_lead.application_status)
+const (
+	ApplicationStatusNew                = "NEW"
+	ApplicationStatusMeetingScheduled   = "MEETING_SCHEDULED"
+	ApplicationStatusInterviewScheduled = "INTERVIEW_SCHEDULED"
+	ApplicationStatusWaitlisted         = "WAITLISTED"
+	ApplicationStatusPendingApproval    = "PENDING_APPROVAL" // accepted once, awaiting a second approver
+	ApplicationStatusAccepted           = "ACCEPTED"
+	ApplicationStatusRejected           = "REJECTED"
+	ApplicationStatusOfferExpired       = "OFFER_EXPIRED"
+)
+
+// applicationTransitions lists the statuses each application status may move to.
+// Decisions (accept, waitlist, reject) can be taken at any point before one is final;
+// an accepted offer either stays (course fee paid), is withdrawn, or expires unpaid.
+// Acceptances needing dual approval pass through PENDING_APPROVAL wherever ACCEPTED is allowed.
+var applicationTransitions = map[string][]string{
+	ApplicationStatusNew:                {ApplicationStatusMeetingScheduled, ApplicationStatusInterviewScheduled, ApplicationStatusWaitlisted, ApplicationStatusPendingApproval, ApplicationStatusAccepted, ApplicationStatusRejected},
+	ApplicationStatusMeetingScheduled:   {ApplicationStatusInterviewScheduled, ApplicationStatusWaitlisted, ApplicationStatusPendingApproval, ApplicationStatusAccepted, ApplicationStatusRejected},
+	ApplicationStatusInterviewScheduled: {ApplicationStatusMeetingScheduled, ApplicationStatusWaitlisted, ApplicationStatusPendingApproval, ApplicationStatusAccepted, ApplicationStatusRejected},
+	ApplicationStatusWaitlisted:         {ApplicationStatusPendingApproval, ApplicationStatusAccepted, ApplicationStatusRejected},
+	ApplicationStatusPendingApproval:    {ApplicationStatusAccepted, ApplicationStatusWaitlisted, ApplicationStatusRejected},
+	ApplicationStatusAccepted:           {ApplicationStatusPendingApproval, ApplicationStatusAccepted, ApplicationStatusRejected, ApplicationStatusOfferExpired},
+	ApplicationStatusOfferExpired:       {ApplicationStatusPendingApproval, ApplicationStatusAccepted, ApplicationStatusWaitlisted, ApplicationStatusRejected},
+	ApplicationStatusRejected:           {},
+}
+
+// ErrInvalidStatusTransition is returned when an application cannot move to the requested status
+var ErrInvalidStatusTransition = errors.New("invalid application status transition")
+
+// CanTransitionApplication reports whether an application in status from may move to status to
+func CanTransitionApplication(from, to string) bool {
+	for _, allowed := range applicationTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionApplicationStatus locks the lead, checks the move against applicationTransitions
+// and updates application_status within tx. Returns the previous status.
+func transitionApplicationStatus(ctx context.Context, tx *sql.Tx, leadID int, to string) (string, error) {
+	var from string
+	err := tx.QueryRowContext(ctx,
+		"SELECT COALESCE(application_status, 'NEW') FROM student_lead WHERE id = $1 AND deleted_at IS NULL FOR UPDATE",
+		leadID).Scan(&from)
+	if err == sql.ErrNoRows {
+		return "", ErrLeadNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("error locking lead: %w", err)
+	}
+
+	if !CanTransitionApplication(from, to) {
+		return from, fmt.Errorf("%w: %s -> %s", ErrInvalidStatusTransition, from, to)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE student_lead SET application_status = $1, updated_at = NOW() WHERE id = $2", to, leadID); err != nil {
+		return from, fmt.Errorf("error updating lead status: %w", err)
+	}
+	return from, nil
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Audited entity types
+const (
+	AuditEntityLead       = "lead"
+	AuditEntityCourse     = "course"
+	AuditEntityDLQMessage = "dlq_message"
+	AuditEntityPayment    = "payment"
+)
+
+// Audited actions
+const (
+	AuditActionUpdate         = "update"
+	AuditActionMerge          = "merge"
+	AuditActionReviewApproved = "review_approved"
+	AuditActionReviewMerged   = "review_merged"
+	AuditActionStatusChange   = "status_change"
+	AuditActionResolve        = "resolve"
+	AuditActionDataExport     = "data_export"
+	AuditActionDataErasure    = "data_erasure"
+	AuditActionRefund         = "refund"
+)
+
+// AuditEntityTypes lists the entity types accepted by the audit log filters
+var AuditEntityTypes = []string{AuditEntityLead, AuditEntityCourse, AuditEntityDLQMessage, AuditEntityPayment}
+
+// ErrInvalidAuditEntity is returned when listing the audit log of an unknown entity type
+var ErrInvalidAuditEntity = errors.New("unknown audit entity type")
+
+// RecordAudit writes entry to the audit log, with the request ID carried by ctx
+func RecordAudit(ctx context.Context, entry *models.AuditLogEntry) error {
+	if db.DB == nil {
+		return fmt.Errorf("database is not initialized")
+	}
+	return recordAudit(ctx, db.DB, entry)
+}
+
+// recordAudit writes entry to the audit log over q, e.g. in the transaction of the change
+func recordAudit(ctx context.Context, q querier, entry *models.AuditLogEntry) error {
+	var changes []byte
+	if len(entry.Changes) > 0 {
+		var err error
+		if changes, err = json.Marshal(entry.Changes); err != nil {
+			return fmt.Errorf("error encoding audit changes: %w", err)
+		}
+	}
+	entry.RequestID = logger.RequestIDFromContext(ctx)
+
+	err := q.QueryRowContext(ctx, `
+		INSERT INTO audit_log (entity_type, entity_id, action, actor, changes, request_id)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))
+		RETURNING id, created_at`,
+		entry.EntityType, entry.EntityID, entry.Action, entry.Actor, changes, entry.RequestID,
+	).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error writing audit log: %w", err)
+	}
+	return nil
+}
+
+// AuditLogFilter narrows ListAuditLog; zero values do not filter
+type AuditLogFilter struct {
+	EntityType    string
+	EntityID      string
+	Actor         string
+	Action        string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Limit         int
+}
+
+// ListAuditLog returns the audit log entries matching filter, newest first
+func ListAuditLog(ctx context.Context, filter AuditLogFilter) ([]models.AuditLogEntry, error) {
+	if filter.EntityType != "" && !isAuditEntityType(filter.EntityType) {
+		return nil, ErrInvalidAuditEntity
+	}
+
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT id, entity_type, entity_id, action, actor, changes, COALESCE(request_id, ''), created_at
+		FROM audit_log
+		WHERE ($1 = '' OR entity_type = $1)
+			AND ($2 = '' OR entity_id = $2)
+			AND ($3 = '' OR actor = $3)
+			AND ($4 = '' OR action = $4)
+			AND ($5::timestamp IS NULL OR created_at >= $5)
+			AND ($6::timestamp IS NULL OR created_at <= $6)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $7`,
+		filter.EntityType, filter.EntityID, filter.Actor, filter.Action, filter.CreatedAfter, filter.CreatedBefore, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.AuditLogEntry{}
+	for rows.Next() {
+		var e models.AuditLogEntry
+		var changes []byte
+		if err := rows.Scan(&e.ID, &e.EntityType, &e.EntityID, &e.Action, &e.Actor, &changes,
+			&e.RequestID, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error reading audit log: %w", err)
+		}
+		if len(changes) > 0 {
+			if err := json.Unmarshal(changes, &e.Changes); err != nil {
+				return nil, fmt.Errorf("error decoding changes of audit entry %d: %w", e.ID, err)
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func isAuditEntityType(entityType string) bool {
+	for _, t := range AuditEntityTypes {
+		if t == entityType {
+			return true
+		}
+	}
+	return false
+}
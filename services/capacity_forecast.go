@@ -0,0 +1,121 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/models"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// referralLeadSource is routed only to referral-enabled counselors (see utils.GetAvailableCounselorID)
+const referralLeadSource = "referral"
+
+// GetCapacityForecast forecasts next week's lead inflow per source from the average inflow
+// per weekday over the last historyWeeks full weeks, and compares it with the free capacity
+// of routable counselors. The forecast week starts tomorrow.
+func GetCapacityForecast(ctx context.Context, historyWeeks int, now time.Time) (*models.CapacityForecast, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	historyFrom := today.AddDate(0, 0, -7*historyWeeks)
+	forecastFrom := today.AddDate(0, 0, 1)
+
+	rows, err := db.Reader().QueryContext(ctx, `
+		SELECT COALESCE(NULLIF(lead_source, ''), 'unknown'), EXTRACT(DOW FROM created_at)::int, COUNT(*)
+		FROM student_lead
+		WHERE merged_into_id IS NULL AND created_at >= $1 AND created_at < $2
+		GROUP BY 1, 2`, historyFrom, today)
+	if err != nil {
+		return nil, fmt.Errorf("error reading lead inflow: %w", err)
+	}
+
+	// inflow[source][weekday] = leads over the whole history
+	inflow := map[string]*[7]int{}
+	for rows.Next() {
+		var source string
+		var weekday, count int
+		if err := rows.Scan(&source, &weekday, &count); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error reading lead inflow: %w", err)
+		}
+		if inflow[source] == nil {
+			inflow[source] = &[7]int{}
+		}
+		inflow[source][weekday] += count
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading lead inflow: %w", err)
+	}
+
+	forecast := &models.CapacityForecast{
+		HistoryWeeks: historyWeeks,
+		ForecastFrom: forecastFrom.Format(ReportDateLayout),
+		ForecastTo:   forecastFrom.AddDate(0, 0, 6).Format(ReportDateLayout),
+		Sources:      []models.SourceForecast{},
+		Days:         make([]models.DailyForecast, 7),
+	}
+	for i := range forecast.Days {
+		forecast.Days[i].Date = forecastFrom.AddDate(0, 0, i).Format(ReportDateLayout)
+	}
+
+	for source, byWeekday := range inflow {
+		sf := models.SourceForecast{LeadSource: source, RoutedToReferrals: source == referralLeadSource}
+		for i := range forecast.Days {
+			expected := float64(byWeekday[forecastFrom.AddDate(0, 0, i).Weekday()]) / float64(historyWeeks)
+			forecast.Days[i].Leads += expected
+			sf.ForecastNextWeek += expected
+		}
+		for _, count := range byWeekday {
+			sf.HistoricalLeads += count
+		}
+		sf.AvgDailyLeads = roundForecast(float64(sf.HistoricalLeads) / float64(7*historyWeeks))
+		sf.ForecastNextWeek = roundForecast(sf.ForecastNextWeek)
+
+		forecast.ForecastLeads += sf.ForecastNextWeek
+		if sf.RoutedToReferrals {
+			forecast.ReferralForecastLeads += sf.ForecastNextWeek
+		}
+		forecast.Sources = append(forecast.Sources, sf)
+	}
+	sort.Slice(forecast.Sources, func(i, j int) bool {
+		if forecast.Sources[i].ForecastNextWeek != forecast.Sources[j].ForecastNextWeek {
+			return forecast.Sources[i].ForecastNextWeek > forecast.Sources[j].ForecastNextWeek
+		}
+		return forecast.Sources[i].LeadSource < forecast.Sources[j].LeadSource
+	})
+	for i := range forecast.Days {
+		forecast.Days[i].Leads = roundForecast(forecast.Days[i].Leads)
+	}
+	forecast.ForecastLeads = roundForecast(forecast.ForecastLeads)
+
+	err = db.Reader().QueryRowContext(ctx, `
+		SELECT
+			COUNT(*),
+			COALESCE(SUM(max_capacity), 0),
+			COALESCE(SUM(assigned_count), 0),
+			COALESCE(SUM(GREATEST(max_capacity - assigned_count, 0)), 0),
+			COALESCE(SUM(GREATEST(max_capacity - assigned_count, 0)) FILTER (WHERE is_referral_enabled), 0)
+		FROM counselor
+		WHERE COALESCE(is_house_account, false) = false`,
+	).Scan(&forecast.Counselors, &forecast.TotalCapacity, &forecast.AssignedLeads,
+		&forecast.FreeCapacity, &forecast.ReferralFreeCapacity)
+	if err != nil {
+		return nil, fmt.Errorf("error reading counselor capacity: %w", err)
+	}
+
+	forecast.Shortfall = roundForecast(math.Max(forecast.ForecastLeads-float64(forecast.FreeCapacity), 0))
+	forecast.ReferralShortfall = roundForecast(math.Max(forecast.ReferralForecastLeads-float64(forecast.ReferralFreeCapacity), 0))
+	if forecast.FreeCapacity > 0 {
+		pressure := roundForecast(forecast.ForecastLeads / float64(forecast.FreeCapacity))
+		forecast.Pressure = &pressure
+	}
+
+	return forecast, nil
+}
+
+// roundForecast rounds to two decimals for display
+func roundForecast(v float64) float64 {
+	return math.Round(v*100) / 100
+}
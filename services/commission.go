@@ -0,0 +1,379 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CommissionMonthLayout is the month format of commission reports
+const CommissionMonthLayout = "2006-01"
+
+var (
+	// ErrInvalidCommissionRule is returned for a rule with no scope, an unknown type or an out of range value
+	ErrInvalidCommissionRule = errors.New("invalid commission rule")
+	// ErrCommissionRuleNotFound is returned when no active rule has the requested id
+	ErrCommissionRuleNotFound = errors.New("commission rule not found")
+	// ErrCommissionNotAccrued is returned when a refund names a course payment that earned no commission
+	ErrCommissionNotAccrued = errors.New("no commission was accrued for this course payment")
+	// ErrCommissionFullyReversed is returned when earlier refunds already reversed the whole commission
+	ErrCommissionFullyReversed = errors.New("the commission for this course payment is already fully reversed")
+)
+
+// ListCommissionRules returns the commission rules, active ones only unless all is set
+func ListCommissionRules(ctx context.Context, all bool) ([]models.CommissionRule, error) {
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT r.id, r.counselor_id, COALESCE(c.name, ''), r.course_id, COALESCE(co.name, ''),
+			r.rule_type, r.value, r.active, COALESCE(r.created_by, ''), r.created_at, r.updated_at
+		FROM commission_rule r
+		LEFT JOIN counselor c ON c.id = r.counselor_id
+		LEFT JOIN course co ON co.id = r.course_id
+		WHERE r.active OR $1
+		ORDER BY r.active DESC, c.name NULLS LAST, co.name NULLS LAST, r.id DESC`, all)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching commission rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []models.CommissionRule{}
+	for rows.Next() {
+		var r models.CommissionRule
+		var counselorID, courseID sql.NullInt64
+		if err := rows.Scan(&r.ID, &counselorID, &r.CounselorName, &courseID, &r.CourseName,
+			&r.RuleType, &r.Value, &r.Active, &r.CreatedBy, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error reading commission rule: %w", err)
+		}
+		r.CounselorID = nullableInt(counselorID)
+		r.CourseID = nullableInt(courseID)
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// SetCommissionRule stores the rule for a counselor, a course or a counselor on a course,
+// replacing the active rule of the same scope. Commissions already accrued keep the rule
+// they were computed with.
+func SetCommissionRule(ctx context.Context, rule *models.CommissionRule) error {
+	rule.RuleType = strings.ToUpper(strings.TrimSpace(rule.RuleType))
+	switch {
+	case rule.CounselorID == nil && rule.CourseID == nil:
+		return fmt.Errorf("%w: counselor_id or course_id is required", ErrInvalidCommissionRule)
+	case rule.RuleType != models.CommissionRuleFlat && rule.RuleType != models.CommissionRulePercentage:
+		return fmt.Errorf("%w: rule_type must be FLAT or PERCENTAGE", ErrInvalidCommissionRule)
+	case rule.Value < 0:
+		return fmt.Errorf("%w: value must not be negative", ErrInvalidCommissionRule)
+	case rule.RuleType == models.CommissionRulePercentage && rule.Value > 100:
+		return fmt.Errorf("%w: a percentage must not exceed 100", ErrInvalidCommissionRule)
+	}
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE commission_rule SET active = FALSE, updated_at = NOW()
+		WHERE active AND counselor_id IS NOT DISTINCT FROM $1 AND course_id IS NOT DISTINCT FROM $2`,
+		rule.CounselorID, rule.CourseID); err != nil {
+		return fmt.Errorf("error replacing commission rule: %w", err)
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO commission_rule (counselor_id, course_id, rule_type, value, created_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		RETURNING id, active, created_at, updated_at`,
+		rule.CounselorID, rule.CourseID, rule.RuleType, rule.Value, rule.CreatedBy,
+	).Scan(&rule.ID, &rule.Active, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error storing commission rule: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// DeactivateCommissionRule stops an active rule from applying to future enrollments
+func DeactivateCommissionRule(ctx context.Context, ruleID int) error {
+	res, err := db.DB.ExecContext(ctx,
+		"UPDATE commission_rule SET active = FALSE, updated_at = NOW() WHERE id = $1 AND active", ruleID)
+	if err != nil {
+		return fmt.Errorf("error deactivating commission rule: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrCommissionRuleNotFound
+	}
+	return nil
+}
+
+// accrueCommission records the commission of the lead's counselor for a captured course
+// fee, within the capture transaction. A counselor-and-course rule beats a counselor rule,
+// which beats a course rule. Nothing is recorded without a counselor or a matching rule,
+// and a course payment accrues once.
+func accrueCommission(ctx context.Context, tx *sql.Tx, payment *models.Payment) error {
+	var counselorID sql.NullInt64
+	err := tx.QueryRowContext(ctx, "SELECT counselor_id FROM student_lead WHERE id = $1", payment.StudentID).Scan(&counselorID)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("error reading counselor for commission: %w", err)
+	}
+	if !counselorID.Valid {
+		return nil
+	}
+
+	var ruleID int
+	var ruleType string
+	var value float64
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, rule_type, value FROM commission_rule
+		WHERE active
+			AND (counselor_id = $1 OR counselor_id IS NULL)
+			AND (course_id = $2 OR course_id IS NULL)
+		ORDER BY (counselor_id IS NOT NULL AND course_id IS NOT NULL) DESC, (counselor_id IS NOT NULL) DESC
+		LIMIT 1`, counselorID.Int64, payment.RelatedCourseID).Scan(&ruleID, &ruleType, &value)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error finding commission rule: %w", err)
+	}
+
+	amount := value
+	if ruleType == models.CommissionRulePercentage {
+		amount = roundAmount(payment.Amount * value / 100)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO commission_entry (counselor_id, student_id, course_id, course_payment_id, rule_id, entry_type, base_amount, amount)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (course_payment_id) WHERE entry_type = 'ACCRUAL' DO NOTHING`,
+		counselorID.Int64, payment.StudentID, payment.RelatedCourseID, payment.ID, ruleID,
+		models.CommissionEntryAccrual, payment.Amount, amount); err != nil {
+		return fmt.Errorf("error accruing commission: %w", err)
+	}
+
+	logger.FromContext(ctx).Info("Accrued commission of %.2f for counselor %d on course payment %d", amount, counselorID.Int64, payment.ID)
+	return nil
+}
+
+// RecordCommissionRefund reverses the commission of a course payment in proportion to the
+// refunded share of its fee. Reversals never exceed the accrued commission.
+func RecordCommissionRefund(ctx context.Context, coursePaymentID int, refundAmount float64, reason, createdBy string) (*models.CommissionEntry, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	entry, err := reverseCommission(ctx, tx, coursePaymentID, refundAmount, reason, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return entry, nil
+}
+
+// reverseCommission records the REFUND entry of RecordCommissionRefund within tx
+func reverseCommission(ctx context.Context, tx *sql.Tx, coursePaymentID int, refundAmount float64, reason, createdBy string) (*models.CommissionEntry, error) {
+	var accrual models.CommissionEntry
+	var studentID, courseID sql.NullInt64
+	var baseAmount float64
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, counselor_id, student_id, course_id, COALESCE(base_amount, 0), amount
+		FROM commission_entry
+		WHERE course_payment_id = $1 AND entry_type = $2
+		FOR UPDATE`, coursePaymentID, models.CommissionEntryAccrual,
+	).Scan(&accrual.ID, &accrual.CounselorID, &studentID, &courseID, &baseAmount, &accrual.Amount)
+	if err == sql.ErrNoRows {
+		return nil, ErrCommissionNotAccrued
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading accrued commission: %w", err)
+	}
+
+	var reversed float64
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(-SUM(amount), 0) FROM commission_entry
+		WHERE course_payment_id = $1 AND entry_type = $2`, coursePaymentID, models.CommissionEntryRefund,
+	).Scan(&reversed); err != nil {
+		return nil, fmt.Errorf("error reading commission refunds: %w", err)
+	}
+	remaining := roundAmount(accrual.Amount - reversed)
+	if remaining <= 0 {
+		return nil, ErrCommissionFullyReversed
+	}
+
+	reversal := accrual.Amount
+	if baseAmount > 0 && refundAmount < baseAmount {
+		reversal = roundAmount(accrual.Amount * refundAmount / baseAmount)
+	}
+	reversal = min(reversal, remaining)
+
+	entry := &models.CommissionEntry{
+		CounselorID:     accrual.CounselorID,
+		StudentID:       nullableInt(studentID),
+		CourseID:        nullableInt(courseID),
+		CoursePaymentID: &coursePaymentID,
+		EntryType:       models.CommissionEntryRefund,
+		BaseAmount:      &refundAmount,
+		Amount:          -reversal,
+		Reason:          strings.TrimSpace(reason),
+		CreatedBy:       strings.TrimSpace(createdBy),
+	}
+	if err := insertCommissionEntry(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// RecordCommissionAdjustment adds a manual correction to a counselor's commissions
+func RecordCommissionAdjustment(ctx context.Context, entry *models.CommissionEntry) error {
+	entry.EntryType = models.CommissionEntryAdjustment
+	entry.Reason = strings.TrimSpace(entry.Reason)
+	entry.CreatedBy = strings.TrimSpace(entry.CreatedBy)
+	return insertCommissionEntry(ctx, db.DB, entry)
+}
+
+func insertCommissionEntry(ctx context.Context, q querier, entry *models.CommissionEntry) error {
+	err := q.QueryRowContext(ctx, `
+		INSERT INTO commission_entry (counselor_id, student_id, course_id, course_payment_id, entry_type, base_amount, amount, reason, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''))
+		RETURNING id, created_at`,
+		entry.CounselorID, entry.StudentID, entry.CourseID, entry.CoursePaymentID, entry.EntryType,
+		entry.BaseAmount, entry.Amount, entry.Reason, entry.CreatedBy,
+	).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error recording commission %s: %w", strings.ToLower(entry.EntryType), err)
+	}
+	return nil
+}
+
+// GetCommissionReport sums the commission ledger per counselor for a month. Entries count
+// in the month they were recorded, so a refund or adjustment after payroll ran lands in
+// the next month instead of changing a paid one. With counselorID the report is limited
+// to that counselor and lists the ledger lines.
+func GetCommissionReport(ctx context.Context, month time.Time, counselorID int) (*models.CommissionReport, error) {
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT c.id, c.name,
+			COUNT(*) FILTER (WHERE e.entry_type = 'ACCRUAL'),
+			COALESCE(SUM(e.amount) FILTER (WHERE e.entry_type = 'ACCRUAL'), 0),
+			COALESCE(SUM(e.amount) FILTER (WHERE e.entry_type = 'REFUND'), 0),
+			COALESCE(SUM(e.amount) FILTER (WHERE e.entry_type = 'ADJUSTMENT'), 0),
+			COALESCE(SUM(e.amount), 0)
+		FROM commission_entry e
+		JOIN counselor c ON c.id = e.counselor_id
+		WHERE e.created_at >= $1 AND e.created_at < $2 AND ($3 = 0 OR e.counselor_id = $3)
+		GROUP BY c.id, c.name
+		ORDER BY c.name, c.id`, from, to, counselorID)
+	if err != nil {
+		return nil, fmt.Errorf("error building commission report: %w", err)
+	}
+	defer rows.Close()
+
+	report := &models.CommissionReport{Month: from.Format(CommissionMonthLayout), Rows: []models.CommissionReportRow{}}
+	for rows.Next() {
+		var row models.CommissionReportRow
+		if err := rows.Scan(&row.CounselorID, &row.CounselorName, &row.Enrollments,
+			&row.Accrued, &row.Refunds, &row.Adjustments, &row.Net); err != nil {
+			return nil, fmt.Errorf("error reading commission report: %w", err)
+		}
+		report.Rows = append(report.Rows, row)
+		report.Totals.Enrollments += row.Enrollments
+		report.Totals.Accrued += row.Accrued
+		report.Totals.Refunds += row.Refunds
+		report.Totals.Adjustments += row.Adjustments
+		report.Totals.Net += row.Net
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if counselorID > 0 {
+		if report.Entries, err = listCommissionEntries(ctx, counselorID, from, to); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// listCommissionEntries returns a counselor's ledger lines recorded in [from, to)
+func listCommissionEntries(ctx context.Context, counselorID int, from, to time.Time) ([]models.CommissionEntry, error) {
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT id, counselor_id, student_id, course_id, course_payment_id, rule_id, entry_type,
+			base_amount, amount, COALESCE(reason, ''), COALESCE(created_by, ''), created_at
+		FROM commission_entry
+		WHERE counselor_id = $1 AND created_at >= $2 AND created_at < $3
+		ORDER BY created_at, id`, counselorID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching commission entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.CommissionEntry{}
+	for rows.Next() {
+		var e models.CommissionEntry
+		var studentID, courseID, coursePaymentID, ruleID sql.NullInt64
+		var baseAmount sql.NullFloat64
+		if err := rows.Scan(&e.ID, &e.CounselorID, &studentID, &courseID, &coursePaymentID, &ruleID, &e.EntryType,
+			&baseAmount, &e.Amount, &e.Reason, &e.CreatedBy, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error reading commission entry: %w", err)
+		}
+		e.StudentID = nullableInt(studentID)
+		e.CourseID = nullableInt(courseID)
+		e.CoursePaymentID = nullableInt(coursePaymentID)
+		e.RuleID = nullableInt(ruleID)
+		if baseAmount.Valid {
+			e.BaseAmount = &baseAmount.Float64
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// WriteCommissionReportCSV writes the report rows and totals as CSV for payroll
+func WriteCommissionReportCSV(w io.Writer, report *models.CommissionReport) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"month", "counselor_id", "counselor_name", "enrollments", "accrued", "refunds", "adjustments", "net"}); err != nil {
+		return err
+	}
+
+	record := func(id, name string, row models.CommissionReportRow) []string {
+		return []string{
+			report.Month, id, csvCell(name), strconv.Itoa(row.Enrollments),
+			strconv.FormatFloat(row.Accrued, 'f', 2, 64),
+			strconv.FormatFloat(row.Refunds, 'f', 2, 64),
+			strconv.FormatFloat(row.Adjustments, 'f', 2, 64),
+			strconv.FormatFloat(row.Net, 'f', 2, 64),
+		}
+	}
+	for _, row := range report.Rows {
+		if err := cw.Write(record(strconv.Itoa(row.CounselorID), row.CounselorName, row)); err != nil {
+			return err
+		}
+	}
+	if err := cw.Write(record("", "TOTAL", report.Totals)); err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func nullableInt(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	n := int(v.Int64)
+	return &n
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// GetCounselorMetrics computes assignment, conversion, payment and response-time
+// metrics for a counselor. Leads are limited to those created within [from, to] when set.
+func GetCounselorMetrics(ctx context.Context, counselorID int64, from, to *time.Time) (*models.CounselorMetrics, error) {
+	metrics := &models.CounselorMetrics{CounselorID: counselorID}
+
+	err := db.Reader().QueryRowContext(ctx, "SELECT name FROM counselor WHERE id = $1", counselorID).Scan(&metrics.CounselorName)
+	if err == sql.ErrNoRows {
+		return nil, ErrCounselorNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching counselor: %w", err)
+	}
+
+	var avgResponseHours sql.NullFloat64
+	err = db.Reader().QueryRowContext(ctx, `
+		WITH leads AS (
+			SELECT id, created_at, course_fee_status
+			FROM student_lead
+			WHERE counselor_id = $1 AND deleted_at IS NULL
+				AND ($2::date IS NULL OR created_at >= $2::date)
+				AND ($3::date IS NULL OR created_at < $3::date + 1)
+		), first_notes AS (
+			SELECT n.lead_id, MIN(n.created_at) AS first_note_at
+			FROM lead_note n
+			JOIN leads l ON l.id = n.lead_id
+			WHERE n.counselor_id = $1
+			GROUP BY n.lead_id
+		)
+		SELECT
+			(SELECT COUNT(*) FROM leads),
+			(SELECT COUNT(*) FROM leads WHERE course_fee_status = $4),
+			(SELECT COUNT(*) FROM registration_payment rp JOIN leads l ON l.id = rp.student_id WHERE rp.status = $4),
+			(SELECT COALESCE(SUM(rp.amount), 0) FROM registration_payment rp JOIN leads l ON l.id = rp.student_id WHERE rp.status = $4),
+			(SELECT AVG(EXTRACT(EPOCH FROM (f.first_note_at - l.created_at)) / 3600)
+				FROM first_notes f JOIN leads l ON l.id = f.lead_id),
+			(SELECT COUNT(*) FROM leads l WHERE NOT EXISTS (SELECT 1 FROM first_notes f WHERE f.lead_id = l.id))`,
+		counselorID, nullableTime(from), nullableTime(to), PaymentStatusPaid,
+	).Scan(&metrics.LeadsAssigned, &metrics.Enrollments, &metrics.RegistrationPayments,
+		&metrics.RegistrationAmountCollected, &avgResponseHours, &metrics.LeadsWithoutNotes)
+	if err != nil {
+		return nil, fmt.Errorf("error computing counselor metrics: %w", err)
+	}
+
+	if metrics.LeadsAssigned > 0 {
+		metrics.ConversionRate = float64(metrics.Enrollments) / float64(metrics.LeadsAssigned)
+	}
+	if avgResponseHours.Valid {
+		metrics.AvgResponseHours = &avgResponseHours.Float64
+	}
+
+	return metrics, nil
+}
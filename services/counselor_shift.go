@@ -0,0 +1,203 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrInvalidCounselorShift is returned for an unknown shift, a weekday outside 1-7 or a
+// schedule for the house account
+var ErrInvalidCounselorShift = errors.New("invalid counselor shift")
+
+// counselorShifts are the shifts in the order their windows are checked
+var counselorShifts = []string{models.CounselorShiftMorning, models.CounselorShiftEvening}
+
+// CounselorShiftRequest schedules a counselor for a shift on ISO weekdays (1 = Monday);
+// no weekdays removes the counselor from the shift
+type CounselorShiftRequest struct {
+	Shift    string  `json:"shift"`
+	Weekdays []int64 `json:"weekdays"`
+}
+
+// counselorShiftWindow returns the configured hours of a shift ("09:00-14:00" or "off")
+func counselorShiftWindow(shift string) string {
+	if shift == models.CounselorShiftEvening {
+		return config.AppConfig.CounselorEveningShift
+	}
+	return config.AppConfig.CounselorMorningShift
+}
+
+// parseShiftWindow turns "09:00-14:00" into minutes since midnight. A window ending at
+// or before its start runs past midnight ("18:00-02:00").
+func parseShiftWindow(value string) (start, end int, ok bool) {
+	from, to, found := strings.Cut(strings.TrimSpace(value), "-")
+	if !found {
+		return 0, 0, false
+	}
+	startAt, err := time.Parse("15:04", strings.TrimSpace(from))
+	if err != nil {
+		return 0, 0, false
+	}
+	endAt, err := time.Parse("15:04", strings.TrimSpace(to))
+	if err != nil {
+		return 0, 0, false
+	}
+	start, end = startAt.Hour()*60+startAt.Minute(), endAt.Hour()*60+endAt.Minute()
+	return start, end, start != end
+}
+
+// isoWeekday numbers the days of the week from 1 (Monday) to 7 (Sunday)
+func isoWeekday(at time.Time) int64 {
+	if at.Weekday() == time.Sunday {
+		return 7
+	}
+	return int64(at.Weekday())
+}
+
+// currentCounselorShift returns the shift running at the given time and the weekday it
+// started on (the previous day after midnight in a window running past it). shift is
+// empty outside every shift window.
+func currentCounselorShift(at time.Time) (shift string, weekday int64) {
+	minute := at.Hour()*60 + at.Minute()
+	for _, name := range counselorShifts {
+		window := counselorShiftWindow(name)
+		if strings.EqualFold(window, "off") {
+			continue
+		}
+		start, end, ok := parseShiftWindow(window)
+		if !ok {
+			logger.Warn("Ignoring invalid %s counselor shift window %q", strings.ToLower(name), window)
+			continue
+		}
+		switch {
+		case start < end && minute >= start && minute < end:
+			return name, isoWeekday(at)
+		case start > end && minute >= start:
+			return name, isoWeekday(at)
+		case start > end && minute < end:
+			return name, isoWeekday(at.AddDate(0, 0, -1))
+		}
+	}
+	return "", 0
+}
+
+// GetCounselorShifts returns a counselor's shift schedule and whether they are on shift at now
+func GetCounselorShifts(ctx context.Context, counselorID int64, now time.Time) (*models.CounselorShiftSchedule, error) {
+	schedule := &models.CounselorShiftSchedule{CounselorID: counselorID, Shifts: []models.CounselorShift{}}
+	err := db.DB.QueryRowContext(ctx, "SELECT name FROM counselor WHERE id = $1", counselorID).Scan(&schedule.CounselorName)
+	if err == sql.ErrNoRows {
+		return nil, ErrCounselorNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching counselor: %w", err)
+	}
+
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT shift, weekdays, updated_at FROM counselor_shift
+		WHERE counselor_id = $1
+		ORDER BY CASE shift WHEN $2 THEN 0 ELSE 1 END`, counselorID, models.CounselorShiftMorning)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching counselor shifts: %w", err)
+	}
+	defer rows.Close()
+
+	current, weekday := currentCounselorShift(now)
+	for rows.Next() {
+		var shift models.CounselorShift
+		if err := rows.Scan(&shift.Shift, pq.Array(&shift.Weekdays), &shift.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning counselor shift: %w", err)
+		}
+		shift.Window = counselorShiftWindow(shift.Shift)
+		if shift.Shift == current {
+			for _, day := range shift.Weekdays {
+				schedule.OnShiftNow = schedule.OnShiftNow || day == weekday
+			}
+		}
+		schedule.Shifts = append(schedule.Shifts, shift)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// SetCounselorShifts replaces a counselor's shift schedule; shifts left out of the
+// request are removed
+func SetCounselorShifts(ctx context.Context, counselorID int64, shifts []CounselorShiftRequest, now time.Time) (*models.CounselorShiftSchedule, error) {
+	weekdaysByShift := map[string][]int64{}
+	for _, req := range shifts {
+		shift := strings.ToUpper(strings.TrimSpace(req.Shift))
+		if shift != models.CounselorShiftMorning && shift != models.CounselorShiftEvening {
+			return nil, fmt.Errorf("%w: shift must be MORNING or EVENING", ErrInvalidCounselorShift)
+		}
+		if _, seen := weekdaysByShift[shift]; seen {
+			return nil, fmt.Errorf("%w: %s is listed twice", ErrInvalidCounselorShift, shift)
+		}
+		days := map[int64]bool{}
+		for _, day := range req.Weekdays {
+			if day < 1 || day > 7 {
+				return nil, fmt.Errorf("%w: weekdays run from 1 (Monday) to 7 (Sunday)", ErrInvalidCounselorShift)
+			}
+			days[day] = true
+		}
+		weekdays := make([]int64, 0, len(days))
+		for day := range days {
+			weekdays = append(weekdays, day)
+		}
+		sort.Slice(weekdays, func(i, j int) bool { return weekdays[i] < weekdays[j] })
+		weekdaysByShift[shift] = weekdays
+	}
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var isHouseAccount bool
+	err = tx.QueryRowContext(ctx, "SELECT is_house_account FROM counselor WHERE id = $1 FOR UPDATE", counselorID).Scan(&isHouseAccount)
+	if err == sql.ErrNoRows {
+		return nil, ErrCounselorNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching counselor: %w", err)
+	}
+	if isHouseAccount {
+		return nil, fmt.Errorf("%w: the house account is never routed leads and has no shifts", ErrInvalidCounselorShift)
+	}
+
+	for _, shift := range counselorShifts {
+		weekdays := weekdaysByShift[shift]
+		if len(weekdays) == 0 {
+			if _, err := tx.ExecContext(ctx,
+				"DELETE FROM counselor_shift WHERE counselor_id = $1 AND shift = $2", counselorID, shift); err != nil {
+				return nil, fmt.Errorf("error removing %s shift: %w", strings.ToLower(shift), err)
+			}
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO counselor_shift (counselor_id, shift, weekdays)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (counselor_id, shift)
+			DO UPDATE SET weekdays = EXCLUDED.weekdays, updated_at = NOW()`,
+			counselorID, shift, pq.Array(weekdays)); err != nil {
+			return nil, fmt.Errorf("error saving %s shift: %w", strings.ToLower(shift), err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return GetCounselorShifts(ctx, counselorID, now)
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrCouponNotFound is returned when no coupon has the requested id
+	ErrCouponNotFound = errors.New("coupon not found")
+	// ErrInvalidCoupon is returned for a coupon with a missing code or an impossible discount,
+	// scope, window or limit
+	ErrInvalidCoupon = errors.New("invalid coupon")
+	// ErrCouponNotApplicable is returned when a student's coupon code is unknown, inactive,
+	// outside its validity window, meant for another fee or used up
+	ErrCouponNotApplicable = errors.New("coupon code cannot be applied")
+)
+
+// minimumOrderAmount is the smallest amount Razorpay accepts for an order, in rupees
+const minimumOrderAmount = 1.0
+
+// CouponRequest is the editable part of a coupon
+type CouponRequest struct {
+	Code                     string     `json:"code"`
+	Description              string     `json:"description"`
+	DiscountType             string     `json:"discount_type"`
+	DiscountValue            float64    `json:"discount_value"`
+	PaymentType              string     `json:"payment_type"` // optional, REGISTRATION or COURSE_FEE
+	CourseID                 *int       `json:"course_id"`    // optional, a course fee only
+	ValidFrom                *time.Time `json:"valid_from"`
+	ValidUntil               *time.Time `json:"valid_until"`
+	MaxRedemptions           *int       `json:"max_redemptions"`             // unlimited when omitted
+	MaxRedemptionsPerStudent int        `json:"max_redemptions_per_student"` // defaults to 1
+	IsActive                 *bool      `json:"is_active"`                   // defaults to true
+}
+
+// normalizeCouponCode makes codes case-insensitive: they are stored and matched upper case
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (req *CouponRequest) normalize() error {
+	req.Code = normalizeCouponCode(req.Code)
+	req.Description = strings.TrimSpace(req.Description)
+	req.DiscountType = strings.ToLower(strings.TrimSpace(req.DiscountType))
+	req.PaymentType = strings.ToUpper(strings.TrimSpace(req.PaymentType))
+	if req.Code == "" || len(req.Code) > 50 || strings.ContainsAny(req.Code, " \t") {
+		return fmt.Errorf("%w: code is required, up to 50 characters without spaces", ErrInvalidCoupon)
+	}
+	switch req.DiscountType {
+	case models.CouponDiscountPercent:
+		if req.DiscountValue <= 0 || req.DiscountValue > 100 {
+			return fmt.Errorf("%w: a percent discount_value must be over 0 and at most 100", ErrInvalidCoupon)
+		}
+	case models.CouponDiscountFlat:
+		if req.DiscountValue <= 0 {
+			return fmt.Errorf("%w: a flat discount_value must be over 0", ErrInvalidCoupon)
+		}
+	default:
+		return fmt.Errorf("%w: discount_type must be percent or flat", ErrInvalidCoupon)
+	}
+	req.DiscountValue = roundAmount(req.DiscountValue)
+	if req.PaymentType != "" && req.PaymentType != PaymentTypeRegistration && req.PaymentType != PaymentTypeCourseFee {
+		return fmt.Errorf("%w: payment_type must be REGISTRATION or COURSE_FEE", ErrInvalidCoupon)
+	}
+	if req.CourseID != nil {
+		if *req.CourseID <= 0 || req.PaymentType == PaymentTypeRegistration {
+			return fmt.Errorf("%w: course_id applies to course fees only", ErrInvalidCoupon)
+		}
+		req.PaymentType = PaymentTypeCourseFee
+	}
+	if req.ValidFrom != nil && req.ValidUntil != nil && !req.ValidUntil.After(*req.ValidFrom) {
+		return fmt.Errorf("%w: valid_until must be after valid_from", ErrInvalidCoupon)
+	}
+	if req.MaxRedemptions != nil && *req.MaxRedemptions < 1 {
+		return fmt.Errorf("%w: max_redemptions must be at least 1", ErrInvalidCoupon)
+	}
+	if req.MaxRedemptionsPerStudent == 0 {
+		req.MaxRedemptionsPerStudent = 1
+	}
+	if req.MaxRedemptionsPerStudent < 1 {
+		return fmt.Errorf("%w: max_redemptions_per_student must be at least 1", ErrInvalidCoupon)
+	}
+	if req.IsActive == nil {
+		active := true
+		req.IsActive = &active
+	}
+	return nil
+}
+
+// couponRedeemed matches the payments that hold a use of their coupon
+const couponRedeemed = `p.status NOT IN ('` + PaymentStatusFailed + `', '` + PaymentStatusCancelled + `')`
+
+const couponQuery = `
+	SELECT c.id, c.code, c.description, c.discount_type, c.discount_value, COALESCE(c.payment_type, ''), c.course_id,
+		c.valid_from, c.valid_until, c.max_redemptions, c.max_redemptions_per_student, c.is_active,
+		(SELECT COUNT(*) FROM payments p WHERE p.coupon_id = c.id AND ` + couponRedeemed + `),
+		c.created_at, c.updated_at
+	FROM coupon c`
+
+func scanCoupon(row interface{ Scan(...interface{}) error }) (*models.Coupon, error) {
+	var c models.Coupon
+	var courseID, maxRedemptions sql.NullInt64
+	var validFrom, validUntil sql.NullTime
+	err := row.Scan(&c.ID, &c.Code, &c.Description, &c.DiscountType, &c.DiscountValue, &c.PaymentType, &courseID,
+		&validFrom, &validUntil, &maxRedemptions, &c.MaxRedemptionsPerStudent, &c.IsActive, &c.Redemptions,
+		&c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if courseID.Valid {
+		id := int(courseID.Int64)
+		c.CourseID = &id
+	}
+	if validFrom.Valid {
+		c.ValidFrom = &validFrom.Time
+	}
+	if validUntil.Valid {
+		c.ValidUntil = &validUntil.Time
+	}
+	if maxRedemptions.Valid {
+		limit := int(maxRedemptions.Int64)
+		c.MaxRedemptions = &limit
+	}
+	return &c, nil
+}
+
+// ListCoupons returns every coupon with its redemptions, newest first
+func ListCoupons(ctx context.Context) ([]models.Coupon, error) {
+	rows, err := db.DB.QueryContext(ctx, couponQuery+" ORDER BY c.created_at DESC, c.id DESC")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching coupons: %w", err)
+	}
+	defer rows.Close()
+
+	coupons := []models.Coupon{}
+	for rows.Next() {
+		c, err := scanCoupon(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning coupon: %w", err)
+		}
+		coupons = append(coupons, *c)
+	}
+	return coupons, rows.Err()
+}
+
+// getCoupon returns one coupon with its redemptions
+func getCoupon(ctx context.Context, q querier, id int) (*models.Coupon, error) {
+	c, err := scanCoupon(q.QueryRowContext(ctx, couponQuery+" WHERE c.id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrCouponNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching coupon: %w", err)
+	}
+	return c, nil
+}
+
+// CreateCoupon adds a coupon
+func CreateCoupon(ctx context.Context, req CouponRequest) (*models.Coupon, error) {
+	if err := req.normalize(); err != nil {
+		return nil, err
+	}
+
+	var id int
+	err := db.DB.QueryRowContext(ctx, `
+		INSERT INTO coupon (code, description, discount_type, discount_value, payment_type, course_id,
+			valid_from, valid_until, max_redemptions, max_redemptions_per_student, is_active)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10, $11)
+		RETURNING id`,
+		req.Code, req.Description, req.DiscountType, req.DiscountValue, req.PaymentType, req.CourseID,
+		req.ValidFrom, req.ValidUntil, req.MaxRedemptions, req.MaxRedemptionsPerStudent, *req.IsActive).Scan(&id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("%w: coupon %s already exists", ErrInvalidCoupon, req.Code)
+		}
+		return nil, fmt.Errorf("error creating coupon: %w", err)
+	}
+	return getCoupon(ctx, db.DB, id)
+}
+
+// UpdateCoupon replaces the editable fields of a coupon. Orders already created keep the
+// discount they were given.
+func UpdateCoupon(ctx context.Context, id int, req CouponRequest) (*models.Coupon, error) {
+	if err := req.normalize(); err != nil {
+		return nil, err
+	}
+
+	result, err := db.DB.ExecContext(ctx, `
+		UPDATE coupon
+		SET code = $1, description = $2, discount_type = $3, discount_value = $4, payment_type = NULLIF($5, ''),
+			course_id = $6, valid_from = $7, valid_until = $8, max_redemptions = $9,
+			max_redemptions_per_student = $10, is_active = $11, updated_at = NOW()
+		WHERE id = $12`,
+		req.Code, req.Description, req.DiscountType, req.DiscountValue, req.PaymentType, req.CourseID,
+		req.ValidFrom, req.ValidUntil, req.MaxRedemptions, req.MaxRedemptionsPerStudent, *req.IsActive, id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("%w: coupon %s already exists", ErrInvalidCoupon, req.Code)
+		}
+		return nil, fmt.Errorf("error updating coupon: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return nil, ErrCouponNotFound
+	}
+	return getCoupon(ctx, db.DB, id)
+}
+
+// applyCoupon takes the discount of req.CouponCode off req.Amount and records the coupon
+// on req, or returns ErrCouponNotApplicable
+func applyCoupon(ctx context.Context, q querier, req *InitiatePaymentRequest, now time.Time) error {
+	code := normalizeCouponCode(req.CouponCode)
+	c, err := scanCoupon(q.QueryRowContext(ctx, couponQuery+" WHERE c.code = $1", code))
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: %s is not a valid code", ErrCouponNotApplicable, code)
+	}
+	if err != nil {
+		return fmt.Errorf("error fetching coupon: %w", err)
+	}
+	if err := checkCoupon(ctx, q, c, req, now); err != nil {
+		return err
+	}
+
+	var discount float64
+	if c.DiscountType == models.CouponDiscountPercent {
+		discount = roundAmount(req.Amount * c.DiscountValue / 100)
+	} else {
+		discount = math.Min(c.DiscountValue, req.Amount)
+	}
+	if req.Amount-discount < minimumOrderAmount {
+		return fmt.Errorf("%w: %s would leave less than Rs. %.0f to pay", ErrCouponNotApplicable, c.Code, minimumOrderAmount)
+	}
+
+	req.CouponCode = c.Code
+	req.CouponID = &c.ID
+	req.DiscountAmount = discount
+	req.Amount = roundAmount(req.Amount - discount)
+	return nil
+}
+
+// checkCouponReservable runs the check reserveCoupon will make, without keeping the lock,
+// so no Razorpay order is created for a coupon that has run out since the price was quoted
+func checkCouponReservable(ctx context.Context, req InitiatePaymentRequest) error {
+	ctx, cancel := db.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+	return reserveCoupon(ctx, tx, req.StudentID, req)
+}
+
+// reserveCoupon checks the coupon of req again within the transaction saving its order,
+// holding the coupon's row so concurrent checkouts cannot exceed its limits
+func reserveCoupon(ctx context.Context, tx *sql.Tx, studentID int, req InitiatePaymentRequest) error {
+	if _, err := tx.ExecContext(ctx, "SELECT id FROM coupon WHERE id = $1 FOR UPDATE", *req.CouponID); err != nil {
+		return fmt.Errorf("error locking coupon: %w", err)
+	}
+	c, err := getCoupon(ctx, tx, *req.CouponID)
+	if errors.Is(err, ErrCouponNotFound) {
+		return fmt.Errorf("%w: %s is not a valid code", ErrCouponNotApplicable, req.CouponCode)
+	}
+	if err != nil {
+		return err
+	}
+	req.StudentID = studentID
+	return checkCoupon(ctx, tx, c, &req, time.Now())
+}
+
+// checkCoupon returns ErrCouponNotApplicable unless the coupon is active, valid at now,
+// meant for the fee of req and has uses left, overall and for the student. The student's
+// own earlier order for the same fee does not count: the new order replaces it.
+func checkCoupon(ctx context.Context, q querier, c *models.Coupon, req *InitiatePaymentRequest, now time.Time) error {
+	switch {
+	case !c.IsActive:
+		return fmt.Errorf("%w: %s is no longer active", ErrCouponNotApplicable, c.Code)
+	case c.ValidFrom != nil && now.Before(*c.ValidFrom):
+		return fmt.Errorf("%w: %s is not valid yet", ErrCouponNotApplicable, c.Code)
+	case c.ValidUntil != nil && !now.Before(*c.ValidUntil):
+		return fmt.Errorf("%w: %s has expired", ErrCouponNotApplicable, c.Code)
+	case c.PaymentType != "" && c.PaymentType != req.PaymentType:
+		return fmt.Errorf("%w: %s is not valid for this fee", ErrCouponNotApplicable, c.Code)
+	case c.CourseID != nil && (req.CourseID == nil || *req.CourseID != *c.CourseID):
+		return fmt.Errorf("%w: %s is not valid for this course", ErrCouponNotApplicable, c.Code)
+	}
+
+	var courseID *int
+	if req.PaymentType == PaymentTypeCourseFee {
+		courseID = req.CourseID
+	}
+	var total, byStudent int
+	err := q.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE p.student_id = $2)
+		FROM payments p
+		WHERE p.coupon_id = $1 AND `+couponRedeemed+`
+			AND NOT (p.student_id = $2 AND p.payment_type = $3 AND p.course_id IS NOT DISTINCT FROM $4::int)`,
+		c.ID, req.StudentID, req.PaymentType, courseID).Scan(&total, &byStudent)
+	if err != nil {
+		return fmt.Errorf("error counting coupon redemptions: %w", err)
+	}
+	if c.MaxRedemptions != nil && total >= *c.MaxRedemptions {
+		return fmt.Errorf("%w: %s has been fully redeemed", ErrCouponNotApplicable, c.Code)
+	}
+	if byStudent >= c.MaxRedemptionsPerStudent {
+		return fmt.Errorf("%w: you have already used %s", ErrCouponNotApplicable, c.Code)
+	}
+	return nil
+}
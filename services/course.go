@@ -0,0 +1,262 @@
+package services
+
+import (
+	"admission-module/db"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// UpsertCourseRequest represents a course definition from the API or a catalog import
+type UpsertCourseRequest struct {
+	Name        string
+	Description string
+	Fee         float64
+	Duration    string
+	Batch       string // current intake; kept as is when empty
+}
+
+// UpsertCourseResult reports which course row the request resolved to
+type UpsertCourseResult struct {
+	CourseID int
+	Created  bool
+}
+
+// DuplicateCourseGroup describes courses sharing the same name and duration
+type DuplicateCourseGroup struct {
+	Name         string `json:"name"`
+	Duration     string `json:"duration"`
+	KeptCourseID int    `json:"kept_course_id"`
+	MergedIDs    []int  `json:"merged_course_ids"`
+	SkippedIDs   []int  `json:"skipped_course_ids,omitempty"`
+	SkipReason   string `json:"skip_reason,omitempty"`
+}
+
+// ErrDuplicateCourse is returned when a course is renamed onto another course's name and duration
+var ErrDuplicateCourse = errors.New("a course with this name and duration already exists")
+
+// UpsertCourse creates a course, or updates the existing one with the same name and duration.
+// Matching is case-insensitive on name so re-running catalog imports is idempotent.
+// With uq_course_name_duration in place, concurrent imports cannot create the same course twice.
+func UpsertCourse(ctx context.Context, req UpsertCourseRequest) (*UpsertCourseResult, error) {
+	now := time.Now()
+	result := &UpsertCourseResult{}
+	// xmax is 0 only for a row this statement inserted
+	err := db.DB.QueryRowContext(ctx, `
+		INSERT INTO course (name, description, fee, duration, batch, is_active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), 1, $6, $6)
+		ON CONFLICT ((LOWER(name)), (COALESCE(duration, ''))) DO UPDATE
+		SET description = EXCLUDED.description, fee = EXCLUDED.fee,
+			batch = COALESCE(EXCLUDED.batch, course.batch), is_active = 1, updated_at = EXCLUDED.updated_at
+		RETURNING id, xmax = 0`,
+		req.Name, req.Description, req.Fee, req.Duration, req.Batch, now).Scan(&result.CourseID, &result.Created)
+	if isMissingConflictIndex(err) {
+		// The migration only creates the index once duplicates are merged (cmd/merge-courses)
+		return upsertCourseByLookup(ctx, req)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error saving course: %w", err)
+	}
+	return result, nil
+}
+
+// isMissingConflictIndex reports whether err is PostgreSQL's "no unique or exclusion
+// constraint matching the ON CONFLICT specification"
+func isMissingConflictIndex(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "42P10"
+}
+
+// upsertCourseByLookup is UpsertCourse for databases still holding duplicate courses,
+// where uq_course_name_duration does not exist. It locks the oldest matching course;
+// two concurrent imports of a new course can still both insert it.
+func upsertCourseByLookup(ctx context.Context, req UpsertCourseRequest) (*UpsertCourseResult, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var courseID int
+	err = tx.QueryRowContext(ctx, `
+		SELECT id FROM course
+		WHERE LOWER(name) = LOWER($1) AND COALESCE(duration, '') = $2
+		ORDER BY id ASC
+		LIMIT 1
+		FOR UPDATE`, req.Name, req.Duration).Scan(&courseID)
+
+	result := &UpsertCourseResult{}
+	switch {
+	case err == sql.ErrNoRows:
+		err = tx.QueryRowContext(ctx,
+			`INSERT INTO course (name, description, fee, duration, batch, is_active, created_at, updated_at) VALUES ($1, $2, $3, $4, NULLIF($5, ''), 1, $6, $7) RETURNING id`,
+			req.Name, req.Description, req.Fee, req.Duration, req.Batch, now, now).Scan(&courseID)
+		if err != nil {
+			return nil, fmt.Errorf("error creating course: %w", err)
+		}
+		result.Created = true
+	case err != nil:
+		return nil, fmt.Errorf("error looking up course: %w", err)
+	default:
+		_, err = tx.ExecContext(ctx,
+			`UPDATE course SET description = $1, fee = $2, batch = COALESCE(NULLIF($3, ''), batch), is_active = 1, updated_at = $4 WHERE id = $5`,
+			req.Description, req.Fee, req.Batch, now, courseID)
+		if err != nil {
+			return nil, fmt.Errorf("error updating course: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	result.CourseID = courseID
+	return result, nil
+}
+
+// UpdateCourse replaces the fields of a course. It returns ErrCourseNotFound for an unknown
+// id and ErrDuplicateCourse when the new name and duration belong to another course.
+func UpdateCourse(ctx context.Context, id int, req UpsertCourseRequest, isActive int) error {
+	result, err := db.DB.ExecContext(ctx,
+		`UPDATE course SET name = $1, description = $2, fee = $3, duration = $4, batch = NULLIF($5, ''), is_active = $6, updated_at = $7 WHERE id = $8`,
+		req.Name, req.Description, req.Fee, req.Duration, req.Batch, isActive, time.Now(), id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: %s (%s)", ErrDuplicateCourse, req.Name, req.Duration)
+		}
+		return fmt.Errorf("error updating course: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("error checking update: %w", err)
+	} else if affected == 0 {
+		return ErrCourseNotFound
+	}
+	return nil
+}
+
+// MergeDuplicateCourses folds courses sharing a name and duration into the oldest one.
+// Payments and lead selections pointing at a duplicate are re-pointed to the kept course.
+// A duplicate is skipped when a student has payments for both courses, since the
+// unique (student_id, course_id) constraint would otherwise be violated.
+// With dryRun set, the groups are reported without changing anything.
+func MergeDuplicateCourses(ctx context.Context, dryRun bool) ([]DuplicateCourseGroup, error) {
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT MIN(name), COALESCE(duration, ''), ARRAY_AGG(id ORDER BY id)
+		FROM course
+		GROUP BY LOWER(name), COALESCE(duration, '')
+		HAVING COUNT(*) > 1`)
+	if err != nil {
+		return nil, fmt.Errorf("error finding duplicate courses: %w", err)
+	}
+
+	groups := []DuplicateCourseGroup{}
+	for rows.Next() {
+		var group DuplicateCourseGroup
+		var ids pq.Int64Array
+		if err := rows.Scan(&group.Name, &group.Duration, &ids); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error reading duplicate courses: %w", err)
+		}
+		group.KeptCourseID = int(ids[0])
+		for _, id := range ids[1:] {
+			group.MergedIDs = append(group.MergedIDs, int(id))
+		}
+		groups = append(groups, group)
+	}
+	rows.Close()
+
+	if dryRun {
+		return groups, nil
+	}
+
+	for i := range groups {
+		if err := mergeCourseGroup(ctx, &groups[i]); err != nil {
+			return groups, err
+		}
+	}
+
+	// Once the catalog is clean, enforce uniqueness going forward
+	_, err = db.DB.ExecContext(ctx,
+		`CREATE UNIQUE INDEX IF NOT EXISTS uq_course_name_duration ON course (LOWER(name), COALESCE(duration, ''))`)
+	if err != nil {
+		log.Printf("Warning: could not create unique course index (duplicates may remain): %v", err)
+	}
+
+	return groups, nil
+}
+
+// mergeCourseGroup re-points references from each duplicate to the kept course and deletes it
+func mergeCourseGroup(ctx context.Context, group *DuplicateCourseGroup) error {
+	candidates := group.MergedIDs
+	group.MergedIDs = []int{}
+
+	for _, duplicateID := range candidates {
+		tx, err := db.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("error starting transaction: %w", err)
+		}
+
+		var conflicts int
+		err = tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM course_payment dup
+			JOIN course_payment kept ON kept.student_id = dup.student_id AND kept.course_id = $1
+			WHERE dup.course_id = $2`, group.KeptCourseID, duplicateID).Scan(&conflicts)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error checking payment conflicts for course %d: %w", duplicateID, err)
+		}
+		if conflicts > 0 {
+			tx.Rollback()
+			group.SkippedIDs = append(group.SkippedIDs, duplicateID)
+			group.SkipReason = "students have payments for both the kept and the duplicate course"
+			continue
+		}
+
+		statements := []string{
+			"UPDATE course_payment SET course_id = $1, updated_at = CURRENT_TIMESTAMP WHERE course_id = $2",
+			"UPDATE student_lead SET selected_course_id = $1, updated_at = CURRENT_TIMESTAMP WHERE selected_course_id = $2",
+			// Blocks the kept course already has win; the duplicate's copies go with it
+			`UPDATE course_content_block SET course_id = $1, updated_at = CURRENT_TIMESTAMP
+			WHERE course_id = $2 AND block_key NOT IN (SELECT block_key FROM course_content_block WHERE course_id = $1)`,
+			"UPDATE invoice SET course_id = $1, updated_at = CURRENT_TIMESTAMP WHERE course_id = $2",
+			"UPDATE enrollment_sync SET course_id = $1, updated_at = CURRENT_TIMESTAMP WHERE course_id = $2",
+			"UPDATE acceptance_approval SET course_id = $1 WHERE course_id = $2",
+			// An active rule the kept course already has for the same counselor wins
+			`UPDATE commission_rule SET active = FALSE, updated_at = CURRENT_TIMESTAMP
+			WHERE course_id = $2 AND active AND COALESCE(counselor_id, 0) IN
+				(SELECT COALESCE(counselor_id, 0) FROM commission_rule WHERE course_id = $1 AND active)`,
+			"UPDATE commission_rule SET course_id = $1, updated_at = CURRENT_TIMESTAMP WHERE course_id = $2",
+			"UPDATE commission_entry SET course_id = $1 WHERE course_id = $2",
+			"UPDATE coupon SET course_id = $1, updated_at = CURRENT_TIMESTAMP WHERE course_id = $2",
+			"UPDATE razorpay_payment_link SET course_id = $1, updated_at = CURRENT_TIMESTAMP WHERE course_id = $2",
+			"UPDATE payment_receipt SET course_id = $1 WHERE course_id = $2",
+			`UPDATE interviewer SET course_ids = ARRAY(SELECT DISTINCT unnest(array_replace(course_ids, $2, $1))),
+				updated_at = CURRENT_TIMESTAMP
+			WHERE $2 = ANY(course_ids)`,
+		}
+		for _, stmt := range statements {
+			if _, err := tx.ExecContext(ctx, stmt, group.KeptCourseID, duplicateID); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("error merging course %d into %d: %w", duplicateID, group.KeptCourseID, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM course WHERE id = $1", duplicateID); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error deleting duplicate course %d: %w", duplicateID, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("error committing merge of course %d: %w", duplicateID, err)
+		}
+		group.MergedIDs = append(group.MergedIDs, duplicateID)
+		log.Printf("Merged duplicate course %d into %d (%s)", duplicateID, group.KeptCourseID, group.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrCourseNotFound is returned when a content block references a missing course
+	ErrCourseNotFound = errors.New("course not found")
+	// ErrContentBlockNotFound is returned when a course has no block with the given key
+	ErrContentBlockNotFound = errors.New("content block not found")
+	// ErrInvalidContentBlock is returned for a malformed key, empty title or body, or unknown email type
+	ErrInvalidContentBlock = errors.New("invalid content block")
+)
+
+// contentBlockKeyPattern keeps keys URL-safe, e.g. orientation, documents-needed, dept_contacts
+var contentBlockKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+// UpsertContentBlockRequest is the editable part of a course content block
+type UpsertContentBlockRequest struct {
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	EmailType string `json:"email_type"` // all (default), acceptance or enrollment
+	Position  int    `json:"position"`
+	IsActive  *bool  `json:"is_active"` // defaults to true
+	UpdatedBy string `json:"updated_by"`
+}
+
+const contentBlockColumns = `id, course_id, block_key, title, body, email_type, position, is_active,
+	COALESCE(updated_by, ''), created_at, updated_at`
+
+func scanContentBlock(row interface{ Scan(...interface{}) error }) (*models.CourseContentBlock, error) {
+	var b models.CourseContentBlock
+	err := row.Scan(&b.ID, &b.CourseID, &b.Key, &b.Title, &b.Body, &b.EmailType, &b.Position, &b.IsActive,
+		&b.UpdatedBy, &b.CreatedAt, &b.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// ListCourseContentBlocks returns all blocks of a course, active or not, in render order
+func ListCourseContentBlocks(ctx context.Context, courseID int) ([]models.CourseContentBlock, error) {
+	if err := ensureCourseExists(ctx, courseID); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.DB.QueryContext(ctx,
+		"SELECT "+contentBlockColumns+" FROM course_content_block WHERE course_id = $1 ORDER BY position, block_key",
+		courseID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching content blocks: %w", err)
+	}
+	defer rows.Close()
+
+	blocks := []models.CourseContentBlock{}
+	for rows.Next() {
+		b, err := scanContentBlock(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning content block: %w", err)
+		}
+		blocks = append(blocks, *b)
+	}
+	return blocks, rows.Err()
+}
+
+// UpsertCourseContentBlock creates or replaces the block with the given key on a course
+func UpsertCourseContentBlock(ctx context.Context, courseID int, key string, req UpsertContentBlockRequest) (*models.CourseContentBlock, error) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	if !contentBlockKeyPattern.MatchString(key) {
+		return nil, fmt.Errorf("%w: key must be 1-50 lowercase letters, digits, '-' or '_'", ErrInvalidContentBlock)
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	req.Body = strings.TrimSpace(req.Body)
+	if req.Title == "" || req.Body == "" {
+		return nil, fmt.Errorf("%w: title and body are required", ErrInvalidContentBlock)
+	}
+	if len(req.Title) > 150 {
+		return nil, fmt.Errorf("%w: title must be at most 150 characters", ErrInvalidContentBlock)
+	}
+	switch req.EmailType {
+	case "":
+		req.EmailType = models.ContentBlockEmailAll
+	case models.ContentBlockEmailAll, models.ContentBlockEmailAcceptance, models.ContentBlockEmailEnrollment:
+	default:
+		return nil, fmt.Errorf("%w: email_type must be all, acceptance or enrollment", ErrInvalidContentBlock)
+	}
+	active := true
+	if req.IsActive != nil {
+		active = *req.IsActive
+	}
+
+	if err := ensureCourseExists(ctx, courseID); err != nil {
+		return nil, err
+	}
+
+	row := db.DB.QueryRowContext(ctx, `
+		INSERT INTO course_content_block (course_id, block_key, title, body, email_type, position, is_active, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
+		ON CONFLICT (course_id, block_key) DO UPDATE
+		SET title = EXCLUDED.title,
+			body = EXCLUDED.body,
+			email_type = EXCLUDED.email_type,
+			position = EXCLUDED.position,
+			is_active = EXCLUDED.is_active,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING `+contentBlockColumns,
+		courseID, key, req.Title, req.Body, req.EmailType, req.Position, active, req.UpdatedBy)
+	block, err := scanContentBlock(row)
+	if err != nil {
+		return nil, fmt.Errorf("error saving content block: %w", err)
+	}
+	return block, nil
+}
+
+// DeleteCourseContentBlock removes a block from a course
+func DeleteCourseContentBlock(ctx context.Context, courseID int, key string) error {
+	result, err := db.DB.ExecContext(ctx,
+		"DELETE FROM course_content_block WHERE course_id = $1 AND block_key = $2",
+		courseID, strings.ToLower(strings.TrimSpace(key)))
+	if err != nil {
+		return fmt.Errorf("error deleting content block: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrContentBlockNotFound
+	}
+	return nil
+}
+
+func ensureCourseExists(ctx context.Context, courseID int) error {
+	var exists bool
+	if err := db.DB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM course WHERE id = $1)", courseID).Scan(&exists); err != nil {
+		return fmt.Errorf("error checking course: %w", err)
+	}
+	if !exists {
+		return ErrCourseNotFound
+	}
+	return nil
+}
+
+// renderCourseContentBlocks renders the active blocks of a course for one email type as
+// HTML. Block text is escaped. Errors are logged and yield no blocks, so the email itself
+// still goes out. It reads outside any caller transaction so a failure cannot abort it.
+func renderCourseContentBlocks(ctx context.Context, courseID int, emailType string) string {
+	if courseID == 0 || db.DB == nil {
+		return ""
+	}
+
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT title, body FROM course_content_block
+		WHERE course_id = $1 AND is_active = TRUE AND email_type IN ('all', $2)
+		ORDER BY position, block_key`, courseID, emailType)
+	if err != nil {
+		logger.Warn("Error loading content blocks of course %d: %v", courseID, err)
+		return ""
+	}
+	defer rows.Close()
+
+	var sb strings.Builder
+	for rows.Next() {
+		var title, body string
+		if err := rows.Scan(&title, &body); err != nil {
+			logger.Warn("Error scanning content block of course %d: %v", courseID, err)
+			return ""
+		}
+		writeContentBlock(&sb, title, body)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Warn("Error loading content blocks of course %d: %v", courseID, err)
+		return ""
+	}
+	return sb.String()
+}
+
+// writeContentBlock renders one content block; title and body are escaped
+func writeContentBlock(sb *strings.Builder, title, body string) {
+	fmt.Fprintf(sb, `<div style="background-color: #e3f2fd; padding: 15px; margin: 15px 0; border-left: 4px solid #2196F3;">
+                <p><strong>%s</strong></p>
+                <p>%s</p>
+            </div>
+            `, html.EscapeString(title), strings.ReplaceAll(html.EscapeString(body), "\n", "<br/>"))
+}
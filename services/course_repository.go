@@ -0,0 +1,42 @@
+package services
+
+import (
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// CourseRepository reads courses
+type CourseRepository interface {
+	// FindByID returns the course, active or not, or ErrCourseNotFound
+	FindByID(ctx context.Context, id int) (*models.Course, error)
+}
+
+// postgresCourseRepository is the CourseRepository over the course table
+type postgresCourseRepository struct {
+	q querier
+}
+
+// NewCourseRepository creates a course repository over a database handle or transaction
+func NewCourseRepository(q querier) CourseRepository {
+	return &postgresCourseRepository{q: q}
+}
+
+func (r *postgresCourseRepository) FindByID(ctx context.Context, id int) (*models.Course, error) {
+	var c models.Course
+	var createdAt, updatedAt sql.NullTime
+	err := r.q.QueryRowContext(ctx, `
+		SELECT id, name, COALESCE(description, ''), fee, COALESCE(duration, ''), COALESCE(batch, ''),
+			COALESCE(is_active, 0), created_at, updated_at
+		FROM course WHERE id = $1`, id).Scan(
+		&c.ID, &c.Name, &c.Description, &c.Fee, &c.Duration, &c.Batch, &c.IsActive, &createdAt, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrCourseNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching course %d: %w", id, err)
+	}
+	c.CreatedAt, c.UpdatedAt = createdAt.Time, updatedAt.Time
+	return &c, nil
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ParseDecisionSheet reads offline application decisions from the first sheet of an
+// Excel file. The header row names the student_id, decision and course columns;
+// decisions may be written accept/reject/waitlist. Empty rows are skipped.
+func ParseDecisionSheet(filePath string) ([]models.DecisionImportRow, error) {
+	f, err := excelize.OpenFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Excel file: %w", err)
+	}
+	defer f.Close()
+
+	sheetList := f.GetSheetList()
+	if len(sheetList) == 0 {
+		return nil, fmt.Errorf("no sheets found in Excel file")
+	}
+	rows, err := f.GetRows(sheetList[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no data in sheet")
+	}
+
+	studentCol, decisionCol, courseCol := -1, -1, -1
+	for i, header := range rows[0] {
+		switch strings.ToLower(strings.TrimSpace(header)) {
+		case "student_id", "student id", "id":
+			studentCol = i
+		case "decision", "status":
+			decisionCol = i
+		case "course", "course_id", "course id", "course name":
+			courseCol = i
+		}
+	}
+	if studentCol < 0 || decisionCol < 0 {
+		return nil, fmt.Errorf("sheet must have student_id and decision columns")
+	}
+
+	var decisions []models.DecisionImportRow
+	for i := 1; i < len(rows); i++ {
+		row := rows[i]
+		studentField := extractField(row, studentCol)
+		decision := extractField(row, decisionCol)
+		course := extractField(row, courseCol)
+		if studentField == "" && decision == "" && course == "" {
+			continue
+		}
+
+		// An unparsable ID is left at 0 and reported by ImportDecisions
+		studentID, _ := strconv.Atoi(studentField)
+		decisions = append(decisions, models.DecisionImportRow{
+			Row:       i + 1,
+			StudentID: studentID,
+			Decision:  normalizeDecision(decision),
+			Course:    course,
+		})
+	}
+	return decisions, nil
+}
+
+// normalizeDecision maps the spellings committees use to an application status
+func normalizeDecision(decision string) string {
+	switch strings.ToUpper(strings.TrimSpace(decision)) {
+	case "ACCEPT", "ACCEPTED":
+		return ApplicationStatusAccepted
+	case "REJECT", "REJECTED":
+		return ApplicationStatusRejected
+	case "WAITLIST", "WAITLISTED":
+		return ApplicationStatusWaitlisted
+	}
+	return strings.ToUpper(strings.TrimSpace(decision))
+}
+
+// DecisionImportOptions controls an import of offline decisions
+type DecisionImportOptions struct {
+	// DecidedBy names the committee or staff member; it is the approver of acceptances
+	// into courses needing dual approval
+	DecidedBy string
+	// DryRun validates every row without applying decisions or emailing students
+	DryRun bool
+	// ReportTo receives a summary of the import by email, when set
+	ReportTo string
+}
+
+// ImportDecisions validates each decision against the application state machine and the
+// registration payment, then applies it through ApplicationService in its own transaction
+// and queues the student's decision email. A failing row does not stop the others. Rows
+// are applied in sheet order, so a later row for the same student sees the earlier
+// decision, in dry runs as well.
+func ImportDecisions(ctx context.Context, rows []models.DecisionImportRow, opts DecisionImportOptions) (*models.DecisionImportResult, error) {
+	applications := NewApplicationService()
+	result := &models.DecisionImportResult{
+		DryRun:     opts.DryRun,
+		DecidedBy:  strings.TrimSpace(opts.DecidedBy),
+		TotalCount: len(rows),
+		Rows:       []models.DecisionImportRowResult{},
+	}
+	// Statuses the dry run would have set, read in place of the lead's
+	simulated := map[int]string{}
+
+	for _, row := range rows {
+		rowResult := models.DecisionImportRowResult{DecisionImportRow: row}
+
+		next, err := validateDecisionRow(ctx, &rowResult, result.DecidedBy, simulated)
+		if err == nil {
+			if opts.DryRun {
+				rowResult.Action = models.DecisionRowValid
+				simulated[row.StudentID] = next
+			} else {
+				err = applyDecisionRow(ctx, applications, &rowResult, result.DecidedBy)
+			}
+		}
+
+		if err != nil {
+			rowResult.Action = models.DecisionRowFailed
+			rowResult.Error = err.Error()
+			result.FailedCount++
+		} else if !opts.DryRun {
+			result.AppliedCount++
+		}
+		result.Rows = append(result.Rows, rowResult)
+	}
+
+	logger.Info("Decision import by %q (dry run: %t): %d rows, %d applied, %d failed",
+		result.DecidedBy, opts.DryRun, result.TotalCount, result.AppliedCount, result.FailedCount)
+
+	if opts.ReportTo != "" {
+		subject, body := buildDecisionImportReportEmail(result)
+		if err := SendCategorizedEmail(EmailCategoryStaff, opts.ReportTo, subject, body); err != nil {
+			logger.Warn("Failed to queue decision import report to %s: %v", opts.ReportTo, err)
+		}
+	}
+	return result, nil
+}
+
+// validateDecisionRow checks a row the way the application-action API would and resolves
+// its course. Returns the status the application would move to.
+func validateDecisionRow(ctx context.Context, row *models.DecisionImportRowResult, decidedBy string, simulated map[int]string) (string, error) {
+	if row.StudentID <= 0 {
+		return "", fmt.Errorf("invalid student_id")
+	}
+	decision := row.Decision
+	if decision != ApplicationStatusAccepted && decision != ApplicationStatusRejected && decision != ApplicationStatusWaitlisted {
+		return "", fmt.Errorf("invalid decision %q, must be ACCEPTED, REJECTED or WAITLISTED", row.Decision)
+	}
+
+	var courseFee float64
+	if decision != ApplicationStatusRejected {
+		if row.Course == "" {
+			return "", fmt.Errorf("course is required for acceptance and waitlisting")
+		}
+		courseID, fee, err := resolveDecisionCourse(ctx, row.Course)
+		if err != nil {
+			return "", err
+		}
+		row.CourseID, courseFee = courseID, fee
+	}
+
+	err := db.DB.QueryRowContext(ctx,
+		"SELECT COALESCE(application_status, 'NEW') FROM student_lead WHERE id = $1 AND deleted_at IS NULL",
+		row.StudentID).Scan(&row.PreviousStatus)
+	if err == sql.ErrNoRows {
+		return "", ErrLeadNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading lead: %w", err)
+	}
+	current := row.PreviousStatus
+	if status, ok := simulated[row.StudentID]; ok {
+		current = status
+	}
+
+	var paymentStatus string
+	err = db.DB.QueryRowContext(ctx,
+		"SELECT status FROM registration_payment WHERE student_id = $1", row.StudentID).Scan(&paymentStatus)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("registration payment record not found")
+	}
+	if err != nil {
+		return "", fmt.Errorf("error checking registration payment status: %w", err)
+	}
+	if paymentStatus != "PAID" {
+		return "", fmt.Errorf("registration payment status is %s", paymentStatus)
+	}
+
+	if !CanTransitionApplication(current, decision) {
+		return "", fmt.Errorf("%w: %s -> %s", ErrInvalidStatusTransition, current, decision)
+	}
+
+	if decision == ApplicationStatusAccepted && requiresDualApproval(courseFee) {
+		if decidedBy == "" {
+			return "", ErrApproverRequired
+		}
+		if current != ApplicationStatusPendingApproval {
+			return ApplicationStatusPendingApproval, nil
+		}
+		if err := checkPendingApprovalConfirmation(ctx, row.StudentID, row.CourseID, decidedBy); err != nil {
+			return "", err
+		}
+	}
+	return decision, nil
+}
+
+// resolveDecisionCourse finds a course by ID or, failing that, by name
+func resolveDecisionCourse(ctx context.Context, course string) (int, float64, error) {
+	var id int
+	var fee float64
+	query := "SELECT id, fee FROM course WHERE LOWER(name) = LOWER($1)"
+	arg := interface{}(course)
+	if courseID, err := strconv.Atoi(course); err == nil {
+		query, arg = "SELECT id, fee FROM course WHERE id = $1", courseID
+	}
+	err := db.DB.QueryRowContext(ctx, query, arg).Scan(&id, &fee)
+	if err == sql.ErrNoRows {
+		return 0, 0, fmt.Errorf("course %q not found", course)
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("error reading course: %w", err)
+	}
+	return id, fee, nil
+}
+
+// checkPendingApprovalConfirmation reports whether decidedBy may confirm the student's
+// pending acceptance into courseID, as AcceptApplication would
+func checkPendingApprovalConfirmation(ctx context.Context, studentID, courseID int, decidedBy string) error {
+	var pendingCourseID int
+	var requestedBy string
+	err := db.DB.QueryRowContext(ctx,
+		"SELECT course_id, requested_by FROM acceptance_approval WHERE student_id = $1 AND status = $2",
+		studentID, models.AcceptanceApprovalPending).Scan(&pendingCourseID, &requestedBy)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading pending approval: %w", err)
+	}
+	if pendingCourseID != courseID {
+		return ErrApprovalCourseMismatch
+	}
+	if strings.EqualFold(requestedBy, decidedBy) {
+		return ErrSameApprover
+	}
+	return nil
+}
+
+// applyDecisionRow applies a validated decision and queues its email
+func applyDecisionRow(ctx context.Context, applications *ApplicationService, row *models.DecisionImportRowResult, decidedBy string) error {
+	var notifyErr error
+	switch row.Decision {
+	case ApplicationStatusAccepted:
+		accepted, err := applications.AcceptApplication(ctx, AcceptApplicationRequest{
+			StudentID:        row.StudentID,
+			SelectedCourseID: row.CourseID,
+			ApprovedBy:       decidedBy,
+		})
+		if err != nil {
+			return err
+		}
+		row.Action = models.DecisionRowApplied
+		if accepted.PendingApproval {
+			row.Action = models.DecisionRowPendingApproval
+		}
+		notifyErr = applications.NotifyAccepted(accepted)
+	case ApplicationStatusWaitlisted:
+		waitlisted, err := applications.WaitlistApplication(ctx, WaitlistApplicationRequest{
+			StudentID:        row.StudentID,
+			SelectedCourseID: row.CourseID,
+		})
+		if err != nil {
+			return err
+		}
+		row.Action = models.DecisionRowApplied
+		notifyErr = applications.NotifyWaitlisted(waitlisted)
+	default:
+		rejected, err := applications.RejectApplication(ctx, RejectApplicationRequest{StudentID: row.StudentID})
+		if err != nil {
+			return err
+		}
+		row.Action = models.DecisionRowApplied
+		notifyErr = applications.NotifyRejected(rejected)
+	}
+
+	if notifyErr != nil {
+		logger.Warn("Failed to queue decision email for student %d: %v", row.StudentID, notifyErr)
+	}
+	row.EmailQueued = notifyErr == nil
+	return nil
+}
+
+// buildDecisionImportReportEmail renders the summary of a decision import for staff
+func buildDecisionImportReportEmail(result *models.DecisionImportResult) (subject, body string) {
+	var rows strings.Builder
+	for _, row := range result.Rows {
+		outcome := row.Action
+		if row.Error != "" {
+			outcome += ": " + row.Error
+		}
+		fmt.Fprintf(&rows, "<tr><td>%d</td><td>%d</td><td>%s</td><td>%s</td><td>%s</td></tr>\n",
+			row.Row, row.StudentID, html.EscapeString(row.Decision), html.EscapeString(row.Course), html.EscapeString(outcome))
+	}
+
+	mode := "Decision import"
+	if result.DryRun {
+		mode = "Decision import dry run"
+	}
+	body = fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <p>%s by <strong>%s</strong>: %d rows, %d applied, %d failed.</p>
+    <table border="1" cellpadding="4" style="border-collapse: collapse;">
+        <tr><th>Row</th><th>Student ID</th><th>Decision</th><th>Course</th><th>Outcome</th></tr>
+%s    </table>
+</body>
+</html>
+	`, mode, html.EscapeString(result.DecidedBy), result.TotalCount, result.AppliedCount, result.FailedCount, rows.String())
+
+	return fmt.Sprintf("%s: %d applied, %d failed", mode, result.AppliedCount, result.FailedCount), body
+}
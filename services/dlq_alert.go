@@ -0,0 +1,153 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const slackWebhookTimeout = 10 * time.Second
+
+var (
+	dlqAlertMutex sync.Mutex
+	dlqAlertState models.DLQAlertState
+)
+
+// runDLQRetry is the dlq-retry job: it retries due messages, then checks the backlog
+// alert threshold even when the retry pass failed
+func runDLQRetry(ctx context.Context) error {
+	err := RetryDueDLQMessages(ctx)
+	if alertErr := CheckDLQBacklog(ctx); alertErr != nil {
+		logger.Error("Error checking DLQ backlog: %v", alertErr)
+	}
+	return err
+}
+
+// CheckDLQBacklog alerts DLQ_ALERT_EMAIL and DLQ_ALERT_SLACK_WEBHOOK_URL when more than
+// DLQ_ALERT_THRESHOLD messages are unresolved. While the backlog stays above the threshold
+// the alert repeats at most once per DLQ_ALERT_COOLDOWN_MINUTES.
+func CheckDLQBacklog(ctx context.Context) error {
+	if db.DB == nil {
+		return nil
+	}
+
+	var unresolved int
+	if err := db.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM dlq_messages WHERE resolved = FALSE").Scan(&unresolved); err != nil {
+		return err
+	}
+
+	threshold := config.AppConfig.DLQAlertThreshold
+	cooldown := time.Duration(config.AppConfig.DLQAlertCooldownMinutes) * time.Minute
+	now := time.Now()
+
+	dlqAlertMutex.Lock()
+	defer dlqAlertMutex.Unlock()
+
+	state := &dlqAlertState
+	state.Unresolved = unresolved
+	state.Threshold = threshold
+	state.CheckedAt = &now
+
+	if unresolved <= threshold {
+		if state.Firing {
+			logger.Info("DLQ backlog back under threshold (%d unresolved, threshold %d)", unresolved, threshold)
+		}
+		state.Firing = false
+		state.FiringSince = nil
+		state.NextAlertAfter = nil
+		return nil
+	}
+
+	if !state.Firing {
+		state.Firing = true
+		state.FiringSince = &now
+	}
+	if state.LastAlertAt != nil && now.Sub(*state.LastAlertAt) < cooldown {
+		return nil
+	}
+
+	logger.Warn("DLQ backlog above threshold: %d unresolved messages (threshold %d)", unresolved, threshold)
+
+	var errs []error
+	delivered := false
+	if target := config.AppConfig.DLQAlertEmail; target != "" {
+		subject := fmt.Sprintf("[DLQ] %d unresolved messages (threshold %d)", unresolved, threshold)
+		if err := DeliverEmail(target, subject, formatDLQBacklogAlert(state)); err != nil {
+			errs = append(errs, fmt.Errorf("error sending DLQ backlog email: %w", err))
+		} else {
+			delivered = true
+		}
+	}
+	if url := config.AppConfig.DLQAlertSlackWebhookURL; url != "" {
+		text := fmt.Sprintf(":rotating_light: DLQ backlog: %d unresolved messages (threshold %d), firing since %s",
+			unresolved, threshold, state.FiringSince.Format(time.RFC3339))
+		if err := postSlackMessage(ctx, url, text); err != nil {
+			errs = append(errs, fmt.Errorf("error sending DLQ backlog Slack alert: %w", err))
+		} else {
+			delivered = true
+		}
+	}
+
+	// A failed channel is retried on the next pass unless another one got through,
+	// so a broken Slack hook does not turn the email into spam
+	if delivered || len(errs) == 0 {
+		next := now.Add(cooldown)
+		state.LastAlertAt = &now
+		state.NextAlertAfter = &next
+	}
+	return errors.Join(errs...)
+}
+
+// GetDLQAlertState returns the backlog alert state from the last check
+func GetDLQAlertState() models.DLQAlertState {
+	dlqAlertMutex.Lock()
+	defer dlqAlertMutex.Unlock()
+
+	state := dlqAlertState
+	state.Threshold = config.AppConfig.DLQAlertThreshold
+	return state
+}
+
+// formatDLQBacklogAlert renders the backlog alert email
+func formatDLQBacklogAlert(state *models.DLQAlertState) string {
+	return fmt.Sprintf("<p><strong>%d</strong> DLQ messages are unresolved (threshold %d) since %s.</p>"+
+		"<p>Review them at <code>GET /api/dlq/messages</code> and <code>GET /api/dlq/quarantine</code>.</p>",
+		state.Unresolved, state.Threshold, state.FiringSince.Format(time.RFC1123))
+}
+
+// postSlackMessage posts text to a Slack incoming webhook
+func postSlackMessage(ctx context.Context, url, text string) error {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, slackWebhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("slack webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/models"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/razorpay/razorpay-go"
+	"gopkg.in/gomail.v2"
+)
+
+// doctorProbeKey is the storage key written and read back by the storage check
+const doctorProbeKey = ".doctor/probe"
+
+// RunDoctor verifies the external integrations the service depends on: database
+// connectivity and schema, Kafka brokers and topics, SMTP login, Razorpay keys,
+// document storage, the LMS/ERP enrollment handoff and the TLS certificate. Integrations that are not
+// configured are reported as skipped.
+func RunDoctor(ctx context.Context) models.DoctorReport {
+	checks := []struct {
+		name string
+		fn   func(context.Context) (string, string)
+	}{
+		{"database", checkDatabase},
+		{"schema", checkSchema},
+		{"kafka", checkKafka},
+		{"smtp", checkSMTP},
+		{"razorpay", checkRazorpay},
+		{"storage", checkStorage},
+		{"enrollment-sync", checkEnrollmentSync},
+		{"tls", checkTLS},
+	}
+
+	report := models.DoctorReport{Healthy: true, Checks: []models.DoctorCheck{}}
+	for _, c := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		start := time.Now()
+		status, detail := c.fn(checkCtx)
+		cancel()
+
+		if status == models.DoctorStatusFail {
+			report.Healthy = false
+		}
+		report.Checks = append(report.Checks, models.DoctorCheck{
+			Name:       c.name,
+			Status:     status,
+			Detail:     detail,
+			DurationMS: time.Since(start).Milliseconds(),
+		})
+	}
+
+	return report
+}
+
+func checkDatabase(ctx context.Context) (string, string) {
+	if db.DB == nil {
+		return models.DoctorStatusFail, "database is not initialized"
+	}
+	if err := db.DB.PingContext(ctx); err != nil {
+		return models.DoctorStatusFail, err.Error()
+	}
+	return models.DoctorStatusPass, fmt.Sprintf("connected to %s@%s:%s/%s",
+		config.AppConfig.DBUser, config.AppConfig.DBHost, config.AppConfig.DBPort, config.AppConfig.DBName)
+}
+
+func checkSchema(ctx context.Context) (string, string) {
+	state, missing, err := db.CheckSchema(ctx)
+	if err != nil {
+		return models.DoctorStatusFail, err.Error()
+	}
+	if len(missing) > 0 {
+		return models.DoctorStatusFail, fmt.Sprintf("%s, missing tables: %s", state, strings.Join(missing, ", "))
+	}
+	if err := db.CheckSchemaVersion(); err != nil {
+		return models.DoctorStatusFail, err.Error()
+	}
+	return models.DoctorStatusPass, state + " applied"
+}
+
+func checkKafka(ctx context.Context) (string, string) {
+	if strings.TrimSpace(config.AppConfig.KafkaBrokers) == "" {
+		return models.DoctorStatusSkip, "Kafka disabled (KAFKA_BROKERS is empty)"
+	}
+	missing, err := CheckKafkaBroker(ctx)
+	if err != nil {
+		return models.DoctorStatusFail, err.Error()
+	}
+	if len(missing) > 0 {
+		return models.DoctorStatusFail, "missing topics: " + strings.Join(missing, ", ")
+	}
+	return models.DoctorStatusPass, "broker reachable, all topics present"
+}
+
+func checkSMTP(ctx context.Context) (string, string) {
+	if !IsSMTPConfigured() {
+		return models.DoctorStatusSkip, "SMTP_USER/SMTP_PASS not set (emails are queued in the outbox)"
+	}
+
+	port, err := strconv.Atoi(config.AppConfig.SMTPPort)
+	if err != nil {
+		return models.DoctorStatusFail, fmt.Sprintf("invalid SMTP_PORT %q", config.AppConfig.SMTPPort)
+	}
+
+	dialer := gomail.NewDialer(config.AppConfig.SMTPHost, port, config.AppConfig.SMTPUser, config.AppConfig.SMTPPass)
+	conn, err := dialer.Dial()
+	if err != nil {
+		return models.DoctorStatusFail, fmt.Sprintf("login to %s:%d failed: %v", config.AppConfig.SMTPHost, port, err)
+	}
+	conn.Close()
+
+	return models.DoctorStatusPass, fmt.Sprintf("logged in to %s:%d as %s", config.AppConfig.SMTPHost, port, config.AppConfig.SMTPUser)
+}
+
+func checkRazorpay(ctx context.Context) (string, string) {
+	if config.AppConfig.RazorpayKeyID == "" || config.AppConfig.RazorpayKeySecret == "" {
+		return models.DoctorStatusFail, "razorpay credentials not configured (set RazorpayKeyID and RazorpayKeySecret)"
+	}
+
+	// Listing a single order is the cheapest authenticated call
+	client := razorpay.NewClient(config.AppConfig.RazorpayKeyID, config.AppConfig.RazorpayKeySecret)
+	if _, err := client.Order.All(map[string]interface{}{"count": 1}, nil); err != nil {
+		return models.DoctorStatusFail, fmt.Sprintf("test API call failed: %v", err)
+	}
+
+	detail := "key " + config.AppConfig.RazorpayKeyID + " accepted"
+	if config.AppConfig.RazorpayWebhookSecret == "" {
+		detail += " (RAZORPAY_WEBHOOK_SECRET not set)"
+	}
+	return models.DoctorStatusPass, detail
+}
+
+func checkStorage(ctx context.Context) (string, string) {
+	probe := []byte(time.Now().Format(time.RFC3339Nano))
+
+	storage := GetDocumentStorage()
+	if err := storage.Save(ctx, doctorProbeKey, bytes.NewReader(probe)); err != nil {
+		return models.DoctorStatusFail, fmt.Sprintf("write failed: %v", err)
+	}
+
+	r, err := storage.Open(ctx, doctorProbeKey)
+	if err != nil {
+		return models.DoctorStatusFail, fmt.Sprintf("read failed: %v", err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return models.DoctorStatusFail, fmt.Sprintf("read failed: %v", err)
+	}
+	if !bytes.Equal(data, probe) {
+		return models.DoctorStatusFail, "read back different content than written"
+	}
+
+	return models.DoctorStatusPass, "read/write OK in " + config.AppConfig.DocumentStorageDir
+}
+
+func checkEnrollmentSync(ctx context.Context) (string, string) {
+	connector, err := newEnrollmentConnector()
+	if err != nil {
+		return models.DoctorStatusFail, err.Error()
+	}
+	if connector == nil {
+		return models.DoctorStatusSkip, "ENROLLMENT_SYNC_MODE not set (enrolled students stay queued)"
+	}
+	// No test delivery: the LMS/ERP would create a record for it
+	if config.AppConfig.EnrollmentSyncMode == EnrollmentSyncModeREST {
+		return models.DoctorStatusPass, "posting enrollments to " + config.AppConfig.EnrollmentSyncURL
+	}
+	return models.DoctorStatusPass, "writing enrollments to document storage (enrollment-exports/)"
+}
+
+func checkTLS(ctx context.Context) (string, string) {
+	cfg := config.AppConfig
+	if domains := cfg.AutocertDomains(); len(domains) > 0 {
+		// Certificates are issued on the first handshake; only the cache can be checked here
+		if err := os.MkdirAll(cfg.TLSAutocertCacheDir, 0700); err != nil {
+			return models.DoctorStatusFail, fmt.Sprintf("certificate cache %s not writable: %v", cfg.TLSAutocertCacheDir, err)
+		}
+		return models.DoctorStatusPass, "Let's Encrypt certificates for " + strings.Join(domains, ", ")
+	}
+	if cfg.TLSCertFile == "" && cfg.TLSKeyFile == "" {
+		return models.DoctorStatusSkip, "TLS_CERT_FILE/TLS_AUTOCERT_DOMAINS not set (plain HTTP)"
+	}
+
+	pair, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+	if err != nil {
+		return models.DoctorStatusFail, err.Error()
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return models.DoctorStatusFail, err.Error()
+	}
+	if time.Now().After(cert.NotAfter) {
+		return models.DoctorStatusFail, "certificate expired on " + cert.NotAfter.Format(time.RFC3339)
+	}
+	return models.DoctorStatusPass, fmt.Sprintf("certificate for %s valid until %s", cert.Subject.CommonName, cert.NotAfter.Format(time.RFC3339))
+}
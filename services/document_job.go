@@ -0,0 +1,293 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"time"
+)
+
+var (
+	// ErrDocumentJobNotFound is returned when a document job does not exist
+	ErrDocumentJobNotFound = errors.New("document job not found")
+	// ErrDocumentNotReady is returned when downloading a job that has not completed
+	ErrDocumentNotReady = errors.New("document is not ready yet")
+	// ErrUnsupportedDocument is returned for unknown job types or formats
+	ErrUnsupportedDocument = errors.New("unsupported document type or format")
+)
+
+// documentGenerator renders a document for a job into w
+type documentGenerator func(ctx context.Context, job *models.DocumentJob, w io.Writer) error
+
+// documentGenerators lists the job types the worker can produce and their formats
+var documentGenerators = map[string]struct {
+	formats  []string
+	generate documentGenerator
+}{
+	models.DocumentJobTypeLeadExport: {[]string{ExportFormatXLSX, ExportFormatCSV}, generateLeadExport},
+	models.DocumentJobTypeCACReport:  {[]string{ExportFormatCSV}, generateCACReport},
+}
+
+// documentProcessingTimeout is how long a job may stay PROCESSING before another worker reclaims it
+const documentProcessingTimeout = 30 * time.Minute
+
+const documentJobColumns = `
+	id, job_type, format, params, status, COALESCE(requested_by, ''), COALESCE(notify_email, ''),
+	COALESCE(file_name, ''), COALESCE(storage_key, ''), COALESCE(error_message, ''), attempts,
+	created_at, started_at, completed_at`
+
+// scanDocumentJob reads a document_job row selected with documentJobColumns
+func scanDocumentJob(scanner interface{ Scan(...interface{}) error }) (*models.DocumentJob, error) {
+	var job models.DocumentJob
+	var params []byte
+	var startedAt, completedAt sql.NullTime
+
+	err := scanner.Scan(
+		&job.ID, &job.JobType, &job.Format, &params, &job.Status, &job.RequestedBy, &job.NotifyEmail,
+		&job.FileName, &job.StorageKey, &job.ErrorMessage, &job.Attempts,
+		&job.CreatedAt, &startedAt, &completedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	job.Params = params
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if completedAt.Valid {
+		job.CompletedAt = &completedAt.Time
+	}
+	if job.Status == models.DocumentJobStatusCompleted {
+		job.DownloadURL = documentDownloadURL(job.ID)
+	}
+
+	return &job, nil
+}
+
+// EnqueueDocumentJob validates and queues a document generation request
+func EnqueueDocumentJob(ctx context.Context, job *models.DocumentJob) error {
+	generator, ok := documentGenerators[job.JobType]
+	if !ok || !containsString(generator.formats, job.Format) {
+		return ErrUnsupportedDocument
+	}
+	if len(job.Params) == 0 {
+		job.Params = json.RawMessage(`{}`)
+	}
+
+	job.Status = models.DocumentJobStatusPending
+	err := db.DB.QueryRowContext(ctx, `
+		INSERT INTO document_job (job_type, format, params, status, requested_by, notify_email)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''))
+		RETURNING id, created_at`,
+		job.JobType, job.Format, string(job.Params), job.Status, job.RequestedBy, job.NotifyEmail,
+	).Scan(&job.ID, &job.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error queueing document job: %w", err)
+	}
+
+	return nil
+}
+
+// GetDocumentJob returns a document job by ID
+func GetDocumentJob(ctx context.Context, jobID int) (*models.DocumentJob, error) {
+	job, err := scanDocumentJob(db.DB.QueryRowContext(ctx,
+		`SELECT `+documentJobColumns+` FROM document_job WHERE id = $1`, jobID))
+	if err == sql.ErrNoRows {
+		return nil, ErrDocumentJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching document job: %w", err)
+	}
+	return job, nil
+}
+
+// OpenDocument returns the generated file of a completed job
+func OpenDocument(ctx context.Context, jobID int) (*models.DocumentJob, io.ReadCloser, error) {
+	job, err := GetDocumentJob(ctx, jobID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.Status != models.DocumentJobStatusCompleted {
+		return nil, nil, ErrDocumentNotReady
+	}
+
+	file, err := GetDocumentStorage().Open(ctx, job.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening document: %w", err)
+	}
+	return job, file, nil
+}
+
+// ProcessDocumentJobs drains the document_job outbox, generating queued documents in order
+func ProcessDocumentJobs(ctx context.Context) error {
+	for ctx.Err() == nil && processNextDocumentJob(ctx) {
+	}
+	return nil
+}
+
+// processNextDocumentJob claims and runs one queued job. Returns false when the queue is empty.
+func processNextDocumentJob(ctx context.Context) bool {
+	if db.DB == nil {
+		return false
+	}
+
+	job, err := scanDocumentJob(db.DB.QueryRowContext(ctx, `
+		UPDATE document_job
+		SET status = $1, started_at = NOW(), attempts = attempts + 1
+		WHERE id = (
+			SELECT id FROM document_job
+			WHERE status = $2 OR (status = $1 AND started_at < NOW() - make_interval(secs => $3))
+			ORDER BY created_at ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+documentJobColumns,
+		models.DocumentJobStatusProcessing, models.DocumentJobStatusPending, documentProcessingTimeout.Seconds()))
+	if err == sql.ErrNoRows {
+		return false
+	}
+	if err != nil {
+		logger.Error("Error claiming document job: %v", err)
+		return false
+	}
+
+	if err := runDocumentJob(ctx, job); err != nil {
+		logger.Error("Document job %d (%s) failed: %v", job.ID, job.JobType, err)
+		_, _ = db.DB.ExecContext(ctx,
+			"UPDATE document_job SET status = $1, error_message = $2, completed_at = NOW() WHERE id = $3",
+			models.DocumentJobStatusFailed, err.Error(), job.ID)
+		job.Status = models.DocumentJobStatusFailed
+		job.ErrorMessage = err.Error()
+	} else {
+		job.Status = models.DocumentJobStatusCompleted
+		logger.Info("Document job %d (%s) completed: %s", job.ID, job.JobType, job.FileName)
+	}
+
+	notifyDocumentRequester(job)
+	return true
+}
+
+// runDocumentJob generates the document, stores it and marks the job completed
+func runDocumentJob(ctx context.Context, job *models.DocumentJob) error {
+	generator, ok := documentGenerators[job.JobType]
+	if !ok {
+		return ErrUnsupportedDocument
+	}
+
+	var buf bytes.Buffer
+	if err := generator.generate(ctx, job, &buf); err != nil {
+		return err
+	}
+
+	job.FileName = fmt.Sprintf("%s_%d_%s.%s", job.JobType, job.ID, time.Now().Format("20060102_150405"), job.Format)
+	job.StorageKey = fmt.Sprintf("documents/%d/%s", job.ID, job.FileName)
+	if err := GetDocumentStorage().Save(ctx, job.StorageKey, &buf); err != nil {
+		return fmt.Errorf("error storing document: %w", err)
+	}
+
+	_, err := db.DB.ExecContext(ctx, `
+		UPDATE document_job
+		SET status = $1, file_name = $2, storage_key = $3, error_message = NULL, completed_at = NOW()
+		WHERE id = $4`,
+		models.DocumentJobStatusCompleted, job.FileName, job.StorageKey, job.ID)
+	if err != nil {
+		return fmt.Errorf("error completing document job: %w", err)
+	}
+	return nil
+}
+
+// notifyDocumentRequester emails the requester a download link (or the failure reason)
+func notifyDocumentRequester(job *models.DocumentJob) {
+	if job.NotifyEmail == "" {
+		return
+	}
+
+	var subject, body string
+	if job.Status == models.DocumentJobStatusCompleted {
+		link := documentDownloadURL(job.ID)
+		subject = fmt.Sprintf("Your %s is ready", job.JobType)
+		body = fmt.Sprintf(`<p>Your requested document <strong>%s</strong> has been generated.</p>
+<p><a href="%s">Download it here</a></p>`, html.EscapeString(job.FileName), html.EscapeString(link))
+	} else {
+		subject = fmt.Sprintf("Your %s could not be generated", job.JobType)
+		body = fmt.Sprintf(`<p>Document job #%d failed: %s</p><p>Please try again or contact support.</p>`,
+			job.ID, html.EscapeString(job.ErrorMessage))
+	}
+
+	if err := SendCategorizedEmail(EmailCategoryStaff, job.NotifyEmail, subject, body); err != nil {
+		logger.Error("Error notifying requester of document job %d: %v", job.ID, err)
+	}
+}
+
+func documentDownloadURL(jobID int) string {
+	return fmt.Sprintf("%s/documents/%d/download", config.AppConfig.AppBaseURL, jobID)
+}
+
+// generateLeadExport renders the lead export (params: created_after, created_before as RFC3339)
+func generateLeadExport(ctx context.Context, job *models.DocumentJob, w io.Writer) error {
+	var params struct {
+		CreatedAfter  *time.Time `json:"created_after"`
+		CreatedBefore *time.Time `json:"created_before"`
+	}
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return fmt.Errorf("invalid lead export params: %w", err)
+	}
+
+	leads, err := GetLeadExportRows(ctx, params.CreatedAfter, params.CreatedBefore)
+	if err != nil {
+		return err
+	}
+	return WriteLeadsExport(w, job.Format, leads)
+}
+
+// generateCACReport renders the CAC report (params: from, to as YYYY-MM-DD)
+func generateCACReport(ctx context.Context, job *models.DocumentJob, w io.Writer) error {
+	var params struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return fmt.Errorf("invalid CAC report params: %w", err)
+	}
+
+	var from, to *time.Time
+	if params.From != "" {
+		parsed, err := time.Parse(ReportDateLayout, params.From)
+		if err != nil {
+			return fmt.Errorf("invalid from date: %w", err)
+		}
+		from = &parsed
+	}
+	if params.To != "" {
+		parsed, err := time.Parse(ReportDateLayout, params.To)
+		if err != nil {
+			return fmt.Errorf("invalid to date: %w", err)
+		}
+		to = &parsed
+	}
+
+	report, err := GetCACReport(ctx, from, to)
+	if err != nil {
+		return err
+	}
+	return WriteCACReportCSV(w, report)
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"admission-module/models"
+	"context"
 	"fmt"
 	"log"
 	"time"
@@ -10,11 +12,18 @@ import (
 // Email will NOT be sent directly - instead it's queued via Kafka
 // Kafka Consumer will handle the actual email sending
 func SendEmail(to, subject, body string, attachment ...string) error {
+	return SendCategorizedEmail(EmailCategoryGeneral, to, subject, body, attachment...)
+}
+
+// SendCategorizedEmail queues an email like SendEmail, tagged with its category (one of
+// the EmailCategory constants) for the daily recipient cap
+func SendCategorizedEmail(category, to, subject, body string, attachment ...string) error {
 	log.Printf("Publishing email event to Kafka. Recipient: %s, Subject: %s", to, subject)
 
 	// Build email payload
 	emailPayload := map[string]interface{}{
 		"event":     "email.send",
+		"category":  category,
 		"recipient": to,
 		"subject":   subject,
 		"body":      body,
@@ -26,8 +35,9 @@ func SendEmail(to, subject, body string, attachment ...string) error {
 		emailPayload["attachment"] = attachment[0]
 	}
 
-	// Publish to Kafka emails topic
-	if err := Publish("emails", fmt.Sprintf("email-%s", to), emailPayload); err != nil {
+	// Publish to Kafka emails topic. Emails are mostly queued once a change is committed,
+	// often after the request that made it has been answered, so they are not tied to it.
+	if err := Publish(context.Background(), "emails", fmt.Sprintf("email-%s", to), emailPayload); err != nil {
 		log.Printf("Failed to publish email event to Kafka: %v", err)
 		return fmt.Errorf("failed to queue email: %w", err)
 	}
@@ -36,9 +46,17 @@ func SendEmail(to, subject, body string, attachment ...string) error {
 	return nil
 }
 
-// SendAcceptanceEmail sends acceptance email via Kafka
-func SendAcceptanceEmail(studentName, studentEmail, courseName string, courseFee float64) error {
-	emailBody := fmt.Sprintf(`
+// SendAcceptanceEmail sends acceptance email via Kafka, including the course's acceptance content blocks
+func SendAcceptanceEmail(studentName, studentEmail string, courseID int, courseName string, courseFee float64, paymentDeadline time.Time) error {
+	blocks := renderCourseContentBlocks(context.Background(), courseID, models.ContentBlockEmailAcceptance)
+	subject, body := buildAcceptanceEmail(studentName, courseName, courseFee, paymentDeadline, blocks)
+	return SendCategorizedEmail(EmailCategoryTransactional, studentEmail, subject, body)
+}
+
+// buildAcceptanceEmail renders the acceptance email asking for the course fee.
+// blocks is the rendered HTML of the course's acceptance content blocks.
+func buildAcceptanceEmail(studentName, courseName string, courseFee float64, paymentDeadline time.Time, blocks string) (subject, body string) {
+	body = fmt.Sprintf(`
 <!DOCTYPE html>
 <html>
 <head>
@@ -59,27 +77,68 @@ func SendAcceptanceEmail(studentName, studentEmail, courseName string, courseFee
             <div class="course-info">
                 <p><strong>Selected Course:</strong> %s</p>
                 <p><strong>Course Fee:</strong> ₹%.2f</p>
+                <p><strong>Pay By:</strong> %s</p>
             </div>
-            <p>To complete your admission, please proceed with the course fee payment.</p>
-            <p>Best regards,<br/>University Admissions Team</p>
+            <p>To complete your admission, please proceed with the course fee payment. If the fee is not paid by the date above, the offer will be withdrawn.</p>
+            %s<p>Best regards,<br/>University Admissions Team</p>
         </div>
     </div>
 </body>
 </html>
-	`, studentName, courseName, courseFee)
+	`, studentName, courseName, courseFee, paymentDeadline.Format("02 Jan 2006 15:04"), blocks)
 
-	subject := fmt.Sprintf("Congratulations %s - Your Application is Accepted!", studentName)
+	return fmt.Sprintf("Congratulations %s - Your Application is Accepted!", studentName), body
+}
 
-	if err := SendEmail(studentEmail, subject, emailBody); err != nil {
-		return err
+// buildEnrollmentEmail renders the enrollment confirmation sent once the course fee is paid.
+// blocks is the rendered HTML of the course's enrollment content blocks.
+func buildEnrollmentEmail(studentName, courseName, batch, blocks string) (subject, body string) {
+	batchLine := ""
+	if batch != "" {
+		batchLine = fmt.Sprintf("<p><strong>Batch:</strong> %s</p>", batch)
 	}
 
-	return nil
+	body = fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #4CAF50; color: white; padding: 20px; text-align: center; border-radius: 5px; }
+        .content { background-color: #f9f9f9; padding: 20px; margin-top: 20px; border-radius: 5px; }
+        .course-info { background-color: #e8f5e9; padding: 15px; margin: 15px 0; border-left: 4px solid #4CAF50; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header"><h2>Welcome Aboard!</h2></div>
+        <div class="content">
+            <p>Dear <strong>%s</strong>,</p>
+            <p>We have received your course fee. You are now <strong>ENROLLED</strong>.</p>
+            <div class="course-info">
+                <p><strong>Course:</strong> %s</p>
+                %s
+            </div>
+            %s<p>Best regards,<br/>University Admissions Team</p>
+        </div>
+    </div>
+</body>
+</html>
+	`, studentName, courseName, batchLine, blocks)
+
+	return fmt.Sprintf("Enrollment confirmed - %s", courseName), body
 }
 
 // SendRejectionEmail sends rejection email via Kafka
 func SendRejectionEmail(studentName, studentEmail string) error {
-	emailBody := fmt.Sprintf(`
+	subject, body := buildRejectionEmail(studentName)
+	return SendCategorizedEmail(EmailCategoryStatus, studentEmail, subject, body)
+}
+
+// buildRejectionEmail renders the application rejection email
+func buildRejectionEmail(studentName string) (subject, body string) {
+	body = fmt.Sprintf(`
 <!DOCTYPE html>
 <html>
 <head>
@@ -104,11 +163,185 @@ func SendRejectionEmail(studentName, studentEmail string) error {
 </html>
 	`, studentName)
 
-	subject := "Application Status - Rejection"
+	return "Application Status - Rejection", body
+}
+
+// SendWaitlistEmail tells a student they are on the waitlist of a course
+func SendWaitlistEmail(studentName, studentEmail, courseName string, position int) error {
+	subject, body := buildWaitlistEmail(studentName, courseName, position)
+	return SendCategorizedEmail(EmailCategoryStatus, studentEmail, subject, body)
+}
+
+// buildWaitlistEmail renders the waitlist email with the student's position
+func buildWaitlistEmail(studentName, courseName string, position int) (subject, body string) {
+	body = fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #FF9800; color: white; padding: 20px; text-align: center; border-radius: 5px; }
+        .content { background-color: #f9f9f9; padding: 20px; margin-top: 20px; border-radius: 5px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header"><h2>Application Status</h2></div>
+        <div class="content">
+            <p>Dear <strong>%s</strong>,</p>
+            <p>Your application for <strong>%s</strong> has been placed on the <strong>WAITLIST</strong> (position %d).</p>
+            <p>We will email you with an offer as soon as a seat becomes available.</p>
+            <p>Best regards,<br/>University Admissions Team</p>
+        </div>
+    </div>
+</body>
+</html>
+	`, studentName, courseName, position)
+
+	return fmt.Sprintf("Application Status - Waitlisted for %s", courseName), body
+}
 
-	if err := SendEmail(studentEmail, subject, emailBody); err != nil {
-		return err
+// SendOfferExpiredEmail tells a student their offer was withdrawn because the course fee was not paid in time
+func SendOfferExpiredEmail(studentName, studentEmail, courseName string, deadline time.Time) error {
+	subject, body := buildOfferExpiredEmail(studentName, courseName, deadline)
+	return SendCategorizedEmail(EmailCategoryStatus, studentEmail, subject, body)
+}
+
+// buildOfferExpiredEmail renders the email withdrawing an unpaid offer
+func buildOfferExpiredEmail(studentName, courseName string, deadline time.Time) (subject, body string) {
+	body = fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #f44336; color: white; padding: 20px; text-align: center; border-radius: 5px; }
+        .content { background-color: #f9f9f9; padding: 20px; margin-top: 20px; border-radius: 5px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header"><h2>Offer Expired</h2></div>
+        <div class="content">
+            <p>Dear <strong>%s</strong>,</p>
+            <p>Your offer for <strong>%s</strong> has been <strong>WITHDRAWN</strong> because the course fee was not paid by %s.</p>
+            <p>Please contact your counselor if you would still like to join.</p>
+            <p>Best regards,<br/>University Admissions Team</p>
+        </div>
+    </div>
+</body>
+</html>
+	`, studentName, courseName, deadline.Format("02 Jan 2006 15:04"))
+
+	return fmt.Sprintf("Your offer for %s has expired", courseName), body
+}
+
+// SendOfferExpiredCounselorEmail tells the counselor that a student's offer expired, and
+// which waitlisted student (if any) was offered the released seat
+func SendOfferExpiredCounselorEmail(counselorName, counselorEmail, studentName, courseName, promotedStudent string) error {
+	subject, body := buildOfferExpiredCounselorEmail(counselorName, studentName, courseName, promotedStudent)
+	return SendCategorizedEmail(EmailCategoryStaff, counselorEmail, subject, body)
+}
+
+// buildOfferExpiredCounselorEmail renders the counselor's notice of an expired offer
+func buildOfferExpiredCounselorEmail(counselorName, studentName, courseName, promotedStudent string) (subject, body string) {
+	seat := "No student was waiting for this course, so the seat is now free."
+	if promotedStudent != "" {
+		seat = fmt.Sprintf("The seat was offered to <strong>%s</strong> from the waitlist.", promotedStudent)
 	}
 
-	return nil
+	body = fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .content { background-color: #f9f9f9; padding: 20px; border-radius: 5px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="content">
+            <p>Dear <strong>%s</strong>,</p>
+            <p>The offer for <strong>%s</strong> (%s) has expired because the course fee was not paid in time.</p>
+            <p>%s</p>
+        </div>
+    </div>
+</body>
+</html>
+	`, counselorName, studentName, courseName, seat)
+
+	return fmt.Sprintf("Offer expired: %s (%s)", studentName, courseName), body
+}
+
+// buildRefundCounselorEmail renders the counselor's notice that a refund of their student's
+// payment was processed or failed
+func buildRefundCounselorEmail(counselorName, studentName, orderID, refundID string, amount float64, status string) (subject, body string) {
+	outcome := "has been <strong>processed</strong>; the money is on its way back to the student."
+	if status == RefundStatusFailed {
+		outcome = "has <strong>FAILED</strong> and the money was not returned. The payment counts as paid again; please let the student know and arrange the refund again if needed."
+	}
+
+	body = fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .content { background-color: #f9f9f9; padding: 20px; border-radius: 5px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="content">
+            <p>Dear <strong>%s</strong>,</p>
+            <p>The refund of ₹%.2f to <strong>%s</strong> (order %s, refund %s) %s</p>
+        </div>
+    </div>
+</body>
+</html>
+	`, counselorName, amount, studentName, orderID, refundID, outcome)
+
+	return fmt.Sprintf("Refund %s: %s", status, studentName), body
+}
+
+// buildDisputeCounselorEmail renders the counselor's notice of a chargeback dispute on
+// their student's payment
+func buildDisputeCounselorEmail(counselorName, studentName, orderID string, dispute disputeNotice) (subject, body string) {
+	respondBy := ""
+	if dispute.RespondBy != nil {
+		respondBy = fmt.Sprintf("<p>Evidence must reach Razorpay by <strong>%s</strong>.</p>", dispute.RespondBy.Format("02 Jan 2006 15:04"))
+	}
+	reason := ""
+	if dispute.ReasonCode != "" {
+		reason = fmt.Sprintf(" Reason: %s.", dispute.ReasonCode)
+	}
+
+	body = fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .content { background-color: #f9f9f9; padding: 20px; border-radius: 5px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="content">
+            <p>Dear <strong>%s</strong>,</p>
+            <p>The payment of <strong>%s</strong> (order %s) is disputed for ₹%.2f. Dispute %s is now <strong>%s</strong>.%s</p>
+            %s
+        </div>
+    </div>
+</body>
+</html>
+	`, counselorName, studentName, orderID, dispute.Amount, dispute.ID, dispute.Status, reason, respondBy)
+
+	return fmt.Sprintf("Payment dispute %s: %s", dispute.Status, studentName), body
 }
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Email outbox status constants
+const (
+	OutboxStatusPending = "PENDING"
+	OutboxStatusSent    = "SENT"
+)
+
+var (
+	emailChannelMutex   sync.Mutex
+	emailChannelEnabled bool
+)
+
+// InitEmailChannel detects whether SMTP is configured. When SMTP is missing the
+// email channel is marked disabled and emails are parked in the email_outbox table
+// until configuration appears and FlushEmailOutbox delivers them.
+func InitEmailChannel() {
+	enabled := IsSMTPConfigured()
+	setEmailChannelEnabled(enabled)
+
+	if enabled {
+		logger.Info("✓ Email channel enabled (SMTP configured)")
+	} else {
+		logger.Warn("Email channel disabled: SMTP_USER/SMTP_PASS not set. Emails will be queued in the outbox")
+	}
+}
+
+// IsEmailChannelEnabled returns true if emails can currently be delivered over SMTP
+func IsEmailChannelEnabled() bool {
+	emailChannelMutex.Lock()
+	defer emailChannelMutex.Unlock()
+	return emailChannelEnabled
+}
+
+func setEmailChannelEnabled(enabled bool) {
+	emailChannelMutex.Lock()
+	defer emailChannelMutex.Unlock()
+	emailChannelEnabled = enabled
+}
+
+// DeliverEmail sends an email over SMTP, or queues it in the outbox while the
+// email channel is disabled. Called by the Kafka consumer for email.send events.
+func DeliverEmail(to, subject, body string, attachment ...string) error {
+	if !IsEmailChannelEnabled() {
+		return QueueEmailInOutbox(to, subject, body, attachment...)
+	}
+	return SendEmailDirect(to, subject, body, attachment...)
+}
+
+// DeliverEmailEvent delivers the email of an email.send event (see DeliverCategorizedEmail).
+// Registered as the Kafka consumer's email processor. Delivery of an interview invite is
+// recorded on the lead, unless the email only went to the outbox.
+func DeliverEmailEvent(event map[string]interface{}) error {
+	recipient, ok := event["recipient"].(string)
+	if !ok || recipient == "" {
+		return fmt.Errorf("invalid recipient in email event")
+	}
+	subject, ok := event["subject"].(string)
+	if !ok || subject == "" {
+		return fmt.Errorf("invalid subject in email event")
+	}
+	body, ok := event["body"].(string)
+	if !ok || body == "" {
+		return fmt.Errorf("invalid body in email event")
+	}
+	var attachment []string
+	if att, ok := event["attachment"].(string); ok && att != "" {
+		attachment = append(attachment, att)
+	}
+	category, _ := event["category"].(string)
+
+	ctx := context.Background()
+	channelEnabled := IsEmailChannelEnabled()
+	if err := DeliverCategorizedEmail(ctx, category, recipient, subject, body, attachment...); err != nil {
+		return err
+	}
+
+	if purpose, _ := event["purpose"].(string); purpose == EmailPurposeInterviewInvite && channelEnabled {
+		if studentID, ok := event["student_id"].(float64); ok {
+			markInterviewInviteSent(ctx, int(studentID))
+		}
+	}
+	return nil
+}
+
+// QueueEmailInOutbox stores an email for later delivery
+func QueueEmailInOutbox(to, subject, body string, attachment ...string) error {
+	var att string
+	if len(attachment) > 0 {
+		att = attachment[0]
+	}
+
+	_, err := db.DB.Exec(
+		"INSERT INTO email_outbox (recipient, subject, body, attachment, status) VALUES ($1, $2, $3, $4, $5)",
+		to, subject, body, att, OutboxStatusPending)
+	if err != nil {
+		return fmt.Errorf("error queueing email in outbox: %w", err)
+	}
+
+	logger.Info("Email to %s queued in outbox (email channel disabled)", to)
+	return nil
+}
+
+// GetPendingOutboxCount returns the number of emails waiting in the outbox
+func GetPendingOutboxCount(ctx context.Context) (int, error) {
+	var count int
+	err := db.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM email_outbox WHERE status = $1", OutboxStatusPending).Scan(&count)
+	return count, err
+}
+
+// FlushEmailOutbox re-checks SMTP configuration and, once the email channel is
+// available, delivers pending outbox emails. Runs as a scheduled job.
+func FlushEmailOutbox(ctx context.Context) error {
+	refreshEmailChannel()
+	if IsEmailChannelEnabled() {
+		flushEmailOutbox(ctx)
+	}
+	return nil
+}
+
+// refreshEmailChannel re-reads the .env file and enables the email channel
+// as soon as SMTP credentials show up
+func refreshEmailChannel() {
+	if IsEmailChannelEnabled() {
+		return
+	}
+
+	config.ReloadEnv()
+	if IsSMTPConfigured() {
+		setEmailChannelEnabled(true)
+		logger.Info("✓ SMTP configuration detected, email channel enabled. Flushing outbox")
+	}
+}
+
+// flushEmailOutbox sends pending outbox emails in creation order
+func flushEmailOutbox(ctx context.Context) {
+	if db.DB == nil {
+		return
+	}
+
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT id, recipient, subject, body, COALESCE(attachment, '')
+		FROM email_outbox
+		WHERE status = $1
+		ORDER BY created_at ASC
+		LIMIT 50`, OutboxStatusPending)
+	if err != nil {
+		logger.Error("Error reading email outbox: %v", err)
+		return
+	}
+
+	type outboxEmail struct {
+		id                               int
+		recipient, subject, body, attach string
+	}
+	var pending []outboxEmail
+	for rows.Next() {
+		var e outboxEmail
+		if err := rows.Scan(&e.id, &e.recipient, &e.subject, &e.body, &e.attach); err != nil {
+			continue
+		}
+		pending = append(pending, e)
+	}
+	rows.Close()
+
+	for _, e := range pending {
+		var attachment []string
+		if e.attach != "" {
+			attachment = append(attachment, e.attach)
+		}
+
+		if err := SendEmailDirect(e.recipient, e.subject, e.body, attachment...); err != nil {
+			_, _ = db.DB.ExecContext(ctx,
+				"UPDATE email_outbox SET attempts = attempts + 1, last_error = $1 WHERE id = $2",
+				err.Error(), e.id)
+			continue
+		}
+
+		_, _ = db.DB.ExecContext(ctx,
+			"UPDATE email_outbox SET status = $1, attempts = attempts + 1, last_error = NULL, sent_at = NOW() WHERE id = $2",
+			OutboxStatusSent, e.id)
+	}
+}
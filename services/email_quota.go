@@ -0,0 +1,143 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/metrics"
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Email categories, carried as "category" on email.send events. The daily recipient cap
+// (EMAIL_DAILY_RECIPIENT_CAP) applies to the categories not listed in
+// EMAIL_QUOTA_EXEMPT_CATEGORIES, by default general and status emails.
+const (
+	EmailCategoryGeneral       = "general"       // welcome and other notices to students
+	EmailCategoryStatus        = "status"        // rejection, waitlist and expired offer notices
+	EmailCategoryTransactional = "transactional" // acceptance, payment links, enrollment, interview invites
+	EmailCategoryStaff         = "staff"         // counselors, interviewers, approvers, report recipients
+)
+
+// emailQuotaRetentionDays is how long daily email counts and overflow records are kept
+// by default
+const emailQuotaRetentionDays = 30
+
+// DeliverCategorizedEmail delivers an email (see DeliverEmail) unless its category is
+// capped and the recipient already got EMAIL_DAILY_RECIPIENT_CAP such emails today. A
+// dropped email is recorded in email_overflow, logged and counted in
+// admission_email_quota_overflow_total, and is not an error, so the event is not retried.
+func DeliverCategorizedEmail(ctx context.Context, category, to, subject, body string, attachment ...string) error {
+	if category == "" {
+		category = EmailCategoryGeneral
+	}
+	if !config.EmailQuotaExempt(category) && db.DB != nil {
+		allowed, err := reserveEmailQuota(ctx, to)
+		if err != nil {
+			// The cap guards against storms; it must not stop email when its table is unavailable
+			logger.Warn("Email quota check failed for %s, sending anyway: %v", to, err)
+		} else if !allowed {
+			recordEmailOverflow(ctx, category, to, subject)
+			return nil
+		}
+	}
+	return DeliverEmail(to, subject, body, attachment...)
+}
+
+// reserveEmailQuota counts one more email to recipient today, reporting false (and not
+// counting it) once the cap is reached
+func reserveEmailQuota(ctx context.Context, recipient string) (bool, error) {
+	var sent int
+	err := db.DB.QueryRowContext(ctx, `
+		INSERT INTO email_recipient_quota (recipient, quota_date, sent_count)
+		VALUES ($1, CURRENT_DATE, 1)
+		ON CONFLICT (recipient, quota_date) DO UPDATE SET sent_count = email_recipient_quota.sent_count + 1
+		WHERE email_recipient_quota.sent_count < $2
+		RETURNING sent_count`,
+		normalizeEmailRecipient(recipient), config.AppConfig.EmailDailyRecipientCap).Scan(&sent)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error updating email quota: %w", err)
+	}
+	return true, nil
+}
+
+func recordEmailOverflow(ctx context.Context, category, recipient, subject string) {
+	metrics.ObserveEmailQuotaOverflow(category)
+
+	var dropped int
+	err := db.DB.QueryRowContext(ctx, `
+		WITH dropped AS (
+			INSERT INTO email_overflow (recipient, category, subject) VALUES ($1, $2, $3)
+		)
+		SELECT COUNT(*) + 1 FROM email_overflow WHERE recipient = $1 AND created_at >= CURRENT_DATE`,
+		normalizeEmailRecipient(recipient), category, subject).Scan(&dropped)
+	if err != nil {
+		logger.Error("Error recording email overflow for %s: %v", recipient, err)
+	}
+	logger.Warn("Daily email cap (%d) reached for %s: dropped %s email %q (%d dropped today)",
+		config.AppConfig.EmailDailyRecipientCap, recipient, category, subject, dropped)
+}
+
+// ListEmailOverflow returns the recipients that hit the daily cap since since, per day,
+// most recent first
+func ListEmailOverflow(ctx context.Context, since time.Time, limit int) ([]models.EmailOverflow, error) {
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT o.recipient, TO_CHAR(o.created_at::date, 'YYYY-MM-DD'), COALESCE(q.sent_count, 0), COUNT(*),
+			ARRAY_AGG(DISTINCT o.category), (ARRAY_AGG(o.subject ORDER BY o.created_at DESC))[1], MAX(o.created_at)
+		FROM email_overflow o
+		LEFT JOIN email_recipient_quota q ON q.recipient = o.recipient AND q.quota_date = o.created_at::date
+		WHERE o.created_at >= $1
+		GROUP BY o.recipient, o.created_at::date, q.sent_count
+		ORDER BY MAX(o.created_at) DESC
+		LIMIT $2`, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching email overflow: %w", err)
+	}
+	defer rows.Close()
+
+	overflow := []models.EmailOverflow{}
+	for rows.Next() {
+		var o models.EmailOverflow
+		if err := rows.Scan(&o.Recipient, &o.Date, &o.SentCount, &o.DroppedCount,
+			pq.Array(&o.Categories), &o.LastSubject, &o.LastDroppedAt); err != nil {
+			return nil, fmt.Errorf("error scanning email overflow: %w", err)
+		}
+		overflow = append(overflow, o)
+	}
+	return overflow, rows.Err()
+}
+
+// purgeEmailQuota is a retention policy deleting daily email counts and overflow records
+// older than cutoff
+func purgeEmailQuota(ctx context.Context, cutoff time.Time, dryRun bool) (int, int, error) {
+	if dryRun {
+		return countRows(ctx, `
+			SELECT (SELECT COUNT(*) FROM email_recipient_quota WHERE quota_date < $1::date)
+				+ (SELECT COUNT(*) FROM email_overflow WHERE created_at < $1)`, cutoff)
+	}
+	counts, err := db.DB.ExecContext(ctx, "DELETE FROM email_recipient_quota WHERE quota_date < $1::date", cutoff)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error purging email quota: %w", err)
+	}
+	overflow, err := db.DB.ExecContext(ctx, "DELETE FROM email_overflow WHERE created_at < $1", cutoff)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error purging email overflow: %w", err)
+	}
+	purgedCounts, _ := counts.RowsAffected()
+	purgedOverflow, _ := overflow.RowsAffected()
+	return int(purgedCounts + purgedOverflow), 0, nil
+}
+
+func normalizeEmailRecipient(recipient string) string {
+	return strings.ToLower(strings.TrimSpace(recipient))
+}
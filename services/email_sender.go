@@ -1,6 +1,7 @@
 package services
 
 import (
+	"admission-module/metrics"
 	"fmt"
 	"log"
 	"os"
@@ -56,6 +57,7 @@ func SendEmailDirect(to, subject, body string, attachment ...string) error {
 	d := gomail.NewDialer(host, port, smtpUser, smtpPass)
 
 	err := d.DialAndSend(m)
+	metrics.ObserveEmailSend(err)
 	if err != nil {
 		log.Printf("❌ Failed to send email to %s: %v", to, err)
 		return fmt.Errorf("failed to send email: %w", err)
@@ -64,3 +66,9 @@ func SendEmailDirect(to, subject, body string, attachment ...string) error {
 	log.Printf("✅ Email successfully sent to: %s", to)
 	return nil
 }
+
+// IsSMTPConfigured reports whether SMTP credentials are present in the environment.
+// The sender falls back to SMTP_USER, so credentials alone are enough to send.
+func IsSMTPConfigured() bool {
+	return os.Getenv("SMTP_USER") != "" && os.Getenv("SMTP_PASS") != ""
+}
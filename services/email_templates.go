@@ -0,0 +1,406 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// emailTemplate renders one email with sample data. Each sample value must appear in
+// the rendered subject or body, so a variable dropped by a template edit is caught.
+type emailTemplate struct {
+	name    string
+	samples []string
+	render  func() (subject, body string)
+}
+
+// emailTemplateSample is the data the templates are rendered with
+type emailTemplateSample struct {
+	courseName string
+	courseFee  float64
+	blocks     map[string]string // rendered content blocks by email type
+}
+
+// Sample values, distinct enough to be found in the rendered emails
+const (
+	sampleStudentName   = "Sample Student"
+	sampleStudentEmail  = "sample.student@example.com"
+	sampleStudentPhone  = "+91 90000 00001"
+	sampleCounselorName = "Sample Counselor"
+	sampleCounselorMail = "sample.counselor@example.com"
+	sampleCounselorTel  = "+91 90000 00002"
+	sampleCourseName    = "Sample Course"
+	sampleOrderID       = "order_SAMPLE123"
+	sampleInvoiceNo     = "INV/2026-27/000042"
+	sampleMeetLink      = "https://meet.google.com/sample-meet"
+)
+
+var (
+	// %!s(MISSING), %!d(string=x), %!(EXTRA int=1): fmt's markers for mismatched verbs
+	brokenVariablePattern = regexp.MustCompile(`%!\w*\([^)]*\)`)
+	// Template placeholders that were never substituted
+	unresolvedTokenPattern = regexp.MustCompile(`\{\{[^}]*\}\}|\$\{[^}]*\}|<no value>|%(?:\.\d+)?[sdvfq]\b`)
+	htmlTagPattern         = regexp.MustCompile(`<(/?)([a-zA-Z][a-zA-Z0-9]*)\b[^>]*>`)
+	htmlCommentPattern     = regexp.MustCompile(`(?s)<!--.*?-->`)
+)
+
+// htmlVoidElements never have a closing tag
+var htmlVoidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// CheckEmailTemplates renders every email template with sample data and reports broken
+// variables, unresolved tokens, unbalanced HTML and content block email types without a
+// template. With a courseID the acceptance and enrollment emails are rendered with that
+// course's name, fee and active content blocks instead of samples.
+func CheckEmailTemplates(ctx context.Context, courseID int) (*models.EmailTemplateReport, error) {
+	sample := emailTemplateSample{
+		courseName: sampleCourseName,
+		courseFee:  125000,
+		blocks:     map[string]string{},
+	}
+	if courseID > 0 {
+		err := db.DB.QueryRowContext(ctx, "SELECT name, fee FROM course WHERE id = $1", courseID).
+			Scan(&sample.courseName, &sample.courseFee)
+		if err == sql.ErrNoRows {
+			return nil, ErrCourseNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error loading course: %w", err)
+		}
+		for _, emailType := range []string{models.ContentBlockEmailAcceptance, models.ContentBlockEmailEnrollment} {
+			sample.blocks[emailType] = renderCourseContentBlocks(ctx, courseID, emailType)
+		}
+	} else {
+		var sb strings.Builder
+		writeContentBlock(&sb, "Orientation", "Orientation starts on the first Monday of the batch.\nBring your ID.")
+		sample.blocks[models.ContentBlockEmailAcceptance] = sb.String()
+		sample.blocks[models.ContentBlockEmailEnrollment] = sb.String()
+	}
+
+	report := &models.EmailTemplateReport{CheckedAt: time.Now().UTC(), CourseID: courseID}
+	templates := emailTemplates(sample)
+	registered := make(map[string]bool, len(templates))
+	for _, tmpl := range templates {
+		registered[tmpl.name] = true
+		subject, body := tmpl.render()
+		check := models.EmailTemplateCheck{
+			Name:      tmpl.name,
+			Subject:   subject,
+			BodyBytes: len(body),
+			Problems:  validateEmail(subject, body, tmpl.samples),
+		}
+		report.Templates = append(report.Templates, check)
+	}
+
+	// Content blocks are edited per email type, so each type needs an email to land in
+	for _, emailType := range []string{models.ContentBlockEmailAcceptance, models.ContentBlockEmailEnrollment} {
+		if !registered[emailType] {
+			report.Templates = append(report.Templates, models.EmailTemplateCheck{
+				Name:     emailType,
+				Problems: []string{"missing template for content block email type " + emailType},
+			})
+		}
+	}
+
+	for i := range report.Templates {
+		report.Templates[i].OK = len(report.Templates[i].Problems) == 0
+		if !report.Templates[i].OK {
+			report.Failed++
+		}
+	}
+	report.Checked = len(report.Templates)
+	return report, nil
+}
+
+// emailTemplates lists every email the service sends, rendered with the sample data
+func emailTemplates(sample emailTemplateSample) []emailTemplate {
+	now := time.Now()
+	deadline := now.AddDate(0, 0, 14)
+	interviewer := &models.Interviewer{Name: "Sample Interviewer", Email: "sample.interviewer@example.com"}
+	fee := fmt.Sprintf("%.2f", sample.courseFee)
+
+	return []emailTemplate{
+		{
+			name:    models.ContentBlockEmailAcceptance,
+			samples: []string{sampleStudentName, sample.courseName, fee},
+			render: func() (string, string) {
+				return buildAcceptanceEmail(sampleStudentName, sample.courseName, sample.courseFee, deadline,
+					sample.blocks[models.ContentBlockEmailAcceptance])
+			},
+		},
+		{
+			name:    models.ContentBlockEmailEnrollment,
+			samples: []string{sampleStudentName, sample.courseName, "Batch 2026-A"},
+			render: func() (string, string) {
+				return buildEnrollmentEmail(sampleStudentName, sample.courseName, "Batch 2026-A",
+					sample.blocks[models.ContentBlockEmailEnrollment])
+			},
+		},
+		{
+			name:    "rejection",
+			samples: []string{sampleStudentName},
+			render:  func() (string, string) { return buildRejectionEmail(sampleStudentName) },
+		},
+		{
+			name:    "waitlist",
+			samples: []string{sampleStudentName, sample.courseName, "position 3"},
+			render:  func() (string, string) { return buildWaitlistEmail(sampleStudentName, sample.courseName, 3) },
+		},
+		{
+			name:    "offer_expired",
+			samples: []string{sampleStudentName, sample.courseName},
+			render: func() (string, string) {
+				return buildOfferExpiredEmail(sampleStudentName, sample.courseName, deadline)
+			},
+		},
+		{
+			name:    "offer_expired_counselor",
+			samples: []string{sampleCounselorName, sampleStudentName, sample.courseName, "Waitlisted Student"},
+			render: func() (string, string) {
+				return buildOfferExpiredCounselorEmail(sampleCounselorName, sampleStudentName, sample.courseName, "Waitlisted Student")
+			},
+		},
+		{
+			name:    "refund_counselor",
+			samples: []string{sampleCounselorName, sampleStudentName, sampleOrderID, "rfnd_SAMPLE123"},
+			render: func() (string, string) {
+				return buildRefundCounselorEmail(sampleCounselorName, sampleStudentName, sampleOrderID, "rfnd_SAMPLE123", 1870, RefundStatusFailed)
+			},
+		},
+		{
+			name:    "dispute_counselor",
+			samples: []string{sampleCounselorName, sampleStudentName, sampleOrderID, "disp_SAMPLE123", "service_not_provided"},
+			render: func() (string, string) {
+				respondBy := deadline
+				return buildDisputeCounselorEmail(sampleCounselorName, sampleStudentName, sampleOrderID, disputeNotice{
+					ID: "disp_SAMPLE123", Status: DisputeStatusOpen, ReasonCode: "service_not_provided", Amount: sample.courseFee, RespondBy: &respondBy,
+				})
+			},
+		},
+		{
+			name:    "counselor_assignment",
+			samples: []string{sampleStudentName, sampleCounselorName, sampleCounselorMail, sampleCounselorTel},
+			render: func() (string, string) {
+				return buildCounselorAssignmentEmail(sampleStudentName, sampleCounselorName, sampleCounselorMail, sampleCounselorTel)
+			},
+		},
+		{
+			name:    "lead_assignment",
+			samples: []string{sampleCounselorName, sampleStudentName, sampleStudentPhone, sampleStudentEmail, "Website"},
+			render: func() (string, string) {
+				return buildCounselorLeadAssignmentEmail(sampleCounselorName, sampleStudentName, sampleStudentPhone, sampleStudentEmail, "Website")
+			},
+		},
+		{
+			name:    "mention",
+			samples: []string{sampleCounselorName, "Mentioning Counselor", sampleStudentName, "Please call back"},
+			render: func() (string, string) {
+				return buildMentionEmail(sampleCounselorName, "Mentioning Counselor", sampleStudentName, "Please call back tomorrow")
+			},
+		},
+		{
+			name:    "payment_link",
+			samples: []string{sampleStudentName, sampleOrderID, fee, sampleInvoiceNo},
+			render: func() (string, string) {
+				return buildPaymentLinkEmail(sampleStudentName, &PaymentLinkResendResult{
+					OrderID:       sampleOrderID,
+					PaymentType:   PaymentTypeCourseFee,
+					Amount:        sample.courseFee,
+					PaymentLink:   "https://pay.example.com/?order_id=" + sampleOrderID,
+					InvoiceNumber: sampleInvoiceNo,
+				})
+			},
+		},
+		{
+			name:    "razorpay_payment_link",
+			samples: []string{sampleStudentName, fee, "https://rzp.io/i/SAMPLE123"},
+			render: func() (string, string) {
+				return buildRazorpayPaymentLinkEmail(sampleStudentName, &models.RazorpayPaymentLink{
+					PaymentType: PaymentTypeCourseFee,
+					Amount:      sample.courseFee,
+					ShortURL:    "https://rzp.io/i/SAMPLE123",
+					ExpiresAt:   &deadline,
+				})
+			},
+		},
+		{
+			name:    "payment_receipt",
+			samples: []string{sampleStudentName, sampleOrderID, "RCPT/2026-27/000042"},
+			render: func() (string, string) {
+				return buildPaymentReceiptEmail(sampleStudentName, &models.PaymentReceipt{
+					ReceiptNumber: "RCPT/2026-27/000042",
+					OrderID:       sampleOrderID,
+					PaymentType:   PaymentTypeRegistration,
+					TotalAmount:   RegistrationFee,
+					DownloadURL:   "https://admissions.example.com/payments/" + sampleOrderID + "/receipt",
+				})
+			},
+		},
+		{
+			name:    "invoice",
+			samples: []string{sampleInvoiceNo, sampleStudentName, sample.courseName, sampleOrderID, fee},
+			render: func() (string, string) {
+				taxable, cgst, sgst, _ := gstBreakdown(sample.courseFee, 18, false)
+				invoice := &models.Invoice{
+					InvoiceNumber: sampleInvoiceNo, StudentID: 42, StudentName: sampleStudentName,
+					StudentEmail: sampleStudentEmail, StudentPhone: sampleStudentPhone, CourseName: sample.courseName,
+					OrderID: sampleOrderID, InstallmentNo: 1, InstallmentCount: 1, TaxRate: 18,
+					TaxableAmount: taxable, CGSTAmount: cgst, SGSTAmount: sgst, TotalAmount: sample.courseFee,
+					Status: models.InvoiceStatusIssued, IssuedAt: now,
+				}
+				return "Invoice " + invoice.InvoiceNumber, buildInvoiceDocument(invoice)
+			},
+		},
+		{
+			name:    "approval_request",
+			samples: []string{"first.approver@example.com", sampleStudentName, sample.courseName, fee},
+			render: func() (string, string) {
+				return buildApprovalRequestEmail(&AcceptApplicationResult{
+					StudentID:   42,
+					StudentName: sampleStudentName,
+					CourseName:  sample.courseName,
+					CourseFee:   sample.courseFee,
+					RequestedBy: "first.approver@example.com",
+				})
+			},
+		},
+		{
+			name:    "meeting_scheduled",
+			samples: []string{interviewer.Name, sampleMeetLink},
+			render: func() (string, string) {
+				return buildMeetingScheduledEmail(interviewer, sampleMeetLink, now, now.Add(time.Hour))
+			},
+		},
+		{
+			name:    "interviewer_invite",
+			samples: []string{interviewer.Name, sampleStudentName, "B.Tech", sampleMeetLink},
+			render: func() (string, string) {
+				return buildInterviewerInviteEmail(interviewer.Name, sampleStudentName, "B.Tech", 42, sampleMeetLink, now)
+			},
+		},
+		{
+			name:    "follow_up_digest",
+			samples: []string{sampleCounselorName, sampleStudentName, sampleStudentEmail},
+			render: func() (string, string) {
+				digest := &counselorDigest{name: sampleCounselorName, email: sampleCounselorMail, leads: []staleLead{{
+					id: 42, name: sampleStudentName, email: sampleStudentEmail,
+					applicationStatus: ApplicationStatusInterviewScheduled, lastActivityAt: now.AddDate(0, 0, -8),
+				}}}
+				return fmt.Sprintf("Follow-up reminder: %d leads need your attention", len(digest.leads)),
+					buildFollowUpDigestBody(digest, 7)
+			},
+		},
+		{
+			name:    "manager_summary",
+			samples: []string{"Website", "1870.00", fee},
+			render: func() (string, string) {
+				summary := &models.ManagerSummary{
+					Period:               models.SummaryPeriodWeekly,
+					From:                 now.AddDate(0, 0, -7),
+					To:                   now,
+					NewLeads:             5,
+					NewLeadsBySource:     []models.SourceCount{{LeadSource: "Website", Count: 5}},
+					RegistrationPayments: 1,
+					RegistrationAmount:   1870,
+					CoursePayments:       1,
+					CourseAmount:         sample.courseFee,
+				}
+				return fmt.Sprintf("Admissions %s summary: %s", summary.Period, summaryRange(summary)),
+					buildManagerSummaryBody(summary)
+			},
+		},
+		{
+			name:    "admin_daily_summary",
+			samples: []string{"1870.00", fee, "Quarantined"},
+			render: func() (string, string) {
+				return buildAdminDailySummaryEmail(&models.AdminDailySummary{
+					ManagerSummary: models.ManagerSummary{
+						Period:               models.SummaryPeriodDaily,
+						From:                 now.Truncate(24 * time.Hour),
+						To:                   now,
+						NewLeads:             5,
+						RegistrationPayments: 1,
+						RegistrationAmount:   1870,
+						CoursePayments:       1,
+						CourseAmount:         sample.courseFee,
+					},
+					Acceptances:            2,
+					NewDLQMessages:         1,
+					UnresolvedDLQMessages:  3,
+					QuarantinedDLQMessages: 1,
+				})
+			},
+		},
+	}
+}
+
+// validateEmail returns the problems found in a rendered email
+func validateEmail(subject, body string, samples []string) []string {
+	var problems []string
+	if strings.TrimSpace(subject) == "" {
+		problems = append(problems, "empty subject")
+	}
+	if strings.ContainsAny(subject, "\r\n") {
+		problems = append(problems, "subject contains a line break")
+	}
+	if strings.TrimSpace(body) == "" {
+		return append(problems, "empty body")
+	}
+
+	text := subject + "\n" + body
+	for _, match := range brokenVariablePattern.FindAllString(text, -1) {
+		problems = append(problems, "broken variable: "+match)
+	}
+	for _, match := range unresolvedTokenPattern.FindAllString(text, -1) {
+		problems = append(problems, "unresolved token: "+match)
+	}
+	for _, value := range samples {
+		if !strings.Contains(text, value) && !strings.Contains(text, html.EscapeString(value)) {
+			problems = append(problems, fmt.Sprintf("sample value %q is not rendered", value))
+		}
+	}
+	return append(problems, validateHTML(body)...)
+}
+
+// validateHTML checks that every opened element is closed, in order
+func validateHTML(body string) []string {
+	var problems []string
+	var open []string
+	for _, m := range htmlTagPattern.FindAllStringSubmatch(htmlCommentPattern.ReplaceAllString(body, ""), -1) {
+		closing, tag := m[1] == "/", strings.ToLower(m[2])
+		if htmlVoidElements[tag] {
+			continue
+		}
+		if !closing {
+			if !strings.HasSuffix(m[0], "/>") {
+				open = append(open, tag)
+			}
+			continue
+		}
+
+		i := len(open) - 1
+		for i >= 0 && open[i] != tag {
+			i--
+		}
+		if i < 0 {
+			problems = append(problems, fmt.Sprintf("unexpected </%s>", tag))
+			continue
+		}
+		for _, unclosed := range open[i+1:] {
+			problems = append(problems, fmt.Sprintf("<%s> not closed before </%s>", unclosed, tag))
+		}
+		open = open[:i]
+	}
+	for _, unclosed := range open {
+		problems = append(problems, fmt.Sprintf("<%s> is never closed", unclosed))
+	}
+	return problems
+}
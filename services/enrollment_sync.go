@@ -0,0 +1,370 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"admission-module/utils"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// enrollmentSyncBatchSize is how many due enrollments one run of the sync job delivers
+	enrollmentSyncBatchSize = 20
+	// enrollmentSyncBaseBackoff is the wait after the first failed attempt; it doubles per attempt
+	enrollmentSyncBaseBackoff = time.Minute
+	// enrollmentSyncMaxBackoff caps the wait between attempts
+	enrollmentSyncMaxBackoff = 6 * time.Hour
+	// enrollmentSyncTimeout bounds one delivery to the LMS/ERP
+	enrollmentSyncTimeout = 15 * time.Second
+)
+
+// Enrollment sync modes (ENROLLMENT_SYNC_MODE)
+const (
+	EnrollmentSyncModeREST = "rest"
+	EnrollmentSyncModeFile = "file"
+)
+
+// ErrEnrollmentSyncNotFound is returned when a student has no enrollment handoff
+var ErrEnrollmentSyncNotFound = errors.New("enrollment sync not found")
+
+// enrollmentConnector delivers an enrollment record to the LMS/ERP and returns its id there
+type enrollmentConnector interface {
+	Push(ctx context.Context, record *models.EnrollmentRecord) (string, error)
+}
+
+// newEnrollmentConnector returns the connector for ENROLLMENT_SYNC_MODE, or nil when the handoff is disabled
+func newEnrollmentConnector() (enrollmentConnector, error) {
+	cfg := config.AppConfig
+	switch cfg.EnrollmentSyncMode {
+	case "":
+		return nil, nil
+	case EnrollmentSyncModeREST:
+		if cfg.EnrollmentSyncURL == "" {
+			return nil, fmt.Errorf("ENROLLMENT_SYNC_URL is required when ENROLLMENT_SYNC_MODE=rest")
+		}
+		return &restEnrollmentConnector{
+			url:    cfg.EnrollmentSyncURL,
+			token:  cfg.EnrollmentSyncToken,
+			client: &http.Client{Timeout: enrollmentSyncTimeout},
+		}, nil
+	case EnrollmentSyncModeFile:
+		return fileEnrollmentConnector{}, nil
+	default:
+		return nil, fmt.Errorf("unknown ENROLLMENT_SYNC_MODE %q (use rest or file)", cfg.EnrollmentSyncMode)
+	}
+}
+
+// restEnrollmentConnector POSTs records as JSON to the LMS/ERP
+type restEnrollmentConnector struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func (c *restEnrollmentConnector) Push(ctx context.Context, record *models.EnrollmentRecord) (string, error) {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Lets the receiver drop a record delivered twice (e.g. a retry after a lost response)
+	req.Header.Set("Idempotency-Key", fmt.Sprintf("enrollment-%d-%d", record.Student.ID, record.Course.ID))
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("LMS/ERP responded %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+	}
+
+	// The id is optional: receivers answering without a JSON body still count as synced
+	var created struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if json.Unmarshal(respBody, &created) == nil && len(created.ID) > 0 {
+		var id string
+		if json.Unmarshal(created.ID, &id) != nil {
+			id = string(created.ID)
+		}
+		return id, nil
+	}
+	return "", nil
+}
+
+// fileEnrollmentConnector writes each record as a JSON file to document storage, for
+// LMS/ERP systems that pick up exports instead of exposing an API
+type fileEnrollmentConnector struct{}
+
+func (fileEnrollmentConnector) Push(ctx context.Context, record *models.EnrollmentRecord) (string, error) {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	key := fmt.Sprintf("enrollment-exports/%s/student-%d.json", record.EnrolledAt.UTC().Format("2006-01-02"), record.Student.ID)
+	if err := GetDocumentStorage().Save(ctx, key, bytes.NewReader(data)); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// queueEnrollmentSync queues the handoff of the student who paid the course fee of orderID,
+// within the payment's transaction. A student already synced for the same course is left alone.
+func queueEnrollmentSync(ctx context.Context, tx *sql.Tx, orderID string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO enrollment_sync (student_id, course_id)
+		SELECT student_id, course_id FROM course_payment WHERE order_id = $1
+		ON CONFLICT (student_id) DO UPDATE
+		SET course_id = EXCLUDED.course_id, status = $2, attempts = 0, last_error = NULL,
+			next_attempt_at = NOW(), updated_at = NOW()
+		WHERE enrollment_sync.status <> $3 OR enrollment_sync.course_id <> EXCLUDED.course_id`,
+		orderID, models.EnrollmentSyncPending, models.EnrollmentSyncSynced)
+	if err != nil {
+		return fmt.Errorf("error queueing enrollment sync: %w", err)
+	}
+	return nil
+}
+
+// SyncEnrollments delivers due enrollment handoffs to the LMS/ERP. Failed deliveries are
+// retried with exponential backoff until ENROLLMENT_SYNC_MAX_ATTEMPTS, then marked FAILED.
+// Runs as a scheduled job; does nothing while ENROLLMENT_SYNC_MODE is unset.
+func SyncEnrollments(ctx context.Context) error {
+	if db.DB == nil {
+		return nil
+	}
+	connector, err := newEnrollmentConnector()
+	if err != nil {
+		return err
+	}
+	if connector == nil {
+		return nil
+	}
+
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT student_id FROM enrollment_sync
+		WHERE status = $1 AND next_attempt_at <= NOW()
+		ORDER BY next_attempt_at ASC
+		LIMIT $2`, models.EnrollmentSyncPending, enrollmentSyncBatchSize)
+	if err != nil {
+		return fmt.Errorf("error finding due enrollment syncs: %w", err)
+	}
+	var studentIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("error reading due enrollment syncs: %w", err)
+		}
+		studentIDs = append(studentIDs, id)
+	}
+	rows.Close()
+
+	for _, studentID := range studentIDs {
+		if ctx.Err() != nil {
+			break
+		}
+		if err := syncEnrollment(ctx, connector, studentID); err != nil {
+			logger.Error("Error syncing enrollment of student %d: %v", studentID, err)
+		}
+	}
+	return nil
+}
+
+// syncEnrollment claims one due handoff, delivers it and records the outcome
+func syncEnrollment(ctx context.Context, connector enrollmentConnector, studentID int) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the row so two instances never deliver the same student concurrently
+	var courseID, attempts int
+	err = tx.QueryRowContext(ctx, `
+		SELECT course_id, attempts FROM enrollment_sync
+		WHERE student_id = $1 AND status = $2 AND next_attempt_at <= NOW()
+		FOR UPDATE SKIP LOCKED`, studentID, models.EnrollmentSyncPending).Scan(&courseID, &attempts)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error locking enrollment sync: %w", err)
+	}
+
+	record, err := buildEnrollmentRecord(ctx, tx, studentID, courseID)
+	var externalID string
+	if err == nil {
+		pushCtx, cancel := context.WithTimeout(ctx, enrollmentSyncTimeout)
+		externalID, err = connector.Push(pushCtx, record)
+		cancel()
+	}
+
+	attempts++
+	if err != nil {
+		status := models.EnrollmentSyncPending
+		if attempts >= config.AppConfig.EnrollmentSyncMaxAttempts {
+			status = models.EnrollmentSyncFailed
+		}
+		backoff := enrollmentSyncBaseBackoff << min(attempts-1, 16)
+		if backoff > enrollmentSyncMaxBackoff {
+			backoff = enrollmentSyncMaxBackoff
+		}
+		if _, updateErr := tx.ExecContext(ctx, `
+			UPDATE enrollment_sync
+			SET status = $1, attempts = $2, last_error = $3, next_attempt_at = NOW() + make_interval(secs => $4), updated_at = NOW()
+			WHERE student_id = $5`,
+			status, attempts, err.Error(), backoff.Seconds(), studentID); updateErr != nil {
+			return fmt.Errorf("error recording enrollment sync failure: %w", updateErr)
+		}
+		if commitErr := tx.Commit(); commitErr != nil {
+			return fmt.Errorf("error committing transaction: %w", commitErr)
+		}
+		if status == models.EnrollmentSyncFailed {
+			logger.Error("Enrollment sync of student %d failed after %d attempts: %v", studentID, attempts, err)
+		} else {
+			logger.Warn("Enrollment sync of student %d failed (attempt %d), retrying in %s: %v", studentID, attempts, backoff, err)
+		}
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE enrollment_sync
+		SET status = $1, attempts = $2, last_error = NULL, external_id = NULLIF($3, ''), synced_at = NOW(),
+			next_attempt_at = NULL, updated_at = NOW()
+		WHERE student_id = $4`,
+		models.EnrollmentSyncSynced, attempts, externalID, studentID); err != nil {
+		return fmt.Errorf("error recording enrollment sync: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	logger.Info("Enrollment of student %d synced to the LMS/ERP", studentID)
+	return nil
+}
+
+// buildEnrollmentRecord assembles the student's profile, course, batch and paid fees
+func buildEnrollmentRecord(ctx context.Context, tx *sql.Tx, studentID, courseID int) (*models.EnrollmentRecord, error) {
+	record := &models.EnrollmentRecord{Payments: []models.EnrollmentPayment{}}
+	err := tx.QueryRowContext(ctx, `
+		SELECT sl.id, sl.name, sl.email, sl.phone, COALESCE(sl.education, ''), COALESCE(sl.lead_source, ''),
+		       c.id, c.name, COALESCE(c.duration, ''), COALESCE(c.batch, ''), c.fee
+		FROM student_lead sl, course c
+		WHERE sl.id = $1 AND c.id = $2 AND sl.deleted_at IS NULL`, studentID, courseID).Scan(
+		&record.Student.ID, &record.Student.Name, utils.DecryptedPII(&record.Student.Email), utils.DecryptedPII(&record.Student.Phone),
+		&record.Student.Education, &record.Student.LeadSource,
+		&record.Course.ID, &record.Course.Name, &record.Course.Duration, &record.Course.Batch, &record.Course.Fee)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("student %d or course %d no longer exists", studentID, courseID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading enrollment: %w", err)
+	}
+
+	payments, err := NewPaymentRepository(tx).FindByStudent(ctx, studentID)
+	if err != nil {
+		return nil, fmt.Errorf("error reading enrollment payments: %w", err)
+	}
+
+	// Paid registration fee and this course's fee, oldest first
+	for i := len(payments) - 1; i >= 0; i-- {
+		payment := payments[i]
+		if payment.Status != PaymentStatusPaid {
+			continue
+		}
+		if payment.PaymentType == PaymentTypeCourseFee &&
+			(payment.RelatedCourseID == nil || *payment.RelatedCourseID != courseID) {
+			continue
+		}
+
+		p := models.EnrollmentPayment{
+			Type:      payment.PaymentType,
+			OrderID:   payment.OrderID,
+			PaymentID: payment.PaymentID,
+			Amount:    payment.Amount,
+			PaidAt:    payment.UpdatedAt,
+		}
+		record.Payments = append(record.Payments, p)
+		if p.Type == PaymentTypeCourseFee {
+			record.EnrolledAt = p.PaidAt
+		}
+	}
+	if record.EnrolledAt.IsZero() {
+		return nil, fmt.Errorf("course fee for course %d is not paid", courseID)
+	}
+	return record, nil
+}
+
+// GetEnrollmentSyncs lists enrollment handoffs, optionally with one status, most recently updated first
+func GetEnrollmentSyncs(ctx context.Context, status string, limit int) ([]models.EnrollmentSync, error) {
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT es.student_id, sl.name, es.course_id, c.name, es.status, es.attempts,
+		       COALESCE(es.last_error, ''), COALESCE(es.external_id, ''), es.next_attempt_at, es.synced_at,
+		       es.created_at, es.updated_at
+		FROM enrollment_sync es
+		JOIN student_lead sl ON sl.id = es.student_id
+		JOIN course c ON c.id = es.course_id
+		WHERE ($1 = '' OR es.status = $1)
+		ORDER BY es.updated_at DESC
+		LIMIT $2`, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching enrollment syncs: %w", err)
+	}
+	defer rows.Close()
+
+	syncs := []models.EnrollmentSync{}
+	for rows.Next() {
+		var s models.EnrollmentSync
+		var nextAttemptAt, syncedAt sql.NullTime
+		if err := rows.Scan(&s.StudentID, &s.StudentName, &s.CourseID, &s.CourseName, &s.Status, &s.Attempts,
+			&s.LastError, &s.ExternalID, &nextAttemptAt, &syncedAt, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error reading enrollment syncs: %w", err)
+		}
+		if nextAttemptAt.Valid && s.Status == models.EnrollmentSyncPending {
+			s.NextAttemptAt = &nextAttemptAt.Time
+		}
+		if syncedAt.Valid {
+			s.SyncedAt = &syncedAt.Time
+		}
+		syncs = append(syncs, s)
+	}
+	return syncs, rows.Err()
+}
+
+// RetryEnrollmentSync makes a student's handoff due again with a fresh attempt budget
+// (also re-sends an already synced student, e.g. after the LMS/ERP lost the record)
+func RetryEnrollmentSync(ctx context.Context, studentID int) error {
+	result, err := db.DB.ExecContext(ctx, `
+		UPDATE enrollment_sync
+		SET status = $1, attempts = 0, last_error = NULL, next_attempt_at = NOW(), updated_at = NOW()
+		WHERE student_id = $2`, models.EnrollmentSyncPending, studentID)
+	if err != nil {
+		return fmt.Errorf("error retrying enrollment sync: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrEnrollmentSyncNotFound
+	}
+	return nil
+}
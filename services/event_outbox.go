@@ -0,0 +1,155 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/logger"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Event outbox status constants
+const (
+	EventOutboxStatusPending = "PENDING"
+	EventOutboxStatusSent    = "SENT"
+)
+
+// eventOutboxBatchSize is how many events one relay run publishes at most
+const eventOutboxBatchSize = 100
+
+// sentEventRetentionDays is how long relayed events are kept by default before retention
+// purges them
+const sentEventRetentionDays = 7
+
+// execer is satisfied by *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// EnqueueEvent writes a Kafka event to the event_outbox table. Pass the transaction that
+// performs the state change so the event is stored if and only if the change commits;
+// RelayEventOutbox publishes it afterwards. Events enqueued while handling a request
+// carry its request_id.
+func EnqueueEvent(ctx context.Context, exec execer, topic, key string, evt map[string]interface{}) error {
+	if requestID := logger.RequestIDFromContext(ctx); requestID != "" {
+		if _, set := evt["request_id"]; !set {
+			evt["request_id"] = requestID
+		}
+	}
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		return fmt.Errorf("error encoding %s event: %w", topic, err)
+	}
+
+	_, err = exec.ExecContext(ctx,
+		"INSERT INTO event_outbox (topic, event_key, payload, status) VALUES ($1, $2, $3, $4)",
+		topic, key, payload, EventOutboxStatusPending)
+	if err != nil {
+		return fmt.Errorf("error writing event to outbox: %w", err)
+	}
+	return nil
+}
+
+// eventOutboxRelayLock is the advisory lock key held by the one instance relaying the outbox
+const eventOutboxRelayLock = 0x6f7574626f78 // "outbox"
+
+// RelayEventOutbox publishes pending outbox events to Kafka in insertion order and marks
+// them sent. Only the server instance holding the relay's advisory lock publishes, so
+// events for the same key are never delivered out of order by two instances. A failed
+// publish stops the run so later events for the same key are not delivered ahead of it.
+// Each event is marked sent in its own statement after Kafka acknowledged it, so no row
+// lock or transaction is held while waiting for the broker. Runs as a scheduled job;
+// skipped while Kafka is unavailable.
+func RelayEventOutbox(ctx context.Context) error {
+	if db.DB == nil || !IsConnected() {
+		return nil
+	}
+
+	// The lock belongs to the session, so it is taken and released on one connection
+	conn, err := db.DB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting a connection: %w", err)
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", eventOutboxRelayLock).Scan(&locked); err != nil {
+		return fmt.Errorf("error locking event outbox relay: %w", err)
+	}
+	if !locked {
+		// Another instance is relaying
+		return nil
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", eventOutboxRelayLock); err != nil {
+			logger.Warn("Event outbox: failed to release relay lock: %v", err)
+		}
+	}()
+
+	rows, err := conn.QueryContext(ctx, `
+		SELECT id, topic, event_key, payload
+		FROM event_outbox
+		WHERE status = $1
+		ORDER BY id ASC
+		LIMIT $2`, EventOutboxStatusPending, eventOutboxBatchSize)
+	if err != nil {
+		return fmt.Errorf("error reading event outbox: %w", err)
+	}
+
+	type outboxEvent struct {
+		id         int64
+		topic, key string
+		payload    json.RawMessage
+	}
+	var pending []outboxEvent
+	for rows.Next() {
+		var e outboxEvent
+		if err := rows.Scan(&e.id, &e.topic, &e.key, &e.payload); err != nil {
+			rows.Close()
+			return fmt.Errorf("error reading event outbox: %w", err)
+		}
+		pending = append(pending, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading event outbox: %w", err)
+	}
+
+	for _, e := range pending {
+		// Always wait for the broker: a row is only marked sent once Kafka acknowledged it
+		if publishErr := PublishSync(ctx, e.topic, e.key, e.payload); publishErr != nil {
+			_, _ = conn.ExecContext(ctx,
+				"UPDATE event_outbox SET attempts = attempts + 1, last_error = $1 WHERE id = $2",
+				publishErr.Error(), e.id)
+			logger.Warn("Event outbox: failed to publish event %d to %s: %v", e.id, e.topic, publishErr)
+			return nil
+		}
+
+		_, err := conn.ExecContext(ctx,
+			"UPDATE event_outbox SET status = $1, attempts = attempts + 1, last_error = NULL, sent_at = NOW() WHERE id = $2",
+			EventOutboxStatusSent, e.id)
+		if err != nil {
+			// Published but not marked: the next run sends it again, which consumers deduplicate
+			return fmt.Errorf("error marking event %d sent: %w", e.id, err)
+		}
+	}
+	return nil
+}
+
+// purgeSentEvents is a retention policy deleting events relayed before cutoff
+func purgeSentEvents(ctx context.Context, cutoff time.Time, dryRun bool) (int, int, error) {
+	if dryRun {
+		return countRows(ctx, "SELECT COUNT(*) FROM event_outbox WHERE status = $1 AND sent_at < $2",
+			EventOutboxStatusSent, cutoff)
+	}
+	result, err := db.DB.ExecContext(ctx,
+		"DELETE FROM event_outbox WHERE status = $1 AND sent_at < $2",
+		EventOutboxStatusSent, cutoff)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error purging sent events: %w", err)
+	}
+	purged, _ := result.RowsAffected()
+	return int(purged), 0, nil
+}
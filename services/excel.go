@@ -58,6 +58,9 @@ func ParseExcel(filePath string) ([]models.Lead, error) {
 		phone := extractField(row, colIndices["phone"])
 		education := extractField(row, colIndices["education"])
 		leadSource := extractField(row, colIndices["lead_source"])
+		campaign := extractField(row, colIndices["campaign"])
+		utmSource := extractField(row, colIndices["utm_source"])
+		utmMedium := extractField(row, colIndices["utm_medium"])
 
 		fmt.Printf("[DEBUG] Row %d: Name=%s, Email=%s, Phone=%s, Education=%s, LeadSource=%s\n",
 			i+1, name, email, phone, education, leadSource)
@@ -74,6 +77,9 @@ func ParseExcel(filePath string) ([]models.Lead, error) {
 			Phone:      phone,
 			Education:  education,
 			LeadSource: leadSource,
+			Campaign:   campaign,
+			UTMSource:  utmSource,
+			UTMMedium:  utmMedium,
 		}
 
 		// Default lead source if empty
@@ -96,6 +102,9 @@ func detectColumns(headers []string) map[string]int {
 		"phone":       -1,
 		"education":   -1,
 		"lead_source": -1,
+		"campaign":    -1,
+		"utm_source":  -1,
+		"utm_medium":  -1,
 	}
 
 	for i, header := range headers {
@@ -113,6 +122,12 @@ func detectColumns(headers []string) map[string]int {
 			indices["education"] = i
 		case lower == "lead_source" || lower == "lead source" || lower == "source":
 			indices["lead_source"] = i
+		case lower == "campaign" || lower == "utm_campaign" || lower == "campaign name":
+			indices["campaign"] = i
+		case lower == "utm_source" || lower == "utm source":
+			indices["utm_source"] = i
+		case lower == "utm_medium" || lower == "utm medium":
+			indices["utm_medium"] = i
 		}
 	}
 
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/models"
+	"admission-module/utils"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Export format constants
+const (
+	ExportFormatXLSX = "xlsx"
+	ExportFormatCSV  = "csv"
+)
+
+var leadExportHeaders = []string{
+	"ID", "Name", "Email", "Phone", "Education", "Lead Source", "Counselor",
+	"Registration Fee Status", "Course Fee Status", "Application Status", "Created At",
+}
+
+// leadExportTextColumns are the free-text columns of leadExportHeaders: name, email,
+// education, lead source and counselor
+var leadExportTextColumns = []int{1, 2, 4, 5, 6}
+
+// GetLeadExportRows loads active leads created within the optional bounds, oldest first
+func GetLeadExportRows(ctx context.Context, createdAfter, createdBefore *time.Time) ([]models.LeadExportRow, error) {
+	rows, err := db.Reader().QueryContext(ctx, `
+		SELECT
+			sl.id, sl.name, sl.email, sl.phone, COALESCE(sl.education, ''), COALESCE(sl.lead_source, ''),
+			COALESCE(c.name, ''), COALESCE(sl.registration_fee_status, ''), COALESCE(sl.course_fee_status, ''),
+			COALESCE(sl.application_status, ''), sl.created_at
+		FROM student_lead sl
+		LEFT JOIN counselor c ON c.id = sl.counselor_id
+		WHERE sl.deleted_at IS NULL
+			AND ($1::timestamp IS NULL OR sl.created_at >= $1)
+			AND ($2::timestamp IS NULL OR sl.created_at <= $2)
+		ORDER BY sl.id ASC`, nullableTime(createdAfter), nullableTime(createdBefore))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching leads for export: %w", err)
+	}
+	defer rows.Close()
+
+	leads := []models.LeadExportRow{}
+	for rows.Next() {
+		var lead models.LeadExportRow
+		if err := rows.Scan(
+			&lead.ID, &lead.Name, utils.DecryptedPII(&lead.Email), utils.DecryptedPII(&lead.Phone), &lead.Education, &lead.LeadSource,
+			&lead.CounselorName, &lead.RegistrationFeeStatus, &lead.CourseFeeStatus,
+			&lead.ApplicationStatus, &lead.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("error reading leads for export: %w", err)
+		}
+		leads = append(leads, lead)
+	}
+
+	return leads, rows.Err()
+}
+
+// WriteLeadsExport writes leads to w in the requested format (xlsx or csv)
+func WriteLeadsExport(w io.Writer, format string, leads []models.LeadExportRow) error {
+	switch format {
+	case ExportFormatCSV:
+		return writeLeadsCSV(w, leads)
+	case ExportFormatXLSX:
+		return writeLeadsXLSX(w, leads)
+	default:
+		return fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+func leadExportRecord(lead models.LeadExportRow) []string {
+	return []string{
+		strconv.Itoa(lead.ID), lead.Name, lead.Email, lead.Phone, lead.Education, lead.LeadSource,
+		lead.CounselorName, lead.RegistrationFeeStatus, lead.CourseFeeStatus, lead.ApplicationStatus,
+		lead.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// csvFormulaPrefixes are the leading characters that make a spreadsheet evaluate a CSV cell
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvCell neutralizes text a spreadsheet would run as a formula (CSV injection) by
+// prefixing it with a quote, e.g. a lead named =HYPERLINK(...)
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune(csvFormulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// csvRecord applies csvCell to the free-text columns of a record. Validated values such as
+// phone numbers (+91...) and amounts are left as they are.
+func csvRecord(record []string, textColumns []int) []string {
+	for _, i := range textColumns {
+		record[i] = csvCell(record[i])
+	}
+	return record
+}
+
+func writeLeadsCSV(w io.Writer, leads []models.LeadExportRow) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(leadExportHeaders); err != nil {
+		return err
+	}
+	for _, lead := range leads {
+		if err := writer.Write(csvRecord(leadExportRecord(lead), leadExportTextColumns)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeLeadsXLSX uses excelize's stream writer so large exports stay memory friendly
+func writeLeadsXLSX(w io.Writer, leads []models.LeadExportRow) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheetName := "Leads"
+	if err := f.SetSheetName("Sheet1", sheetName); err != nil {
+		return fmt.Errorf("failed to name sheet: %w", err)
+	}
+
+	sw, err := f.NewStreamWriter(sheetName)
+	if err != nil {
+		return fmt.Errorf("failed to create stream writer: %w", err)
+	}
+
+	header := make([]interface{}, len(leadExportHeaders))
+	for i, h := range leadExportHeaders {
+		header[i] = h
+	}
+	if err := sw.SetRow("A1", header); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+
+	for i, lead := range leads {
+		record := leadExportRecord(lead)
+		row := make([]interface{}, len(record))
+		for j, v := range record {
+			row[j] = v
+		}
+		row[0] = lead.ID
+
+		cell, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			return err
+		}
+		if err := sw.SetRow(cell, row); err != nil {
+			return fmt.Errorf("failed to write row %d: %w", i+2, err)
+		}
+	}
+
+	if err := sw.Flush(); err != nil {
+		return fmt.Errorf("failed to flush sheet: %w", err)
+	}
+
+	_, err = f.WriteTo(w)
+	return err
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/utils"
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// staleLead is a lead whose assigned counselor should follow up
+type staleLead struct {
+	id                int64
+	name, email       string
+	applicationStatus string
+	lastActivityAt    time.Time
+}
+
+// counselorDigest batches all stale leads of one counselor into a single email
+type counselorDigest struct {
+	name, email string
+	leads       []staleLead
+}
+
+// SendFollowUpReminders emails a digest to every counselor with stale leads and
+// returns the number of leads reminded. A lead is stale when neither the lead row,
+// its notes nor its timeline events changed for FollowUpStaleDays; it is reminded
+// again only after another FollowUpStaleDays without activity.
+func SendFollowUpReminders(ctx context.Context) (int, error) {
+	if db.DB == nil {
+		return 0, nil
+	}
+
+	staleDays := config.AppConfig.FollowUpStaleDays
+	if staleDays <= 0 {
+		staleDays = 3
+	}
+
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT c.id, c.name, c.email, sl.id, sl.name, sl.email, COALESCE(sl.application_status, ''), activity.last_at
+		FROM student_lead sl
+		JOIN counselor c ON c.id = sl.counselor_id
+		CROSS JOIN LATERAL (
+			SELECT GREATEST(
+				sl.created_at, sl.updated_at,
+				(SELECT MAX(created_at) FROM lead_note WHERE lead_id = sl.id),
+				(SELECT MAX(occurred_at) FROM lead_event WHERE lead_id = sl.id)
+			) AS last_at
+		) activity
+		WHERE sl.deleted_at IS NULL
+			AND COALESCE(sl.application_status, '') <> 'REJECTED'
+			AND COALESCE(sl.course_fee_status, '') <> $1
+			AND activity.last_at < NOW() - make_interval(days => $2)
+			AND (sl.follow_up_reminded_at IS NULL OR sl.follow_up_reminded_at < NOW() - make_interval(days => $2))
+		ORDER BY c.id, activity.last_at ASC`, PaymentStatusPaid, staleDays)
+	if err != nil {
+		return 0, fmt.Errorf("error finding stale leads: %w", err)
+	}
+
+	digests := map[int64]*counselorDigest{}
+	var counselorIDs []int64
+	for rows.Next() {
+		var counselorID int64
+		var counselorName, counselorEmail string
+		var lead staleLead
+		if err := rows.Scan(&counselorID, &counselorName, &counselorEmail,
+			&lead.id, &lead.name, utils.DecryptedPII(&lead.email), &lead.applicationStatus, &lead.lastActivityAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("error reading stale leads: %w", err)
+		}
+		digest, ok := digests[counselorID]
+		if !ok {
+			digest = &counselorDigest{name: counselorName, email: counselorEmail}
+			digests[counselorID] = digest
+			counselorIDs = append(counselorIDs, counselorID)
+		}
+		digest.leads = append(digest.leads, lead)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error reading stale leads: %w", err)
+	}
+
+	reminded := 0
+	for _, counselorID := range counselorIDs {
+		digest := digests[counselorID]
+		subject := fmt.Sprintf("Follow-up reminder: %d leads need your attention", len(digest.leads))
+		if err := SendCategorizedEmail(EmailCategoryStaff, digest.email, subject, buildFollowUpDigestBody(digest, staleDays)); err != nil {
+			logger.Error("Error sending follow-up digest to counselor %d: %v", counselorID, err)
+			continue
+		}
+
+		leadIDs := make([]int64, len(digest.leads))
+		for i, lead := range digest.leads {
+			leadIDs[i] = lead.id
+		}
+		// Only the reminder timestamp changes, so updated_at is left alone
+		if _, err := db.DB.ExecContext(ctx,
+			"UPDATE student_lead SET follow_up_reminded_at = NOW() WHERE id = ANY($1)", pq.Int64Array(leadIDs)); err != nil {
+			logger.Error("Error marking leads as reminded for counselor %d: %v", counselorID, err)
+			continue
+		}
+		reminded += len(leadIDs)
+	}
+
+	if reminded > 0 {
+		logger.Info("Sent follow-up reminders for %d leads to %d counselors", reminded, len(counselorIDs))
+	}
+	return reminded, nil
+}
+
+// buildFollowUpDigestBody renders the HTML digest listing a counselor's stale leads
+func buildFollowUpDigestBody(digest *counselorDigest, staleDays int) string {
+	var rows strings.Builder
+	for _, lead := range digest.leads {
+		days := int(time.Since(lead.lastActivityAt).Hours() / 24)
+		fmt.Fprintf(&rows, "<tr><td>%d</td><td>%s</td><td>%s</td><td>%s</td><td>%d days ago</td></tr>",
+			lead.id, html.EscapeString(lead.name), html.EscapeString(lead.email),
+			html.EscapeString(lead.applicationStatus), days)
+	}
+
+	return fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; color: #333;">
+    <p>Hi %s,</p>
+    <p>The following leads have had no status change or note for at least %d days:</p>
+    <table border="1" cellpadding="6" cellspacing="0" style="border-collapse: collapse;">
+        <tr><th>Lead ID</th><th>Name</th><th>Email</th><th>Status</th><th>Last Activity</th></tr>
+        %s
+    </table>
+    <p>Please reach out and log a note once you have followed up.</p>
+</body>
+</html>`, html.EscapeString(digest.name), staleDays, rows.String())
+}
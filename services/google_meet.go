@@ -2,6 +2,8 @@ package services
 
 import (
 	"admission-module/db"
+	"admission-module/models"
+	"context"
 	"fmt"
 	"log"
 	"time"
@@ -9,7 +11,8 @@ import (
 
 // ScheduleMeet creates a meeting invite for the given email and stores meet_link in database.
 // Instead of using Google Calendar API, it generates a simple meeting link and sends an email with the details.
-func ScheduleMeet(studentID int, email string) (string, error) {
+// The interview is assigned to the least-loaded matching interviewer (see assignInterviewer), who is emailed too.
+func ScheduleMeet(ctx context.Context, studentID int, email string) (string, error) {
 	// Generate a unique meeting ID using timestamp
 	meetID := fmt.Sprintf("%d", time.Now().Unix())
 
@@ -19,40 +22,111 @@ func ScheduleMeet(studentID int, email string) (string, error) {
 	meetTime := time.Now().Add(time.Hour)
 	endTime := meetTime.Add(time.Hour)
 
-	emailBody := fmt.Sprintf(`
+	bookCtx, cancel := db.WithTimeout(ctx)
+	interviewer, err := bookInterview(bookCtx, studentID, meetLink, meetTime)
+	cancel()
+	if err != nil {
+		return "", err
+	}
+
+	// Send the meeting invite via email
+	subject, body := buildMeetingScheduledEmail(interviewer, meetLink, meetTime, endTime)
+	if err := sendInterviewInvite(ctx, studentID, email, subject, body); err != nil {
+		return "", fmt.Errorf("failed to send meeting invite: %w", err)
+	}
+
+	if interviewer != nil {
+		if err := sendInterviewerInvite(ctx, interviewer, studentID, meetLink, meetTime); err != nil {
+			log.Printf("Warning: failed to notify interviewer %s: %v", interviewer.Email, err)
+		}
+	}
+
+	return meetLink, nil
+}
+
+// buildMeetingScheduledEmail renders the student's interview invite; interviewer may be nil
+func buildMeetingScheduledEmail(interviewer *models.Interviewer, meetLink string, meetTime, endTime time.Time) (subject, body string) {
+	interviewerLine := ""
+	if interviewer != nil {
+		interviewerLine = fmt.Sprintf("<p><strong>Interviewer:</strong> %s</p>", interviewer.Name)
+	}
+
+	body = fmt.Sprintf(`
         <h2>Meeting Scheduled</h2>
-		<p>Your interview meeting with Sai University has been scheduled.<p>
+        <p>Your interview meeting with Sai University has been scheduled.</p>
         <p><strong>Date:</strong> %s</p>
         <p><strong>Time:</strong> %s - %s</p>
+        %s
         <p><strong>Meeting Link:</strong> <a href="%s">%s</a></p>
         <p>Click the link above to join the meeting at the scheduled time.</p>
     `,
 		meetTime.Format("Monday, January 2, 2006"),
 		meetTime.Format("3:04 PM"),
 		endTime.Format("3:04 PM"),
+		interviewerLine,
 		meetLink,
 		meetLink,
 	)
 
-	// Send the meeting invite via email
-	err := SendEmail(
-		email,
-		fmt.Sprintf("Meeting Scheduled for %s", meetTime.Format("Jan 2, 2006 3:04 PM")),
-		emailBody,
-	)
+	return fmt.Sprintf("Meeting Scheduled for %s", meetTime.Format("Jan 2, 2006 3:04 PM")), body
+}
+
+// bookInterview stores the meeting on the lead and assigns an interviewer in one transaction.
+// Returns the interviewer, or nil when none is available.
+func bookInterview(ctx context.Context, studentID int, meetLink string, meetTime time.Time) (*models.Interviewer, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to send meeting invite: %w", err)
+		return nil, fmt.Errorf("error starting transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	// Store meet_link in student_lead table
-	_, err = db.DB.Exec(
-		"UPDATE student_lead SET meet_link = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2",
-		meetLink, studentID)
+	// Store meet_link and the interview time in student_lead table
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE student_lead SET meet_link = $1, interview_scheduled_at = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3",
+		meetLink, meetTime, studentID); err != nil {
+		return nil, fmt.Errorf("error storing meet_link: %w", err)
+	}
+
+	interviewer, err := assignInterviewer(ctx, tx, studentID, meetTime)
 	if err != nil {
-		log.Printf("Warning: failed to store meet_link in database: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	if interviewer != nil {
+		log.Printf("✅ meet_link stored in database: %s (interviewer: %s)", meetLink, interviewer.Name)
 	} else {
-		log.Printf("✅ meet_link stored in database: %s", meetLink)
+		log.Printf("✅ meet_link stored in database: %s (no interviewer available)", meetLink)
 	}
+	return interviewer, nil
+}
 
-	return meetLink, nil
+// sendInterviewerInvite tells the assigned interviewer about the interview
+func sendInterviewerInvite(ctx context.Context, interviewer *models.Interviewer, studentID int, meetLink string, meetTime time.Time) error {
+	var name, education string
+	if err := db.DB.QueryRowContext(ctx,
+		"SELECT name, COALESCE(education, '') FROM student_lead WHERE id = $1", studentID).Scan(&name, &education); err != nil {
+		return fmt.Errorf("error fetching student: %w", err)
+	}
+
+	subject, body := buildInterviewerInviteEmail(interviewer.Name, name, education, studentID, meetLink, meetTime)
+	return SendCategorizedEmail(EmailCategoryStaff, interviewer.Email, subject, body)
+}
+
+// buildInterviewerInviteEmail renders the interviewer's notice of an assigned interview
+func buildInterviewerInviteEmail(interviewerName, studentName, education string, studentID int, meetLink string, meetTime time.Time) (subject, body string) {
+	body = fmt.Sprintf(`
+        <h2>Interview Assigned</h2>
+        <p>Dear %s, you have been assigned an interview with <strong>%s</strong> (student ID %d, education: %s).</p>
+        <p><strong>When:</strong> %s</p>
+        <p><strong>Meeting Link:</strong> <a href="%s">%s</a></p>
+    `,
+		interviewerName, studentName, studentID, education,
+		meetTime.Format("Monday, January 2, 2006 3:04 PM"),
+		meetLink, meetLink,
+	)
+	return fmt.Sprintf("Interview assigned: %s", studentName), body
 }
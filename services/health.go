@@ -0,0 +1,129 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/models"
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// smtpHealthTTL is how long an SMTP dial result is reused, so frequent load balancer
+// probes do not open a connection to the mail server each time
+const smtpHealthTTL = 30 * time.Second
+
+var (
+	smtpHealthMutex sync.Mutex
+	smtpHealthAt    time.Time
+	smtpHealth      models.HealthComponent
+)
+
+// CheckHealth reports the state of the database (critical), Kafka and SMTP. Unlike
+// RunDoctor it only checks what is cheap enough to run on every probe: a database ping,
+// the Kafka client state and an SMTP greeting without login.
+func CheckHealth(ctx context.Context) models.HealthReport {
+	report := models.HealthReport{
+		Status:     models.HealthStatusUp,
+		Components: []models.HealthComponent{checkDatabaseHealth(ctx), checkKafkaHealth(), checkSMTPHealth(ctx)},
+		CheckedAt:  time.Now(),
+	}
+	for _, c := range report.Components {
+		if c.Status != models.HealthStatusDown {
+			continue
+		}
+		if c.Critical {
+			report.Status = models.HealthStatusDown
+			break
+		}
+		report.Status = models.HealthStatusDegraded
+	}
+	return report
+}
+
+func checkDatabaseHealth(ctx context.Context) models.HealthComponent {
+	component := models.HealthComponent{Name: "database", Status: models.HealthStatusUp, Critical: true}
+	if db.DB == nil {
+		component.Status, component.Detail = models.HealthStatusDown, "not connected"
+		return component
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	start := time.Now()
+	err := db.DB.PingContext(pingCtx)
+	component.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		component.Status, component.Detail = models.HealthStatusDown, err.Error()
+	}
+	return component
+}
+
+func checkKafkaHealth() models.HealthComponent {
+	component := models.HealthComponent{Name: "kafka", Status: models.HealthStatusUp}
+	if strings.TrimSpace(config.AppConfig.KafkaBrokers) == "" {
+		component.Status, component.Detail = models.HealthStatusDisabled, "KAFKA_BROKERS is empty"
+		return component
+	}
+
+	var problems []string
+	if !IsConnected() {
+		problems = append(problems, "producer not connected")
+	}
+	if len(ConsumedTopics()) > 0 && !IsConsumerRunning() {
+		problems = append(problems, "consumer not running")
+	}
+	if len(problems) > 0 {
+		component.Status, component.Detail = models.HealthStatusDown, strings.Join(problems, ", ")
+	}
+	return component
+}
+
+// checkSMTPHealth dials the mail server and waits for its 220 greeting; the result is
+// cached for smtpHealthTTL
+func checkSMTPHealth(ctx context.Context) models.HealthComponent {
+	if !IsSMTPConfigured() {
+		return models.HealthComponent{Name: "smtp", Status: models.HealthStatusDisabled, Detail: "SMTP_USER/SMTP_PASS not set"}
+	}
+
+	smtpHealthMutex.Lock()
+	defer smtpHealthMutex.Unlock()
+	if time.Since(smtpHealthAt) < smtpHealthTTL {
+		return smtpHealth
+	}
+
+	component := models.HealthComponent{Name: "smtp", Status: models.HealthStatusUp}
+	start := time.Now()
+	if err := dialSMTP(ctx, net.JoinHostPort(config.AppConfig.SMTPHost, config.AppConfig.SMTPPort)); err != nil {
+		component.Status, component.Detail = models.HealthStatusDown, err.Error()
+	}
+	component.LatencyMS = time.Since(start).Milliseconds()
+
+	smtpHealth, smtpHealthAt = component, time.Now()
+	return component
+}
+
+func dialSMTP(ctx context.Context, addr string) error {
+	dialCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(dialCtx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+	greeting, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("no greeting from %s: %w", addr, err)
+	}
+	if !strings.HasPrefix(greeting, "220") {
+		return fmt.Errorf("unexpected greeting from %s: %s", addr, strings.TrimSpace(greeting))
+	}
+	fmt.Fprint(conn, "QUIT\r\n")
+	return nil
+}
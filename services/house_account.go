@@ -0,0 +1,63 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+var (
+	houseAccountMutex sync.Mutex
+	houseAccountID    *int64
+)
+
+// EnsureHouseAccount creates or refreshes the house account counselor from configuration.
+// Leads fall back to it when no counselor has capacity, so welcome emails still go out
+// with generic contact details and the unassigned queue can be worked from one place.
+func EnsureHouseAccount(ctx context.Context) error {
+	cfg := config.AppConfig
+	if cfg.HouseAccountEmail == "" {
+		logger.Warn("House account disabled: HOUSE_ACCOUNT_EMAIL/EMAIL_FROM not set. Leads without capacity stay unassigned")
+		return nil
+	}
+
+	var id int64
+	err := db.DB.QueryRowContext(ctx, `
+		UPDATE counselor SET name = $1, email = $2, phone = NULLIF($3, ''), updated_at = NOW()
+		WHERE is_house_account
+		RETURNING id`, cfg.HouseAccountName, cfg.HouseAccountEmail, cfg.HouseAccountPhone).Scan(&id)
+	if err == sql.ErrNoRows {
+		// No house account yet: create it
+		err = db.DB.QueryRowContext(ctx, `
+			INSERT INTO counselor (name, email, phone, assigned_count, max_capacity, is_referral_enabled, is_house_account)
+			VALUES ($1, $2, NULLIF($3, ''), 0, 0, false, true)
+			RETURNING id`, cfg.HouseAccountName, cfg.HouseAccountEmail, cfg.HouseAccountPhone).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("error creating house account: %w", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("error updating house account: %w", err)
+	}
+
+	houseAccountMutex.Lock()
+	houseAccountID = &id
+	houseAccountMutex.Unlock()
+
+	logger.Info("✓ House account ready (counselor %d, %s)", id, cfg.HouseAccountEmail)
+	return nil
+}
+
+// GetHouseAccountID returns the house account counselor ID, or nil when it is disabled
+func GetHouseAccountID() *int64 {
+	houseAccountMutex.Lock()
+	defer houseAccountMutex.Unlock()
+	if houseAccountID == nil {
+		return nil
+	}
+	id := *houseAccountID
+	return &id
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrImportNotFound is returned when an import job does not exist
+	ErrImportNotFound = errors.New("import job not found")
+	// ErrImportAlreadyRolledBack is returned when rolling back an import twice
+	ErrImportAlreadyRolledBack = errors.New("import job has already been rolled back")
+	// ErrInvalidImportFile is returned when an uploaded spreadsheet cannot be parsed
+	ErrInvalidImportFile = errors.New("error parsing Excel")
+	// ErrInvalidDuplicatePolicy is returned for a duplicate policy other than skip, update or merge
+	ErrInvalidDuplicatePolicy = errors.New("invalid duplicate policy")
+)
+
+const importHistoryColumns = `
+	id, file_name, file_hash, COALESCE(uploaded_by, ''), total_count, success_count, failed_count,
+	COALESCE(updated_count, 0), COALESCE(skipped_count, 0), COALESCE(duplicate_policy, 'skip'),
+	created_lead_ids, status, rolled_back_at, created_at`
+
+// scanImportHistory reads an import_history row selected with importHistoryColumns
+func scanImportHistory(scanner interface{ Scan(...interface{}) error }) (*models.ImportHistory, error) {
+	var record models.ImportHistory
+	var leadIDs pq.Int64Array
+	var rolledBackAt sql.NullTime
+
+	err := scanner.Scan(
+		&record.ID, &record.FileName, &record.FileHash, &record.UploadedBy,
+		&record.TotalCount, &record.SuccessCount, &record.FailedCount,
+		&record.UpdatedCount, &record.SkippedCount, &record.DuplicatePolicy,
+		&leadIDs, &record.Status, &rolledBackAt, &record.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	record.CreatedLeadIDs = []int64(leadIDs)
+	if record.CreatedLeadIDs == nil {
+		record.CreatedLeadIDs = []int64{}
+	}
+	if rolledBackAt.Valid {
+		record.RolledBackAt = &rolledBackAt.Time
+	}
+
+	return &record, nil
+}
+
+// FindImportByHash returns the most recent active import of a file with the given SHA-256 hash.
+// Returns nil (and no error) when the file has never been imported or its import was rolled back.
+func FindImportByHash(ctx context.Context, fileHash string) (*models.ImportHistory, error) {
+	query := `SELECT ` + importHistoryColumns + `
+		FROM import_history
+		WHERE file_hash = $1 AND status <> $2
+		ORDER BY created_at DESC
+		LIMIT 1`
+
+	record, err := scanImportHistory(db.DB.QueryRowContext(ctx, query, fileHash, models.ImportStatusRolledBack))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error checking import history: %w", err)
+	}
+
+	return record, nil
+}
+
+// RecordImport stores the outcome of an upload run in import_history
+func RecordImport(ctx context.Context, record *models.ImportHistory) error {
+	if record.Status == "" {
+		record.Status = models.ImportStatusCompleted
+	}
+	if record.DuplicatePolicy == "" {
+		record.DuplicatePolicy = models.DuplicatePolicySkip
+	}
+
+	query := `
+		INSERT INTO import_history (file_name, file_hash, uploaded_by, total_count, success_count, failed_count,
+			updated_count, skipped_count, duplicate_policy, created_lead_ids, status)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at`
+
+	err := db.DB.QueryRowContext(ctx, query,
+		record.FileName, record.FileHash, record.UploadedBy,
+		record.TotalCount, record.SuccessCount, record.FailedCount,
+		record.UpdatedCount, record.SkippedCount, record.DuplicatePolicy,
+		pq.Int64Array(record.CreatedLeadIDs), record.Status,
+	).Scan(&record.ID, &record.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error recording import history: %w", err)
+	}
+
+	return nil
+}
+
+// GetImportHistory lists the most recent import runs
+func GetImportHistory(ctx context.Context, limit int) ([]models.ImportHistory, error) {
+	query := `SELECT ` + importHistoryColumns + `
+		FROM import_history
+		ORDER BY created_at DESC
+		LIMIT $1`
+
+	rows, err := db.DB.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching import history: %w", err)
+	}
+	defer rows.Close()
+
+	records := []models.ImportHistory{}
+	for rows.Next() {
+		record, err := scanImportHistory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading import history: %w", err)
+		}
+		records = append(records, *record)
+	}
+
+	return records, rows.Err()
+}
+
+// RollbackImport soft-deletes the leads created by an import and releases their counselor slots.
+// Leads that have already paid the registration fee are kept and reported as skipped.
+func RollbackImport(ctx context.Context, importID int) (*models.ImportRollbackResult, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	record, err := scanImportHistory(tx.QueryRowContext(ctx,
+		`SELECT `+importHistoryColumns+` FROM import_history WHERE id = $1 FOR UPDATE`, importID))
+	if err == sql.ErrNoRows {
+		return nil, ErrImportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching import job: %w", err)
+	}
+	if record.Status == models.ImportStatusRolledBack {
+		return nil, ErrImportAlreadyRolledBack
+	}
+
+	result := &models.ImportRollbackResult{
+		ImportID:       importID,
+		DeletedLeadIDs: []int64{},
+		SkippedLeadIDs: []int64{},
+	}
+
+	// Soft-delete leads that have not progressed past registration
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE student_lead
+		SET deleted_at = NOW(), updated_at = NOW()
+		WHERE id = ANY($1) AND deleted_at IS NULL AND COALESCE(registration_fee_status, '') <> $2
+		RETURNING id, counselor_id`,
+		pq.Int64Array(record.CreatedLeadIDs), PaymentStatusPaid)
+	if err != nil {
+		return nil, fmt.Errorf("error deleting imported leads: %w", err)
+	}
+
+	freed := map[int64]int{}
+	for rows.Next() {
+		var leadID int64
+		var counselorID sql.NullInt64
+		if err := rows.Scan(&leadID, &counselorID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error reading deleted leads: %w", err)
+		}
+		result.DeletedLeadIDs = append(result.DeletedLeadIDs, leadID)
+		if counselorID.Valid {
+			freed[counselorID.Int64]++
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading deleted leads: %w", err)
+	}
+
+	// Release counselor capacity held by the deleted leads
+	for counselorID, count := range freed {
+		_, err := tx.ExecContext(ctx,
+			"UPDATE counselor SET assigned_count = GREATEST(assigned_count - $1, 0), updated_at = NOW() WHERE id = $2",
+			count, counselorID)
+		if err != nil {
+			return nil, fmt.Errorf("error updating counselor count: %w", err)
+		}
+	}
+
+	deleted := map[int64]bool{}
+	for _, id := range result.DeletedLeadIDs {
+		deleted[id] = true
+	}
+	for _, id := range record.CreatedLeadIDs {
+		if !deleted[id] {
+			result.SkippedLeadIDs = append(result.SkippedLeadIDs, id)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE import_history SET status = $1, rolled_back_at = NOW() WHERE id = $2",
+		models.ImportStatusRolledBack, importID)
+	if err != nil {
+		return nil, fmt.Errorf("error updating import job: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	result.DeletedCount = len(result.DeletedLeadIDs)
+	result.SkippedCount = len(result.SkippedLeadIDs)
+	result.CounselorsFreed = len(freed)
+	return result, nil
+}
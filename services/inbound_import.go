@@ -0,0 +1,426 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// inboundMaxMessageBytes caps the size of a downloaded email (attachments included)
+	inboundMaxMessageBytes = 25 << 20
+	// inboundMaxMessagesPerPoll bounds one poll so a flooded mailbox cannot stall the job
+	inboundMaxMessagesPerPoll = 50
+	// inboundProcessingTimeout is how long an import may stay PROCESSING before it is reclaimed
+	inboundProcessingTimeout = 30 * time.Minute
+	// inboundReplyMaxFailedRows limits the failed rows listed in the reply
+	inboundReplyMaxFailedRows = 20
+)
+
+const inboundImportColumns = `
+	id, sender, COALESCE(subject, ''), file_name, file_hash, storage_key, status, import_id,
+	COALESCE(error_message, ''), attempts, created_at, processed_at`
+
+// inboundAttachment is a spreadsheet extracted from an inbound email
+type inboundAttachment struct {
+	fileName string
+	data     []byte
+}
+
+// scanInboundImport reads an inbound_import row selected with inboundImportColumns
+func scanInboundImport(scanner interface{ Scan(...interface{}) error }) (*models.InboundImport, error) {
+	var job models.InboundImport
+	var importID sql.NullInt64
+	var processedAt sql.NullTime
+
+	err := scanner.Scan(
+		&job.ID, &job.Sender, &job.Subject, &job.FileName, &job.FileHash, &job.StorageKey, &job.Status,
+		&importID, &job.ErrorMessage, &job.Attempts, &job.CreatedAt, &processedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if importID.Valid {
+		id := int(importID.Int64)
+		job.ImportID = &id
+	}
+	if processedAt.Valid {
+		job.ProcessedAt = &processedAt.Time
+	}
+	return &job, nil
+}
+
+// RunInboundMailImport polls the import mailbox and imports the queued spreadsheets.
+// Runs as a scheduled job; does nothing until INBOUND_MAIL_HOST is configured.
+func RunInboundMailImport(ctx context.Context) error {
+	if err := PollInboundMail(ctx); err != nil {
+		logger.Error("Error polling inbound mail: %v", err)
+	}
+	return ProcessInboundImports(ctx)
+}
+
+// PollInboundMail downloads new emails from the import mailbox, queues their spreadsheet
+// attachments in inbound_import and deletes them from the mailbox
+func PollInboundMail(ctx context.Context) error {
+	cfg := config.AppConfig
+	if cfg.InboundMailHost == "" || db.DB == nil {
+		return nil
+	}
+
+	client, err := dialPOP3(ctx, cfg.InboundMailHost, cfg.InboundMailPort)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// QUIT commits the deletions of the messages queued below
+		if err := client.quit(); err != nil {
+			logger.Warn("Error closing POP3 session: %v", err)
+		}
+	}()
+
+	if err := client.login(cfg.InboundMailUser, cfg.InboundMailPassword); err != nil {
+		return err
+	}
+
+	ids, err := client.list()
+	if err != nil {
+		return fmt.Errorf("error listing inbound mail: %w", err)
+	}
+	if len(ids) > inboundMaxMessagesPerPoll {
+		ids = ids[:inboundMaxMessagesPerPoll]
+	}
+
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			break
+		}
+
+		raw, err := client.retr(id, inboundMaxMessageBytes)
+		if err != nil && !errors.Is(err, errPOP3MessageTooLarge) {
+			return fmt.Errorf("error downloading inbound mail %d: %w", id, err)
+		}
+		if errors.Is(err, errPOP3MessageTooLarge) {
+			logger.Warn("Dropping inbound mail %d: larger than %d bytes", id, inboundMaxMessageBytes)
+		} else if err := queueInboundMessage(ctx, raw); err != nil {
+			// Keep the message in the mailbox so the next poll retries it
+			logger.Error("Error queueing inbound mail %d: %v", id, err)
+			continue
+		}
+
+		if err := client.dele(id); err != nil {
+			return fmt.Errorf("error deleting inbound mail %d: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// queueInboundMessage stores the spreadsheets of one email for import. Mail from senders
+// outside INBOUND_MAIL_ALLOWED_SENDERS is dropped; returning nil means the mail can be deleted.
+func queueInboundMessage(ctx context.Context, raw []byte) error {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		logger.Warn("Dropping unreadable inbound mail: %v", err)
+		return nil
+	}
+
+	from, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil {
+		logger.Warn("Dropping inbound mail without a valid sender: %v", err)
+		return nil
+	}
+	sender := strings.ToLower(from.Address)
+	if !inboundSenderAllowed(sender) {
+		logger.Warn("Dropping inbound mail from %s: sender not in INBOUND_MAIL_ALLOWED_SENDERS", sender)
+		return nil
+	}
+
+	subject := msg.Header.Get("Subject")
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(subject); err == nil {
+		subject = decoded
+	}
+
+	attachments, err := extractSpreadsheets(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	if err != nil {
+		logger.Warn("Error reading attachments of inbound mail from %s: %v", sender, err)
+	}
+	if len(attachments) == 0 {
+		replyToInboundSender(sender, "No spreadsheet found in your email",
+			fmt.Sprintf(`<p>We received your email <strong>%s</strong> but found no .xlsx attachment to import.</p>
+<p>Please attach the leads spreadsheet (Excel .xlsx) and send it again.</p>`, html.EscapeString(subject)))
+		return nil
+	}
+
+	for _, attachment := range attachments {
+		sum := sha256.Sum256(attachment.data)
+		fileHash := hex.EncodeToString(sum[:])
+		storageKey := fmt.Sprintf("inbound/%s/%s", fileHash, sanitizeAttachmentName(attachment.fileName))
+
+		if err := GetDocumentStorage().Save(ctx, storageKey, bytes.NewReader(attachment.data)); err != nil {
+			return fmt.Errorf("error storing attachment %s: %w", attachment.fileName, err)
+		}
+
+		_, err := db.DB.ExecContext(ctx, `
+			INSERT INTO inbound_import (sender, subject, file_name, file_hash, storage_key, status)
+			VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6)`,
+			sender, subject, attachment.fileName, fileHash, storageKey, models.InboundImportStatusPending)
+		if err != nil {
+			return fmt.Errorf("error queueing attachment %s: %w", attachment.fileName, err)
+		}
+		logger.Info("Queued inbound import of %s from %s", attachment.fileName, sender)
+	}
+
+	return nil
+}
+
+// inboundSenderAllowed reports whether address matches INBOUND_MAIL_ALLOWED_SENDERS
+// (exact addresses or @domain entries). Nobody is allowed while the list is empty.
+func inboundSenderAllowed(address string) bool {
+	for _, entry := range strings.Split(config.AppConfig.InboundMailAllowedSenders, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case strings.HasPrefix(entry, "@"):
+			if strings.HasSuffix(address, entry) {
+				return true
+			}
+		case entry == address:
+			return true
+		}
+	}
+	return false
+}
+
+// extractSpreadsheets walks a (possibly nested multipart) message body and returns its .xlsx attachments
+func extractSpreadsheets(contentType, transferEncoding string, body io.Reader) ([]inboundAttachment, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		// A single-part email cannot carry an attachment besides its body
+		return nil, nil
+	}
+
+	var attachments []inboundAttachment
+	reader := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return attachments, nil
+		}
+		if err != nil {
+			return attachments, err
+		}
+
+		partType := part.Header.Get("Content-Type")
+		if strings.HasPrefix(strings.ToLower(partType), "multipart/") {
+			nested, err := extractSpreadsheets(partType, part.Header.Get("Content-Transfer-Encoding"), part)
+			if err != nil {
+				return attachments, err
+			}
+			attachments = append(attachments, nested...)
+			continue
+		}
+
+		fileName := part.FileName()
+		if decoded, err := new(mime.WordDecoder).DecodeHeader(fileName); err == nil {
+			fileName = decoded
+		}
+		if !strings.EqualFold(filepath.Ext(fileName), ".xlsx") {
+			continue
+		}
+
+		data, err := io.ReadAll(decodeTransferEncoding(part.Header.Get("Content-Transfer-Encoding"), part))
+		if err != nil {
+			return attachments, fmt.Errorf("error decoding %s: %w", fileName, err)
+		}
+		attachments = append(attachments, inboundAttachment{fileName: fileName, data: data})
+	}
+}
+
+// decodeTransferEncoding undoes the Content-Transfer-Encoding of a MIME part
+func decodeTransferEncoding(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r) // skips the CRLF line breaks
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	default:
+		return r
+	}
+}
+
+// sanitizeAttachmentName keeps an attachment name safe to use as a storage key segment
+func sanitizeAttachmentName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		return "attachment.xlsx"
+	}
+	return name
+}
+
+// ProcessInboundImports runs the queued inbound spreadsheets through the import pipeline in order
+func ProcessInboundImports(ctx context.Context) error {
+	for ctx.Err() == nil && processNextInboundImport(ctx) {
+	}
+	return nil
+}
+
+// processNextInboundImport claims and imports one queued spreadsheet. Returns false when the queue is empty.
+func processNextInboundImport(ctx context.Context) bool {
+	if db.DB == nil {
+		return false
+	}
+
+	job, err := scanInboundImport(db.DB.QueryRowContext(ctx, `
+		UPDATE inbound_import
+		SET status = $1, started_at = NOW(), attempts = attempts + 1
+		WHERE id = (
+			SELECT id FROM inbound_import
+			WHERE status = $2 OR (status = $1 AND started_at < NOW() - make_interval(secs => $3))
+			ORDER BY created_at ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+inboundImportColumns,
+		models.InboundImportStatusProcessing, models.InboundImportStatusPending, inboundProcessingTimeout.Seconds()))
+	if err == sql.ErrNoRows {
+		return false
+	}
+	if err != nil {
+		logger.Error("Error claiming inbound import: %v", err)
+		return false
+	}
+
+	result, err := runInboundImport(ctx, job)
+	if err != nil {
+		logger.Error("Inbound import %d (%s from %s) failed: %v", job.ID, job.FileName, job.Sender, err)
+		_, _ = db.DB.ExecContext(ctx,
+			"UPDATE inbound_import SET status = $1, error_message = $2, processed_at = NOW() WHERE id = $3",
+			models.InboundImportStatusFailed, err.Error(), job.ID)
+		replyToInboundSender(job.Sender, fmt.Sprintf("Import of %s failed", job.FileName),
+			fmt.Sprintf(`<p>Your spreadsheet <strong>%s</strong> could not be imported:</p><p>%s</p>`,
+				html.EscapeString(job.FileName), html.EscapeString(err.Error())))
+		return true
+	}
+
+	_, _ = db.DB.ExecContext(ctx,
+		"UPDATE inbound_import SET status = $1, import_id = NULLIF($2, 0), error_message = NULL, processed_at = NOW() WHERE id = $3",
+		models.InboundImportStatusCompleted, result.ImportID, job.ID)
+	logger.Info("Inbound import %d (%s from %s): %d of %d leads imported",
+		job.ID, job.FileName, job.Sender, result.SuccessCount, result.TotalCount)
+	replyToInboundSender(job.Sender, fmt.Sprintf("Import of %s: %d of %d leads imported", job.FileName, result.SuccessCount, result.TotalCount),
+		inboundImportSummary(job, result))
+	return true
+}
+
+// runInboundImport imports a queued spreadsheet, attributed to its sender. Files that
+// were already imported are refused, as in the upload API without ?force=true.
+func runInboundImport(ctx context.Context, job *models.InboundImport) (*models.ImportResult, error) {
+	previous, err := FindImportByHash(ctx, job.FileHash)
+	if err != nil {
+		return nil, err
+	}
+	if previous != nil {
+		return nil, fmt.Errorf("this file was already imported on %s (import #%d)",
+			previous.CreatedAt.Format(time.RFC3339), previous.ID)
+	}
+
+	file, err := GetDocumentStorage().Open(ctx, job.StorageKey)
+	if err != nil {
+		return nil, fmt.Errorf("error opening stored attachment: %w", err)
+	}
+	defer file.Close()
+
+	tempFile, err := os.CreateTemp("", "inbound_*.xlsx")
+	if err != nil {
+		return nil, fmt.Errorf("error processing file: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	_, err = io.Copy(tempFile, file)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error processing file: %w", err)
+	}
+
+	return ImportLeadsFile(ctx, tempFile.Name(), &models.ImportHistory{
+		FileName:   job.FileName,
+		FileHash:   job.FileHash,
+		UploadedBy: job.Sender,
+	})
+}
+
+// inboundImportSummary renders the reply listing the import counts and the first failed rows
+func inboundImportSummary(job *models.InboundImport, result *models.ImportResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<p>Your spreadsheet <strong>%s</strong> has been imported (import #%d).</p>
+<ul><li>Rows: %d</li><li>Imported: %d</li><li>Already existing (skipped): %d</li><li>Held for duplicate review: %d</li><li>Failed: %d</li></ul>`,
+		html.EscapeString(job.FileName), result.ImportID, result.TotalCount, result.SuccessCount, result.SkippedCount-result.ReviewCount, result.ReviewCount, result.FailedCount)
+
+	if len(result.FailedLeads) > 0 {
+		b.WriteString(`<table border="1" cellpadding="4" cellspacing="0"><tr><th>Row</th><th>Email</th><th>Phone</th><th>Error</th></tr>`)
+		for i, row := range result.FailedLeads {
+			if i == inboundReplyMaxFailedRows {
+				break
+			}
+			fmt.Fprintf(&b, "<tr><td>%d</td><td>%s</td><td>%s</td><td>%s</td></tr>",
+				row.Row, html.EscapeString(row.Email), html.EscapeString(row.Phone), html.EscapeString(row.Error))
+		}
+		b.WriteString("</table>")
+		if len(result.FailedLeads) > inboundReplyMaxFailedRows {
+			fmt.Fprintf(&b, "<p>... and %d more failed rows.</p>", len(result.FailedLeads)-inboundReplyMaxFailedRows)
+		}
+	}
+	return b.String()
+}
+
+// replyToInboundSender emails the sender of an inbound import
+func replyToInboundSender(sender, subject, body string) {
+	if err := SendCategorizedEmail(EmailCategoryStaff, sender, subject, body); err != nil {
+		logger.Error("Error replying to inbound import sender %s: %v", sender, err)
+	}
+}
+
+// GetInboundImports lists the most recent spreadsheets received by email
+func GetInboundImports(ctx context.Context, limit int) ([]models.InboundImport, error) {
+	rows, err := db.DB.QueryContext(ctx, `SELECT `+inboundImportColumns+`
+		FROM inbound_import
+		ORDER BY created_at DESC
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching inbound imports: %w", err)
+	}
+	defer rows.Close()
+
+	imports := []models.InboundImport{}
+	for rows.Next() {
+		job, err := scanInboundImport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading inbound imports: %w", err)
+		}
+		imports = append(imports, *job)
+	}
+	return imports, rows.Err()
+}
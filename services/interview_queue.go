@@ -0,0 +1,127 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"admission-module/utils"
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// EmailPurposeInterviewInvite marks the email.send event of a student's interview invite,
+// whose delivery is recorded in student_lead.interview_invite_sent_at
+const EmailPurposeInterviewInvite = "interview_invite"
+
+// sendInterviewInvite queues the student's interview invite via Kafka like
+// SendCategorizedEmail, tagged with the student so its delivery can be tracked
+func sendInterviewInvite(ctx context.Context, studentID int, to, subject, body string) error {
+	return Publish(ctx, "emails", fmt.Sprintf("email-%s", to), map[string]interface{}{
+		"event":      "email.send",
+		"category":   EmailCategoryTransactional,
+		"purpose":    EmailPurposeInterviewInvite,
+		"student_id": studentID,
+		"recipient":  to,
+		"subject":    subject,
+		"body":       body,
+		"timestamp":  time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+func markInterviewInviteSent(ctx context.Context, studentID int) {
+	if _, err := db.DB.ExecContext(ctx,
+		"UPDATE student_lead SET interview_invite_sent_at = NOW() WHERE id = $1", studentID); err != nil {
+		logger.Warn("Error recording interview invite delivery for student %d: %v", studentID, err)
+	}
+}
+
+// ListPendingInterviews returns the interview requests since since that have not
+// completed, oldest first: interview.schedule events still in the event outbox, and
+// consumed ones whose meeting link is not stored yet or whose invite was not sent.
+// The payment webhook sets interview_scheduled_at itself, so only the meet link shows
+// the scheduler ran.
+func ListPendingInterviews(ctx context.Context, since time.Time) ([]models.PendingInterview, error) {
+	pending := []models.PendingInterview{}
+
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT sl.id, sl.name, sl.email, o.created_at
+		FROM event_outbox o
+		JOIN student_lead sl ON sl.id = (o.payload->>'student_id')::int
+		WHERE o.status = 'PENDING' AND o.payload->>'event' = 'interview.schedule' AND o.created_at >= $1`, since)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching queued interview requests: %w", err)
+	}
+	for rows.Next() {
+		p := models.PendingInterview{Stage: models.InterviewStageQueued}
+		if err := rows.Scan(&p.StudentID, &p.StudentName, utils.DecryptedPII(&p.StudentEmail), &p.RequestedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error scanning queued interview request: %w", err)
+		}
+		pending = append(pending, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.DB.QueryContext(ctx, `
+		WITH requested AS (
+			SELECT DISTINCT ON (lead_id) lead_id, occurred_at, created_at
+			FROM lead_event
+			WHERE event_type = 'interview.schedule' AND created_at >= $1
+			ORDER BY lead_id, created_at DESC
+		)
+		SELECT sl.id, sl.name, sl.email, r.occurred_at, r.created_at,
+			CASE WHEN COALESCE(sl.meet_link, '') <> '' THEN sl.interview_scheduled_at END,
+			COALESCE(sl.meet_link, ''),
+			CASE WHEN sl.interview_invite_sent_at >= r.created_at THEN sl.interview_invite_sent_at END,
+			(SELECT d.message_id::text FROM dlq_messages d
+			 WHERE d.resolved = FALSE AND d.value->>'event' = 'interview.schedule'
+			   AND d.value->>'student_id' = sl.id::text
+			 ORDER BY d.created_at DESC LIMIT 1)
+		FROM requested r
+		JOIN student_lead sl ON sl.id = r.lead_id
+		WHERE sl.deleted_at IS NULL
+		  AND (COALESCE(sl.meet_link, '') = ''
+		       OR sl.interview_invite_sent_at IS NULL OR sl.interview_invite_sent_at < r.created_at)`, since)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching consumed interview requests: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p models.PendingInterview
+		var consumedAt time.Time
+		var scheduledAt, inviteSentAt sql.NullTime
+		var dlqMessageID sql.NullString
+		if err := rows.Scan(&p.StudentID, &p.StudentName, utils.DecryptedPII(&p.StudentEmail), &p.RequestedAt, &consumedAt,
+			&scheduledAt, &p.MeetLink, &inviteSentAt, &dlqMessageID); err != nil {
+			return nil, fmt.Errorf("error scanning consumed interview request: %w", err)
+		}
+		p.ConsumedAt = &consumedAt
+		p.Stage = models.InterviewStageScheduling
+		if p.MeetLink != "" {
+			p.Stage = models.InterviewStageInvitePending
+		}
+		if scheduledAt.Valid {
+			p.ScheduledAt = &scheduledAt.Time
+		}
+		if inviteSentAt.Valid {
+			p.InviteSentAt = &inviteSentAt.Time
+		}
+		p.DLQMessageID = dlqMessageID.String
+		pending = append(pending, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := range pending {
+		pending[i].WaitingMinutes = int(now.Sub(pending[i].RequestedAt).Minutes())
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].RequestedAt.Before(pending[j].RequestedAt) })
+	return pending, nil
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrInterviewerNotFound is returned when no interviewer has the requested id
+	ErrInterviewerNotFound = errors.New("interviewer not found")
+	// ErrInvalidInterviewer is returned for a missing name or email, or a negative daily cap
+	ErrInvalidInterviewer = errors.New("invalid interviewer")
+)
+
+// defaultInterviewerDailyCap applies when an interviewer is created without a daily cap
+const defaultInterviewerDailyCap = 4
+
+// InterviewerRequest is the editable part of an interviewer
+type InterviewerRequest struct {
+	Name      string   `json:"name"`
+	Email     string   `json:"email"`
+	DailyCap  *int     `json:"daily_cap"`  // defaults to 4; 0 stops new assignments
+	CourseIDs []int64  `json:"course_ids"` // empty = any program
+	Skills    []string `json:"skills"`
+	IsActive  *bool    `json:"is_active"` // defaults to true
+}
+
+func (req *InterviewerRequest) normalize() error {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	if req.Name == "" || req.Email == "" {
+		return fmt.Errorf("%w: name and email are required", ErrInvalidInterviewer)
+	}
+	if req.DailyCap == nil {
+		dailyCap := defaultInterviewerDailyCap
+		req.DailyCap = &dailyCap
+	}
+	if *req.DailyCap < 0 {
+		return fmt.Errorf("%w: daily_cap cannot be negative", ErrInvalidInterviewer)
+	}
+	if req.IsActive == nil {
+		active := true
+		req.IsActive = &active
+	}
+	if req.CourseIDs == nil {
+		req.CourseIDs = []int64{}
+	}
+	skills := []string{}
+	for _, skill := range req.Skills {
+		if skill = strings.ToLower(strings.TrimSpace(skill)); skill != "" {
+			skills = append(skills, skill)
+		}
+	}
+	req.Skills = skills
+	return nil
+}
+
+const interviewerColumns = `id, name, email, daily_cap, course_ids, skills, is_active, created_at, updated_at`
+
+func scanInterviewer(row interface{ Scan(...interface{}) error }) (*models.Interviewer, error) {
+	var i models.Interviewer
+	err := row.Scan(&i.ID, &i.Name, &i.Email, &i.DailyCap, pq.Array(&i.CourseIDs), pq.Array(&i.Skills),
+		&i.IsActive, &i.CreatedAt, &i.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// interviewWindow returns the start of the day and of the (Monday-based) week containing at
+func interviewWindow(at time.Time) (dayStart, weekStart time.Time) {
+	dayStart = time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+	weekday := (int(dayStart.Weekday()) + 6) % 7 // Monday = 0
+	return dayStart, dayStart.AddDate(0, 0, -weekday)
+}
+
+// rowsQuerier is satisfied by both *sql.DB and *sql.Tx
+type rowsQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// interviewerLoad counts the interviews booked with each interviewer in the day and week
+// containing at, leaving out excludeStudentID (a student being rescheduled)
+func interviewerLoad(ctx context.Context, q rowsQuerier, at time.Time, excludeStudentID int) (map[int][2]int, error) {
+	dayStart, weekStart := interviewWindow(at)
+	rows, err := q.QueryContext(ctx, `
+		SELECT interviewer_id,
+			COUNT(*) FILTER (WHERE interview_scheduled_at >= $1 AND interview_scheduled_at < $2),
+			COUNT(*)
+		FROM student_lead
+		WHERE interviewer_id IS NOT NULL AND deleted_at IS NULL AND id <> $5
+			AND interview_scheduled_at >= $3 AND interview_scheduled_at < $4
+		GROUP BY interviewer_id`,
+		dayStart, dayStart.AddDate(0, 0, 1), weekStart, weekStart.AddDate(0, 0, 7), excludeStudentID)
+	if err != nil {
+		return nil, fmt.Errorf("error counting booked interviews: %w", err)
+	}
+	defer rows.Close()
+
+	load := map[int][2]int{}
+	for rows.Next() {
+		var id, today, week int
+		if err := rows.Scan(&id, &today, &week); err != nil {
+			return nil, fmt.Errorf("error reading booked interviews: %w", err)
+		}
+		load[id] = [2]int{today, week}
+	}
+	return load, rows.Err()
+}
+
+// ListInterviewers returns all interviewers with the interviews booked today and this week
+func ListInterviewers(ctx context.Context, now time.Time) ([]models.Interviewer, error) {
+	rows, err := db.DB.QueryContext(ctx, "SELECT "+interviewerColumns+" FROM interviewer ORDER BY name, id")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching interviewers: %w", err)
+	}
+	defer rows.Close()
+
+	interviewers := []models.Interviewer{}
+	for rows.Next() {
+		i, err := scanInterviewer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning interviewer: %w", err)
+		}
+		interviewers = append(interviewers, *i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	load, err := interviewerLoad(ctx, db.DB, now, 0)
+	if err != nil {
+		return nil, err
+	}
+	for idx := range interviewers {
+		counts := load[interviewers[idx].ID]
+		interviewers[idx].BookedToday, interviewers[idx].BookedThisWeek = counts[0], counts[1]
+	}
+	return interviewers, nil
+}
+
+// CreateInterviewer adds an interviewer
+func CreateInterviewer(ctx context.Context, req InterviewerRequest) (*models.Interviewer, error) {
+	if err := req.normalize(); err != nil {
+		return nil, err
+	}
+
+	i, err := scanInterviewer(db.DB.QueryRowContext(ctx, `
+		INSERT INTO interviewer (name, email, daily_cap, course_ids, skills, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+interviewerColumns,
+		req.Name, req.Email, *req.DailyCap, pq.Array(req.CourseIDs), pq.Array(req.Skills), *req.IsActive))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("%w: an interviewer with email %s already exists", ErrInvalidInterviewer, req.Email)
+		}
+		return nil, fmt.Errorf("error creating interviewer: %w", err)
+	}
+	return i, nil
+}
+
+// UpdateInterviewer replaces the editable fields of an interviewer
+func UpdateInterviewer(ctx context.Context, id int, req InterviewerRequest) (*models.Interviewer, error) {
+	if err := req.normalize(); err != nil {
+		return nil, err
+	}
+
+	i, err := scanInterviewer(db.DB.QueryRowContext(ctx, `
+		UPDATE interviewer
+		SET name = $1, email = $2, daily_cap = $3, course_ids = $4, skills = $5, is_active = $6, updated_at = NOW()
+		WHERE id = $7
+		RETURNING `+interviewerColumns,
+		req.Name, req.Email, *req.DailyCap, pq.Array(req.CourseIDs), pq.Array(req.Skills), *req.IsActive, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrInterviewerNotFound
+	}
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("%w: an interviewer with email %s already exists", ErrInvalidInterviewer, req.Email)
+		}
+		return nil, fmt.Errorf("error updating interviewer: %w", err)
+	}
+	return i, nil
+}
+
+// assignInterviewer picks the interviewer for a student's interview at the given time and
+// records it on the lead. Interviewers at their daily cap or not covering the student's
+// program are skipped; among the rest, those whose skills match the student's education
+// come first, then the fewest interviews booked that week, then that day. Returns nil
+// when nobody is available, leaving the interview unassigned.
+func assignInterviewer(ctx context.Context, tx *sql.Tx, studentID int, at time.Time) (*models.Interviewer, error) {
+	var education string
+	var courseID sql.NullInt64
+	err := tx.QueryRowContext(ctx,
+		"SELECT COALESCE(education, ''), selected_course_id FROM student_lead WHERE id = $1",
+		studentID).Scan(&education, &courseID)
+	if err == sql.ErrNoRows {
+		return nil, ErrLeadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading lead: %w", err)
+	}
+
+	// Lock the active interviewers so concurrent schedulings cannot both take the last slot
+	rows, err := tx.QueryContext(ctx,
+		"SELECT "+interviewerColumns+" FROM interviewer WHERE is_active = TRUE ORDER BY id FOR UPDATE")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching interviewers: %w", err)
+	}
+	var candidates []models.Interviewer
+	for rows.Next() {
+		i, err := scanInterviewer(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error scanning interviewer: %w", err)
+		}
+		candidates = append(candidates, *i)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	load, err := interviewerLoad(ctx, tx, at, studentID)
+	if err != nil {
+		return nil, err
+	}
+
+	education = strings.ToLower(education)
+	type scored struct {
+		interviewer models.Interviewer
+		skillMatch  bool
+	}
+	var eligible []scored
+	for _, i := range candidates {
+		counts := load[i.ID]
+		i.BookedToday, i.BookedThisWeek = counts[0], counts[1]
+		if i.BookedToday >= i.DailyCap {
+			continue
+		}
+		if len(i.CourseIDs) > 0 && courseID.Valid && !containsInt64(i.CourseIDs, courseID.Int64) {
+			continue
+		}
+		match := false
+		for _, skill := range i.Skills {
+			if education != "" && strings.Contains(education, skill) {
+				match = true
+				break
+			}
+		}
+		eligible = append(eligible, scored{interviewer: i, skillMatch: match})
+	}
+	if len(eligible) == 0 {
+		// Drop an interviewer left over from an earlier booking of this student
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE student_lead SET interviewer_id = NULL WHERE id = $1", studentID); err != nil {
+			return nil, fmt.Errorf("error clearing interviewer: %w", err)
+		}
+		return nil, nil
+	}
+
+	sort.SliceStable(eligible, func(a, b int) bool {
+		x, y := eligible[a], eligible[b]
+		if x.skillMatch != y.skillMatch {
+			return x.skillMatch
+		}
+		if x.interviewer.BookedThisWeek != y.interviewer.BookedThisWeek {
+			return x.interviewer.BookedThisWeek < y.interviewer.BookedThisWeek
+		}
+		return x.interviewer.BookedToday < y.interviewer.BookedToday
+	})
+
+	chosen := eligible[0].interviewer
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE student_lead SET interviewer_id = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2",
+		chosen.ID, studentID); err != nil {
+		return nil, fmt.Errorf("error assigning interviewer: %w", err)
+	}
+	return &chosen, nil
+}
+
+func containsInt64(values []int64, v int64) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// isUniqueViolation reports whether err is a PostgreSQL unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -0,0 +1,369 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"admission-module/utils"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// ErrInvoiceNotFound is returned when an invoice does not exist
+var ErrInvoiceNotFound = errors.New("invoice not found")
+
+const invoiceColumns = `
+	i.id, i.invoice_number, i.student_id, COALESCE(l.name, ''), COALESCE(l.email, ''), COALESCE(l.phone, ''),
+	i.course_id, COALESCE(c.name, ''), COALESCE(i.order_id, ''), COALESCE(i.payment_id, ''),
+	i.installment_no, i.installment_count, i.tax_rate, i.taxable_amount, i.cgst_amount, i.sgst_amount,
+	i.igst_amount, COALESCE(i.place_of_supply, ''), i.total_amount, i.status, i.issued_at, i.paid_at,
+	COALESCE(i.storage_key, '')`
+
+const invoiceFrom = `
+	FROM invoice i
+	LEFT JOIN student_lead l ON l.id = i.student_id
+	LEFT JOIN course c ON c.id = i.course_id`
+
+// scanInvoice reads an invoice row selected with invoiceColumns
+func scanInvoice(scanner interface{ Scan(...interface{}) error }) (*models.Invoice, error) {
+	var inv models.Invoice
+	var paidAt sql.NullTime
+	err := scanner.Scan(
+		&inv.ID, &inv.InvoiceNumber, &inv.StudentID, &inv.StudentName,
+		utils.DecryptedPII(&inv.StudentEmail), utils.DecryptedPII(&inv.StudentPhone),
+		&inv.CourseID, &inv.CourseName, &inv.OrderID, &inv.PaymentID,
+		&inv.InstallmentNo, &inv.InstallmentCount, &inv.TaxRate, &inv.TaxableAmount, &inv.CGSTAmount, &inv.SGSTAmount,
+		&inv.IGSTAmount, &inv.PlaceOfSupply, &inv.TotalAmount, &inv.Status, &inv.IssuedAt, &paidAt, &inv.StorageKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if paidAt.Valid {
+		inv.PaidAt = &paidAt.Time
+	}
+	inv.DownloadURL = fmt.Sprintf("%s/invoices/%d/download", config.AppConfig.AppBaseURL, inv.ID)
+	return &inv, nil
+}
+
+// issueCourseFeeInvoice issues (or, while unpaid, brings up to date) the invoice of the
+// course payment with the given order. Today a course fee is a single installment, so each
+// course payment has one invoice (1 of 1); the number is kept when the order is retried.
+func issueCourseFeeInvoice(ctx context.Context, q querier, orderID string) (*models.Invoice, error) {
+	var coursePaymentID, studentID, courseID int
+	var amount float64
+	err := q.QueryRowContext(ctx,
+		"SELECT id, student_id, course_id, amount FROM course_payment WHERE order_id = $1", orderID,
+	).Scan(&coursePaymentID, &studentID, &courseID, &amount)
+	if err != nil {
+		return nil, fmt.Errorf("error loading course payment for invoice: %w", err)
+	}
+
+	const installmentNo, installmentCount = 1, 1
+	taxRate := config.AppConfig.InvoiceTaxRate
+	placeOfSupply, interState, err := placeOfSupply(ctx, q, studentID)
+	if err != nil {
+		return nil, err
+	}
+	taxable, cgst, sgst, igst := gstBreakdown(amount, taxRate, interState)
+
+	var invoiceID int
+	var status string
+	err = q.QueryRowContext(ctx,
+		"SELECT id, status FROM invoice WHERE course_payment_id = $1 AND installment_no = $2 FOR UPDATE",
+		coursePaymentID, installmentNo).Scan(&invoiceID, &status)
+	switch {
+	case err == sql.ErrNoRows:
+		number, err := nextDocumentNumber(ctx, q, documentSeriesInvoice, config.AppConfig.InvoicePrefix, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("error numbering invoice: %w", err)
+		}
+		err = q.QueryRowContext(ctx, `
+			INSERT INTO invoice (invoice_number, student_id, course_id, course_payment_id, order_id, installment_no,
+				installment_count, tax_rate, taxable_amount, cgst_amount, sgst_amount, igst_amount, place_of_supply,
+				total_amount, status)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, ''), $14, $15)
+			RETURNING id`,
+			number, studentID, courseID, coursePaymentID, orderID, installmentNo, installmentCount,
+			taxRate, taxable, cgst, sgst, igst, placeOfSupply, amount, models.InvoiceStatusIssued,
+		).Scan(&invoiceID)
+		if err != nil {
+			return nil, fmt.Errorf("error issuing invoice: %w", err)
+		}
+	case err != nil:
+		return nil, fmt.Errorf("error loading invoice: %w", err)
+	case status == models.InvoiceStatusIssued:
+		// A retried order may carry a changed course fee or place of supply; the document is
+		// re-rendered then
+		_, err = q.ExecContext(ctx, `
+			UPDATE invoice SET order_id = $1, tax_rate = $2, taxable_amount = $3, cgst_amount = $4, sgst_amount = $5,
+				igst_amount = $6, place_of_supply = NULLIF($7, ''),
+				storage_key = CASE WHEN total_amount = $8 AND tax_rate = $2
+					AND COALESCE(place_of_supply, '') = $7 THEN storage_key END,
+				total_amount = $8, updated_at = NOW()
+			WHERE id = $9`,
+			orderID, taxRate, taxable, cgst, sgst, igst, placeOfSupply, amount, invoiceID)
+		if err != nil {
+			return nil, fmt.Errorf("error updating invoice: %w", err)
+		}
+	}
+
+	inv, err := scanInvoice(q.QueryRowContext(ctx, "SELECT "+invoiceColumns+invoiceFrom+" WHERE i.id = $1", invoiceID))
+	if err != nil {
+		return nil, fmt.Errorf("error loading invoice: %w", err)
+	}
+	return inv, nil
+}
+
+// markInvoicePaid records the captured payment on the invoice of a course payment. The
+// stored document is dropped so it is re-rendered showing the payment.
+func markInvoicePaid(ctx context.Context, tx *sql.Tx, coursePaymentID int, paymentID string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE invoice SET status = $1, payment_id = NULLIF($2, ''), paid_at = NOW(), storage_key = NULL, updated_at = NOW()
+		WHERE course_payment_id = $3 AND status = $4`,
+		models.InvoiceStatusPaid, paymentID, coursePaymentID, models.InvoiceStatusIssued)
+	if err != nil {
+		return fmt.Errorf("error marking invoice paid: %w", err)
+	}
+	return nil
+}
+
+// gstBreakdown splits a tax-inclusive amount into its taxable value and the GST at
+// ratePercent: all IGST for an inter-state supply, otherwise equal CGST and SGST shares
+func gstBreakdown(total, ratePercent float64, interState bool) (taxable, cgst, sgst, igst float64) {
+	taxable = roundAmount(total / (1 + ratePercent/100))
+	tax := roundAmount(total - taxable)
+	if interState {
+		return taxable, 0, 0, tax
+	}
+	cgst = roundAmount(tax / 2)
+	return taxable, cgst, roundAmount(tax - cgst), 0
+}
+
+// placeOfSupply returns the GST state code of a student, or of the institution when the
+// student's is not known, and whether the supply is inter-state
+func placeOfSupply(ctx context.Context, q querier, studentID int) (string, bool, error) {
+	var stateCode string
+	err := q.QueryRowContext(ctx, "SELECT COALESCE(state_code, '') FROM student_lead WHERE id = $1", studentID).Scan(&stateCode)
+	if err != nil && err != sql.ErrNoRows {
+		return "", false, fmt.Errorf("error loading place of supply: %w", err)
+	}
+	institution := config.AppConfig.InstitutionStateCode
+	if stateCode == "" {
+		return institution, false, nil
+	}
+	return stateCode, institution != "" && stateCode != institution, nil
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// Document series numbered per financial year in document_number_series
+const (
+	documentSeriesInvoice = "invoice"
+	documentSeriesReceipt = "receipt"
+)
+
+// nextDocumentNumber takes the next serial of series in the financial year of at and
+// formats it as prefix/<financial year>/<serial>, e.g. INV/2026-27/000042. The serial's row
+// stays locked until q's transaction ends, so numbers have no gaps.
+func nextDocumentNumber(ctx context.Context, q querier, series, prefix string, at time.Time) (string, error) {
+	year := financialYear(at)
+	var serial int64
+	err := q.QueryRowContext(ctx, `
+		INSERT INTO document_number_series (series, financial_year, last_serial) VALUES ($1, $2, 1)
+		ON CONFLICT (series, financial_year) DO UPDATE SET last_serial = document_number_series.last_serial + 1
+		RETURNING last_serial`, series, year).Scan(&serial)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s/%06d", prefix, year, serial), nil
+}
+
+// financialYear names the Indian financial year of t, which starts on 1 April: 2026-27
+func financialYear(t time.Time) string {
+	year := t.Year()
+	if t.Month() < time.April {
+		year--
+	}
+	return fmt.Sprintf("%d-%02d", year, (year+1)%100)
+}
+
+// invoiceStorageKey is where the rendered document of an invoice is stored
+func invoiceStorageKey(inv *models.Invoice) string {
+	return "invoices/" + strings.ReplaceAll(inv.InvoiceNumber, "/", "-") + ".html"
+}
+
+// storeInvoiceDocument renders the invoice, saves it to document storage and records the key
+func storeInvoiceDocument(ctx context.Context, q querier, inv *models.Invoice) error {
+	key := invoiceStorageKey(inv)
+	if err := GetDocumentStorage().Save(ctx, key, strings.NewReader(buildInvoiceDocument(inv))); err != nil {
+		return fmt.Errorf("error storing invoice %s: %w", inv.InvoiceNumber, err)
+	}
+	if _, err := q.ExecContext(ctx, "UPDATE invoice SET storage_key = $1 WHERE id = $2", key, inv.ID); err != nil {
+		return fmt.Errorf("error recording invoice document: %w", err)
+	}
+	inv.StorageKey = key
+	return nil
+}
+
+// ListStudentInvoices returns the invoices of a student, newest first
+func ListStudentInvoices(ctx context.Context, studentID int) ([]models.Invoice, error) {
+	rows, err := db.DB.QueryContext(ctx,
+		"SELECT "+invoiceColumns+invoiceFrom+" WHERE i.student_id = $1 ORDER BY i.issued_at DESC, i.id DESC", studentID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing invoices: %w", err)
+	}
+	defer rows.Close()
+
+	invoices := []models.Invoice{}
+	for rows.Next() {
+		inv, err := scanInvoice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning invoice: %w", err)
+		}
+		invoices = append(invoices, *inv)
+	}
+	return invoices, rows.Err()
+}
+
+// OpenInvoice returns an invoice and its document, rendering the document first when it
+// has not been stored yet or changed since
+func OpenInvoice(ctx context.Context, invoiceID int) (*models.Invoice, io.ReadCloser, error) {
+	inv, err := scanInvoice(db.DB.QueryRowContext(ctx, "SELECT "+invoiceColumns+invoiceFrom+" WHERE i.id = $1", invoiceID))
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching invoice: %w", err)
+	}
+
+	if inv.StorageKey != "" {
+		if file, err := GetDocumentStorage().Open(ctx, inv.StorageKey); err == nil {
+			return inv, file, nil
+		}
+		logger.Warn("Invoice %s document missing from storage, rendering it again", inv.InvoiceNumber)
+	}
+	if err := storeInvoiceDocument(ctx, db.DB, inv); err != nil {
+		return nil, nil, err
+	}
+	file, err := GetDocumentStorage().Open(ctx, inv.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening invoice: %w", err)
+	}
+	return inv, file, nil
+}
+
+// InvoiceFileName is the download name of an invoice document
+func InvoiceFileName(inv *models.Invoice) string {
+	return strings.ReplaceAll(inv.InvoiceNumber, "/", "-") + ".html"
+}
+
+// buildInvoiceDocument renders an invoice as a standalone HTML document
+func buildInvoiceDocument(inv *models.Invoice) string {
+	cfg := config.AppConfig
+	var institution strings.Builder
+	fmt.Fprintf(&institution, "<p><strong>%s</strong>", html.EscapeString(cfg.InstitutionName))
+	if cfg.InstitutionAddress != "" {
+		fmt.Fprintf(&institution, "<br/>%s", html.EscapeString(cfg.InstitutionAddress))
+	}
+	if cfg.InstitutionGSTIN != "" {
+		fmt.Fprintf(&institution, "<br/>GSTIN: %s", html.EscapeString(cfg.InstitutionGSTIN))
+	}
+	if cfg.InstitutionEmail != "" {
+		fmt.Fprintf(&institution, "<br/>%s", html.EscapeString(cfg.InstitutionEmail))
+	}
+	institution.WriteString("</p>")
+
+	status := "Payment due"
+	if inv.Status == models.InvoiceStatusPaid && inv.PaidAt != nil {
+		status = fmt.Sprintf("Paid on %s (payment %s)", inv.PaidAt.Format("02 Jan 2006"), html.EscapeString(inv.PaymentID))
+	}
+	var taxRows string
+	if inv.IGSTAmount > 0 {
+		taxRows = fmt.Sprintf(`<tr><td>IGST @ %.2f%%</td><td class="amount">%.2f</td></tr>`, inv.TaxRate, inv.IGSTAmount)
+	} else {
+		halfRate := inv.TaxRate / 2
+		taxRows = fmt.Sprintf(`<tr><td>CGST @ %.2f%%</td><td class="amount">%.2f</td></tr>
+        <tr><td>SGST @ %.2f%%</td><td class="amount">%.2f</td></tr>`, halfRate, inv.CGSTAmount, halfRate, inv.SGSTAmount)
+	}
+	placeOfSupply := "-"
+	if inv.PlaceOfSupply != "" {
+		placeOfSupply = inv.PlaceOfSupply
+	}
+
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <title>Invoice %s</title>
+    <style>
+        body { font-family: Arial, sans-serif; color: #333; max-width: 700px; margin: 0 auto; padding: 20px; }
+        table { width: 100%%; border-collapse: collapse; margin: 15px 0; }
+        th, td { border: 1px solid #ddd; padding: 8px; text-align: left; }
+        td.amount { text-align: right; }
+    </style>
+</head>
+<body>
+    <h2>Tax Invoice</h2>
+    %s
+    <table>
+        <tr><th>Invoice Number</th><td>%s</td><th>Date</th><td>%s</td></tr>
+        <tr><th>Order</th><td>%s</td><th>Installment</th><td>%d of %d</td></tr>
+        <tr><th>Place of Supply</th><td colspan="3">State code %s</td></tr>
+    </table>
+    <p><strong>Billed to:</strong><br/>%s<br/>%s<br/>%s<br/>Student ID %d</p>
+    <table>
+        <tr><th>Description</th><th>Amount (INR)</th></tr>
+        <tr><td>Course fee: %s</td><td class="amount">%.2f</td></tr>
+        %s
+        <tr><th>Total</th><th class="amount">%.2f</th></tr>
+    </table>
+    <p><strong>Status:</strong> %s</p>
+    <p>This is a computer generated invoice and does not require a signature.</p>
+</body>
+</html>`,
+		html.EscapeString(inv.InvoiceNumber),
+		institution.String(),
+		html.EscapeString(inv.InvoiceNumber), inv.IssuedAt.Format("02 Jan 2006"),
+		html.EscapeString(inv.OrderID), inv.InstallmentNo, inv.InstallmentCount, html.EscapeString(placeOfSupply),
+		html.EscapeString(inv.StudentName), html.EscapeString(inv.StudentEmail), html.EscapeString(inv.StudentPhone), inv.StudentID,
+		html.EscapeString(inv.CourseName), inv.TaxableAmount,
+		taxRows,
+		inv.TotalAmount,
+		status)
+}
+
+// invoiceAttachment returns the local file of the invoice document for an email
+// attachment, or "" when storage has no local files
+func invoiceAttachment(ctx context.Context, q querier, inv *models.Invoice) string {
+	if inv.StorageKey == "" {
+		if err := storeInvoiceDocument(ctx, q, inv); err != nil {
+			logger.Warn("Error storing invoice %s for attachment: %v", inv.InvoiceNumber, err)
+			return ""
+		}
+	}
+	return localDocumentPath(inv.StorageKey)
+}
+
+// localDocumentPath returns the local file of a stored document, or "" when storage has
+// no local files
+func localDocumentPath(key string) string {
+	local, ok := GetDocumentStorage().(*LocalStorage)
+	if !ok {
+		return ""
+	}
+	path, err := local.path(key)
+	if err != nil {
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/models"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ErrInvalidFinancialYear is returned for a financial year not written like 2026-27
+var ErrInvalidFinancialYear = errors.New("invalid financial year")
+
+var invoiceExportHeaders = []string{
+	"Invoice Number", "Invoice Date", "Student", "Course", "Place of Supply", "Tax Rate",
+	"Taxable Amount", "CGST", "SGST", "IGST", "Total", "Status", "Order ID", "Payment ID",
+}
+
+// invoiceExportTextColumns are the free-text columns of invoiceExportHeaders: student and course
+var invoiceExportTextColumns = []int{2, 3}
+
+// financialYearBounds returns the first day of a financial year written like 2026-27 and
+// the first day of the next one
+func financialYearBounds(year string) (time.Time, time.Time, error) {
+	var start, end int
+	if _, err := fmt.Sscanf(year, "%4d-%2d", &start, &end); err != nil {
+		return time.Time{}, time.Time{}, ErrInvalidFinancialYear
+	}
+	from := time.Date(start, time.April, 1, 0, 0, 0, 0, time.Local)
+	if financialYear(from) != year {
+		return time.Time{}, time.Time{}, ErrInvalidFinancialYear
+	}
+	return from, from.AddDate(1, 0, 0), nil
+}
+
+// CurrentFinancialYear names the financial year running now, e.g. 2026-27
+func CurrentFinancialYear() string {
+	return financialYear(time.Now())
+}
+
+// ListInvoicesForExport returns the invoices issued in a financial year (like 2026-27) in
+// the order they were numbered, for the accounting export
+func ListInvoicesForExport(ctx context.Context, year string) ([]models.Invoice, error) {
+	from, to, err := financialYearBounds(year)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Reader().QueryContext(ctx,
+		"SELECT "+invoiceColumns+invoiceFrom+" WHERE i.issued_at >= $1 AND i.issued_at < $2 ORDER BY i.issued_at, i.id", from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching invoices for export: %w", err)
+	}
+	defer rows.Close()
+
+	invoices := []models.Invoice{}
+	for rows.Next() {
+		inv, err := scanInvoice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading invoices for export: %w", err)
+		}
+		invoices = append(invoices, *inv)
+	}
+	return invoices, rows.Err()
+}
+
+// WriteInvoicesExport writes invoices to w in the requested format (xlsx or csv)
+func WriteInvoicesExport(w io.Writer, format string, invoices []models.Invoice) error {
+	switch format {
+	case ExportFormatCSV:
+		return writeInvoicesCSV(w, invoices)
+	case ExportFormatXLSX:
+		return writeInvoicesXLSX(w, invoices)
+	default:
+		return fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+func invoiceExportRecord(inv models.Invoice) []string {
+	amount := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	return []string{
+		inv.InvoiceNumber, inv.IssuedAt.Format("2006-01-02"), inv.StudentName, inv.CourseName, inv.PlaceOfSupply,
+		amount(inv.TaxRate), amount(inv.TaxableAmount), amount(inv.CGSTAmount), amount(inv.SGSTAmount),
+		amount(inv.IGSTAmount), amount(inv.TotalAmount), inv.Status, inv.OrderID, inv.PaymentID,
+	}
+}
+
+func writeInvoicesCSV(w io.Writer, invoices []models.Invoice) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(invoiceExportHeaders); err != nil {
+		return err
+	}
+	for _, inv := range invoices {
+		if err := writer.Write(csvRecord(invoiceExportRecord(inv), invoiceExportTextColumns)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeInvoicesXLSX writes amounts as numbers so they can be summed in the sheet
+func writeInvoicesXLSX(w io.Writer, invoices []models.Invoice) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheetName := "Invoices"
+	if err := f.SetSheetName("Sheet1", sheetName); err != nil {
+		return fmt.Errorf("failed to name sheet: %w", err)
+	}
+
+	sw, err := f.NewStreamWriter(sheetName)
+	if err != nil {
+		return fmt.Errorf("failed to create stream writer: %w", err)
+	}
+
+	header := make([]interface{}, len(invoiceExportHeaders))
+	for i, h := range invoiceExportHeaders {
+		header[i] = h
+	}
+	if err := sw.SetRow("A1", header); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+
+	for i, inv := range invoices {
+		row := []interface{}{
+			inv.InvoiceNumber, inv.IssuedAt.Format("2006-01-02"), inv.StudentName, inv.CourseName, inv.PlaceOfSupply,
+			inv.TaxRate, inv.TaxableAmount, inv.CGSTAmount, inv.SGSTAmount, inv.IGSTAmount, inv.TotalAmount,
+			inv.Status, inv.OrderID, inv.PaymentID,
+		}
+		cell, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			return err
+		}
+		if err := sw.SetRow(cell, row); err != nil {
+			return fmt.Errorf("failed to write row %d: %w", i+2, err)
+		}
+	}
+
+	if err := sw.Flush(); err != nil {
+		return fmt.Errorf("failed to flush sheet: %w", err)
+	}
+
+	_, err = f.WriteTo(w)
+	return err
+}
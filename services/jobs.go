@@ -0,0 +1,141 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/models"
+	"admission-module/services/scheduler"
+	"context"
+	"time"
+)
+
+// RegisterScheduledJobs registers the background jobs with the scheduler.
+// Reminder, escalation, offer expiry, inbound mail, retention, report, snapshot, integration health and payment reconciliation schedules come from config; the queue drainers
+// (DLQ retry, event and email outboxes, document worker, enrollment sync, API usage flush) poll at fixed intervals,
+// and upcoming table partitions are created daily.
+func RegisterScheduledJobs() error {
+	jobs := []scheduler.Job{
+		{
+			Name: "dlq-retry",
+			Spec: config.AppConfig.DLQRetrySchedule,
+			Run:  runDLQRetry,
+		},
+		{
+			Name: "event-outbox-relay",
+			Spec: "@every 2s",
+			Run:  RelayEventOutbox,
+		},
+		{
+			Name: "email-outbox-flush",
+			Spec: "@every 30s",
+			Run:  FlushEmailOutbox,
+		},
+		{
+			Name: "document-worker",
+			Spec: "@every 10s",
+			Run:  ProcessDocumentJobs,
+		},
+		{
+			Name: "enrollment-sync",
+			Spec: "@every 1m",
+			Run:  SyncEnrollments,
+		},
+		{
+			Name: "api-usage-flush",
+			Spec: "@every 1m",
+			Run:  FlushAPIUsage,
+		},
+		{
+			Name:   "follow-up-reminders",
+			Spec:   config.AppConfig.FollowUpReminderSchedule,
+			Jitter: 5 * time.Minute,
+			Run: func(ctx context.Context) error {
+				_, err := SendFollowUpReminders(ctx)
+				return err
+			},
+		},
+		{
+			Name:   "lead-escalation",
+			Spec:   config.AppConfig.LeadEscalationSchedule,
+			Jitter: 5 * time.Minute,
+			Run: func(ctx context.Context) error {
+				_, err := EscalateStaleLeads(ctx)
+				return err
+			},
+		},
+		{
+			Name: "offer-expiry",
+			Spec: config.AppConfig.OfferExpirySchedule,
+			Run: func(ctx context.Context) error {
+				_, err := ExpireUnpaidOffers(ctx)
+				return err
+			},
+		},
+		{
+			Name: "inbound-mail-import",
+			Spec: config.AppConfig.InboundMailSchedule,
+			Run:  RunInboundMailImport,
+		},
+		{
+			Name:   "retention",
+			Spec:   config.AppConfig.RetentionSchedule,
+			Jitter: 15 * time.Minute,
+			Run: func(ctx context.Context) error {
+				_, err := RunRetention(ctx, config.AppConfig.RetentionDryRun)
+				return err
+			},
+		},
+		{
+			Name: "webhook-partitions",
+			Spec: "@daily",
+			Run:  EnsurePartitions,
+		},
+		{
+			Name: "webhook-slo-check",
+			Spec: config.AppConfig.WebhookSLOSchedule,
+			Run:  CheckWebhookSLO,
+		},
+		{
+			Name: "integration-health",
+			Spec: config.AppConfig.IntegrationHealthSchedule,
+			Run:  CheckLeadIntegrations,
+		},
+		{
+			Name: "payment-reconciliation",
+			Spec: config.AppConfig.PaymentReconciliationSchedule,
+			Run:  ReconcilePayments,
+		},
+		{
+			Name: "metrics-snapshot",
+			Spec: config.AppConfig.MetricsSnapshotSchedule,
+			Run:  RecordMetricsSnapshot,
+		},
+		{
+			Name:   "daily-manager-summary",
+			Spec:   config.AppConfig.DailyReportSchedule,
+			Jitter: 5 * time.Minute,
+			Run: func(ctx context.Context) error {
+				return SendManagerSummary(ctx, models.SummaryPeriodDaily)
+			},
+		},
+		{
+			Name: "admin-daily-summary",
+			Spec: config.AppConfig.AdminSummarySchedule,
+			Run:  SendAdminDailySummary,
+		},
+		{
+			Name:   "weekly-manager-summary",
+			Spec:   config.AppConfig.WeeklyReportSchedule,
+			Jitter: 5 * time.Minute,
+			Run: func(ctx context.Context) error {
+				return SendManagerSummary(ctx, models.SummaryPeriodWeekly)
+			},
+		},
+	}
+
+	for _, job := range jobs {
+		if err := scheduler.Register(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
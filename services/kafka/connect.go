@@ -7,6 +7,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
@@ -15,10 +16,8 @@ import (
 )
 
 var (
-	dlqProducer    *kafka.Writer
-	dlqMutex       sync.Mutex
-	dlqRetryTicker *time.Ticker
-	stopDLQRetry   chan bool
+	dlqProducer *kafka.Writer
+	dlqMutex    sync.Mutex
 )
 
 // InitDLQProducer initializes a Kafka writer for the DLQ topic
@@ -46,6 +45,12 @@ func InitDLQProducer() {
 		return
 	}
 
+	transport, _, err := connectionSecurity()
+	if err != nil {
+		logger.Error("Kafka DLQ producer disabled: %v", err)
+		return
+	}
+
 	dlqProducer = &kafka.Writer{
 		Addr:         kafka.TCP(validBrokers...),
 		Topic:        config.AppConfig.KafkaDLQTopic,
@@ -53,11 +58,14 @@ func InitDLQProducer() {
 		Async:        false,
 		WriteTimeout: 10 * time.Second,
 		RequiredAcks: kafka.RequireAll,
+		Transport:    transport,
 	}
 }
 
 // SendToDLQ publishes a failed message to the Dead Letter Queue
-// Stores both in Kafka and in database for later retrieval
+// Stores both in Kafka and in database for later retrieval. The database copy is written
+// first and shares its message_id with the Kafka copy, so the DLQ topic consumer
+// recognises the message instead of storing it twice.
 func SendToDLQ(topic, key string, value []byte, errorMsg string) error {
 	dlqMutex.Lock()
 	if dlqProducer == nil && config.AppConfig.KafkaBrokers != "" {
@@ -67,16 +75,21 @@ func SendToDLQ(topic, key string, value []byte, errorMsg string) error {
 	}
 	defer dlqMutex.Unlock()
 
-	// If DLQ producer is available, attempt a single publish and on failure
-	// fall back to storing the message in the DB.
+	messageID := newDLQMessageID()
+	_, storeErr := insertDLQMessage(dlqRecord{MessageID: messageID, Topic: topic, Key: key, Value: value, ErrorMsg: errorMsg})
+
+	// If DLQ producer is available, attempt a single publish; the database copy above
+	// is what gets retried
 	if dlqProducer != nil && config.AppConfig.KafkaDLQTopic != "" {
 		dlqMessage := map[string]interface{}{
-			"original_topic": topic,
-			"original_key":   key,
-			"original_value": string(value),
-			"error_message":  errorMsg,
-			"timestamp":      time.Now().Unix(),
-			"failure_reason": "Processing failed",
+			"message_id":       messageID,
+			"original_topic":   topic,
+			"original_key":     key,
+			"original_value":   string(redactDLQPayload(value, config.AppConfig.DLQMaxFieldChars)),
+			"payload_redacted": true,
+			"error_message":    errorMsg,
+			"timestamp":        time.Now().Unix(),
+			"failure_reason":   "Processing failed",
 		}
 
 		dlqPayload, err := json.Marshal(dlqMessage)
@@ -86,65 +99,180 @@ func SendToDLQ(topic, key string, value []byte, errorMsg string) error {
 			err = dlqProducer.WriteMessages(ctx, msg)
 			cancel()
 			if err == nil {
-				// also store in DB for persistence
-				_ = StoreDLQMessage(topic, key, value, errorMsg)
+				if storeErr != nil {
+					logger.Error("DLQ message %s published but not stored in the database: %v", messageID, storeErr)
+				}
 				return nil
-			} // If topic is missing on broker, disable DLQ producer and fallback
+			} // If topic is missing on broker, disable DLQ producer
 			if strings.Contains(err.Error(), "Unknown Topic Or Partition") || strings.Contains(strings.ToLower(err.Error()), "unknown topic") {
 				dlqProducer = nil
-				// fallthrough to store in DB
 			}
 		}
 	}
 
-	// Always store in database for persistence
-	return StoreDLQMessage(topic, key, value, errorMsg)
+	return storeErr
 }
 
 // StoreDLQMessage stores a failed message in the database
 func StoreDLQMessage(topic, key string, value []byte, errorMsg string) error {
+	_, err := insertDLQMessage(dlqRecord{Topic: topic, Key: key, Value: value, ErrorMsg: errorMsg})
+	return err
+}
+
+// dlqRecord is a failed message to store in dlq_messages
+type dlqRecord struct {
+	MessageID string // generated when empty
+	Topic     string
+	Key       string
+	Value     []byte
+	ErrorMsg  string
+	// QuarantineNote quarantines the message on arrival, e.g. when only a redacted
+	// payload is available so retrying it would process masked data
+	QuarantineNote string
+}
+
+// insertDLQMessage stores a DLQ message and reports whether a row was inserted; a message
+// whose message_id is already stored is skipped
+func insertDLQMessage(rec dlqRecord) (bool, error) {
 	// Get database connection from your db package
 	dbConn := getDBConnection()
 	if dbConn == nil {
-		return nil
+		return false, nil
 	}
 
+	// value holds the redacted copy shown in the admin APIs; raw_value keeps the original,
+	// encrypted with the PII key, for retries and audited reveals until the message is resolved.
+	// max_retries is taken from the topic's retry policy (falling back to the '*' policy, then
+	// DLQ_RETRY_MAX_RETRIES); the first automatic retry is due right away
 	query := `
-		INSERT INTO dlq_messages (message_id, topic, key, value, error_message, created_at)
-		VALUES (gen_random_uuid(), $1, $2, $3::jsonb, $4, NOW())
+		INSERT INTO dlq_messages (message_id, topic, key, value, raw_value, payload_size, error_message, max_retries,
+			next_retry_at, quarantined_at, escalated_at, notes, created_at)
+		VALUES (COALESCE(NULLIF($8, '')::uuid, gen_random_uuid()), $1, $2, $3::jsonb, $4, $5, $6, COALESCE(
+			(SELECT max_retries FROM dlq_retry_policy WHERE topic IN ($1, '*') ORDER BY topic = '*' LIMIT 1), $7), NOW(),
+			CASE WHEN $9 <> '' THEN NOW() END, CASE WHEN $9 <> '' THEN NOW() END, NULLIF($9, ''), NOW())
 		ON CONFLICT (message_id) DO NOTHING
 	`
 
-	// Pass value as []byte directly - PostgreSQL will handle JSONB conversion
-	_, err := dbConn.Exec(query, topic, key, value, errorMsg)
+	redacted := redactDLQPayload(rec.Value, config.AppConfig.DLQMaxFieldChars)
+	raw := retainedRawPayload(rec.Value)
+	if rec.QuarantineNote != "" {
+		raw = nil
+	}
+	raw, err := encryptRawPayload(raw)
+	if err != nil {
+		return false, err
+	}
+	result, err := dbConn.Exec(query, rec.Topic, rec.Key, redacted, raw, len(rec.Value), rec.ErrorMsg,
+		config.AppConfig.DLQRetryMaxRetries, rec.MessageID, rec.QuarantineNote)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	return nil
+	n, _ := result.RowsAffected()
+	return n > 0, nil
 }
 
 func GetDLQMessages(limit int) ([]map[string]interface{}, error) {
+	unresolved := false
+	page, err := ListDLQMessages(DLQFilter{Resolved: &unresolved, Limit: limit})
+	if err != nil || page == nil {
+		return nil, err
+	}
+	return page.Messages, nil
+}
+
+// DLQFilter narrows and pages the DLQ message listing
+type DLQFilter struct {
+	Topic    string
+	Resolved *bool // nil lists resolved and unresolved messages
+	// Quarantined lists only messages that exhausted their retry budget (true) or only the others (false)
+	Quarantined *bool
+	Category    string
+	From        *time.Time // created_at >= From
+	To          *time.Time // created_at < To
+	Search      string     // case-insensitive substring of error_message
+	Limit       int
+	Offset      int
+	BeforeID    int // cursor: only messages with a smaller id (newest first)
+}
+
+// DLQPage is one page of the DLQ message listing
+type DLQPage struct {
+	Messages   []map[string]interface{} `json:"data"`
+	Count      int                      `json:"count"`
+	Total      int                      `json:"total"`                 // messages matching the filter
+	NextCursor int                      `json:"next_cursor,omitempty"` // pass as cursor for the next page
+}
+
+// likeEscaper escapes LIKE wildcards so search terms match literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ListDLQMessages returns DLQ messages matching the filter, newest first
+func ListDLQMessages(filter DLQFilter) (*DLQPage, error) {
 	dbConn := getDBConnection()
 	if dbConn == nil {
 		return nil, nil
 	}
 
-	query := `
-		SELECT id, message_id, topic, key, value, error_message, retry_count, created_at
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Topic != "" {
+		where("topic = $%d", filter.Topic)
+	}
+	if filter.Resolved != nil {
+		where("resolved = $%d", *filter.Resolved)
+	}
+	if filter.Quarantined != nil {
+		where("(quarantined_at IS NOT NULL) = $%d", *filter.Quarantined)
+	}
+	if filter.Category != "" {
+		where("failure_category = $%d", filter.Category)
+	}
+	if filter.From != nil {
+		where("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		where("created_at < $%d", *filter.To)
+	}
+	if filter.Search != "" {
+		where("error_message ILIKE '%%' || $%d || '%%'", likeEscaper.Replace(filter.Search))
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	page := &DLQPage{Messages: []map[string]interface{}{}}
+	if err := dbConn.QueryRow("SELECT COUNT(*) FROM dlq_messages "+whereClause, args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	if filter.BeforeID > 0 {
+		where("id < $%d", filter.BeforeID)
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`
+		SELECT id, message_id, topic, key, value, COALESCE(error_message, ''), retry_count, created_at,
+		       resolved, COALESCE(failure_category, ''), COALESCE(notes, ''), next_retry_at, quarantined_at
 		FROM dlq_messages
-		WHERE resolved = FALSE
-		ORDER BY created_at DESC
-		LIMIT $1
-	`
+		%s
+		ORDER BY id DESC
+		LIMIT $%d OFFSET $%d
+	`, whereClause, len(args)-1, len(args))
 
-	rows, err := dbConn.Query(query, limit)
+	rows, err := dbConn.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var messages []map[string]interface{}
+	lastID := 0
 	for rows.Next() {
 		var id int
 		var messageID, topic, key string
@@ -152,97 +280,127 @@ func GetDLQMessages(limit int) ([]map[string]interface{}, error) {
 		var errorMsg string
 		var retryCount int
 		var createdAt time.Time
+		var resolved bool
+		var category, notes string
+		var nextRetryAt, quarantinedAt sql.NullTime
 
-		if err := rows.Scan(&id, &messageID, &topic, &key, &value, &errorMsg, &retryCount, &createdAt); err != nil {
+		if err := rows.Scan(&id, &messageID, &topic, &key, &value, &errorMsg, &retryCount, &createdAt, &resolved, &category, &notes, &nextRetryAt, &quarantinedAt); err != nil {
 			continue
 		}
 
-		messages = append(messages, map[string]interface{}{
-			"id":            id,
-			"message_id":    messageID,
-			"topic":         topic,
-			"key":           key,
-			"value":         value,
-			"error_message": errorMsg,
-			"retry_count":   retryCount,
-			"created_at":    createdAt,
-		})
+		message := map[string]interface{}{
+			"id":               id,
+			"message_id":       messageID,
+			"topic":            topic,
+			"key":              key,
+			"value":            json.RawMessage(value),
+			"error_message":    errorMsg,
+			"retry_count":      retryCount,
+			"created_at":       createdAt,
+			"resolved":         resolved,
+			"failure_category": category,
+			"notes":            notes,
+		}
+		if quarantinedAt.Valid {
+			message["quarantined_at"] = quarantinedAt.Time
+		} else if nextRetryAt.Valid && !resolved {
+			message["next_retry_at"] = nextRetryAt.Time
+		}
+		page.Messages = append(page.Messages, message)
+		lastID = id
 	}
 
-	return messages, nil
+	page.Count = len(page.Messages)
+	if page.Count == filter.Limit && lastID > 0 {
+		page.NextCursor = lastID
+	}
+	return page, rows.Err()
 }
 
-// RetryDLQMessage attempts to reprocess a DLQ message
+// RetryDLQMessage attempts to reprocess a DLQ message. Quarantined messages are refused
+// with ErrDLQMessageQuarantined; they need ForceRetryDLQMessage.
 func RetryDLQMessage(messageID string) error {
+	_, err := retryDLQMessage(messageID, false)
+	return err
+}
+
+// retryDLQMessage reprocesses one DLQ message and records the outcome. It returns the
+// processing error separately from errors reading or updating the message.
+func retryDLQMessage(messageID string, force bool) (processErr error, err error) {
 	dbConn := getDBConnection()
 	if dbConn == nil {
-		return nil
+		return nil, nil
 	}
 
+	// Retries need the original payload; the redacted value is only a fallback
 	query := `
-		SELECT value, topic, key FROM dlq_messages WHERE message_id = $1
+		SELECT COALESCE(raw_value, convert_to(value::text, 'UTF8')), topic, key, retry_count, quarantined_at IS NOT NULL
+		FROM dlq_messages WHERE message_id = $1
 	`
 
 	var value []byte
 	var topic, key string
-	err := dbConn.QueryRow(query, messageID).Scan(&value, &topic, &key)
+	var retryCount int
+	var quarantined bool
+	err = dbConn.QueryRow(query, messageID).Scan(&value, &topic, &key, &retryCount, &quarantined)
 	if err != nil {
 		logger.Error("Error retrieving DLQ message for retry: %v", err)
-		return err
+		return nil, err
+	}
+	if quarantined && !force {
+		return nil, ErrDLQMessageQuarantined
+	}
+	if value, err = decryptRawPayload(value); err != nil {
+		return nil, err
 	}
 
 	// Attempt to reprocess
 	var eventData map[string]interface{}
 	if err := json.Unmarshal(value, &eventData); err != nil {
 		logger.Error("Error unmarshaling DLQ message for retry: %v", err)
-		return err
+		return nil, err
 	}
 
-	// Reprocess the message and check if successful
-	wasSuccessful := HandleKafkaMessageForRetry(kafka.Message{
+	// Reprocess the message and record the outcome on the existing entry
+	processErr = ProcessKafkaMessage(kafka.Message{
 		Topic: topic,
 		Key:   []byte(key),
 		Value: value,
 	})
+	note := "Manually retried successfully"
+	if force {
+		note = "Force retried from quarantine successfully"
+	}
+	policy := retryPolicyFor(topic)
+	return processErr, recordRetryOutcome(dbConn, messageID, processErr, note, policy.Backoff(retryCount+1))
+}
 
-	// Update retry count and resolve only if successful
-	var updateQuery string
-	if wasSuccessful {
-		updateQuery = `
+// recordRetryOutcome increments the retry count of a DLQ message and resolves it
+// when processErr is nil; otherwise the next automatic retry is scheduled after nextRetryIn
+func recordRetryOutcome(dbConn *sql.DB, messageID string, processErr error, resolvedNote string, nextRetryIn time.Duration) error {
+	var err error
+	if processErr == nil {
+		_, err = dbConn.Exec(`
 			UPDATE dlq_messages
-			SET retry_count = retry_count + 1, last_retry_at = NOW(), resolved = TRUE, resolved_at = NOW(), notes = 'Manually retried successfully'
+			SET retry_count = retry_count + 1, last_retry_at = NOW(), next_retry_at = NULL,
+				resolved = TRUE, resolved_at = NOW(), notes = $2, raw_value = NULL
 			WHERE message_id = $1
-		`
+		`, messageID, resolvedNote)
 	} else {
-		updateQuery = `
+		_, err = dbConn.Exec(`
 			UPDATE dlq_messages
-			SET retry_count = retry_count + 1, last_retry_at = NOW()
+			SET retry_count = retry_count + 1, last_retry_at = NOW(), error_message = $2,
+				next_retry_at = NOW() + make_interval(secs => $3)
 			WHERE message_id = $1
-		`
+		`, messageID, processErr.Error(), nextRetryIn.Seconds())
 	}
-	_, err = dbConn.Exec(updateQuery, messageID)
 	return err
 }
 
 // ResolveDLQMessage marks a DLQ message as resolved
 func ResolveDLQMessage(messageID string, notes string) error {
-	dbConn := getDBConnection()
-	if dbConn == nil {
-		return nil
-	}
-
-	query := `
-		UPDATE dlq_messages
-		SET resolved = TRUE, resolved_at = NOW(), notes = $2
-		WHERE message_id = $1
-	`
-
-	_, err := dbConn.Exec(query, messageID, notes)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	_, err := ResolveDLQMessages([]string{messageID}, notes, "")
+	return err
 }
 
 // GetDLQStats retrieves statistics about DLQ messages
@@ -272,97 +430,112 @@ func GetDLQStats() (map[string]interface{}, error) {
 		return nil, err
 	}
 
+	var quarantinedMessages int
+	err = dbConn.QueryRow("SELECT COUNT(*) FROM dlq_messages WHERE resolved = FALSE AND quarantined_at IS NOT NULL").Scan(&quarantinedMessages)
+	if err != nil {
+		return nil, err
+	}
+
+	// Break messages down by failure category so recurring root causes stand out
+	byCategory, err := dlqCategoryStats()
+	if err != nil {
+		return nil, err
+	}
+
 	return map[string]interface{}{
-		"total_dlq_messages":  totalMessages,
-		"unresolved_messages": unresolvedMessages,
-		"resolved_messages":   resolvedMessages,
+		"total_dlq_messages":   totalMessages,
+		"unresolved_messages":  unresolvedMessages,
+		"resolved_messages":    resolvedMessages,
+		"quarantined_messages": quarantinedMessages,
+		"by_category":          byCategory,
 	}, nil
 }
 
-// StartDLQAutoRetry starts a background goroutine that automatically retries failed DLQ messages
-// Retries unresolved messages every 10 seconds for testing (change to 5*time.Minute in production)
-func StartDLQAutoRetry() {
-	dlqRetryTicker = time.NewTicker(10 * time.Second)
-	stopDLQRetry = make(chan bool)
-
-	go func() {
-		for {
-			select {
-			case <-dlqRetryTicker.C:
-				retryUnresolvedDLQMessages()
-			case <-stopDLQRetry:
-				return
-			}
-		}
-	}()
-}
-
-// retryUnresolvedDLQMessages retrieves unresolved messages and attempts to retry them
-func retryUnresolvedDLQMessages() {
+// RetryDueDLQMessages retries unresolved messages whose next_retry_at has passed, and
+// quarantines (and escalates) messages whose retry budget is exhausted. Each failed attempt pushes
+// next_retry_at back by the topic policy's exponential backoff.
+func RetryDueDLQMessages(ctx context.Context) error {
 	dbConn := getDBConnection()
 	if dbConn == nil {
-		return
+		return nil
 	}
 
+	policies, err := LoadRetryPolicies()
+	if err != nil {
+		return fmt.Errorf("error loading DLQ retry policies: %w", err)
+	}
+
+	// Messages stored before next_retry_at existed have it NULL and are due at once
 	query := `
-		SELECT message_id, value, topic, key, retry_count, max_retries
+		SELECT message_id, COALESCE(raw_value, convert_to(value::text, 'UTF8')), topic, key, retry_count, COALESCE(error_message, '')
 		FROM dlq_messages
-		WHERE resolved = FALSE AND retry_count < max_retries
-		ORDER BY created_at ASC
-		LIMIT 10
+		WHERE resolved = FALSE AND quarantined_at IS NULL
+			AND (next_retry_at IS NULL OR next_retry_at <= NOW())
+		ORDER BY next_retry_at ASC NULLS FIRST, created_at ASC
+		LIMIT 50
 	`
 
-	rows, err := dbConn.Query(query)
+	rows, err := dbConn.QueryContext(ctx, query)
 	if err != nil {
-		return
+		return fmt.Errorf("error reading DLQ messages: %w", err)
 	}
-	defer rows.Close()
 
+	type dlqEntry struct {
+		messageID, topic, key, errorMsg string
+		value                           []byte
+		retryCount                      int
+	}
+	var entries []dlqEntry
 	for rows.Next() {
-		var messageID string
-		var value []byte
-		var topic, key string
-		var retryAttempts, maxRetries int
+		var e dlqEntry
+		if err := rows.Scan(&e.messageID, &e.value, &e.topic, &e.key, &e.retryCount, &e.errorMsg); err != nil {
+			continue
+		}
+		if e.value, err = decryptRawPayload(e.value); err != nil {
+			logger.Error("Skipping DLQ message %s: %v", e.messageID, err)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	rows.Close()
+
+	for _, e := range entries {
+		policy := policies.For(e.topic)
 
-		if err := rows.Scan(&messageID, &value, &topic, &key, &retryAttempts, &maxRetries); err != nil {
+		// Budget already spent (e.g. policy lowered since the last retry)
+		if e.retryCount >= policy.MaxRetries {
+			escalateDLQMessage(dbConn, e.messageID, e.topic, e.errorMsg, e.retryCount, policy)
 			continue
 		}
 
 		// Attempt to reprocess the message
-		wasReprocessed := HandleKafkaMessageForRetry(kafka.Message{
-			Topic: topic,
-			Key:   []byte(key),
-			Value: value,
+		processErr := ProcessKafkaMessage(kafka.Message{
+			Topic: e.topic,
+			Key:   []byte(e.key),
+			Value: e.value,
 		})
+		if err := recordRetryOutcome(dbConn, e.messageID, processErr, "Auto-retried successfully", policy.Backoff(e.retryCount+1)); err != nil {
+			logger.Error("Error updating DLQ message %s after retry: %v", e.messageID, err)
+			continue
+		}
 
-		// Update retry count and mark as resolved if successful
-		if wasReprocessed {
-			updateQuery := `
-				UPDATE dlq_messages
-				SET retry_count = retry_count + 1, last_retry_at = NOW(), resolved = TRUE, resolved_at = NOW(), notes = 'Auto-retried successfully'
-				WHERE message_id = $1
-			`
-			_, _ = dbConn.Exec(updateQuery, messageID)
-		} else {
-			// Still increment retry but don't mark as resolved
-			updateQuery := `
-				UPDATE dlq_messages
-				SET retry_count = retry_count + 1, last_retry_at = NOW()
-				WHERE message_id = $1
-			`
-			_, _ = dbConn.Exec(updateQuery, messageID)
+		if processErr != nil && e.retryCount+1 >= policy.MaxRetries {
+			escalateDLQMessage(dbConn, e.messageID, e.topic, processErr.Error(), e.retryCount+1, policy)
 		}
 	}
+
+	return nil
 }
 
-// StopDLQAutoRetry stops the automatic DLQ retry mechanism
-func StopDLQAutoRetry() {
-	if dlqRetryTicker != nil {
-		dlqRetryTicker.Stop()
-	}
-	if stopDLQRetry != nil {
-		close(stopDLQRetry)
+// retryPolicyFor loads the retry policy of topic, falling back to DefaultRetryPolicy
+// when the policies cannot be read
+func retryPolicyFor(topic string) RetryPolicy {
+	policies, err := LoadRetryPolicies()
+	if err != nil {
+		logger.Warn("Error loading DLQ retry policies, using the default policy: %v", err)
+		return DefaultRetryPolicy()
 	}
+	return policies.For(topic)
 }
 
 // getDBConnection is a helper to get the database connection
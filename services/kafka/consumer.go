@@ -6,6 +6,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -14,18 +16,22 @@ import (
 )
 
 var (
-	consumer        *kafka.Reader
+	// readers holds one reader per consumed topic, so a slow or failing topic
+	// does not hold back the others
+	readers         map[string]*kafka.Reader
 	consumerMutex   sync.Mutex
 	consumerRunning bool
-	stopConsumer    chan bool
+	stopConsumer    chan struct{}
+	consumerWG      sync.WaitGroup
 	// emailProcessor is a callback to handle email sending from Kafka consumer
 	emailProcessor func(map[string]interface{}) error
 	// interviewScheduler is a callback to handle interview scheduling from Kafka consumer
 	interviewScheduler func(int, string) error
 )
 
-// InitConsumer initializes a Kafka reader (consumer) for specified topics
-// This creates a consumer group that reads messages from Kafka topics
+// InitConsumer initializes one Kafka reader per topic in the consumer group.
+// The "emails" topic is always consumed, and so is KAFKA_DLQ_TOPIC, whose messages
+// are ingested into dlq_messages instead of being processed.
 func InitConsumer(topics []string) error {
 	consumerMutex.Lock()
 	defer consumerMutex.Unlock()
@@ -50,26 +56,69 @@ func InitConsumer(topics []string) error {
 		return nil
 	}
 
-	// Listen specifically to "emails" topic for email events
-	emailTopic := "emails"
-	consumer = kafka.NewReader(kafka.ReaderConfig{
-		Brokers:          validBrokers,
-		Topic:            emailTopic,
-		GroupID:          "admission-module-consumer-group",
-		StartOffset:      -1,
-		CommitInterval:   time.Second,
-		MaxBytes:         10e6,
-		SessionTimeout:   20 * time.Second,
-		ReadBackoffMin:   100 * time.Millisecond,
-		ReadBackoffMax:   1 * time.Second,
-		QueueCapacity:    100,
-		RebalanceTimeout: 60 * time.Second,
-	})
-
-	stopConsumer = make(chan bool)
+	startOffset, err := parseStartOffset(config.AppConfig.KafkaStartOffset)
+	if err != nil {
+		return err
+	}
+	_, dialer, err := connectionSecurity()
+	if err != nil {
+		return err
+	}
+
+	// Always listen to "emails" topic for email events
+	readers = map[string]*kafka.Reader{}
+	for _, t := range append([]string{"emails", config.AppConfig.KafkaDLQTopic}, topics...) {
+		t = strings.TrimSpace(t)
+		if t == "" || readers[t] != nil {
+			continue
+		}
+		readers[t] = kafka.NewReader(kafka.ReaderConfig{
+			Brokers:          validBrokers,
+			Dialer:           dialer,
+			Topic:            t,
+			GroupID:          config.AppConfig.KafkaConsumerGroup,
+			StartOffset:      startOffset,
+			CommitInterval:   time.Second,
+			MaxBytes:         10e6,
+			SessionTimeout:   20 * time.Second,
+			ReadBackoffMin:   100 * time.Millisecond,
+			ReadBackoffMax:   1 * time.Second,
+			QueueCapacity:    100,
+			RebalanceTimeout: 60 * time.Second,
+		})
+	}
+
+	stopConsumer = make(chan struct{})
+	logger.Info("Kafka consumer group %s (start offset: %s)", config.AppConfig.KafkaConsumerGroup, config.AppConfig.KafkaStartOffset)
 	return nil
 }
 
+// parseStartOffset maps KAFKA_START_OFFSET to the reader's start offset.
+// It only applies when the consumer group has no committed offset yet.
+func parseStartOffset(value string) (int64, error) {
+	switch value {
+	case "", "latest":
+		return kafka.LastOffset, nil
+	case "earliest":
+		return kafka.FirstOffset, nil
+	default:
+		return 0, fmt.Errorf("invalid KAFKA_START_OFFSET %q (use latest or earliest)", value)
+	}
+}
+
+// ConsumedTopics returns the topics the consumer has a reader for, sorted
+func ConsumedTopics() []string {
+	consumerMutex.Lock()
+	defer consumerMutex.Unlock()
+
+	topics := make([]string, 0, len(readers))
+	for t := range readers {
+		topics = append(topics, t)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
 // RegisterEmailProcessor registers the callback function that handles email.send events
 func RegisterEmailProcessor(fn func(map[string]interface{}) error) {
 	consumerMutex.Lock()
@@ -84,35 +133,74 @@ func RegisterInterviewScheduler(fn func(int, string) error) {
 	interviewScheduler = fn
 }
 
-// StartConsumer starts consuming messages in a separate goroutine
-// This runs continuously until StopConsumer() is called
+// StartConsumer starts one goroutine per topic reader
+// They run continuously until StopConsumer() is called
 func StartConsumer() {
 	consumerMutex.Lock()
-	if consumer == nil {
-		consumerMutex.Unlock()
-		return
-	}
-	if consumerRunning {
-		consumerMutex.Unlock()
+	defer consumerMutex.Unlock()
+
+	if len(readers) == 0 || consumerRunning {
 		return
 	}
 	consumerRunning = true
-	consumerMutex.Unlock()
 
-	// Run consumer in a goroutine so it doesn't block the main server
-	go consumeMessages()
+	// Run readers in goroutines so they don't block the main server
+	for topic, reader := range readers {
+		consumerWG.Add(1)
+		go consumeMessages(topic, reader)
+	}
+	logger.Info("Kafka consumer started for %d topics (%d workers per topic by default)", len(readers), config.AppConfig.KafkaConsumerConcurrency)
 }
 
-// consumeMessages continuously reads messages from Kafka and processes them
-func consumeMessages() {
+// consumeMessages continuously reads messages of one topic from Kafka and hands them
+// to a bounded pool of workers, so a slow handler (e.g. an SMTP send) does not stall
+// the topic. Messages with the same key always go to the same worker, keeping their order.
+func consumeMessages(topic string, reader *kafka.Reader) {
+	defer consumerWG.Done()
+
+	workers := config.AppConfig.ConsumerConcurrency(topic)
+	commits := newPartitionCommits()
+	queues := make([]chan kafka.Message, workers)
+	var workerWG sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan kafka.Message)
+		workerWG.Add(1)
+		go func(queue <-chan kafka.Message) {
+			defer workerWG.Done()
+			for msg := range queue {
+				if isDLQTopic(topic) {
+					ingestDLQMessage(msg)
+				} else {
+					handleKafkaMessage(msg)
+				}
+				commitFinished(reader, commits, msg)
+			}
+		}(queues[i])
+	}
 	defer func() {
-		consumerMutex.Lock()
-		consumerRunning = false
-		consumerMutex.Unlock()
+		for _, queue := range queues {
+			close(queue)
+		}
+		workerWG.Wait()
+	}()
+
+	// Stopping cancels a pending fetch; the reader stays open until the workers have committed
+	stopCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go func() {
+		select {
+		case <-stopConsumer:
+			stop()
+		case <-stopCtx.Done():
+		}
 	}()
 
 	// Allow time for broker to stabilize
-	time.Sleep(2 * time.Second)
+	select {
+	case <-stopConsumer:
+		return
+	case <-time.After(2 * time.Second):
+	}
 
 	for {
 		select {
@@ -120,13 +208,13 @@ func consumeMessages() {
 			return
 		default:
 			// Read the next message with timeout
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-			msg, err := consumer.ReadMessage(ctx)
+			ctx, cancel := context.WithTimeout(stopCtx, 10*time.Second)
+			msg, err := reader.FetchMessage(ctx)
 			cancel()
 
 			if err != nil {
 				// Silently ignore expected errors (no messages or timeout)
-				if err == context.DeadlineExceeded || err.Error() == "EOF" {
+				if err == context.DeadlineExceeded || err == context.Canceled || err.Error() == "EOF" {
 					continue
 				}
 				// Silently ignore group coordinator startup errors
@@ -134,17 +222,89 @@ func consumeMessages() {
 					time.Sleep(500 * time.Millisecond)
 					continue
 				}
-				// For other errors, silently retry with backoff
+				// For other errors, retry with backoff
+				logger.Debug("Kafka consumer for %s: read error: %v", topic, err)
 				time.Sleep(1 * time.Second)
 				continue
 			}
 
-			// Process the message
-			handleKafkaMessage(msg)
+			// Wait for the key's worker to be free
+			commits.fetched(msg)
+			select {
+			case queues[workerFor(msg.Key, workers)] <- msg:
+			case <-stopConsumer:
+				return
+			}
 		}
 	}
 }
 
+// partitionCommits tracks the fetched messages of one reader. Workers finish them out of
+// order, so a partition's offset is only committed past messages that are all processed;
+// a crash then redelivers unfinished messages instead of skipping them.
+type partitionCommits struct {
+	mu      sync.Mutex
+	pending map[int][]kafka.Message // fetched and not yet committable, in offset order
+	done    map[int]map[int64]bool
+}
+
+func newPartitionCommits() *partitionCommits {
+	return &partitionCommits{pending: map[int][]kafka.Message{}, done: map[int]map[int64]bool{}}
+}
+
+// fetched records a message handed to a worker
+func (c *partitionCommits) fetched(msg kafka.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending[msg.Partition] = append(c.pending[msg.Partition], msg)
+}
+
+// finished marks msg processed and returns the last message of its partition that can be
+// committed, i.e. the end of the processed run at the front of the partition
+func (c *partitionCommits) finished(msg kafka.Message) (kafka.Message, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	done := c.done[msg.Partition]
+	if done == nil {
+		done = map[int64]bool{}
+		c.done[msg.Partition] = done
+	}
+	done[msg.Offset] = true
+
+	pending := c.pending[msg.Partition]
+	var last kafka.Message
+	n := 0
+	for n < len(pending) && done[pending[n].Offset] {
+		last = pending[n]
+		delete(done, last.Offset)
+		n++
+	}
+	c.pending[msg.Partition] = pending[n:]
+	return last, n > 0
+}
+
+// commitFinished commits msg's partition up to the processed messages before it
+func commitFinished(reader *kafka.Reader, commits *partitionCommits, msg kafka.Message) {
+	next, ok := commits.finished(msg)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := reader.CommitMessages(ctx, next); err != nil {
+		logger.Warn("Kafka consumer for %s: could not commit offset %d of partition %d: %v",
+			msg.Topic, next.Offset, next.Partition, err)
+	}
+}
+
+// workerFor maps a message key to one of n workers
+func workerFor(key []byte, n int) int {
+	h := fnv.New32a()
+	h.Write(key)
+	return int(h.Sum32() % uint32(n))
+}
+
 // handleKafkaMessage processes incoming Kafka messages
 // This is where you handle different event types
 // On error, messages are sent to the DLQ
@@ -155,23 +315,48 @@ func handleKafkaMessage(msg kafka.Message) {
 // HandleKafkaMessageForRetry processes incoming Kafka messages and returns whether it was successful
 // Returns true if message was processed successfully (not sent to DLQ)
 // Returns false if message was sent to DLQ
+// Redelivered events that were already processed are skipped, so emails are not
+// sent twice and interviews not scheduled twice.
 func HandleKafkaMessageForRetry(msg kafka.Message) bool {
-	// Parse the message value as JSON
-	var eventData map[string]interface{}
-	err := json.Unmarshal(msg.Value, &eventData)
+	id := eventID(msg)
+	claimed, err := claimEvent(id, msg.Topic)
 	if err != nil {
-		// Send to DLQ on unmarshal error
-		_ = SendToDLQ(msg.Topic, string(msg.Key), msg.Value, "Failed to unmarshal JSON: "+err.Error())
+		// Without the dedup table, processing twice beats not processing at all
+		logger.Warn("Could not check processed events for %s: %v", msg.Topic, err)
+		claimed = true
+	} else if !claimed {
+		logger.Debug("Skipping already processed event %s on %s", id, msg.Topic)
+		return true
+	}
+
+	processErr := ProcessKafkaMessage(msg)
+	finishEvent(id, processErr)
+	if processErr != nil {
+		_ = SendToDLQ(msg.Topic, string(msg.Key), msg.Value, processErr.Error())
 		return false
 	}
+	return true
+}
+
+// ProcessKafkaMessage routes a message to its event handler without touching the DLQ.
+// DLQ retries use it directly so a failed retry updates the existing DLQ entry
+// instead of creating a new one.
+func ProcessKafkaMessage(msg kafka.Message) error {
+	// Parse the message value as JSON
+	var eventData map[string]interface{}
+	if err := json.Unmarshal(msg.Value, &eventData); err != nil {
+		return fmt.Errorf("Failed to unmarshal JSON: %v", err)
+	}
 
 	// Route to appropriate handler based on event type
 	eventType, ok := eventData["event"].(string)
 	if !ok {
-		_ = SendToDLQ(msg.Topic, string(msg.Key), msg.Value, "Message does not contain valid event type")
-		return false
+		return fmt.Errorf("Message does not contain valid event type")
 	}
 
+	// Record events about a lead for its activity timeline
+	recordErr := recordLeadEvent(msg, eventType, eventData)
+
 	var handlerErr error
 	switch eventType {
 	case "email.send":
@@ -181,16 +366,23 @@ func HandleKafkaMessageForRetry(msg kafka.Message) bool {
 	case "email.sent", "email.acceptance":
 		handlerErr = handleEmailSentTracking(eventData)
 	default:
-		_ = SendToDLQ(msg.Topic, string(msg.Key), msg.Value, "Unknown event type: "+eventType)
-		return false
+		if !isTimelineEvent(eventType) {
+			return fmt.Errorf("Unknown event type: %s", eventType)
+		}
+		// Timeline-only events: recording them is the whole job
+		handlerErr = recordErr
+		recordErr = nil
+	}
+
+	if recordErr != nil {
+		logger.Warn("Failed to record %s in lead timeline: %v", eventType, recordErr)
 	}
 
 	if handlerErr != nil {
-		_ = SendToDLQ(msg.Topic, string(msg.Key), msg.Value, "Handler error: "+handlerErr.Error())
-		return false
+		return fmt.Errorf("Handler error: %v", handlerErr)
 	}
 
-	return true
+	return nil
 }
 
 // handleEmailSend processes email.send events
@@ -264,32 +456,52 @@ func handleEmailSentTracking(event map[string]interface{}) error {
 	return nil
 }
 
-// StopConsumer stops the consumer gracefully
+// StopConsumer stops all topic readers and waits for their goroutines to exit
 func StopConsumer() error {
 	consumerMutex.Lock()
-	defer consumerMutex.Unlock()
-
-	if !consumerRunning || consumer == nil {
+	if !consumerRunning {
+		consumerMutex.Unlock()
 		logger.Warn("Consumer not running")
 		return nil
 	}
+	consumerRunning = false
 
-	// Signal the consumer to stop
+	// Signal the readers to stop and let the workers finish and commit before closing them
 	close(stopConsumer)
+	consumerMutex.Unlock()
 
-	// Close the consumer reader
-	if err := consumer.Close(); err != nil {
-		logger.Error("Error closing consumer: %v", err)
-		return err
+	consumerWG.Wait()
+	var closeErr error
+	for topic, reader := range readers {
+		if err := reader.Close(); err != nil {
+			logger.Error("Error closing consumer for %s: %v", topic, err)
+			closeErr = err
+		}
+	}
+	if closeErr != nil {
+		return closeErr
 	}
 
 	logger.Info("✅ Kafka consumer stopped")
 	return nil
 }
 
-// IsConsumerRunning returns true if the consumer is actively running
+// IsConsumerRunning returns true if the topic readers are actively running
 func IsConsumerRunning() bool {
 	consumerMutex.Lock()
 	defer consumerMutex.Unlock()
-	return consumerRunning && consumer != nil
+	return consumerRunning && len(readers) > 0
+}
+
+// ConsumerLag returns how many messages each topic reader is behind the partition head.
+// Reader.Lag is unavailable for consumer group readers, so it comes from Stats
+func ConsumerLag() map[string]int64 {
+	consumerMutex.Lock()
+	defer consumerMutex.Unlock()
+
+	lag := make(map[string]int64, len(readers))
+	for topic, reader := range readers {
+		lag[topic] = reader.Stats().Lag
+	}
+	return lag
 }
@@ -0,0 +1,199 @@
+package kafka
+
+import (
+	"admission-module/logger"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// dlqArchiveBatchSize is how many resolved DLQ messages are archived per transaction
+const dlqArchiveBatchSize = 500
+
+// ErrArchivedDLQMessageNotFound is returned when a message is not in the DLQ archive
+var ErrArchivedDLQMessageNotFound = errors.New("archived DLQ message not found")
+
+// archivedDLQMessage is the row stored (gzip-compressed) in dlq_messages_archive.payload
+type archivedDLQMessage struct {
+	ID              int             `json:"id"`
+	MessageID       string          `json:"message_id"`
+	Topic           string          `json:"topic"`
+	Key             string          `json:"key"`
+	Value           json.RawMessage `json:"value"` // redacted payload
+	ErrorMessage    string          `json:"error_message"`
+	RetryCount      int             `json:"retry_count"`
+	FailureCategory string          `json:"failure_category,omitempty"`
+	Notes           string          `json:"notes,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+	ResolvedAt      *time.Time      `json:"resolved_at,omitempty"`
+}
+
+// ArchiveResolvedDLQMessages moves DLQ messages resolved before cutoff into
+// dlq_messages_archive, one gzip-compressed JSON row each, and deletes them from
+// dlq_messages. Works in batches; returns the number of messages archived.
+func ArchiveResolvedDLQMessages(ctx context.Context, cutoff time.Time) (int, error) {
+	dbConn := getDBConnection()
+	if dbConn == nil {
+		return 0, nil
+	}
+
+	archived := 0
+	for {
+		n, err := archiveDLQBatch(ctx, dbConn, cutoff)
+		archived += n
+		if err != nil {
+			return archived, err
+		}
+		if n < dlqArchiveBatchSize || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if archived > 0 {
+		logger.Info("Archived %d resolved DLQ messages resolved before %s", archived, cutoff.Format(time.RFC3339))
+	}
+	return archived, nil
+}
+
+// CountArchivableDLQMessages returns how many messages ArchiveResolvedDLQMessages would
+// archive for cutoff
+func CountArchivableDLQMessages(ctx context.Context, cutoff time.Time) (int, error) {
+	dbConn := getDBConnection()
+	if dbConn == nil {
+		return 0, nil
+	}
+
+	var n int
+	err := dbConn.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM dlq_messages WHERE resolved = TRUE AND resolved_at < $1", cutoff).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("error counting resolved DLQ messages: %w", err)
+	}
+	return n, nil
+}
+
+// archiveDLQBatch archives one batch in a single transaction so a message is never
+// both archived and still in dlq_messages, or in neither
+func archiveDLQBatch(ctx context.Context, dbConn *sql.DB, cutoff time.Time) (int, error) {
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, message_id, topic, COALESCE(key, ''), value, COALESCE(error_message, ''), retry_count,
+		       COALESCE(failure_category, ''), COALESCE(notes, ''), created_at, resolved_at
+		FROM dlq_messages
+		WHERE resolved = TRUE AND resolved_at < $1
+		ORDER BY id ASC
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`, cutoff, dlqArchiveBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("error reading resolved DLQ messages: %w", err)
+	}
+
+	var batch []archivedDLQMessage
+	for rows.Next() {
+		var m archivedDLQMessage
+		var value []byte
+		var resolvedAt sql.NullTime
+		if err := rows.Scan(&m.ID, &m.MessageID, &m.Topic, &m.Key, &value, &m.ErrorMessage, &m.RetryCount,
+			&m.FailureCategory, &m.Notes, &m.CreatedAt, &resolvedAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("error reading resolved DLQ message: %w", err)
+		}
+		m.Value = value
+		if resolvedAt.Valid {
+			m.ResolvedAt = &resolvedAt.Time
+		}
+		batch = append(batch, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error reading resolved DLQ messages: %w", err)
+	}
+	if len(batch) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]int64, 0, len(batch))
+	for _, m := range batch {
+		payload, err := compressArchivedDLQMessage(m)
+		if err != nil {
+			return 0, fmt.Errorf("error compressing DLQ message %s: %w", m.MessageID, err)
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO dlq_messages_archive (id, message_id, topic, failure_category, created_at, resolved_at, payload)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7)
+			ON CONFLICT (id) DO NOTHING`,
+			m.ID, m.MessageID, m.Topic, m.FailureCategory, m.CreatedAt, m.ResolvedAt, payload)
+		if err != nil {
+			return 0, fmt.Errorf("error archiving DLQ message %s: %w", m.MessageID, err)
+		}
+		ids = append(ids, int64(m.ID))
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM dlq_messages WHERE id = ANY($1)", pq.Array(ids)); err != nil {
+		return 0, fmt.Errorf("error deleting archived DLQ messages: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return len(batch), nil
+}
+
+// compressArchivedDLQMessage encodes a DLQ message as gzip-compressed JSON
+func compressArchivedDLQMessage(m archivedDLQMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(m); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GetArchivedDLQMessage returns an archived DLQ message, decompressed
+func GetArchivedDLQMessage(messageID string) (json.RawMessage, error) {
+	if !uuidPattern.MatchString(messageID) {
+		return nil, ErrInvalidMessageID
+	}
+
+	dbConn := getDBConnection()
+	if dbConn == nil {
+		return nil, ErrArchivedDLQMessageNotFound
+	}
+
+	var payload []byte
+	err := dbConn.QueryRow("SELECT payload FROM dlq_messages_archive WHERE message_id = $1", messageID).Scan(&payload)
+	if err == sql.ErrNoRows {
+		return nil, ErrArchivedDLQMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing archived DLQ message: %w", err)
+	}
+	defer zr.Close()
+
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing archived DLQ message: %w", err)
+	}
+	return bytes.TrimSpace(data), nil
+}
@@ -0,0 +1,173 @@
+package kafka
+
+import (
+	"admission-module/logger"
+	"context"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// dlqBulkBatchSize is how many DLQ messages a bulk operation reads or updates per query
+const dlqBulkBatchSize = 100
+
+// DLQBulkResult summarises a bulk retry or resolve
+type DLQBulkResult struct {
+	Topic      string   `json:"topic,omitempty"`
+	Processed  int      `json:"processed"`
+	Succeeded  int      `json:"succeeded"`
+	Failed     int      `json:"failed"`
+	Batches    int      `json:"batches"`
+	FailedIDs  []string `json:"failed_message_ids,omitempty"`
+	Incomplete bool     `json:"incomplete,omitempty"` // stopped early (max reached or request cancelled)
+}
+
+// RetryAllDLQMessages reprocesses unresolved DLQ messages, optionally only those of one
+// topic, oldest first in batches. Unlike the scheduled retry it ignores the policy backoff.
+// Quarantined messages are left out; they need ForceRetryDLQMessage. max caps the number
+// of messages (0 = no cap).
+func RetryAllDLQMessages(ctx context.Context, topic string, max int) (*DLQBulkResult, error) {
+	result := &DLQBulkResult{Topic: topic}
+	dbConn := getDBConnection()
+	if dbConn == nil {
+		return result, nil
+	}
+
+	policies, err := LoadRetryPolicies()
+	if err != nil {
+		return result, fmt.Errorf("error loading DLQ retry policies: %w", err)
+	}
+
+	type dlqEntry struct {
+		id             int
+		messageID, key string
+		entryTopic     string
+		value          []byte
+		retryCount     int
+	}
+
+	lastID := 0
+	for {
+		batchSize := dlqBulkBatchSize
+		if max > 0 {
+			if result.Processed >= max {
+				result.Incomplete = true
+				break
+			}
+			batchSize = min(batchSize, max-result.Processed)
+		}
+
+		rows, err := dbConn.QueryContext(ctx, `
+			SELECT id, message_id, COALESCE(raw_value, convert_to(value::text, 'UTF8')), topic, key, retry_count
+			FROM dlq_messages
+			WHERE resolved = FALSE AND quarantined_at IS NULL AND ($1 = '' OR topic = $1) AND id > $2
+			ORDER BY id ASC
+			LIMIT $3
+		`, topic, lastID, batchSize)
+		if err != nil {
+			return result, fmt.Errorf("error reading DLQ messages: %w", err)
+		}
+
+		var entries []dlqEntry
+		for rows.Next() {
+			var e dlqEntry
+			if err := rows.Scan(&e.id, &e.messageID, &e.value, &e.entryTopic, &e.key, &e.retryCount); err != nil {
+				continue
+			}
+			if e.value, err = decryptRawPayload(e.value); err != nil {
+				logger.Error("Skipping DLQ message %s: %v", e.messageID, err)
+				continue
+			}
+			entries = append(entries, e)
+		}
+		rows.Close()
+		if len(entries) == 0 {
+			break
+		}
+		result.Batches++
+
+		for _, e := range entries {
+			if ctx.Err() != nil {
+				result.Incomplete = true
+				return result, nil
+			}
+			lastID = e.id
+			result.Processed++
+
+			processErr := ProcessKafkaMessage(kafka.Message{
+				Topic: e.entryTopic,
+				Key:   []byte(e.key),
+				Value: e.value,
+			})
+			backoff := policies.For(e.entryTopic).Backoff(e.retryCount + 1)
+			if err := recordRetryOutcome(dbConn, e.messageID, processErr, "Bulk retried successfully", backoff); err != nil {
+				logger.Error("Error updating DLQ message %s after bulk retry: %v", e.messageID, err)
+			}
+			if processErr != nil {
+				result.Failed++
+				result.FailedIDs = append(result.FailedIDs, e.messageID)
+				continue
+			}
+			result.Succeeded++
+		}
+
+		if len(entries) < batchSize {
+			break
+		}
+	}
+
+	logger.Info("DLQ bulk retry (topic=%q): %d processed, %d succeeded, %d failed", topic, result.Processed, result.Succeeded, result.Failed)
+	return result, nil
+}
+
+// ResolveAllDLQMessages marks every unresolved DLQ message, optionally only those of one
+// topic, as resolved with a shared note and failure category, in batches
+func ResolveAllDLQMessages(ctx context.Context, topic, notes, category string) (*DLQBulkResult, error) {
+	if category != "" && !IsValidFailureCategory(category) {
+		return nil, ErrInvalidFailureCategory
+	}
+
+	result := &DLQBulkResult{Topic: topic}
+	dbConn := getDBConnection()
+	if dbConn == nil {
+		return result, nil
+	}
+
+	for {
+		if ctx.Err() != nil {
+			result.Incomplete = true
+			break
+		}
+
+		res, err := dbConn.ExecContext(ctx, `
+			UPDATE dlq_messages
+			SET resolved = TRUE, resolved_at = NOW(), notes = $2, failure_category = NULLIF($3, ''), raw_value = NULL
+			WHERE id IN (
+				SELECT id FROM dlq_messages
+				WHERE resolved = FALSE AND ($1 = '' OR topic = $1)
+				ORDER BY id ASC
+				LIMIT $4
+			)
+		`, topic, notes, category, dlqBulkBatchSize)
+		if err != nil {
+			return result, fmt.Errorf("error resolving DLQ messages: %w", err)
+		}
+
+		resolved, err := res.RowsAffected()
+		if err != nil {
+			return result, err
+		}
+		if resolved == 0 {
+			break
+		}
+		result.Batches++
+		result.Processed += int(resolved)
+		result.Succeeded += int(resolved)
+		if resolved < dlqBulkBatchSize {
+			break
+		}
+	}
+
+	logger.Info("DLQ bulk resolve (topic=%q, category=%q): %d resolved", topic, category, result.Succeeded)
+	return result, nil
+}
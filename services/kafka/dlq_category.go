@@ -0,0 +1,102 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/lib/pq"
+)
+
+// Failure categories tag resolved DLQ messages with their root cause
+const (
+	FailureCategorySMTPOutage = "smtp-outage"
+	FailureCategoryBadSchema  = "bad-schema"
+	FailureCategoryKafkaDown  = "kafka-down"
+)
+
+// uncategorizedLabel groups messages without a failure category in the stats
+const uncategorizedLabel = "uncategorized"
+
+// FailureCategories lists the accepted failure categories
+var FailureCategories = []string{FailureCategorySMTPOutage, FailureCategoryBadSchema, FailureCategoryKafkaDown}
+
+var (
+	// ErrInvalidFailureCategory is returned for a category outside FailureCategories
+	ErrInvalidFailureCategory = errors.New("invalid failure category")
+	// ErrInvalidMessageID is returned when a DLQ message ID is not a UUID
+	ErrInvalidMessageID = errors.New("invalid DLQ message ID")
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// IsValidFailureCategory reports whether category is one of FailureCategories
+func IsValidFailureCategory(category string) bool {
+	for _, c := range FailureCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveDLQMessages marks unresolved DLQ messages as resolved with a shared note and an
+// optional failure category, returning how many were resolved. Messages that are already
+// resolved or do not exist are left alone.
+func ResolveDLQMessages(messageIDs []string, notes, category string) (int, error) {
+	if category != "" && !IsValidFailureCategory(category) {
+		return 0, ErrInvalidFailureCategory
+	}
+	for _, id := range messageIDs {
+		if !uuidPattern.MatchString(id) {
+			return 0, fmt.Errorf("%w: %s", ErrInvalidMessageID, id)
+		}
+	}
+
+	dbConn := getDBConnection()
+	if dbConn == nil || len(messageIDs) == 0 {
+		return 0, nil
+	}
+
+	result, err := dbConn.Exec(`
+		UPDATE dlq_messages
+		SET resolved = TRUE, resolved_at = NOW(), notes = $2, failure_category = NULLIF($3, ''), raw_value = NULL
+		WHERE message_id = ANY($1::uuid[]) AND resolved = FALSE
+	`, pq.Array(messageIDs), notes, category)
+	if err != nil {
+		return 0, err
+	}
+
+	resolved, err := result.RowsAffected()
+	return int(resolved), err
+}
+
+// dlqCategoryStats counts DLQ messages per failure category
+func dlqCategoryStats() (map[string]map[string]int, error) {
+	dbConn := getDBConnection()
+	if dbConn == nil {
+		return nil, nil
+	}
+
+	rows, err := dbConn.Query(`
+		SELECT COALESCE(failure_category, $1), COUNT(*), COUNT(*) FILTER (WHERE resolved = FALSE)
+		FROM dlq_messages
+		GROUP BY 1
+	`, uncategorizedLabel)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := map[string]map[string]int{}
+	for rows.Next() {
+		var category string
+		var total, unresolved int
+		if err := rows.Scan(&category, &total, &unresolved); err != nil {
+			return nil, err
+		}
+		stats[category] = map[string]int{"total": total, "unresolved": unresolved}
+	}
+
+	return stats, rows.Err()
+}
@@ -0,0 +1,121 @@
+package kafka
+
+import (
+	"admission-module/config"
+	"admission-module/logger"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// dlqEnvelope is the DLQ topic message format written by SendToDLQ. Other producers may
+// publish the same envelope; original_value can be a JSON string or an embedded document.
+type dlqEnvelope struct {
+	MessageID       string          `json:"message_id"`
+	OriginalTopic   string          `json:"original_topic"`
+	OriginalKey     string          `json:"original_key"`
+	OriginalValue   json.RawMessage `json:"original_value"`
+	PayloadRedacted bool            `json:"payload_redacted"`
+	ErrorMessage    string          `json:"error_message"`
+}
+
+// redactedPayloadNote explains why an ingested message starts out quarantined
+const redactedPayloadNote = "Ingested from the DLQ topic with a redacted payload; it cannot be retried as is"
+
+// ingestDLQMessage stores a message read from KAFKA_DLQ_TOPIC in dlq_messages, so failures
+// published by other producers show up in the DLQ APIs. Messages written by SendToDLQ carry
+// the message_id of their database copy and are skipped as duplicates; other messages get an
+// ID derived from their partition and offset, so redeliveries are skipped too.
+func ingestDLQMessage(msg kafka.Message) {
+	rec := dlqRecordFromKafka(msg)
+
+	inserted, err := insertDLQMessage(rec)
+	if err != nil {
+		logger.Error("Error storing message from DLQ topic %s (partition %d, offset %d): %v", msg.Topic, msg.Partition, msg.Offset, err)
+		return
+	}
+	if inserted {
+		logger.Warn("Ingested DLQ message %s from %s (original topic %s): %s", rec.MessageID, msg.Topic, rec.Topic, rec.ErrorMsg)
+	}
+}
+
+// dlqRecordFromKafka maps a DLQ topic message to a dlq_messages row. Messages that are not
+// an envelope are stored whole, under the DLQ topic itself.
+func dlqRecordFromKafka(msg kafka.Message) dlqRecord {
+	var env dlqEnvelope
+	if err := json.Unmarshal(msg.Value, &env); err != nil || env.OriginalTopic == "" {
+		return dlqRecord{
+			MessageID: offsetMessageID(msg),
+			Topic:     msg.Topic,
+			Key:       string(msg.Key),
+			Value:     msg.Value,
+			ErrorMsg:  headerValue(msg, "error", "Published to the DLQ topic without an envelope"),
+		}
+	}
+
+	rec := dlqRecord{
+		MessageID: env.MessageID,
+		Topic:     env.OriginalTopic,
+		Key:       env.OriginalKey,
+		Value:     env.OriginalValue,
+		ErrorMsg:  env.ErrorMessage,
+	}
+	if !uuidPattern.MatchString(rec.MessageID) {
+		rec.MessageID = offsetMessageID(msg)
+	}
+	// SendToDLQ stores original_value as a JSON string holding the document
+	var text string
+	if err := json.Unmarshal(env.OriginalValue, &text); err == nil {
+		rec.Value = []byte(text)
+	}
+	if rec.Key == "" {
+		rec.Key = string(msg.Key)
+	}
+	if rec.ErrorMsg == "" {
+		rec.ErrorMsg = headerValue(msg, "error", "No error message in DLQ envelope")
+	}
+	if env.PayloadRedacted {
+		rec.QuarantineNote = redactedPayloadNote
+	}
+	return rec
+}
+
+// headerValue returns the value of a Kafka header, or fallback when it is missing
+func headerValue(msg kafka.Message, key, fallback string) string {
+	for _, h := range msg.Headers {
+		if h.Key == key && len(h.Value) > 0 {
+			return string(h.Value)
+		}
+	}
+	return fallback
+}
+
+// offsetMessageID derives a stable UUID from the message's position in the DLQ topic
+func offsetMessageID(msg kafka.Message) string {
+	sum := md5.Sum([]byte(fmt.Sprintf("%s:%d:%d", msg.Topic, msg.Partition, msg.Offset)))
+	return formatUUID(sum)
+}
+
+// newDLQMessageID returns a random (version 4) UUID
+func newDLQMessageID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// crypto/rand does not fail on supported platforms; let the database generate the ID
+		return ""
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return formatUUID(b)
+}
+
+func formatUUID(b [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// isDLQTopic reports whether topic is the configured DLQ topic
+func isDLQTopic(topic string) bool {
+	return config.AppConfig.KafkaDLQTopic != "" && topic == config.AppConfig.KafkaDLQTopic
+}
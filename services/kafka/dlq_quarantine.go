@@ -0,0 +1,48 @@
+package kafka
+
+import (
+	"errors"
+)
+
+var (
+	// ErrDLQMessageQuarantined is returned when retrying a quarantined message without forcing
+	ErrDLQMessageQuarantined = errors.New("DLQ message is quarantined; use force retry to reprocess it")
+	// ErrDLQMessageNotQuarantined is returned when force retrying a message that is not in quarantine
+	ErrDLQMessageNotQuarantined = errors.New("DLQ message is not quarantined")
+)
+
+// ListQuarantinedDLQMessages lists unresolved messages that exhausted their retry budget,
+// newest first. The topic, paging and search fields of filter apply.
+func ListQuarantinedDLQMessages(filter DLQFilter) (*DLQPage, error) {
+	unresolved, quarantined := false, true
+	filter.Resolved = &unresolved
+	filter.Quarantined = &quarantined
+	return ListDLQMessages(filter)
+}
+
+// ForceRetryDLQMessage reprocesses a quarantined message once. On success the message is
+// resolved; on failure it stays quarantined and the processing error is returned as
+// processErr.
+func ForceRetryDLQMessage(messageID string) (processErr error, err error) {
+	if !uuidPattern.MatchString(messageID) {
+		return nil, ErrInvalidMessageID
+	}
+
+	dbConn := getDBConnection()
+	if dbConn == nil {
+		return nil, nil
+	}
+
+	var quarantined bool
+	err = dbConn.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM dlq_messages WHERE message_id = $1 AND resolved = FALSE AND quarantined_at IS NOT NULL)",
+		messageID).Scan(&quarantined)
+	if err != nil {
+		return nil, err
+	}
+	if !quarantined {
+		return nil, ErrDLQMessageNotQuarantined
+	}
+
+	return retryDLQMessage(messageID, true)
+}
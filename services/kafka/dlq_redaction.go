@@ -0,0 +1,192 @@
+package kafka
+
+import (
+	"admission-module/config"
+	"admission-module/logger"
+	"admission-module/utils"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrDLQMessageNotFound is returned when a DLQ message does not exist
+	ErrDLQMessageNotFound = errors.New("DLQ message not found")
+	// ErrDLQPayloadUnavailable is returned when the original payload is no longer stored
+	// (the message was resolved, or its payload exceeded DLQ_MAX_PAYLOAD_BYTES)
+	ErrDLQPayloadUnavailable = errors.New("original DLQ payload is not available")
+)
+
+// piiMaskers mask the event fields carrying personal data, keyed by lower-case field name
+var piiMaskers = map[string]func(string) string{
+	"email":         maskEmail,
+	"recipient":     maskEmail,
+	"student_email": maskEmail,
+	"phone":         maskPhone,
+	"name":          maskName,
+	"student_name":  maskName,
+	"body":          maskText,
+	"attachment":    maskText,
+}
+
+// redactDLQPayload returns the copy of a message value stored for display: PII fields are
+// masked and long strings truncated to maxChars. Values that are not JSON objects are
+// replaced by a placeholder.
+func redactDLQPayload(value []byte, maxChars int) []byte {
+	var payload interface{}
+	if err := json.Unmarshal(value, &payload); err != nil {
+		placeholder, _ := json.Marshal(map[string]interface{}{
+			"redacted": fmt.Sprintf("%d bytes, not JSON", len(value)),
+		})
+		return placeholder
+	}
+
+	redacted, err := json.Marshal(redactValue("", payload, maxChars))
+	if err != nil {
+		return []byte(`{}`)
+	}
+	return redacted
+}
+
+// redactValue walks a decoded JSON value, masking PII fields and truncating long strings
+func redactValue(field string, value interface{}, maxChars int) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range v {
+			v[key] = redactValue(key, inner, maxChars)
+		}
+		return v
+	case []interface{}:
+		for i, inner := range v {
+			v[i] = redactValue(field, inner, maxChars)
+		}
+		return v
+	case string:
+		if mask, ok := piiMaskers[strings.ToLower(field)]; ok {
+			return mask(v)
+		}
+		return truncate(v, maxChars)
+	default:
+		return v
+	}
+}
+
+// truncate shortens s to maxChars runes, noting how much was cut
+func truncate(s string, maxChars int) string {
+	runes := []rune(s)
+	if maxChars <= 0 || len(runes) <= maxChars {
+		return s
+	}
+	return fmt.Sprintf("%s…[%d chars truncated]", string(runes[:maxChars]), len(runes)-maxChars)
+}
+
+// maskEmail keeps the first character of the local part and the domain: j***@example.com
+func maskEmail(s string) string {
+	local, domain, ok := strings.Cut(s, "@")
+	if !ok || local == "" {
+		return maskText(s)
+	}
+	return string([]rune(local)[:1]) + "***@" + domain
+}
+
+// maskPhone keeps the last two digits
+func maskPhone(s string) string {
+	if len(s) <= 2 {
+		return "***"
+	}
+	return "***" + s[len(s)-2:]
+}
+
+// maskName keeps the initial of every word: "Ravi Kumar" becomes "R*** K***"
+func maskName(s string) string {
+	words := strings.Fields(s)
+	for i, word := range words {
+		words[i] = string([]rune(word)[:1]) + "***"
+	}
+	return strings.Join(words, " ")
+}
+
+// maskText hides free text entirely, keeping only its length
+func maskText(s string) string {
+	if s == "" {
+		return s
+	}
+	return fmt.Sprintf("[redacted %d chars]", len([]rune(s)))
+}
+
+// retainedRawPayload returns the original value kept for retries and reveals,
+// or nil when it exceeds the configured size limit
+func retainedRawPayload(value []byte) []byte {
+	if limit := config.AppConfig.DLQMaxPayloadBytes; limit > 0 && len(value) > limit {
+		return nil
+	}
+	return value
+}
+
+// encryptRawPayload encrypts a retained original payload with the PII key before it is
+// stored in raw_value. Without PII_ENCRYPTION_KEY it is stored as is.
+func encryptRawPayload(raw []byte) ([]byte, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	sealed, err := utils.EncryptPII(string(raw))
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting DLQ payload: %w", err)
+	}
+	return []byte(sealed), nil
+}
+
+// decryptRawPayload returns the plaintext of a payload read from raw_value. Payloads stored
+// before encryption, and the redacted fallback, are returned unchanged.
+func decryptRawPayload(value []byte) ([]byte, error) {
+	if value == nil {
+		return nil, nil
+	}
+	plain, err := utils.DecryptPII(string(value))
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting DLQ payload: %w", err)
+	}
+	return []byte(plain), nil
+}
+
+// RevealDLQMessage returns the original (unredacted) payload of a DLQ message and records
+// who revealed it and why in dlq_reveal_log
+func RevealDLQMessage(messageID, revealedBy, reason string) (json.RawMessage, error) {
+	if !uuidPattern.MatchString(messageID) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMessageID, messageID)
+	}
+
+	dbConn := getDBConnection()
+	if dbConn == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+
+	var raw []byte
+	err := dbConn.QueryRow("SELECT raw_value FROM dlq_messages WHERE message_id = $1", messageID).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return nil, ErrDLQMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Access is logged even when the payload is gone, so attempts stay visible
+	_, err = dbConn.Exec(
+		"INSERT INTO dlq_reveal_log (message_id, revealed_by, reason, payload_available) VALUES ($1, $2, $3, $4)",
+		messageID, revealedBy, reason, raw != nil)
+	if err != nil {
+		return nil, fmt.Errorf("error logging DLQ reveal: %w", err)
+	}
+	logger.Warn("DLQ message %s payload revealed by %s (reason: %s)", messageID, revealedBy, reason)
+
+	if raw == nil {
+		return nil, ErrDLQPayloadUnavailable
+	}
+	raw, err = decryptRawPayload(raw)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(raw), nil
+}
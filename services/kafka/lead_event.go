@@ -0,0 +1,69 @@
+package kafka
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// timelineEventPrefixes are event families that are only recorded in the lead timeline
+var timelineEventPrefixes = []string{"lead.", "payment.", "application."}
+
+// isTimelineEvent returns true if the event type has no handler besides the lead timeline
+func isTimelineEvent(eventType string) bool {
+	for _, prefix := range timelineEventPrefixes {
+		if strings.HasPrefix(eventType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordLeadEvent stores an event carrying a student_id in lead_event.
+// Events are keyed by a hash of the message value, so redeliveries and DLQ
+// retries are stored once. Events for unknown leads are ignored.
+func recordLeadEvent(msg kafka.Message, eventType string, event map[string]interface{}) error {
+	studentID, ok := event["student_id"].(float64)
+	if !ok || studentID <= 0 {
+		return nil
+	}
+
+	dbConn := getDBConnection()
+	if dbConn == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	hash := sha256.Sum256(msg.Value)
+
+	_, err := dbConn.Exec(`
+		INSERT INTO lead_event (lead_id, event_type, topic, payload, event_hash, occurred_at)
+		SELECT $1, $2, $3, $4, $5, $6
+		WHERE EXISTS (SELECT 1 FROM student_lead WHERE id = $1)
+		ON CONFLICT (event_hash) DO NOTHING
+	`, int(studentID), eventType, msg.Topic, string(msg.Value), hex.EncodeToString(hash[:]), eventOccurredAt(msg, event))
+	if err != nil {
+		return fmt.Errorf("error recording lead event: %w", err)
+	}
+
+	return nil
+}
+
+// eventOccurredAt reads the event's own timestamp ("ts" or "timestamp"),
+// falling back to the Kafka message time
+func eventOccurredAt(msg kafka.Message, event map[string]interface{}) time.Time {
+	for _, field := range []string{"ts", "timestamp"} {
+		if value, ok := event[field].(string); ok {
+			if t, err := time.Parse(time.RFC3339, value); err == nil {
+				return t.UTC()
+			}
+		}
+	}
+	if !msg.Time.IsZero() {
+		return msg.Time.UTC()
+	}
+	return time.Now().UTC()
+}
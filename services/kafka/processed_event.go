@@ -0,0 +1,121 @@
+package kafka
+
+import (
+	"admission-module/logger"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Processed event status constants
+const (
+	processedEventStatusProcessing = "PROCESSING"
+	processedEventStatusDone       = "DONE"
+)
+
+// processedEventClaimTimeout is how long a claim may stay PROCESSING before another
+// consumer may take the event over (e.g. after a crash mid-handler)
+const processedEventClaimTimeout = 10 * time.Minute
+
+// eventID identifies a message for deduplication: the event's own "event_id" when it
+// carries one, otherwise a hash of topic and value (redeliveries are byte-identical)
+func eventID(msg kafka.Message) string {
+	var envelope struct {
+		EventID string `json:"event_id"`
+	}
+	if err := json.Unmarshal(msg.Value, &envelope); err == nil && envelope.EventID != "" {
+		return envelope.EventID
+	}
+
+	hash := sha256.Sum256(append([]byte(msg.Topic+"\x00"), msg.Value...))
+	return hex.EncodeToString(hash[:])
+}
+
+// claimEvent records that this consumer is processing the event. It returns false when the
+// event was already processed, or is being processed by someone else right now.
+func claimEvent(id, topic string) (bool, error) {
+	dbConn := getDBConnection()
+	if dbConn == nil {
+		return true, nil
+	}
+
+	var claimed string
+	err := dbConn.QueryRow(`
+		INSERT INTO processed_events (event_id, topic, status, claimed_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (event_id) DO UPDATE SET status = $3, claimed_at = NOW()
+		WHERE processed_events.status <> $4 AND processed_events.claimed_at < $5
+		RETURNING event_id
+	`, id, topic, processedEventStatusProcessing, processedEventStatusDone,
+		time.Now().Add(-processedEventClaimTimeout)).Scan(&claimed)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// finishEvent marks a claimed event as processed, or releases the claim when processing
+// failed so a redelivery or DLQ retry can process it again
+func finishEvent(id string, processErr error) {
+	dbConn := getDBConnection()
+	if dbConn == nil {
+		return
+	}
+
+	var err error
+	if processErr == nil {
+		_, err = dbConn.Exec(
+			"UPDATE processed_events SET status = $1, processed_at = NOW() WHERE event_id = $2",
+			processedEventStatusDone, id)
+	} else {
+		_, err = dbConn.Exec("DELETE FROM processed_events WHERE event_id = $1", id)
+	}
+	if err != nil {
+		logger.Warn("Could not update processed event %s: %v", id, err)
+	}
+}
+
+// CountPurgeableProcessedEvents returns how many dedup records PurgeProcessedEvents would
+// delete for cutoff
+func CountPurgeableProcessedEvents(ctx context.Context, cutoff time.Time) (int, error) {
+	dbConn := getDBConnection()
+	if dbConn == nil {
+		return 0, nil
+	}
+
+	var n int
+	err := dbConn.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM processed_events WHERE status = $1 AND processed_at < $2",
+		processedEventStatusDone, cutoff).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("error counting processed events: %w", err)
+	}
+	return n, nil
+}
+
+// PurgeProcessedEvents deletes dedup records of events processed before cutoff. Kafka
+// does not redeliver that far back, so they are no longer needed.
+func PurgeProcessedEvents(ctx context.Context, cutoff time.Time) (int, error) {
+	dbConn := getDBConnection()
+	if dbConn == nil {
+		return 0, nil
+	}
+
+	result, err := dbConn.ExecContext(ctx,
+		"DELETE FROM processed_events WHERE status = $1 AND processed_at < $2",
+		processedEventStatusDone, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("error purging processed events: %w", err)
+	}
+	purged, _ := result.RowsAffected()
+	return int(purged), nil
+}
@@ -3,11 +3,14 @@ package kafka
 import (
 	"admission-module/config"
 	"admission-module/logger"
+	"admission-module/metrics"
 	"context"
 	"encoding/json"
+	"fmt"
 	"math"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/segmentio/kafka-go"
@@ -17,13 +20,33 @@ var (
 	producer      *kafka.Writer
 	producerMutex sync.Mutex
 	isConnected   bool
+	// asyncProducer is used by Publish when KAFKA_ASYNC_PUBLISH is set; failed
+	// deliveries are reported to onAsyncCompletion
+	asyncProducer   *kafka.Writer
+	producerBrokers []string
+	// producerTransport carries the TLS/SASL settings; nil uses the kafka-go default
+	producerTransport kafka.RoundTripper
+
+	asyncDelivered atomic.Int64
+	asyncFailed    atomic.Int64
 )
 
+// AsyncDeliveryStats counts messages delivered or failed by the async producer
+type AsyncDeliveryStats struct {
+	Enabled   bool  `json:"enabled"`
+	Delivered int64 `json:"delivered"`
+	Failed    int64 `json:"failed"`
+}
+
 // InitProducer initializes a Kafka writer using brokers from the config
 func InitProducer() {
 	producerMutex.Lock()
 	defer producerMutex.Unlock()
+	initProducerLocked()
+}
 
+// initProducerLocked creates the writers; producerMutex must be held
+func initProducerLocked() {
 	if config.AppConfig.KafkaBrokers == "" {
 		logger.Info("Kafka is disabled (KAFKA_BROKERS is empty)")
 		return
@@ -44,21 +67,80 @@ func InitProducer() {
 		return
 	}
 
+	transport, _, err := connectionSecurity()
+	if err != nil {
+		logger.Error("Kafka producer disabled: %v", err)
+		return
+	}
+
 	// Attempt to create required topics
 	ensureTopicsExist(validBrokers)
 
-	producer = &kafka.Writer{
-		Addr:     kafka.TCP(validBrokers...),
+	producerBrokers = validBrokers
+	producerTransport = transport
+	producer = newSyncWriter(validBrokers)
+	if config.AppConfig.KafkaAsyncPublish && asyncProducer == nil {
+		asyncProducer = &kafka.Writer{
+			Addr:         kafka.TCP(validBrokers...),
+			Balancer:     &kafka.LeastBytes{},
+			Async:        true,
+			WriteTimeout: 10 * time.Second,
+			RequiredAcks: kafka.RequireAll,
+			Completion:   onAsyncCompletion,
+			Transport:    transport,
+		}
+	}
+
+	logger.Info("✓ Kafka producer initialized. Brokers=%v, Topic=%s, Async=%t", validBrokers, config.AppConfig.KafkaTopic, asyncProducer != nil)
+	isConnected = true
+}
+
+// newSyncWriter creates the writer used by PublishSync
+func newSyncWriter(brokers []string) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:     kafka.TCP(brokers...),
 		Balancer: &kafka.LeastBytes{},
 		Async:    false,
 		// Set a reasonable write timeout
 		WriteTimeout: 10 * time.Second,
 		// Allow up to 10 retries
 		RequiredAcks: kafka.RequireAll,
+		Transport:    producerTransport,
 	}
+}
 
-	logger.Info("✓ Kafka producer initialized. Brokers=%v, Topic=%s", validBrokers, config.AppConfig.KafkaTopic)
-	isConnected = true
+// onAsyncCompletion is called by the async writer after each delivered batch.
+// Failed messages are stored in the DLQ so they can be inspected and retried.
+func onAsyncCompletion(messages []kafka.Message, err error) {
+	perTopic := map[string]int{}
+	for _, msg := range messages {
+		perTopic[msg.Topic]++
+	}
+	for topic, n := range perTopic {
+		metrics.ObserveKafkaPublishes(topic, n, err)
+	}
+
+	if err == nil {
+		asyncDelivered.Add(int64(len(messages)))
+		return
+	}
+
+	asyncFailed.Add(int64(len(messages)))
+	logger.Warn("Async Kafka delivery of %d messages failed: %v", len(messages), err)
+	for _, msg := range messages {
+		if dlqErr := StoreDLQMessage(msg.Topic, string(msg.Key), msg.Value, "async delivery failed: "+err.Error()); dlqErr != nil {
+			logger.Error("Failed to send message to DLQ: %v", dlqErr)
+		}
+	}
+}
+
+// GetAsyncDeliveryStats returns the async producer's delivery counters
+func GetAsyncDeliveryStats() AsyncDeliveryStats {
+	return AsyncDeliveryStats{
+		Enabled:   config.AppConfig.KafkaAsyncPublish,
+		Delivered: asyncDelivered.Load(),
+		Failed:    asyncFailed.Load(),
+	}
 }
 
 // ensureTopicsExist creates Kafka topics if they don't already exist
@@ -76,7 +158,12 @@ func ensureTopicsExist(brokers []string) {
 				time.Sleep(1 * time.Second) // Initial wait
 			}
 
-			conn, err := kafka.Dial("tcp", brokers[0])
+			dialer, err := kafkaDialer(10 * time.Second)
+			if err != nil {
+				logger.Warn("Could not create Kafka topics: %v", err)
+				return
+			}
+			conn, err := dialer.Dial("tcp", brokers[0])
 			if err != nil {
 				if attempt == maxRetries-1 {
 					logger.Warn("Could not connect to Kafka broker for topic creation after %d attempts: %v (Kafka topics may need manual creation)", maxRetries, err)
@@ -84,21 +171,7 @@ func ensureTopicsExist(brokers []string) {
 				continue
 			}
 
-			requiredTopics := []string{"payments", "applications", "emails", "interviews"}
-			// include configured DLQ topic if present
-			if t := strings.TrimSpace(config.AppConfig.KafkaDLQTopic); t != "" {
-				// avoid duplicates
-				found := false
-				for _, rt := range requiredTopics {
-					if rt == t {
-						found = true
-						break
-					}
-				}
-				if !found {
-					requiredTopics = append(requiredTopics, t)
-				}
-			}
+			requiredTopics := RequiredTopics()
 			successCount := 0
 
 			for _, topic := range requiredTopics {
@@ -135,15 +208,112 @@ func ensureTopicsExist(brokers []string) {
 	}()
 }
 
-// Publish marshals value to JSON and publishes to the given topic with key
-// Uses exponential backoff retry logic (3 attempts)
+// RequiredTopics lists the topics the service produces to, including the configured DLQ topic
+func RequiredTopics() []string {
+	requiredTopics := []string{"leads", "payments", "applications", "emails", "interviews"}
+	// include configured DLQ topic if present
+	if t := strings.TrimSpace(config.AppConfig.KafkaDLQTopic); t != "" {
+		// avoid duplicates
+		found := false
+		for _, rt := range requiredTopics {
+			if rt == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			requiredTopics = append(requiredTopics, t)
+		}
+	}
+	return requiredTopics
+}
+
+// CheckBroker dials the first configured broker and returns the required topics that are missing
+func CheckBroker(ctx context.Context) ([]string, error) {
+	var broker string
+	for _, b := range strings.Split(config.AppConfig.KafkaBrokers, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			broker = b
+			break
+		}
+	}
+	if broker == "" {
+		return nil, fmt.Errorf("no Kafka brokers configured (set KAFKA_BROKERS)")
+	}
+
+	dialer, err := kafkaDialer(5 * time.Second)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", broker)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to broker %s: %w", broker, err)
+	}
+	defer conn.Close()
+
+	partitions, err := conn.ReadPartitions()
+	if err != nil {
+		return nil, fmt.Errorf("error reading topic metadata from %s: %w", broker, err)
+	}
+
+	existing := map[string]bool{}
+	for _, p := range partitions {
+		existing[p.Topic] = true
+	}
+
+	missing := []string{}
+	for _, topic := range RequiredTopics() {
+		if !existing[topic] {
+			missing = append(missing, topic)
+		}
+	}
+	return missing, nil
+}
+
+// Publish marshals value to JSON and publishes to the given topic with key.
+// With KAFKA_ASYNC_PUBLISH set it only enqueues the message and returns; delivery
+// failures end up in the DLQ through the completion callback. Otherwise it behaves
+// like PublishSync.
 // If Kafka is disabled or not initialized, returns nil (best-effort)
-func Publish(topic, key string, value interface{}) error {
+func Publish(ctx context.Context, topic, key string, value interface{}) error {
+	if !config.AppConfig.KafkaAsyncPublish {
+		return PublishSync(ctx, topic, key, value)
+	}
+
 	producerMutex.Lock()
 	if producer == nil && config.AppConfig.KafkaBrokers != "" {
-		producerMutex.Unlock()
-		InitProducer()
-		producerMutex.Lock()
+		initProducerLocked()
+	}
+	writer := asyncProducer
+	producerMutex.Unlock()
+
+	if writer == nil {
+		return PublishSync(ctx, topic, key, value)
+	}
+
+	payload, err := json.Marshal(value)
+	if err != nil {
+		logger.Error("Error marshaling Kafka message: %v", err)
+		return err
+	}
+
+	// Async writers return immediately; the error only reports a closed writer
+	return writer.WriteMessages(ctx, kafka.Message{
+		Topic: topic,
+		Key:   []byte(key),
+		Value: payload,
+	})
+}
+
+// PublishSync publishes and waits for the broker's acknowledgement.
+// Uses exponential backoff retry logic (3 attempts of KAFKA_PUBLISH_TIMEOUT_SECONDS each);
+// callers needing delivery guarantees (e.g. the event outbox relay) use it regardless of
+// the async setting. Retrying stops when ctx is done, and the message goes to the DLQ.
+// If Kafka is disabled or not initialized, returns nil (best-effort)
+func PublishSync(ctx context.Context, topic, key string, value interface{}) error {
+	producerMutex.Lock()
+	if producer == nil && config.AppConfig.KafkaBrokers != "" {
+		initProducerLocked()
 	}
 	defer producerMutex.Unlock()
 
@@ -165,35 +335,45 @@ func Publish(topic, key string, value interface{}) error {
 	}
 
 	// Retry with exponential backoff
+	attemptTimeout := time.Duration(config.AppConfig.KafkaPublishTimeoutSeconds) * time.Second
 	var lastErr error
 	for attempt := 0; attempt < 3; attempt++ {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		err := producer.WriteMessages(ctx, msg)
+		attemptCtx, cancel := context.WithTimeout(ctx, attemptTimeout)
+		err := producer.WriteMessages(attemptCtx, msg)
 		cancel()
 
 		if err == nil {
 			isConnected = true
+			metrics.ObserveKafkaPublish(topic, nil)
 			return nil
 		}
 
 		lastErr = err
+		if ctx.Err() != nil {
+			// The caller gave up (request cancelled or its deadline passed): the broker
+			// is not to blame, so keep the connection state and writer as they are
+			break
+		}
 
 		if attempt < 2 {
 			backoffTime := time.Duration(math.Pow(2, float64(attempt))) * time.Second
-			time.Sleep(backoffTime)
+			select {
+			case <-time.After(backoffTime):
+			case <-ctx.Done():
+			}
 		}
 		isConnected = false
 
-		// If this is the second attempt failing, try to recreate the producer
+		// If this is the second attempt failing, recreate the writer
 		// to avoid stale broker metadata
 		if attempt == 1 {
-			if producer != nil {
-				producer.Close()
-			}
-			InitProducer()
+			producer.Close()
+			producer = newSyncWriter(producerBrokers)
 		}
 	}
 
+	metrics.ObserveKafkaPublish(topic, lastErr)
+
 	// Send to DLQ if all retries failed (database only, avoid recursion)
 	if dlqErr := StoreDLQMessage(topic, key, payload, lastErr.Error()); dlqErr != nil {
 		logger.Error("Failed to send message to DLQ: %v", dlqErr)
@@ -214,6 +394,13 @@ func Close() error {
 	producerMutex.Lock()
 	defer producerMutex.Unlock()
 
+	// Closing the async writer flushes messages still in flight
+	if asyncProducer != nil {
+		if err := asyncProducer.Close(); err != nil {
+			logger.Error("Error closing async Kafka producer: %v", err)
+		}
+		asyncProducer = nil
+	}
 	if producer != nil {
 		return producer.Close()
 	}
@@ -0,0 +1,168 @@
+package kafka
+
+import (
+	"admission-module/config"
+	"admission-module/logger"
+	"database/sql"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultPolicyTopic is the policy applied to topics without their own retry policy
+const DefaultPolicyTopic = "*"
+
+// RetryPolicy controls how the DLQ auto-retry loop treats messages of a topic
+type RetryPolicy struct {
+	Topic             string  `json:"topic"`
+	MaxRetries        int     `json:"max_retries"`
+	BackoffSeconds    int     `json:"backoff_seconds"`
+	BackoffMultiplier float64 `json:"backoff_multiplier"`
+	// EscalationTarget is the email address alerted when the retry budget is exhausted
+	EscalationTarget string `json:"escalation_target,omitempty"`
+}
+
+// Backoff returns the wait before the next attempt after retryCount failed attempts,
+// capped at DLQ_RETRY_MAX_BACKOFF_SECONDS
+func (p RetryPolicy) Backoff(retryCount int) time.Duration {
+	multiplier := p.BackoffMultiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	seconds := float64(p.BackoffSeconds) * math.Pow(multiplier, float64(retryCount))
+	if maxSeconds := float64(config.AppConfig.DLQRetryMaxBackoffSeconds); maxSeconds > 0 && seconds > maxSeconds {
+		seconds = maxSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// RetryPolicies maps topic names to their retry policy
+type RetryPolicies map[string]RetryPolicy
+
+// DefaultRetryPolicy is the policy from DLQ_RETRY_* settings, used when neither the topic
+// nor the '*' policy exists
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Topic:             DefaultPolicyTopic,
+		MaxRetries:        config.AppConfig.DLQRetryMaxRetries,
+		BackoffSeconds:    config.AppConfig.DLQRetryBackoffSeconds,
+		BackoffMultiplier: config.AppConfig.DLQRetryBackoffMultiplier,
+	}
+}
+
+// For returns the policy for topic, falling back to the '*' policy and then to DefaultRetryPolicy
+func (p RetryPolicies) For(topic string) RetryPolicy {
+	if policy, ok := p[topic]; ok {
+		return policy
+	}
+	if policy, ok := p[DefaultPolicyTopic]; ok {
+		return policy
+	}
+	return DefaultRetryPolicy()
+}
+
+// DLQAlert describes a DLQ message whose retry budget is exhausted
+type DLQAlert struct {
+	MessageID    string
+	Topic        string
+	ErrorMessage string
+	RetryCount   int
+	Policy       RetryPolicy
+}
+
+var (
+	alertMutex sync.Mutex
+	// dlqAlertHandler is a callback notifying humans when a retry budget is exhausted
+	dlqAlertHandler func(DLQAlert) error
+)
+
+// RegisterDLQAlertHandler registers the callback invoked when a DLQ message exhausts its retry budget
+func RegisterDLQAlertHandler(fn func(DLQAlert) error) {
+	alertMutex.Lock()
+	defer alertMutex.Unlock()
+	dlqAlertHandler = fn
+}
+
+// LoadRetryPolicies reads all retry policies from the database
+func LoadRetryPolicies() (RetryPolicies, error) {
+	dbConn := getDBConnection()
+	if dbConn == nil {
+		return RetryPolicies{}, nil
+	}
+
+	rows, err := dbConn.Query(`
+		SELECT topic, max_retries, backoff_seconds, backoff_multiplier, COALESCE(escalation_target, '')
+		FROM dlq_retry_policy
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := RetryPolicies{}
+	for rows.Next() {
+		var p RetryPolicy
+		if err := rows.Scan(&p.Topic, &p.MaxRetries, &p.BackoffSeconds, &p.BackoffMultiplier, &p.EscalationTarget); err != nil {
+			return nil, err
+		}
+		policies[p.Topic] = p
+	}
+
+	return policies, rows.Err()
+}
+
+// UpsertRetryPolicy creates or replaces the retry policy for a topic
+func UpsertRetryPolicy(p RetryPolicy) error {
+	dbConn := getDBConnection()
+	if dbConn == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	_, err := dbConn.Exec(`
+		INSERT INTO dlq_retry_policy (topic, max_retries, backoff_seconds, backoff_multiplier, escalation_target, updated_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NOW())
+		ON CONFLICT (topic) DO UPDATE
+		SET max_retries = EXCLUDED.max_retries,
+			backoff_seconds = EXCLUDED.backoff_seconds,
+			backoff_multiplier = EXCLUDED.backoff_multiplier,
+			escalation_target = EXCLUDED.escalation_target,
+			updated_at = NOW()
+	`, p.Topic, p.MaxRetries, p.BackoffSeconds, p.BackoffMultiplier, p.EscalationTarget)
+	return err
+}
+
+// escalateDLQMessage quarantines a message whose retry budget is exhausted, so the
+// retry loop skips it, and notifies the registered alert handler (once per message)
+func escalateDLQMessage(dbConn *sql.DB, messageID, topic, errorMsg string, retryCount int, policy RetryPolicy) {
+	result, err := dbConn.Exec(
+		"UPDATE dlq_messages SET escalated_at = NOW(), quarantined_at = NOW() WHERE message_id = $1 AND escalated_at IS NULL", messageID)
+	if err != nil {
+		logger.Error("Error escalating DLQ message %s: %v", messageID, err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return
+	}
+
+	logger.Error("DLQ retry budget exhausted for message %s (topic=%s, retries=%d), quarantined: %s", messageID, topic, retryCount, errorMsg)
+
+	alertMutex.Lock()
+	handler := dlqAlertHandler
+	alertMutex.Unlock()
+
+	if handler == nil {
+		return
+	}
+
+	alert := DLQAlert{
+		MessageID:    messageID,
+		Topic:        topic,
+		ErrorMessage: errorMsg,
+		RetryCount:   retryCount,
+		Policy:       policy,
+	}
+	if err := handler(alert); err != nil {
+		logger.Error("Error sending DLQ alert for message %s: %v", messageID, err)
+	}
+}
@@ -0,0 +1,109 @@
+package kafka
+
+import (
+	"admission-module/config"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// SASL mechanisms accepted in KAFKA_SASL_MECHANISM
+const (
+	SASLMechanismPlain       = "PLAIN"
+	SASLMechanismSCRAMSHA256 = "SCRAM-SHA-256"
+	SASLMechanismSCRAMSHA512 = "SCRAM-SHA-512"
+)
+
+// connectionSecurity builds the transport (for writers) and dialer (for readers and admin
+// connections) carrying the configured TLS and SASL settings. Both are nil when neither
+// KAFKA_TLS nor KAFKA_SASL_MECHANISM is set, so the kafka-go defaults apply.
+func connectionSecurity() (kafka.RoundTripper, *kafka.Dialer, error) {
+	cfg := config.AppConfig
+	if !cfg.KafkaTLSEnabled && cfg.KafkaSASLMechanism == "" {
+		return nil, nil, nil
+	}
+
+	tlsConfig, err := kafkaTLSConfig(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	mechanism, err := kafkaSASLMechanism(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	transport := &kafka.Transport{
+		TLS:  tlsConfig,
+		SASL: mechanism,
+	}
+	dialer := &kafka.Dialer{
+		Timeout:       10 * time.Second,
+		DualStack:     true,
+		TLS:           tlsConfig,
+		SASLMechanism: mechanism,
+	}
+	return transport, dialer, nil
+}
+
+// kafkaDialer returns the secured dialer, or a plain one with the given timeout
+func kafkaDialer(timeout time.Duration) (*kafka.Dialer, error) {
+	_, dialer, err := connectionSecurity()
+	if err != nil {
+		return nil, err
+	}
+	if dialer == nil {
+		dialer = &kafka.Dialer{DualStack: true}
+	}
+	dialer.Timeout = timeout
+	return dialer, nil
+}
+
+// kafkaTLSConfig returns the TLS settings, trusting KAFKA_TLS_CA_FILE in addition to
+// the system roots when it is set. Returns nil when TLS is disabled.
+func kafkaTLSConfig(cfg config.Config) (*tls.Config, error) {
+	if !cfg.KafkaTLSEnabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.KafkaTLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.KafkaTLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading Kafka CA certificate: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.KafkaTLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// kafkaSASLMechanism returns the configured SASL mechanism, or nil when SASL is disabled
+func kafkaSASLMechanism(cfg config.Config) (sasl.Mechanism, error) {
+	switch strings.ToUpper(cfg.KafkaSASLMechanism) {
+	case "":
+		return nil, nil
+	case SASLMechanismPlain:
+		return plain.Mechanism{Username: cfg.KafkaSASLUsername, Password: cfg.KafkaSASLPassword}, nil
+	case SASLMechanismSCRAMSHA256:
+		return scram.Mechanism(scram.SHA256, cfg.KafkaSASLUsername, cfg.KafkaSASLPassword)
+	case SASLMechanismSCRAMSHA512:
+		return scram.Mechanism(scram.SHA512, cfg.KafkaSASLUsername, cfg.KafkaSASLPassword)
+	default:
+		return nil, fmt.Errorf("unsupported KAFKA_SASL_MECHANISM %q (use %s, %s or %s)",
+			cfg.KafkaSASLMechanism, SASLMechanismPlain, SASLMechanismSCRAMSHA256, SASLMechanismSCRAMSHA512)
+	}
+}
@@ -2,20 +2,35 @@ package services
 
 import (
 	"admission-module/services/kafka"
+	"context"
+	"encoding/json"
+	"time"
 )
 
 func InitProducer() {
 	kafka.InitProducer()
 }
 
-func Publish(topic, key string, value interface{}) error {
-	return kafka.Publish(topic, key, value)
+func Publish(ctx context.Context, topic, key string, value interface{}) error {
+	return kafka.Publish(ctx, topic, key, value)
+}
+
+func PublishSync(ctx context.Context, topic, key string, value interface{}) error {
+	return kafka.PublishSync(ctx, topic, key, value)
+}
+
+func GetAsyncDeliveryStats() kafka.AsyncDeliveryStats {
+	return kafka.GetAsyncDeliveryStats()
 }
 
 func IsConnected() bool {
 	return kafka.IsConnected()
 }
 
+func CheckKafkaBroker(ctx context.Context) ([]string, error) {
+	return kafka.CheckBroker(ctx)
+}
+
 func Close() error {
 	return kafka.Close()
 }
@@ -36,6 +51,14 @@ func IsConsumerRunning() bool {
 	return kafka.IsConsumerRunning()
 }
 
+func ConsumedTopics() []string {
+	return kafka.ConsumedTopics()
+}
+
+func ConsumerLag() map[string]int64 {
+	return kafka.ConsumerLag()
+}
+
 func RegisterEmailProcessor(fn func(map[string]interface{}) error) {
 	kafka.RegisterEmailProcessor(fn)
 }
@@ -60,22 +83,78 @@ func GetDLQMessages(limit int) ([]map[string]interface{}, error) {
 	return kafka.GetDLQMessages(limit)
 }
 
+func ListDLQMessages(filter kafka.DLQFilter) (*kafka.DLQPage, error) {
+	return kafka.ListDLQMessages(filter)
+}
+
 func RetryDLQMessage(messageID string) error {
 	return kafka.RetryDLQMessage(messageID)
 }
 
+func ListQuarantinedDLQMessages(filter kafka.DLQFilter) (*kafka.DLQPage, error) {
+	return kafka.ListQuarantinedDLQMessages(filter)
+}
+
+func ForceRetryDLQMessage(messageID string) (error, error) {
+	return kafka.ForceRetryDLQMessage(messageID)
+}
+
 func ResolveDLQMessage(messageID string, notes string) error {
 	return kafka.ResolveDLQMessage(messageID, notes)
 }
 
+func RetryAllDLQMessages(ctx context.Context, topic string, max int) (*kafka.DLQBulkResult, error) {
+	return kafka.RetryAllDLQMessages(ctx, topic, max)
+}
+
+func ResolveAllDLQMessages(ctx context.Context, topic, notes, category string) (*kafka.DLQBulkResult, error) {
+	return kafka.ResolveAllDLQMessages(ctx, topic, notes, category)
+}
+
+func ArchiveResolvedDLQMessages(ctx context.Context, cutoff time.Time) (int, error) {
+	return kafka.ArchiveResolvedDLQMessages(ctx, cutoff)
+}
+
+func CountArchivableDLQMessages(ctx context.Context, cutoff time.Time) (int, error) {
+	return kafka.CountArchivableDLQMessages(ctx, cutoff)
+}
+
+func GetArchivedDLQMessage(messageID string) (json.RawMessage, error) {
+	return kafka.GetArchivedDLQMessage(messageID)
+}
+
+func RevealDLQMessage(messageID, revealedBy, reason string) (json.RawMessage, error) {
+	return kafka.RevealDLQMessage(messageID, revealedBy, reason)
+}
+
+func PurgeProcessedEvents(ctx context.Context, cutoff time.Time) (int, error) {
+	return kafka.PurgeProcessedEvents(ctx, cutoff)
+}
+
+func CountPurgeableProcessedEvents(ctx context.Context, cutoff time.Time) (int, error) {
+	return kafka.CountPurgeableProcessedEvents(ctx, cutoff)
+}
+
+func ResolveDLQMessages(messageIDs []string, notes, category string) (int, error) {
+	return kafka.ResolveDLQMessages(messageIDs, notes, category)
+}
+
 func GetDLQStats() (map[string]interface{}, error) {
 	return kafka.GetDLQStats()
 }
 
-func StartDLQAutoRetry() {
-	kafka.StartDLQAutoRetry()
+func RetryDueDLQMessages(ctx context.Context) error {
+	return kafka.RetryDueDLQMessages(ctx)
+}
+
+func RegisterDLQAlertHandler(fn func(kafka.DLQAlert) error) {
+	kafka.RegisterDLQAlertHandler(fn)
+}
+
+func GetDLQRetryPolicies() (kafka.RetryPolicies, error) {
+	return kafka.LoadRetryPolicies()
 }
 
-func StopDLQAutoRetry() {
-	kafka.StopDLQAutoRetry()
+func UpsertDLQRetryPolicy(policy kafka.RetryPolicy) error {
+	return kafka.UpsertRetryPolicy(policy)
 }
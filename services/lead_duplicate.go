@@ -0,0 +1,367 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/models"
+	"admission-module/utils"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var (
+	// ErrMergeSameLead is returned when a lead is merged into itself
+	ErrMergeSameLead = errors.New("cannot merge a lead into itself")
+	// ErrMergeHasPayments is returned when the duplicate has payments that need manual reconciliation
+	ErrMergeHasPayments = errors.New("duplicate lead has payments; reconcile them before merging")
+)
+
+// Confidence of each kind of match between two leads
+const (
+	confidenceEmailAndPhone = 1.0
+	confidenceEmail         = 0.95
+	confidencePhone         = 0.9
+	// Name-only matches are scaled by their similarity, so they never outrank contact matches
+	confidenceNameWeight = 0.7
+	// minNameSimilarity is the lowest normalized similarity treated as "very similar"
+	minNameSimilarity = 0.85
+)
+
+// FindPotentialDuplicates clusters active leads sharing a normalized email or phone,
+// or having very similar names, and returns clusters with at least minConfidence
+// ordered by confidence.
+func FindPotentialDuplicates(ctx context.Context, minConfidence float64) ([]models.DuplicateCluster, error) {
+	rows, err := db.Reader().QueryContext(ctx, `
+		SELECT id, name, email, phone, COALESCE(lead_source, ''), created_at
+		FROM student_lead
+		WHERE deleted_at IS NULL
+		ORDER BY created_at ASC, id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching leads: %w", err)
+	}
+
+	var leads []models.DuplicateLead
+	for rows.Next() {
+		var lead models.DuplicateLead
+		if err := rows.Scan(&lead.ID, &lead.Name, utils.DecryptedPII(&lead.Email), utils.DecryptedPII(&lead.Phone),
+			&lead.LeadSource, &lead.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error reading leads: %w", err)
+		}
+		leads = append(leads, lead)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading leads: %w", err)
+	}
+
+	// Candidate pairs come from shared keys, so only plausible pairs are compared
+	type pairKey struct{ a, b int }
+	type match struct {
+		confidence float64
+		reason     string
+	}
+	pairs := map[pairKey]match{}
+	addPair := func(a, b int, confidence float64, reason string) {
+		if a > b {
+			a, b = b, a
+		}
+		key := pairKey{a, b}
+		if existing, ok := pairs[key]; !ok || confidence > existing.confidence {
+			pairs[key] = match{confidence, reason}
+		}
+	}
+
+	byEmail := map[string][]int{}
+	byPhone := map[string][]int{}
+	byNameBlock := map[string][]int{}
+	for i, lead := range leads {
+		if email := normalizeEmail(lead.Email); email != "" {
+			byEmail[email] = append(byEmail[email], i)
+		}
+		if phone := normalizePhone(lead.Phone); phone != "" {
+			byPhone[phone] = append(byPhone[phone], i)
+		}
+		if name := normalizeName(lead.Name); len(name) >= 3 {
+			byNameBlock[name[:3]] = append(byNameBlock[name[:3]], i)
+		}
+	}
+
+	for _, group := range byEmail {
+		for x := 0; x < len(group); x++ {
+			for y := x + 1; y < len(group); y++ {
+				a, b := leads[group[x]], leads[group[y]]
+				if normalizePhone(a.Phone) != "" && normalizePhone(a.Phone) == normalizePhone(b.Phone) {
+					addPair(group[x], group[y], confidenceEmailAndPhone, "same email and phone")
+				} else {
+					addPair(group[x], group[y], confidenceEmail, "same email")
+				}
+			}
+		}
+	}
+	for _, group := range byPhone {
+		for x := 0; x < len(group); x++ {
+			for y := x + 1; y < len(group); y++ {
+				addPair(group[x], group[y], confidencePhone, "same phone")
+			}
+		}
+	}
+	for _, group := range byNameBlock {
+		for x := 0; x < len(group); x++ {
+			for y := x + 1; y < len(group); y++ {
+				similarity := nameSimilarity(leads[group[x]].Name, leads[group[y]].Name)
+				if similarity >= minNameSimilarity {
+					addPair(group[x], group[y], similarity*confidenceNameWeight,
+						fmt.Sprintf("similar names (%.0f%%)", similarity*100))
+				}
+			}
+		}
+	}
+
+	// Union matched pairs into clusters
+	parent := make([]int, len(leads))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for key, m := range pairs {
+		if m.confidence < minConfidence {
+			continue
+		}
+		parent[find(key.a)] = find(key.b)
+	}
+
+	type clusterAcc struct {
+		members    []int
+		confidence float64
+		reasons    map[string]bool
+	}
+	clusters := map[int]*clusterAcc{}
+	for key, m := range pairs {
+		if m.confidence < minConfidence {
+			continue
+		}
+		root := find(key.a)
+		acc, ok := clusters[root]
+		if !ok {
+			acc = &clusterAcc{reasons: map[string]bool{}}
+			clusters[root] = acc
+		}
+		if m.confidence > acc.confidence {
+			acc.confidence = m.confidence
+		}
+		acc.reasons[m.reason] = true
+	}
+	for i := range leads {
+		if acc, ok := clusters[find(i)]; ok {
+			acc.members = append(acc.members, i)
+		}
+	}
+
+	result := []models.DuplicateCluster{}
+	for _, acc := range clusters {
+		cluster := models.DuplicateCluster{Confidence: acc.confidence}
+		sources := map[string]bool{}
+		// Members are in creation order, so the first one is the oldest lead
+		for _, i := range acc.members {
+			cluster.Leads = append(cluster.Leads, leads[i])
+			sources[strings.ToLower(leads[i].LeadSource)] = true
+		}
+		cluster.KeepLeadID = cluster.Leads[0].ID
+		for _, lead := range cluster.Leads[1:] {
+			cluster.MergeLinks = append(cluster.MergeLinks,
+				fmt.Sprintf("%s/leads/%d/merge?into=%d", config.AppConfig.AppBaseURL, lead.ID, cluster.KeepLeadID))
+		}
+		for reason := range acc.reasons {
+			cluster.Reasons = append(cluster.Reasons, reason)
+		}
+		sort.Strings(cluster.Reasons)
+		cluster.SourceCount = len(sources)
+		result = append(result, cluster)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Confidence != result[j].Confidence {
+			return result[i].Confidence > result[j].Confidence
+		}
+		return result[i].KeepLeadID < result[j].KeepLeadID
+	})
+	return result, nil
+}
+
+// MergeLeads folds duplicateID into keepID: notes, timeline events, notifications and
+// escalations move to the kept lead, missing fields are copied over, and the duplicate
+// is soft-deleted with merged_into_id set. Duplicates with payments are refused.
+func MergeLeads(ctx context.Context, duplicateID, keepID int) (*models.LeadMergeResult, error) {
+	if duplicateID == keepID {
+		return nil, ErrMergeSameLead
+	}
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock both leads in ID order to avoid deadlocks with a concurrent reverse merge
+	var locked int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM (
+			SELECT id FROM student_lead WHERE id IN ($1, $2) AND deleted_at IS NULL ORDER BY id FOR UPDATE
+		) l`, duplicateID, keepID).Scan(&locked)
+	if err != nil {
+		return nil, fmt.Errorf("error locking leads: %w", err)
+	}
+	if locked != 2 {
+		return nil, ErrLeadNotFound
+	}
+
+	var hasPayments bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM payments WHERE student_id = $1)", duplicateID).Scan(&hasPayments)
+	if err != nil {
+		return nil, fmt.Errorf("error checking payments: %w", err)
+	}
+	if hasPayments {
+		return nil, ErrMergeHasPayments
+	}
+
+	result := &models.LeadMergeResult{KeptLeadID: keepID, MergedLeadID: duplicateID}
+
+	res, err := tx.ExecContext(ctx, "UPDATE lead_note SET lead_id = $1 WHERE lead_id = $2", keepID, duplicateID)
+	if err != nil {
+		return nil, fmt.Errorf("error moving notes: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil {
+		result.NotesMoved = int(n)
+	}
+
+	res, err = tx.ExecContext(ctx, "UPDATE lead_event SET lead_id = $1 WHERE lead_id = $2", keepID, duplicateID)
+	if err != nil {
+		return nil, fmt.Errorf("error moving timeline events: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil {
+		result.EventsMoved = int(n)
+	}
+
+	statements := []string{
+		"UPDATE user_notification SET lead_id = $1 WHERE lead_id = $2",
+		"UPDATE lead_escalation SET lead_id = $1 WHERE lead_id = $2",
+		// Fill gaps on the kept lead from the duplicate
+		`UPDATE student_lead k SET
+			education = COALESCE(NULLIF(k.education, ''), d.education),
+			campaign = COALESCE(NULLIF(k.campaign, ''), d.campaign),
+			selected_course_id = COALESCE(k.selected_course_id, d.selected_course_id),
+			updated_at = NOW()
+		FROM student_lead d
+		WHERE k.id = $1 AND d.id = $2`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt, keepID, duplicateID); err != nil {
+			return nil, fmt.Errorf("error merging lead %d into %d: %w", duplicateID, keepID, err)
+		}
+	}
+
+	var counselorID sql.NullInt64
+	err = tx.QueryRowContext(ctx, `
+		UPDATE student_lead SET deleted_at = NOW(), merged_into_id = $1, updated_at = NOW()
+		WHERE id = $2
+		RETURNING counselor_id`, keepID, duplicateID).Scan(&counselorID)
+	if err != nil {
+		return nil, fmt.Errorf("error removing duplicate lead: %w", err)
+	}
+
+	// The duplicate no longer occupies a counselor slot
+	if counselorID.Valid {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE counselor SET assigned_count = GREATEST(assigned_count - 1, 0), updated_at = NOW() WHERE id = $1",
+			counselorID.Int64); err != nil {
+			return nil, fmt.Errorf("error updating counselor count: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return result, nil
+}
+
+// normalizeEmail lowercases and trims an email address
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// normalizePhone keeps the last 10 digits so "+91 98765-43210" matches "9876543210"
+func normalizePhone(phone string) string {
+	var digits strings.Builder
+	for _, r := range phone {
+		if unicode.IsDigit(r) {
+			digits.WriteRune(r)
+		}
+	}
+	d := digits.String()
+	if len(d) > 10 {
+		d = d[len(d)-10:]
+	}
+	if len(d) < 7 {
+		return ""
+	}
+	return d
+}
+
+// normalizeName lowercases a name and drops everything but letters and single spaces
+func normalizeName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) {
+			b.WriteRune(r)
+		} else if unicode.IsSpace(r) {
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// nameSimilarity returns 1 - (edit distance / longer length) for normalized names
+func nameSimilarity(a, b string) float64 {
+	ra, rb := []rune(normalizeName(a)), []rune(normalizeName(b))
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
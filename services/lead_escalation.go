@@ -0,0 +1,240 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrEscalationNotFound is returned when an open admin-queue escalation does not exist
+var ErrEscalationNotFound = errors.New("escalation not found or already resolved")
+
+// EscalateStaleLeads reassigns leads that stayed NEW for LeadEscalationDays (since creation
+// or their last escalation) to another counselor with capacity. When no counselor is
+// available the lead is flagged to the admin queue. Returns the number of leads escalated.
+func EscalateStaleLeads(ctx context.Context) (int, error) {
+	if db.DB == nil {
+		return 0, nil
+	}
+
+	days := config.AppConfig.LeadEscalationDays
+	if days <= 0 {
+		days = 7
+	}
+
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT id FROM student_lead
+		WHERE deleted_at IS NULL
+			AND COALESCE(application_status, 'NEW') = 'NEW'
+			AND COALESCE(escalated_at, created_at) < NOW() - make_interval(days => $1)
+		ORDER BY created_at ASC
+		LIMIT 200`, days)
+	if err != nil {
+		return 0, fmt.Errorf("error finding stale leads: %w", err)
+	}
+
+	var leadIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("error reading stale leads: %w", err)
+		}
+		leadIDs = append(leadIDs, id)
+	}
+	rows.Close()
+
+	escalated := 0
+	for _, leadID := range leadIDs {
+		escalation, err := escalateLead(ctx, leadID, days)
+		if err != nil {
+			logger.Error("Error escalating lead %d: %v", leadID, err)
+			continue
+		}
+		if escalation == nil {
+			continue
+		}
+		escalated++
+	}
+
+	if escalated > 0 {
+		logger.Info("Escalated %d leads that stayed NEW for more than %d days", escalated, days)
+	}
+	return escalated, nil
+}
+
+// escalateLead moves one lead to a different counselor, or flags it to the admin queue.
+// Returns nil when the lead no longer qualifies (e.g. it progressed meanwhile).
+func escalateLead(ctx context.Context, leadID, days int) (*models.LeadEscalation, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var currentCounselor sql.NullInt64
+	var leadSource string
+	err = tx.QueryRowContext(ctx, `
+		SELECT counselor_id, COALESCE(lead_source, '') FROM student_lead
+		WHERE id = $1 AND deleted_at IS NULL
+			AND COALESCE(application_status, 'NEW') = 'NEW'
+			AND COALESCE(escalated_at, created_at) < NOW() - make_interval(days => $2)
+		FOR UPDATE`, leadID, days).Scan(&currentCounselor, &leadSource)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error locking lead: %w", err)
+	}
+
+	escalation := &models.LeadEscalation{
+		LeadID: leadID,
+		Reason: fmt.Sprintf("Lead remained NEW for more than %d days", days),
+	}
+	if currentCounselor.Valid {
+		escalation.FromCounselorID = &currentCounselor.Int64
+	}
+
+	// Same routing as initial assignment, but never back to the current counselor
+	query := `
+		SELECT id FROM counselor
+		WHERE assigned_count < max_capacity AND is_house_account = false AND id <> COALESCE($1, 0)`
+	if leadSource == "referral" {
+		query += ` AND is_referral_enabled = true`
+	}
+	query += ` ORDER BY assigned_count ASC, id ASC LIMIT 1 FOR UPDATE SKIP LOCKED`
+
+	var newCounselor int64
+	err = tx.QueryRowContext(ctx, query, currentCounselor).Scan(&newCounselor)
+	switch {
+	case err == sql.ErrNoRows:
+		escalation.Action = models.EscalationActionAdminQueue
+	case err != nil:
+		return nil, fmt.Errorf("error finding counselor: %w", err)
+	default:
+		escalation.Action = models.EscalationActionReassigned
+		escalation.ToCounselorID = &newCounselor
+
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE student_lead SET counselor_id = $1, updated_at = NOW() WHERE id = $2", newCounselor, leadID); err != nil {
+			return nil, fmt.Errorf("error reassigning lead: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE counselor SET assigned_count = assigned_count + 1, updated_at = NOW() WHERE id = $1", newCounselor); err != nil {
+			return nil, fmt.Errorf("error updating counselor count: %w", err)
+		}
+		if currentCounselor.Valid {
+			if _, err := tx.ExecContext(ctx,
+				"UPDATE counselor SET assigned_count = GREATEST(assigned_count - 1, 0), updated_at = NOW() WHERE id = $1",
+				currentCounselor.Int64); err != nil {
+				return nil, fmt.Errorf("error updating counselor count: %w", err)
+			}
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE student_lead SET escalated_at = NOW() WHERE id = $1", leadID); err != nil {
+		return nil, fmt.Errorf("error marking lead escalated: %w", err)
+	}
+
+	// Keep a single open admin-queue entry per lead
+	if escalation.Action == models.EscalationActionAdminQueue {
+		var open bool
+		err := tx.QueryRowContext(ctx, `
+			SELECT EXISTS(SELECT 1 FROM lead_escalation WHERE lead_id = $1 AND action = $2 AND resolved_at IS NULL)`,
+			leadID, models.EscalationActionAdminQueue).Scan(&open)
+		if err != nil {
+			return nil, fmt.Errorf("error checking admin queue: %w", err)
+		}
+		if open {
+			return nil, tx.Commit()
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO lead_escalation (lead_id, action, from_counselor_id, to_counselor_id, reason)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		leadID, escalation.Action, escalation.FromCounselorID, escalation.ToCounselorID, escalation.Reason,
+	).Scan(&escalation.ID, &escalation.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error recording escalation: %w", err)
+	}
+
+	if err := enqueueLeadEscalatedEvent(ctx, tx, escalation); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return escalation, nil
+}
+
+// enqueueLeadEscalatedEvent writes lead.escalated to the event outbox (it also lands in the lead timeline)
+func enqueueLeadEscalatedEvent(ctx context.Context, tx *sql.Tx, escalation *models.LeadEscalation) error {
+	evt := map[string]interface{}{
+		"event":             "lead.escalated",
+		"student_id":        escalation.LeadID,
+		"escalation_id":     escalation.ID,
+		"action":            escalation.Action,
+		"from_counselor_id": escalation.FromCounselorID,
+		"to_counselor_id":   escalation.ToCounselorID,
+		"reason":            escalation.Reason,
+		"ts":                escalation.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	return EnqueueEvent(ctx, tx, "leads", fmt.Sprintf("student-%d", escalation.LeadID), evt)
+}
+
+// GetAdminEscalationQueue lists unresolved leads flagged to the admin queue, oldest first
+func GetAdminEscalationQueue(ctx context.Context) ([]models.LeadEscalation, error) {
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT e.id, e.lead_id, sl.name, e.action, e.from_counselor_id, e.to_counselor_id,
+			COALESCE(e.reason, ''), e.created_at
+		FROM lead_escalation e
+		JOIN student_lead sl ON sl.id = e.lead_id
+		WHERE e.action = $1 AND e.resolved_at IS NULL AND sl.deleted_at IS NULL
+		ORDER BY e.created_at ASC`, models.EscalationActionAdminQueue)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching admin queue: %w", err)
+	}
+	defer rows.Close()
+
+	escalations := []models.LeadEscalation{}
+	for rows.Next() {
+		var e models.LeadEscalation
+		var from, to sql.NullInt64
+		if err := rows.Scan(&e.ID, &e.LeadID, &e.LeadName, &e.Action, &from, &to, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error reading admin queue: %w", err)
+		}
+		if from.Valid {
+			e.FromCounselorID = &from.Int64
+		}
+		if to.Valid {
+			e.ToCounselorID = &to.Int64
+		}
+		escalations = append(escalations, e)
+	}
+
+	return escalations, rows.Err()
+}
+
+// ResolveEscalation closes an admin-queue entry once an admin has handled the lead
+func ResolveEscalation(ctx context.Context, escalationID int, notes string) error {
+	result, err := db.DB.ExecContext(ctx, `
+		UPDATE lead_escalation SET resolved_at = NOW(), resolution_notes = NULLIF($1, '')
+		WHERE id = $2 AND action = $3 AND resolved_at IS NULL`,
+		notes, escalationID, models.EscalationActionAdminQueue)
+	if err != nil {
+		return fmt.Errorf("error resolving escalation: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrEscalationNotFound
+	}
+	return nil
+}
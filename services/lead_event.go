@@ -0,0 +1,73 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// EnqueueLeadCreatedEvent writes lead.created to the event outbox within the lead's
+// insert transaction, so the lead's timeline starts at creation
+func EnqueueLeadCreatedEvent(ctx context.Context, tx *sql.Tx, lead *models.Lead) error {
+	evt := map[string]interface{}{
+		"event":        "lead.created",
+		"student_id":   lead.ID,
+		"name":         lead.Name,
+		"email":        lead.Email,
+		"lead_source":  lead.LeadSource,
+		"counselor_id": lead.CounsellorID,
+		"ts":           lead.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	return EnqueueEvent(ctx, tx, "leads", fmt.Sprintf("student-%d", lead.ID), evt)
+}
+
+// EnqueueLeadUpdatedEvent writes a lead.updated event listing the changed columns to the event outbox within tx
+func EnqueueLeadUpdatedEvent(ctx context.Context, tx *sql.Tx, leadID int, fields []string, source string) error {
+	evt := map[string]interface{}{
+		"event":      "lead.updated",
+		"student_id": leadID,
+		"fields":     fields,
+		"source":     source,
+		"ts":         time.Now().UTC().Format(time.RFC3339),
+	}
+	return EnqueueEvent(ctx, tx, "leads", fmt.Sprintf("student-%d", leadID), evt)
+}
+
+// GetLeadTimeline returns the events recorded for a lead in chronological order
+func GetLeadTimeline(ctx context.Context, leadID int) ([]models.LeadEvent, error) {
+	var exists bool
+	err := db.DB.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM student_lead WHERE id = $1 AND deleted_at IS NULL)", leadID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("error checking lead: %w", err)
+	}
+	if !exists {
+		return nil, ErrLeadNotFound
+	}
+
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT id, lead_id, event_type, topic, payload, occurred_at
+		FROM lead_event
+		WHERE lead_id = $1
+		ORDER BY occurred_at ASC, id ASC`, leadID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching lead timeline: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.LeadEvent{}
+	for rows.Next() {
+		var event models.LeadEvent
+		var payload []byte
+		if err := rows.Scan(&event.ID, &event.LeadID, &event.EventType, &event.Topic, &payload, &event.OccurredAt); err != nil {
+			return nil, fmt.Errorf("error reading lead event: %w", err)
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,352 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"admission-module/utils"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrLeadExists is returned when creating a lead whose email and phone belong to an active lead
+var ErrLeadExists = errors.New("lead already exists with this email and phone")
+
+// CreateLead validates a lead, assigns a counselor (falling back to the house account),
+// inserts it with its lead.created event and sends the welcome email. A lead sharing both
+// email and phone with an active lead is rejected with ErrLeadExists; one sharing only one
+// of them is queued for manual review and a *LeadReviewError is returned.
+func CreateLead(ctx context.Context, lead *models.Lead) error {
+	return createLead(ctx, lead, createLeadOptions{source: LeadReviewSourceAPI})
+}
+
+// createLeadOptions tune CreateLead for imports and approved reviews
+type createLeadOptions struct {
+	source string // recorded on the review when the lead is queued
+	// reviewID approves that pending review: partial matches are accepted and the review is
+	// resolved in the same transaction as the insert
+	reviewID   int
+	resolvedBy string
+}
+
+func createLead(ctx context.Context, lead *models.Lead, opts createLeadOptions) error {
+	// Set timestamps
+	now := time.Now()
+	lead.CreatedAt = now
+	lead.UpdatedAt = now
+
+	// UTM campaign doubles as the campaign used for CAC reporting
+	if lead.Campaign == "" {
+		lead.Campaign = lead.UTMCampaign
+	}
+
+	// Validate lead data
+	if err := utils.ValidateLead(lead); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	// Start database transaction
+	tx, err := db.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Check for duplicate lead: exact matches are rejected, partial ones need a human
+	matches, err := findLeadMatches(ctx, tx, lead)
+	if err != nil {
+		return fmt.Errorf("error checking duplicate: %w", err)
+	}
+	if len(matches) > 0 {
+		exact, reason, matchIDs := classifyLeadMatches(lead, matches)
+		if exact {
+			return ErrLeadExists
+		}
+		if opts.reviewID == 0 {
+			reviewID, err := queueLeadReview(ctx, tx, lead, opts.source, reason, matchIDs)
+			if err != nil {
+				return err
+			}
+			if err := tx.Commit(); err != nil {
+				return fmt.Errorf("failed to commit transaction: %w", err)
+			}
+			return &LeadReviewError{ReviewID: reviewID, Reason: reason}
+		}
+	}
+
+	// Assign counselor if not already assigned, on-shift counselors first
+	if lead.CounsellorID == nil {
+		shift, weekday := currentCounselorShift(now)
+		counselorID, err := utils.GetAvailableCounselorID(ctx, tx, lead.LeadSource, shift, weekday)
+		if err != nil {
+			return fmt.Errorf("error assigning counselor: %w", err)
+		}
+		// Nobody has capacity: park the lead on the house account
+		if counselorID == nil {
+			counselorID = GetHouseAccountID()
+		}
+		lead.CounsellorID = counselorID
+	}
+
+	// Insert lead into database
+	leadID, err := utils.InsertLead(ctx, tx, lead)
+	if err != nil {
+		return fmt.Errorf("error inserting lead: %w", err)
+	}
+	lead.ID = int(leadID)
+
+	// Update counselor assignment count atomically
+	if lead.CounsellorID != nil {
+		if err := utils.UpdateCounselorAssignmentCount(ctx, tx, *lead.CounsellorID); err != nil {
+			return fmt.Errorf("error updating counselor count: %w", err)
+		}
+	}
+
+	if err := EnqueueLeadCreatedEvent(ctx, tx, lead); err != nil {
+		return err
+	}
+
+	if opts.reviewID != 0 {
+		if err := resolveLeadReview(ctx, tx, opts.reviewID, models.LeadReviewApproved, lead.ID, opts.resolvedBy); err != nil {
+			return err
+		}
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Send welcome email asynchronously
+	if err := SendWelcomeEmailWithCounselorInfo(ctx, lead); err != nil {
+		// Don't fail the operation if email fails
+	}
+
+	return nil
+}
+
+// ParseDuplicatePolicy validates the duplicate policy requested for an import; empty means skip
+func ParseDuplicatePolicy(policy string) (string, error) {
+	switch normalized := strings.ToLower(strings.TrimSpace(policy)); normalized {
+	case "":
+		return models.DuplicatePolicySkip, nil
+	case models.DuplicatePolicySkip, models.DuplicatePolicyUpdate, models.DuplicatePolicyMerge:
+		return normalized, nil
+	default:
+		return "", fmt.Errorf("%w: %q (use skip, update or merge)", ErrInvalidDuplicatePolicy, policy)
+	}
+}
+
+// ImportLeadsFile runs the bulk import pipeline on an Excel file: parse, drop in-file
+// duplicates, create each lead (or apply record.DuplicatePolicy to rows matching an
+// existing lead) and record the run in import_history. record carries the file name,
+// hash, uploader and policy; its ID is set once the run is recorded.
+func ImportLeadsFile(ctx context.Context, filePath string, record *models.ImportHistory) (*models.ImportResult, error) {
+	policy, err := ParseDuplicatePolicy(record.DuplicatePolicy)
+	if err != nil {
+		return nil, err
+	}
+	record.DuplicatePolicy = policy
+
+	leads, err := ParseExcel(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+
+	// Remove duplicates within the uploaded file
+	leads = utils.DeduplicateLeads(leads)
+
+	result := &models.ImportResult{
+		DuplicatePolicy: policy,
+		TotalCount:      len(leads),
+		FailedLeads:     []models.ImportRowError{},
+		Rows:            make([]models.ImportRowResult, 0, len(leads)),
+	}
+	createdLeadIDs := []int64{}
+
+	for i, lead := range leads {
+		row := models.ImportRowResult{Row: i + 2, Email: lead.Email, Phone: lead.Phone}
+
+		err := createLead(ctx, &lead, createLeadOptions{source: "import:" + record.FileName})
+		var reviewErr *LeadReviewError
+		switch {
+		case err == nil:
+			row.Action = models.ImportRowCreated
+			row.LeadID = lead.ID
+			createdLeadIDs = append(createdLeadIDs, int64(lead.ID))
+		case errors.As(err, &reviewErr):
+			row.Action = models.ImportRowReview
+			row.ReviewID = reviewErr.ReviewID
+			row.Error = reviewErr.Reason
+		case errors.Is(err, ErrLeadExists) && policy == models.DuplicatePolicySkip:
+			row.Action = models.ImportRowSkipped
+			row.Error = err.Error()
+		case errors.Is(err, ErrLeadExists):
+			row.LeadID, row.UpdatedFields, err = applyDuplicatePolicy(ctx, &lead, policy)
+			switch {
+			case err != nil:
+				row.Action = models.ImportRowFailed
+				row.Error = err.Error()
+			case len(row.UpdatedFields) == 0:
+				row.Action = models.ImportRowSkipped
+				row.Error = "existing lead already up to date"
+			default:
+				row.Action = models.ImportRowUpdated
+			}
+		default:
+			row.Action = models.ImportRowFailed
+			row.Error = err.Error()
+		}
+
+		switch row.Action {
+		case models.ImportRowCreated:
+			result.SuccessCount++
+		case models.ImportRowUpdated:
+			result.UpdatedCount++
+		case models.ImportRowSkipped:
+			result.SkippedCount++
+		case models.ImportRowReview:
+			result.ReviewCount++
+			result.SkippedCount++
+		case models.ImportRowFailed:
+			result.FailedLeads = append(result.FailedLeads, models.ImportRowError{
+				Row:   row.Row,
+				Email: row.Email,
+				Phone: row.Phone,
+				Error: row.Error,
+			})
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	result.FailedCount = len(result.FailedLeads)
+
+	// Record the import run so later uploads of the same file can be detected
+	record.TotalCount = result.TotalCount
+	record.SuccessCount = result.SuccessCount
+	record.FailedCount = result.FailedCount
+	record.UpdatedCount = result.UpdatedCount
+	record.SkippedCount = result.SkippedCount
+	record.CreatedLeadIDs = createdLeadIDs
+	if err := RecordImport(ctx, record); err != nil {
+		logger.Warn("%v", err)
+	} else {
+		result.ImportID = record.ID
+	}
+
+	return result, nil
+}
+
+// applyDuplicatePolicy applies an imported row to the existing lead sharing its email or
+// phone: the update policy only fills fields that are empty on the lead, merge overwrites
+// them with the row's non-empty values. Email and phone identify the lead and lead source
+// drove its counselor assignment, so those are never changed. Returns the lead ID and the
+// columns updated.
+func applyDuplicatePolicy(ctx context.Context, lead *models.Lead, policy string) (int, []string, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id FROM student_lead
+		WHERE (email_index = $1 OR phone_index = $2) AND deleted_at IS NULL
+		ORDER BY id ASC
+		LIMIT 2
+		FOR UPDATE`, utils.PIIBlindIndex(lead.Email), utils.PIIBlindIndex(lead.Phone))
+	if err != nil {
+		return 0, nil, fmt.Errorf("error finding existing lead: %w", err)
+	}
+	var matches []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, nil, fmt.Errorf("error reading existing lead: %w", err)
+		}
+		matches = append(matches, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("error reading existing lead: %w", err)
+	}
+
+	switch len(matches) {
+	case 0:
+		return 0, nil, fmt.Errorf("existing lead no longer found")
+	case 2:
+		return 0, nil, fmt.Errorf("email and phone match different leads (#%d and #%d)", matches[0], matches[1])
+	}
+	existingID := matches[0]
+
+	updated, err := fillLeadFields(ctx, tx, existingID, lead, policy)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(updated) == 0 {
+		return existingID, nil, nil
+	}
+
+	if err := EnqueueLeadUpdatedEvent(ctx, tx, existingID, updated, "import:"+policy); err != nil {
+		return 0, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return existingID, updated, nil
+}
+
+// fillLeadFields copies the non-empty profile and attribution fields of lead onto the
+// existing lead leadID: the update policy only fills fields that are empty, merge overwrites
+// them. Returns the updated columns.
+func fillLeadFields(ctx context.Context, tx *sql.Tx, leadID int, lead *models.Lead, policy string) ([]string, error) {
+	var existing models.Lead
+	err := tx.QueryRowContext(ctx, `
+		SELECT name, COALESCE(education, ''), COALESCE(campaign, ''),
+		       COALESCE(utm_source, ''), COALESCE(utm_medium, ''), COALESCE(utm_campaign, '')
+		FROM student_lead WHERE id = $1`, leadID).Scan(&existing.Name, &existing.Education, &existing.Campaign,
+		&existing.UTMSource, &existing.UTMMedium, &existing.UTMCampaign)
+	if err != nil {
+		return nil, fmt.Errorf("error reading existing lead: %w", err)
+	}
+
+	fields := []struct {
+		column, current, value string
+	}{
+		{"name", existing.Name, lead.Name},
+		{"education", existing.Education, lead.Education},
+		{"campaign", existing.Campaign, lead.Campaign},
+		{"utm_source", existing.UTMSource, lead.UTMSource},
+		{"utm_medium", existing.UTMMedium, lead.UTMMedium},
+		{"utm_campaign", existing.UTMCampaign, lead.UTMCampaign},
+	}
+
+	var sets, updated []string
+	var args []interface{}
+	for _, f := range fields {
+		if f.value == "" || f.value == f.current {
+			continue
+		}
+		if policy == models.DuplicatePolicyUpdate && f.current != "" {
+			continue
+		}
+		args = append(args, f.value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", f.column, len(args)))
+		updated = append(updated, f.column)
+	}
+	if len(updated) == 0 {
+		return nil, nil
+	}
+
+	args = append(args, leadID)
+	query := fmt.Sprintf("UPDATE student_lead SET %s, updated_at = NOW() WHERE id = $%d", strings.Join(sets, ", "), len(args))
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return nil, fmt.Errorf("error updating existing lead: %w", err)
+	}
+	return updated, nil
+}
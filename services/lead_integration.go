@@ -0,0 +1,361 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrLeadIntegrationNotFound is returned when no lead integration has the requested id
+	ErrLeadIntegrationNotFound = errors.New("lead integration not found")
+	// ErrInvalidLeadIntegration is returned for a missing name or lead source, or a cadence under a minute
+	ErrInvalidLeadIntegration = errors.New("invalid lead integration")
+)
+
+// LeadIntegrationRequest is the editable part of a lead integration
+type LeadIntegrationRequest struct {
+	Name                   string `json:"name"`
+	LeadSource             string `json:"lead_source"`
+	UTMSource              string `json:"utm_source"` // optional, narrows the match
+	ExpectedCadenceMinutes int    `json:"expected_cadence_minutes"`
+	IsActive               *bool  `json:"is_active"` // defaults to true
+}
+
+func (req *LeadIntegrationRequest) normalize() error {
+	req.Name = strings.TrimSpace(req.Name)
+	req.LeadSource = strings.TrimSpace(req.LeadSource)
+	req.UTMSource = strings.TrimSpace(req.UTMSource)
+	if req.Name == "" || req.LeadSource == "" {
+		return fmt.Errorf("%w: name and lead_source are required", ErrInvalidLeadIntegration)
+	}
+	if req.ExpectedCadenceMinutes < 1 {
+		return fmt.Errorf("%w: expected_cadence_minutes must be at least 1", ErrInvalidLeadIntegration)
+	}
+	if req.IsActive == nil {
+		active := true
+		req.IsActive = &active
+	}
+	return nil
+}
+
+// leadIntegrationQuery selects integrations with the newest lead they delivered, counting
+// leads held in the review queue (the pipe worked even if the lead was a duplicate)
+const leadIntegrationQuery = `
+	SELECT i.id, i.name, i.lead_source, i.utm_source, i.expected_cadence_minutes, i.is_active, i.status,
+		GREATEST(
+			(SELECT MAX(s.created_at) FROM student_lead s
+			 WHERE LOWER(s.lead_source) = LOWER(i.lead_source)
+				AND (i.utm_source = '' OR LOWER(s.utm_source) = LOWER(i.utm_source))),
+			(SELECT MAX(r.created_at) FROM lead_review r
+			 WHERE LOWER(r.lead_data->>'lead_source') = LOWER(i.lead_source)
+				AND (i.utm_source = '' OR LOWER(r.lead_data->>'utm_source') = LOWER(i.utm_source)))
+		),
+		i.last_alert_at, i.checked_at, i.created_at, i.updated_at
+	FROM lead_integration i`
+
+func scanLeadIntegration(row interface{ Scan(...interface{}) error }) (*models.LeadIntegration, error) {
+	var i models.LeadIntegration
+	var lastLeadAt, lastAlertAt, checkedAt sql.NullTime
+	err := row.Scan(&i.ID, &i.Name, &i.LeadSource, &i.UTMSource, &i.ExpectedCadenceMinutes, &i.IsActive, &i.Status,
+		&lastLeadAt, &lastAlertAt, &checkedAt, &i.CreatedAt, &i.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if lastLeadAt.Valid {
+		i.LastLeadAt = &lastLeadAt.Time
+	}
+	if lastAlertAt.Valid {
+		i.LastAlertAt = &lastAlertAt.Time
+	}
+	if checkedAt.Valid {
+		i.CheckedAt = &checkedAt.Time
+	}
+	return &i, nil
+}
+
+// evaluateLeadIntegration sets the quiet time and health of an integration at now.
+// An integration that never delivered a lead is quiet since it was added.
+func evaluateLeadIntegration(i *models.LeadIntegration, now time.Time) {
+	quietSince := i.CreatedAt
+	if i.LastLeadAt != nil {
+		quietSince = *i.LastLeadAt
+	}
+	i.QuietMinutes = int(now.Sub(quietSince).Minutes())
+	if i.QuietMinutes < 0 {
+		i.QuietMinutes = 0
+	}
+	i.UnhealthySince = nil
+
+	switch {
+	case !i.IsActive:
+		i.Status = models.IntegrationStatusUnknown
+	case i.QuietMinutes > i.ExpectedCadenceMinutes:
+		i.Status = models.IntegrationStatusUnhealthy
+		since := quietSince.Add(time.Duration(i.ExpectedCadenceMinutes) * time.Minute)
+		i.UnhealthySince = &since
+	default:
+		i.Status = models.IntegrationStatusHealthy
+	}
+}
+
+// loadLeadIntegrations returns the integrations matching where (may be empty) with their
+// stored status; evaluateLeadIntegration brings it up to date
+func loadLeadIntegrations(ctx context.Context, where string, args ...interface{}) ([]models.LeadIntegration, error) {
+	rows, err := db.DB.QueryContext(ctx, leadIntegrationQuery+" "+where+" ORDER BY i.name, i.id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching lead integrations: %w", err)
+	}
+	defer rows.Close()
+
+	integrations := []models.LeadIntegration{}
+	for rows.Next() {
+		i, err := scanLeadIntegration(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning lead integration: %w", err)
+		}
+		integrations = append(integrations, *i)
+	}
+	return integrations, rows.Err()
+}
+
+// ListLeadIntegrations returns every lead integration with its health at now
+func ListLeadIntegrations(ctx context.Context, now time.Time) ([]models.LeadIntegration, error) {
+	integrations, err := loadLeadIntegrations(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	for idx := range integrations {
+		evaluateLeadIntegration(&integrations[idx], now)
+	}
+	return integrations, nil
+}
+
+// getLeadIntegration returns one integration with its health at now
+func getLeadIntegration(ctx context.Context, id int, now time.Time) (*models.LeadIntegration, error) {
+	integrations, err := loadLeadIntegrations(ctx, "WHERE i.id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(integrations) == 0 {
+		return nil, ErrLeadIntegrationNotFound
+	}
+	evaluateLeadIntegration(&integrations[0], now)
+	return &integrations[0], nil
+}
+
+// CreateLeadIntegration adds a lead integration to monitor
+func CreateLeadIntegration(ctx context.Context, req LeadIntegrationRequest) (*models.LeadIntegration, error) {
+	if err := req.normalize(); err != nil {
+		return nil, err
+	}
+
+	var id int
+	err := db.DB.QueryRowContext(ctx, `
+		INSERT INTO lead_integration (name, lead_source, utm_source, expected_cadence_minutes, is_active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`,
+		req.Name, req.LeadSource, req.UTMSource, req.ExpectedCadenceMinutes, *req.IsActive).Scan(&id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("%w: an integration named %s already exists", ErrInvalidLeadIntegration, req.Name)
+		}
+		return nil, fmt.Errorf("error creating lead integration: %w", err)
+	}
+	return getLeadIntegration(ctx, id, time.Now())
+}
+
+// UpdateLeadIntegration replaces the editable fields of a lead integration
+func UpdateLeadIntegration(ctx context.Context, id int, req LeadIntegrationRequest) (*models.LeadIntegration, error) {
+	if err := req.normalize(); err != nil {
+		return nil, err
+	}
+
+	result, err := db.DB.ExecContext(ctx, `
+		UPDATE lead_integration
+		SET name = $1, lead_source = $2, utm_source = $3, expected_cadence_minutes = $4, is_active = $5, updated_at = NOW()
+		WHERE id = $6`,
+		req.Name, req.LeadSource, req.UTMSource, req.ExpectedCadenceMinutes, *req.IsActive, id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("%w: an integration named %s already exists", ErrInvalidLeadIntegration, req.Name)
+		}
+		return nil, fmt.Errorf("error updating lead integration: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return nil, ErrLeadIntegrationNotFound
+	}
+	return getLeadIntegration(ctx, id, time.Now())
+}
+
+// CheckLeadIntegrations is the integration-health job: it records the health of every
+// active integration and alerts when one turns unhealthy, again every
+// INTEGRATION_ALERT_REPEAT_HOURS while it stays so, and once it recovers
+func CheckLeadIntegrations(ctx context.Context) error {
+	if db.DB == nil {
+		return nil
+	}
+
+	now := time.Now()
+	integrations, err := loadLeadIntegrations(ctx, "WHERE i.is_active")
+	if err != nil {
+		return err
+	}
+
+	repeat := time.Duration(config.AppConfig.IntegrationAlertRepeatHours) * time.Hour
+	var alerting, recovered []models.LeadIntegration
+	for _, i := range integrations {
+		stored := i.Status
+		evaluateLeadIntegration(&i, now)
+		switch {
+		case i.Status == models.IntegrationStatusUnhealthy && (i.LastAlertAt == nil || now.Sub(*i.LastAlertAt) >= repeat):
+			alerting = append(alerting, i)
+		case i.Status == models.IntegrationStatusHealthy && stored == models.IntegrationStatusUnhealthy:
+			logger.Info("Lead integration %s is delivering leads again", i.Name)
+			if i.LastAlertAt != nil {
+				recovered = append(recovered, i)
+			}
+		}
+
+		// A healthy integration starts its next unhealthy period without an alert on record
+		_, err := db.DB.ExecContext(ctx, `
+			UPDATE lead_integration
+			SET status = $1, checked_at = $2,
+				last_alert_at = CASE WHEN $1 = $3 THEN last_alert_at END
+			WHERE id = $4`,
+			i.Status, now, models.IntegrationStatusUnhealthy, i.ID)
+		if err != nil {
+			return fmt.Errorf("error recording status of integration %s: %w", i.Name, err)
+		}
+	}
+
+	if len(alerting) == 0 && len(recovered) == 0 {
+		return nil
+	}
+	for _, i := range alerting {
+		logger.Warn("Lead integration %s has delivered no lead for %d minutes (expected every %d)",
+			i.Name, i.QuietMinutes, i.ExpectedCadenceMinutes)
+	}
+
+	delivered, err := sendIntegrationAlert(ctx, alerting, recovered)
+	if delivered {
+		for _, i := range alerting {
+			if _, updateErr := db.DB.ExecContext(ctx,
+				"UPDATE lead_integration SET last_alert_at = $1 WHERE id = $2", now, i.ID); updateErr != nil {
+				err = errors.Join(err, fmt.Errorf("error recording alert of integration %s: %w", i.Name, updateErr))
+			}
+		}
+	}
+	return err
+}
+
+// sendIntegrationAlert reports unhealthy and recovered integrations to the integration
+// alert channels (the DLQ alert channels when unset). delivered is true when a channel
+// took the alert or none is configured, so the alert is not retried on every pass.
+func sendIntegrationAlert(ctx context.Context, alerting, recovered []models.LeadIntegration) (delivered bool, err error) {
+	email := config.AppConfig.IntegrationAlertEmail
+	if email == "" {
+		email = config.AppConfig.DLQAlertEmail
+	}
+	slackURL := config.AppConfig.IntegrationAlertSlackWebhookURL
+	if slackURL == "" {
+		slackURL = config.AppConfig.DLQAlertSlackWebhookURL
+	}
+	if email == "" && slackURL == "" {
+		return true, nil
+	}
+
+	var names []string
+	for _, i := range alerting {
+		names = append(names, i.Name)
+	}
+	subject := fmt.Sprintf("[Integrations] %d lead integration(s) quiet: %s", len(alerting), strings.Join(names, ", "))
+	if len(alerting) == 0 {
+		subject = fmt.Sprintf("[Integrations] %d lead integration(s) recovered", len(recovered))
+	}
+
+	var errs []error
+	if email != "" {
+		if err := DeliverEmail(email, subject, formatIntegrationAlert(alerting, recovered)); err != nil {
+			errs = append(errs, fmt.Errorf("error sending integration alert email: %w", err))
+		} else {
+			delivered = true
+		}
+	}
+	if slackURL != "" {
+		var text strings.Builder
+		for _, i := range alerting {
+			fmt.Fprintf(&text, ":rotating_light: Lead integration *%s* (%s) has delivered no lead for %s, expected every %s\n",
+				i.Name, integrationMatch(i), formatMinutes(i.QuietMinutes), formatMinutes(i.ExpectedCadenceMinutes))
+		}
+		for _, i := range recovered {
+			fmt.Fprintf(&text, ":white_check_mark: Lead integration *%s* is delivering leads again\n", i.Name)
+		}
+		if err := postSlackMessage(ctx, slackURL, strings.TrimSpace(text.String())); err != nil {
+			errs = append(errs, fmt.Errorf("error sending integration Slack alert: %w", err))
+		} else {
+			delivered = true
+		}
+	}
+	return delivered, errors.Join(errs...)
+}
+
+// formatIntegrationAlert renders the integration alert email
+func formatIntegrationAlert(alerting, recovered []models.LeadIntegration) string {
+	var sb strings.Builder
+	if len(alerting) > 0 {
+		sb.WriteString("<p>These lead integrations have stopped delivering leads:</p><ul>")
+		for _, i := range alerting {
+			last := "never"
+			if i.LastLeadAt != nil {
+				last = i.LastLeadAt.Format(time.RFC1123)
+			}
+			fmt.Fprintf(&sb, "<li><strong>%s</strong> (%s): no lead for %s, expected every %s. Last lead: %s.</li>",
+				html.EscapeString(i.Name), html.EscapeString(integrationMatch(i)),
+				formatMinutes(i.QuietMinutes), formatMinutes(i.ExpectedCadenceMinutes), last)
+		}
+		sb.WriteString("</ul><p>Check the connector (Zapier, ad platform) before more leads are lost. Current status: <code>GET /integrations</code>.</p>")
+	}
+	if len(recovered) > 0 {
+		sb.WriteString("<p>Delivering leads again:</p><ul>")
+		for _, i := range recovered {
+			fmt.Fprintf(&sb, "<li><strong>%s</strong></li>", html.EscapeString(i.Name))
+		}
+		sb.WriteString("</ul>")
+	}
+	return sb.String()
+}
+
+// integrationMatch describes the leads an integration is recognised by
+func integrationMatch(i models.LeadIntegration) string {
+	if i.UTMSource != "" {
+		return fmt.Sprintf("lead_source %s, utm_source %s", i.LeadSource, i.UTMSource)
+	}
+	return "lead_source " + i.LeadSource
+}
+
+// formatMinutes renders a duration in minutes as "45m", "6h" or "2d 3h"
+func formatMinutes(minutes int) string {
+	switch {
+	case minutes < 60:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes < 24*60:
+		if minutes%60 == 0 {
+			return fmt.Sprintf("%dh", minutes/60)
+		}
+		return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+	default:
+		if hours := minutes / 60 % 24; hours != 0 {
+			return fmt.Sprintf("%dd %dh", minutes/(24*60), hours)
+		}
+		return fmt.Sprintf("%dd", minutes/(24*60))
+	}
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrLeadNotFound is returned when a lead does not exist or has been deleted
+var ErrLeadNotFound = errors.New("lead not found")
+
+// CreateLeadNote records a counselor interaction against a lead.
+// @mentions in the content are resolved to counselors, who receive an in-app
+// notification and, when notifyByEmail is set, an email.
+func CreateLeadNote(ctx context.Context, note *models.LeadNote, notifyByEmail bool) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var leadName string
+	var assignedCounselorID sql.NullInt64
+	err = tx.QueryRowContext(ctx,
+		"SELECT name, counselor_id FROM student_lead WHERE id = $1 AND deleted_at IS NULL", note.LeadID).
+		Scan(&leadName, &assignedCounselorID)
+	if err == sql.ErrNoRows {
+		return ErrLeadNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error checking lead: %w", err)
+	}
+
+	if note.NoteType == "" {
+		note.NoteType = models.NoteTypeNote
+	}
+
+	if note.CounselorID != nil {
+		err = tx.QueryRowContext(ctx, "SELECT name FROM counselor WHERE id = $1", *note.CounselorID).Scan(&note.CounselorName)
+		if err == sql.ErrNoRows {
+			return ErrCounselorNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("error checking counselor: %w", err)
+		}
+	}
+
+	mentioned, err := resolveMentions(ctx, tx, note, assignedCounselorID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO lead_note (lead_id, counselor_id, note_type, content, follow_up_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		note.LeadID, note.CounselorID, note.NoteType, note.Content, note.FollowUpAt,
+	).Scan(&note.ID, &note.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error saving lead note: %w", err)
+	}
+
+	note.Mentions = []models.LeadNoteMention{}
+	for _, counselor := range mentioned {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO lead_note_mention (note_id, counselor_id) VALUES ($1, $2)", note.ID, counselor.ID); err != nil {
+			return fmt.Errorf("error saving mention: %w", err)
+		}
+
+		notification := &models.UserNotification{
+			CounselorID: counselor.ID,
+			Type:        models.NotificationTypeMention,
+			Title:       fmt.Sprintf("%s mentioned you on lead %s", note.CounselorName, leadName),
+			Message:     note.Content,
+			LeadID:      &note.LeadID,
+			NoteID:      &note.ID,
+		}
+		if err := createUserNotification(ctx, tx, notification); err != nil {
+			return err
+		}
+
+		note.Mentions = append(note.Mentions, models.LeadNoteMention{CounselorID: counselor.ID, Name: counselor.Name})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	if notifyByEmail {
+		for _, counselor := range mentioned {
+			go SendMentionNotificationEmail(counselor.Name, counselor.Email, note.CounselorName, leadName, note.Content)
+		}
+	}
+
+	return nil
+}
+
+// GetLeadNotes returns the notes for a lead, newest first
+func GetLeadNotes(ctx context.Context, leadID int) ([]models.LeadNote, error) {
+	var exists bool
+	err := db.DB.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM student_lead WHERE id = $1 AND deleted_at IS NULL)", leadID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("error checking lead: %w", err)
+	}
+	if !exists {
+		return nil, ErrLeadNotFound
+	}
+
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT n.id, n.lead_id, n.counselor_id, COALESCE(c.name, ''), n.note_type, n.content, n.follow_up_at, n.created_at
+		FROM lead_note n
+		LEFT JOIN counselor c ON c.id = n.counselor_id
+		WHERE n.lead_id = $1
+		ORDER BY n.created_at DESC, n.id DESC`, leadID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching lead notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := []models.LeadNote{}
+	for rows.Next() {
+		var note models.LeadNote
+		var counselorID sql.NullInt64
+		var followUpAt sql.NullTime
+		if err := rows.Scan(&note.ID, &note.LeadID, &counselorID, &note.CounselorName,
+			&note.NoteType, &note.Content, &followUpAt, &note.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error reading lead note: %w", err)
+		}
+		if counselorID.Valid {
+			note.CounselorID = &counselorID.Int64
+		}
+		if followUpAt.Valid {
+			note.FollowUpAt = &followUpAt.Time
+		}
+		notes = append(notes, note)
+	}
+
+	return notes, rows.Err()
+}
+
+// GetLastNoteSummaries returns the most recent note for each of the given leads, keyed by lead ID
+func GetLastNoteSummaries(ctx context.Context, leadIDs []int64) (map[int]models.LeadNoteSummary, error) {
+	summaries := map[int]models.LeadNoteSummary{}
+	if len(leadIDs) == 0 {
+		return summaries, nil
+	}
+
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT DISTINCT ON (n.lead_id) n.lead_id, n.note_type, COALESCE(c.name, ''), n.follow_up_at, n.created_at
+		FROM lead_note n
+		LEFT JOIN counselor c ON c.id = n.counselor_id
+		WHERE n.lead_id = ANY($1)
+		ORDER BY n.lead_id, n.created_at DESC, n.id DESC`, pq.Int64Array(leadIDs))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching last notes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var leadID int
+		var summary models.LeadNoteSummary
+		var followUpAt sql.NullTime
+		var createdAt time.Time
+		if err := rows.Scan(&leadID, &summary.NoteType, &summary.CounselorName, &followUpAt, &createdAt); err != nil {
+			return nil, fmt.Errorf("error reading last note: %w", err)
+		}
+		if followUpAt.Valid {
+			formatted := followUpAt.Time.Format(time.RFC3339)
+			summary.FollowUpAt = &formatted
+		}
+		summary.CreatedAt = createdAt.Format(time.RFC3339)
+		summaries[leadID] = summary
+	}
+
+	return summaries, rows.Err()
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/utils"
+	"context"
+	"fmt"
+)
+
+// leadContactBatchSize is how many leads EncryptLeadContacts rewrites per query
+const leadContactBatchSize = 500
+
+// EncryptLeadContacts brings stored lead emails and phone numbers in line with the PII
+// settings (utils.InitPII): it fills missing blind indexes and, with encryption enabled,
+// encrypts the values still stored in plaintext. Anonymized leads keep their placeholder
+// contact details in plaintext and no index, so lookups never match them. Run at startup,
+// before requests are served; returns the number of leads rewritten.
+func EncryptLeadContacts(ctx context.Context) (int, error) {
+	if !utils.PIIEncryptionEnabled() {
+		var encrypted bool
+		err := db.DB.QueryRowContext(ctx,
+			"SELECT EXISTS(SELECT 1 FROM student_lead WHERE email LIKE $1)", utils.EncryptedPIIPrefix+"%").Scan(&encrypted)
+		if err != nil {
+			return 0, fmt.Errorf("error checking for encrypted leads: %w", err)
+		}
+		if encrypted {
+			return 0, fmt.Errorf("leads are stored encrypted: %w", utils.ErrPIIKeyMissing)
+		}
+	}
+
+	rewritten, lastID := 0, 0
+	for {
+		n, last, err := encryptLeadContactBatch(ctx, lastID)
+		if err != nil {
+			return rewritten, err
+		}
+		rewritten += n
+		if n < leadContactBatchSize {
+			return rewritten, nil
+		}
+		lastID = last
+	}
+}
+
+func encryptLeadContactBatch(ctx context.Context, afterID int) (int, int, error) {
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT id, email, phone FROM student_lead
+		WHERE id > $1 AND anonymized_at IS NULL
+			AND (email_index IS NULL OR phone_index IS NULL
+				OR ($2 AND (email NOT LIKE $3 OR (phone <> '' AND phone NOT LIKE $3))))
+		ORDER BY id
+		LIMIT $4`, afterID, utils.PIIEncryptionEnabled(), utils.EncryptedPIIPrefix+"%", leadContactBatchSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error fetching leads to encrypt: %w", err)
+	}
+	type contact struct {
+		id           int
+		email, phone string
+	}
+	var contacts []contact
+	for rows.Next() {
+		var c contact
+		if err := rows.Scan(&c.id, utils.DecryptedPII(&c.email), utils.DecryptedPII(&c.phone)); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("error reading lead to encrypt: %w", err)
+		}
+		contacts = append(contacts, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("error reading leads to encrypt: %w", err)
+	}
+
+	for _, c := range contacts {
+		_, err := db.DB.ExecContext(ctx, `
+			UPDATE student_lead SET email = $2, phone = $3, email_index = $4, phone_index = $5
+			WHERE id = $1`,
+			c.id, utils.EncryptedPII(c.email), utils.EncryptedPII(c.phone),
+			utils.PIIBlindIndex(c.email), utils.PIIBlindIndex(c.phone))
+		if err != nil {
+			return 0, 0, fmt.Errorf("error encrypting contact details of lead %d: %w", c.id, err)
+		}
+	}
+	if len(contacts) == 0 {
+		return 0, afterID, nil
+	}
+	return len(contacts), contacts[len(contacts)-1].id, nil
+}
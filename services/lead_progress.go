@@ -0,0 +1,196 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/models"
+	"admission-module/utils"
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// progressSnapshot is the lead state the progress steps are derived from
+type progressSnapshot struct {
+	name, email, phone, education string
+	applicationStatus             string
+	interviewAt                   sql.NullTime
+	statusChangedAt               sql.NullTime
+	courseFeeDeadline             sql.NullTime
+	createdAt                     time.Time
+	registrationStatus            sql.NullString
+	registrationPaidAt            sql.NullTime
+	courseName                    sql.NullString
+	courseFeeStatus               sql.NullString
+	courseFeePaidAt               sql.NullTime
+}
+
+// GetLeadProgress returns the lead's application steps (profile, registration fee,
+// interview, decision, course fee, enrollment) with timestamps and the next action
+func GetLeadProgress(ctx context.Context, leadID int) (*models.LeadProgress, error) {
+	var s progressSnapshot
+	err := db.DB.QueryRowContext(ctx, `
+		SELECT sl.name, sl.email, sl.phone, COALESCE(sl.education, ''),
+		       COALESCE(sl.application_status, 'NEW'), sl.interview_scheduled_at, sl.status_changed_at, sl.course_fee_deadline, sl.created_at,
+		       rp.status, rp.updated_at,
+		       c.name, cp.status, cp.updated_at
+		FROM student_lead sl
+		LEFT JOIN registration_payment rp ON rp.student_id = sl.id
+		LEFT JOIN course c ON c.id = sl.selected_course_id
+		LEFT JOIN course_payment cp ON cp.student_id = sl.id AND cp.course_id = sl.selected_course_id
+		WHERE sl.id = $1 AND sl.deleted_at IS NULL`, leadID).Scan(
+		&s.name, utils.DecryptedPII(&s.email), utils.DecryptedPII(&s.phone), &s.education,
+		&s.applicationStatus, &s.interviewAt, &s.statusChangedAt, &s.courseFeeDeadline, &s.createdAt,
+		&s.registrationStatus, &s.registrationPaidAt,
+		&s.courseName, &s.courseFeeStatus, &s.courseFeePaidAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrLeadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching lead progress: %w", err)
+	}
+
+	return buildLeadProgress(leadID, s, time.Now()), nil
+}
+
+// buildLeadProgress marks every step up to the first unfinished one as completed,
+// that step as current and the rest as pending (or not applicable after a rejection)
+func buildLeadProgress(leadID int, s progressSnapshot, now time.Time) *models.LeadProgress {
+	accepted := s.applicationStatus == ApplicationStatusAccepted
+	rejected := s.applicationStatus == ApplicationStatusRejected
+	expired := s.applicationStatus == ApplicationStatusOfferExpired
+	decided := accepted || rejected || expired
+
+	var missing []string
+	for field, value := range map[string]string{"name": s.name, "email": s.email, "phone": s.phone, "education": s.education} {
+		if strings.TrimSpace(value) == "" {
+			missing = append(missing, field)
+		}
+	}
+
+	registrationPaid := s.registrationStatus.String == PaymentStatusPaid
+	interviewDone := decided || (s.interviewAt.Valid && s.interviewAt.Time.Before(now))
+	courseFeePaid := accepted && s.courseFeeStatus.String == PaymentStatusPaid
+
+	type stepState struct {
+		step     models.ProgressStep
+		done     bool
+		doneAt   sql.NullTime
+		nextHint string
+	}
+	states := []stepState{
+		{
+			step:   models.ProgressStep{Key: models.ProgressStepProfile, Label: "Profile complete"},
+			done:   len(missing) == 0,
+			doneAt: sql.NullTime{Time: s.createdAt, Valid: true},
+		},
+		{
+			step:     models.ProgressStep{Key: models.ProgressStepRegistration, Label: "Registration fee paid"},
+			done:     registrationPaid,
+			doneAt:   s.registrationPaidAt,
+			nextHint: "Pay the registration fee",
+		},
+		{
+			step:     models.ProgressStep{Key: models.ProgressStepInterview, Label: "Interview done"},
+			done:     interviewDone,
+			doneAt:   s.interviewAt,
+			nextHint: "Schedule the interview with the counselor",
+		},
+		{
+			step:     models.ProgressStep{Key: models.ProgressStepDecision, Label: "Admission decision"},
+			done:     decided,
+			doneAt:   s.statusChangedAt,
+			nextHint: "Awaiting the admission decision",
+		},
+		{
+			step:     models.ProgressStep{Key: models.ProgressStepCourseFee, Label: "Course fee paid"},
+			done:     courseFeePaid,
+			doneAt:   s.courseFeePaidAt,
+			nextHint: "Pay the course fee",
+		},
+		{
+			step:   models.ProgressStep{Key: models.ProgressStepEnrolled, Label: "Enrolled"},
+			done:   courseFeePaid,
+			doneAt: s.courseFeePaidAt,
+		},
+	}
+
+	// Refine hints and details with what is known about each step
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		states[0].nextHint = "Complete the profile: missing " + strings.Join(missing, ", ")
+	}
+	if s.registrationStatus.String == PaymentStatusFailed {
+		states[1].nextHint = "The registration payment failed; retry it from the payment link"
+	}
+	if s.interviewAt.Valid {
+		states[2].step.Detail = "Scheduled for " + s.interviewAt.Time.Format(time.RFC3339)
+		if !interviewDone {
+			states[2].nextHint = "Attend the interview scheduled for " + s.interviewAt.Time.Format(time.RFC3339)
+		}
+	}
+	if decided {
+		states[3].step.Detail = s.applicationStatus
+	}
+	if s.applicationStatus == ApplicationStatusWaitlisted {
+		states[3].step.Detail = ApplicationStatusWaitlisted
+		states[3].nextHint = "On the waitlist: a seat will be offered when one is released"
+	}
+	if s.applicationStatus == ApplicationStatusPendingApproval {
+		states[3].step.Detail = ApplicationStatusPendingApproval
+		states[3].nextHint = "The acceptance is awaiting a second approval"
+	}
+	if s.courseName.Valid {
+		states[4].step.Detail = s.courseName.String
+		states[4].nextHint = "Pay the course fee for " + s.courseName.String
+		if s.courseFeeDeadline.Valid {
+			states[4].nextHint += " by " + s.courseFeeDeadline.Time.Format(time.RFC3339)
+		}
+	}
+	if expired {
+		states[4].nextHint = "The offer expired because the course fee was not paid in time; contact the counselor"
+	}
+
+	progress := &models.LeadProgress{
+		LeadID:            leadID,
+		ApplicationStatus: s.applicationStatus,
+		TotalSteps:        len(states),
+		Steps:             make([]models.ProgressStep, 0, len(states)),
+	}
+
+	blocked := false
+	for _, state := range states {
+		step := state.step
+		switch {
+		case blocked:
+			step.Status = models.StepStatusPending
+			if rejected {
+				step.Status = models.StepStatusNotApplicable
+			}
+		case state.done:
+			step.Status = models.StepStatusCompleted
+			if state.doneAt.Valid {
+				doneAt := state.doneAt.Time
+				step.CompletedAt = &doneAt
+			}
+			progress.CompletedSteps++
+			if rejected && state.step.Key == models.ProgressStepDecision {
+				blocked = true
+				progress.NextAction = "No further action: the application was rejected"
+			}
+		default:
+			step.Status = models.StepStatusCurrent
+			progress.CurrentStep = step.Key
+			progress.NextAction = state.nextHint
+			blocked = true
+		}
+		progress.Steps = append(progress.Steps, step)
+	}
+
+	if progress.CompletedSteps == progress.TotalSteps {
+		progress.NextAction = "No further action: the student is enrolled"
+	}
+	return progress
+}
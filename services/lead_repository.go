@@ -0,0 +1,129 @@
+package services
+
+import (
+	"admission-module/models"
+	"admission-module/utils"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// LeadRepository reads student leads
+type LeadRepository interface {
+	// Exists reports whether a lead with the ID exists and is not soft-deleted
+	Exists(ctx context.Context, id int) (bool, error)
+	// FindContact returns the name and email of a lead that is not deleted, or ErrLeadNotFound
+	FindContact(ctx context.Context, id int) (name, email string, err error)
+	// ApplicationStatus returns the lead's application status ("" when unset), or ErrLeadNotFound
+	ApplicationStatus(ctx context.Context, id int) (string, error)
+	// List returns the leads that are not deleted and match filter, by ID, each with its
+	// assigned counselor
+	List(ctx context.Context, filter LeadListFilter) ([]models.Lead, error)
+}
+
+// LeadListFilter narrows LeadRepository.List; zero values do not filter
+type LeadListFilter struct {
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// Unassigned keeps the leads on the house account or without a counselor
+	Unassigned bool
+}
+
+// postgresLeadRepository is the LeadRepository over the student_lead table
+type postgresLeadRepository struct {
+	q querier
+}
+
+// NewLeadRepository creates a lead repository over a database handle or transaction
+func NewLeadRepository(q querier) LeadRepository {
+	return &postgresLeadRepository{q: q}
+}
+
+func (r *postgresLeadRepository) Exists(ctx context.Context, id int) (bool, error) {
+	var exists bool
+	err := r.q.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM student_lead WHERE id = $1 AND deleted_at IS NULL)", id).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error checking lead %d: %w", id, err)
+	}
+	return exists, nil
+}
+
+func (r *postgresLeadRepository) FindContact(ctx context.Context, id int) (string, string, error) {
+	var name, email string
+	err := r.q.QueryRowContext(ctx, "SELECT name, email FROM student_lead WHERE id = $1 AND deleted_at IS NULL", id).Scan(&name, utils.DecryptedPII(&email))
+	if err == sql.ErrNoRows {
+		return "", "", ErrLeadNotFound
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("error fetching lead %d: %w", id, err)
+	}
+	return name, email, nil
+}
+
+func (r *postgresLeadRepository) ApplicationStatus(ctx context.Context, id int) (string, error) {
+	var status string
+	err := r.q.QueryRowContext(ctx,
+		"SELECT COALESCE(application_status, '') FROM student_lead WHERE id = $1 AND deleted_at IS NULL", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", ErrLeadNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("error fetching application status of lead %d: %w", id, err)
+	}
+	return status, nil
+}
+
+func (r *postgresLeadRepository) List(ctx context.Context, filter LeadListFilter) ([]models.Lead, error) {
+	// The assigned counselor is joined in rather than looked up per lead
+	query := `
+		SELECT
+			l.id, l.name, l.email, l.phone, l.education, l.lead_source,
+			l.counselor_id, l.meet_link,
+			l.application_status, l.registration_payment_id, l.selected_course_id,
+			l.course_payment_id, l.interview_scheduled_at, l.created_at, l.updated_at,
+			c.name, c.email, c.phone
+		FROM student_lead l
+		LEFT JOIN counselor c ON c.id = l.counselor_id
+		WHERE l.deleted_at IS NULL`
+	args := []interface{}{}
+
+	// Unassigned queue: leads on the house account (or without any counselor)
+	if filter.Unassigned {
+		query += " AND (l.counselor_id IS NULL OR c.is_house_account)"
+	}
+	if filter.CreatedAfter != nil {
+		args = append(args, *filter.CreatedAfter)
+		query += fmt.Sprintf(" AND l.created_at >= $%d", len(args))
+	}
+	if filter.CreatedBefore != nil {
+		args = append(args, *filter.CreatedBefore)
+		query += fmt.Sprintf(" AND l.created_at <= $%d", len(args))
+	}
+	query += " ORDER BY l.id ASC"
+
+	rows, err := r.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching leads: %w", err)
+	}
+	defer rows.Close()
+
+	leads := []models.Lead{}
+	for rows.Next() {
+		var counselorName, counselorEmail, counselorPhone sql.NullString
+		lead, err := utils.ScanLead(rows, &counselorName, &counselorEmail, &counselorPhone)
+		if err != nil {
+			return nil, fmt.Errorf("error reading leads: %w", err)
+		}
+		if lead.CounsellorID != nil && counselorName.Valid {
+			lead.Counselor = &models.LeadCounselor{
+				ID:    *lead.CounsellorID,
+				Name:  counselorName.String,
+				Email: counselorEmail.String,
+				Phone: counselorPhone.String,
+			}
+		}
+		leads = append(leads, lead)
+	}
+	return leads, rows.Err()
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// fakeLead is a student_lead row of the fake database
+type fakeLead struct {
+	name, email, status string
+	deleted             bool
+}
+
+// fakeLeadDriver serves the single-lead queries of postgresLeadRepository from memory.
+// Like PostgreSQL, it only hides soft-deleted rows when the query filters on deleted_at.
+type fakeLeadDriver struct {
+	leads map[int64]fakeLead
+}
+
+func (d *fakeLeadDriver) Open(string) (driver.Conn, error) { return &fakeLeadConn{leads: d.leads}, nil }
+
+type fakeLeadConn struct {
+	leads map[int64]fakeLead
+}
+
+func (c *fakeLeadConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+func (c *fakeLeadConn) Close() error { return nil }
+func (c *fakeLeadConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+func (c *fakeLeadConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	lead, found := c.leads[args[0].Value.(int64)]
+	if found && lead.deleted && strings.Contains(query, "deleted_at IS NULL") {
+		found = false
+	}
+
+	switch {
+	case strings.Contains(query, "SELECT EXISTS"):
+		return &fakeLeadRows{columns: []string{"exists"}, values: [][]driver.Value{{found}}}, nil
+	case strings.Contains(query, "SELECT name, email"):
+		rows := &fakeLeadRows{columns: []string{"name", "email"}}
+		if found {
+			rows.values = [][]driver.Value{{lead.name, lead.email}}
+		}
+		return rows, nil
+	case strings.Contains(query, "application_status"):
+		rows := &fakeLeadRows{columns: []string{"application_status"}}
+		if found {
+			rows.values = [][]driver.Value{{lead.status}}
+		}
+		return rows, nil
+	}
+	return nil, errors.New("unexpected query: " + query)
+}
+
+type fakeLeadRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeLeadRows) Columns() []string { return r.columns }
+func (r *fakeLeadRows) Close() error      { return nil }
+func (r *fakeLeadRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func newFakeLeadDB(t *testing.T, leads map[int64]fakeLead) *sql.DB {
+	t.Helper()
+	driverName := "fakelead-" + t.Name()
+	sql.Register(driverName, &fakeLeadDriver{leads: leads})
+	conn, err := sql.Open(driverName, "")
+	if err != nil {
+		t.Fatalf("opening fake database: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestLeadRepositoryRejectsRolledBackLeads(t *testing.T) {
+	const active, rolledBack = 1, 2
+	repo := NewLeadRepository(newFakeLeadDB(t, map[int64]fakeLead{
+		active:     {name: "Asha", email: "asha@example.com", status: ApplicationStatusAccepted},
+		rolledBack: {name: "Imported", email: "imported@example.com", status: ApplicationStatusAccepted, deleted: true},
+	}))
+	ctx := context.Background()
+
+	tests := []struct {
+		id         int
+		wantExists bool
+		wantErr    error
+	}{
+		{id: active, wantExists: true},
+		{id: rolledBack, wantErr: ErrLeadNotFound},
+		{id: 3, wantErr: ErrLeadNotFound},
+	}
+	for _, tt := range tests {
+		exists, err := repo.Exists(ctx, tt.id)
+		if err != nil || exists != tt.wantExists {
+			t.Errorf("Exists(%d) = %v, %v; want %v", tt.id, exists, err, tt.wantExists)
+		}
+		if _, _, err := repo.FindContact(ctx, tt.id); !errors.Is(err, tt.wantErr) {
+			t.Errorf("FindContact(%d) error = %v, want %v", tt.id, err, tt.wantErr)
+		}
+		if _, err := repo.ApplicationStatus(ctx, tt.id); !errors.Is(err, tt.wantErr) {
+			t.Errorf("ApplicationStatus(%d) error = %v, want %v", tt.id, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/models"
+	"admission-module/utils"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Review sources of leads submitted outside an import
+const LeadReviewSourceAPI = "api"
+
+var (
+	// ErrLeadNeedsReview is matched (errors.Is) by a *LeadReviewError
+	ErrLeadNeedsReview = errors.New("lead partially matches an existing lead and was queued for review")
+	// ErrLeadReviewNotFound is returned when a review does not exist or is no longer pending
+	ErrLeadReviewNotFound = errors.New("lead review not found or already resolved")
+	// ErrLeadReviewTargetRequired is returned when merging a review with several matches without a lead_id
+	ErrLeadReviewTargetRequired = errors.New("lead_id is required: the submission matches several leads")
+)
+
+// LeadReviewError is returned by CreateLead when the lead was queued for manual review
+type LeadReviewError struct {
+	ReviewID int
+	Reason   string
+}
+
+func (e *LeadReviewError) Error() string {
+	return fmt.Sprintf("%s (review #%d: %s)", ErrLeadNeedsReview, e.ReviewID, e.Reason)
+}
+
+// Is lets errors.Is(err, ErrLeadNeedsReview) match
+func (e *LeadReviewError) Is(target error) bool {
+	return target == ErrLeadNeedsReview
+}
+
+// leadMatch is an active lead sharing the email or phone of a submitted lead
+type leadMatch struct {
+	id           int
+	email, phone string
+}
+
+// findLeadMatches returns the active leads sharing the email or phone of lead, found by
+// their blind indexes
+func findLeadMatches(ctx context.Context, tx *sql.Tx, lead *models.Lead) ([]leadMatch, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, email, phone FROM student_lead
+		WHERE (email_index = $1 OR phone_index = $2) AND deleted_at IS NULL
+		ORDER BY id`, utils.PIIBlindIndex(lead.Email), utils.PIIBlindIndex(lead.Phone))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []leadMatch
+	for rows.Next() {
+		var m leadMatch
+		if err := rows.Scan(&m.id, utils.DecryptedPII(&m.email), utils.DecryptedPII(&m.phone)); err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// classifyLeadMatches reports whether one match has both the email and phone of lead (an
+// exact duplicate) and otherwise describes the partial match for the review queue
+func classifyLeadMatches(lead *models.Lead, matches []leadMatch) (exact bool, reason string, ids []int64) {
+	var reasons []string
+	for _, m := range matches {
+		if m.email == lead.Email && m.phone == lead.Phone {
+			return true, "", nil
+		}
+		ids = append(ids, int64(m.id))
+		if m.email == lead.Email {
+			reasons = append(reasons, fmt.Sprintf("email matches lead #%d with a different phone", m.id))
+		} else {
+			reasons = append(reasons, fmt.Sprintf("phone matches lead #%d with a different email", m.id))
+		}
+	}
+	return false, strings.Join(reasons, "; "), ids
+}
+
+// queueLeadReview holds a partially matching lead for review. A pending review for the same
+// email and phone is refreshed rather than duplicated.
+func queueLeadReview(ctx context.Context, tx *sql.Tx, lead *models.Lead, source, reason string, matchIDs []int64) (int, error) {
+	data, err := json.Marshal(lead)
+	if err != nil {
+		return 0, fmt.Errorf("error encoding lead for review: %w", err)
+	}
+
+	var id int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO lead_review (source, email, phone, lead_data, reason, matched_lead_ids)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (email, phone) WHERE status = 'PENDING' DO UPDATE
+		SET source = EXCLUDED.source,
+			lead_data = EXCLUDED.lead_data,
+			reason = EXCLUDED.reason,
+			matched_lead_ids = EXCLUDED.matched_lead_ids,
+			updated_at = NOW()
+		RETURNING id`,
+		source, lead.Email, lead.Phone, data, reason, pq.Array(matchIDs)).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("error queueing lead for review: %w", err)
+	}
+	return id, nil
+}
+
+const leadReviewColumns = `id, status, source, lead_data, reason, matched_lead_ids, resolved_lead_id,
+	COALESCE(resolved_by, ''), resolved_at, created_at, updated_at`
+
+func scanLeadReview(row interface{ Scan(...interface{}) error }) (*models.LeadReview, []int64, error) {
+	var r models.LeadReview
+	var data []byte
+	var matchIDs []int64
+	var resolvedLeadID sql.NullInt64
+	var resolvedAt sql.NullTime
+	err := row.Scan(&r.ID, &r.Status, &r.Source, &data, &r.Reason, pq.Array(&matchIDs), &resolvedLeadID,
+		&r.ResolvedBy, &resolvedAt, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(data, &r.Lead); err != nil {
+		return nil, nil, fmt.Errorf("error decoding reviewed lead %d: %w", r.ID, err)
+	}
+	if resolvedLeadID.Valid {
+		id := int(resolvedLeadID.Int64)
+		r.ResolvedLeadID = &id
+	}
+	if resolvedAt.Valid {
+		r.ResolvedAt = &resolvedAt.Time
+	}
+	return &r, matchIDs, nil
+}
+
+// GetLeadReviews lists lead reviews, oldest first, with the current details of the matched
+// leads. An empty status lists all reviews.
+func GetLeadReviews(ctx context.Context, status string, limit int) ([]models.LeadReview, error) {
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT `+leadReviewColumns+` FROM lead_review
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY created_at ASC, id ASC
+		LIMIT $2`, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching lead reviews: %w", err)
+	}
+
+	reviews := []models.LeadReview{}
+	var matchIDs [][]int64
+	var allIDs []int64
+	for rows.Next() {
+		r, ids, err := scanLeadReview(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error reading lead review: %w", err)
+		}
+		reviews = append(reviews, *r)
+		matchIDs = append(matchIDs, ids)
+		allIDs = append(allIDs, ids...)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading lead reviews: %w", err)
+	}
+
+	matched, err := loadReviewMatches(ctx, allIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range reviews {
+		reviews[i].Matches = []models.LeadReviewMatch{}
+		for _, id := range matchIDs[i] {
+			m, ok := matched[int(id)]
+			if !ok {
+				continue
+			}
+			if m.Email == reviews[i].Lead.Email {
+				m.MatchedOn = "email"
+			} else {
+				m.MatchedOn = "phone"
+			}
+			reviews[i].Matches = append(reviews[i].Matches, m)
+		}
+	}
+	return reviews, nil
+}
+
+// loadReviewMatches loads the matched leads by ID, including leads deleted since
+func loadReviewMatches(ctx context.Context, ids []int64) (map[int]models.LeadReviewMatch, error) {
+	matches := map[int]models.LeadReviewMatch{}
+	if len(ids) == 0 {
+		return matches, nil
+	}
+
+	rows, err := db.DB.QueryContext(ctx,
+		"SELECT id, name, email, phone, deleted_at IS NOT NULL FROM student_lead WHERE id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching matched leads: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.LeadReviewMatch
+		if err := rows.Scan(&m.LeadID, &m.Name, utils.DecryptedPII(&m.Email), utils.DecryptedPII(&m.Phone), &m.Deleted); err != nil {
+			return nil, fmt.Errorf("error reading matched lead: %w", err)
+		}
+		matches[m.LeadID] = m
+	}
+	return matches, rows.Err()
+}
+
+// ApproveLeadReview creates the reviewed submission as a new lead. An exact duplicate
+// created in the meantime is still rejected with ErrLeadExists.
+func ApproveLeadReview(ctx context.Context, reviewID int, resolvedBy string) (*models.Lead, error) {
+	var data []byte
+	err := db.DB.QueryRowContext(ctx,
+		"SELECT lead_data FROM lead_review WHERE id = $1 AND status = $2", reviewID, models.LeadReviewPending).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrLeadReviewNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching lead review: %w", err)
+	}
+
+	var lead models.Lead
+	if err := json.Unmarshal(data, &lead); err != nil {
+		return nil, fmt.Errorf("error decoding reviewed lead: %w", err)
+	}
+	lead.ID = 0
+
+	if err := createLead(ctx, &lead, createLeadOptions{reviewID: reviewID, resolvedBy: resolvedBy}); err != nil {
+		return nil, err
+	}
+	return &lead, nil
+}
+
+// MergeLeadReview folds the reviewed submission into an existing lead: empty fields of the
+// lead are filled from the submission, and a differing email or phone is kept in a note.
+// leadID may be 0 when the submission matched a single lead.
+func MergeLeadReview(ctx context.Context, reviewID, leadID int, resolvedBy string) (int, []string, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	review, matchIDs, err := scanLeadReview(tx.QueryRowContext(ctx,
+		"SELECT "+leadReviewColumns+" FROM lead_review WHERE id = $1 AND status = $2 FOR UPDATE",
+		reviewID, models.LeadReviewPending))
+	if err == sql.ErrNoRows {
+		return 0, nil, ErrLeadReviewNotFound
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("error fetching lead review: %w", err)
+	}
+
+	if leadID == 0 {
+		if len(matchIDs) != 1 {
+			return 0, nil, ErrLeadReviewTargetRequired
+		}
+		leadID = int(matchIDs[0])
+	}
+
+	var email, phone string
+	err = tx.QueryRowContext(ctx,
+		"SELECT email, phone FROM student_lead WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", leadID,
+	).Scan(utils.DecryptedPII(&email), utils.DecryptedPII(&phone))
+	if err == sql.ErrNoRows {
+		return 0, nil, ErrLeadNotFound
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("error fetching lead: %w", err)
+	}
+
+	updated, err := fillLeadFields(ctx, tx, leadID, &review.Lead, models.DuplicatePolicyUpdate)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(updated) > 0 {
+		if err := EnqueueLeadUpdatedEvent(ctx, tx, leadID, updated, fmt.Sprintf("lead-review:%d", reviewID)); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	var alternates []string
+	if review.Lead.Email != email {
+		alternates = append(alternates, "email "+review.Lead.Email)
+	}
+	if review.Lead.Phone != phone {
+		alternates = append(alternates, "phone "+review.Lead.Phone)
+	}
+	if len(alternates) > 0 {
+		content := fmt.Sprintf("Merged lead review #%d (%s): alternate %s", reviewID, review.Source, strings.Join(alternates, ", "))
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO lead_note (lead_id, note_type, content) VALUES ($1, $2, $3)",
+			leadID, models.NoteTypeNote, content); err != nil {
+			return 0, nil, fmt.Errorf("error recording alternate contact: %w", err)
+		}
+	}
+
+	if err := resolveLeadReview(ctx, tx, reviewID, models.LeadReviewMerged, leadID, resolvedBy); err != nil {
+		return 0, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, fmt.Errorf("error committing merge: %w", err)
+	}
+	return leadID, updated, nil
+}
+
+// resolveLeadReview marks a pending review as approved or merged into leadID
+func resolveLeadReview(ctx context.Context, tx *sql.Tx, reviewID int, status string, leadID int, resolvedBy string) error {
+	result, err := tx.ExecContext(ctx, `
+		UPDATE lead_review
+		SET status = $1, resolved_lead_id = $2, resolved_by = NULLIF($3, ''), resolved_at = NOW(), updated_at = NOW()
+		WHERE id = $4 AND status = $5`,
+		status, leadID, resolvedBy, reviewID, models.LeadReviewPending)
+	if err != nil {
+		return fmt.Errorf("error resolving lead review: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrLeadReviewNotFound
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/models"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReportDateLayout is the date format used by spend periods and report filters
+const ReportDateLayout = "2006-01-02"
+
+// RecordMarketingSpend stores spend for a source/campaign period.
+// Recording the same source, campaign and period again replaces the amount.
+func RecordMarketingSpend(ctx context.Context, spend *models.MarketingSpend) error {
+	spend.LeadSource = strings.ToLower(strings.TrimSpace(spend.LeadSource))
+	spend.Campaign = strings.TrimSpace(spend.Campaign)
+
+	err := db.DB.QueryRowContext(ctx, `
+		INSERT INTO marketing_spend (lead_source, campaign, period_start, period_end, amount, notes, recorded_by)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''))
+		ON CONFLICT (lead_source, campaign, period_start, period_end) DO UPDATE
+		SET amount = EXCLUDED.amount,
+			notes = EXCLUDED.notes,
+			recorded_by = EXCLUDED.recorded_by,
+			updated_at = NOW()
+		RETURNING id, created_at, updated_at`,
+		spend.LeadSource, spend.Campaign, spend.PeriodStart, spend.PeriodEnd,
+		spend.Amount, spend.Notes, spend.RecordedBy,
+	).Scan(&spend.ID, &spend.CreatedAt, &spend.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error recording marketing spend: %w", err)
+	}
+
+	return nil
+}
+
+// GetMarketingSpend lists spend entries whose period falls within [from, to]; nil bounds are open
+func GetMarketingSpend(ctx context.Context, from, to *time.Time) ([]models.MarketingSpend, error) {
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT id, lead_source, campaign, period_start, period_end, amount,
+			COALESCE(notes, ''), COALESCE(recorded_by, ''), created_at, updated_at
+		FROM marketing_spend
+		WHERE ($1::date IS NULL OR period_start >= $1::date)
+			AND ($2::date IS NULL OR period_end <= $2::date)
+		ORDER BY period_start DESC, lead_source, campaign`, nullableTime(from), nullableTime(to))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching marketing spend: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.MarketingSpend{}
+	for rows.Next() {
+		var e models.MarketingSpend
+		if err := rows.Scan(&e.ID, &e.LeadSource, &e.Campaign, &e.PeriodStart, &e.PeriodEnd, &e.Amount,
+			&e.Notes, &e.RecordedBy, &e.CreatedAt, &e.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error reading marketing spend: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetCACReport computes cost per lead and cost per enrollment by lead source and campaign.
+// Spend counts when its period lies within [from, to]; leads count when created within
+// the same dates. A lead is enrolled once its course fee is paid.
+func GetCACReport(ctx context.Context, from, to *time.Time) (*models.CACReport, error) {
+	rows, err := db.Reader().QueryContext(ctx, `
+		WITH spend AS (
+			SELECT lead_source, campaign, SUM(amount) AS total
+			FROM marketing_spend
+			WHERE ($1::date IS NULL OR period_start >= $1::date)
+				AND ($2::date IS NULL OR period_end <= $2::date)
+			GROUP BY lead_source, campaign
+		), conversions AS (
+			SELECT LOWER(COALESCE(lead_source, '')) AS lead_source, COALESCE(campaign, '') AS campaign,
+				COUNT(*) AS leads,
+				COUNT(*) FILTER (WHERE course_fee_status = $3) AS enrollments
+			FROM student_lead
+			WHERE deleted_at IS NULL
+				AND ($1::date IS NULL OR created_at >= $1::date)
+				AND ($2::date IS NULL OR created_at < $2::date + 1)
+			GROUP BY 1, 2
+		)
+		SELECT COALESCE(s.lead_source, c.lead_source), COALESCE(s.campaign, c.campaign),
+			COALESCE(s.total, 0), COALESCE(c.leads, 0), COALESCE(c.enrollments, 0)
+		FROM spend s
+		FULL OUTER JOIN conversions c ON c.lead_source = s.lead_source AND c.campaign = s.campaign
+		ORDER BY 1, 2`, nullableTime(from), nullableTime(to), PaymentStatusPaid)
+	if err != nil {
+		return nil, fmt.Errorf("error building CAC report: %w", err)
+	}
+	defer rows.Close()
+
+	report := &models.CACReport{From: from, To: to, Rows: []models.CACReportRow{}}
+	for rows.Next() {
+		var row models.CACReportRow
+		if err := rows.Scan(&row.LeadSource, &row.Campaign, &row.Spend, &row.Leads, &row.Enrollments); err != nil {
+			return nil, fmt.Errorf("error reading CAC report: %w", err)
+		}
+		fillCACRatios(&row)
+		report.Rows = append(report.Rows, row)
+
+		report.Totals.Spend += row.Spend
+		report.Totals.Leads += row.Leads
+		report.Totals.Enrollments += row.Enrollments
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading CAC report: %w", err)
+	}
+
+	report.Totals.LeadSource = "TOTAL"
+	fillCACRatios(&report.Totals)
+	return report, nil
+}
+
+// fillCACRatios derives conversion rate and unit costs from the raw counts
+func fillCACRatios(row *models.CACReportRow) {
+	if row.Leads > 0 {
+		row.ConversionRate = float64(row.Enrollments) / float64(row.Leads)
+		costPerLead := row.Spend / float64(row.Leads)
+		row.CostPerLead = &costPerLead
+	}
+	if row.Enrollments > 0 {
+		costPerEnrollment := row.Spend / float64(row.Enrollments)
+		row.CostPerEnrollment = &costPerEnrollment
+	}
+}
+
+// WriteCACReportCSV writes the report rows followed by the totals row as CSV
+func WriteCACReportCSV(w io.Writer, report *models.CACReport) error {
+	writer := csv.NewWriter(w)
+	header := []string{"Lead Source", "Campaign", "Spend", "Leads", "Enrollments",
+		"Conversion Rate", "Cost Per Lead", "Cost Per Enrollment"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, row := range append(report.Rows, report.Totals) {
+		record := []string{
+			csvCell(row.LeadSource), csvCell(row.Campaign), formatAmount(&row.Spend),
+			strconv.Itoa(row.Leads), strconv.Itoa(row.Enrollments),
+			strconv.FormatFloat(row.ConversionRate, 'f', 4, 64),
+			formatAmount(row.CostPerLead), formatAmount(row.CostPerEnrollment),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatAmount renders a currency amount with two decimals, or blank when not applicable
+func formatAmount(amount *float64) string {
+	if amount == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*amount, 'f', 2, 64)
+}
+
+// nullableTime converts an optional time into a query argument
+func nullableTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrCounselorNotFound is returned when a referenced counselor does not exist
+	ErrCounselorNotFound = errors.New("counselor not found")
+	// ErrMentionForbidden is returned when the note author may not mention colleagues on the lead
+	ErrMentionForbidden = errors.New("only the counselor assigned to this lead can mention colleagues")
+)
+
+// mentionRegex matches @handle at the start of the text or after whitespace,
+// so email addresses inside a note are not mistaken for mentions
+var mentionRegex = regexp.MustCompile(`(?:^|\s)@([A-Za-z0-9._-]+)`)
+
+// UnknownMentionError lists @handles that do not match any counselor
+type UnknownMentionError struct {
+	Handles []string
+}
+
+func (e *UnknownMentionError) Error() string {
+	return "unknown mentions: @" + strings.Join(e.Handles, ", @")
+}
+
+// ParseMentions extracts the distinct, lower-cased @handles from note content.
+// A handle is the local part of a counselor's email address (e.g. @priya for priya@university.edu).
+func ParseMentions(content string) []string {
+	seen := map[string]bool{}
+	handles := []string{}
+	for _, match := range mentionRegex.FindAllStringSubmatch(content, -1) {
+		handle := strings.ToLower(strings.TrimRight(match[1], "._-"))
+		if handle == "" || seen[handle] {
+			continue
+		}
+		seen[handle] = true
+		handles = append(handles, handle)
+	}
+	return handles
+}
+
+type mentionedCounselor struct {
+	ID    int64
+	Name  string
+	Email string
+}
+
+// resolveMentions maps the note's @handles to counselors and applies the permission rules:
+// mentions need a known author, and on an assigned lead only its counselor may mention others.
+// Self-mentions are dropped.
+func resolveMentions(ctx context.Context, tx *sql.Tx, note *models.LeadNote, assignedCounselorID sql.NullInt64) ([]mentionedCounselor, error) {
+	handles := ParseMentions(note.Content)
+	if len(handles) == 0 {
+		return nil, nil
+	}
+
+	if note.CounselorID == nil {
+		return nil, fmt.Errorf("%w: counselor_id is required to mention colleagues", ErrMentionForbidden)
+	}
+	if assignedCounselorID.Valid && assignedCounselorID.Int64 != *note.CounselorID {
+		return nil, ErrMentionForbidden
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, name, email, LOWER(SPLIT_PART(email, '@', 1))
+		FROM counselor
+		WHERE LOWER(SPLIT_PART(email, '@', 1)) = ANY($1)`, pq.Array(handles))
+	if err != nil {
+		return nil, fmt.Errorf("error resolving mentions: %w", err)
+	}
+	defer rows.Close()
+
+	found := map[string]bool{}
+	mentioned := []mentionedCounselor{}
+	for rows.Next() {
+		var c mentionedCounselor
+		var handle string
+		if err := rows.Scan(&c.ID, &c.Name, &c.Email, &handle); err != nil {
+			return nil, fmt.Errorf("error reading mentioned counselor: %w", err)
+		}
+		found[handle] = true
+		if c.ID == *note.CounselorID {
+			continue
+		}
+		mentioned = append(mentioned, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error resolving mentions: %w", err)
+	}
+
+	unknown := []string{}
+	for _, handle := range handles {
+		if !found[handle] {
+			unknown = append(unknown, handle)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, &UnknownMentionError{Handles: unknown}
+	}
+
+	return mentioned, nil
+}
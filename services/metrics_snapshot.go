@@ -0,0 +1,150 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"context"
+	"fmt"
+	"time"
+)
+
+// defaultSnapshotDays is the range of the snapshot series when no from date is given
+const defaultSnapshotDays = 30
+
+// RecordMetricsSnapshot stores the snapshot of the day that ended at the last midnight.
+// Run by the metrics-snapshot job shortly after midnight, so the totals it takes are
+// those at the end of that day.
+func RecordMetricsSnapshot(ctx context.Context) error {
+	now := time.Now()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -1)
+	snapshot, err := CaptureMetricsSnapshot(ctx, day)
+	if err != nil {
+		return err
+	}
+	logger.Info("Recorded metrics snapshot for %s: %d leads, %d enrollments, DLQ depth %d",
+		snapshot.Date, snapshot.TotalLeads, snapshot.Enrollments, snapshot.DLQDepth)
+	return nil
+}
+
+// CaptureMetricsSnapshot computes the metrics for a day and stores them, replacing an
+// earlier snapshot of that day. New leads, registrations and enrollments are counted
+// within the day; totals, outstanding course fees and DLQ depth are taken as they are now.
+func CaptureMetricsSnapshot(ctx context.Context, day time.Time) (*models.MetricsSnapshot, error) {
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	to := from.AddDate(0, 0, 1)
+	snapshot := &models.MetricsSnapshot{Date: from.Format(ReportDateLayout)}
+
+	err := db.DB.QueryRowContext(ctx, `
+		WITH leads AS (
+			SELECT created_at, registration_fee_status, course_fee_status
+			FROM student_lead
+			WHERE deleted_at IS NULL
+		), paid AS (
+			SELECT payment_type FROM payments WHERE status = $3 AND updated_at >= $1 AND updated_at < $2
+		)
+		SELECT
+			(SELECT COUNT(*) FROM leads),
+			(SELECT COUNT(*) FROM leads WHERE created_at >= $1 AND created_at < $2),
+			(SELECT COUNT(*) FROM leads WHERE registration_fee_status = $3),
+			(SELECT COUNT(*) FROM paid WHERE payment_type = $4),
+			(SELECT COUNT(*) FROM leads WHERE course_fee_status = $3),
+			(SELECT COUNT(*) FROM paid WHERE payment_type = $5),
+			(SELECT COUNT(*) FROM payments WHERE status = $6)`,
+		from, to, PaymentStatusPaid, PaymentTypeRegistration, PaymentTypeCourseFee, PaymentStatusPending,
+	).Scan(&snapshot.TotalLeads, &snapshot.NewLeads, &snapshot.RegistrationsPaid, &snapshot.NewRegistrationsPaid,
+		&snapshot.Enrollments, &snapshot.NewEnrollments, &snapshot.PendingPaymentOrders)
+	if err != nil {
+		return nil, fmt.Errorf("error computing lead and payment metrics: %w", err)
+	}
+
+	err = db.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(c.fee), 0)
+		FROM student_lead s
+		LEFT JOIN course c ON c.id = s.selected_course_id
+		WHERE s.deleted_at IS NULL AND s.application_status = $1 AND s.course_fee_status <> $2`,
+		ApplicationStatusAccepted, PaymentStatusPaid,
+	).Scan(&snapshot.OutstandingCourseFees, &snapshot.OutstandingCourseFeeAmount)
+	if err != nil {
+		return nil, fmt.Errorf("error computing outstanding course fees: %w", err)
+	}
+
+	err = db.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE quarantined_at IS NOT NULL)
+		FROM dlq_messages WHERE resolved = FALSE`,
+	).Scan(&snapshot.DLQDepth, &snapshot.DLQQuarantined)
+	if err != nil {
+		return nil, fmt.Errorf("error computing DLQ depth: %w", err)
+	}
+
+	err = db.DB.QueryRowContext(ctx, `
+		INSERT INTO metrics_snapshot (
+			snapshot_date, total_leads, new_leads, registrations_paid, new_registrations_paid,
+			enrollments, new_enrollments, outstanding_course_fees, outstanding_course_fee_amount,
+			pending_payment_orders, dlq_depth, dlq_quarantined, captured_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW())
+		ON CONFLICT (snapshot_date) DO UPDATE SET
+			total_leads = EXCLUDED.total_leads,
+			new_leads = EXCLUDED.new_leads,
+			registrations_paid = EXCLUDED.registrations_paid,
+			new_registrations_paid = EXCLUDED.new_registrations_paid,
+			enrollments = EXCLUDED.enrollments,
+			new_enrollments = EXCLUDED.new_enrollments,
+			outstanding_course_fees = EXCLUDED.outstanding_course_fees,
+			outstanding_course_fee_amount = EXCLUDED.outstanding_course_fee_amount,
+			pending_payment_orders = EXCLUDED.pending_payment_orders,
+			dlq_depth = EXCLUDED.dlq_depth,
+			dlq_quarantined = EXCLUDED.dlq_quarantined,
+			captured_at = EXCLUDED.captured_at
+		RETURNING captured_at`,
+		snapshot.Date, snapshot.TotalLeads, snapshot.NewLeads, snapshot.RegistrationsPaid, snapshot.NewRegistrationsPaid,
+		snapshot.Enrollments, snapshot.NewEnrollments, snapshot.OutstandingCourseFees, snapshot.OutstandingCourseFeeAmount,
+		snapshot.PendingPaymentOrders, snapshot.DLQDepth, snapshot.DLQQuarantined,
+	).Scan(&snapshot.CapturedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error storing metrics snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+// ListMetricsSnapshots returns the stored snapshots from from to to (inclusive), oldest
+// first. The range defaults to the last 30 days up to today. Days without a snapshot
+// (the job was not running) are left out.
+func ListMetricsSnapshots(ctx context.Context, from, to *time.Time) (*models.MetricsSnapshotSeries, error) {
+	end := time.Now()
+	if to != nil {
+		end = *to
+	}
+	start := end.AddDate(0, 0, -(defaultSnapshotDays - 1))
+	if from != nil {
+		start = *from
+	}
+	series := &models.MetricsSnapshotSeries{
+		From:      start.Format(ReportDateLayout),
+		To:        end.Format(ReportDateLayout),
+		Snapshots: []models.MetricsSnapshot{},
+	}
+
+	rows, err := db.Reader().QueryContext(ctx, `
+		SELECT TO_CHAR(snapshot_date, 'YYYY-MM-DD'), total_leads, new_leads, registrations_paid, new_registrations_paid,
+			enrollments, new_enrollments, outstanding_course_fees, outstanding_course_fee_amount,
+			pending_payment_orders, dlq_depth, dlq_quarantined, captured_at
+		FROM metrics_snapshot
+		WHERE snapshot_date >= $1::date AND snapshot_date <= $2::date
+		ORDER BY snapshot_date`, series.From, series.To)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching metrics snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.MetricsSnapshot
+		if err := rows.Scan(&s.Date, &s.TotalLeads, &s.NewLeads, &s.RegistrationsPaid, &s.NewRegistrationsPaid,
+			&s.Enrollments, &s.NewEnrollments, &s.OutstandingCourseFees, &s.OutstandingCourseFeeAmount,
+			&s.PendingPaymentOrders, &s.DLQDepth, &s.DLQQuarantined, &s.CapturedAt); err != nil {
+			return nil, fmt.Errorf("error scanning metrics snapshot: %w", err)
+		}
+		series.Snapshots = append(series.Snapshots, s)
+	}
+	return series, rows.Err()
+}
@@ -5,6 +5,7 @@ import (
 	"admission-module/models"
 	"context"
 	"fmt"
+	"html"
 	"log"
 )
 
@@ -34,7 +35,18 @@ func SendCounselorAssignmentEmail(studentName, studentEmail, counselorName, coun
 		return fmt.Errorf("student email is required")
 	}
 
-	emailBody := fmt.Sprintf(`
+	subject, body := buildCounselorAssignmentEmail(studentName, counselorName, counselorEmail, counselorPhone)
+	if err := SendEmail(studentEmail, subject, body); err != nil {
+		log.Printf("Warning: Failed to queue welcome email to %s: %v", studentEmail, err)
+		return nil
+	}
+
+	return nil
+}
+
+// buildCounselorAssignmentEmail renders the student welcome email introducing their counselor
+func buildCounselorAssignmentEmail(studentName, counselorName, counselorEmail, counselorPhone string) (subject, body string) {
+	body = fmt.Sprintf(`
 <!DOCTYPE html>
 <html>
 <head>
@@ -139,14 +151,7 @@ func SendCounselorAssignmentEmail(studentName, studentEmail, counselorName, coun
 </html>
 	`, studentName, counselorName, counselorEmail, counselorEmail, counselorPhone, counselorPhone)
 
-	subject := fmt.Sprintf("Welcome %s - Your Counselor Assignment", studentName)
-
-	if err := SendEmail(studentEmail, subject, emailBody); err != nil {
-		log.Printf("Warning: Failed to queue welcome email to %s: %v", studentEmail, err)
-		return nil
-	}
-
-	return nil
+	return fmt.Sprintf("Welcome %s - Your Counselor Assignment", studentName), body
 }
 
 // SendCounselorAssignmentNotificationEmail queues counselor notification email via Kafka
@@ -155,7 +160,18 @@ func SendCounselorAssignmentNotificationEmail(counselorName, counselorEmail, stu
 		return fmt.Errorf("counselor email is required")
 	}
 
-	emailBody := fmt.Sprintf(`
+	subject, body := buildCounselorLeadAssignmentEmail(counselorName, studentName, studentPhone, studentEmail, leadSource)
+	if err := SendCategorizedEmail(EmailCategoryStaff, counselorEmail, subject, body); err != nil {
+		log.Printf("Warning: Failed to queue counselor notification to %s: %v", counselorEmail, err)
+		return nil
+	}
+
+	return nil
+}
+
+// buildCounselorLeadAssignmentEmail renders the counselor's notice of a newly assigned lead
+func buildCounselorLeadAssignmentEmail(counselorName, studentName, studentPhone, studentEmail, leadSource string) (subject, body string) {
+	body = fmt.Sprintf(`
 <!DOCTYPE html>
 <html>
 <head>
@@ -237,12 +253,51 @@ func SendCounselorAssignmentNotificationEmail(counselorName, counselorEmail, stu
 </html>
 	`, counselorName, studentName, studentEmail, studentEmail, studentPhone, studentPhone, leadSource)
 
-	subject := fmt.Sprintf("New Lead Assignment - %s", studentName)
+	return fmt.Sprintf("New Lead Assignment - %s", studentName), body
+}
 
-	if err := SendEmail(counselorEmail, subject, emailBody); err != nil {
-		log.Printf("Warning: Failed to queue counselor notification to %s: %v", counselorEmail, err)
+// SendMentionNotificationEmail queues an email telling a counselor they were mentioned in a lead note
+func SendMentionNotificationEmail(counselorName, counselorEmail, authorName, leadName, noteContent string) error {
+	if counselorEmail == "" {
+		return fmt.Errorf("counselor email is required")
+	}
+
+	subject, body := buildMentionEmail(counselorName, authorName, leadName, noteContent)
+	if err := SendCategorizedEmail(EmailCategoryStaff, counselorEmail, subject, body); err != nil {
+		log.Printf("Warning: Failed to queue mention notification to %s: %v", counselorEmail, err)
 		return nil
 	}
 
 	return nil
 }
+
+// buildMentionEmail renders the email quoting the note a counselor was mentioned in
+func buildMentionEmail(counselorName, authorName, leadName, noteContent string) (subject, body string) {
+	body = fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #2196F3; color: white; padding: 20px; text-align: center; border-radius: 5px; }
+        .content { background-color: #f9f9f9; padding: 20px; margin-top: 20px; border-radius: 5px; }
+        .note { background-color: #e3f2fd; padding: 15px; margin: 15px 0; border-left: 4px solid #2196F3; white-space: pre-wrap; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header"><h2>You were mentioned</h2></div>
+        <div class="content">
+            <p>Dear <strong>%s</strong>,</p>
+            <p><strong>%s</strong> mentioned you in a note on lead <strong>%s</strong>:</p>
+            <div class="note">%s</div>
+            <p>Best regards,<br/><strong>Admission System</strong></p>
+        </div>
+    </div>
+</body>
+</html>
+	`, html.EscapeString(counselorName), html.EscapeString(authorName), html.EscapeString(leadName), html.EscapeString(noteContent))
+
+	return fmt.Sprintf("%s mentioned you on lead %s", authorName, leadName), body
+}
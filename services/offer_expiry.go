@@ -0,0 +1,193 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/utils"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// expiredOffer is an offer withdrawn by ExpireUnpaidOffers, with the waitlisted
+// student (if any) who was offered the released seat
+type expiredOffer struct {
+	leadID                        int
+	studentName, studentEmail     string
+	courseID                      int
+	courseName                    string
+	courseFee                     float64
+	deadline                      time.Time
+	counselorName, counselorEmail sql.NullString
+	promoted                      *AcceptApplicationResult
+}
+
+// ExpireUnpaidOffers withdraws accepted offers whose course fee deadline passed without
+// payment (ACCEPTED -> OFFER_EXPIRED) and offers each released seat to the longest-waiting
+// student on the course's waitlist. Returns the number of offers expired.
+func ExpireUnpaidOffers(ctx context.Context) (int, error) {
+	if db.DB == nil {
+		return 0, nil
+	}
+
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT sl.id FROM student_lead sl
+		WHERE sl.deleted_at IS NULL
+			AND sl.application_status = $1
+			AND sl.course_fee_deadline < NOW()
+			AND NOT EXISTS (
+				SELECT 1 FROM course_payment cp
+				WHERE cp.student_id = sl.id AND cp.course_id = sl.selected_course_id AND cp.status = $2)
+		ORDER BY sl.course_fee_deadline ASC
+		LIMIT 200`, ApplicationStatusAccepted, PaymentStatusPaid)
+	if err != nil {
+		return 0, fmt.Errorf("error finding unpaid offers: %w", err)
+	}
+
+	var leadIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("error reading unpaid offers: %w", err)
+		}
+		leadIDs = append(leadIDs, id)
+	}
+	rows.Close()
+
+	expired := 0
+	for _, leadID := range leadIDs {
+		offer, err := expireOffer(ctx, leadID)
+		if err != nil {
+			logger.Error("Error expiring offer for lead %d: %v", leadID, err)
+			continue
+		}
+		if offer == nil {
+			continue
+		}
+		expired++
+		notifyOfferExpired(offer)
+	}
+
+	if expired > 0 {
+		logger.Info("Expired %d offers with unpaid course fees", expired)
+	}
+	return expired, nil
+}
+
+// expireOffer withdraws one offer and promotes the next waitlisted student in a single
+// transaction. Returns nil when the offer no longer qualifies (e.g. paid meanwhile).
+func expireOffer(ctx context.Context, leadID int) (*expiredOffer, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	offer := &expiredOffer{leadID: leadID}
+	err = tx.QueryRowContext(ctx, `
+		SELECT sl.name, sl.email, sl.selected_course_id, c.name, c.fee, sl.course_fee_deadline, co.name, co.email
+		FROM student_lead sl
+		JOIN course c ON c.id = sl.selected_course_id
+		LEFT JOIN counselor co ON co.id = sl.counselor_id
+		WHERE sl.id = $1 AND sl.deleted_at IS NULL
+			AND sl.application_status = $2
+			AND sl.course_fee_deadline < NOW()
+			AND NOT EXISTS (
+				SELECT 1 FROM course_payment cp
+				WHERE cp.student_id = sl.id AND cp.course_id = sl.selected_course_id AND cp.status = $3)
+		FOR UPDATE OF sl`, leadID, ApplicationStatusAccepted, PaymentStatusPaid).Scan(
+		&offer.studentName, utils.DecryptedPII(&offer.studentEmail), &offer.courseID, &offer.courseName, &offer.courseFee,
+		&offer.deadline, &offer.counselorName, &offer.counselorEmail)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error locking offer: %w", err)
+	}
+
+	if _, err := transitionApplicationStatus(ctx, tx, leadID, ApplicationStatusOfferExpired); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE student_lead SET offer_expired_at = NOW() WHERE id = $1", leadID); err != nil {
+		return nil, fmt.Errorf("error marking offer expired: %w", err)
+	}
+	if err := enqueueApplicationEvent(ctx, tx, "offer_expired", leadID, offer.courseID, offer.courseName); err != nil {
+		return nil, err
+	}
+
+	offer.promoted, err = promoteFromWaitlist(ctx, tx, offer)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return offer, nil
+}
+
+// promoteFromWaitlist accepts the student who has waited longest for the released seat.
+// Returns nil when nobody is waiting for the course.
+func promoteFromWaitlist(ctx context.Context, tx *sql.Tx, offer *expiredOffer) (*AcceptApplicationResult, error) {
+	var waitingID int
+	var name, email string
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, name, email FROM student_lead
+		WHERE application_status = $1 AND selected_course_id = $2 AND deleted_at IS NULL
+		ORDER BY status_changed_at ASC NULLS LAST, id ASC
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`, ApplicationStatusWaitlisted, offer.courseID).Scan(&waitingID, &name, utils.DecryptedPII(&email))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading waitlist: %w", err)
+	}
+
+	if _, err := transitionApplicationStatus(ctx, tx, waitingID, ApplicationStatusAccepted); err != nil {
+		return nil, err
+	}
+	deadline, err := setCourseFeeDeadline(ctx, tx, waitingID, offer.courseID)
+	if err != nil {
+		return nil, err
+	}
+	if err := enqueueApplicationEvent(ctx, tx, "accepted", waitingID, offer.courseID, offer.courseName); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Seat in %s released by lead %d offered to waitlisted lead %d", offer.courseName, offer.leadID, waitingID)
+	return &AcceptApplicationResult{
+		StudentName:     name,
+		StudentEmail:    email,
+		CourseName:      offer.courseName,
+		CourseFee:       offer.courseFee,
+		CourseID:        offer.courseID,
+		PaymentDeadline: deadline,
+	}, nil
+}
+
+// notifyOfferExpired emails the student and counselor about the expired offer, and the
+// promoted student about their new offer
+func notifyOfferExpired(offer *expiredOffer) {
+	if err := SendOfferExpiredEmail(offer.studentName, offer.studentEmail, offer.courseName, offer.deadline); err != nil {
+		logger.Warn("Failed to queue offer expiry email for lead %d: %v", offer.leadID, err)
+	}
+
+	promotedName := ""
+	if offer.promoted != nil {
+		promotedName = offer.promoted.StudentName
+		if err := SendAcceptanceEmail(offer.promoted.StudentName, offer.promoted.StudentEmail,
+			offer.promoted.CourseID, offer.promoted.CourseName, offer.promoted.CourseFee, offer.promoted.PaymentDeadline); err != nil {
+			logger.Warn("Failed to queue acceptance email for waitlisted student %s: %v", offer.promoted.StudentEmail, err)
+		}
+	}
+
+	if offer.counselorEmail.Valid && offer.counselorEmail.String != "" {
+		if err := SendOfferExpiredCounselorEmail(offer.counselorName.String, offer.counselorEmail.String,
+			offer.studentName, offer.courseName, promotedName); err != nil {
+			logger.Warn("Failed to queue offer expiry email to counselor %s: %v", offer.counselorEmail.String, err)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// partitionMonthLayout is the month suffix of a partition name (razorpay_webhooks_p2025_03)
+const partitionMonthLayout = "2006_01"
+
+// partitionedTable is a table range-partitioned by month on created_at, with a DEFAULT
+// partition named <table>_default for rows no monthly partition covers
+type partitionedTable struct {
+	name string
+	// onDrop removes rows kept elsewhere for the month [from, to) of a dropped partition
+	onDrop func(ctx context.Context, tx *sql.Tx, from, to time.Time) error
+}
+
+// partitionedTables are maintained by EnsurePartitions and archiveOldPartitions
+var partitionedTables = []partitionedTable{
+	{name: "razorpay_webhooks", onDrop: deleteWebhookKeys},
+}
+
+// deleteWebhookKeys forgets the IDs of the webhooks of a dropped razorpay_webhooks partition
+func deleteWebhookKeys(ctx context.Context, tx *sql.Tx, from, to time.Time) error {
+	_, err := tx.ExecContext(ctx,
+		"DELETE FROM razorpay_webhook_key WHERE created_at >= $1 AND created_at < $2", from, to)
+	return err
+}
+
+func partitionName(table string, month time.Time) string {
+	return table + "_p" + month.Format(partitionMonthLayout)
+}
+
+// partitionMonth parses the month of a partition name created by partitionName
+func partitionMonth(table, name string) (time.Time, bool) {
+	suffix, ok := strings.CutPrefix(name, table+"_p")
+	if !ok {
+		return time.Time{}, false
+	}
+	month, err := time.Parse(partitionMonthLayout, suffix)
+	return month, err == nil
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// EnsurePartitions creates the monthly partitions of every partitioned table from the
+// current month to WebhookPartitionPremakeMonths ahead. Rows that already landed in the
+// DEFAULT partition for a new month are moved into it.
+func EnsurePartitions(ctx context.Context) error {
+	if db.DB == nil {
+		return nil
+	}
+
+	current := monthStart(time.Now())
+	for _, table := range partitionedTables {
+		attached, err := listPartitions(ctx, table.name, true)
+		if err != nil {
+			return err
+		}
+		for i := 0; i <= config.AppConfig.WebhookPartitionPremakeMonths; i++ {
+			month := current.AddDate(0, i, 0)
+			if attached[partitionName(table.name, month)] {
+				continue
+			}
+			if err := createPartition(ctx, table.name, month); err != nil {
+				return err
+			}
+			logger.Info("Created partition %s", partitionName(table.name, month))
+		}
+	}
+	return nil
+}
+
+// createPartition creates the partition of table for month. It is built standalone and
+// attached, so rows for the month in the DEFAULT partition can be moved into it first.
+func createPartition(ctx context.Context, table string, month time.Time) error {
+	parent := pq.QuoteIdentifier(table)
+	partition := pq.QuoteIdentifier(partitionName(table, month))
+	defaultPartition := pq.QuoteIdentifier(table + "_default")
+	from, to := month, month.AddDate(0, 1, 0)
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS)", partition, parent), nil},
+		{fmt.Sprintf(`WITH moved AS (DELETE FROM %s WHERE created_at >= $1 AND created_at < $2 RETURNING *)
+			INSERT INTO %s SELECT * FROM moved`, defaultPartition, partition), []interface{}{from, to}},
+		{fmt.Sprintf("ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM (%s) TO (%s)", parent, partition,
+			pq.QuoteLiteral(from.Format("2006-01-02")), pq.QuoteLiteral(to.Format("2006-01-02"))), nil},
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+			return fmt.Errorf("error creating partition %s: %w", partitionName(table, month), err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// listPartitions returns the monthly partition tables of table: those attached to it, or
+// the detached ones left in cold storage
+func listPartitions(ctx context.Context, table string, attached bool) (map[string]bool, error) {
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT c.relname,
+			EXISTS (
+				SELECT 1 FROM pg_inherits i JOIN pg_class p ON p.oid = i.inhparent
+				WHERE i.inhrelid = c.oid AND p.relname = $1
+			)
+		FROM pg_class c
+		WHERE c.relkind = 'r' AND c.relnamespace = current_schema()::regnamespace
+			AND starts_with(c.relname, $1 || '_p')`, table)
+	if err != nil {
+		return nil, fmt.Errorf("error listing partitions of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	partitions := map[string]bool{}
+	for rows.Next() {
+		var name string
+		var isAttached bool
+		if err := rows.Scan(&name, &isAttached); err != nil {
+			return nil, fmt.Errorf("error reading partitions of %s: %w", table, err)
+		}
+		if _, ok := partitionMonth(table, name); ok && isAttached == attached {
+			partitions[name] = true
+		}
+	}
+	return partitions, rows.Err()
+}
+
+// archiveOldPartitions is a retention policy detaching the partitions of months that
+// ended more than WebhookPartitionArchiveMonths ago and, when WebhookPartitionDropMonths is
+// set, dropping detached partitions older than that. A detached partition stays a plain
+// table (e.g. razorpay_webhooks_p2024_01) that can be dumped to cold storage. The windows
+// are counted in months, so cutoff is not used; with dryRun the partitions are only counted.
+func archiveOldPartitions(ctx context.Context, _ time.Time, dryRun bool) (int, int, error) {
+	current := monthStart(time.Now())
+	archiveBefore := current.AddDate(0, -config.AppConfig.WebhookPartitionArchiveMonths, 0)
+	var dropBefore time.Time
+	if months := config.AppConfig.WebhookPartitionDropMonths; months > 0 {
+		dropBefore = current.AddDate(0, -months, 0)
+	}
+
+	processed := 0
+	for _, table := range partitionedTables {
+		attached, err := listPartitions(ctx, table.name, true)
+		if err != nil {
+			return processed, 0, err
+		}
+		for _, name := range sortedNames(attached) {
+			month, _ := partitionMonth(table.name, name)
+			if !month.Before(archiveBefore) {
+				continue
+			}
+			if dryRun {
+				processed++
+				continue
+			}
+			_, err := db.DB.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s",
+				pq.QuoteIdentifier(table.name), pq.QuoteIdentifier(name)))
+			if err != nil {
+				return processed, 0, fmt.Errorf("error detaching partition %s: %w", name, err)
+			}
+			logger.Info("Detached partition %s to cold storage", name)
+			processed++
+		}
+
+		if dropBefore.IsZero() {
+			continue
+		}
+		detached, err := listPartitions(ctx, table.name, false)
+		if err != nil {
+			return processed, 0, err
+		}
+		for _, name := range sortedNames(detached) {
+			month, _ := partitionMonth(table.name, name)
+			if !month.Before(dropBefore) {
+				continue
+			}
+			if dryRun {
+				processed++
+				continue
+			}
+			if err := dropPartition(ctx, table, name, month); err != nil {
+				return processed, 0, err
+			}
+			logger.Info("Dropped archived partition %s", name)
+			processed++
+		}
+	}
+	return processed, 0, nil
+}
+
+// dropPartition drops a detached partition together with the rows table.onDrop keeps for it
+func dropPartition(ctx context.Context, table partitionedTable, name string, month time.Time) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if table.onDrop != nil {
+		if err := table.onDrop(ctx, tx, month, month.AddDate(0, 1, 0)); err != nil {
+			return fmt.Errorf("error cleaning up partition %s: %w", name, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DROP TABLE "+pq.QuoteIdentifier(name)); err != nil {
+		return fmt.Errorf("error dropping partition %s: %w", name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// sortedNames returns the keys of set in order, so partitions are handled oldest first
+func sortedNames(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
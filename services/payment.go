@@ -2,9 +2,13 @@ package services
 
 import (
 	"admission-module/db"
+	"admission-module/models"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"time"
 
@@ -25,10 +29,17 @@ const (
 	PaymentStatusPaid      = "PAID"
 	PaymentStatusFailed    = "FAILED"
 	PaymentStatusCancelled = "CANCELLED"
+
+	PaymentStatusRefunded          = "REFUNDED"
+	PaymentStatusPartiallyRefunded = "PARTIALLY_REFUNDED"
 )
 
 // PaymentService handles payment operations
-type PaymentService struct{}
+type PaymentService struct {
+	leads    LeadRepository
+	courses  CourseRepository
+	payments PaymentRepository
+}
 
 // InitiatePaymentRequest represents payment initiation request
 type InitiatePaymentRequest struct {
@@ -36,6 +47,11 @@ type InitiatePaymentRequest struct {
 	Amount      float64
 	PaymentType string
 	CourseID    *int
+	// CouponCode is the code the student entered; ValidateAndPreparePayment sets CouponID
+	// and takes DiscountAmount off Amount when it applies
+	CouponCode     string
+	CouponID       *int
+	DiscountAmount float64
 }
 
 // InitiatePaymentResponse represents payment initiation response
@@ -46,12 +62,21 @@ type InitiatePaymentResponse struct {
 	Receipt  string  `json:"receipt"`
 }
 
-// NewPaymentService creates a new PaymentService instance
+// NewPaymentService creates a PaymentService over the application database
 func NewPaymentService() *PaymentService {
-	return &PaymentService{}
+	return NewPaymentServiceWith(PostgresRepositories())
+}
+
+// NewPaymentServiceWith creates a PaymentService over the given repositories. Orders are
+// still saved (SavePaymentRecord) in a database transaction.
+func NewPaymentServiceWith(repos Repositories) *PaymentService {
+	return &PaymentService{leads: repos.Leads, courses: repos.Courses, payments: repos.Payments}
 }
 
-func (s *PaymentService) ValidateAndPreparePayment(req InitiatePaymentRequest) (*InitiatePaymentRequest, error) {
+func (s *PaymentService) ValidateAndPreparePayment(ctx context.Context, req InitiatePaymentRequest) (*InitiatePaymentRequest, error) {
+	ctx, cancel := db.WithTimeout(ctx)
+	defer cancel()
+
 	// Validate payment type using tagged switch
 	switch req.PaymentType {
 	case PaymentTypeRegistration:
@@ -66,12 +91,17 @@ func (s *PaymentService) ValidateAndPreparePayment(req InitiatePaymentRequest) (
 		}
 
 		// Get course fee from database
-		var courseFee float64
-		err := db.DB.QueryRow("SELECT fee FROM course WHERE id = $1", *req.CourseID).Scan(&courseFee)
+		course, err := s.courses.FindByID(ctx, *req.CourseID)
 		if err != nil {
 			return nil, fmt.Errorf("course not found")
 		}
-		req.Amount = courseFee
+		req.Amount = course.Fee
+
+		// An expired offer can no longer be paid for
+		applicationStatus, err := s.leads.ApplicationStatus(ctx, req.StudentID)
+		if err == nil && applicationStatus == ApplicationStatusOfferExpired {
+			return nil, fmt.Errorf("offer has expired: the course fee deadline has passed")
+		}
 
 	default:
 		return nil, fmt.Errorf("invalid payment type. must be REGISTRATION or COURSE_FEE")
@@ -83,8 +113,7 @@ func (s *PaymentService) ValidateAndPreparePayment(req InitiatePaymentRequest) (
 	}
 
 	// Verify student exists
-	var exists bool
-	err := db.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM student_lead WHERE id = $1)", req.StudentID).Scan(&exists)
+	exists, err := s.leads.Exists(ctx, req.StudentID)
 	if err != nil {
 		return nil, fmt.Errorf("error checking student")
 	}
@@ -92,25 +121,44 @@ func (s *PaymentService) ValidateAndPreparePayment(req InitiatePaymentRequest) (
 		return nil, fmt.Errorf("student not found")
 	}
 
+	if req.CouponCode != "" {
+		if err := applyCoupon(ctx, currentDB{}, &req, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
 	return &req, nil
 }
 
-// CreateRazorpayOrder creates a Razorpay order
-func (s *PaymentService) CreateRazorpayOrder(req InitiatePaymentRequest) (*InitiatePaymentResponse, error) {
+// CreateRazorpayOrder creates a Razorpay order. The Razorpay client cannot be cancelled,
+// so no order is created once ctx is done, nor for a coupon that can no longer be reserved.
+func (s *PaymentService) CreateRazorpayOrder(ctx context.Context, req InitiatePaymentRequest) (*InitiatePaymentResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	keyID := os.Getenv("RazorpayKeyID")
 	keySecret := os.Getenv("RazorpayKeySecret")
 
 	if keyID == "" || keySecret == "" {
 		return nil, fmt.Errorf("razorpay credentials not configured")
 	}
+	if req.CouponID != nil {
+		if err := checkCouponReservable(ctx, req); err != nil {
+			return nil, err
+		}
+	}
 
 	client := razorpay.NewClient(keyID, keySecret)
 
 	data := map[string]interface{}{
-		"amount":   int(req.Amount * 100), // Convert to paise
+		"amount":   int(math.Round(req.Amount * 100)), // Convert to paise
 		"currency": "INR",
 		"receipt":  fmt.Sprintf("rcpt_%d_%s", req.StudentID, req.PaymentType),
 	}
+	if req.CouponID != nil {
+		data["notes"] = map[string]interface{}{"coupon_code": req.CouponCode, "discount_amount": req.DiscountAmount}
+	}
 
 	// Create Razorpay order
 	resp, err := client.Order.Create(data, nil)
@@ -129,18 +177,31 @@ func (s *PaymentService) CreateRazorpayOrder(req InitiatePaymentRequest) (*Initi
 }
 
 // SavePaymentRecord saves the payment record to the appropriate table
-func (s *PaymentService) SavePaymentRecord(studentID int, orderID string, req InitiatePaymentRequest) error {
-	tx, err := db.DB.Begin()
+func (s *PaymentService) SavePaymentRecord(ctx context.Context, studentID int, orderID string, req InitiatePaymentRequest) error {
+	ctx, cancel := db.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("error starting transaction: %w", err)
 	}
 	defer tx.Rollback()
 
+	// The coupon's uses are counted again with its row held, and the order records it
+	var couponCode sql.NullString
+	if req.CouponID != nil {
+		if err := reserveCoupon(ctx, tx, studentID, req); err != nil {
+			return err
+		}
+		couponCode = sql.NullString{String: req.CouponCode, Valid: true}
+	}
+
+	var invoice *models.Invoice
 	if req.PaymentType == PaymentTypeRegistration {
 		// Check if registration payment already exists
 		var existingPaymentID int
 		var existingStatus string
-		err = tx.QueryRow("SELECT id, status FROM registration_payment WHERE student_id = $1", studentID).Scan(&existingPaymentID, &existingStatus)
+		err = tx.QueryRowContext(ctx, "SELECT id, status FROM registration_payment WHERE student_id = $1", studentID).Scan(&existingPaymentID, &existingStatus)
 
 		if err == nil {
 			// Payment already exists
@@ -150,26 +211,26 @@ func (s *PaymentService) SavePaymentRecord(studentID int, orderID string, req In
 			}
 			if existingStatus == PaymentStatusFailed || existingStatus == PaymentStatusCancelled {
 				// Can retry failed/cancelled payment
-				_, err = tx.Exec(
-					"UPDATE registration_payment SET order_id = $1, amount = $2, status = $3, payment_id = NULL, razorpay_sign = NULL, updated_at = CURRENT_TIMESTAMP WHERE student_id = $4",
-					orderID, req.Amount, PaymentStatusPending, studentID)
+				_, err = tx.ExecContext(ctx,
+					"UPDATE registration_payment SET order_id = $1, amount = $2, status = $3, payment_id = NULL, razorpay_sign = NULL, coupon_id = $5, coupon_code = $6, discount_amount = $7, updated_at = CURRENT_TIMESTAMP WHERE student_id = $4",
+					orderID, req.Amount, PaymentStatusPending, studentID, req.CouponID, couponCode, req.DiscountAmount)
 				if err != nil {
 					return fmt.Errorf("error updating failed registration payment: %w", err)
 				}
 			} else if existingStatus == PaymentStatusPending {
 				// Update existing PENDING payment with new order_id (retry)
-				_, err = tx.Exec(
-					"UPDATE registration_payment SET order_id = $1, amount = $2, updated_at = CURRENT_TIMESTAMP WHERE student_id = $3",
-					orderID, req.Amount, studentID)
+				_, err = tx.ExecContext(ctx,
+					"UPDATE registration_payment SET order_id = $1, amount = $2, coupon_id = $4, coupon_code = $5, discount_amount = $6, updated_at = CURRENT_TIMESTAMP WHERE student_id = $3",
+					orderID, req.Amount, studentID, req.CouponID, couponCode, req.DiscountAmount)
 				if err != nil {
 					return fmt.Errorf("error updating pending registration payment: %w", err)
 				}
 			}
 		} else if err == sql.ErrNoRows {
 			// No existing payment, insert new one
-			_, err = tx.Exec(
-				"INSERT INTO registration_payment (student_id, amount, status, order_id) VALUES ($1, $2, $3, $4)",
-				studentID, req.Amount, PaymentStatusPending, orderID)
+			_, err = tx.ExecContext(ctx,
+				"INSERT INTO registration_payment (student_id, amount, status, order_id, coupon_id, coupon_code, discount_amount) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+				studentID, req.Amount, PaymentStatusPending, orderID, req.CouponID, couponCode, req.DiscountAmount)
 			if err != nil {
 				return fmt.Errorf("error saving registration payment: %w", err)
 			}
@@ -178,7 +239,7 @@ func (s *PaymentService) SavePaymentRecord(studentID int, orderID string, req In
 		}
 
 		// Update student_lead registration_fee_status
-		_, err = tx.Exec("UPDATE student_lead SET registration_fee_status = $1 WHERE id = $2", PaymentStatusPending, studentID)
+		_, err = tx.ExecContext(ctx, "UPDATE student_lead SET registration_fee_status = $1 WHERE id = $2", PaymentStatusPending, studentID)
 		if err != nil {
 			return fmt.Errorf("error updating registration fee status: %w", err)
 		}
@@ -192,7 +253,7 @@ func (s *PaymentService) SavePaymentRecord(studentID int, orderID string, req In
 		// Check if course payment already exists for this student+course
 		var existingPaymentID int
 		var existingStatus string
-		err = tx.QueryRow("SELECT id, status FROM course_payment WHERE student_id = $1 AND course_id = $2", studentID, *req.CourseID).Scan(&existingPaymentID, &existingStatus)
+		err = tx.QueryRowContext(ctx, "SELECT id, status FROM course_payment WHERE student_id = $1 AND course_id = $2", studentID, *req.CourseID).Scan(&existingPaymentID, &existingStatus)
 
 		if err == nil {
 			// Payment already exists
@@ -202,26 +263,26 @@ func (s *PaymentService) SavePaymentRecord(studentID int, orderID string, req In
 			}
 			if existingStatus == PaymentStatusFailed || existingStatus == PaymentStatusCancelled {
 				// Can retry failed/cancelled payment
-				_, err = tx.Exec(
-					"UPDATE course_payment SET order_id = $1, amount = $2, status = $3, payment_id = NULL, razorpay_sign = NULL, updated_at = CURRENT_TIMESTAMP WHERE student_id = $4 AND course_id = $5",
-					orderID, req.Amount, PaymentStatusPending, studentID, *req.CourseID)
+				_, err = tx.ExecContext(ctx,
+					"UPDATE course_payment SET order_id = $1, amount = $2, status = $3, payment_id = NULL, razorpay_sign = NULL, coupon_id = $6, coupon_code = $7, discount_amount = $8, updated_at = CURRENT_TIMESTAMP WHERE student_id = $4 AND course_id = $5",
+					orderID, req.Amount, PaymentStatusPending, studentID, *req.CourseID, req.CouponID, couponCode, req.DiscountAmount)
 				if err != nil {
 					return fmt.Errorf("error updating failed course payment: %w", err)
 				}
 			} else if existingStatus == PaymentStatusPending {
 				// Update existing PENDING payment with new order_id (retry)
-				_, err = tx.Exec(
-					"UPDATE course_payment SET order_id = $1, amount = $2, updated_at = CURRENT_TIMESTAMP WHERE student_id = $3 AND course_id = $4",
-					orderID, req.Amount, studentID, *req.CourseID)
+				_, err = tx.ExecContext(ctx,
+					"UPDATE course_payment SET order_id = $1, amount = $2, coupon_id = $5, coupon_code = $6, discount_amount = $7, updated_at = CURRENT_TIMESTAMP WHERE student_id = $3 AND course_id = $4",
+					orderID, req.Amount, studentID, *req.CourseID, req.CouponID, couponCode, req.DiscountAmount)
 				if err != nil {
 					return fmt.Errorf("error updating pending course payment: %w", err)
 				}
 			}
 		} else if err == sql.ErrNoRows {
 			// No existing payment, insert new one
-			_, err = tx.Exec(
-				"INSERT INTO course_payment (student_id, course_id, amount, status, order_id) VALUES ($1, $2, $3, $4, $5)",
-				studentID, *req.CourseID, req.Amount, PaymentStatusPending, orderID)
+			_, err = tx.ExecContext(ctx,
+				"INSERT INTO course_payment (student_id, course_id, amount, status, order_id, coupon_id, coupon_code, discount_amount) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+				studentID, *req.CourseID, req.Amount, PaymentStatusPending, orderID, req.CouponID, couponCode, req.DiscountAmount)
 			if err != nil {
 				return fmt.Errorf("error saving course payment: %w", err)
 			}
@@ -229,8 +290,12 @@ func (s *PaymentService) SavePaymentRecord(studentID int, orderID string, req In
 			return fmt.Errorf("error checking existing course payment: %w", err)
 		}
 
+		if invoice, err = issueCourseFeeInvoice(ctx, tx, orderID); err != nil {
+			return err
+		}
+
 		// Update student_lead course_fee_status
-		_, err = tx.Exec("UPDATE student_lead SET course_fee_status = $1 WHERE id = $2", PaymentStatusPending, studentID)
+		_, err = tx.ExecContext(ctx, "UPDATE student_lead SET course_fee_status = $1 WHERE id = $2", PaymentStatusPending, studentID)
 		if err != nil {
 			// Not critical - continue
 			log.Printf("Warning: error updating course fee status: %v", err)
@@ -239,31 +304,42 @@ func (s *PaymentService) SavePaymentRecord(studentID int, orderID string, req In
 		return fmt.Errorf("invalid payment type: %s", req.PaymentType)
 	}
 
+	if err = enqueuePaymentInitiatedEvent(ctx, tx, studentID, orderID, req); err != nil {
+		return err
+	}
+
 	// Commit transaction
 	if err = tx.Commit(); err != nil {
 		return fmt.Errorf("error committing transaction: %w", err)
 	}
 
+	// The invoice document is rendered again on download if storing it fails here
+	if invoice != nil {
+		if err := storeInvoiceDocument(ctx, db.DB, invoice); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
 	return nil
 }
 
-// PublishPaymentInitiatedEvent publishes payment initiated event to Kafka
-func (s *PaymentService) PublishPaymentInitiatedEvent(studentID int, orderID string, req InitiatePaymentRequest) {
-	go func() {
-		evt := map[string]interface{}{
-			"event":        "payment.initiated",
-			"student_id":   studentID,
-			"order_id":     orderID,
-			"amount":       req.Amount,
-			"currency":     "INR",
-			"payment_type": req.PaymentType,
-			"status":       "PENDING",
-			"ts":           time.Now().UTC().Format(time.RFC3339),
-		}
-		if err := Publish("payments", fmt.Sprintf("student-%d", studentID), evt); err != nil {
-			// Silently fail - event publishing is non-critical
-		}
-	}()
+// enqueuePaymentInitiatedEvent writes payment.initiated to the event outbox
+func enqueuePaymentInitiatedEvent(ctx context.Context, tx *sql.Tx, studentID int, orderID string, req InitiatePaymentRequest) error {
+	evt := map[string]interface{}{
+		"event":        "payment.initiated",
+		"student_id":   studentID,
+		"order_id":     orderID,
+		"amount":       req.Amount,
+		"currency":     "INR",
+		"payment_type": req.PaymentType,
+		"status":       "PENDING",
+		"ts":           time.Now().UTC().Format(time.RFC3339),
+	}
+	if req.CouponID != nil {
+		evt["coupon_code"] = req.CouponCode
+		evt["discount_amount"] = req.DiscountAmount
+	}
+	return EnqueueEvent(ctx, tx, "payments", fmt.Sprintf("student-%d", studentID), evt)
 }
 
 // VerifyPaymentRequest represents payment verification request
@@ -284,92 +360,50 @@ type VerifyPaymentResult struct {
 
 // VerifyPayment verifies payment signature WITHOUT updating database
 // Database is updated ONLY when webhook arrives from Razorpay (payment.captured event)
-func (s *PaymentService) VerifyPayment(req VerifyPaymentRequest) (*VerifyPaymentResult, error) {
-	var studentID int
-	var paymentType string
-	var amount float64
-	var courseID *int
-	var email string
-
-	// Try registration_payment table first
-	err := db.DB.QueryRow("SELECT student_id, amount FROM registration_payment WHERE order_id = $1", req.OrderID).Scan(&studentID, &amount)
+func (s *PaymentService) VerifyPayment(ctx context.Context, req VerifyPaymentRequest) (*VerifyPaymentResult, error) {
+	ctx, cancel := db.WithTimeout(ctx)
+	defer cancel()
 
+	payment, err := s.payments.FindByOrderID(ctx, req.OrderID)
 	if err != nil {
-		// If not found in registration_payment, check course_payment
-		paymentType = PaymentTypeCourseFee
-		err = db.DB.QueryRow(
-			"SELECT student_id, course_id, amount FROM course_payment WHERE order_id = $1",
-			req.OrderID,
-		).Scan(&studentID, &courseID, &amount)
-
-		if err != nil {
-			return nil, fmt.Errorf("payment not found for order_id: %s", req.OrderID)
-		}
-
-	} else {
-		// Found in registration_payment
-		paymentType = PaymentTypeRegistration
+		return nil, fmt.Errorf("payment not found for order_id: %s", req.OrderID)
 	}
 
-	// Get student email
-	err = db.DB.QueryRow("SELECT email FROM student_lead WHERE id = $1", studentID).Scan(&email)
-	if err != nil {
-		// Email retrieval is optional
-	}
+	// Email retrieval is optional
+	_, email, _ := s.leads.FindContact(ctx, payment.StudentID)
 
 	return &VerifyPaymentResult{
-		StudentID:   studentID,
-		PaymentType: paymentType,
+		StudentID:   payment.StudentID,
+		PaymentType: payment.PaymentType,
 		Email:       email,
-		Amount:      amount,
-		CourseID:    courseID,
+		Amount:      payment.Amount,
+		CourseID:    payment.RelatedCourseID,
 	}, nil
 }
 
-// PublishPaymentVerifiedEvent publishes payment verified event to Kafka
-func (s *PaymentService) PublishPaymentVerifiedEvent(studentID int, orderID, paymentID, paymentType string) {
-	go func() {
-		evt := map[string]interface{}{
-			"event":        "payment.verified",
-			"student_id":   studentID,
-			"order_id":     orderID,
-			"payment_id":   paymentID,
-			"payment_type": paymentType,
-			"source":       "webhook",
-			"status":       "PAID",
-			"ts":           time.Now().UTC().Format(time.RFC3339),
-		}
-		if err := Publish("payments", fmt.Sprintf("student-%d", studentID), evt); err != nil {
-			log.Printf("Warning: failed to publish payment.verified event: %v", err)
-		}
-	}()
-}
-
 // IsRegistrationPayment checks if payment type is registration
 func (s *PaymentService) IsRegistrationPayment(paymentType string) bool {
 	return paymentType == PaymentTypeRegistration
 }
 
 // GetPaymentStatus retrieves the current payment status for a given order ID
-func (s *PaymentService) GetPaymentStatus(orderID string) (status string, paymentType string, studentID int, err error) {
-	// Try registration_payment first
-	err = db.DB.QueryRow("SELECT status, student_id FROM registration_payment WHERE order_id = $1", orderID).Scan(&status, &studentID)
-	if err == nil {
-		return status, PaymentTypeRegistration, studentID, nil
-	}
+func (s *PaymentService) GetPaymentStatus(ctx context.Context, orderID string) (status string, paymentType string, studentID int, err error) {
+	ctx, cancel := db.WithTimeout(ctx)
+	defer cancel()
 
-	// Try course_payment
-	err = db.DB.QueryRow("SELECT status, student_id FROM course_payment WHERE order_id = $1", orderID).Scan(&status, &studentID)
-	if err == nil {
-		return status, PaymentTypeCourseFee, studentID, nil
+	payment, err := s.payments.FindByOrderID(ctx, orderID)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("payment not found for order_id: %s", orderID)
 	}
-
-	return "", "", 0, fmt.Errorf("payment not found for order_id: %s", orderID)
+	return payment.Status, payment.PaymentType, payment.StudentID, nil
 }
 
 // ValidateStudentExists checks if student exists and returns student details
-func (s *PaymentService) ValidateStudentExists(studentID int) (name, email string, err error) {
-	err = db.DB.QueryRow("SELECT name, email FROM student_lead WHERE id = $1", studentID).Scan(&name, &email)
+func (s *PaymentService) ValidateStudentExists(ctx context.Context, studentID int) (name, email string, err error) {
+	ctx, cancel := db.WithTimeout(ctx)
+	defer cancel()
+
+	name, email, err = s.leads.FindContact(ctx, studentID)
 	if err != nil {
 		return "", "", fmt.Errorf("student not found with id: %d", studentID)
 	}
@@ -377,22 +411,26 @@ func (s *PaymentService) ValidateStudentExists(studentID int) (name, email strin
 }
 
 // CheckPaymentEligibility checks if student can make a payment
-func (s *PaymentService) CheckPaymentEligibility(studentID int, paymentType string, courseID *int) (canPay bool, reason string, err error) {
+func (s *PaymentService) CheckPaymentEligibility(ctx context.Context, studentID int, paymentType string, courseID *int) (canPay bool, reason string, err error) {
+	ctx, cancel := db.WithTimeout(ctx)
+	defer cancel()
+
 	// Check if student exists
-	var exists bool
-	err = db.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM student_lead WHERE id = $1)", studentID).Scan(&exists)
+	exists, err := s.leads.Exists(ctx, studentID)
 	if err != nil || !exists {
 		return false, "Student not found", err
 	}
 
 	if paymentType == PaymentTypeRegistration {
 		// Check if registration payment already paid
-		var status string
-		err = db.DB.QueryRow("SELECT status FROM registration_payment WHERE student_id = $1", studentID).Scan(&status)
+		status, err := s.payments.RegistrationStatus(ctx, studentID)
 		if err == nil {
 			if status == PaymentStatusPaid {
 				return false, "Registration payment already completed", nil
 			}
+			if isRefundedStatus(status) {
+				return false, "Registration payment was refunded", nil
+			}
 			// PENDING or FAILED - can retry
 			return true, "", nil
 		}
@@ -405,16 +443,16 @@ func (s *PaymentService) CheckPaymentEligibility(studentID int, paymentType stri
 			return false, "Course ID is required for course fee payment", nil
 		}
 
-		var courseExists bool
-		err = db.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM course WHERE id = $1)", *courseID).Scan(&courseExists)
-		if err != nil || !courseExists {
+		if _, err := s.courses.FindByID(ctx, *courseID); err != nil {
+			if errors.Is(err, ErrCourseNotFound) {
+				return false, "Course not found", nil
+			}
 			return false, "Course not found", err
 		}
 
 		// Check if registration fee is PAID (REQUIREMENT: Student cannot pay course fee until registration fee is paid)
-		var regPaymentStatus string
-		err = db.DB.QueryRow("SELECT status FROM registration_payment WHERE student_id = $1", studentID).Scan(&regPaymentStatus)
-		if err == sql.ErrNoRows {
+		regPaymentStatus, err := s.payments.RegistrationStatus(ctx, studentID)
+		if errors.Is(err, ErrPaymentNotFound) {
 			return false, "Registration payment not initiated. Please pay the registration fee first", nil
 		}
 		if err != nil {
@@ -425,12 +463,14 @@ func (s *PaymentService) CheckPaymentEligibility(studentID int, paymentType stri
 		}
 
 		// Check if course payment already paid
-		var status string
-		err = db.DB.QueryRow("SELECT status FROM course_payment WHERE student_id = $1 AND course_id = $2", studentID, *courseID).Scan(&status)
+		status, err := s.payments.CourseFeeStatus(ctx, studentID, *courseID)
 		if err == nil {
 			if status == PaymentStatusPaid {
 				return false, fmt.Sprintf("Course payment already completed for course %d", *courseID), nil
 			}
+			if isRefundedStatus(status) {
+				return false, fmt.Sprintf("Course payment for course %d was refunded", *courseID), nil
+			}
 			// PENDING or FAILED - can retry
 			return true, "", nil
 		}
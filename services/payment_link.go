@@ -0,0 +1,186 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/utils"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"time"
+)
+
+var (
+	// ErrNoPendingPayment is returned when a lead has no pending order to resend
+	ErrNoPendingPayment = errors.New("lead has no pending payment order")
+	// ErrResendLimitReached is returned when the daily resend budget for a lead is used up
+	ErrResendLimitReached = errors.New("payment link resend limit reached for today")
+)
+
+// PaymentLinkResendResult describes the payment link that was re-sent
+type PaymentLinkResendResult struct {
+	LeadID         int     `json:"lead_id"`
+	OrderID        string  `json:"order_id"`
+	PaymentType    string  `json:"payment_type"`
+	Amount         float64 `json:"amount"`
+	PaymentLink    string  `json:"payment_link"`
+	Channel        string  `json:"channel"`
+	ResendsToday   int     `json:"resends_today"`
+	RemainingToday int     `json:"remaining_today"`
+	InvoiceNumber  string  `json:"invoice_number,omitempty"` // course fee invoice attached to the email
+}
+
+// ResendPaymentLink re-sends the payment instructions of the lead's latest pending order.
+// Resends are limited to PaymentLinkResendsPerDay per lead in a rolling 24 hours, recorded
+// in payment_link_resend and published as payment.link_resent for the lead timeline.
+// Only email is supported as a channel; there is no SMS provider configured.
+func ResendPaymentLink(ctx context.Context, leadID int, counselorID *int64) (*PaymentLinkResendResult, error) {
+	var name, email string
+	err := db.DB.QueryRowContext(ctx,
+		"SELECT name, email FROM student_lead WHERE id = $1 AND deleted_at IS NULL", leadID).Scan(&name, utils.DecryptedPII(&email))
+	if err == sql.ErrNoRows {
+		return nil, ErrLeadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching lead: %w", err)
+	}
+
+	result := &PaymentLinkResendResult{LeadID: leadID, Channel: "EMAIL"}
+	err = db.DB.QueryRowContext(ctx, `
+		SELECT order_id, payment_type, amount FROM payments
+		WHERE student_id = $1 AND status = $2 AND order_id IS NOT NULL
+		ORDER BY updated_at DESC
+		LIMIT 1`,
+		leadID, PaymentStatusPending,
+	).Scan(&result.OrderID, &result.PaymentType, &result.Amount)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoPendingPayment
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching pending payment: %w", err)
+	}
+
+	limit := config.AppConfig.PaymentLinkResendsPerDay
+	if limit <= 0 {
+		limit = 3
+	}
+
+	// Count and record in one transaction so concurrent clicks cannot exceed the limit
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT id FROM student_lead WHERE id = $1 FOR UPDATE", leadID); err != nil {
+		return nil, fmt.Errorf("error locking lead: %w", err)
+	}
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM payment_link_resend
+		WHERE lead_id = $1 AND created_at > NOW() - INTERVAL '24 hours'`, leadID).Scan(&result.ResendsToday)
+	if err != nil {
+		return nil, fmt.Errorf("error checking resend limit: %w", err)
+	}
+	if result.ResendsToday >= limit {
+		return nil, ErrResendLimitReached
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO payment_link_resend (lead_id, order_id, payment_type, channel, counselor_id)
+		VALUES ($1, $2, $3, $4, $5)`,
+		leadID, result.OrderID, result.PaymentType, result.Channel, counselorID)
+	if err != nil {
+		return nil, fmt.Errorf("error recording resend: %w", err)
+	}
+
+	if err := enqueuePaymentLinkResentEvent(ctx, tx, result, counselorID); err != nil {
+		return nil, err
+	}
+
+	// Course fee reminders carry the invoice, issued now for orders that predate invoicing
+	var attachment []string
+	if result.PaymentType == PaymentTypeCourseFee {
+		invoice, err := issueCourseFeeInvoice(ctx, tx, result.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		result.InvoiceNumber = invoice.InvoiceNumber
+		if path := invoiceAttachment(ctx, tx, invoice); path != "" {
+			attachment = append(attachment, path)
+		}
+	}
+
+	result.PaymentLink = buildPaymentLink(leadID, result.OrderID)
+	if err := SendPaymentLinkEmail(name, email, result, attachment...); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	result.ResendsToday++
+	result.RemainingToday = limit - result.ResendsToday
+	return result, nil
+}
+
+// buildPaymentLink points the student at the payment page for an existing order
+func buildPaymentLink(leadID int, orderID string) string {
+	query := url.Values{}
+	query.Set("order_id", orderID)
+	query.Set("student_id", fmt.Sprintf("%d", leadID))
+	return config.AppConfig.PaymentPageURL + "?" + query.Encode()
+}
+
+// SendPaymentLinkEmail queues the payment instructions email via Kafka
+func SendPaymentLinkEmail(studentName, studentEmail string, link *PaymentLinkResendResult, attachment ...string) error {
+	if studentEmail == "" {
+		return fmt.Errorf("student email is required")
+	}
+
+	subject, body := buildPaymentLinkEmail(studentName, link)
+	return SendCategorizedEmail(EmailCategoryTransactional, studentEmail, subject, body, attachment...)
+}
+
+// buildPaymentLinkEmail renders the reminder carrying the link to a pending payment
+func buildPaymentLinkEmail(studentName string, link *PaymentLinkResendResult) (subject, body string) {
+	purpose := "registration fee"
+	if link.PaymentType == PaymentTypeCourseFee {
+		purpose = "course fee"
+	}
+	invoiceLine := ""
+	if link.InvoiceNumber != "" {
+		invoiceLine = fmt.Sprintf("\n    <p>Your invoice <strong>%s</strong> is attached.</p>", html.EscapeString(link.InvoiceNumber))
+	}
+
+	body = fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <p>Dear <strong>%s</strong>,</p>
+    <p>Your %s payment of <strong>INR %.2f</strong> is still pending.</p>
+    <p>Order reference: <strong>%s</strong></p>%s
+    <p><a href="%s" style="background-color: #4CAF50; color: white; padding: 10px 20px; text-decoration: none; border-radius: 5px;">Complete Payment</a></p>
+    <p>If you have already paid, please ignore this email.</p>
+</body>
+</html>`, html.EscapeString(studentName), purpose, link.Amount, html.EscapeString(link.OrderID), invoiceLine, html.EscapeString(link.PaymentLink))
+
+	return fmt.Sprintf("Reminder: complete your %s payment", purpose), body
+}
+
+// enqueuePaymentLinkResentEvent writes payment.link_resent to the event outbox (recorded in the lead timeline)
+func enqueuePaymentLinkResentEvent(ctx context.Context, tx *sql.Tx, result *PaymentLinkResendResult, counselorID *int64) error {
+	evt := map[string]interface{}{
+		"event":        "payment.link_resent",
+		"student_id":   result.LeadID,
+		"order_id":     result.OrderID,
+		"payment_type": result.PaymentType,
+		"channel":      result.Channel,
+		"counselor_id": counselorID,
+		"ts":           time.Now().UTC().Format(time.RFC3339),
+	}
+	return EnqueueEvent(ctx, tx, "payments", fmt.Sprintf("student-%d", result.LeadID), evt)
+}
@@ -0,0 +1,387 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/razorpay/razorpay-go"
+)
+
+var (
+	// ErrReconciliationUnavailable is returned when reconciliation runs without Razorpay credentials
+	ErrReconciliationUnavailable = errors.New("razorpay credentials not configured")
+	// ErrReconciliationIssueNotFound is returned when no reconciliation issue has the requested id
+	ErrReconciliationIssueNotFound = errors.New("reconciliation issue not found")
+)
+
+const (
+	// reconciliationGrace leaves out the newest payments and orders, whose webhooks may
+	// still be on their way
+	reconciliationGrace = 15 * time.Minute
+	// razorpayPageSize is the largest page Razorpay's list APIs return
+	razorpayPageSize = 100
+)
+
+// reconciliationFinding is a discrepancy found in one run, before it is stored
+type reconciliationFinding struct {
+	issueType      string
+	orderID        string
+	paymentID      string
+	razorpayStatus string
+	razorpayAmount float64
+	payment        *models.Payment // the payment row of the order, nil for an orphaned order
+	detail         string
+}
+
+// ReconcilePayments is the payment-reconciliation job. Without Razorpay credentials there
+// is nothing to reconcile against.
+func ReconcilePayments(ctx context.Context) error {
+	_, err := RunPaymentReconciliation(ctx, time.Now())
+	if errors.Is(err, ErrReconciliationUnavailable) {
+		return nil
+	}
+	return err
+}
+
+// RunPaymentReconciliation compares the payments and orders Razorpay lists for the last
+// PAYMENT_RECONCILIATION_WINDOW_HOURS before now with the payment tables, records the
+// discrepancies as issues and resolves the open issues the payment rows now agree with
+func RunPaymentReconciliation(ctx context.Context, now time.Time) (*models.PaymentReconciliationRun, error) {
+	if db.DB == nil {
+		return nil, nil
+	}
+	if config.AppConfig.RazorpayKeyID == "" || config.AppConfig.RazorpayKeySecret == "" {
+		return nil, ErrReconciliationUnavailable
+	}
+
+	run := &models.PaymentReconciliationRun{
+		WindowStart: now.Add(-time.Duration(config.AppConfig.PaymentReconciliationWindowHours) * time.Hour),
+		WindowEnd:   now.Add(-reconciliationGrace),
+		StartedAt:   time.Now(),
+	}
+	err := db.DB.QueryRowContext(ctx, `
+		INSERT INTO payment_reconciliation_run (window_start, window_end, started_at) VALUES ($1, $2, $3)
+		RETURNING id`, run.WindowStart, run.WindowEnd, run.StartedAt).Scan(&run.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error recording reconciliation run: %w", err)
+	}
+
+	runErr := reconcileWindow(ctx, run)
+	if runErr != nil {
+		run.ErrorMessage = runErr.Error()
+		logger.Error("Payment reconciliation run %d failed: %v", run.ID, runErr)
+	} else if run.IssuesFound > 0 {
+		logger.Warn("Payment reconciliation run %d: %d discrepancies in %d payments and %d orders",
+			run.ID, run.IssuesFound, run.PaymentsChecked, run.OrdersChecked)
+	} else {
+		logger.Info("Payment reconciliation run %d: %d payments and %d orders match",
+			run.ID, run.PaymentsChecked, run.OrdersChecked)
+	}
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+
+	_, err = db.DB.ExecContext(ctx, `
+		UPDATE payment_reconciliation_run
+		SET payments_checked = $1, orders_checked = $2, issues_found = $3, error_message = NULLIF($4, ''), finished_at = $5
+		WHERE id = $6`,
+		run.PaymentsChecked, run.OrdersChecked, run.IssuesFound, run.ErrorMessage, finishedAt, run.ID)
+	if err != nil {
+		return run, fmt.Errorf("error recording reconciliation run: %w", err)
+	}
+	return run, runErr
+}
+
+// reconcileWindow checks the captured payments and the paid orders of the run's window.
+// An order is reported once per issue type even when both its payment and the order
+// show the discrepancy.
+func reconcileWindow(ctx context.Context, run *models.PaymentReconciliationRun) error {
+	client := razorpay.NewClient(config.AppConfig.RazorpayKeyID, config.AppConfig.RazorpayKeySecret)
+	payments, err := listRazorpayCollection(ctx, client.Payment.All, run.WindowStart, run.WindowEnd)
+	if err != nil {
+		return fmt.Errorf("error listing razorpay payments: %w", err)
+	}
+	orders, err := listRazorpayCollection(ctx, client.Order.All, run.WindowStart, run.WindowEnd)
+	if err != nil {
+		return fmt.Errorf("error listing razorpay orders: %w", err)
+	}
+	run.PaymentsChecked, run.OrdersChecked = len(payments), len(orders)
+
+	repo := NewPaymentRepository(db.DB)
+	findings := map[string]reconciliationFinding{}
+	check := func(orderID, paymentID, status string, amount float64, what string) error {
+		payment, err := repo.FindByOrderID(ctx, orderID)
+		if err != nil && !errors.Is(err, ErrPaymentNotFound) {
+			return err
+		}
+		for _, f := range compareWithRazorpay(payment, orderID, paymentID, status, amount, what) {
+			if _, seen := findings[f.issueType+"/"+f.orderID]; !seen {
+				findings[f.issueType+"/"+f.orderID] = f
+			}
+		}
+		return nil
+	}
+
+	for _, p := range payments {
+		captured, _ := p["captured"].(bool)
+		orderID := razorpayString(p, "order_id")
+		if !captured || orderID == "" {
+			continue
+		}
+		if err := check(orderID, razorpayString(p, "id"), razorpayString(p, "status"), razorpayRupees(p, "amount"), "payment"); err != nil {
+			return err
+		}
+	}
+	for _, o := range orders {
+		if razorpayString(o, "status") != "paid" {
+			continue
+		}
+		if err := check(razorpayString(o, "id"), "", "paid", razorpayRupees(o, "amount_paid"), "order"); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range findings {
+		if err := recordReconciliationIssue(ctx, run.ID, f); err != nil {
+			return err
+		}
+	}
+	run.IssuesFound = len(findings)
+	return resolveReconciledIssues(ctx)
+}
+
+// compareWithRazorpay returns the discrepancies between a payment row (nil when the order
+// has none) and what Razorpay collected for its order
+func compareWithRazorpay(payment *models.Payment, orderID, paymentID, status string, amount float64, what string) []reconciliationFinding {
+	finding := reconciliationFinding{orderID: orderID, paymentID: paymentID, razorpayStatus: status, razorpayAmount: amount, payment: payment}
+	if payment == nil {
+		finding.issueType = models.ReconciliationOrphanedOrder
+		finding.detail = fmt.Sprintf("Razorpay %s of Rs. %.2f was collected for an order with no payment row, e.g. one replaced by a retry or made outside the app", what, amount)
+		return []reconciliationFinding{finding}
+	}
+
+	var found []reconciliationFinding
+	if payment.Status != PaymentStatusPaid && !isRefundedStatus(payment.Status) {
+		f := finding
+		f.issueType = models.ReconciliationMissingWebhook
+		f.detail = fmt.Sprintf("Razorpay %s is %s but the payment row is still %s; the capture webhook was not processed", what, status, payment.Status)
+		found = append(found, f)
+	}
+	if math.Abs(payment.Amount-amount) >= 0.01 {
+		f := finding
+		f.issueType = models.ReconciliationAmountMismatch
+		f.detail = fmt.Sprintf("Razorpay %s collected Rs. %.2f but the payment row is for Rs. %.2f", what, amount, payment.Amount)
+		found = append(found, f)
+	}
+	return found
+}
+
+// listRazorpayCollection pages through a Razorpay list API for the entities created
+// between from and to
+func listRazorpayCollection(ctx context.Context, list func(map[string]interface{}, map[string]string) (map[string]interface{}, error), from, to time.Time) ([]map[string]interface{}, error) {
+	items := []map[string]interface{}{}
+	for skip := 0; ; skip += razorpayPageSize {
+		// The Razorpay client cannot be cancelled, so stop between pages
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		resp, err := list(map[string]interface{}{
+			"from":  from.Unix(),
+			"to":    to.Unix(),
+			"count": razorpayPageSize,
+			"skip":  skip,
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		page, _ := resp["items"].([]interface{})
+		for _, item := range page {
+			if entity, ok := item.(map[string]interface{}); ok {
+				items = append(items, entity)
+			}
+		}
+		if len(page) < razorpayPageSize {
+			return items, nil
+		}
+	}
+}
+
+func razorpayString(entity map[string]interface{}, key string) string {
+	s, _ := entity[key].(string)
+	return s
+}
+
+// razorpayRupees reads an amount Razorpay gives in paise
+func razorpayRupees(entity map[string]interface{}, key string) float64 {
+	paise, _ := entity[key].(float64)
+	return roundAmount(paise / 100)
+}
+
+// recordReconciliationIssue stores a finding, or refreshes the issue already open for its
+// type and order. A resolved issue stays resolved.
+func recordReconciliationIssue(ctx context.Context, runID int, f reconciliationFinding) error {
+	var paymentType, recordedStatus sql.NullString
+	var studentID sql.NullInt64
+	var recordedAmount sql.NullFloat64
+	if f.payment != nil {
+		paymentType = sql.NullString{String: f.payment.PaymentType, Valid: true}
+		recordedStatus = sql.NullString{String: f.payment.Status, Valid: true}
+		studentID = sql.NullInt64{Int64: int64(f.payment.StudentID), Valid: true}
+		recordedAmount = sql.NullFloat64{Float64: f.payment.Amount, Valid: true}
+	}
+	_, err := db.DB.ExecContext(ctx, `
+		INSERT INTO payment_reconciliation_issue (issue_type, order_id, payment_id, payment_type, student_id,
+			razorpay_status, razorpay_amount, recorded_status, recorded_amount, detail, run_id)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (issue_type, order_id) DO UPDATE SET
+			payment_id = COALESCE(EXCLUDED.payment_id, payment_reconciliation_issue.payment_id),
+			payment_type = EXCLUDED.payment_type, student_id = EXCLUDED.student_id,
+			razorpay_status = EXCLUDED.razorpay_status, razorpay_amount = EXCLUDED.razorpay_amount,
+			recorded_status = EXCLUDED.recorded_status, recorded_amount = EXCLUDED.recorded_amount,
+			detail = EXCLUDED.detail, run_id = EXCLUDED.run_id, last_seen_at = NOW()`,
+		f.issueType, f.orderID, f.paymentID, paymentType, studentID,
+		f.razorpayStatus, f.razorpayAmount, recordedStatus, recordedAmount, f.detail, runID)
+	if err != nil {
+		return fmt.Errorf("error recording reconciliation issue for order %s: %w", f.orderID, err)
+	}
+	return nil
+}
+
+// resolveReconciledIssues resolves the open issues whose payment row now agrees with
+// Razorpay: paid (or refunded) after a late webhook, for the collected amount, or recorded
+func resolveReconciledIssues(ctx context.Context) error {
+	_, err := db.DB.ExecContext(ctx, `
+		UPDATE payment_reconciliation_issue i
+		SET resolved_at = NOW(), resolution_note = 'payment row now agrees with Razorpay'
+		FROM payments p
+		WHERE i.resolved_at IS NULL AND p.order_id = i.order_id AND (
+			(i.issue_type = $1 AND p.status IN ($4, $5, $6))
+			OR (i.issue_type = $2 AND p.amount = i.razorpay_amount)
+			OR i.issue_type = $3)`,
+		models.ReconciliationMissingWebhook, models.ReconciliationAmountMismatch, models.ReconciliationOrphanedOrder,
+		PaymentStatusPaid, PaymentStatusRefunded, PaymentStatusPartiallyRefunded)
+	if err != nil {
+		return fmt.Errorf("error resolving reconciliation issues: %w", err)
+	}
+	return nil
+}
+
+const reconciliationIssueColumns = `id, issue_type, order_id, COALESCE(payment_id, ''), COALESCE(payment_type, ''), student_id,
+	COALESCE(razorpay_status, ''), razorpay_amount, COALESCE(recorded_status, ''), recorded_amount, detail,
+	first_seen_at, last_seen_at, resolved_at, COALESCE(resolution_note, '')`
+
+func scanReconciliationIssue(row interface{ Scan(...interface{}) error }) (*models.PaymentReconciliationIssue, error) {
+	var issue models.PaymentReconciliationIssue
+	var studentID sql.NullInt64
+	var razorpayAmount, recordedAmount sql.NullFloat64
+	var resolvedAt sql.NullTime
+	err := row.Scan(&issue.ID, &issue.IssueType, &issue.OrderID, &issue.PaymentID, &issue.PaymentType, &studentID,
+		&issue.RazorpayStatus, &razorpayAmount, &issue.RecordedStatus, &recordedAmount, &issue.Detail,
+		&issue.FirstSeenAt, &issue.LastSeenAt, &resolvedAt, &issue.ResolutionNote)
+	if err != nil {
+		return nil, err
+	}
+	if studentID.Valid {
+		id := int(studentID.Int64)
+		issue.StudentID = &id
+	}
+	if razorpayAmount.Valid {
+		issue.RazorpayAmount = &razorpayAmount.Float64
+	}
+	if recordedAmount.Valid {
+		issue.RecordedAmount = &recordedAmount.Float64
+	}
+	if resolvedAt.Valid {
+		issue.ResolvedAt = &resolvedAt.Time
+	}
+	return &issue, nil
+}
+
+// GetPaymentReconciliationReport returns the latest reconciliation run, the number of open
+// issues by type and up to limit issues, newest first: open, resolved or all of them
+func GetPaymentReconciliationReport(ctx context.Context, status string, limit int) (*models.PaymentReconciliationReport, error) {
+	report := &models.PaymentReconciliationReport{OpenCounts: map[string]int{}, Issues: []models.PaymentReconciliationIssue{}}
+
+	var run models.PaymentReconciliationRun
+	var finishedAt sql.NullTime
+	err := db.DB.QueryRowContext(ctx, `
+		SELECT id, window_start, window_end, payments_checked, orders_checked, issues_found,
+			COALESCE(error_message, ''), started_at, finished_at
+		FROM payment_reconciliation_run
+		ORDER BY started_at DESC
+		LIMIT 1`).Scan(&run.ID, &run.WindowStart, &run.WindowEnd, &run.PaymentsChecked, &run.OrdersChecked,
+		&run.IssuesFound, &run.ErrorMessage, &run.StartedAt, &finishedAt)
+	switch {
+	case err == nil:
+		if finishedAt.Valid {
+			run.FinishedAt = &finishedAt.Time
+		}
+		report.LastRun = &run
+	case err != sql.ErrNoRows:
+		return nil, fmt.Errorf("error fetching reconciliation run: %w", err)
+	}
+
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT issue_type, COUNT(*) FROM payment_reconciliation_issue WHERE resolved_at IS NULL GROUP BY issue_type`)
+	if err != nil {
+		return nil, fmt.Errorf("error counting reconciliation issues: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var issueType string
+		var count int
+		if err := rows.Scan(&issueType, &count); err != nil {
+			return nil, fmt.Errorf("error counting reconciliation issues: %w", err)
+		}
+		report.OpenCounts[issueType] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	where := ""
+	switch status {
+	case "open":
+		where = "WHERE resolved_at IS NULL"
+	case "resolved":
+		where = "WHERE resolved_at IS NOT NULL"
+	}
+	issues, err := db.DB.QueryContext(ctx, "SELECT "+reconciliationIssueColumns+" FROM payment_reconciliation_issue "+where+
+		" ORDER BY last_seen_at DESC, id DESC LIMIT $1", limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching reconciliation issues: %w", err)
+	}
+	defer issues.Close()
+	for issues.Next() {
+		issue, err := scanReconciliationIssue(issues)
+		if err != nil {
+			return nil, fmt.Errorf("error reading reconciliation issues: %w", err)
+		}
+		report.Issues = append(report.Issues, *issue)
+	}
+	return report, issues.Err()
+}
+
+// ResolveReconciliationIssue closes an issue by hand, e.g. once a payment on a replaced
+// order has been refunded, with a note of what was done
+func ResolveReconciliationIssue(ctx context.Context, id int, note string) (*models.PaymentReconciliationIssue, error) {
+	issue, err := scanReconciliationIssue(db.DB.QueryRowContext(ctx, `
+		UPDATE payment_reconciliation_issue
+		SET resolved_at = COALESCE(resolved_at, NOW()), resolution_note = NULLIF($1, '')
+		WHERE id = $2
+		RETURNING `+reconciliationIssueColumns, note, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrReconciliationIssueNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error resolving reconciliation issue: %w", err)
+	}
+	return issue, nil
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/razorpay/razorpay-go"
+)
+
+var (
+	// ErrPaymentNotRefundable is returned when refunding a payment that was never captured
+	ErrPaymentNotRefundable = errors.New("only paid payments can be refunded")
+	// ErrRefundExceedsPayment is returned when a refund is larger than what is left to refund
+	ErrRefundExceedsPayment = errors.New("refund amount exceeds the amount left to refund")
+)
+
+// RefundPaymentRequest asks for a refund of a captured payment. An Amount of 0 refunds
+// everything not yet refunded.
+type RefundPaymentRequest struct {
+	OrderID string
+	Amount  float64
+	Reason  string
+}
+
+// isRefundedStatus reports whether a payment in status has been refunded, in whole or in part
+func isRefundedStatus(status string) bool {
+	return status == PaymentStatusRefunded || status == PaymentStatusPartiallyRefunded
+}
+
+// RefundPayment refunds a captured payment through Razorpay and records the refund on the
+// payment, in one transaction with the fee status of the lead, the reversal of the
+// counselor's commission on a course fee, the payment.refunded event and audit, whose
+// Changes are filled in with the refund. A payment can be refunded in several parts until
+// its whole amount is.
+func RefundPayment(ctx context.Context, req RefundPaymentRequest, audit *models.AuditLogEntry) (*models.PaymentRefund, error) {
+	payment, err := NewPaymentRepository(db.DB).FindByOrderID(ctx, req.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if (payment.Status != PaymentStatusPaid && payment.Status != PaymentStatusPartiallyRefunded) || payment.PaymentID == "" {
+		return nil, ErrPaymentNotRefundable
+	}
+	remaining := roundAmount(payment.Amount - payment.RefundAmount)
+	amount := roundAmount(req.Amount)
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount > remaining || amount <= 0 {
+		return nil, ErrRefundExceedsPayment
+	}
+
+	refund, err := createRazorpayRefund(ctx, payment, amount, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	// Razorpay has refunded the money from here on, so failures are logged with the refund
+	// ID for the finance team to record it by hand
+	if err := recordRefund(ctx, payment, refund, req.Reason, audit); err != nil {
+		logger.FromContext(ctx).Error("Refund %s of order %s was made but not recorded: %v",
+			refund.RefundID, payment.OrderID, err)
+		return nil, fmt.Errorf("refund %s was made but not recorded: %w", refund.RefundID, err)
+	}
+	logger.FromContext(ctx).Info("Refunded %.2f of order %s (refund %s, %s)",
+		refund.Amount, refund.OrderID, refund.RefundID, refund.RefundStatus)
+	return refund, nil
+}
+
+// createRazorpayRefund asks Razorpay to refund amount of payment. The Razorpay client cannot
+// be cancelled, so no refund is made once ctx is done.
+func createRazorpayRefund(ctx context.Context, payment *models.Payment, amount float64, reason string) (*models.PaymentRefund, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if config.AppConfig.RazorpayKeyID == "" || config.AppConfig.RazorpayKeySecret == "" {
+		return nil, fmt.Errorf("razorpay credentials not configured")
+	}
+	client := razorpay.NewClient(config.AppConfig.RazorpayKeyID, config.AppConfig.RazorpayKeySecret)
+
+	data := map[string]interface{}{
+		"notes": map[string]interface{}{
+			"order_id":     payment.OrderID,
+			"payment_type": payment.PaymentType,
+			"reason":       reason,
+		},
+	}
+	resp, err := client.Payment.Refund(payment.PaymentID, int(math.Round(amount*100)), data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating razorpay refund: %w", err)
+	}
+	refundID, _ := resp["id"].(string)
+	if refundID == "" {
+		return nil, fmt.Errorf("razorpay refund response has no id")
+	}
+	status, _ := resp["status"].(string)
+
+	return &models.PaymentRefund{
+		RefundID:     refundID,
+		RefundStatus: status,
+		StudentID:    payment.StudentID,
+		OrderID:      payment.OrderID,
+		PaymentID:    payment.PaymentID,
+		PaymentType:  payment.PaymentType,
+		Amount:       amount,
+		RefundedAt:   time.Now(),
+	}, nil
+}
+
+// recordRefund stores refund on its payment and fills in its totals and the new status
+func recordRefund(ctx context.Context, payment *models.Payment, refund *models.PaymentRefund, reason string, audit *models.AuditLogEntry) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	applied, err := applyRefund(ctx, tx, payment, refund, reason, audit.Actor)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		// The refund.processed webhook arrived first and recorded the refund
+		table, _ := paymentTable(payment.PaymentType)
+		err := tx.QueryRowContext(ctx, "SELECT COALESCE(refund_amount, 0), status FROM "+table+" WHERE id = $1",
+			payment.ID).Scan(&refund.TotalRefunded, &refund.PaymentStatus)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", table, err)
+		}
+	}
+
+	if audit.Changes == nil {
+		audit.Changes = models.AuditChanges{}
+	}
+	audit.Changes.Add("status", payment.Status, refund.PaymentStatus)
+	audit.Changes.Add("refund_amount", payment.RefundAmount, refund.TotalRefunded)
+	var previousRefundID interface{}
+	if payment.RefundID != "" {
+		previousRefundID = payment.RefundID
+	}
+	audit.Changes.Add("refund_id", previousRefundID, refund.RefundID)
+	if err := recordAudit(ctx, tx, audit); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// applyRefund records refund in payment_refund within tx and, unless it was recorded
+// before, adds it to its payment: the refunded total and status, the lead's fee status,
+// the reversal of the counselor's commission on a course fee and the payment.refunded
+// event. It fills in refund's totals and reports whether the refund was new.
+func applyRefund(ctx context.Context, tx *sql.Tx, payment *models.Payment, refund *models.PaymentRefund, reason, actor string) (bool, error) {
+	table, err := paymentTable(payment.PaymentType)
+	if err != nil {
+		return false, err
+	}
+	feeStatusColumn := "registration_fee_status"
+	if payment.PaymentType == PaymentTypeCourseFee {
+		feeStatusColumn = "course_fee_status"
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO payment_refund (refund_id, order_id, payment_type, amount, status, reason)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))
+		ON CONFLICT (refund_id) DO NOTHING`,
+		refund.RefundID, payment.OrderID, payment.PaymentType, refund.Amount, refund.RefundStatus, reason)
+	if err != nil {
+		return false, fmt.Errorf("error recording refund %s: %w", refund.RefundID, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	// Totals add up in SQL so that concurrent refunds of one payment are all counted
+	err = tx.QueryRowContext(ctx, `
+		UPDATE `+table+`
+		SET refund_id = $1, refund_amount = COALESCE(refund_amount, 0) + $2, refunded_at = $3,
+			status = CASE WHEN COALESCE(refund_amount, 0) + $2 >= amount THEN $4 ELSE $5 END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6
+		RETURNING refund_amount, status`,
+		refund.RefundID, refund.Amount, refund.RefundedAt, PaymentStatusRefunded, PaymentStatusPartiallyRefunded, payment.ID,
+	).Scan(&refund.TotalRefunded, &refund.PaymentStatus)
+	if err != nil {
+		return false, fmt.Errorf("error updating %s: %w", table, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE student_lead SET "+feeStatusColumn+" = $1, updated_at = NOW() WHERE id = $2",
+		refund.PaymentStatus, payment.StudentID)
+	if err != nil {
+		return false, fmt.Errorf("error updating %s: %w", feeStatusColumn, err)
+	}
+
+	// The counselor's commission on a course fee shrinks with the refunded share
+	if payment.PaymentType == PaymentTypeCourseFee {
+		entry, err := reverseCommission(ctx, tx, payment.ID, refund.Amount, "Refund "+refund.RefundID+": "+reason, actor)
+		switch {
+		case err == nil:
+			_, err = tx.ExecContext(ctx, "UPDATE payment_refund SET commission_entry_id = $1 WHERE refund_id = $2",
+				entry.ID, refund.RefundID)
+			if err != nil {
+				return false, fmt.Errorf("error recording commission reversal of refund %s: %w", refund.RefundID, err)
+			}
+		case !errors.Is(err, ErrCommissionNotAccrued) && !errors.Is(err, ErrCommissionFullyReversed):
+			return false, err
+		}
+	}
+
+	evt := map[string]interface{}{
+		"event":          "payment.refunded",
+		"student_id":     payment.StudentID,
+		"order_id":       payment.OrderID,
+		"payment_id":     payment.PaymentID,
+		"refund_id":      refund.RefundID,
+		"amount":         refund.Amount,
+		"total_refunded": refund.TotalRefunded,
+		"currency":       "INR",
+		"payment_type":   payment.PaymentType,
+		"status":         refund.PaymentStatus,
+		"ts":             refund.RefundedAt.UTC().Format(time.RFC3339),
+	}
+	if err := EnqueueEvent(ctx, tx, "payments", fmt.Sprintf("student-%d", payment.StudentID), evt); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrPaymentNotFound is returned when no payment of either type has the requested order id
+var ErrPaymentNotFound = errors.New("payment not found")
+
+// PaymentRepository reads and updates registration and course fee payments
+type PaymentRepository interface {
+	// FindByOrderID returns the payment of either type created for a Razorpay order, or ErrPaymentNotFound
+	FindByOrderID(ctx context.Context, orderID string) (*models.Payment, error)
+	// FindByStudent returns all payments of a student, most recently updated first
+	FindByStudent(ctx context.Context, studentID int) ([]models.Payment, error)
+	// RegistrationStatus returns the status of the student's registration payment, or ErrPaymentNotFound
+	RegistrationStatus(ctx context.Context, studentID int) (string, error)
+	// CourseFeeStatus returns the status of the student's payment for a course, or ErrPaymentNotFound
+	CourseFeeStatus(ctx context.Context, studentID, courseID int) (string, error)
+	// MarkPaid records a captured payment
+	MarkPaid(ctx context.Context, p *models.Payment, paymentID, signature string) error
+	// MarkFailed records a failed payment
+	MarkFailed(ctx context.Context, p *models.Payment, paymentID, errorMsg string) error
+}
+
+// postgresPaymentRepository reads payments through the payments view and writes them
+// back to the table of their type
+type postgresPaymentRepository struct {
+	q querier
+}
+
+// NewPaymentRepository creates a payment repository over a database handle or transaction
+func NewPaymentRepository(q querier) PaymentRepository {
+	return &postgresPaymentRepository{q: q}
+}
+
+const paymentColumns = `id, payment_type, student_id, course_id, amount, COALESCE(status, ''),
+	COALESCE(order_id, ''), COALESCE(payment_id, ''), COALESCE(razorpay_sign, ''),
+	COALESCE(error_message, ''), timestamp, updated_at, COALESCE(refund_id, ''), COALESCE(refund_amount, 0), refunded_at,
+	COALESCE(dispute_id, ''), COALESCE(dispute_status, '')`
+
+func scanPayment(row interface{ Scan(...interface{}) error }) (*models.Payment, error) {
+	var p models.Payment
+	var courseID sql.NullInt64
+	var refundedAt sql.NullTime
+	if err := row.Scan(&p.ID, &p.PaymentType, &p.StudentID, &courseID, &p.Amount, &p.Status,
+		&p.OrderID, &p.PaymentID, &p.RazorpaySign, &p.ErrorMessage, &p.Timestamp, &p.UpdatedAt,
+		&p.RefundID, &p.RefundAmount, &refundedAt, &p.DisputeID, &p.DisputeStatus); err != nil {
+		return nil, err
+	}
+	if courseID.Valid {
+		id := int(courseID.Int64)
+		p.RelatedCourseID = &id
+	}
+	if refundedAt.Valid {
+		p.RefundedAt = &refundedAt.Time
+	}
+	return &p, nil
+}
+
+func (r *postgresPaymentRepository) FindByOrderID(ctx context.Context, orderID string) (*models.Payment, error) {
+	p, err := scanPayment(r.q.QueryRowContext(ctx,
+		"SELECT "+paymentColumns+" FROM payments WHERE order_id = $1", orderID))
+	if err == sql.ErrNoRows {
+		return nil, ErrPaymentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching payment for order %s: %w", orderID, err)
+	}
+	return p, nil
+}
+
+func (r *postgresPaymentRepository) FindByStudent(ctx context.Context, studentID int) ([]models.Payment, error) {
+	rows, err := r.q.QueryContext(ctx,
+		"SELECT "+paymentColumns+" FROM payments WHERE student_id = $1 ORDER BY updated_at DESC, id DESC", studentID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching payments for student %d: %w", studentID, err)
+	}
+	defer rows.Close()
+
+	payments := []models.Payment{}
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading payment: %w", err)
+		}
+		payments = append(payments, *p)
+	}
+	return payments, rows.Err()
+}
+
+func (r *postgresPaymentRepository) RegistrationStatus(ctx context.Context, studentID int) (string, error) {
+	var status string
+	err := r.q.QueryRowContext(ctx,
+		"SELECT COALESCE(status, '') FROM registration_payment WHERE student_id = $1", studentID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", ErrPaymentNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("error fetching registration payment of student %d: %w", studentID, err)
+	}
+	return status, nil
+}
+
+func (r *postgresPaymentRepository) CourseFeeStatus(ctx context.Context, studentID, courseID int) (string, error) {
+	var status string
+	err := r.q.QueryRowContext(ctx,
+		"SELECT COALESCE(status, '') FROM course_payment WHERE student_id = $1 AND course_id = $2", studentID, courseID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", ErrPaymentNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("error fetching course payment of student %d: %w", studentID, err)
+	}
+	return status, nil
+}
+
+// MarkPaid records a captured payment on the table of its type
+func (r *postgresPaymentRepository) MarkPaid(ctx context.Context, p *models.Payment, paymentID, signature string) error {
+	table, err := paymentTable(p.PaymentType)
+	if err != nil {
+		return err
+	}
+	_, err = r.q.ExecContext(ctx,
+		"UPDATE "+table+" SET status = $1, payment_id = $2, razorpay_sign = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $4",
+		PaymentStatusPaid, paymentID, signature, p.ID)
+	if err != nil {
+		return fmt.Errorf("error updating %s: %w", table, err)
+	}
+	p.Status = PaymentStatusPaid
+	p.PaymentID = paymentID
+	p.RazorpaySign = signature
+	return nil
+}
+
+// MarkFailed records a failed payment on the table of its type
+func (r *postgresPaymentRepository) MarkFailed(ctx context.Context, p *models.Payment, paymentID, errorMsg string) error {
+	table, err := paymentTable(p.PaymentType)
+	if err != nil {
+		return err
+	}
+	_, err = r.q.ExecContext(ctx,
+		"UPDATE "+table+" SET status = $1, payment_id = $2, error_message = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $4",
+		PaymentStatusFailed, paymentID, errorMsg, p.ID)
+	if err != nil {
+		return fmt.Errorf("error updating %s: %w", table, err)
+	}
+	p.Status = PaymentStatusFailed
+	p.PaymentID = paymentID
+	p.ErrorMessage = errorMsg
+	return nil
+}
+
+// paymentTable maps a payment type to the table its rows live in
+func paymentTable(paymentType string) (string, error) {
+	switch paymentType {
+	case PaymentTypeRegistration:
+		return "registration_payment", nil
+	case PaymentTypeCourseFee:
+		return "course_payment", nil
+	}
+	return "", fmt.Errorf("invalid payment type: %s", paymentType)
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pop3Client is a minimal POP3 (RFC 1939) client over TLS: enough to log in,
+// list, download and delete messages of the inbound import mailbox
+type pop3Client struct {
+	conn net.Conn
+	text *textproto.Conn
+}
+
+// dialPOP3 connects to a POP3S server (implicit TLS, usually port 995) and reads its greeting
+func dialPOP3(ctx context.Context, host string, port int) (*pop3Client, error) {
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: 15 * time.Second},
+		Config:    &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to POP3 server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c := &pop3Client{conn: conn, text: textproto.NewConn(conn)}
+	if _, err := c.readResponse(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected POP3 greeting: %w", err)
+	}
+	return c, nil
+}
+
+// readResponse reads a status line, returning the text after +OK or an error for -ERR
+func (c *pop3Client) readResponse() (string, error) {
+	line, err := c.text.ReadLine()
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case strings.HasPrefix(line, "+OK"):
+		return strings.TrimSpace(strings.TrimPrefix(line, "+OK")), nil
+	case strings.HasPrefix(line, "-ERR"):
+		return "", fmt.Errorf("POP3 error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+	default:
+		return "", fmt.Errorf("malformed POP3 response: %q", line)
+	}
+}
+
+// cmd sends a command and reads its status line
+func (c *pop3Client) cmd(format string, args ...interface{}) (string, error) {
+	if err := c.text.PrintfLine(format, args...); err != nil {
+		return "", err
+	}
+	return c.readResponse()
+}
+
+// login authenticates with USER/PASS
+func (c *pop3Client) login(user, password string) error {
+	if _, err := c.cmd("USER %s", user); err != nil {
+		return err
+	}
+	if _, err := c.cmd("PASS %s", password); err != nil {
+		return fmt.Errorf("POP3 login failed: %w", err)
+	}
+	return nil
+}
+
+// list returns the numbers of the messages in the mailbox
+func (c *pop3Client) list() ([]int, error) {
+	if _, err := c.cmd("LIST"); err != nil {
+		return nil, err
+	}
+	lines, err := c.text.ReadDotLines()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		id, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("malformed POP3 LIST line: %q", line)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// errPOP3MessageTooLarge is returned by retr for messages over its size limit
+var errPOP3MessageTooLarge = errors.New("message too large")
+
+// retr downloads a message of at most maxBytes
+func (c *pop3Client) retr(id int, maxBytes int64) ([]byte, error) {
+	if _, err := c.cmd("RETR %d", id); err != nil {
+		return nil, err
+	}
+	body := c.text.DotReader()
+	data, err := io.ReadAll(io.LimitReader(body, maxBytes))
+	if err != nil {
+		return nil, err
+	}
+	// Drain the rest of an oversized message so the connection stays usable
+	rest, err := io.Copy(io.Discard, body)
+	if err != nil {
+		return nil, err
+	}
+	if rest > 0 {
+		return nil, errPOP3MessageTooLarge
+	}
+	return data, nil
+}
+
+// dele marks a message for deletion; it is removed when the session ends with QUIT
+func (c *pop3Client) dele(id int) error {
+	_, err := c.cmd("DELE %d", id)
+	return err
+}
+
+// quit ends the session, committing deletions, and closes the connection
+func (c *pop3Client) quit() error {
+	_, err := c.cmd("QUIT")
+	c.conn.Close()
+	return err
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/models"
+	"context"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	processStartedAt  = time.Now()
+	migrationsApplied atomic.Bool
+	startupComplete   atomic.Bool
+)
+
+// MarkMigrationsApplied records that the schema is in place (db.InitDB returned)
+func MarkMigrationsApplied() {
+	migrationsApplied.Store(true)
+}
+
+// MarkStartupComplete records that every startup step ran and the service may take traffic
+func MarkStartupComplete() {
+	startupComplete.Store(true)
+}
+
+// StartupComplete reports whether MarkStartupComplete was called
+func StartupComplete() bool {
+	return startupComplete.Load()
+}
+
+// CheckReadiness reports whether the service may take traffic: schema migrations
+// applied, the Kafka consumer started (unless Kafka is disabled), startup finished and
+// the database answering. Every component is critical. A broker outage after startup
+// does not fail it: the consumer keeps running and reconnects on its own.
+func CheckReadiness(ctx context.Context) models.HealthReport {
+	report := models.HealthReport{Status: models.HealthStatusUp, CheckedAt: time.Now()}
+	add := func(name string, up bool, detail string) {
+		component := models.HealthComponent{Name: name, Status: models.HealthStatusUp, Critical: true}
+		if !up {
+			component.Status, component.Detail = models.HealthStatusDown, detail
+			report.Status = models.HealthStatusDown
+		}
+		report.Components = append(report.Components, component)
+	}
+
+	add("migrations", migrationsApplied.Load(), "schema migrations still running")
+
+	if strings.TrimSpace(config.AppConfig.KafkaBrokers) == "" {
+		report.Components = append(report.Components, models.HealthComponent{
+			Name: "kafka-consumer", Status: models.HealthStatusDisabled, Critical: true, Detail: "KAFKA_BROKERS is empty"})
+	} else {
+		add("kafka-consumer", IsConsumerRunning(), "consumer not started")
+	}
+
+	add("startup", startupComplete.Load(), "startup still running")
+
+	if migrationsApplied.Load() {
+		report.Components = append(report.Components, checkDatabaseHealth(ctx))
+		if report.Components[len(report.Components)-1].Status == models.HealthStatusDown {
+			report.Status = models.HealthStatusDown
+		}
+	}
+	return report
+}
+
+// Liveness describes the process itself; it deliberately checks no dependency, so an
+// outage of one never gets the pod restarted
+func Liveness() map[string]interface{} {
+	return map[string]interface{}{
+		"status":         models.HealthStatusUp,
+		"started":        startupComplete.Load(),
+		"uptime_seconds": int64(time.Since(processStartedAt).Seconds()),
+		"goroutines":     runtime.NumGoroutine(),
+	}
+}
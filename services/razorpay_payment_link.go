@@ -0,0 +1,466 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"admission-module/utils"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/razorpay/razorpay-go"
+)
+
+// Statuses of a Razorpay Payment Link, as stored in razorpay_payment_link
+const (
+	PaymentLinkStatusCreated   = "created"
+	PaymentLinkStatusPaid      = "paid"
+	PaymentLinkStatusExpired   = "expired"
+	PaymentLinkStatusCancelled = "cancelled"
+)
+
+// ErrPaymentNotAllowed is returned when a lead cannot be asked for a payment, e.g. a fee
+// that is already paid or a course fee before the registration fee
+var ErrPaymentNotAllowed = errors.New("payment not allowed")
+
+// errPaymentLinkUnknown is returned for webhooks of links not created by this service
+var errPaymentLinkUnknown = errors.New("payment link not found")
+
+// CreatePaymentLinkRequest asks for a Razorpay Payment Link for one of a lead's fees
+type CreatePaymentLinkRequest struct {
+	LeadID      int
+	PaymentType string
+	CourseID    *int
+	CounselorID *int64
+}
+
+// CreateRazorpayPaymentLink creates a Razorpay Payment Link for the lead's registration or
+// course fee and emails it, so that the student pays on Razorpay's page instead of our
+// checkout. The link is recorded in razorpay_payment_link and published as
+// payment.link_created for the lead timeline; the payment itself is recorded when the
+// payment_link.paid webhook arrives. Links expire after PaymentLinkExpiryDays.
+func CreateRazorpayPaymentLink(ctx context.Context, req CreatePaymentLinkRequest) (*models.RazorpayPaymentLink, error) {
+	var name, email, phone string
+	err := db.DB.QueryRowContext(ctx,
+		"SELECT name, email, phone FROM student_lead WHERE id = $1 AND deleted_at IS NULL", req.LeadID,
+	).Scan(&name, utils.DecryptedPII(&email), utils.DecryptedPII(&phone))
+	if err == sql.ErrNoRows {
+		return nil, ErrLeadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching lead: %w", err)
+	}
+	if email == "" {
+		return nil, fmt.Errorf("%w: lead has no email address", ErrPaymentNotAllowed)
+	}
+
+	payments := NewPaymentService()
+	canPay, reason, err := payments.CheckPaymentEligibility(ctx, req.LeadID, req.PaymentType, req.CourseID)
+	if err != nil {
+		return nil, err
+	}
+	if !canPay {
+		return nil, fmt.Errorf("%w: %s", ErrPaymentNotAllowed, reason)
+	}
+	prepared, err := payments.ValidateAndPreparePayment(ctx, InitiatePaymentRequest{
+		StudentID:   req.LeadID,
+		PaymentType: req.PaymentType,
+		CourseID:    req.CourseID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPaymentNotAllowed, err)
+	}
+
+	days := config.AppConfig.PaymentLinkExpiryDays
+	if days <= 0 {
+		days = 7
+	}
+	expiresAt := time.Now().Add(time.Duration(days) * 24 * time.Hour).Truncate(time.Second)
+
+	link, err := createRazorpayPaymentLink(ctx, prepared, name, email, phone, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	link.CounselorID = req.CounselorID
+
+	// Razorpay has created the link from here on; if it is not recorded, its payment will
+	// be acknowledged but not recorded, so the link is cancelled again
+	if err := recordPaymentLink(ctx, link, name, email); err != nil {
+		if cancelErr := cancelRazorpayPaymentLink(link.ID); cancelErr != nil {
+			logger.FromContext(ctx).Error("Payment link %s was created but neither recorded nor cancelled: %v", link.ID, cancelErr)
+		}
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Info("Created payment link %s for the %s of lead %d", link.ID, link.PaymentType, link.LeadID)
+	return link, nil
+}
+
+// createRazorpayPaymentLink asks Razorpay for a payment link for the prepared payment.
+// Razorpay's own notifications are turned off: the link is emailed by this service.
+func createRazorpayPaymentLink(ctx context.Context, req *InitiatePaymentRequest, name, email, phone string, expiresAt time.Time) (*models.RazorpayPaymentLink, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if config.AppConfig.RazorpayKeyID == "" || config.AppConfig.RazorpayKeySecret == "" {
+		return nil, fmt.Errorf("razorpay credentials not configured")
+	}
+	client := razorpay.NewClient(config.AppConfig.RazorpayKeyID, config.AppConfig.RazorpayKeySecret)
+
+	customer := map[string]interface{}{"name": name, "email": email}
+	if phone != "" {
+		customer["contact"] = phone
+	}
+	notes := map[string]interface{}{
+		"student_id":   req.StudentID,
+		"payment_type": req.PaymentType,
+	}
+	if req.CourseID != nil {
+		notes["course_id"] = *req.CourseID
+	}
+	data := map[string]interface{}{
+		"amount":          int(math.Round(req.Amount * 100)),
+		"currency":        "INR",
+		"accept_partial":  false,
+		"reference_id":    fmt.Sprintf("lead-%d-%d", req.StudentID, time.Now().UnixNano()),
+		"description":     paymentPurpose(req.PaymentType),
+		"customer":        customer,
+		"notify":          map[string]interface{}{"sms": false, "email": false},
+		"reminder_enable": false,
+		"expire_by":       expiresAt.Unix(),
+		"notes":           notes,
+	}
+
+	resp, err := client.PaymentLink.Create(data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating razorpay payment link: %w", err)
+	}
+	id, _ := resp["id"].(string)
+	shortURL, _ := resp["short_url"].(string)
+	if id == "" || shortURL == "" {
+		return nil, fmt.Errorf("razorpay payment link response has no id or short_url")
+	}
+
+	return &models.RazorpayPaymentLink{
+		ID:          id,
+		LeadID:      req.StudentID,
+		PaymentType: req.PaymentType,
+		CourseID:    req.CourseID,
+		Amount:      req.Amount,
+		ShortURL:    shortURL,
+		Status:      PaymentLinkStatusCreated,
+		ExpiresAt:   &expiresAt,
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// cancelRazorpayPaymentLink cancels a link in Razorpay so that it can no longer be paid
+func cancelRazorpayPaymentLink(id string) error {
+	client := razorpay.NewClient(config.AppConfig.RazorpayKeyID, config.AppConfig.RazorpayKeySecret)
+	if _, err := client.PaymentLink.Cancel(id, nil, nil); err != nil {
+		return fmt.Errorf("error cancelling razorpay payment link %s: %w", id, err)
+	}
+	return nil
+}
+
+// recordPaymentLink stores link with its payment.link_created event and emails it to the
+// student, in one transaction
+func recordPaymentLink(ctx context.Context, link *models.RazorpayPaymentLink, name, email string) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO razorpay_payment_link (id, lead_id, payment_type, course_id, amount, short_url, status, counselor_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING created_at`,
+		link.ID, link.LeadID, link.PaymentType, link.CourseID, link.Amount, link.ShortURL, link.Status,
+		link.CounselorID, link.ExpiresAt,
+	).Scan(&link.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error recording payment link: %w", err)
+	}
+
+	evt := map[string]interface{}{
+		"event":           "payment.link_created",
+		"student_id":      link.LeadID,
+		"payment_link_id": link.ID,
+		"payment_type":    link.PaymentType,
+		"amount":          link.Amount,
+		"currency":        "INR",
+		"counselor_id":    link.CounselorID,
+		"expires_at":      link.ExpiresAt.UTC().Format(time.RFC3339),
+		"ts":              time.Now().UTC().Format(time.RFC3339),
+	}
+	if err := EnqueueEvent(ctx, tx, "payments", fmt.Sprintf("student-%d", link.LeadID), evt); err != nil {
+		return err
+	}
+
+	subject, body := buildRazorpayPaymentLinkEmail(name, link)
+	if err := SendCategorizedEmail(EmailCategoryTransactional, email, subject, body); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// ListRazorpayPaymentLinks returns the payment links created for a lead, newest first
+func ListRazorpayPaymentLinks(ctx context.Context, leadID int) ([]models.RazorpayPaymentLink, error) {
+	var exists bool
+	if err := db.DB.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM student_lead WHERE id = $1 AND deleted_at IS NULL)",
+		leadID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("error fetching lead: %w", err)
+	}
+	if !exists {
+		return nil, ErrLeadNotFound
+	}
+
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT id, lead_id, payment_type, course_id, amount, short_url, status, COALESCE(order_id, ''),
+			COALESCE(payment_id, ''), COALESCE(error_message, ''), counselor_id, expires_at, paid_at, created_at
+		FROM razorpay_payment_link
+		WHERE lead_id = $1
+		ORDER BY created_at DESC`, leadID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching payment links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []models.RazorpayPaymentLink{}
+	for rows.Next() {
+		var l models.RazorpayPaymentLink
+		var courseID sql.NullInt64
+		var counselorID sql.NullInt64
+		var expiresAt, paidAt sql.NullTime
+		if err := rows.Scan(&l.ID, &l.LeadID, &l.PaymentType, &courseID, &l.Amount, &l.ShortURL, &l.Status,
+			&l.OrderID, &l.PaymentID, &l.ErrorMessage, &counselorID, &expiresAt, &paidAt, &l.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error reading payment link: %w", err)
+		}
+		if courseID.Valid {
+			id := int(courseID.Int64)
+			l.CourseID = &id
+		}
+		if counselorID.Valid {
+			l.CounselorID = &counselorID.Int64
+		}
+		if expiresAt.Valid {
+			l.ExpiresAt = &expiresAt.Time
+		}
+		if paidAt.Valid {
+			l.PaidAt = &paidAt.Time
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// paymentPurpose names the fee paid with paymentType, for emails and Razorpay
+func paymentPurpose(paymentType string) string {
+	if paymentType == PaymentTypeCourseFee {
+		return "Course fee"
+	}
+	return "Registration fee"
+}
+
+// buildRazorpayPaymentLinkEmail renders the email carrying a Razorpay Payment Link
+func buildRazorpayPaymentLinkEmail(studentName string, link *models.RazorpayPaymentLink) (subject, body string) {
+	purpose := paymentPurpose(link.PaymentType)
+	expiry := ""
+	if link.ExpiresAt != nil {
+		expiry = fmt.Sprintf("\n    <p>The link is valid until <strong>%s</strong>.</p>", link.ExpiresAt.Format("02 Jan 2006"))
+	}
+
+	body = fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <p>Dear <strong>%s</strong>,</p>
+    <p>Your counselor has sent you a secure Razorpay link to pay your %s of <strong>INR %.2f</strong>.</p>%s
+    <p><a href="%s" style="background-color: #4CAF50; color: white; padding: 10px 20px; text-decoration: none; border-radius: 5px;">Pay Now</a></p>
+    <p>If the button does not work, open this link: %s</p>
+</body>
+</html>`, html.EscapeString(studentName), purpose, link.Amount, expiry,
+		html.EscapeString(link.ShortURL), html.EscapeString(link.ShortURL))
+
+	return fmt.Sprintf("Pay your %s online", purpose), body
+}
+
+// handlePaymentLinkEvent handles payment_link.paid, payment_link.expired and
+// payment_link.cancelled. A paid link is recorded as the order of its fee, then captured
+// like a payment made on our checkout page.
+func handlePaymentLinkEvent(ctx context.Context, w http.ResponseWriter, payload RazorpayWebhookPayload, signature string) {
+	linkEntity, ok := webhookEntity(payload, "payment_link")
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid payment_link data structure"})
+		return
+	}
+	linkID, _ := linkEntity["id"].(string)
+	if linkID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Missing payment link id"})
+		return
+	}
+
+	var orderID, paymentID string
+	var err error
+	if payload.Event == "payment_link.paid" {
+		paymentEntity, ok := webhookEntity(payload, "payment")
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid payment data structure"})
+			return
+		}
+		paymentID, _ = paymentEntity["id"].(string)
+		orderID, _ = paymentEntity["order_id"].(string)
+		if paymentID == "" || orderID == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Missing payment_id or order_id"})
+			return
+		}
+		err = processPaymentLinkPaid(ctx, linkID, orderID, paymentID, signature)
+	} else {
+		status := PaymentLinkStatusExpired
+		if payload.Event == "payment_link.cancelled" {
+			status = PaymentLinkStatusCancelled
+		}
+		err = closePaymentLink(ctx, linkID, status)
+	}
+
+	if errors.Is(err, errPaymentLinkUnknown) {
+		// Links created in the Razorpay dashboard are not ours to record
+		logger.FromContext(ctx).Warn("Ignoring %s of unknown payment link %s", payload.Event, linkID)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": "ignored", "event": payload.Event})
+		return
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("Error processing %s of payment link %s: %v", payload.Event, linkID, err)
+		if updateErr := updateWebhookProcessingStatus(ctx, payload.ID, "FAILED", err.Error()); updateErr != nil {
+			log.Printf("Error updating webhook status: %v", updateErr)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	if updateErr := updateWebhookProcessingStatus(ctx, payload.ID, "COMPLETED", ""); updateErr != nil {
+		log.Printf("Error updating webhook status: %v", updateErr)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":          "processed",
+		"event":           payload.Event,
+		"payment_link_id": linkID,
+		"order_id":        orderID,
+		"payment_id":      paymentID,
+	})
+}
+
+// processPaymentLinkPaid records the order Razorpay created for a paid link as the order of
+// the link's fee and captures it. A fee paid by then some other way is not recorded twice:
+// the link is marked paid with an error for the finance team to refund the payment.
+// Redelivered webhooks change nothing.
+func processPaymentLinkPaid(ctx context.Context, linkID, orderID, paymentID, signature string) error {
+	var link models.RazorpayPaymentLink
+	var courseID sql.NullInt64
+	err := db.DB.QueryRowContext(ctx,
+		"SELECT lead_id, payment_type, course_id, amount, status FROM razorpay_payment_link WHERE id = $1", linkID,
+	).Scan(&link.LeadID, &link.PaymentType, &courseID, &link.Amount, &link.Status)
+	if err == sql.ErrNoRows {
+		return errPaymentLinkUnknown
+	}
+	if err != nil {
+		return fmt.Errorf("error fetching payment link %s: %w", linkID, err)
+	}
+	if link.Status == PaymentLinkStatusPaid {
+		return nil
+	}
+	if courseID.Valid {
+		id := int(courseID.Int64)
+		link.CourseID = &id
+	}
+
+	// On a redelivery the order may be recorded already, by an attempt that failed later on
+	var errorMessage string
+	_, err = NewPaymentRepository(db.DB).FindByOrderID(ctx, orderID)
+	switch {
+	case errors.Is(err, ErrPaymentNotFound):
+		canPay, reason, err := NewPaymentService().CheckPaymentEligibility(ctx, link.LeadID, link.PaymentType, link.CourseID)
+		if err != nil {
+			return err
+		}
+		if !canPay {
+			errorMessage = fmt.Sprintf("paid but not recorded: %s; payment %s needs a refund", reason, paymentID)
+			break
+		}
+		err = NewPaymentService().SavePaymentRecord(ctx, link.LeadID, orderID, InitiatePaymentRequest{
+			StudentID:   link.LeadID,
+			Amount:      link.Amount,
+			PaymentType: link.PaymentType,
+			CourseID:    link.CourseID,
+		})
+		if err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	}
+
+	if errorMessage == "" {
+		if err := processPaymentCaptured(ctx, orderID, paymentID, signature); err != nil {
+			return err
+		}
+	} else {
+		logger.FromContext(ctx).Error("Payment link %s of lead %d was %s", linkID, link.LeadID, errorMessage)
+	}
+
+	_, err = db.DB.ExecContext(ctx, `
+		UPDATE razorpay_payment_link
+		SET status = $2, order_id = $3, payment_id = $4, error_message = NULLIF($5, ''),
+			paid_at = NOW(), updated_at = NOW()
+		WHERE id = $1`,
+		linkID, PaymentLinkStatusPaid, orderID, paymentID, errorMessage)
+	if err != nil {
+		return fmt.Errorf("error updating payment link %s: %w", linkID, err)
+	}
+	return nil
+}
+
+// closePaymentLink records that an unpaid link expired or was cancelled
+func closePaymentLink(ctx context.Context, linkID, status string) error {
+	result, err := db.DB.ExecContext(ctx, `
+		UPDATE razorpay_payment_link SET status = $2, updated_at = NOW()
+		WHERE id = $1 AND status = $3`,
+		linkID, status, PaymentLinkStatusCreated)
+	if err != nil {
+		return fmt.Errorf("error updating payment link %s: %w", linkID, err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		return nil
+	}
+	var exists bool
+	if err := db.DB.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM razorpay_payment_link WHERE id = $1)",
+		linkID).Scan(&exists); err != nil {
+		return fmt.Errorf("error fetching payment link %s: %w", linkID, err)
+	}
+	if !exists {
+		return errPaymentLinkUnknown
+	}
+	return nil
+}
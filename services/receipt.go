@@ -0,0 +1,330 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"admission-module/utils"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// ErrReceiptNotFound is returned when a payment has no receipt, e.g. before it is captured
+var ErrReceiptNotFound = errors.New("receipt not found")
+
+const receiptColumns = `
+	r.id, r.receipt_number, r.student_id, COALESCE(l.name, ''), COALESCE(l.email, ''), COALESCE(l.phone, ''),
+	r.order_id, r.payment_id, r.payment_type, r.course_id, COALESCE(c.name, ''), COALESCE(i.invoice_number, ''),
+	r.tax_rate, r.taxable_amount, r.cgst_amount, r.sgst_amount, r.igst_amount, r.total_amount, r.paid_at, COALESCE(r.storage_key, '')`
+
+const receiptFrom = `
+	FROM payment_receipt r
+	LEFT JOIN student_lead l ON l.id = r.student_id
+	LEFT JOIN course c ON c.id = r.course_id
+	LEFT JOIN invoice i ON i.id = r.invoice_id`
+
+// scanReceipt reads a receipt row selected with receiptColumns
+func scanReceipt(scanner interface{ Scan(...interface{}) error }) (*models.PaymentReceipt, error) {
+	var r models.PaymentReceipt
+	var courseID sql.NullInt64
+	err := scanner.Scan(
+		&r.ID, &r.ReceiptNumber, &r.StudentID, &r.StudentName,
+		utils.DecryptedPII(&r.StudentEmail), utils.DecryptedPII(&r.StudentPhone),
+		&r.OrderID, &r.PaymentID, &r.PaymentType, &courseID, &r.CourseName, &r.InvoiceNumber,
+		&r.TaxRate, &r.TaxableAmount, &r.CGSTAmount, &r.SGSTAmount, &r.IGSTAmount, &r.TotalAmount, &r.PaidAt, &r.StorageKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if courseID.Valid {
+		id := int(courseID.Int64)
+		r.CourseID = &id
+	}
+	r.DownloadURL = fmt.Sprintf("%s/payments/%s/receipt", config.AppConfig.AppBaseURL, r.OrderID)
+	return &r, nil
+}
+
+// issuePaymentReceipt numbers the receipt of a payment captured as paymentID, within the
+// capture's transaction. A course fee receipt takes the GST breakdown of its invoice; other
+// payments are split at INVOICE_TAX_RATE for the student's place of supply. A payment keeps
+// the receipt it was issued.
+func issuePaymentReceipt(ctx context.Context, tx *sql.Tx, payment *models.Payment, paymentID string) (*models.PaymentReceipt, error) {
+	receipt, err := scanReceipt(tx.QueryRowContext(ctx,
+		"SELECT "+receiptColumns+receiptFrom+" WHERE r.order_id = $1", payment.OrderID))
+	if err == nil {
+		return receipt, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("error loading receipt: %w", err)
+	}
+
+	var invoiceID sql.NullInt64
+	taxRate := config.AppConfig.InvoiceTaxRate
+	_, interState, err := placeOfSupply(ctx, tx, payment.StudentID)
+	if err != nil {
+		return nil, err
+	}
+	taxable, cgst, sgst, igst := gstBreakdown(payment.Amount, taxRate, interState)
+	if payment.PaymentType == PaymentTypeCourseFee {
+		err := tx.QueryRowContext(ctx, `
+			SELECT id, tax_rate, taxable_amount, cgst_amount, sgst_amount, igst_amount FROM invoice
+			WHERE course_payment_id = $1 AND total_amount = $2
+			ORDER BY installment_no DESC
+			LIMIT 1`, payment.ID, payment.Amount,
+		).Scan(&invoiceID, &taxRate, &taxable, &cgst, &sgst, &igst)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("error loading invoice for receipt: %w", err)
+		}
+	}
+
+	paidAt := time.Now()
+	number, err := nextDocumentNumber(ctx, tx, documentSeriesReceipt, config.AppConfig.ReceiptPrefix, paidAt)
+	if err != nil {
+		return nil, fmt.Errorf("error numbering receipt: %w", err)
+	}
+	var receiptID int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO payment_receipt (receipt_number, student_id, order_id, payment_id, payment_type, course_id, invoice_id,
+			tax_rate, taxable_amount, cgst_amount, sgst_amount, igst_amount, total_amount, paid_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id`,
+		number, payment.StudentID, payment.OrderID, paymentID, payment.PaymentType, payment.RelatedCourseID, invoiceID,
+		taxRate, taxable, cgst, sgst, igst, payment.Amount, paidAt,
+	).Scan(&receiptID)
+	if err != nil {
+		return nil, fmt.Errorf("error issuing receipt: %w", err)
+	}
+
+	receipt, err = scanReceipt(tx.QueryRowContext(ctx, "SELECT "+receiptColumns+receiptFrom+" WHERE r.id = $1", receiptID))
+	if err != nil {
+		return nil, fmt.Errorf("error loading receipt: %w", err)
+	}
+	return receipt, nil
+}
+
+// receiptStorageKey is where the PDF of a receipt is stored
+func receiptStorageKey(r *models.PaymentReceipt) string {
+	return "receipts/" + ReceiptFileName(r)
+}
+
+// storeReceiptDocument renders the receipt, saves it to document storage and records the key
+func storeReceiptDocument(ctx context.Context, q querier, r *models.PaymentReceipt) error {
+	doc, err := buildReceiptPDF(r)
+	if err != nil {
+		return fmt.Errorf("error rendering receipt %s: %w", r.ReceiptNumber, err)
+	}
+	key := receiptStorageKey(r)
+	if err := GetDocumentStorage().Save(ctx, key, bytes.NewReader(doc)); err != nil {
+		return fmt.Errorf("error storing receipt %s: %w", r.ReceiptNumber, err)
+	}
+	if _, err := q.ExecContext(ctx, "UPDATE payment_receipt SET storage_key = $1 WHERE id = $2", key, r.ID); err != nil {
+		return fmt.Errorf("error recording receipt document: %w", err)
+	}
+	r.StorageKey = key
+	return nil
+}
+
+// receiptAttachment stores the receipt's PDF and returns its local file for an email
+// attachment, or "" when it cannot be attached; the receipt can still be downloaded then
+func receiptAttachment(ctx context.Context, q querier, r *models.PaymentReceipt) string {
+	if r.StorageKey == "" {
+		if err := storeReceiptDocument(ctx, q, r); err != nil {
+			logger.Warn("Error storing receipt %s for attachment: %v", r.ReceiptNumber, err)
+			return ""
+		}
+	}
+	return localDocumentPath(r.StorageKey)
+}
+
+// ListStudentReceipts returns the receipts of a student, newest first
+func ListStudentReceipts(ctx context.Context, studentID int) ([]models.PaymentReceipt, error) {
+	rows, err := db.DB.QueryContext(ctx,
+		"SELECT "+receiptColumns+receiptFrom+" WHERE r.student_id = $1 ORDER BY r.paid_at DESC, r.id DESC", studentID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing receipts: %w", err)
+	}
+	defer rows.Close()
+
+	receipts := []models.PaymentReceipt{}
+	for rows.Next() {
+		r, err := scanReceipt(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning receipt: %w", err)
+		}
+		receipts = append(receipts, *r)
+	}
+	return receipts, rows.Err()
+}
+
+// OpenPaymentReceipt returns the receipt of the payment with the given order and its PDF,
+// rendering the PDF first when it has not been stored yet
+func OpenPaymentReceipt(ctx context.Context, orderID string) (*models.PaymentReceipt, io.ReadCloser, error) {
+	receipt, err := scanReceipt(db.DB.QueryRowContext(ctx,
+		"SELECT "+receiptColumns+receiptFrom+" WHERE r.order_id = $1", orderID))
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrReceiptNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching receipt: %w", err)
+	}
+
+	if receipt.StorageKey != "" {
+		if file, err := GetDocumentStorage().Open(ctx, receipt.StorageKey); err == nil {
+			return receipt, file, nil
+		}
+		logger.Warn("Receipt %s document missing from storage, rendering it again", receipt.ReceiptNumber)
+	}
+	if err := storeReceiptDocument(ctx, db.DB, receipt); err != nil {
+		return nil, nil, err
+	}
+	file, err := GetDocumentStorage().Open(ctx, receipt.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening receipt: %w", err)
+	}
+	return receipt, file, nil
+}
+
+// ReceiptFileName is the download name of a receipt
+func ReceiptFileName(r *models.PaymentReceipt) string {
+	return strings.ReplaceAll(r.ReceiptNumber, "/", "-") + ".pdf"
+}
+
+// buildReceiptPDF renders a receipt as an A4 PDF
+func buildReceiptPDF(r *models.PaymentReceipt) ([]byte, error) {
+	cfg := config.AppConfig
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Receipt "+r.ReceiptNumber, true)
+	pdf.SetMargins(20, 20, 20)
+	pdf.AddPage()
+	// The core fonts are cp1252; names outside it are printed as close as it allows
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 10, "Payment Receipt", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(0, 6, tr(cfg.InstitutionName), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	for _, line := range []string{cfg.InstitutionAddress, gstinLine(cfg.InstitutionGSTIN), cfg.InstitutionEmail} {
+		if line != "" {
+			pdf.CellFormat(0, 5, tr(line), "", 1, "L", false, 0, "")
+		}
+	}
+	pdf.Ln(6)
+
+	labelled := func(label, value string) {
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(40, 7, label, "1", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(0, 7, tr(value), "1", 1, "L", false, 0, "")
+	}
+	labelled("Receipt Number", r.ReceiptNumber)
+	labelled("Date", r.PaidAt.Format("02 Jan 2006"))
+	labelled("Order", r.OrderID)
+	labelled("Payment", r.PaymentID)
+	if r.InvoiceNumber != "" {
+		labelled("Invoice", r.InvoiceNumber)
+	}
+	pdf.Ln(6)
+
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(0, 6, "Received from:", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	for _, line := range []string{r.StudentName, r.StudentEmail, r.StudentPhone, fmt.Sprintf("Student ID %d", r.StudentID)} {
+		if line != "" {
+			pdf.CellFormat(0, 5, tr(line), "", 1, "L", false, 0, "")
+		}
+	}
+	pdf.Ln(6)
+
+	description := paymentPurpose(r.PaymentType)
+	if r.CourseName != "" {
+		description += ": " + r.CourseName
+	}
+	halfRate := r.TaxRate / 2
+	amountRow := func(style, label string, amount float64) {
+		pdf.SetFont("Helvetica", style, 10)
+		pdf.CellFormat(130, 7, tr(label), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 7, fmt.Sprintf("%.2f", amount), "1", 1, "R", false, 0, "")
+	}
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(130, 7, "Description", "1", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 7, "Amount (INR)", "1", 1, "R", false, 0, "")
+	amountRow("", description, r.TaxableAmount)
+	if r.IGSTAmount > 0 {
+		amountRow("", fmt.Sprintf("IGST @ %.2f%%", r.TaxRate), r.IGSTAmount)
+	} else {
+		amountRow("", fmt.Sprintf("CGST @ %.2f%%", halfRate), r.CGSTAmount)
+		amountRow("", fmt.Sprintf("SGST @ %.2f%%", halfRate), r.SGSTAmount)
+	}
+	amountRow("B", "Total paid", r.TotalAmount)
+	pdf.Ln(8)
+
+	pdf.SetFont("Helvetica", "I", 9)
+	pdf.CellFormat(0, 5, "This is a computer generated receipt and does not require a signature.", "", 1, "L", false, 0, "")
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gstinLine labels the institution's GSTIN, "" when there is none
+func gstinLine(gstin string) string {
+	if gstin == "" {
+		return ""
+	}
+	return "GSTIN: " + gstin
+}
+
+// enqueuePaymentReceiptEmail queues the confirmation of a registration payment with its
+// receipt attached, in the capture's transaction
+func enqueuePaymentReceiptEmail(ctx context.Context, tx *sql.Tx, receipt *models.PaymentReceipt, attachment string) error {
+	if receipt.StudentEmail == "" {
+		return nil
+	}
+	subject, body := buildPaymentReceiptEmail(receipt.StudentName, receipt)
+	evt := map[string]interface{}{
+		"event":      "email.send",
+		"category":   EmailCategoryTransactional,
+		"recipient":  receipt.StudentEmail,
+		"subject":    subject,
+		"body":       body,
+		"student_id": receipt.StudentID,
+		"timestamp":  time.Now().UTC().Format(time.RFC3339),
+	}
+	if attachment != "" {
+		evt["attachment"] = attachment
+	}
+	return EnqueueEvent(ctx, tx, "emails", fmt.Sprintf("email-%s", receipt.StudentEmail), evt)
+}
+
+// buildPaymentReceiptEmail renders the confirmation of a captured payment
+func buildPaymentReceiptEmail(studentName string, receipt *models.PaymentReceipt) (subject, body string) {
+	purpose := strings.ToLower(paymentPurpose(receipt.PaymentType))
+
+	body = fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <p>Dear <strong>%s</strong>,</p>
+    <p>We have received your %s payment of <strong>INR %.2f</strong>. Thank you!</p>
+    <p>Receipt number: <strong>%s</strong><br/>Order reference: %s</p>
+    <p>Your receipt is attached. You can also download it at <a href="%s">%s</a>.</p>
+</body>
+</html>`, html.EscapeString(studentName), purpose, receipt.TotalAmount,
+		html.EscapeString(receipt.ReceiptNumber), html.EscapeString(receipt.OrderID),
+		html.EscapeString(receipt.DownloadURL), html.EscapeString(receipt.DownloadURL))
+
+	return fmt.Sprintf("Payment received: %s", receipt.ReceiptNumber), body
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSummaryPeriod is returned for a summary period other than daily or weekly
+var ErrInvalidSummaryPeriod = errors.New("period must be daily or weekly")
+
+// summaryWindow returns the reporting window for a period: the previous full day,
+// or the seven full days, ending at midnight of now
+func summaryWindow(period string, now time.Time) (time.Time, time.Time, error) {
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch period {
+	case models.SummaryPeriodDaily:
+		return to.AddDate(0, 0, -1), to, nil
+	case models.SummaryPeriodWeekly:
+		return to.AddDate(0, 0, -7), to, nil
+	default:
+		return time.Time{}, time.Time{}, ErrInvalidSummaryPeriod
+	}
+}
+
+// GetManagerSummary collects new leads, payments and interviews for the period ending at
+// midnight of now. Upcoming interviews cover the same length of time after the window.
+func GetManagerSummary(ctx context.Context, period string, now time.Time) (*models.ManagerSummary, error) {
+	from, to, err := summaryWindow(period, now)
+	if err != nil {
+		return nil, err
+	}
+	summary := &models.ManagerSummary{Period: period, From: from, To: to}
+	if err := collectActivity(ctx, summary); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// collectActivity fills in the new leads, payments and interviews between summary.From
+// and summary.To. Upcoming interviews cover the same length of time after To.
+func collectActivity(ctx context.Context, summary *models.ManagerSummary) error {
+	from, to := summary.From, summary.To
+	summary.NewLeadsBySource = []models.SourceCount{}
+
+	rows, err := db.Reader().QueryContext(ctx, `
+		SELECT COALESCE(NULLIF(lead_source, ''), 'unknown'), COUNT(*)
+		FROM student_lead
+		WHERE deleted_at IS NULL AND created_at >= $1 AND created_at < $2
+		GROUP BY 1
+		ORDER BY 2 DESC, 1`, from, to)
+	if err != nil {
+		return fmt.Errorf("error counting new leads: %w", err)
+	}
+	for rows.Next() {
+		var sc models.SourceCount
+		if err := rows.Scan(&sc.LeadSource, &sc.Count); err != nil {
+			rows.Close()
+			return fmt.Errorf("error reading new leads: %w", err)
+		}
+		summary.NewLeads += sc.Count
+		summary.NewLeadsBySource = append(summary.NewLeadsBySource, sc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading new leads: %w", err)
+	}
+
+	err = db.Reader().QueryRowContext(ctx, `
+		WITH paid AS (
+			SELECT payment_type, amount FROM payments WHERE status = $3 AND updated_at >= $1 AND updated_at < $2
+		)
+		SELECT
+			(SELECT COUNT(*) FROM paid WHERE payment_type = $5),
+			(SELECT COALESCE(SUM(amount), 0) FROM paid WHERE payment_type = $5),
+			(SELECT COUNT(*) FROM paid WHERE payment_type = $6),
+			(SELECT COALESCE(SUM(amount), 0) FROM paid WHERE payment_type = $6),
+			(SELECT COUNT(*) FROM student_lead WHERE deleted_at IS NULL AND interview_scheduled_at >= $1 AND interview_scheduled_at < $2),
+			(SELECT COUNT(*) FROM student_lead WHERE deleted_at IS NULL AND interview_scheduled_at >= $2 AND interview_scheduled_at < $4)`,
+		from, to, PaymentStatusPaid, to.Add(to.Sub(from)), PaymentTypeRegistration, PaymentTypeCourseFee,
+	).Scan(&summary.RegistrationPayments, &summary.RegistrationAmount,
+		&summary.CoursePayments, &summary.CourseAmount,
+		&summary.InterviewsHeld, &summary.UpcomingInterviews)
+	if err != nil {
+		return fmt.Errorf("error computing payment and interview totals: %w", err)
+	}
+	return nil
+}
+
+// SendManagerSummary emails the period's summary to every address in MANAGER_REPORT_EMAILS.
+// Does nothing when no managers are configured.
+func SendManagerSummary(ctx context.Context, period string) error {
+	var recipients []string
+	for _, email := range strings.Split(config.AppConfig.ManagerReportEmails, ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			recipients = append(recipients, email)
+		}
+	}
+	if len(recipients) == 0 || db.DB == nil {
+		return nil
+	}
+
+	summary, err := GetManagerSummary(ctx, period, time.Now())
+	if err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("Admissions %s summary: %s", period, summaryRange(summary))
+	body := buildManagerSummaryBody(summary)
+
+	var failed int
+	for _, recipient := range recipients {
+		if err := SendCategorizedEmail(EmailCategoryStaff, recipient, subject, body); err != nil {
+			logger.Error("Error sending %s summary to %s: %v", period, recipient, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d %s summary emails failed", failed, len(recipients), period)
+	}
+
+	logger.Info("Sent %s summary to %d managers", period, len(recipients))
+	return nil
+}
+
+// summaryRange formats the (inclusive) dates covered by a summary
+func summaryRange(summary *models.ManagerSummary) string {
+	last := summary.To.AddDate(0, 0, -1)
+	if summary.From.Equal(last) {
+		return summary.From.Format("02 Jan 2006")
+	}
+	return summary.From.Format("02 Jan") + " - " + last.Format("02 Jan 2006")
+}
+
+func buildManagerSummaryBody(summary *models.ManagerSummary) string {
+	var sources strings.Builder
+	for _, sc := range summary.NewLeadsBySource {
+		fmt.Fprintf(&sources, "<tr><td>%s</td><td>%d</td></tr>", html.EscapeString(sc.LeadSource), sc.Count)
+	}
+	if sources.Len() == 0 {
+		sources.WriteString(`<tr><td colspan="2">No new leads</td></tr>`)
+	}
+
+	return fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; color: #333;">
+    <h2>Admissions %s summary</h2>
+    <p>%s</p>
+    <h3>New leads: %d</h3>
+    <table border="1" cellpadding="6" cellspacing="0" style="border-collapse: collapse;">
+        <tr><th>Source</th><th>Leads</th></tr>
+        %s
+    </table>
+    <h3>Payments</h3>
+    <table border="1" cellpadding="6" cellspacing="0" style="border-collapse: collapse;">
+        <tr><th>Type</th><th>Count</th><th>Amount (INR)</th></tr>
+        <tr><td>Registration fee</td><td>%d</td><td>%.2f</td></tr>
+        <tr><td>Course fee</td><td>%d</td><td>%.2f</td></tr>
+    </table>
+    <h3>Interviews</h3>
+    <p>Held: %d<br>Upcoming (next %s): %d</p>
+</body>
+</html>`,
+		summary.Period, summaryRange(summary),
+		summary.NewLeads, sources.String(),
+		summary.RegistrationPayments, summary.RegistrationAmount,
+		summary.CoursePayments, summary.CourseAmount,
+		summary.InterviewsHeld, upcomingLabel(summary.Period), summary.UpcomingInterviews)
+}
+
+func upcomingLabel(period string) string {
+	if period == models.SummaryPeriodWeekly {
+		return "7 days"
+	}
+	return "day"
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"admission-module/db"
+	"context"
+	"database/sql"
+)
+
+// querier is satisfied by both *sql.DB and *sql.Tx, so a repository can run inside the
+// caller's transaction
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// currentDB queries db.DB as it is at the time of the call. Services are created with the
+// routes, before the database connects, so their repositories cannot keep db.DB itself.
+type currentDB struct{}
+
+func (currentDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRowContext(ctx, query, args...)
+}
+
+func (currentDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.DB.QueryContext(ctx, query, args...)
+}
+
+func (currentDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.DB.ExecContext(ctx, query, args...)
+}
+
+// currentReader queries db.Reader() as it is at the time of the call: the read replica
+// when one is connected, the primary otherwise
+type currentReader struct{}
+
+func (currentReader) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.Reader().QueryRowContext(ctx, query, args...)
+}
+
+func (currentReader) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.Reader().QueryContext(ctx, query, args...)
+}
+
+func (currentReader) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.Reader().ExecContext(ctx, query, args...)
+}
+
+// Repositories is the data access PaymentService and ApplicationService are built on.
+// Tests can swap in fakes for any of them.
+type Repositories struct {
+	Leads    LeadRepository
+	Courses  CourseRepository
+	Payments PaymentRepository
+}
+
+// PostgresRepositories returns the repositories over the application database
+func PostgresRepositories() Repositories {
+	return Repositories{
+		Leads:    NewLeadRepository(currentDB{}),
+		Courses:  NewCourseRepository(currentDB{}),
+		Payments: NewPaymentRepository(currentDB{}),
+	}
+}
+
+// ReportingRepositories returns read-only repositories over the read replica, for listings
+// and reports that tolerate replication lag. Use PostgresRepositories for anything that
+// writes or decides a write.
+func ReportingRepositories() Repositories {
+	return Repositories{
+		Leads:    NewLeadRepository(currentReader{}),
+		Courses:  NewCourseRepository(currentReader{}),
+		Payments: NewPaymentRepository(currentReader{}),
+	}
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// retentionPolicy is one data retention rule applied by the retention engine.
+// apply processes the records older than cutoff, or with dryRun only counts them, and
+// returns how many records were processed and how many were deliberately kept.
+type retentionPolicy struct {
+	name string
+	// defaultDays is the retention window unless RETENTION_POLICIES sets one; 0 keeps
+	// everything. Nil for a policy with its own window settings, which RETENTION_POLICIES
+	// can only switch off.
+	defaultDays func() int
+	apply       func(ctx context.Context, cutoff time.Time, dryRun bool) (processed, skipped int, err error)
+}
+
+// retentionPolicies are applied in order on every retention run
+var retentionPolicies = []retentionPolicy{
+	{
+		name:        models.RetentionPolicyAnonymizeRejectedLeads,
+		defaultDays: func() int { return config.AppConfig.RejectedLeadRetentionDays },
+		apply:       anonymizeRejectedLeads,
+	},
+	{name: models.RetentionPolicyPurgeRejectedLeads, defaultDays: days(0), apply: purgeRejectedLeads},
+	{name: models.RetentionPolicyPurgeSentEvents, defaultDays: days(sentEventRetentionDays), apply: purgeSentEvents},
+	{name: models.RetentionPolicyPurgeProcessedEvents, defaultDays: days(processedEventRetentionDays), apply: purgeProcessedEvents},
+	{
+		name:        models.RetentionPolicyArchiveResolvedDLQ,
+		defaultDays: func() int { return config.AppConfig.DLQArchiveAfterDays },
+		apply:       archiveResolvedDLQ,
+	},
+	{name: models.RetentionPolicyPurgeArchivedDLQ, defaultDays: days(0), apply: purgeArchivedDLQ},
+	{name: models.RetentionPolicyPurgeAPIUsage, defaultDays: days(apiUsageRetentionDays), apply: purgeAPIUsage},
+	{name: models.RetentionPolicyPurgeEmailQuota, defaultDays: days(emailQuotaRetentionDays), apply: purgeEmailQuota},
+	// Webhook partitions are archived after WEBHOOK_PARTITION_ARCHIVE_MONTHS
+	{name: models.RetentionPolicyArchiveWebhookPartitions, apply: archiveOldPartitions},
+}
+
+// days is a fixed default retention window
+func days(n int) func() int {
+	return func() int { return n }
+}
+
+// window returns the retention window of the policy in days, 0 when it has its own window
+// settings, and whether it is switched on
+func (p retentionPolicy) window() (int, bool) {
+	if p.defaultDays == nil {
+		return 0, config.RetentionDays(p.name, 1) > 0
+	}
+	days := config.RetentionDays(p.name, p.defaultDays())
+	return days, days > 0
+}
+
+// archiveResolvedDLQ is a retention policy archiving DLQ messages resolved before cutoff
+func archiveResolvedDLQ(ctx context.Context, cutoff time.Time, dryRun bool) (int, int, error) {
+	if dryRun {
+		n, err := CountArchivableDLQMessages(ctx, cutoff)
+		return n, 0, err
+	}
+	archived, err := ArchiveResolvedDLQMessages(ctx, cutoff)
+	return archived, 0, err
+}
+
+// purgeArchivedDLQ is a retention policy deleting archived DLQ messages resolved before cutoff
+func purgeArchivedDLQ(ctx context.Context, cutoff time.Time, dryRun bool) (int, int, error) {
+	const where = " FROM dlq_messages_archive WHERE resolved_at < $1"
+	if dryRun {
+		return countRows(ctx, "SELECT COUNT(*)"+where, cutoff)
+	}
+	result, err := db.DB.ExecContext(ctx, "DELETE"+where, cutoff)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error purging archived DLQ messages: %w", err)
+	}
+	purged, _ := result.RowsAffected()
+	return int(purged), 0, nil
+}
+
+// DLQArchiveCutoff is the resolution time before which DLQ messages are archived (30 days when days <= 0)
+func DLQArchiveCutoff(days int) time.Time {
+	if days <= 0 {
+		days = 30
+	}
+	return time.Now().AddDate(0, 0, -days)
+}
+
+// processedEventRetentionDays is how long consumer dedup records are kept by default
+const processedEventRetentionDays = 30
+
+// purgeProcessedEvents is a retention policy deleting consumer dedup records older than cutoff
+func purgeProcessedEvents(ctx context.Context, cutoff time.Time, dryRun bool) (int, int, error) {
+	if dryRun {
+		n, err := CountPurgeableProcessedEvents(ctx, cutoff)
+		return n, 0, err
+	}
+	purged, err := PurgeProcessedEvents(ctx, cutoff)
+	return purged, 0, err
+}
+
+// countRows runs a COUNT query for a dry run
+func countRows(ctx context.Context, query string, args ...interface{}) (int, int, error) {
+	var n int
+	if err := db.DB.QueryRowContext(ctx, query, args...).Scan(&n); err != nil {
+		return 0, 0, fmt.Errorf("error counting records: %w", err)
+	}
+	return n, 0, nil
+}
+
+// anonymizedLeadName replaces the name of anonymized leads
+const anonymizedLeadName = "Anonymized Lead"
+
+// RunRetention applies every retention policy that is switched on and records each run
+// with its counts. With dryRun the policies only count what they would process. A failing
+// policy is recorded and does not stop the others.
+func RunRetention(ctx context.Context, dryRun bool) ([]models.RetentionRun, error) {
+	if db.DB == nil {
+		return nil, nil
+	}
+
+	runs := []models.RetentionRun{}
+	for _, policy := range retentionPolicies {
+		days, enabled := policy.window()
+		if !enabled {
+			continue
+		}
+		run := models.RetentionRun{Policy: policy.name, DryRun: dryRun, RetentionDays: days, StartedAt: time.Now()}
+		var cutoff time.Time
+		if days > 0 {
+			cutoff = run.StartedAt.AddDate(0, 0, -days)
+		}
+
+		processed, skipped, err := policy.apply(ctx, cutoff, dryRun)
+		run.ProcessedCount, run.SkippedCount = processed, skipped
+		if err != nil {
+			run.ErrorMessage = err.Error()
+			logger.Error("Retention policy %s failed: %v", policy.name, err)
+		} else if dryRun {
+			logger.Info("Retention policy %s (dry run): would process=%d skip=%d", policy.name, processed, skipped)
+		} else {
+			logger.Info("Retention policy %s: processed=%d skipped=%d", policy.name, processed, skipped)
+		}
+		finishedAt := time.Now()
+		run.FinishedAt = &finishedAt
+
+		err = db.DB.QueryRowContext(ctx, `
+			INSERT INTO retention_run (policy, dry_run, retention_days, processed_count, skipped_count, error_message, started_at, finished_at)
+			VALUES ($1, $2, NULLIF($3, 0), $4, $5, NULLIF($6, ''), $7, $8)
+			RETURNING id`,
+			run.Policy, run.DryRun, run.RetentionDays, run.ProcessedCount, run.SkippedCount, run.ErrorMessage,
+			run.StartedAt, finishedAt).Scan(&run.ID)
+		if err != nil {
+			return runs, fmt.Errorf("error recording retention run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, nil
+}
+
+// GetRetentionRuns lists the most recent retention runs
+func GetRetentionRuns(ctx context.Context, limit int) ([]models.RetentionRun, error) {
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT id, policy, dry_run, COALESCE(retention_days, 0), processed_count, skipped_count,
+			COALESCE(error_message, ''), started_at, finished_at
+		FROM retention_run
+		ORDER BY started_at DESC
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching retention runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []models.RetentionRun{}
+	for rows.Next() {
+		var run models.RetentionRun
+		var finishedAt sql.NullTime
+		if err := rows.Scan(&run.ID, &run.Policy, &run.DryRun, &run.RetentionDays, &run.ProcessedCount, &run.SkippedCount,
+			&run.ErrorMessage, &run.StartedAt, &finishedAt); err != nil {
+			return nil, fmt.Errorf("error reading retention runs: %w", err)
+		}
+		if finishedAt.Valid {
+			run.FinishedAt = &finishedAt.Time
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+// findExpiredRejectedLeads returns the leads rejected before cutoff whose student gave no
+// re-marketing consent, with anonymized leads only when includeAnonymized is set. Leads with
+// payment records are kept intact for financial bookkeeping and only counted.
+func findExpiredRejectedLeads(ctx context.Context, cutoff time.Time, includeAnonymized bool) (leadIDs []int64, withPayments int, err error) {
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT sl.id,
+			EXISTS (SELECT 1 FROM payments p WHERE p.student_id = sl.id)
+		FROM student_lead sl
+		WHERE sl.application_status = 'REJECTED'
+			AND ($2 OR sl.anonymized_at IS NULL)
+			AND COALESCE(sl.remarketing_consent, false) = false
+			AND COALESCE(sl.status_changed_at, sl.updated_at) < $1`, cutoff, includeAnonymized)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error finding rejected leads: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var leadID int64
+		var hasPayments bool
+		if err := rows.Scan(&leadID, &hasPayments); err != nil {
+			return nil, 0, fmt.Errorf("error reading rejected leads: %w", err)
+		}
+		if hasPayments {
+			withPayments++
+			continue
+		}
+		leadIDs = append(leadIDs, leadID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error reading rejected leads: %w", err)
+	}
+	return leadIDs, withPayments, nil
+}
+
+// anonymizeRejectedLeads strips PII from leads rejected before cutoff (by default
+// RejectedLeadRetentionDays ago), unless the student gave re-marketing consent. Leads with
+// payment records are counted as skipped.
+func anonymizeRejectedLeads(ctx context.Context, cutoff time.Time, dryRun bool) (int, int, error) {
+	leadIDs, skipped, err := findExpiredRejectedLeads(ctx, cutoff, false)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(leadIDs) == 0 || dryRun {
+		return len(leadIDs), skipped, nil
+	}
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, skipped, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	ids := pq.Int64Array(leadIDs)
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`UPDATE student_lead
+			SET name = $2, email = 'anonymized-' || id || '@invalid', phone = '',
+				email_index = NULL, phone_index = NULL,
+				education = NULL, meet_link = NULL, anonymized_at = NOW(), updated_at = NOW()
+			WHERE id = ANY($1)`, []interface{}{ids, anonymizedLeadName}},
+		// Notes and timeline payloads can quote the student's contact details
+		{`UPDATE lead_note SET content = '[redacted]' WHERE lead_id = ANY($1)`, []interface{}{ids}},
+		{`UPDATE lead_event SET payload = '{}'::jsonb WHERE lead_id = ANY($1)`, []interface{}{ids}},
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+			return 0, skipped, fmt.Errorf("error anonymizing leads: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, skipped, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return len(leadIDs), skipped, nil
+}
+
+// purgeRejectedLeads deletes leads rejected before cutoff, anonymized or not, unless the
+// student gave re-marketing consent; their notes, timeline and other records go with them.
+// Leads with payment records are counted as skipped. Off by default.
+func purgeRejectedLeads(ctx context.Context, cutoff time.Time, dryRun bool) (int, int, error) {
+	leadIDs, skipped, err := findExpiredRejectedLeads(ctx, cutoff, true)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(leadIDs) == 0 || dryRun {
+		return len(leadIDs), skipped, nil
+	}
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, skipped, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The deleted leads no longer occupy counselor slots
+	_, err = tx.ExecContext(ctx, `
+		UPDATE counselor c SET assigned_count = GREATEST(c.assigned_count - l.n, 0), updated_at = NOW()
+		FROM (
+			SELECT counselor_id, COUNT(*) AS n FROM student_lead
+			WHERE id = ANY($1) AND counselor_id IS NOT NULL AND deleted_at IS NULL
+			GROUP BY counselor_id
+		) l
+		WHERE c.id = l.counselor_id`, pq.Int64Array(leadIDs))
+	if err != nil {
+		return 0, skipped, fmt.Errorf("error updating counselor counts: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, "DELETE FROM student_lead WHERE id = ANY($1)", pq.Int64Array(leadIDs))
+	if err != nil {
+		return 0, skipped, fmt.Errorf("error purging rejected leads: %w", err)
+	}
+	purged, _ := result.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, skipped, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return int(purged), skipped, nil
+}
@@ -0,0 +1,156 @@
+package scheduler
+
+import (
+	"admission-module/logger"
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Job is a named unit of background work run on a schedule
+type Job struct {
+	Name string
+	// Spec is a cron expression or "@every <duration>", see ParseSchedule
+	Spec string
+	// Jitter delays each run by a random duration up to this value so that
+	// several instances do not hit the database at the same instant
+	Jitter time.Duration
+	Run    func(ctx context.Context) error
+}
+
+type scheduledJob struct {
+	Job
+	schedule Schedule
+	// running guards against overlapping runs: a tick that fires while the
+	// previous run is still in progress is skipped
+	running atomic.Bool
+}
+
+var (
+	mu   sync.Mutex
+	jobs []*scheduledJob
+	// schedulerCtx is cancelled by Stop; nil while the scheduler is not running
+	schedulerCtx context.Context
+	cancel       context.CancelFunc
+	running      sync.WaitGroup
+)
+
+// Register adds a job to the scheduler. Jobs registered after Start begin immediately.
+func Register(job Job) error {
+	if job.Name == "" || job.Run == nil {
+		return fmt.Errorf("job requires a name and a run function")
+	}
+	schedule, err := ParseSchedule(job.Spec)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", job.Name, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, j := range jobs {
+		if j.Name == job.Name {
+			return fmt.Errorf("job %s is already registered", job.Name)
+		}
+	}
+
+	sj := &scheduledJob{Job: job, schedule: schedule}
+	jobs = append(jobs, sj)
+	if schedulerCtx != nil {
+		startJob(sj)
+	}
+	return nil
+}
+
+// Start runs every registered job on its schedule until Stop is called
+func Start() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if schedulerCtx != nil {
+		return
+	}
+
+	schedulerCtx, cancel = context.WithCancel(context.Background())
+	for _, j := range jobs {
+		startJob(j)
+	}
+
+	logger.Info("✓ Scheduler started with %d jobs", len(jobs))
+}
+
+// Stop cancels in-flight runs and waits for them to return
+func Stop() {
+	mu.Lock()
+	if schedulerCtx == nil {
+		mu.Unlock()
+		return
+	}
+	cancel()
+	schedulerCtx, cancel = nil, nil
+	mu.Unlock()
+
+	running.Wait()
+}
+
+// startJob launches the scheduling loop of one job. Callers hold mu.
+func startJob(j *scheduledJob) {
+	ctx := schedulerCtx
+	running.Add(1)
+	go func() {
+		defer running.Done()
+		for {
+			next := j.schedule.Next(time.Now())
+			if next.IsZero() {
+				logger.Warn("Scheduler: job %s (%s) never fires again, stopping it", j.Name, j.Spec)
+				return
+			}
+
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			if !j.running.CompareAndSwap(false, true) {
+				logger.Warn("Scheduler: skipping %s, previous run still in progress", j.Name)
+				continue
+			}
+
+			running.Add(1)
+			go func() {
+				defer running.Done()
+				defer j.running.Store(false)
+				runJob(ctx, j)
+			}()
+		}
+	}()
+}
+
+// runJob waits out the job's jitter and runs it once, recovering from panics
+func runJob(ctx context.Context, j *scheduledJob) {
+	if j.Jitter > 0 {
+		delay := time.NewTimer(time.Duration(rand.Int63n(int64(j.Jitter))))
+		select {
+		case <-ctx.Done():
+			delay.Stop()
+			return
+		case <-delay.C:
+		}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Scheduler: job %s panicked: %v", j.Name, r)
+		}
+	}()
+
+	if err := j.Run(ctx); err != nil {
+		logger.Error("Scheduler: job %s failed: %v", j.Name, err)
+	}
+}
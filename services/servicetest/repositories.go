@@ -0,0 +1,205 @@
+// Package servicetest provides in-memory repositories for exercising PaymentService,
+// ApplicationService and the lead handlers without a database.
+package servicetest
+
+import (
+	"admission-module/models"
+	"admission-module/services"
+	"context"
+	"sort"
+	"sync"
+)
+
+var (
+	_ services.LeadRepository    = (*FakeLeadRepository)(nil)
+	_ services.CourseRepository  = (*FakeCourseRepository)(nil)
+	_ services.PaymentRepository = (*FakePaymentRepository)(nil)
+)
+
+// Repositories returns services.Repositories over fresh, empty fakes
+func Repositories() (services.Repositories, *FakeLeadRepository, *FakeCourseRepository, *FakePaymentRepository) {
+	leads := &FakeLeadRepository{Leads: map[int]models.Lead{}}
+	courses := &FakeCourseRepository{Courses: map[int]models.Course{}}
+	payments := &FakePaymentRepository{}
+	return services.Repositories{Leads: leads, Courses: courses, Payments: payments}, leads, courses, payments
+}
+
+// FakeLeadRepository keeps leads in memory by ID. Err makes every call fail.
+type FakeLeadRepository struct {
+	mu    sync.Mutex
+	Leads map[int]models.Lead
+	Err   error
+}
+
+func (f *FakeLeadRepository) Exists(ctx context.Context, id int) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return false, f.Err
+	}
+	_, ok := f.Leads[id]
+	return ok, nil
+}
+
+func (f *FakeLeadRepository) FindContact(ctx context.Context, id int) (string, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return "", "", f.Err
+	}
+	lead, ok := f.Leads[id]
+	if !ok {
+		return "", "", services.ErrLeadNotFound
+	}
+	return lead.Name, lead.Email, nil
+}
+
+func (f *FakeLeadRepository) ApplicationStatus(ctx context.Context, id int) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return "", f.Err
+	}
+	lead, ok := f.Leads[id]
+	if !ok {
+		return "", services.ErrLeadNotFound
+	}
+	return lead.ApplicationStatus, nil
+}
+
+// List filters on the creation time only; Unassigned keeps the leads without a counselor
+func (f *FakeLeadRepository) List(ctx context.Context, filter services.LeadListFilter) ([]models.Lead, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	leads := []models.Lead{}
+	for _, lead := range f.Leads {
+		if filter.CreatedAfter != nil && lead.CreatedAt.Before(*filter.CreatedAfter) {
+			continue
+		}
+		if filter.CreatedBefore != nil && lead.CreatedAt.After(*filter.CreatedBefore) {
+			continue
+		}
+		if filter.Unassigned && lead.CounsellorID != nil {
+			continue
+		}
+		leads = append(leads, lead)
+	}
+	sort.Slice(leads, func(i, j int) bool { return leads[i].ID < leads[j].ID })
+	return leads, nil
+}
+
+// FakeCourseRepository keeps courses in memory by ID. Err makes every call fail.
+type FakeCourseRepository struct {
+	mu      sync.Mutex
+	Courses map[int]models.Course
+	Err     error
+}
+
+func (f *FakeCourseRepository) FindByID(ctx context.Context, id int) (*models.Course, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	course, ok := f.Courses[id]
+	if !ok {
+		return nil, services.ErrCourseNotFound
+	}
+	return &course, nil
+}
+
+// FakePaymentRepository keeps payments in memory. Err makes every call fail.
+type FakePaymentRepository struct {
+	mu       sync.Mutex
+	Payments []models.Payment
+	Err      error
+}
+
+func (f *FakePaymentRepository) FindByOrderID(ctx context.Context, orderID string) (*models.Payment, error) {
+	return f.find(func(p *models.Payment) bool { return p.OrderID == orderID })
+}
+
+func (f *FakePaymentRepository) FindByStudent(ctx context.Context, studentID int) ([]models.Payment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	payments := []models.Payment{}
+	for _, p := range f.Payments {
+		if p.StudentID == studentID {
+			payments = append(payments, p)
+		}
+	}
+	sort.SliceStable(payments, func(i, j int) bool { return payments[i].UpdatedAt.After(payments[j].UpdatedAt) })
+	return payments, nil
+}
+
+func (f *FakePaymentRepository) RegistrationStatus(ctx context.Context, studentID int) (string, error) {
+	p, err := f.find(func(p *models.Payment) bool {
+		return p.StudentID == studentID && p.PaymentType == services.PaymentTypeRegistration
+	})
+	if err != nil {
+		return "", err
+	}
+	return p.Status, nil
+}
+
+func (f *FakePaymentRepository) CourseFeeStatus(ctx context.Context, studentID, courseID int) (string, error) {
+	p, err := f.find(func(p *models.Payment) bool {
+		return p.StudentID == studentID && p.PaymentType == services.PaymentTypeCourseFee &&
+			p.RelatedCourseID != nil && *p.RelatedCourseID == courseID
+	})
+	if err != nil {
+		return "", err
+	}
+	return p.Status, nil
+}
+
+func (f *FakePaymentRepository) MarkPaid(ctx context.Context, p *models.Payment, paymentID, signature string) error {
+	return f.update(p, func(stored *models.Payment) {
+		stored.Status, stored.PaymentID, stored.RazorpaySign = services.PaymentStatusPaid, paymentID, signature
+	})
+}
+
+func (f *FakePaymentRepository) MarkFailed(ctx context.Context, p *models.Payment, paymentID, errorMsg string) error {
+	return f.update(p, func(stored *models.Payment) {
+		stored.Status, stored.PaymentID, stored.ErrorMessage = services.PaymentStatusFailed, paymentID, errorMsg
+	})
+}
+
+// find returns a copy of the first payment matching, or services.ErrPaymentNotFound
+func (f *FakePaymentRepository) find(match func(p *models.Payment) bool) (*models.Payment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	for i := range f.Payments {
+		if match(&f.Payments[i]) {
+			p := f.Payments[i]
+			return &p, nil
+		}
+	}
+	return nil, services.ErrPaymentNotFound
+}
+
+// update applies change to the stored payment with p's type and ID, and to p itself
+func (f *FakePaymentRepository) update(p *models.Payment, change func(stored *models.Payment)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return f.Err
+	}
+	for i := range f.Payments {
+		if f.Payments[i].ID == p.ID && f.Payments[i].PaymentType == p.PaymentType {
+			change(&f.Payments[i])
+			break
+		}
+	}
+	change(p)
+	return nil
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// maxStatusProjectionLeads caps the leads rebuilt by one request
+const maxStatusProjectionLeads = 1000
+
+// statusSourceProjectionRebuild marks history rows written by a rebuild (see the
+// record_application_status_history trigger)
+const statusSourceProjectionRebuild = "projection_rebuild"
+
+// ErrInvalidStatusProjection is returned when no lead ids, or too many, are given
+var ErrInvalidStatusProjection = fmt.Errorf("lead_ids must list between 1 and %d leads", maxStatusProjectionLeads)
+
+// statusEventProjections maps lead_event types to the application status they imply,
+// for leads whose changes predate application_status_history
+var statusEventProjections = map[string]string{
+	"application.pending_approval": ApplicationStatusPendingApproval,
+	"application.accepted":         ApplicationStatusAccepted,
+	"application.waitlisted":       ApplicationStatusWaitlisted,
+	"application.rejected":         ApplicationStatusRejected,
+	"application.offer_expired":    ApplicationStatusOfferExpired,
+}
+
+// RebuildStatusProjection recomputes the application status of each lead by replaying its
+// status history (or, without history, its lead events) through applicationTransitions,
+// and reports where the stored status differs. With apply the differing leads are
+// corrected; each lead is handled in its own transaction and a failing lead is reported
+// without stopping the others.
+func RebuildStatusProjection(ctx context.Context, leadIDs []int, apply bool) ([]models.StatusProjectionDiff, error) {
+	if len(leadIDs) == 0 || len(leadIDs) > maxStatusProjectionLeads {
+		return nil, ErrInvalidStatusProjection
+	}
+
+	diffs := make([]models.StatusProjectionDiff, 0, len(leadIDs))
+	for _, leadID := range leadIDs {
+		diff, err := rebuildLeadStatus(ctx, leadID, apply)
+		if err != nil {
+			diff.Error = err.Error()
+			logger.Error("Status projection rebuild failed for lead %d: %v", leadID, err)
+		} else if diff.Applied {
+			logger.Info("Status projection rebuild corrected lead %d: %s -> %s", leadID, diff.CurrentStatus, diff.ProjectedStatus)
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs, nil
+}
+
+func rebuildLeadStatus(ctx context.Context, leadID int, apply bool) (models.StatusProjectionDiff, error) {
+	diff := models.StatusProjectionDiff{LeadID: leadID}
+
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return diff, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		"SELECT COALESCE(application_status, 'NEW') FROM student_lead WHERE id = $1 AND deleted_at IS NULL FOR UPDATE",
+		leadID).Scan(&diff.CurrentStatus)
+	if err == sql.ErrNoRows {
+		return diff, ErrLeadNotFound
+	}
+	if err != nil {
+		return diff, fmt.Errorf("error locking lead: %w", err)
+	}
+
+	changes, err := statusHistory(ctx, tx, leadID)
+	if err != nil {
+		return diff, err
+	}
+	diff.Source = models.StatusProjectionSourceHistory
+	if len(changes) == 0 {
+		if changes, err = statusEvents(ctx, tx, leadID); err != nil {
+			return diff, err
+		}
+		diff.Source = models.StatusProjectionSourceEvents
+	}
+	if len(changes) == 0 {
+		diff.Source = models.StatusProjectionSourceNone
+		diff.ProjectedStatus = diff.CurrentStatus
+		return diff, nil
+	}
+
+	diff.ProjectedStatus, diff.Rejected = projectApplicationStatus(changes)
+	diff.Replayed = len(changes)
+	diff.Changed = diff.ProjectedStatus != diff.CurrentStatus
+	if !apply || !diff.Changed {
+		return diff, nil
+	}
+
+	// Recorded in the history as a rebuild correction, which later rebuilds accept as is
+	if _, err := tx.ExecContext(ctx,
+		"SELECT set_config('admission.status_source', $1, true)", statusSourceProjectionRebuild); err != nil {
+		return diff, fmt.Errorf("error marking status correction: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE student_lead SET application_status = $1, updated_at = NOW() WHERE id = $2",
+		diff.ProjectedStatus, leadID); err != nil {
+		return diff, fmt.Errorf("error correcting lead status: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return diff, fmt.Errorf("error committing transaction: %w", err)
+	}
+	diff.Applied = true
+	return diff, nil
+}
+
+// projectApplicationStatus folds status changes, oldest first, starting from NEW (or,
+// for a lead whose history began after it was created, the status it was in then).
+// A change the transitions do not allow from the status reached so far is rejected
+// and skipped; earlier rebuild corrections are taken as they are.
+func projectApplicationStatus(changes []models.StatusChange) (string, []models.StatusChange) {
+	status := ApplicationStatusNew
+	if len(changes) > 0 && changes[0].From != "" {
+		status = changes[0].From
+	}
+	var rejected []models.StatusChange
+	for _, change := range changes {
+		switch {
+		case change.Source == statusSourceProjectionRebuild, change.Status == status:
+			status = change.Status
+		case CanTransitionApplication(status, change.Status):
+			status = change.Status
+		default:
+			rejected = append(rejected, change)
+		}
+	}
+	return status, rejected
+}
+
+// statusHistory returns the lead's recorded application status changes, oldest first
+func statusHistory(ctx context.Context, tx *sql.Tx, leadID int) ([]models.StatusChange, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT COALESCE(from_status, ''), to_status, source, changed_at
+		FROM application_status_history
+		WHERE lead_id = $1
+		ORDER BY id ASC`, leadID)
+	if err != nil {
+		return nil, fmt.Errorf("error reading status history: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []models.StatusChange
+	for rows.Next() {
+		var c models.StatusChange
+		if err := rows.Scan(&c.From, &c.Status, &c.Source, &c.ChangedAt); err != nil {
+			return nil, fmt.Errorf("error reading status history: %w", err)
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// statusEvents derives status changes from the lead's timeline events: application
+// decisions, and registration payments (which schedule the interview)
+func statusEvents(ctx context.Context, tx *sql.Tx, leadID int) ([]models.StatusChange, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT event_type, payload, occurred_at
+		FROM lead_event
+		WHERE lead_id = $1 AND (event_type LIKE 'application.%' OR event_type = 'payment.verified')
+		ORDER BY occurred_at ASC, id ASC`, leadID)
+	if err != nil {
+		return nil, fmt.Errorf("error reading lead events: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []models.StatusChange
+	for rows.Next() {
+		var eventType string
+		var payload []byte
+		var c models.StatusChange
+		if err := rows.Scan(&eventType, &payload, &c.ChangedAt); err != nil {
+			return nil, fmt.Errorf("error reading lead events: %w", err)
+		}
+
+		c.Source = eventType
+		if status, ok := statusEventProjections[eventType]; ok {
+			c.Status = status
+		} else if eventType == "payment.verified" {
+			var evt struct {
+				PaymentType string `json:"payment_type"`
+			}
+			if err := json.Unmarshal(payload, &evt); err != nil || evt.PaymentType != PaymentTypeRegistration {
+				continue
+			}
+			c.Status = ApplicationStatusInterviewScheduled
+		} else {
+			continue
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"admission-module/config"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DocumentStorage stores generated documents under opaque keys
+type DocumentStorage interface {
+	Save(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// LocalStorage keeps documents on the local filesystem below a root directory
+type LocalStorage struct {
+	root string
+}
+
+// NewLocalStorage returns a LocalStorage rooted at dir
+func NewLocalStorage(dir string) *LocalStorage {
+	return &LocalStorage{root: dir}
+}
+
+var (
+	storageOnce     sync.Once
+	documentStorage DocumentStorage
+)
+
+// GetDocumentStorage returns the storage configured by DOCUMENT_STORAGE_DIR
+func GetDocumentStorage() DocumentStorage {
+	storageOnce.Do(func() {
+		documentStorage = NewLocalStorage(config.AppConfig.DocumentStorageDir)
+	})
+	return documentStorage
+}
+
+// Save writes r to the file for key, creating parent directories as needed
+func (s *LocalStorage) Save(ctx context.Context, key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("error creating storage directory: %w", err)
+	}
+
+	// Write to a temp file first so readers never see a partial document
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("error creating document file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing document: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing document: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Open returns a reader for the document stored under key
+func (s *LocalStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// path maps a key to a file below the root, rejecting keys that escape it
+func (s *LocalStorage) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if cleaned == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid storage key: %q", key)
+	}
+	return filepath.Join(s.root, cleaned), nil
+}
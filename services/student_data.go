@@ -0,0 +1,231 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"admission-module/utils"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrStudentDataAlreadyErased is returned when erasing a student whose data was already
+// anonymized, by an earlier erasure or the retention engine
+var ErrStudentDataAlreadyErased = errors.New("this student's personal data has already been erased")
+
+// studentDataStudent is prepended to the queries of a data export: student holds the ID
+// and the decrypted email and phone
+const studentDataStudent = `WITH student AS (SELECT $1::int AS id, $2::text AS email, $3::text AS phone) `
+
+// studentDataSections are the queries of a data export, by section name, each returning
+// one JSON value
+var studentDataSections = map[string]string{
+	// The lead row with its contact details decrypted, without their blind indexes
+	"lead": `SELECT (to_jsonb(sl) - 'email_index' - 'phone_index') || jsonb_build_object('email', student.email, 'phone', student.phone)
+		FROM student_lead sl, student WHERE sl.id = student.id`,
+	"payments": `SELECT COALESCE(json_agg(p ORDER BY p.timestamp), '[]') FROM (
+		SELECT payment_type, id, course_id, amount, status, order_id, payment_id, error_message, timestamp, updated_at,
+			refund_id, refund_amount, refunded_at, dispute_id, dispute_status, disputed_at, coupon_code, discount_amount
+		FROM payments WHERE student_id = (SELECT id FROM student)) p`,
+	"invoices": `SELECT COALESCE(json_agg(i ORDER BY i.issued_at), '[]') FROM (
+		SELECT id, invoice_number, course_id, order_id, installment_no, installment_count, taxable_amount,
+			cgst_amount, sgst_amount, igst_amount, place_of_supply, total_amount, status, payment_id, issued_at, paid_at
+		FROM invoice WHERE student_id = (SELECT id FROM student)) i`,
+	"receipts": `SELECT COALESCE(json_agg(r ORDER BY r.paid_at), '[]') FROM (
+		SELECT id, receipt_number, order_id, payment_id, payment_type, course_id, taxable_amount,
+			cgst_amount, sgst_amount, igst_amount, total_amount, paid_at
+		FROM payment_receipt WHERE student_id = (SELECT id FROM student)) r`,
+	"notes": `SELECT COALESCE(json_agg(n ORDER BY n.created_at), '[]') FROM (
+		SELECT id, note_type, content, follow_up_at, created_at FROM lead_note WHERE lead_id = (SELECT id FROM student)) n`,
+	"timeline": `SELECT COALESCE(json_agg(e ORDER BY e.occurred_at), '[]') FROM (
+		SELECT event_type, payload, occurred_at FROM lead_event WHERE lead_id = (SELECT id FROM student)) e`,
+	"status_history": `SELECT COALESCE(json_agg(h ORDER BY h.changed_at), '[]') FROM (
+		SELECT from_status, to_status, changed_at FROM application_status_history WHERE lead_id = (SELECT id FROM student)) h`,
+	"escalations": `SELECT COALESCE(json_agg(e ORDER BY e.created_at), '[]') FROM (
+		SELECT action, reason, resolved_at, created_at FROM lead_escalation WHERE lead_id = (SELECT id FROM student)) e`,
+	"payment_links": `SELECT COALESCE(json_agg(l ORDER BY l.created_at), '[]') FROM (
+		SELECT order_id, payment_type, channel, created_at FROM payment_link_resend WHERE lead_id = (SELECT id FROM student)) l`,
+	"razorpay_payment_links": `SELECT COALESCE(json_agg(l ORDER BY l.created_at), '[]') FROM (
+		SELECT id, payment_type, course_id, amount, short_url, status, order_id, payment_id, expires_at, paid_at, created_at
+		FROM razorpay_payment_link WHERE lead_id = (SELECT id FROM student)) l`,
+	"enrollment": `SELECT COALESCE(json_agg(s), '[]') FROM (
+		SELECT course_id, status, external_id, synced_at, created_at FROM enrollment_sync WHERE student_id = (SELECT id FROM student)) s`,
+	"emails": `SELECT COALESCE(json_agg(o ORDER BY o.created_at), '[]') FROM (
+		SELECT recipient, subject, body, status, created_at, sent_at
+		FROM email_outbox
+		WHERE lower(recipient) = (SELECT lower(email) FROM student)) o`,
+	"emails_not_sent": `SELECT COALESCE(json_agg(o ORDER BY o.created_at), '[]') FROM (
+		SELECT recipient, category, subject, created_at FROM email_overflow
+		WHERE lower(recipient) = (SELECT lower(email) FROM student)) o`,
+	"lead_reviews": `SELECT COALESCE(json_agg(r ORDER BY r.created_at), '[]') FROM (
+		SELECT r.id, r.status, r.source, r.email, r.phone, r.lead_data, r.reason, r.resolved_at, r.created_at
+		FROM lead_review r, student
+		WHERE lower(r.email) = lower(student.email) OR (student.phone <> '' AND r.phone = student.phone)
+			OR r.resolved_lead_id = student.id OR student.id = ANY(r.matched_lead_ids)) r`,
+	"audit_log": `SELECT COALESCE(json_agg(a ORDER BY a.created_at), '[]') FROM (
+		SELECT action, actor, changes, created_at FROM audit_log
+		WHERE entity_type = 'lead' AND entity_id = (SELECT id::text FROM student)) a`,
+}
+
+// ExportStudentData collects everything held about a student: the lead, payments and
+// invoices, notes, timeline and status history, the emails sent to them and the audit
+// log of changes to their record
+func ExportStudentData(ctx context.Context, studentID int) (*models.StudentDataExport, error) {
+	// Emails, and the reviews of submissions, are matched on the decrypted contact details
+	var email, phone string
+	err := db.DB.QueryRowContext(ctx, "SELECT email, phone FROM student_lead WHERE id = $1", studentID).Scan(
+		utils.DecryptedPII(&email), utils.DecryptedPII(&phone))
+	if err == sql.ErrNoRows {
+		return nil, ErrLeadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching student %d: %w", studentID, err)
+	}
+
+	export := &models.StudentDataExport{
+		StudentID:  studentID,
+		ExportedAt: time.Now(),
+		Sections:   make(map[string]json.RawMessage, len(studentDataSections)),
+	}
+	for name, query := range studentDataSections {
+		var section []byte
+		err := db.DB.QueryRowContext(ctx, studentDataStudent+query, studentID, email, phone).Scan(&section)
+		if err != nil {
+			return nil, fmt.Errorf("error exporting %s of student %d: %w", name, studentID, err)
+		}
+		export.Sections[name] = section
+	}
+	return export, nil
+}
+
+// EraseStudentData anonymizes a student's personal data wherever it is held, in one
+// transaction: the lead's contact details (as the retention engine does), notes and
+// timeline payloads, the emails queued for or dropped to them, matching lead reviews,
+// notifications about the lead and the contact details in Razorpay webhook payloads of
+// their orders. Payments, invoices and receipts are kept for accounting, without personal
+// data; their stored documents are rendered again with the anonymized name. audit is written
+// to the audit log with the erasure, so the two succeed or fail together.
+func EraseStudentData(ctx context.Context, studentID int, audit *models.AuditLogEntry) (*models.StudentDataErasure, error) {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var email, phone string
+	var anonymizedAt sql.NullTime
+	err = tx.QueryRowContext(ctx,
+		"SELECT email, phone, anonymized_at FROM student_lead WHERE id = $1 FOR UPDATE", studentID,
+	).Scan(utils.DecryptedPII(&email), utils.DecryptedPII(&phone), &anonymizedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrLeadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching student %d: %w", studentID, err)
+	}
+	if anonymizedAt.Valid {
+		return nil, ErrStudentDataAlreadyErased
+	}
+
+	var orderIDs pq.StringArray
+	err = tx.QueryRowContext(ctx,
+		"SELECT COALESCE(array_agg(order_id), '{}') FROM payments WHERE student_id = $1 AND order_id IS NOT NULL",
+		studentID).Scan(&orderIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching orders of student %d: %w", studentID, err)
+	}
+
+	anonymizedEmail := fmt.Sprintf("anonymized-%d@invalid", studentID)
+	statements := []struct {
+		section string
+		query   string
+		args    []interface{}
+	}{
+		// The placeholder email is not personal data: it stays in plaintext, without blind
+		// indexes so that lookups never match the lead
+		{"lead", `UPDATE student_lead
+			SET name = $2, email = $3, phone = '', email_index = NULL, phone_index = NULL,
+				education = NULL, meet_link = NULL,
+				remarketing_consent = false, anonymized_at = NOW(), updated_at = NOW()
+			WHERE id = $1`, []interface{}{studentID, anonymizedLeadName, anonymizedEmail}},
+		{"notes", `UPDATE lead_note SET content = '[redacted]' WHERE lead_id = $1`, []interface{}{studentID}},
+		{"timeline", `UPDATE lead_event SET payload = '{}'::jsonb WHERE lead_id = $1`, []interface{}{studentID}},
+		{"notifications", `UPDATE user_notification SET title = '[redacted]', message = NULL WHERE lead_id = $1`,
+			[]interface{}{studentID}},
+		{"payments", `UPDATE registration_payment SET error_message = NULL WHERE student_id = $1 AND error_message IS NOT NULL`,
+			[]interface{}{studentID}},
+		{"payments", `UPDATE course_payment SET error_message = NULL WHERE student_id = $1 AND error_message IS NOT NULL`,
+			[]interface{}{studentID}},
+		// Payment entities in webhook payloads carry the payer's email and phone
+		{"webhooks", `UPDATE razorpay_webhooks
+			SET payload = payload #- '{payload,payment,entity,email}' #- '{payload,payment,entity,contact}'
+			WHERE payload #>> '{payload,payment,entity,order_id}' = ANY($1)`, []interface{}{orderIDs}},
+		{"emails", `UPDATE email_outbox SET recipient = $2, subject = '[redacted]', body = '[redacted]', attachment = NULL
+			WHERE lower(recipient) = lower($1)`, []interface{}{email, anonymizedEmail}},
+		{"emails", `UPDATE email_overflow SET recipient = $2, subject = '[redacted]' WHERE lower(recipient) = lower($1)`,
+			[]interface{}{email, anonymizedEmail}},
+		{"emails", `DELETE FROM email_recipient_quota WHERE lower(recipient) = lower($1)`, []interface{}{email}},
+		{"lead_reviews", `UPDATE lead_review SET email = $4, phone = '', lead_data = '{}'::jsonb, updated_at = NOW()
+			WHERE lower(email) = lower($2) OR ($3 <> '' AND phone = $3) OR resolved_lead_id = $1`,
+			[]interface{}{studentID, email, phone, anonymizedEmail}},
+	}
+
+	erasure := &models.StudentDataErasure{StudentID: studentID, Rows: map[string]int{}}
+	for _, stmt := range statements {
+		res, err := tx.ExecContext(ctx, stmt.query, stmt.args...)
+		if err != nil {
+			return nil, fmt.Errorf("error erasing %s of student %d: %w", stmt.section, studentID, err)
+		}
+		n, _ := res.RowsAffected()
+		erasure.Rows[stmt.section] += int(n)
+	}
+	if err := recordAudit(ctx, tx, audit); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	erasure.ErasedAt = time.Now()
+
+	// Stored invoice and receipt documents still show the student's name until rendered again
+	invoices, err := ListStudentInvoices(ctx, studentID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error listing invoices of erased student %d: %v", studentID, err)
+	}
+	for i := range invoices {
+		if invoices[i].StorageKey == "" {
+			continue
+		}
+		if err := storeInvoiceDocument(ctx, db.DB, &invoices[i]); err != nil {
+			logger.FromContext(ctx).Error("Error re-rendering invoice %s of erased student %d: %v",
+				invoices[i].InvoiceNumber, studentID, err)
+			continue
+		}
+		erasure.Rows["invoice_documents"]++
+	}
+	receipts, err := ListStudentReceipts(ctx, studentID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error listing receipts of erased student %d: %v", studentID, err)
+	}
+	for i := range receipts {
+		if receipts[i].StorageKey == "" {
+			continue
+		}
+		if err := storeReceiptDocument(ctx, db.DB, &receipts[i]); err != nil {
+			logger.FromContext(ctx).Error("Error re-rendering receipt %s of erased student %d: %v",
+				receipts[i].ReceiptNumber, studentID, err)
+			continue
+		}
+		erasure.Rows["receipt_documents"]++
+	}
+
+	logger.FromContext(ctx).Info("Erased personal data of student %d", studentID)
+	return erasure, nil
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrNotificationNotFound is returned when a notification does not exist for the counselor
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// createUserNotification stores an in-app notification within the caller's transaction
+func createUserNotification(ctx context.Context, tx *sql.Tx, n *models.UserNotification) error {
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO user_notification (counselor_id, type, title, message, lead_id, note_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		n.CounselorID, n.Type, n.Title, n.Message, n.LeadID, n.NoteID,
+	).Scan(&n.ID, &n.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating notification: %w", err)
+	}
+	return nil
+}
+
+// GetCounselorNotifications lists a counselor's notifications, newest first
+func GetCounselorNotifications(ctx context.Context, counselorID int64, unreadOnly bool, limit int) ([]models.UserNotification, error) {
+	query := `
+		SELECT id, counselor_id, type, title, message, lead_id, note_id, read_at, created_at
+		FROM user_notification
+		WHERE counselor_id = $1`
+	if unreadOnly {
+		query += " AND read_at IS NULL"
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT $2"
+
+	rows, err := db.DB.QueryContext(ctx, query, counselorID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []models.UserNotification{}
+	for rows.Next() {
+		var n models.UserNotification
+		var leadID, noteID sql.NullInt64
+		var readAt sql.NullTime
+		if err := rows.Scan(&n.ID, &n.CounselorID, &n.Type, &n.Title, &n.Message,
+			&leadID, &noteID, &readAt, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error reading notification: %w", err)
+		}
+		if leadID.Valid {
+			id := int(leadID.Int64)
+			n.LeadID = &id
+		}
+		if noteID.Valid {
+			id := int(noteID.Int64)
+			n.NoteID = &id
+		}
+		if readAt.Valid {
+			n.ReadAt = &readAt.Time
+		}
+		notifications = append(notifications, n)
+	}
+
+	return notifications, rows.Err()
+}
+
+// MarkNotificationRead marks one of the counselor's notifications as read
+func MarkNotificationRead(ctx context.Context, counselorID int64, notificationID int) error {
+	result, err := db.DB.ExecContext(ctx,
+		"UPDATE user_notification SET read_at = COALESCE(read_at, NOW()) WHERE id = $1 AND counselor_id = $2",
+		notificationID, counselorID)
+	if err != nil {
+		return fmt.Errorf("error updating notification: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking update: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
@@ -3,10 +3,16 @@ package services
 import (
 	"admission-module/config"
 	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"admission-module/utils"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
+	"database/sql"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -41,6 +47,7 @@ func VerifyWebhookSignature(payload []byte, signature string) bool {
 
 // RazorpayWebhookHandler handles incoming Razorpay webhooks
 func RazorpayWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	started := time.Now()
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
@@ -74,25 +81,41 @@ func RazorpayWebhookHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("[WEBHOOK] Received: %s", payload.Event)
+	// Keep the request ID but not the cancellation: processing must finish even if
+	// Razorpay disconnects
+	ctx := context.WithoutCancel(r.Context())
+	logger.FromContext(ctx).Info("[WEBHOOK] Received: %s", payload.Event)
 
 	// Log the webhook to database
-	if err := logWebhookToDB(payload, signature, signatureValid, ""); err != nil {
-		log.Printf("Webhook DB logging error: %v", err)
+	webhookID, err := logWebhookToDB(ctx, payload, signature, signatureValid, "")
+	if err != nil {
+		logger.FromContext(ctx).Error("Webhook DB logging error: %v", err)
 	}
 
+	// Record processing latency and outcome for the webhook SLO
+	recorder := &webhookStatusRecorder{ResponseWriter: w, status: http.StatusOK}
+	w = recorder
+	defer func() { recordWebhookOutcome(ctx, webhookID, started, recorder.status) }()
+
 	// Handle different webhook events
 	switch payload.Event {
 	case "payment.authorized":
 		handlePaymentAuthorized(w, payload)
 	case "payment.captured":
-		handlePaymentCaptured(w, payload, signature)
+		handlePaymentCaptured(ctx, w, payload, signature)
 	case "order.paid":
-		handlePaymentCaptured(w, payload, signature)
+		handlePaymentCaptured(ctx, w, payload, signature)
 	case "payment.failed":
-		handlePaymentFailed(w, payload)
+		handlePaymentFailed(ctx, w, payload)
 	case "payment.error":
 		handlePaymentError(w, payload)
+	case "refund.processed", "refund.failed":
+		handleRefundEvent(ctx, w, payload)
+	case "payment.dispute.created", "payment.dispute.won", "payment.dispute.lost", "payment.dispute.closed",
+		"payment.dispute.under_review", "payment.dispute.action_required":
+		handleDisputeEvent(ctx, w, payload)
+	case "payment_link.paid", "payment_link.expired", "payment_link.cancelled":
+		handlePaymentLinkEvent(ctx, w, payload, signature)
 	default:
 		// Acknowledge all webhooks
 		w.WriteHeader(http.StatusOK)
@@ -119,7 +142,7 @@ func handlePaymentAuthorized(w http.ResponseWriter, payload RazorpayWebhookPaylo
 
 // handlePaymentCaptured handles payment.captured event
 // This is the critical event that confirms payment success
-func handlePaymentCaptured(w http.ResponseWriter, payload RazorpayWebhookPayload, signature string) {
+func handlePaymentCaptured(ctx context.Context, w http.ResponseWriter, payload RazorpayWebhookPayload, signature string) {
 	// Extract payment info directly from map
 	paymentMap, ok := payload.Payload["payment"].(map[string]interface{})
 	if !ok {
@@ -146,9 +169,10 @@ func handlePaymentCaptured(w http.ResponseWriter, payload RazorpayWebhookPayload
 	}
 
 	// Process payment in transaction
-	if err := processPaymentCaptured(orderID, paymentID, signature); err != nil {
+	if err := processPaymentCaptured(ctx, orderID, paymentID, signature); err != nil {
+		logger.FromContext(ctx).Error("Error processing captured payment for order %s: %v", orderID, err)
 		// Update webhook processing status in database using webhook ID
-		if updateErr := updateWebhookProcessingStatus(payload.ID, "FAILED", err.Error()); updateErr != nil {
+		if updateErr := updateWebhookProcessingStatus(ctx, payload.ID, "FAILED", err.Error()); updateErr != nil {
 			log.Printf("Error updating webhook status: %v", updateErr)
 		}
 		w.WriteHeader(http.StatusInternalServerError)
@@ -157,7 +181,7 @@ func handlePaymentCaptured(w http.ResponseWriter, payload RazorpayWebhookPayload
 	}
 
 	// Update webhook processing status as successful
-	if updateErr := updateWebhookProcessingStatus(payload.ID, "COMPLETED", ""); updateErr != nil {
+	if updateErr := updateWebhookProcessingStatus(ctx, payload.ID, "COMPLETED", ""); updateErr != nil {
 		log.Printf("Error updating webhook status: %v", updateErr)
 	}
 
@@ -171,7 +195,7 @@ func handlePaymentCaptured(w http.ResponseWriter, payload RazorpayWebhookPayload
 }
 
 // handlePaymentFailed handles payment.failed event
-func handlePaymentFailed(w http.ResponseWriter, payload RazorpayWebhookPayload) {
+func handlePaymentFailed(ctx context.Context, w http.ResponseWriter, payload RazorpayWebhookPayload) {
 	// Extract payment info directly from map
 	paymentMap, ok := payload.Payload["payment"].(map[string]interface{})
 	if !ok {
@@ -206,9 +230,9 @@ func handlePaymentFailed(w http.ResponseWriter, payload RazorpayWebhookPayload)
 	errorMsg := fmt.Sprintf("%s: %s", errorCode, errorDesc)
 
 	// Update payment status to FAILED
-	if err := updatePaymentStatusFailed(orderID, paymentID, errorMsg); err != nil {
-		log.Printf("Error updating failed payment: %v", err)
-		if updateErr := updateWebhookProcessingStatus(payload.ID, "FAILED", err.Error()); updateErr != nil {
+	if err := updatePaymentStatusFailed(ctx, orderID, paymentID, errorMsg); err != nil {
+		logger.FromContext(ctx).Error("Error updating failed payment for order %s: %v", orderID, err)
+		if updateErr := updateWebhookProcessingStatus(ctx, payload.ID, "FAILED", err.Error()); updateErr != nil {
 			log.Printf("Error updating webhook status: %v", updateErr)
 		}
 		w.WriteHeader(http.StatusInternalServerError)
@@ -217,7 +241,7 @@ func handlePaymentFailed(w http.ResponseWriter, payload RazorpayWebhookPayload)
 	}
 
 	// Update webhook processing status
-	if updateErr := updateWebhookProcessingStatus(payload.ID, "COMPLETED", ""); updateErr != nil {
+	if updateErr := updateWebhookProcessingStatus(ctx, payload.ID, "COMPLETED", ""); updateErr != nil {
 		log.Printf("[WEBHOOK] Status update error: %v", updateErr)
 	}
 
@@ -237,8 +261,8 @@ func handlePaymentError(w http.ResponseWriter, payload RazorpayWebhookPayload) {
 }
 
 // processPaymentCaptured processes a successful payment capture
-func processPaymentCaptured(orderID, paymentID, signature string) error {
-	tx, err := db.DB.Begin()
+func processPaymentCaptured(ctx context.Context, orderID, paymentID, signature string) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("error starting transaction: %w", err)
 	}
@@ -247,66 +271,50 @@ func processPaymentCaptured(orderID, paymentID, signature string) error {
 			tx.Rollback()
 		}
 	}()
+	if err = db.TagTransaction(ctx, tx); err != nil {
+		return err
+	}
 
-	// First, determine which payment table this belongs to
-	var studentID int
-	var paymentType string
-	var amount float64
-	var currentStatus string
-
-	// Try registration_payment first
-	err = tx.QueryRow("SELECT student_id, amount, status FROM registration_payment WHERE order_id = $1", orderID).Scan(&studentID, &amount, &currentStatus)
-	if err == nil {
-		paymentType = PaymentTypeRegistration
-	} else {
-		// Try course_payment
-		var courseID int
-
-		err = tx.QueryRow("SELECT student_id, course_id, amount, status FROM course_payment WHERE order_id = $1", orderID).Scan(&studentID, &courseID, &amount, &currentStatus)
-		if err != nil {
-			if rollbackErr := tx.Rollback(); rollbackErr != nil {
-				log.Printf("Rollback error: %v", rollbackErr)
-			}
+	// Find the payment of either type created for this order
+	payments := NewPaymentRepository(tx)
+	payment, err := payments.FindByOrderID(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, ErrPaymentNotFound) {
 			return fmt.Errorf("payment not found for order_id: %s", orderID)
 		}
-		paymentType = PaymentTypeCourseFee
+		return err
 	}
+	studentID := payment.StudentID
+	paymentType := payment.PaymentType
 
-	// Check if payment is already PAID (idempotency)
-	if currentStatus == "PAID" {
+	// Check if payment is already PAID, or refunded since (idempotency)
+	if payment.Status == PaymentStatusPaid || isRefundedStatus(payment.Status) {
 		if err = tx.Commit(); err != nil {
 			return fmt.Errorf("error committing transaction: %w", err)
 		}
 
-		// Still publish the event in case it failed on the first webhook
-		publishPaymentVerifiedFromWebhook(studentID, orderID, paymentID, paymentType)
-
+		// No republish needed: payment.verified was written to the event outbox
+		// together with the first capture and the relay retries it until delivered
 		return nil
 	}
 
-	// Update payment status to PAID
-	if paymentType == PaymentTypeRegistration {
-		_, err = tx.Exec(
-			"UPDATE registration_payment SET status = $1, payment_id = $2, razorpay_sign = $3, updated_at = CURRENT_TIMESTAMP WHERE order_id = $4",
-			"PAID", paymentID, signature, orderID)
-		if err != nil {
-			if rollbackErr := tx.Rollback(); rollbackErr != nil {
-				log.Printf("Rollback error: %v", rollbackErr)
-			}
-			return fmt.Errorf("error updating registration payment: %w", err)
-		}
+	if err = payments.MarkPaid(ctx, payment, paymentID, signature); err != nil {
+		return err
+	}
 
-		// Get the registration_payment ID
-		var registrationPaymentID int
-		err = tx.QueryRow("SELECT id FROM registration_payment WHERE order_id = $1", orderID).Scan(&registrationPaymentID)
-		if err != nil {
-			log.Printf("Warning retrieving registration_payment ID: %v", err)
-		}
+	// The receipt goes out with the confirmation email; if its PDF cannot be stored now, the
+	// email goes without it and the PDF is rendered on download
+	receipt, err := issuePaymentReceipt(ctx, tx, payment, paymentID)
+	if err != nil {
+		return err
+	}
+	attachment := receiptAttachment(ctx, tx, receipt)
 
+	if paymentType == PaymentTypeRegistration {
 		// Update student_lead registration_fee_status
-		_, err = tx.Exec(
+		_, err = tx.ExecContext(ctx,
 			"UPDATE student_lead SET registration_fee_status = 'PAID', registration_payment_id = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2",
-			registrationPaymentID, studentID)
+			payment.ID, studentID)
 		if err != nil {
 			if rollbackErr := tx.Rollback(); rollbackErr != nil {
 				log.Printf("Rollback error: %v", rollbackErr)
@@ -316,7 +324,7 @@ func processPaymentCaptured(orderID, paymentID, signature string) error {
 
 		// Set interview_scheduled_at to 1 hour from now
 		interviewTime := time.Now().Add(time.Hour)
-		_, err = tx.Exec(
+		_, err = tx.ExecContext(ctx,
 			"UPDATE student_lead SET interview_scheduled_at = $1, application_status = 'INTERVIEW_SCHEDULED', updated_at = CURRENT_TIMESTAMP WHERE id = $2",
 			interviewTime, studentID)
 		if err != nil {
@@ -326,83 +334,73 @@ func processPaymentCaptured(orderID, paymentID, signature string) error {
 			return fmt.Errorf("error updating student interview: %w", err)
 		}
 	} else {
-		_, err = tx.Exec(
-			"UPDATE course_payment SET status = $1, payment_id = $2, razorpay_sign = $3, updated_at = CURRENT_TIMESTAMP WHERE order_id = $4",
-			"PAID", paymentID, signature, orderID)
+		// Update student_lead course_fee_status
+		_, err = tx.ExecContext(ctx,
+			"UPDATE student_lead SET course_fee_status = 'PAID', course_payment_id = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2",
+			payment.ID, studentID)
 		if err != nil {
 			if rollbackErr := tx.Rollback(); rollbackErr != nil {
 				log.Printf("Rollback error: %v", rollbackErr)
 			}
-			return fmt.Errorf("error updating course payment: %w", err)
+			return fmt.Errorf("error updating student course fee: %w", err)
+		}
+		if err = markInvoicePaid(ctx, tx, payment.ID, paymentID); err != nil {
+			return err
+		}
+		if err = accrueCommission(ctx, tx, payment); err != nil {
+			return err
 		}
 
-		// Get the course_payment ID
-		var coursePaymentID int
-		err = tx.QueryRow("SELECT id FROM course_payment WHERE order_id = $1", orderID).Scan(&coursePaymentID)
-		if err != nil {
-			log.Printf("Warning retrieving course_payment ID: %v", err)
+		// The student is now enrolled: queue the handoff to the LMS/ERP and the confirmation email
+		if err = queueEnrollmentSync(ctx, tx, orderID); err != nil {
+			return err
+		}
+		if err = enqueueEnrollmentEmail(ctx, tx, studentID, orderID, attachment); err != nil {
+			return err
 		}
+	}
 
-		// Update student_lead course_fee_status
-		_, err = tx.Exec(
-			"UPDATE student_lead SET course_fee_status = 'PAID', course_payment_id = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2",
-			coursePaymentID, studentID)
-		if err != nil {
-			if rollbackErr := tx.Rollback(); rollbackErr != nil {
-				log.Printf("Rollback error: %v", rollbackErr)
-			}
-			return fmt.Errorf("error updating student course fee: %w", err)
+	// Queue payment.verified (and the interview for registration payments) in the
+	// same transaction so the events cannot be lost once the payment is marked PAID
+	if err = enqueuePaymentVerifiedFromWebhook(ctx, tx, studentID, orderID, paymentID, paymentType); err != nil {
+		return err
+	}
+	if paymentType == PaymentTypeRegistration {
+		if err = enqueuePaymentReceiptEmail(ctx, tx, receipt, attachment); err != nil {
+			return err
+		}
+		if err = enqueueInterviewAfterPayment(ctx, tx, studentID); err != nil {
+			return err
 		}
 	}
 
 	if err = tx.Commit(); err != nil {
 		return fmt.Errorf("error committing transaction: %w", err)
 	}
-
-	// Publish payment.verified event to Kafka
-	publishPaymentVerifiedFromWebhook(studentID, orderID, paymentID, paymentType)
-
-	// If registration payment, schedule interview
-	if paymentType == PaymentTypeRegistration {
-		scheduleInterviewAfterPayment(studentID)
-	}
 	return nil
 }
 
 // updatePaymentStatusFailed updates payment status to FAILED
-func updatePaymentStatusFailed(orderID, paymentID, errorMsg string) error {
-	tx, err := db.DB.Begin()
+func updatePaymentStatusFailed(ctx context.Context, orderID, paymentID, errorMsg string) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("error starting transaction: %w", err)
 	}
 	defer tx.Rollback()
-
-	// Try to update registration_payment first
-	result, err := tx.Exec(
-		"UPDATE registration_payment SET status = $1, payment_id = $2, error_message = $3, updated_at = CURRENT_TIMESTAMP WHERE order_id = $4",
-		"FAILED", paymentID, errorMsg, orderID)
-	if err != nil {
-		return fmt.Errorf("error updating registration payment: %w", err)
+	if err = db.TagTransaction(ctx, tx); err != nil {
+		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	payments := NewPaymentRepository(tx)
+	payment, err := payments.FindByOrderID(ctx, orderID)
 	if err != nil {
-		return fmt.Errorf("error checking rows affected: %w", err)
-	}
-
-	// If no rows in registration_payment, try course_payment
-	if rowsAffected == 0 {
-		result, err = tx.Exec(
-			"UPDATE course_payment SET status = $1, payment_id = $2, error_message = $3, updated_at = CURRENT_TIMESTAMP WHERE order_id = $4",
-			"FAILED", paymentID, errorMsg, orderID)
-		if err != nil {
-			return fmt.Errorf("error updating course payment: %w", err)
-		}
-
-		rowsAffected, _ = result.RowsAffected()
-		if rowsAffected == 0 {
+		if errors.Is(err, ErrPaymentNotFound) {
 			return fmt.Errorf("payment not found for order_id: %s", orderID)
 		}
+		return err
+	}
+	if err = payments.MarkFailed(ctx, payment, paymentID, errorMsg); err != nil {
+		return err
 	}
 
 	if err = tx.Commit(); err != nil {
@@ -412,12 +410,12 @@ func updatePaymentStatusFailed(orderID, paymentID, errorMsg string) error {
 	return nil
 }
 
-// logWebhookToDB logs the webhook event to database
-func logWebhookToDB(payload RazorpayWebhookPayload, signature string, signatureValid bool, errorMsg string) error {
+// logWebhookToDB logs the webhook event to database and returns the webhook ID it was stored under
+func logWebhookToDB(ctx context.Context, payload RazorpayWebhookPayload, signature string, signatureValid bool, errorMsg string) (string, error) {
 	payloadJSON, err := json.Marshal(payload.Payload)
 	if err != nil {
 		log.Printf("Error marshaling webhook payload: %v", err)
-		return fmt.Errorf("error marshaling payload: %w", err)
+		return "", fmt.Errorf("error marshaling payload: %w", err)
 	}
 
 	webhookID := payload.ID
@@ -426,26 +424,48 @@ func logWebhookToDB(payload RazorpayWebhookPayload, signature string, signatureV
 		webhookID = fmt.Sprintf("webhook_%d_%s", time.Now().UnixNano(), payload.Event)
 	}
 
-	// Log to razorpay_webhooks table - with ON CONFLICT for idempotency
-	// Handles duplicate webhook_id (same webhook sent twice by Razorpay)
-	_, err = db.DB.Exec(
-		`INSERT INTO razorpay_webhooks (webhook_id, event_type, payload, status, retry_count, signature_valid)
-		 VALUES ($1, $2, $3, $4, $5, $6)
-		 ON CONFLICT (webhook_id) DO UPDATE
-		 SET updated_at = CURRENT_TIMESTAMP, retry_count = razorpay_webhooks.retry_count + 1, signature_valid = EXCLUDED.signature_valid`,
-		webhookID, payload.Event, string(payloadJSON), "RECEIVED", 0, signatureValid)
+	// Handles duplicate webhook_id (same webhook sent twice by Razorpay). razorpay_webhooks is
+	// partitioned by month and cannot enforce unique IDs, so razorpay_webhook_key does; it
+	// also holds the created_at of the first delivery, which locates its row for the update.
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var createdAt time.Time
+	var firstDelivery bool
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO razorpay_webhook_key (webhook_id, created_at) VALUES ($1, CURRENT_TIMESTAMP)
+		 ON CONFLICT (webhook_id) DO UPDATE SET webhook_id = EXCLUDED.webhook_id
+		 RETURNING created_at, xmax = 0`, webhookID).Scan(&createdAt, &firstDelivery)
+	if err == nil && firstDelivery {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO razorpay_webhooks (webhook_id, event_type, payload, status, retry_count, signature_valid, request_id, created_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8)`,
+			webhookID, payload.Event, string(payloadJSON), "RECEIVED", 0, signatureValid, logger.RequestIDFromContext(ctx), createdAt)
+	} else if err == nil {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE razorpay_webhooks
+			 SET updated_at = CURRENT_TIMESTAMP, retry_count = retry_count + 1, signature_valid = $2, request_id = NULLIF($3, '')
+			 WHERE webhook_id = $1 AND created_at = $4`,
+			webhookID, signatureValid, logger.RequestIDFromContext(ctx), createdAt)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
 
 	if err != nil {
 		log.Printf("❌ Error inserting webhook to database: %v", err)
-		return fmt.Errorf("error inserting webhook: %w", err)
+		return "", fmt.Errorf("error inserting webhook: %w", err)
 	}
 
 	log.Printf("✓ Webhook logged to database with ID: %s", webhookID)
-	return nil
+	return webhookID, nil
 }
 
 // updateWebhookProcessingStatus updates the processing status of a webhook in database
-func updateWebhookProcessingStatus(webhookID, processingStatus, errorMsg string) error {
+func updateWebhookProcessingStatus(ctx context.Context, webhookID, processingStatus, errorMsg string) error {
 	status := "PROCESSED"
 	if processingStatus == "FAILED" {
 		status = "FAILED"
@@ -456,8 +476,9 @@ func updateWebhookProcessingStatus(webhookID, processingStatus, errorMsg string)
 		errorMsg = errorMsg[:500]
 	}
 
-	_, err := db.DB.Exec(
-		"UPDATE razorpay_webhooks SET status = $1, processed_at = CURRENT_TIMESTAMP, error_message = $2 WHERE webhook_id = $3",
+	_, err := db.DB.ExecContext(ctx,
+		`UPDATE razorpay_webhooks SET status = $1, processed_at = CURRENT_TIMESTAMP, error_message = $2
+		 WHERE webhook_id = $3 AND created_at = (SELECT created_at FROM razorpay_webhook_key WHERE webhook_id = $3)`,
 		status, errorMsg, webhookID)
 
 	if err != nil {
@@ -467,46 +488,68 @@ func updateWebhookProcessingStatus(webhookID, processingStatus, errorMsg string)
 	return nil
 }
 
-// publishPaymentVerifiedFromWebhook publishes payment.verified event to Kafka
-func publishPaymentVerifiedFromWebhook(studentID int, orderID, paymentID, paymentType string) {
-	go func() {
-		evt := map[string]interface{}{
-			"event":        "payment.verified",
-			"student_id":   studentID,
-			"order_id":     orderID,
-			"payment_id":   paymentID,
-			"payment_type": paymentType,
-			"source":       "webhook",
-			"status":       "PAID",
-			"ts":           time.Now().UTC().Format(time.RFC3339),
-		}
-		if err := Publish("payments", fmt.Sprintf("student-%d", studentID), evt); err != nil {
-			log.Printf("Warning: failed to publish payment.verified event from webhook: %v", err)
-		}
-	}()
+// enqueuePaymentVerifiedFromWebhook writes payment.verified to the event outbox
+func enqueuePaymentVerifiedFromWebhook(ctx context.Context, tx *sql.Tx, studentID int, orderID, paymentID, paymentType string) error {
+	evt := map[string]interface{}{
+		"event":        "payment.verified",
+		"student_id":   studentID,
+		"order_id":     orderID,
+		"payment_id":   paymentID,
+		"payment_type": paymentType,
+		"source":       "webhook",
+		"status":       "PAID",
+		"ts":           time.Now().UTC().Format(time.RFC3339),
+	}
+	return EnqueueEvent(ctx, tx, "payments", fmt.Sprintf("student-%d", studentID), evt)
 }
 
-// scheduleInterviewAfterPayment schedules an interview after successful registration payment
-func scheduleInterviewAfterPayment(studentID int) {
-	go func() {
-		// Get student details
-		var name, email string
-		err := db.DB.QueryRow("SELECT name, email FROM student_lead WHERE id = $1", studentID).Scan(&name, &email)
-		if err != nil {
-			log.Printf("Error fetching student details: %v", err)
-			return
-		}
+// enqueueEnrollmentEmail writes the enrollment confirmation email, with the course's
+// enrollment content blocks and the payment receipt attached, to the event outbox
+func enqueueEnrollmentEmail(ctx context.Context, tx *sql.Tx, studentID int, orderID, attachment string) error {
+	var name, email, courseName, batch string
+	var courseID int
+	err := tx.QueryRowContext(ctx, `
+		SELECT sl.name, sl.email, c.id, c.name, COALESCE(c.batch, '')
+		FROM course_payment cp
+		JOIN student_lead sl ON sl.id = cp.student_id
+		JOIN course c ON c.id = cp.course_id
+		WHERE cp.order_id = $1`, orderID).Scan(&name, utils.DecryptedPII(&email), &courseID, &courseName, &batch)
+	if err != nil {
+		return fmt.Errorf("error fetching enrollment details: %w", err)
+	}
 
-		// Publish interview.schedule event to emails topic (for unified Kafka consumer processing)
-		evt := map[string]interface{}{
-			"event":      "interview.schedule",
-			"student_id": studentID,
-			"name":       name,
-			"email":      email,
-			"ts":         time.Now().UTC().Format(time.RFC3339),
-		}
-		if err := Publish("emails", fmt.Sprintf("student-%d", studentID), evt); err != nil {
-			log.Printf("Warning: failed to publish interview.schedule event: %v", err)
-		}
-	}()
+	subject, body := buildEnrollmentEmail(name, courseName, batch,
+		renderCourseContentBlocks(ctx, courseID, models.ContentBlockEmailEnrollment))
+	evt := map[string]interface{}{
+		"event":      "email.send",
+		"category":   EmailCategoryTransactional,
+		"recipient":  email,
+		"subject":    subject,
+		"body":       body,
+		"student_id": studentID,
+		"timestamp":  time.Now().UTC().Format(time.RFC3339),
+	}
+	if attachment != "" {
+		evt["attachment"] = attachment
+	}
+	return EnqueueEvent(ctx, tx, "emails", fmt.Sprintf("email-%s", email), evt)
+}
+
+// enqueueInterviewAfterPayment queues the interview.schedule event after a successful registration payment
+func enqueueInterviewAfterPayment(ctx context.Context, tx *sql.Tx, studentID int) error {
+	var name, email string
+	err := tx.QueryRowContext(ctx, "SELECT name, email FROM student_lead WHERE id = $1", studentID).Scan(&name, utils.DecryptedPII(&email))
+	if err != nil {
+		return fmt.Errorf("error fetching student details: %w", err)
+	}
+
+	// interview.schedule goes to the emails topic (for unified Kafka consumer processing)
+	evt := map[string]interface{}{
+		"event":      "interview.schedule",
+		"student_id": studentID,
+		"name":       name,
+		"email":      email,
+		"ts":         time.Now().UTC().Format(time.RFC3339),
+	}
+	return EnqueueEvent(ctx, tx, "emails", fmt.Sprintf("student-%d", studentID), evt)
 }
@@ -0,0 +1,388 @@
+package services
+
+import (
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Razorpay's refund statuses, as stored in payment_refund
+const (
+	RefundStatusPending   = "pending"
+	RefundStatusProcessed = "processed"
+	RefundStatusFailed    = "failed"
+)
+
+// Razorpay's dispute statuses, as stored in dispute_status
+const (
+	DisputeStatusOpen        = "open"
+	DisputeStatusUnderReview = "under_review"
+	DisputeStatusWon         = "won"
+	DisputeStatusLost        = "lost"
+	DisputeStatusClosed      = "closed"
+)
+
+// refundActor is the commission ledger's created_by for refunds made outside the API
+const refundActor = "razorpay"
+
+// webhookEntity returns payload.Payload[name]["entity"]
+func webhookEntity(payload RazorpayWebhookPayload, name string) (map[string]interface{}, bool) {
+	wrapper, ok := payload.Payload[name].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	entity, ok := wrapper["entity"].(map[string]interface{})
+	return entity, ok
+}
+
+// handleRefundEvent handles refund.processed and refund.failed. Refunds made in the
+// Razorpay dashboard are recorded as if made through POST /refund-payment; a failed refund
+// is taken off its payment again.
+func handleRefundEvent(ctx context.Context, w http.ResponseWriter, payload RazorpayWebhookPayload) {
+	refundEntity, ok := webhookEntity(payload, "refund")
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid refund data structure"})
+		return
+	}
+	paymentEntity, ok := webhookEntity(payload, "payment")
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid payment data structure"})
+		return
+	}
+
+	refundID, _ := refundEntity["id"].(string)
+	orderID, _ := paymentEntity["order_id"].(string)
+	amountPaise, _ := refundEntity["amount"].(float64)
+	if refundID == "" || orderID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Missing refund id or order_id"})
+		return
+	}
+	status := RefundStatusProcessed
+	if payload.Event == "refund.failed" {
+		status = RefundStatusFailed
+	}
+
+	if err := processRefundWebhook(ctx, orderID, refundID, amountPaise/100, status); err != nil {
+		logger.FromContext(ctx).Error("Error processing %s of refund %s for order %s: %v", payload.Event, refundID, orderID, err)
+		if updateErr := updateWebhookProcessingStatus(ctx, payload.ID, "FAILED", err.Error()); updateErr != nil {
+			log.Printf("Error updating webhook status: %v", updateErr)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	if updateErr := updateWebhookProcessingStatus(ctx, payload.ID, "COMPLETED", ""); updateErr != nil {
+		log.Printf("Error updating webhook status: %v", updateErr)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "processed",
+		"event":     payload.Event,
+		"order_id":  orderID,
+		"refund_id": refundID,
+	})
+}
+
+// processRefundWebhook brings a refund to status and, when that changes anything, tells
+// the lead's counselor. Redelivered webhooks change nothing.
+func processRefundWebhook(ctx context.Context, orderID, refundID string, amount float64, status string) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err = db.TagTransaction(ctx, tx); err != nil {
+		return err
+	}
+
+	payment, err := NewPaymentRepository(tx).FindByOrderID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+
+	var recordedStatus string
+	var recordedAmount float64
+	var commissionEntryID sql.NullInt64
+	err = tx.QueryRowContext(ctx,
+		"SELECT status, amount, commission_entry_id FROM payment_refund WHERE refund_id = $1 FOR UPDATE",
+		refundID).Scan(&recordedStatus, &recordedAmount, &commissionEntryID)
+	known := err == nil
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("error reading refund %s: %w", refundID, err)
+	}
+	if known && recordedStatus == status {
+		return tx.Commit()
+	}
+
+	refund := &models.PaymentRefund{
+		RefundID:     refundID,
+		RefundStatus: status,
+		StudentID:    payment.StudentID,
+		OrderID:      payment.OrderID,
+		PaymentID:    payment.PaymentID,
+		PaymentType:  payment.PaymentType,
+		Amount:       roundAmount(amount),
+		RefundedAt:   time.Now(),
+	}
+	switch {
+	case status == RefundStatusProcessed && !known:
+		// Made in the Razorpay dashboard rather than through the API
+		if _, err := applyRefund(ctx, tx, payment, refund, "Refunded in Razorpay", refundActor); err != nil {
+			return err
+		}
+
+	case status == RefundStatusFailed && !known:
+		// Never counted, so there is nothing to take back
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO payment_refund (refund_id, order_id, payment_type, amount, status)
+			VALUES ($1, $2, $3, $4, $5)`,
+			refundID, payment.OrderID, payment.PaymentType, refund.Amount, status)
+		if err != nil {
+			return fmt.Errorf("error recording refund %s: %w", refundID, err)
+		}
+
+	case status == RefundStatusFailed:
+		refund.Amount = recordedAmount
+		if err := revertRefund(ctx, tx, payment, refund, commissionEntryID); err != nil {
+			return err
+		}
+
+	default:
+		// A pending refund made through the API has been processed
+		_, err := tx.ExecContext(ctx, "UPDATE payment_refund SET status = $1, updated_at = NOW() WHERE refund_id = $2",
+			status, refundID)
+		if err != nil {
+			return fmt.Errorf("error updating refund %s: %w", refundID, err)
+		}
+	}
+
+	if err := enqueueCounselorPaymentEmail(ctx, tx, payment.StudentID, func(counselorName, studentName string) (string, string) {
+		return buildRefundCounselorEmail(counselorName, studentName, payment.OrderID, refundID, refund.Amount, status)
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	logger.FromContext(ctx).Info("Refund %s of order %s is %s", refundID, orderID, status)
+	return nil
+}
+
+// revertRefund takes a failed refund, counted when it was made, off its payment again and
+// reinstates the commission its reversal took from the counselor
+func revertRefund(ctx context.Context, tx *sql.Tx, payment *models.Payment, refund *models.PaymentRefund, commissionEntryID sql.NullInt64) error {
+	table, err := paymentTable(payment.PaymentType)
+	if err != nil {
+		return err
+	}
+	feeStatusColumn := "registration_fee_status"
+	if payment.PaymentType == PaymentTypeCourseFee {
+		feeStatusColumn = "course_fee_status"
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE payment_refund SET status = $1, updated_at = NOW() WHERE refund_id = $2",
+		RefundStatusFailed, refund.RefundID)
+	if err != nil {
+		return fmt.Errorf("error updating refund %s: %w", refund.RefundID, err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE `+table+`
+		SET refund_amount = GREATEST(COALESCE(refund_amount, 0) - $1, 0),
+			status = CASE WHEN COALESCE(refund_amount, 0) - $1 > 0 THEN $2 ELSE $3 END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4
+		RETURNING refund_amount, status`,
+		refund.Amount, PaymentStatusPartiallyRefunded, PaymentStatusPaid, payment.ID,
+	).Scan(&refund.TotalRefunded, &refund.PaymentStatus)
+	if err != nil {
+		return fmt.Errorf("error updating %s: %w", table, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE student_lead SET "+feeStatusColumn+" = $1, updated_at = NOW() WHERE id = $2",
+		refund.PaymentStatus, payment.StudentID)
+	if err != nil {
+		return fmt.Errorf("error updating %s: %w", feeStatusColumn, err)
+	}
+
+	if !commissionEntryID.Valid {
+		return nil
+	}
+	var reversal models.CommissionEntry
+	var studentID, courseID, coursePaymentID sql.NullInt64
+	err = tx.QueryRowContext(ctx,
+		"SELECT counselor_id, student_id, course_id, course_payment_id, amount FROM commission_entry WHERE id = $1",
+		commissionEntryID.Int64).Scan(&reversal.CounselorID, &studentID, &courseID, &coursePaymentID, &reversal.Amount)
+	if err != nil {
+		return fmt.Errorf("error reading commission reversal of refund %s: %w", refund.RefundID, err)
+	}
+	return insertCommissionEntry(ctx, tx, &models.CommissionEntry{
+		CounselorID:     reversal.CounselorID,
+		StudentID:       nullableInt(studentID),
+		CourseID:        nullableInt(courseID),
+		CoursePaymentID: nullableInt(coursePaymentID),
+		EntryType:       models.CommissionEntryAdjustment,
+		Amount:          -reversal.Amount,
+		Reason:          "Refund " + refund.RefundID + " failed",
+		CreatedBy:       refundActor,
+	})
+}
+
+// handleDisputeEvent handles the payment.dispute.* events, flagging the disputed payment
+func handleDisputeEvent(ctx context.Context, w http.ResponseWriter, payload RazorpayWebhookPayload) {
+	disputeEntity, ok := webhookEntity(payload, "dispute")
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid dispute data structure"})
+		return
+	}
+	paymentEntity, ok := webhookEntity(payload, "payment")
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid payment data structure"})
+		return
+	}
+
+	disputeID, _ := disputeEntity["id"].(string)
+	orderID, _ := paymentEntity["order_id"].(string)
+	if disputeID == "" || orderID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Missing dispute id or order_id"})
+		return
+	}
+	// payment.dispute.action_required and .under_review carry no status of their own
+	status, _ := disputeEntity["status"].(string)
+	if status == "" {
+		status = strings.TrimPrefix(payload.Event, "payment.dispute.")
+	}
+	dispute := disputeNotice{ID: disputeID, Status: status}
+	dispute.ReasonCode, _ = disputeEntity["reason_code"].(string)
+	if amountPaise, ok := disputeEntity["amount"].(float64); ok {
+		dispute.Amount = amountPaise / 100
+	}
+	if respondBy, ok := disputeEntity["respond_by"].(float64); ok && respondBy > 0 {
+		t := time.Unix(int64(respondBy), 0)
+		dispute.RespondBy = &t
+	}
+
+	if err := processDisputeWebhook(ctx, orderID, dispute); err != nil {
+		logger.FromContext(ctx).Error("Error processing %s of dispute %s for order %s: %v", payload.Event, disputeID, orderID, err)
+		if updateErr := updateWebhookProcessingStatus(ctx, payload.ID, "FAILED", err.Error()); updateErr != nil {
+			log.Printf("Error updating webhook status: %v", updateErr)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	if updateErr := updateWebhookProcessingStatus(ctx, payload.ID, "COMPLETED", ""); updateErr != nil {
+		log.Printf("Error updating webhook status: %v", updateErr)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":     "processed",
+		"event":      payload.Event,
+		"order_id":   orderID,
+		"dispute_id": disputeID,
+	})
+}
+
+// disputeNotice is the part of a Razorpay dispute recorded and passed on to the counselor
+type disputeNotice struct {
+	ID         string
+	Status     string
+	ReasonCode string
+	Amount     float64
+	RespondBy  *time.Time
+}
+
+// processDisputeWebhook records the dispute on its payment and, when its status changed,
+// tells the lead's counselor
+func processDisputeWebhook(ctx context.Context, orderID string, dispute disputeNotice) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err = db.TagTransaction(ctx, tx); err != nil {
+		return err
+	}
+
+	payment, err := NewPaymentRepository(tx).FindByOrderID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	table, err := paymentTable(payment.PaymentType)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE `+table+`
+		SET dispute_id = $1, dispute_status = $2, disputed_at = COALESCE(disputed_at, NOW()), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3 AND (dispute_id IS DISTINCT FROM $1 OR dispute_status IS DISTINCT FROM $2)`,
+		dispute.ID, dispute.Status, payment.ID)
+	if err != nil {
+		return fmt.Errorf("error updating %s: %w", table, err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return tx.Commit()
+	}
+
+	if err := enqueueCounselorPaymentEmail(ctx, tx, payment.StudentID, func(counselorName, studentName string) (string, string) {
+		return buildDisputeCounselorEmail(counselorName, studentName, payment.OrderID, dispute)
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	logger.FromContext(ctx).Warn("Payment of order %s is disputed (%s, %s)", orderID, dispute.ID, dispute.Status)
+	return nil
+}
+
+// enqueueCounselorPaymentEmail writes the email render builds for the counselor assigned to
+// a student to the event outbox. Students without a counselor are skipped.
+func enqueueCounselorPaymentEmail(ctx context.Context, tx *sql.Tx, studentID int, render func(counselorName, studentName string) (subject, body string)) error {
+	var counselorName, counselorEmail, studentName string
+	err := tx.QueryRowContext(ctx, `
+		SELECT c.name, c.email, sl.name
+		FROM student_lead sl
+		JOIN counselor c ON c.id = sl.counselor_id
+		WHERE sl.id = $1`, studentID).Scan(&counselorName, &counselorEmail, &studentName)
+	if err == sql.ErrNoRows {
+		logger.FromContext(ctx).Warn("Student %d has no counselor to notify about their payment", studentID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error fetching counselor of student %d: %w", studentID, err)
+	}
+
+	subject, body := render(counselorName, studentName)
+	evt := map[string]interface{}{
+		"event":      "email.send",
+		"category":   EmailCategoryStaff,
+		"recipient":  counselorEmail,
+		"subject":    subject,
+		"body":       body,
+		"student_id": studentID,
+		"timestamp":  time.Now().UTC().Format(time.RFC3339),
+	}
+	return EnqueueEvent(ctx, tx, "emails", fmt.Sprintf("email-%s", counselorEmail), evt)
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"admission-module/config"
+	"admission-module/db"
+	"admission-module/logger"
+	"admission-module/models"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// sloWindow is a rolling window the webhook SLO is evaluated over
+type sloWindow struct {
+	name     string
+	duration time.Duration
+}
+
+// webhookSLOWindows are evaluated on every report; the short pairs drive burn rate alerts
+var webhookSLOWindows = []sloWindow{
+	{"5m", 5 * time.Minute},
+	{"30m", 30 * time.Minute},
+	{"1h", time.Hour},
+	{"6h", 6 * time.Hour},
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+}
+
+// Multi-window burn rate thresholds: a fast burn spends 2% of a 30-day budget in an hour,
+// a slow burn 5% in six hours
+const (
+	fastBurnThreshold = 14.4
+	slowBurnThreshold = 6
+	sloAlertCooldown  = time.Hour
+)
+
+var (
+	sloAlertMutex sync.Mutex
+	lastSLOAlert  time.Time
+)
+
+// webhookStatusRecorder captures the status code written by a webhook handler
+type webhookStatusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *webhookStatusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// recordWebhookOutcome stores how long a webhook took to process and whether it succeeded
+func recordWebhookOutcome(ctx context.Context, webhookID string, started time.Time, status int) {
+	if db.DB == nil || webhookID == "" {
+		return
+	}
+
+	elapsed := time.Since(started).Milliseconds()
+	_, err := db.DB.ExecContext(ctx,
+		`UPDATE razorpay_webhooks SET processing_ms = $1, processing_ok = $2
+		 WHERE webhook_id = $3 AND created_at = (SELECT created_at FROM razorpay_webhook_key WHERE webhook_id = $3)`,
+		elapsed, status < http.StatusInternalServerError, webhookID)
+	if err != nil {
+		logger.Warn("Could not record webhook latency for %s: %v", webhookID, err)
+	}
+}
+
+// GetWebhookSLO computes webhook SLO compliance and burn rate over the rolling windows
+func GetWebhookSLO(ctx context.Context, now time.Time) (*models.SLOReport, error) {
+	target := config.AppConfig.WebhookSLOTarget
+	threshold := config.AppConfig.WebhookSLOLatencyMs
+
+	report := &models.SLOReport{
+		Objective:          fmt.Sprintf("%.2f%% of payment webhooks processed successfully in under %dms", target*100, threshold),
+		Target:             target,
+		LatencyThresholdMs: threshold,
+		Windows:            make([]models.SLOWindow, 0, len(webhookSLOWindows)),
+	}
+
+	burn := map[string]float64{}
+	for _, w := range webhookSLOWindows {
+		window := models.SLOWindow{Window: w.name, Compliance: 1}
+		err := db.DB.QueryRowContext(ctx, `
+			SELECT COUNT(*),
+			       COUNT(*) FILTER (WHERE processing_ok AND processing_ms <= $2),
+			       COUNT(*) FILTER (WHERE NOT processing_ok),
+			       COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY processing_ms), 0),
+			       COALESCE(PERCENTILE_CONT(0.99) WITHIN GROUP (ORDER BY processing_ms), 0)
+			FROM razorpay_webhooks
+			WHERE processing_ms IS NOT NULL AND created_at >= $1`,
+			now.Add(-w.duration), threshold,
+		).Scan(&window.Total, &window.Good, &window.Failed, &window.P50LatencyMs, &window.P99LatencyMs)
+		if err != nil {
+			return nil, fmt.Errorf("error computing webhook SLO for %s: %w", w.name, err)
+		}
+
+		if window.Total > 0 {
+			window.Compliance = float64(window.Good) / float64(window.Total)
+		}
+		if target < 1 {
+			window.BurnRate = (1 - window.Compliance) / (1 - target)
+		}
+		burn[w.name] = window.BurnRate
+		report.Windows = append(report.Windows, window)
+	}
+
+	report.FastBurn = burn["5m"] > fastBurnThreshold && burn["1h"] > fastBurnThreshold
+	report.SlowBurn = burn["30m"] > slowBurnThreshold && burn["6h"] > slowBurnThreshold
+	return report, nil
+}
+
+// CheckWebhookSLO alerts SLO_ALERT_EMAIL (or DLQ_ALERT_EMAIL) when the webhook error budget
+// is burning fast. Alerts are sent at most once per cooldown. Runs as a scheduled job.
+func CheckWebhookSLO(ctx context.Context) error {
+	if db.DB == nil {
+		return nil
+	}
+
+	report, err := GetWebhookSLO(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if !report.FastBurn && !report.SlowBurn {
+		return nil
+	}
+
+	target := config.AppConfig.SLOAlertEmail
+	if target == "" {
+		target = config.AppConfig.DLQAlertEmail
+	}
+
+	sloAlertMutex.Lock()
+	defer sloAlertMutex.Unlock()
+	if time.Since(lastSLOAlert) < sloAlertCooldown {
+		return nil
+	}
+
+	logger.Warn("Payment webhook SLO burn rate high (fast=%t slow=%t)", report.FastBurn, report.SlowBurn)
+	if target == "" {
+		lastSLOAlert = time.Now()
+		return nil
+	}
+
+	if err := DeliverEmail(target, "[SLO] Payment webhook error budget burning", formatSLOAlert(report)); err != nil {
+		return fmt.Errorf("error sending SLO alert: %w", err)
+	}
+	lastSLOAlert = time.Now()
+	return nil
+}
+
+// formatSLOAlert renders the SLO report as an HTML table for the alert email
+func formatSLOAlert(report *models.SLOReport) string {
+	body := fmt.Sprintf("<p>Objective: %s</p><table border=\"1\" cellpadding=\"4\">"+
+		"<tr><th>Window</th><th>Webhooks</th><th>Good</th><th>Failed</th><th>Compliance</th><th>Burn rate</th><th>p99 (ms)</th></tr>",
+		report.Objective)
+	for _, w := range report.Windows {
+		body += fmt.Sprintf("<tr><td>%s</td><td>%d</td><td>%d</td><td>%d</td><td>%.2f%%</td><td>%.1fx</td><td>%.0f</td></tr>",
+			w.Window, w.Total, w.Good, w.Failed, w.Compliance*100, w.BurnRate, w.P99LatencyMs)
+	}
+	return body + "</table>"
+}
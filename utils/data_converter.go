@@ -26,8 +26,9 @@ func DeduplicateLeads(leads []models.Lead) []models.Lead {
 	return unique
 }
 
-// ScanLead reads a single lead row from database query results
-func ScanLead(rows *sql.Rows) (models.Lead, error) {
+// ScanLead reads a single lead row from database query results. Columns selected after
+// the lead's own are scanned into extra.
+func ScanLead(rows *sql.Rows, extra ...interface{}) (models.Lead, error) {
 	var lead models.Lead
 	var counsellorID sql.NullInt64
 	var registrationPaymentID sql.NullInt64
@@ -35,13 +36,13 @@ func ScanLead(rows *sql.Rows) (models.Lead, error) {
 	var coursePaymentID sql.NullInt64
 	var interviewScheduledAt sql.NullTime
 
-	err := rows.Scan(
-		&lead.ID, &lead.Name, &lead.Email, &lead.Phone,
+	err := rows.Scan(append([]interface{}{
+		&lead.ID, &lead.Name, DecryptedPII(&lead.Email), DecryptedPII(&lead.Phone),
 		&lead.Education, &lead.LeadSource, &counsellorID,
 		&lead.MeetLink, &lead.ApplicationStatus,
 		&registrationPaymentID, &selectedCourseID, &coursePaymentID, &interviewScheduledAt,
 		&lead.CreatedAt, &lead.UpdatedAt,
-	)
+	}, extra...)...)
 	if err != nil {
 		return lead, err
 	}
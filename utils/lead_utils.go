@@ -36,6 +36,10 @@ func ValidateLead(lead *models.Lead) error {
 		return err
 	}
 
+	if err := ValidateStateCode(lead.StateCode); err != nil {
+		return err
+	}
+
 	if lead.LeadSource == "" {
 		return fmt.Errorf("lead_source is required")
 	}
@@ -43,20 +47,18 @@ func ValidateLead(lead *models.Lead) error {
 	return nil
 }
 
-// LeadExists checks if a lead already exists by email or phone within a transaction
-func LeadExists(ctx context.Context, tx *sql.Tx, email, phone string) (bool, error) {
-	var count int
-	query := "SELECT COUNT(*) FROM student_lead WHERE email = $1 OR phone = $2"
-	err := tx.QueryRowContext(ctx, query, email, phone).Scan(&count)
-	if err != nil {
-		return false, err
-	}
-	return count > 0, nil
-}
+// onShiftFirst orders counselors scheduled for the running shift ($1 on ISO weekday $2)
+// ahead of the rest; with no shift running it orders nobody ahead
+const onShiftFirst = `EXISTS (
+					SELECT 1 FROM counselor_shift s
+					WHERE s.counselor_id = counselor.id AND s.shift = $1 AND $2 = ANY(s.weekdays)
+				 ) DESC`
 
-// GetAvailableCounselorID finds the best available counselor based on lead source
+// GetAvailableCounselorID finds the best available counselor based on lead source,
+// preferring counselors on the given shift (empty when no shift is running) and
+// falling back to the general pool
 // This should be called within a transaction for consistency
-func GetAvailableCounselorID(ctx context.Context, tx *sql.Tx, leadSource string) (*int64, error) {
+func GetAvailableCounselorID(ctx context.Context, tx *sql.Tx, leadSource, shift string, weekday int64) (*int64, error) {
 	var query string
 
 	// Route to appropriate counselor pool based on lead source
@@ -64,23 +66,26 @@ func GetAvailableCounselorID(ctx context.Context, tx *sql.Tx, leadSource string)
 	case "website":
 		query = `SELECT id FROM counselor 
 				 WHERE assigned_count < max_capacity 
-				 ORDER BY assigned_count ASC, id ASC 
+				 AND is_house_account = false
+				 ORDER BY ` + onShiftFirst + `, assigned_count ASC, id ASC 
 				 LIMIT 1 FOR UPDATE SKIP LOCKED`
 	case "referral":
 		query = `SELECT id FROM counselor 
 				 WHERE is_referral_enabled = true 
 				 AND assigned_count < max_capacity 
-				 ORDER BY assigned_count ASC, id ASC 
+				 AND is_house_account = false
+				 ORDER BY ` + onShiftFirst + `, assigned_count ASC, id ASC 
 				 LIMIT 1 FOR UPDATE SKIP LOCKED`
 	default:
 		query = `SELECT id FROM counselor 
 				 WHERE assigned_count < max_capacity 
-				 ORDER BY assigned_count ASC, id ASC 
+				 AND is_house_account = false
+				 ORDER BY ` + onShiftFirst + `, assigned_count ASC, id ASC 
 				 LIMIT 1 FOR UPDATE SKIP LOCKED`
 	}
 
 	var counselorID int64
-	err := tx.QueryRowContext(ctx, query).Scan(&counselorID)
+	err := tx.QueryRowContext(ctx, query, shift, weekday).Scan(&counselorID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // No available counselor
@@ -91,14 +96,16 @@ func GetAvailableCounselorID(ctx context.Context, tx *sql.Tx, leadSource string)
 	return &counselorID, nil
 }
 
-// InsertLead inserts a new lead record and returns the lead ID
+// InsertLead inserts a new lead record and returns the lead ID. Email and phone are
+// stored encrypted, with their blind indexes.
 func InsertLead(ctx context.Context, tx *sql.Tx, lead *models.Lead) (int64, error) {
 	query := `
 		INSERT INTO student_lead (
-			name, email, phone, education, lead_source, 
+			name, email, phone, education, lead_source, campaign,
+			utm_source, utm_medium, utm_campaign, remarketing_consent,
 			counselor_id, registration_fee_status, course_fee_status, meet_link, 
-			application_status, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			application_status, created_at, updated_at, email_index, phone_index, state_code
+		) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, NULLIF($20, ''))
 		RETURNING id`
 
 	var leadID int64
@@ -106,10 +113,15 @@ func InsertLead(ctx context.Context, tx *sql.Tx, lead *models.Lead) (int64, erro
 		ctx,
 		query,
 		lead.Name,
-		lead.Email,
-		lead.Phone,
+		EncryptedPII(lead.Email),
+		EncryptedPII(lead.Phone),
 		lead.Education,
 		lead.LeadSource,
+		lead.Campaign,
+		lead.UTMSource,
+		lead.UTMMedium,
+		lead.UTMCampaign,
+		lead.RemarketingConsent,
 		lead.CounsellorID,
 		"PENDING",
 		"PENDING",
@@ -117,6 +129,9 @@ func InsertLead(ctx context.Context, tx *sql.Tx, lead *models.Lead) (int64, erro
 		lead.ApplicationStatus,
 		lead.CreatedAt,
 		lead.UpdatedAt,
+		PIIBlindIndex(lead.Email),
+		PIIBlindIndex(lead.Phone),
+		lead.StateCode,
 	).Scan(&leadID)
 
 	if err != nil {
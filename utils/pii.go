@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Lead emails and phone numbers are encrypted at rest with AES-GCM when PII_ENCRYPTION_KEY
+// is set. Since the ciphertext differs on every write, equality lookups go through a blind
+// index instead: a keyed HMAC-SHA256 of the plaintext, stored next to it. Values written
+// before encryption was enabled have no prefix and are read as they are.
+
+// EncryptedPIIPrefix marks an encrypted value; the version allows a change of scheme later
+const EncryptedPIIPrefix = "enc:v1:"
+
+// ErrPIIKeyMissing is returned when reading an encrypted value without the key
+var ErrPIIKeyMissing = errors.New("value is encrypted but PII_ENCRYPTION_KEY is not set")
+
+var (
+	piiAEAD     cipher.AEAD
+	piiIndexKey []byte
+)
+
+// InitPII sets the key for PII encryption and blind indexes: 32 random bytes, base64
+// encoded. Separate keys are derived from it for each use. An empty key disables
+// encryption; blind indexes are then unkeyed hashes.
+func InitPII(key string) error {
+	piiAEAD, piiIndexKey = nil, nil
+	if key == "" {
+		return nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil || len(raw) != 32 {
+		return fmt.Errorf("PII_ENCRYPTION_KEY must be 32 bytes, base64 encoded")
+	}
+
+	block, err := aes.NewCipher(derivePIIKey(raw, "encryption"))
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	piiAEAD, piiIndexKey = aead, derivePIIKey(raw, "blind-index")
+	return nil
+}
+
+// PIIEncryptionEnabled reports whether PII is encrypted on write
+func PIIEncryptionEnabled() bool {
+	return piiAEAD != nil
+}
+
+// IsEncryptedPII reports whether value was written by EncryptPII with encryption enabled
+func IsEncryptedPII(value string) bool {
+	return strings.HasPrefix(value, EncryptedPIIPrefix)
+}
+
+func derivePIIKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// EncryptPII encrypts value with a random nonce. Empty values, and every value while
+// encryption is disabled, are returned unchanged.
+func EncryptPII(value string) (string, error) {
+	if value == "" || piiAEAD == nil {
+		return value, nil
+	}
+	nonce := make([]byte, piiAEAD.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("error generating nonce: %w", err)
+	}
+	sealed := piiAEAD.Seal(nonce, nonce, []byte(value), nil)
+	return EncryptedPIIPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptPII returns the plaintext of a value written by EncryptPII
+func DecryptPII(value string) (string, error) {
+	if !IsEncryptedPII(value) {
+		return value, nil
+	}
+	if piiAEAD == nil {
+		return "", ErrPIIKeyMissing
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, EncryptedPIIPrefix))
+	if err != nil || len(sealed) < piiAEAD.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	nonce, ciphertext := sealed[:piiAEAD.NonceSize()], sealed[piiAEAD.NonceSize():]
+	plaintext, err := piiAEAD.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("error decrypting value (wrong PII_ENCRYPTION_KEY?): %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// PIIBlindIndex returns the lookup key of value, hex encoded; "" for an empty value
+func PIIBlindIndex(value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, piiIndexKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// EncryptedPII is a query argument writing value encrypted
+type EncryptedPII string
+
+// Value implements driver.Valuer
+func (v EncryptedPII) Value() (driver.Value, error) {
+	return EncryptPII(string(v))
+}
+
+// DecryptedPII scans an encrypted column into dest, decrypting it. NULL scans as "".
+func DecryptedPII(dest *string) *PIIScanner {
+	return &PIIScanner{dest: dest}
+}
+
+// PIIScanner is the sql.Scanner returned by DecryptedPII
+type PIIScanner struct {
+	dest *string
+}
+
+// Scan implements sql.Scanner
+func (s *PIIScanner) Scan(src interface{}) error {
+	var value string
+	switch v := src.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into a PII value", src)
+	}
+	plaintext, err := DecryptPII(value)
+	if err != nil {
+		return err
+	}
+	*s.dest = plaintext
+	return nil
+}
@@ -9,6 +9,8 @@ import (
 var (
 	EmailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
 	PhoneRegex = regexp.MustCompile(`^\+?[1-9]\d{1,14}$`)
+	// StateCodeRegex matches a two-digit GST state code, e.g. 29 for Karnataka
+	StateCodeRegex = regexp.MustCompile(`^\d{2}$`)
 )
 
 // LeadValidationRules contains validation configuration
@@ -72,6 +74,14 @@ func ValidateEducation(education string) error {
 	return nil
 }
 
+// ValidateStateCode checks that an optional GST state code has two digits
+func ValidateStateCode(code string) error {
+	if code != "" && !StateCodeRegex.MatchString(code) {
+		return fmt.Errorf("state_code must be a two-digit GST state code, e.g. 29")
+	}
+	return nil
+}
+
 // // ValidateLeadSource checks if lead source is valid
 // func ValidateLeadSource(leadSource string) error {
 // 	validSources := map[string]bool{